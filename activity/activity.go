@@ -0,0 +1,216 @@
+// Package activity reads the Drive Activity API: who created, edited,
+// moved, renamed, shared, or deleted a file or the items of a folder.
+//
+// The client's token needs the drive.activity.readonly scope.
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Endpoint is the activity query method of the Drive Activity API.
+const Endpoint = "https://driveactivity.googleapis.com/v2/activity:query"
+
+// Actions, as used in Query.Actions and reported in Activity.Action.
+const (
+	ActionCreate           = "CREATE"
+	ActionEdit             = "EDIT"
+	ActionMove             = "MOVE"
+	ActionRename           = "RENAME"
+	ActionDelete           = "DELETE"
+	ActionRestore          = "RESTORE"
+	ActionPermissionChange = "PERMISSION_CHANGE"
+	ActionComment          = "COMMENT"
+	ActionSettingsChange   = "SETTINGS_CHANGE"
+)
+
+// Query selects activity.
+type Query struct {
+	// ItemID is the file or folder to report on.
+	ItemID string
+	// Descendants reports on everything below the folder ItemID instead of
+	// the folder itself.
+	Descendants bool
+	// Since and Until bound the time range; zero values leave it open.
+	Since, Until time.Time
+	// Actions, if set, limits the report to these actions.
+	Actions []string
+	// Limit stops after this many activities; 0 means no limit.
+	Limit int
+}
+
+// Activity is one thing that happened to one or more items.
+type Activity struct {
+	Time time.Time
+	// Action is the primary action, e.g. ActionEdit.
+	Action string
+	// Detail is the raw action detail, e.g. the old and new parents of a
+	// move, for callers that need more than Action.
+	Detail  json.RawMessage
+	Actors  []Actor
+	Targets []Target
+}
+
+// Actor is who performed an activity.
+type Actor struct {
+	// Person is a People API resource name such as "people/1234"; the
+	// Activity API does not report email addresses.
+	Person        string
+	IsCurrentUser bool
+	// Kind is "user", "anonymous", "administrator", "system", or
+	// "impersonation".
+	Kind string
+}
+
+// Target is an item an activity applied to.
+type Target struct {
+	ID       string
+	Title    string
+	MimeType string
+}
+
+type rawActivity struct {
+	PrimaryActionDetail map[string]json.RawMessage `json:"primaryActionDetail"`
+	Actors              []struct {
+		User *struct {
+			KnownUser *struct {
+				PersonName    string `json:"personName"`
+				IsCurrentUser bool   `json:"isCurrentUser"`
+			} `json:"knownUser"`
+		} `json:"user"`
+		Anonymous     json.RawMessage `json:"anonymous"`
+		Administrator json.RawMessage `json:"administrator"`
+		System        json.RawMessage `json:"system"`
+		Impersonation json.RawMessage `json:"impersonation"`
+	} `json:"actors"`
+	Targets []struct {
+		DriveItem *struct {
+			Name     string `json:"name"`
+			Title    string `json:"title"`
+			MimeType string `json:"mimeType"`
+		} `json:"driveItem"`
+	} `json:"targets"`
+	Timestamp time.Time `json:"timestamp"`
+	TimeRange *struct {
+		EndTime time.Time `json:"endTime"`
+	} `json:"timeRange"`
+}
+
+func (r rawActivity) activity() Activity {
+	a := Activity{Time: r.Timestamp}
+	if r.TimeRange != nil {
+		a.Time = r.TimeRange.EndTime
+	}
+	for k, v := range r.PrimaryActionDetail {
+		a.Action, a.Detail = upperSnake(k), v
+	}
+	for _, ra := range r.Actors {
+		var act Actor
+		switch {
+		case ra.User != nil:
+			act.Kind = "user"
+			if ra.User.KnownUser != nil {
+				act.Person, act.IsCurrentUser = ra.User.KnownUser.PersonName, ra.User.KnownUser.IsCurrentUser
+			}
+		case ra.Anonymous != nil:
+			act.Kind = "anonymous"
+		case ra.Administrator != nil:
+			act.Kind = "administrator"
+		case ra.System != nil:
+			act.Kind = "system"
+		case ra.Impersonation != nil:
+			act.Kind = "impersonation"
+		}
+		a.Actors = append(a.Actors, act)
+	}
+	for _, rt := range r.Targets {
+		if rt.DriveItem == nil {
+			continue
+		}
+		a.Targets = append(a.Targets, Target{
+			ID:       strings.TrimPrefix(rt.DriveItem.Name, "items/"),
+			Title:    rt.DriveItem.Title,
+			MimeType: rt.DriveItem.MimeType,
+		})
+	}
+	return a
+}
+
+// upperSnake turns an action detail key such as "permissionChange" into
+// the filter form "PERMISSION_CHANGE".
+func upperSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func (q Query) filter() string {
+	var terms []string
+	if !q.Since.IsZero() {
+		terms = append(terms, fmt.Sprintf("time >= %q", q.Since.UTC().Format(time.RFC3339)))
+	}
+	if !q.Until.IsZero() {
+		terms = append(terms, fmt.Sprintf("time < %q", q.Until.UTC().Format(time.RFC3339)))
+	}
+	if len(q.Actions) > 0 {
+		actions := append([]string(nil), q.Actions...)
+		sort.Strings(actions)
+		terms = append(terms, "detail.action_detail_case:("+strings.Join(actions, " ")+")")
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// List returns the activity selected by q, newest first.
+func List(ctx context.Context, c *drive.Client, q Query) ([]Activity, error) {
+	if q.ItemID == "" {
+		return nil, errors.New("item ID is required")
+	}
+	id, err := drive.ParseID(q.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{"pageSize": 100}
+	if q.Descendants {
+		body["ancestorName"] = "items/" + id
+	} else {
+		body["itemName"] = "items/" + id
+	}
+	if f := q.filter(); f != "" {
+		body["filter"] = f
+	}
+	var all []Activity
+	for {
+		var page struct {
+			Activities    []rawActivity `json:"activities"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := c.DoJSON(ctx, http.MethodPost, Endpoint, body, &page); err != nil {
+			return nil, fmt.Errorf("query activity: %w", err)
+		}
+		for _, r := range page.Activities {
+			all = append(all, r.activity())
+			if q.Limit > 0 && len(all) == q.Limit {
+				return all, nil
+			}
+		}
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		body["pageToken"] = page.NextPageToken
+	}
+}
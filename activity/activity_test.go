@@ -0,0 +1,82 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func TestList(t *testing.T) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/activity:query" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["pageToken"] == nil {
+			w.Write([]byte(`{"nextPageToken":"n","activities":[{
+				"primaryActionDetail":{"permissionChange":{"addedPermissions":[]}},
+				"actors":[{"user":{"knownUser":{"personName":"people/1","isCurrentUser":true}}}],
+				"targets":[{"driveItem":{"name":"items/f","title":"SOP-001.pdf","mimeType":"application/pdf"}}],
+				"timestamp":"2024-05-01T10:00:00Z"}]}`))
+			return
+		}
+		w.Write([]byte(`{"activities":[{
+			"primaryActionDetail":{"edit":{}},
+			"actors":[{"anonymous":{}}],
+			"targets":[{"driveItem":{"name":"items/f"}}],
+			"timeRange":{"startTime":"2024-04-01T00:00:00Z","endTime":"2024-04-02T00:00:00Z"}}]}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := List(context.Background(), c, Query{ItemID: "f", Since: since, Actions: []string{ActionEdit, ActionPermissionChange}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if bodies[0]["itemName"] != "items/f" || bodies[0]["filter"] != `time >= "2024-01-01T00:00:00Z" AND detail.action_detail_case:(EDIT PERMISSION_CHANGE)` {
+		t.Fatalf("request = %v", bodies[0])
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d activities", len(got))
+	}
+	a := got[0]
+	if a.Action != ActionPermissionChange || len(a.Actors) != 1 || a.Actors[0].Person != "people/1" || !a.Actors[0].IsCurrentUser || a.Targets[0].ID != "f" || a.Targets[0].Title != "SOP-001.pdf" {
+		t.Fatalf("first activity = %+v", a)
+	}
+	if b := got[1]; b.Action != ActionEdit || b.Actors[0].Kind != "anonymous" || !b.Time.Equal(time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("second activity = %+v", b)
+	}
+
+	if _, err := List(context.Background(), c, Query{ItemID: "folder", Descendants: true, Limit: 1}); err != nil {
+		t.Fatalf("List descendants: %v", err)
+	}
+	if last := bodies[len(bodies)-1]; last["ancestorName"] != "items/folder" || last["filter"] != nil {
+		t.Fatalf("request = %v", last)
+	}
+}
@@ -0,0 +1,115 @@
+// Package appdata keeps small state files (deploy journals, sync cursors,
+// change tokens) in the hidden appDataFolder of the caller's Drive, so the
+// state follows the account rather than living on one machine.
+//
+// The client's token needs the drive.appdata scope. The folder is private
+// to the OAuth client that created it: other applications, and the user in
+// the Drive UI, cannot see it.
+package appdata
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hwalton/gdrivetoolbox/changes"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// FolderID is the alias Drive accepts for the appDataFolder.
+const FolderID = "appDataFolder"
+
+// Store reads and writes named files in the appDataFolder.
+type Store struct {
+	Client *drive.Client
+}
+
+// New returns a Store using c.
+func New(c *drive.Client) *Store {
+	return &Store{Client: c}
+}
+
+func (s *Store) find(ctx context.Context, name string) (drive.File, error) {
+	return list.First(ctx, s.Client, list.Options{
+		Query:  query.New().InParent(FolderID).NameEquals(name).String(),
+		Fields: "id,name,modifiedTime",
+		Spaces: FolderID,
+	})
+}
+
+// Get returns the content of name. A missing file yields an error matching
+// drive.ErrNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	f, err := s.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := s.Client.Download(ctx, f.ID, &buf); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Put stores data as name, replacing any previous content.
+func (s *Store) Put(ctx context.Context, name string, data []byte) error {
+	f, err := s.find(ctx, name)
+	switch {
+	case err == nil:
+		_, err = s.Client.UpdateContent(ctx, f.ID, bytes.NewReader(data), int64(len(data)))
+	case errors.Is(err, drive.ErrNotFound):
+		meta := drive.Metadata{Name: name, Parents: []string{FolderID}, MimeType: "application/octet-stream"}
+		_, err = s.Client.Upload(ctx, meta, bytes.NewReader(data), int64(len(data)))
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes name. Deleting a missing file is not an error.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	f, err := s.find(ctx, name)
+	if errors.Is(err, drive.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Client.Delete(ctx, f.ID)
+}
+
+// List returns the stored files.
+func (s *Store) List(ctx context.Context) ([]drive.File, error) {
+	return list.ListFiles(ctx, s.Client, list.Options{
+		Query:  query.New().InParent(FolderID).String(),
+		Fields: "id,name,size,modifiedTime",
+		Spaces: FolderID,
+	})
+}
+
+// TokenStore returns a changes.TokenStore that keeps the page token in the
+// file name.
+func (s *Store) TokenStore(name string) changes.TokenStore {
+	return tokenStore{s: s, name: name}
+}
+
+type tokenStore struct {
+	s    *Store
+	name string
+}
+
+func (t tokenStore) Load() (string, error) {
+	b, err := t.s.Get(context.Background(), t.name)
+	if errors.Is(err, drive.ErrNotFound) {
+		return "", nil
+	}
+	return string(bytes.TrimSpace(b)), err
+}
+
+func (t tokenStore) Save(token string) error {
+	return t.s.Put(context.Background(), t.name, []byte(token))
+}
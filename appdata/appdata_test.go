@@ -0,0 +1,128 @@
+package appdata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+// fakeAppData serves an in-memory appDataFolder keyed by file name. A
+// file's ID is its name with dots replaced.
+func fakeAppData(t *testing.T, files map[string]string) *httptest.Server {
+	name := func(path string) string {
+		id := path[strings.LastIndex(path, "/")+1:]
+		return strings.ReplaceAll(id, "_", ".")
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			if r.URL.Query().Get("spaces") != FolderID {
+				t.Errorf("listing without spaces=appDataFolder: %s", r.URL.RawQuery)
+			}
+			q := r.URL.Query().Get("q")
+			var out []map[string]string
+			for name := range files {
+				if strings.Contains(q, "name = '"+name+"'") || !strings.Contains(q, "name =") {
+					out = append(out, map[string]string{"id": strings.ReplaceAll(name, ".", "_"), "name": name})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"files": out})
+		case r.Method == http.MethodGet:
+			io.WriteString(w, files[name(r.URL.Path)])
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			var meta drive.Metadata
+			part, _ := mr.NextPart()
+			json.NewDecoder(part).Decode(&meta)
+			if len(meta.Parents) != 1 || meta.Parents[0] != FolderID {
+				t.Errorf("created outside appDataFolder: %+v", meta)
+			}
+			part, _ = mr.NextPart()
+			b, _ := io.ReadAll(part)
+			files[meta.Name] = string(b)
+			w.Write([]byte(`{"id":"` + strings.ReplaceAll(meta.Name, ".", "_") + `"}`))
+		case r.Method == http.MethodPatch:
+			b, _ := io.ReadAll(r.Body)
+			files[name(r.URL.Path)] = string(b)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodDelete:
+			delete(files, name(r.URL.Path))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestStore(t *testing.T) {
+	files := map[string]string{}
+	srv := fakeAppData(t, files)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	s := New(c)
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "journal.json"); !errors.Is(err, drive.ErrNotFound) {
+		t.Fatalf("Get missing = %v", err)
+	}
+	if err := s.Put(ctx, "journal.json", []byte("v1")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := s.Put(ctx, "journal.json", []byte("v2")); err != nil {
+		t.Fatalf("Put again: %v", err)
+	}
+	if b, err := s.Get(ctx, "journal.json"); err != nil || string(b) != "v2" {
+		t.Fatalf("Get = %q, %v", b, err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("files = %v; update should not create a second file", files)
+	}
+
+	ts := s.TokenStore("changes.token")
+	if tok, err := ts.Load(); err != nil || tok != "" {
+		t.Fatalf("Load empty = %q, %v", tok, err)
+	}
+	if err := ts.Save("42"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if tok, err := ts.Load(); err != nil || tok != "42" {
+		t.Fatalf("Load = %q, %v", tok, err)
+	}
+
+	all, err := s.List(ctx)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("List = %v, %v", all, err)
+	}
+	if err := s.Delete(ctx, "journal.json"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "journal.json"); err != nil {
+		t.Fatalf("Delete missing: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// ZipFolder streams every file below folderID into a zip archive written to
+// w. Subfolders become directories in the archive and Google-native files
+// are exported using drive.DefaultExportFormats; native types without an
+// export format (forms, sites) and shortcuts to folders are skipped;
+// shortcuts to files are archived as their target.
+func ZipFolder(ctx context.Context, c *drive.Client, folderID string, w io.Writer) error {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	if err := zipDir(ctx, c, zw, folderID, ""); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+func zipDir(ctx context.Context, c *drive.Client, zw *zip.Writer, folderID, prefix string) error {
+	children, err := list.Children(ctx, c, folderID)
+	if err != nil {
+		return fmt.Errorf("list folder %s: %w", folderID, err)
+	}
+	used := map[string]int{}
+	for _, f := range children {
+		if f.IsFolder() {
+			dir := uniqueName(used, prefix+safeName(f.Name))
+			if _, err := zw.CreateHeader(&zip.FileHeader{Name: dir + "/", Modified: f.ModifiedTime}); err != nil {
+				return err
+			}
+			if err := zipDir(ctx, c, zw, f.ID, dir+"/"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Shortcuts to files are archived as the file they point at.
+		id, mimeType := f.ID, f.MimeType
+		if sd := f.ShortcutDetails; f.IsShortcut() && sd != nil && sd.TargetMimeType != drive.FolderMimeType {
+			id, mimeType = sd.TargetID, sd.TargetMimeType
+		}
+		name := safeName(f.Name)
+		var export drive.ExportFormat
+		if drive.IsNative(mimeType) {
+			var ok bool
+			if export, ok = drive.DefaultExportFormats[mimeType]; !ok {
+				continue
+			}
+			if !strings.EqualFold(path.Ext(name), export.Extension) {
+				name += export.Extension
+			}
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     uniqueName(used, prefix+name),
+			Method:   zip.Deflate,
+			Modified: f.ModifiedTime,
+		})
+		if err != nil {
+			return err
+		}
+		if export.MimeType != "" {
+			_, err = c.Export(ctx, id, export.MimeType, fw)
+		} else {
+			_, err = c.Download(ctx, id, fw)
+		}
+		if err != nil {
+			return fmt.Errorf("fetch %s: %w", prefix+f.Name, err)
+		}
+	}
+	return nil
+}
+
+// safeName keeps Drive names (which may contain slashes) as a single path element.
+func safeName(name string) string {
+	name = strings.ReplaceAll(name, "/", "_")
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// uniqueName disambiguates duplicate names, which Drive allows within a folder.
+func uniqueName(used map[string]int, name string) string {
+	used[name]++
+	if used[name] == 1 {
+		return name
+	}
+	ext := path.Ext(name)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), used[name], ext)
+}
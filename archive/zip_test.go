@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func TestZipFolder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/drive/v3/files" && strings.Contains(q.Get("q"), "'root' in parents"):
+			if q.Get("pageToken") == "" {
+				w.Write([]byte(`{"nextPageToken":"p2","files":[
+					{"id":"f1","name":"report.pdf","mimeType":"application/pdf","modifiedTime":"2025-01-02T03:04:05Z"},
+					{"id":"d1","name":"Notes","mimeType":"application/vnd.google-apps.document"}]}`))
+				return
+			}
+			w.Write([]byte(`{"files":[
+				{"id":"sub","name":"sub","mimeType":"application/vnd.google-apps.folder"},
+				{"id":"f2","name":"report.pdf","mimeType":"application/pdf"},
+				{"id":"form","name":"Survey","mimeType":"application/vnd.google-apps.form"},
+				{"id":"sc","name":"linked.pdf","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"f9","targetMimeType":"application/pdf"}}]}`))
+		case r.URL.Path == "/drive/v3/files" && strings.Contains(q.Get("q"), "'sub' in parents"):
+			w.Write([]byte(`{"files":[{"id":"f3","name":"a/b.csv","mimeType":"text/csv"}]}`))
+		case r.URL.Path == "/drive/v3/files/d1/export":
+			w.Write([]byte("docx:" + q.Get("mimeType")))
+		case strings.HasPrefix(r.URL.Path, "/drive/v3/files/") && q.Get("alt") == "media":
+			w.Write([]byte("content-" + strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")))
+		default:
+			http.Error(w, "unexpected "+r.URL.String(), http.StatusNotImplemented)
+		}
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	if err := ZipFolder(context.Background(), testClient(srv), "root", &buf); err != nil {
+		t.Fatalf("ZipFolder: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+	got := map[string]string{}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		got[f.Name] = string(b)
+	}
+	sort.Strings(names)
+	want := []string{"Notes.docx", "linked.pdf", "report (2).pdf", "report.pdf", "sub/", "sub/a_b.csv"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v; want %v", names, want)
+	}
+	if got["report.pdf"] != "content-f1" || got["report (2).pdf"] != "content-f2" || got["sub/a_b.csv"] != "content-f3" || got["linked.pdf"] != "content-f9" {
+		t.Fatalf("unexpected contents: %v", got)
+	}
+	if !strings.HasPrefix(got["Notes.docx"], "docx:application/vnd.openxmlformats") {
+		t.Fatalf("doc was not exported: %q", got["Notes.docx"])
+	}
+}
+
+func TestZipFolder_ListError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"nope"}}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+	if err := ZipFolder(context.Background(), testClient(srv), "root", io.Discard); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,426 @@
+// Package auditlog keeps an append-only trail of every change made to
+// Drive: uploads, renames, moves, trashing, deletes, and permission
+// changes, each with who made it and what the values were before and after,
+// as document control audits ask for.
+//
+// Changes are seen at the HTTP level by the RoundTripper from
+// Log.Transport, so every request made through it is audited, whichever
+// package sends it. Entries are written as JSON lines:
+//
+//	{"time":"2024-05-02T10:14:00Z","actor":"ada@example.com","operation":"rename","fileId":"1AbC...","old":{"name":"draft.pdf"},"new":{"name":"final.pdf"}}
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operations recorded in an Entry.
+const (
+	OpCreate         = "create"          // a folder or other file made without content
+	OpUpload         = "upload"          // a file uploaded, or new content for one
+	OpCopy           = "copy"            // a server-side copy
+	OpRename         = "rename"          // a new name
+	OpMove           = "move"            // new parents, and maybe a new name
+	OpUpdate         = "update"          // other metadata, such as the description
+	OpTrash          = "trash"           // moved to the trash
+	OpUntrash        = "untrash"         // restored from the trash
+	OpDelete         = "delete"          // deleted for good
+	OpEmptyTrash     = "empty-trash"     // the whole trash deleted
+	OpShare          = "share"           // a permission added
+	OpUpdateShare    = "update-share"    // a permission changed
+	OpUnshare        = "unshare"         // a permission removed
+	OpUpdateRevision = "update-revision" // a revision pinned or unpinned
+	OpDeleteRevision = "delete-revision" // a revision deleted
+)
+
+// Entry is one audited change.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Actor is the account that made the change.
+	Actor        string `json:"actor,omitempty"`
+	Operation    string `json:"operation"`
+	FileID       string `json:"fileId,omitempty"`
+	PermissionID string `json:"permissionId,omitempty"`
+	RevisionID   string `json:"revisionId,omitempty"`
+	// Old holds the changed values as they were, and New as they became.
+	Old map[string]any `json:"old,omitempty"`
+	New map[string]any `json:"new,omitempty"`
+}
+
+// Log writes Entries as JSON lines. Its methods are safe for concurrent
+// use.
+type Log struct {
+	// Actor, if set, is recorded for every entry instead of the account
+	// that the request's credentials belong to.
+	Actor string
+	// Now returns the time of an entry; nil means time.Now.
+	Now func() time.Time
+	// OnRecord, if set, is also given each entry, as to ship it to a log
+	// service.
+	OnRecord func(Entry)
+
+	mu     sync.Mutex
+	w      io.Writer
+	path   string
+	actors map[string]string // by Authorization header
+}
+
+// New returns a Log that writes to w.
+func New(w io.Writer) *Log {
+	return &Log{w: w, actors: map[string]string{}}
+}
+
+// OpenFile returns a Log that appends to the file at path, creating it if
+// needed. The file is opened for each entry, so it can be rotated while
+// the Log is in use.
+func OpenFile(path string) *Log {
+	return &Log{path: path, actors: map[string]string{}}
+}
+
+// Record writes e, setting its time and actor if they are unset.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	e.Time = e.Time.UTC()
+	if l.Actor != "" && e.Actor == "" {
+		e.Actor = l.Actor
+	}
+	if l.OnRecord != nil {
+		l.OnRecord(e)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		_, err = l.w.Write(b)
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *Log) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// Transport returns a RoundTripper that sends requests through base, or
+// http.DefaultTransport if nil, and records an Entry for each one that
+// changes Drive and succeeds. Before a change it reads the values about
+// to be replaced, and it asks Drive once per credential who the actor is.
+// OnError, if not nil, is called when an entry cannot be written; the
+// request itself is not failed.
+func (l *Log) Transport(base http.RoundTripper, onError func(error)) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{l: l, base: base, onError: onError}
+}
+
+type transport struct {
+	l       *Log
+	base    http.RoundTripper
+	onError func(error)
+}
+
+// change is what a request is about to do.
+type change struct {
+	op               string
+	file, perm, rev  string
+	old, new         map[string]any
+	readOld          string   // path to GET more old values from
+	oldFields        []string // fields to read there
+	newFromResponse  []string // fields of the response to record as new
+	fileFromResponse bool
+	permFromResponse bool
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ch, body, err := classify(req)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return t.base.RoundTrip(req)
+	}
+	if body != nil {
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	if ch.readOld != "" && len(ch.oldFields) > 0 {
+		old := t.get(req, ch.readOld, "fields="+url.QueryEscape(strings.Join(ch.oldFields, ",")))
+		for _, k := range ch.oldFields {
+			if v, ok := old[k]; ok {
+				ch.old = with(ch.old, k, v)
+			}
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode/100 != 2 {
+		return resp, err
+	}
+	if ch.fileFromResponse || ch.permFromResponse || len(ch.newFromResponse) > 0 {
+		b, rerr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if rerr != nil {
+			return nil, rerr
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(b))
+		var got map[string]any
+		json.Unmarshal(b, &got)
+		id, _ := got["id"].(string)
+		if ch.fileFromResponse {
+			ch.file = id
+		}
+		if ch.permFromResponse {
+			ch.perm = id
+		}
+		var missing []string
+		for _, k := range ch.newFromResponse {
+			if v, ok := got[k]; ok {
+				ch.new = with(ch.new, k, v)
+			} else {
+				missing = append(missing, k)
+			}
+		}
+		// Uploads often ask only for the ID, so the rest is read after.
+		if len(missing) > 0 && ch.file != "" && !ch.permFromResponse {
+			f := t.get(req, "files/"+ch.file, "fields="+url.QueryEscape(strings.Join(missing, ",")))
+			for _, k := range missing {
+				if v, ok := f[k]; ok {
+					ch.new = with(ch.new, k, v)
+				}
+			}
+		}
+	}
+	e := Entry{Actor: t.actor(req), Operation: ch.op, FileID: ch.file, PermissionID: ch.perm, RevisionID: ch.rev, Old: ch.old, New: ch.new}
+	if rerr := t.l.Record(e); rerr != nil && t.onError != nil {
+		t.onError(rerr)
+	}
+	return resp, err
+}
+
+// get reads the JSON object at path, relative to the Drive API of req,
+// with the same credentials, returning nil if it cannot.
+func (t *transport) get(req *http.Request, path, query string) map[string]any {
+	u := *req.URL
+	u.Path, u.RawPath, u.RawQuery = "/drive/v3/"+path, "", query+"&supportsAllDrives=true"
+	if key := req.URL.Query().Get("key"); key != "" {
+		u.RawQuery += "&key=" + url.QueryEscape(key)
+	}
+	get, err := http.NewRequestWithContext(req.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		get.Header.Set("Authorization", auth)
+	}
+	resp, err := t.base.RoundTrip(get)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil
+	}
+	var out map[string]any
+	if json.NewDecoder(resp.Body).Decode(&out) != nil {
+		return nil
+	}
+	return out
+}
+
+// actor returns the email address of the account req is made as.
+func (t *transport) actor(req *http.Request) string {
+	if t.l.Actor != "" {
+		return t.l.Actor
+	}
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return ""
+	}
+	t.l.mu.Lock()
+	a, ok := t.l.actors[auth]
+	t.l.mu.Unlock()
+	if ok {
+		return a
+	}
+	about := t.get(req, "about", "fields=user(emailAddress)")
+	if user, ok := about["user"].(map[string]any); ok {
+		a, _ = user["emailAddress"].(string)
+	}
+	t.l.mu.Lock()
+	t.l.actors[auth] = a
+	t.l.mu.Unlock()
+	return a
+}
+
+// classify works out which change req makes, returning nil for requests
+// that change nothing. The request body is returned when it had to be
+// read.
+func classify(req *http.Request) (*change, []byte, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return nil, nil, nil
+	}
+	path := req.URL.Path
+	upload := false
+	switch {
+	case strings.HasPrefix(path, "/upload/drive/v3/"):
+		path, upload = strings.TrimPrefix(path, "/upload/drive/v3/"), true
+	case strings.HasPrefix(path, "/drive/v3/"):
+		path = strings.TrimPrefix(path, "/drive/v3/")
+	default:
+		return nil, nil, nil
+	}
+	seg := strings.Split(path, "/")
+	if seg[0] != "files" {
+		return nil, nil, nil
+	}
+	q := req.URL.Query()
+
+	if upload {
+		if len(seg) == 1 && req.Method == http.MethodPost {
+			return &change{op: OpUpload, fileFromResponse: true, newFromResponse: []string{"name", "parents"}}, nil, nil
+		}
+		if len(seg) == 2 && req.Method == http.MethodPatch {
+			return &change{op: OpUpload, file: seg[1], newFromResponse: []string{"name", "headRevisionId"}}, nil, nil
+		}
+		return nil, nil, nil
+	}
+
+	switch {
+	case len(seg) == 1 && req.Method == http.MethodPost:
+		return &change{op: OpCreate, fileFromResponse: true, newFromResponse: []string{"name", "mimeType", "parents"}}, nil, nil
+	case len(seg) == 2 && seg[1] == "trash" && req.Method == http.MethodDelete:
+		return &change{op: OpEmptyTrash}, nil, nil
+	case len(seg) == 2 && req.Method == http.MethodDelete:
+		return &change{op: OpDelete, file: seg[1], readOld: "files/" + seg[1], oldFields: []string{"name", "parents"}}, nil, nil
+	case len(seg) == 2 && req.Method == http.MethodPatch:
+		body, patch, err := readPatch(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch := &change{file: seg[1], new: patch, readOld: "files/" + seg[1], oldFields: slices.Sorted(maps.Keys(patch))}
+		if add := q.Get("addParents"); add != "" {
+			ch.op = OpMove
+			ch.new = with(ch.new, "parents", strings.Split(add, ","))
+			if rm := q.Get("removeParents"); rm != "" {
+				ch.old = with(nil, "parents", strings.Split(rm, ","))
+			}
+		} else if trashed, ok := patch["trashed"].(bool); ok && trashed {
+			ch.op = OpTrash
+		} else if ok {
+			ch.op = OpUntrash
+		} else if _, ok := patch["name"]; ok {
+			ch.op = OpRename
+		} else {
+			ch.op = OpUpdate
+		}
+		return ch, body, nil
+	case len(seg) == 3 && seg[2] == "copy" && req.Method == http.MethodPost:
+		return &change{op: OpCopy, fileFromResponse: true, old: map[string]any{"id": seg[1]}, newFromResponse: []string{"name", "parents"}}, nil, nil
+	case len(seg) >= 3 && seg[2] == "permissions":
+		return classifyPermission(req, seg)
+	case len(seg) == 4 && seg[2] == "revisions":
+		ch := &change{file: seg[1], rev: seg[3]}
+		switch req.Method {
+		case http.MethodDelete:
+			ch.op = OpDeleteRevision
+			return ch, nil, nil
+		case http.MethodPatch:
+			body, patch, err := readPatch(req)
+			if err != nil {
+				return nil, nil, err
+			}
+			ch.op, ch.new = OpUpdateRevision, patch
+			ch.readOld, ch.oldFields = "files/"+seg[1]+"/revisions/"+seg[3], slices.Sorted(maps.Keys(patch))
+			return ch, body, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+func classifyPermission(req *http.Request, seg []string) (*change, []byte, error) {
+	ch := &change{file: seg[1]}
+	if len(seg) == 4 {
+		ch.perm = seg[3]
+	}
+	permFields := []string{"type", "role", "emailAddress", "domain"}
+	switch {
+	case len(seg) == 3 && req.Method == http.MethodPost:
+		body, perm, err := readPatch(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch.op, ch.new, ch.permFromResponse = OpShare, perm, true
+		return ch, body, nil
+	case len(seg) == 4 && req.Method == http.MethodPatch:
+		body, patch, err := readPatch(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		ch.op, ch.new = OpUpdateShare, patch
+		ch.readOld, ch.oldFields = "files/"+seg[1]+"/permissions/"+seg[3], slices.Sorted(maps.Keys(patch))
+		return ch, body, nil
+	case len(seg) == 4 && req.Method == http.MethodDelete:
+		ch.op = OpUnshare
+		ch.readOld, ch.oldFields = "files/"+seg[1]+"/permissions/"+seg[3], permFields
+		return ch, nil, nil
+	}
+	return nil, nil, nil
+}
+
+// readPatch reads the JSON object in req's body.
+func readPatch(req *http.Request) ([]byte, map[string]any, error) {
+	if req.Body == nil {
+		return nil, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	var patch map[string]any
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &patch); err != nil {
+			return body, nil, nil
+		}
+	}
+	if len(patch) == 0 {
+		patch = nil
+	}
+	return body, patch, nil
+}
+
+func with(m map[string]any, k string, v any) map[string]any {
+	if m == nil {
+		m = map[string]any{}
+	}
+	m[k] = v
+	return m
+}
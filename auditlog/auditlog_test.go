@@ -0,0 +1,219 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/permissions"
+)
+
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+// fakeDrive holds files and one permission, answering reads with only the
+// fields asked for, as Drive does.
+type fakeDrive struct {
+	mu     sync.Mutex
+	files  map[string]map[string]any
+	perm   map[string]any
+	abouts int
+}
+
+func (d *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	reply := func(obj map[string]any) {
+		out := obj
+		if fields := r.URL.Query().Get("fields"); fields != "" && r.Method == http.MethodGet {
+			out = map[string]any{}
+			for _, f := range strings.Split(fields, ",") {
+				if v, ok := obj[f]; ok {
+					out[f] = v
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	seg := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/upload"), "/drive/v3/"), "/")
+	switch {
+	case seg[0] == "about":
+		d.abouts++
+		json.NewEncoder(w).Encode(map[string]any{"user": map[string]any{"emailAddress": "ada@example.com"}})
+	case len(seg) == 1 && r.Method == http.MethodPost:
+		io.Copy(io.Discard, r.Body)
+		d.files["new"] = map[string]any{"id": "new", "name": "report.pdf", "parents": []any{"f1"}}
+		json.NewEncoder(w).Encode(map[string]any{"id": "new"})
+	case len(seg) == 2:
+		f := d.files[seg[1]]
+		switch r.Method {
+		case http.MethodPatch:
+			var patch map[string]any
+			json.NewDecoder(r.Body).Decode(&patch)
+			for k, v := range patch {
+				f[k] = v
+			}
+			if add := r.URL.Query().Get("addParents"); add != "" {
+				f["parents"] = []any{add}
+			}
+		case http.MethodDelete:
+			delete(d.files, seg[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		reply(f)
+	case len(seg) >= 3 && seg[2] == "permissions":
+		switch r.Method {
+		case http.MethodPost:
+			json.NewDecoder(r.Body).Decode(&d.perm)
+			d.perm["id"] = "p1"
+		case http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&d.perm)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		reply(d.perm)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	d := &fakeDrive{files: map[string]map[string]any{
+		"a": {"id": "a", "name": "draft.pdf", "parents": []any{"f1"}, "trashed": false},
+	}}
+	srv := httptest.NewServer(d)
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	var out bytes.Buffer
+	log := New(&out)
+	log.Now = func() time.Time { return time.Date(2024, 5, 2, 10, 14, 0, 0, time.FixedZone("", 3600)) }
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: log.Transport(rewriteRT{base: u, rt: http.DefaultTransport}, nil)}
+	ctx := context.Background()
+
+	if _, err := c.Rename(ctx, "a", "final.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Move(ctx, "a", "f2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.TrashFile(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetFile(ctx, "a", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Upload(ctx, drive.Metadata{Name: "report.pdf", Parents: []string{"f1"}}, strings.NewReader("pdf"), 3); err != nil {
+		t.Fatal(err)
+	}
+	p, err := permissions.ShareWithUser(ctx, c, "new", "bob@example.com", permissions.RoleReader, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := permissions.Update(ctx, c, "new", p.ID, permissions.RoleWriter); err != nil {
+		t.Fatal(err)
+	}
+	if err := permissions.Delete(ctx, c, "new", p.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Entry
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Actor != "ada@example.com" || !e.Time.Equal(log.Now()) || e.Time.Location() != time.UTC {
+			t.Errorf("%s: actor %q, time %v", e.Operation, e.Actor, e.Time)
+		}
+		e.Actor, e.Time = "", time.Time{}
+		got = append(got, e)
+	}
+	user := map[string]any{"type": "user", "role": "reader", "emailAddress": "bob@example.com"}
+	want := []Entry{
+		{Operation: OpRename, FileID: "a", Old: map[string]any{"name": "draft.pdf"}, New: map[string]any{"name": "final.pdf"}},
+		{Operation: OpMove, FileID: "a", Old: map[string]any{"parents": []any{"f1"}}, New: map[string]any{"parents": []any{"f2"}}},
+		{Operation: OpTrash, FileID: "a", Old: map[string]any{"trashed": false}, New: map[string]any{"trashed": true}},
+		{Operation: OpDelete, FileID: "a", Old: map[string]any{"name": "final.pdf", "parents": []any{"f2"}}},
+		{Operation: OpUpload, FileID: "new", New: map[string]any{"name": "report.pdf", "parents": []any{"f1"}}},
+		{Operation: OpShare, FileID: "new", PermissionID: "p1", New: user},
+		{Operation: OpUpdateShare, FileID: "new", PermissionID: "p1", Old: map[string]any{"role": "reader"}, New: map[string]any{"role": "writer"}},
+		{Operation: OpUnshare, FileID: "new", PermissionID: "p1", Old: map[string]any{"type": "user", "role": "writer", "emailAddress": "bob@example.com"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d:\n%s", len(got), len(want), out.String())
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("entry %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+	if d.abouts != 1 {
+		t.Errorf("actor looked up %d times, want once", d.abouts)
+	}
+}
+
+func TestTransportFailedRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":403,"message":"denied"}}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	var out bytes.Buffer
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: New(&out).Transport(rewriteRT{base: u, rt: http.DefaultTransport}, nil)}
+	if _, err := c.Rename(context.Background(), "a", "b"); err == nil {
+		t.Fatal("rename succeeded")
+	}
+	if out.Len() != 0 {
+		t.Errorf("failed change was recorded: %s", out.String())
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	log := OpenFile(path)
+	log.Actor = "ci@example.com"
+	for _, op := range []string{OpUpload, OpDelete} {
+		if err := log.Record(Entry{Operation: op, FileID: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"actor":"ci@example.com","operation":"delete","fileId":"x"`) {
+		t.Errorf("audit file:\n%s", b)
+	}
+}
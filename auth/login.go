@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth endpoints used by the login flows.
+const (
+	authURL       = "https://accounts.google.com/o/oauth2/v2/auth"
+	tokenURL      = "https://oauth2.googleapis.com/token"
+	deviceCodeURL = "https://oauth2.googleapis.com/device/code"
+)
+
+// Scopes to request at login. DriveScope grants full access to the user's
+// Drive; Google only allows narrower ones, such as DriveFileScope, with the
+// device flow. SheetsScope is needed besides to record deploys in a sheet,
+// and GmailSendScope to email them.
+const (
+	DriveScope     = "https://www.googleapis.com/auth/drive"
+	DriveFileScope = "https://www.googleapis.com/auth/drive.file"
+	SheetsScope    = "https://www.googleapis.com/auth/spreadsheets"
+	GmailSendScope = "https://www.googleapis.com/auth/gmail.send"
+)
+
+// Token is the result of a completed login.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+}
+
+// TokenError is an error answer of Google's OAuth endpoints, as opposed to
+// a failure to reach them. Code is empty if the body was not an OAuth
+// error.
+type TokenError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *TokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oauth: status %d", e.StatusCode)
+	}
+	if e.Description != "" {
+		return "oauth: " + e.Code + ": " + e.Description
+	}
+	return "oauth: " + e.Code
+}
+
+// postToken posts form to the token endpoint and decodes the token.
+func postToken(ctx context.Context, form url.Values) (*Token, error) {
+	return postForm[Token](ctx, tokenURL, form)
+}
+
+func postForm[T any](ctx context.Context, endpoint string, form url.Values) (*T, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		te := TokenError{StatusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&te); err != nil {
+			te.Code, te.Description = "", ""
+		}
+		return nil, &te
+	}
+	var v T
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return &v, nil
+}
+
+// BrowserLogin runs the installed-app flow: it listens on a loopback port,
+// calls open with the consent page URL, waits for Google to redirect back
+// with an authorization code, and exchanges the code for a token. The code
+// is bound to the request with PKCE, so it is useless to anyone else who
+// sees the redirect.
+func BrowserLogin(ctx context.Context, clientID, clientSecret string, scopes []string, open func(url string) error) (*Token, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	redirect := "http://" + ln.Addr().String() + "/"
+	state, verifier := randomString(), randomString()
+	sum := sha256.Sum256([]byte(verifier))
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			res.err = &TokenError{Code: q.Get("error")}
+		case q.Get("code") == "":
+			res.err = errors.New("oauth: no code in redirect")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			fmt.Fprintln(w, "Login failed. You can close this window.")
+		} else {
+			fmt.Fprintln(w, "Login complete. You can close this window.")
+		}
+		select {
+		case done <- res:
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	consent := authURL + "?" + url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {redirect},
+		"response_type":         {"code"},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+	}.Encode()
+	if err := open(consent); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return postToken(ctx, url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {res.code},
+		"code_verifier": {verifier},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {redirect},
+	})
+}
+
+// pollUnit is the unit of DeviceCode.Interval; tests shorten it.
+var pollUnit = time.Second
+
+// DeviceCode is what the user needs to approve a device login.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// RequestDeviceCode starts the device flow, for machines without a browser.
+// Show the user dc.UserCode and dc.VerificationURL, then call
+// PollDeviceToken.
+func RequestDeviceCode(ctx context.Context, clientID string, scopes []string) (*DeviceCode, error) {
+	return postForm[DeviceCode](ctx, deviceCodeURL, url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(scopes, " ")},
+	})
+}
+
+// PollDeviceToken waits until the user approves or denies dc, or it
+// expires, polling at the interval Google asks for.
+func PollDeviceToken(ctx context.Context, clientID, clientSecret string, dc *DeviceCode) (*Token, error) {
+	interval := time.Duration(dc.Interval) * pollUnit
+	if interval <= 0 {
+		interval = 5 * pollUnit
+	}
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		tok, err := postToken(ctx, url.Values{
+			"client_id":     {clientID},
+			"client_secret": {clientSecret},
+			"device_code":   {dc.DeviceCode},
+			"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+		})
+		var te *TokenError
+		if errors.As(err, &te) {
+			switch te.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * pollUnit
+				continue
+			}
+		}
+		return tok, err
+	}
+}
+
+// randomString returns 32 random bytes, base64url-encoded, for PKCE
+// verifiers and state values.
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
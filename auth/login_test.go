@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestBrowserLogin(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		json.NewEncoder(w).Encode(Token{AccessToken: "acc", RefreshToken: "ref"})
+	}))
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+
+	// The "browser" approves at once and follows the redirect.
+	browser := &http.Client{}
+	open := func(consent string) error {
+		u, err := url.Parse(consent)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("scope") != DriveScope {
+			t.Errorf("consent URL = %s", consent)
+		}
+		back := q.Get("redirect_uri") + "?" + url.Values{"code": {"abc"}, "state": {q.Get("state")}}.Encode()
+		go func() {
+			if resp, err := browser.Get(back); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tok, err := BrowserLogin(ctx, "id", "secret", []string{DriveScope}, open)
+	if err != nil {
+		t.Fatalf("BrowserLogin: %v", err)
+	}
+	if tok.RefreshToken != "ref" {
+		t.Fatalf("token = %+v", tok)
+	}
+	if form.Get("code") != "abc" || form.Get("grant_type") != "authorization_code" || form.Get("code_verifier") == "" {
+		t.Fatalf("exchange form = %v", form)
+	}
+}
+
+func TestDeviceLogin(t *testing.T) {
+	defer func(u time.Duration) { pollUnit = u }(pollUnit)
+	pollUnit = time.Millisecond
+
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/device/code":
+			w.Write([]byte(`{"device_code":"dev","user_code":"ABCD-EFGH","verification_url":"https://www.google.com/device","interval":1}`))
+		case "/token":
+			r.ParseForm()
+			if r.PostForm.Get("device_code") != "dev" {
+				t.Errorf("device_code = %q", r.PostForm.Get("device_code"))
+			}
+			if polls++; polls < 3 {
+				w.WriteHeader(http.StatusPreconditionRequired)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"acc","refresh_token":"ref"}`))
+		}
+	}))
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+
+	ctx := context.Background()
+	dc, err := RequestDeviceCode(ctx, "id", []string{DriveFileScope})
+	if err != nil || dc.UserCode != "ABCD-EFGH" {
+		t.Fatalf("RequestDeviceCode = %+v, %v", dc, err)
+	}
+	tok, err := PollDeviceToken(ctx, "id", "secret", dc)
+	if err != nil || tok.RefreshToken != "ref" || polls != 3 {
+		t.Fatalf("PollDeviceToken = %+v, %v after %d polls", tok, err, polls)
+	}
+}
+
+func TestDeviceLoginDenied(t *testing.T) {
+	defer func(u time.Duration) { pollUnit = u }(pollUnit)
+	pollUnit = time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"access_denied","error_description":"Forbidden"}`))
+	}))
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+
+	_, err := PollDeviceToken(context.Background(), "id", "secret", &DeviceCode{DeviceCode: "dev", Interval: 1})
+	if err == nil || err.Error() != "oauth: access_denied: Forbidden" {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoCredentials is returned by Store.Load when nothing has been saved.
+var ErrNoCredentials = errors.New("auth: no stored credentials")
+
+// Credentials is what a login leaves behind: enough to mint access tokens
+// later with GetGoogleAccessToken, and the account they belong to.
+type Credentials struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	Account      string `json:"account,omitempty"`
+}
+
+// AccessToken exchanges the stored refresh token for an access token.
+func (c Credentials) AccessToken() (string, error) {
+	return GetGoogleAccessToken(c.ClientID, c.ClientSecret, c.RefreshToken)
+}
+
+// Store is a credentials file readable only by its owner.
+type Store struct {
+	Path string
+}
+
+// DefaultStore returns the store in the user's configuration directory,
+// such as ~/.config/gdrivetoolbox/credentials.json on Linux.
+func DefaultStore() (Store, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return Store{}, err
+	}
+	return Store{Path: filepath.Join(dir, "gdrivetoolbox", "credentials.json")}, nil
+}
+
+// Load reads the stored credentials, or fails with ErrNoCredentials.
+func (s Store) Load() (Credentials, error) {
+	var c Credentials
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, ErrNoCredentials
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Save replaces the stored credentials. The file is written with mode 0600
+// through a temporary file, so a crash never leaves it half-written.
+func (s Store) Save(c Credentials) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(b, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
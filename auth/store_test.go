@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	s := Store{Path: filepath.Join(t.TempDir(), "gdrivetoolbox", "credentials.json")}
+	if _, err := s.Load(); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("Load before Save: err = %v", err)
+	}
+	want := Credentials{ClientID: "id", ClientSecret: "secret", RefreshToken: "ref", Account: "me@example.com"}
+	if err := s.Save(want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := s.Load()
+	if err != nil || got != want {
+		t.Fatalf("Load = %+v, %v", got, err)
+	}
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const tokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// TokenInfo is what Google knows of an access token.
+type TokenInfo struct {
+	Scope     string `json:"scope"` // space-separated
+	ExpiresIn int    `json:"expires_in,string"`
+	Email     string `json:"email"` // only with the email scope
+	Audience  string `json:"aud"`   // the client ID the token was issued to
+}
+
+// Scopes returns the scopes granted to the token.
+func (t *TokenInfo) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *TokenInfo) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes(), scope)
+}
+
+// LookupToken asks Google about accessToken. An expired or revoked token
+// fails with an error whose code is "invalid_token".
+func LookupToken(ctx context.Context, accessToken string) (*TokenInfo, error) {
+	return postForm[TokenInfo](ctx, tokenInfoURL, url.Values{"access_token": {accessToken}})
+}
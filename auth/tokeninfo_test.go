@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLookupToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tokeninfo" {
+			http.NotFound(w, r)
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("access_token") != "good" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_token","error_description":"Invalid Value"}`))
+			return
+		}
+		w.Write([]byte(`{"aud":"client","scope":"` + DriveFileScope + ` ` + SheetsScope + `","expires_in":"3599"}`))
+	}))
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+
+	info, err := LookupToken(context.Background(), "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.HasScope(SheetsScope) || info.HasScope(DriveScope) || len(info.Scopes()) != 2 || info.ExpiresIn != 3599 || info.Audience != "client" {
+		t.Errorf("info = %+v", info)
+	}
+	_, err = LookupToken(context.Background(), "expired")
+	var te *TokenError
+	if !errors.As(err, &te) || te.Code != "invalid_token" {
+		t.Errorf("err = %v, want invalid_token", err)
+	}
+}
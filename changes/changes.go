@@ -0,0 +1,266 @@
+// Package changes follows the Drive changes feed, so callers can react to
+// what changed since they last looked instead of rescanning whole trees.
+package changes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Kind classifies a Change.
+type Kind int
+
+const (
+	// FileChanged means a file was created or its content or metadata changed.
+	FileChanged Kind = iota
+	// FileTrashed means a file was moved to the trash.
+	FileTrashed
+	// FileRemoved means a file was deleted or the caller lost access to it.
+	FileRemoved
+	// DriveChanged means a shared drive was created, renamed, or updated.
+	DriveChanged
+	// DriveRemoved means a shared drive was deleted or the caller left it.
+	DriveRemoved
+)
+
+func (k Kind) String() string {
+	switch k {
+	case FileChanged:
+		return "file changed"
+	case FileTrashed:
+		return "file trashed"
+	case FileRemoved:
+		return "file removed"
+	case DriveChanged:
+		return "drive changed"
+	case DriveRemoved:
+		return "drive removed"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Change is one entry of the changes feed.
+type Change struct {
+	Kind    Kind
+	Time    time.Time
+	FileID  string
+	DriveID string
+	// File is the file's current metadata; nil when it was removed or for
+	// drive changes.
+	File *drive.File
+}
+
+// rawChange is a change resource as Drive returns it.
+type rawChange struct {
+	ChangeType string      `json:"changeType"`
+	Time       time.Time   `json:"time"`
+	Removed    bool        `json:"removed"`
+	FileID     string      `json:"fileId"`
+	DriveID    string      `json:"driveId"`
+	File       *drive.File `json:"file"`
+}
+
+func (r rawChange) change() Change {
+	ch := Change{Time: r.Time, FileID: r.FileID, DriveID: r.DriveID}
+	switch {
+	case r.ChangeType == "drive" && r.Removed:
+		ch.Kind = DriveRemoved
+	case r.ChangeType == "drive":
+		ch.Kind = DriveChanged
+	case r.Removed || r.File == nil:
+		ch.Kind = FileRemoved
+	case r.File.Trashed:
+		ch.Kind, ch.File = FileTrashed, r.File
+	default:
+		ch.Kind, ch.File = FileChanged, r.File
+	}
+	return ch
+}
+
+// StartPageToken returns the token marking the current end of the feed;
+// polling with it reports only changes made after this call. A non-empty
+// driveID selects the feed of that shared drive.
+func StartPageToken(ctx context.Context, c *drive.Client, driveID string) (string, error) {
+	q := url.Values{"supportsAllDrives": {"true"}}
+	if driveID != "" {
+		q.Set("driveId", driveID)
+	}
+	var out struct {
+		StartPageToken string `json:"startPageToken"`
+	}
+	if err := c.DoJSON(ctx, http.MethodGet, "changes/startPageToken?"+q.Encode(), nil, &out); err != nil {
+		return "", fmt.Errorf("get start page token: %w", err)
+	}
+	return out.StartPageToken, nil
+}
+
+// Options tunes Poll.
+type Options struct {
+	// DriveID selects the feed of one shared drive; empty follows the
+	// caller's own feed, including shared drive items.
+	DriveID string
+	// Fields is the file field selection; empty means drive.FileFields.
+	Fields string
+	// IncludeRemoved reports removals. Without it only FileChanged,
+	// FileTrashed, and DriveChanged are reported.
+	IncludeRemoved bool
+}
+
+// Poll returns every change after token, in order, and the token to pass to
+// the next Poll.
+func Poll(ctx context.Context, c *drive.Client, token string, opts Options) ([]Change, string, error) {
+	if token == "" {
+		return nil, "", errors.New("page token is required")
+	}
+	fields := opts.Fields
+	if fields == "" {
+		fields = drive.FileFields
+	}
+	q := url.Values{
+		"pageToken":                 {token},
+		"pageSize":                  {"1000"},
+		"supportsAllDrives":         {"true"},
+		"includeItemsFromAllDrives": {"true"},
+		"includeRemoved":            {strconv.FormatBool(opts.IncludeRemoved)},
+		"fields":                    {"nextPageToken,newStartPageToken,changes(changeType,time,removed,fileId,driveId,file(" + fields + "))"},
+	}
+	if opts.DriveID != "" {
+		q.Set("driveId", opts.DriveID)
+	}
+	var all []Change
+	for {
+		var page struct {
+			NextPageToken     string      `json:"nextPageToken"`
+			NewStartPageToken string      `json:"newStartPageToken"`
+			Changes           []rawChange `json:"changes"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, "changes?"+q.Encode(), nil, &page); err != nil {
+			return nil, "", fmt.Errorf("list changes: %w", err)
+		}
+		for _, r := range page.Changes {
+			all = append(all, r.change())
+		}
+		if page.NewStartPageToken != "" {
+			return all, page.NewStartPageToken, nil
+		}
+		if page.NextPageToken == "" {
+			return nil, "", errors.New("list changes: response has neither next nor new start page token")
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// TokenStore persists the page token between runs.
+type TokenStore interface {
+	// Load returns the saved token, or "" if none has been saved.
+	Load() (string, error)
+	Save(token string) error
+}
+
+// FileStore keeps the token in a file.
+type FileStore string
+
+// Load implements TokenStore.
+func (f FileStore) Load() (string, error) {
+	b, err := os.ReadFile(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Save implements TokenStore. The file is replaced atomically so a crash
+// never leaves a truncated token behind.
+func (f FileStore) Save(token string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(string(f)), ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(token + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), string(f))
+}
+
+// DefaultInterval is how often a Tracker polls when Interval is 0.
+const DefaultInterval = time.Minute
+
+// Tracker polls the changes feed and hands each change to a callback,
+// saving the page token after every batch that is handled without error.
+type Tracker struct {
+	Client  *drive.Client
+	Store   TokenStore
+	Options Options
+	// Interval is the pause between polls in Run.
+	Interval time.Duration
+}
+
+// Sync handles every change since the saved token. On the first run there
+// is no token, so Sync saves the current start token and reports nothing:
+// callers are expected to do a full scan once, then follow changes. If fn
+// fails the token is not saved and the same batch is delivered again next
+// time, so fn should be idempotent.
+func (t *Tracker) Sync(ctx context.Context, fn func(Change) error) error {
+	token, err := t.Store.Load()
+	if err != nil {
+		return fmt.Errorf("load page token: %w", err)
+	}
+	if token == "" {
+		token, err = StartPageToken(ctx, t.Client, t.Options.DriveID)
+		if err != nil {
+			return err
+		}
+		return t.Store.Save(token)
+	}
+	changes, next, err := Poll(ctx, t.Client, token, t.Options)
+	if err != nil {
+		return err
+	}
+	for _, ch := range changes {
+		if err := fn(ch); err != nil {
+			return err
+		}
+	}
+	if next == token {
+		return nil
+	}
+	return t.Store.Save(next)
+}
+
+// Run calls Sync every Interval until ctx is done or Sync fails.
+func (t *Tracker) Run(ctx context.Context, fn func(Change) error) error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := t.Sync(ctx, fn); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func feedServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/changes/startPageToken":
+			w.Write([]byte(`{"startPageToken":"10"}`))
+		case "/drive/v3/changes":
+			switch r.URL.Query().Get("pageToken") {
+			case "10":
+				w.Write([]byte(`{"nextPageToken":"11","changes":[
+					{"changeType":"file","fileId":"a","file":{"id":"a","name":"A"}},
+					{"changeType":"file","fileId":"b","file":{"id":"b","trashed":true}}]}`))
+			case "11":
+				w.Write([]byte(`{"newStartPageToken":"12","changes":[
+					{"changeType":"file","fileId":"c","removed":true},
+					{"changeType":"drive","driveId":"d"}]}`))
+			case "12":
+				w.Write([]byte(`{"newStartPageToken":"12"}`))
+			default:
+				http.Error(w, "bad token", http.StatusBadRequest)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestPoll(t *testing.T) {
+	srv := feedServer(t)
+	defer srv.Close()
+	got, next, err := Poll(context.Background(), testClient(srv), "10", Options{IncludeRemoved: true})
+	if err != nil || next != "12" {
+		t.Fatalf("Poll = %d changes, %q, %v", len(got), next, err)
+	}
+	want := []Kind{FileChanged, FileTrashed, FileRemoved, DriveChanged}
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(got), len(want))
+	}
+	for i, k := range want {
+		if got[i].Kind != k {
+			t.Errorf("change %d kind = %v, want %v", i, got[i].Kind, k)
+		}
+	}
+	if got[0].File == nil || got[0].File.Name != "A" || got[2].File != nil {
+		t.Fatalf("unexpected files: %+v", got)
+	}
+}
+
+func TestTrackerSync(t *testing.T) {
+	srv := feedServer(t)
+	defer srv.Close()
+	store := FileStore(filepath.Join(t.TempDir(), "token"))
+	tr := &Tracker{Client: testClient(srv), Store: store}
+	ctx := context.Background()
+	var seen []string
+	handle := func(ch Change) error {
+		seen = append(seen, ch.FileID+ch.DriveID)
+		return nil
+	}
+
+	if err := tr.Sync(ctx, handle); err != nil || len(seen) != 0 {
+		t.Fatalf("first Sync = %v, %v", seen, err)
+	}
+	if tok, _ := store.Load(); tok != "10" {
+		t.Fatalf("token after first Sync = %q", tok)
+	}
+
+	boom := errors.New("boom")
+	if err := tr.Sync(ctx, func(Change) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("failing Sync = %v", err)
+	}
+	if tok, _ := store.Load(); tok != "10" {
+		t.Fatalf("token advanced past failed batch: %q", tok)
+	}
+
+	if err := tr.Sync(ctx, handle); err != nil || len(seen) != 4 {
+		t.Fatalf("Sync = %v, %v", seen, err)
+	}
+	if tok, _ := store.Load(); tok != "12" {
+		t.Fatalf("token = %q, want 12", tok)
+	}
+}
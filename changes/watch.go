@@ -0,0 +1,204 @@
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Channel is a push notification channel registered with Drive.
+type Channel struct {
+	ID         string
+	ResourceID string
+	// Token is sent back with every notification; Handler rejects
+	// notifications that do not carry it.
+	Token      string
+	Expiration time.Time
+}
+
+// WatchOptions tunes channel registration.
+type WatchOptions struct {
+	// Token is echoed in notifications; empty generates a random one.
+	Token string
+	// TTL asks Drive to expire the channel after this long. Drive caps it
+	// (a week for changes, a day for files) and picks its own default if 0.
+	TTL time.Duration
+	// DriveID selects the feed of one shared drive for WatchChanges.
+	DriveID string
+}
+
+type channelRequest struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Address    string `json:"address"`
+	Token      string `json:"token,omitempty"`
+	Expiration int64  `json:"expiration,string,omitempty"`
+}
+
+type channelResponse struct {
+	ID         string `json:"id"`
+	ResourceID string `json:"resourceId"`
+	Token      string `json:"token"`
+	Expiration int64  `json:"expiration,string"`
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func watch(ctx context.Context, c *drive.Client, path, address string, opts WatchOptions) (Channel, error) {
+	if address == "" {
+		return Channel{}, errors.New("webhook address is required")
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return Channel{}, err
+	}
+	token := opts.Token
+	if token == "" {
+		if token, err = randomHex(16); err != nil {
+			return Channel{}, err
+		}
+	}
+	req := channelRequest{ID: id, Type: "web_hook", Address: address, Token: token}
+	if opts.TTL > 0 {
+		req.Expiration = time.Now().Add(opts.TTL).UnixMilli()
+	}
+	var resp channelResponse
+	if err := c.DoJSON(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return Channel{}, err
+	}
+	ch := Channel{ID: resp.ID, ResourceID: resp.ResourceID, Token: token}
+	if resp.Expiration > 0 {
+		ch.Expiration = time.UnixMilli(resp.Expiration)
+	}
+	return ch, nil
+}
+
+// WatchChanges registers address to be notified of changes after pageToken.
+// Notifications only say that something changed; call Poll to find out
+// what.
+func WatchChanges(ctx context.Context, c *drive.Client, pageToken, address string, opts WatchOptions) (Channel, error) {
+	if pageToken == "" {
+		return Channel{}, errors.New("page token is required")
+	}
+	q := url.Values{
+		"pageToken":                 {pageToken},
+		"supportsAllDrives":         {"true"},
+		"includeItemsFromAllDrives": {"true"},
+	}
+	if opts.DriveID != "" {
+		q.Set("driveId", opts.DriveID)
+	}
+	return watch(ctx, c, "changes/watch?"+q.Encode(), address, opts)
+}
+
+// WatchFile registers address to be notified when fileID changes.
+func WatchFile(ctx context.Context, c *drive.Client, fileID, address string, opts WatchOptions) (Channel, error) {
+	fileID, err := drive.ParseID(fileID)
+	if err != nil {
+		return Channel{}, err
+	}
+	return watch(ctx, c, "files/"+url.PathEscape(fileID)+"/watch?supportsAllDrives=true", address, opts)
+}
+
+// StopChannel stops notifications on ch.
+func StopChannel(ctx context.Context, c *drive.Client, ch Channel) error {
+	body := map[string]string{"id": ch.ID, "resourceId": ch.ResourceID}
+	return c.DoJSON(ctx, http.MethodPost, "channels/stop", body, nil)
+}
+
+// Notification is one push message from Drive.
+type Notification struct {
+	ChannelID  string
+	ResourceID string
+	// State is "sync" for the handshake sent when a channel is created,
+	// "change" for the changes feed, and "update", "add", "remove",
+	// "trash", or "untrash" for watched files.
+	State string
+	// Changed lists what changed for file updates, e.g. "content" or
+	// "properties".
+	Changed       string
+	MessageNumber int64
+	Expiration    time.Time
+}
+
+// Handler receives Drive push notifications over HTTP. It accepts only
+// notifications for registered channels carrying the channel's token, and
+// hands the rest to OnNotify. The "sync" handshake is acknowledged without
+// calling OnNotify.
+type Handler struct {
+	// OnNotify is called for every accepted notification. Returning an
+	// error answers 500, which makes Drive retry later.
+	OnNotify func(Notification) error
+
+	mu       sync.RWMutex
+	channels map[string]string
+}
+
+// NewHandler returns a Handler that calls fn.
+func NewHandler(fn func(Notification) error) *Handler {
+	return &Handler{OnNotify: fn}
+}
+
+// Register accepts notifications for ch.
+func (h *Handler) Register(ch Channel) {
+	h.mu.Lock()
+	if h.channels == nil {
+		h.channels = map[string]string{}
+	}
+	h.channels[ch.ID] = ch.Token
+	h.mu.Unlock()
+}
+
+// Unregister stops accepting notifications for the channel with this ID.
+func (h *Handler) Unregister(id string) {
+	h.mu.Lock()
+	delete(h.channels, id)
+	h.mu.Unlock()
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.Header.Get("X-Goog-Channel-ID")
+	h.mu.RLock()
+	token, ok := h.channels[id]
+	h.mu.RUnlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(r.Header.Get("X-Goog-Channel-Token"))) != 1 {
+		http.Error(w, "unknown channel", http.StatusForbidden)
+		return
+	}
+	n := Notification{
+		ChannelID:  id,
+		ResourceID: r.Header.Get("X-Goog-Resource-ID"),
+		State:      r.Header.Get("X-Goog-Resource-State"),
+		Changed:    r.Header.Get("X-Goog-Changed"),
+	}
+	n.MessageNumber, _ = strconv.ParseInt(r.Header.Get("X-Goog-Message-Number"), 10, 64)
+	if exp, err := http.ParseTime(r.Header.Get("X-Goog-Channel-Expiration")); err == nil {
+		n.Expiration = exp
+	}
+	if n.State != "sync" && h.OnNotify != nil {
+		if err := h.OnNotify(n); err != nil {
+			http.Error(w, "notification not handled", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,94 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatchAndStop(t *testing.T) {
+	var got channelRequest
+	var stopped map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/changes/watch":
+			if r.URL.Query().Get("pageToken") != "10" {
+				http.Error(w, "bad token", http.StatusBadRequest)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Write([]byte(`{"id":"` + got.ID + `","resourceId":"res","expiration":"1700000000000"}`))
+		case "/drive/v3/channels/stop":
+			json.NewDecoder(r.Body).Decode(&stopped)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+
+	ch, err := WatchChanges(ctx, c, "10", "https://hooks.example.com/drive", WatchOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("WatchChanges: %v", err)
+	}
+	if got.Type != "web_hook" || got.Address != "https://hooks.example.com/drive" || got.Token == "" || got.Expiration == 0 {
+		t.Fatalf("request = %+v", got)
+	}
+	if ch.ID != got.ID || ch.ResourceID != "res" || ch.Token != got.Token || ch.Expiration.UnixMilli() != 1700000000000 {
+		t.Fatalf("channel = %+v", ch)
+	}
+	if err := StopChannel(ctx, c, ch); err != nil || stopped["id"] != ch.ID || stopped["resourceId"] != "res" {
+		t.Fatalf("StopChannel = %v, %v", err, stopped)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var notes []Notification
+	fail := false
+	h := NewHandler(func(n Notification) error {
+		if fail {
+			return errors.New("busy")
+		}
+		notes = append(notes, n)
+		return nil
+	})
+	h.Register(Channel{ID: "ch1", Token: "secret"})
+
+	send := func(id, token, state string) int {
+		r := httptest.NewRequest(http.MethodPost, "/drive", nil)
+		r.Header.Set("X-Goog-Channel-ID", id)
+		r.Header.Set("X-Goog-Channel-Token", token)
+		r.Header.Set("X-Goog-Resource-State", state)
+		r.Header.Set("X-Goog-Message-Number", "7")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("ch1", "secret", "sync"); code != http.StatusOK || len(notes) != 0 {
+		t.Fatalf("sync: %d, %v", code, notes)
+	}
+	if code := send("ch1", "secret", "change"); code != http.StatusOK || len(notes) != 1 || notes[0].MessageNumber != 7 || notes[0].State != "change" {
+		t.Fatalf("change: %d, %+v", code, notes)
+	}
+	if code := send("ch1", "wrong", "change"); code != http.StatusForbidden {
+		t.Fatalf("bad token: %d", code)
+	}
+	if code := send("other", "secret", "change"); code != http.StatusForbidden {
+		t.Fatalf("unknown channel: %d", code)
+	}
+	fail = true
+	if code := send("ch1", "secret", "change"); code != http.StatusInternalServerError {
+		t.Fatalf("failing callback: %d", code)
+	}
+	h.Unregister("ch1")
+	if code := send("ch1", "secret", "change"); code != http.StatusForbidden {
+		t.Fatalf("unregistered: %d", code)
+	}
+}
@@ -0,0 +1,124 @@
+// Package cleanup finds wasted space and clutter in Drive folder trees:
+// duplicate files, large files and folders, orphans, and empty folders.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// Entry is a file found in a scanned tree.
+type Entry struct {
+	// Path is relative to the scanned folder.
+	Path string
+	File drive.File
+}
+
+// DuplicateOptions tunes FindDuplicates.
+type DuplicateOptions struct {
+	// Prefer, if set, is a path prefix relative to the scanned folder; a
+	// copy below it is kept in preference to newer copies elsewhere.
+	Prefer string
+	// Trash moves every copy but the kept one to the trash.
+	Trash bool
+}
+
+// DuplicateGroup is a set of files with identical content.
+type DuplicateGroup struct {
+	MD5  string
+	Size int64
+	// Keep is the copy that stays; Duplicates are the others, newest first.
+	Keep       Entry
+	Duplicates []Entry
+	// Errs holds trash failures by file ID.
+	Errs map[string]error
+}
+
+// Wasted is the space the duplicates take up.
+func (g DuplicateGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Duplicates))
+}
+
+// FindDuplicates walks the tree below folderID and groups files by content
+// hash and size. In each group it keeps the copy under opts.Prefer if
+// there is one, and otherwise the most recently modified. Google-native
+// files have no checksum and are never reported. Groups are returned
+// largest waste first. If opts.Trash is set, a failure to trash one file is
+// recorded in its group and FindDuplicates returns an error at the end.
+func FindDuplicates(ctx context.Context, c *drive.Client, folderID string, opts DuplicateOptions) ([]DuplicateGroup, error) {
+	type key struct {
+		md5  string
+		size int64
+	}
+	byContent := map[key][]Entry{}
+	err := list.Walk(ctx, c, folderID, func(p string, f drive.File) error {
+		if f.IsShortcut() {
+			return list.SkipDir
+		}
+		if f.MD5 != "" {
+			k := key{f.MD5, f.Size}
+			byContent[k] = append(byContent[k], Entry{Path: p, File: f})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []DuplicateGroup
+	for k, entries := range byContent {
+		if len(entries) < 2 {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			pi, pj := preferred(entries[i], opts.Prefer), preferred(entries[j], opts.Prefer)
+			if pi != pj {
+				return pi
+			}
+			if !entries[i].File.ModifiedTime.Equal(entries[j].File.ModifiedTime) {
+				return entries[i].File.ModifiedTime.After(entries[j].File.ModifiedTime)
+			}
+			return entries[i].Path < entries[j].Path
+		})
+		groups = append(groups, DuplicateGroup{MD5: k.md5, Size: k.size, Keep: entries[0], Duplicates: entries[1:]})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Wasted() != groups[j].Wasted() {
+			return groups[i].Wasted() > groups[j].Wasted()
+		}
+		return groups[i].Keep.Path < groups[j].Keep.Path
+	})
+
+	if !opts.Trash {
+		return groups, nil
+	}
+	failed := 0
+	for i := range groups {
+		for _, d := range groups[i].Duplicates {
+			if _, err := c.TrashFile(ctx, d.File.ID); err != nil {
+				if groups[i].Errs == nil {
+					groups[i].Errs = map[string]error{}
+				}
+				groups[i].Errs[d.File.ID] = err
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return groups, fmt.Errorf("%d duplicates could not be trashed", failed)
+	}
+	return groups, nil
+}
+
+func preferred(e Entry, prefix string) bool {
+	if prefix == "" {
+		return false
+	}
+	prefix = strings.Trim(prefix, "/")
+	return e.Path == prefix || strings.HasPrefix(e.Path, prefix+"/")
+}
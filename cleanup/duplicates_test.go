@@ -0,0 +1,107 @@
+package cleanup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+// treeServer serves folder listings from tree, keyed by folder ID, and
+// records the IDs of trashed files.
+func treeServer(tree map[string]string, trashed *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+			*trashed = append(*trashed, id)
+			w.Write([]byte(`{"id":"` + id + `","trashed":true}`))
+			return
+		}
+		q := r.URL.Query().Get("q")
+		for id, files := range tree {
+			if strings.HasPrefix(q, "'"+id+"' in parents") {
+				w.Write([]byte(`{"files":` + files + `}`))
+				return
+			}
+		}
+		w.Write([]byte(`{"files":[]}`))
+	}))
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tree := map[string]string{
+		"root": `[
+			{"id":"a1","name":"sop.pdf","md5Checksum":"aaa","size":"100","modifiedTime":"2024-03-01T00:00:00Z"},
+			{"id":"pub","name":"Published","mimeType":"application/vnd.google-apps.folder"},
+			{"id":"b1","name":"big.bin","md5Checksum":"bbb","size":"1000","modifiedTime":"2024-01-01T00:00:00Z"},
+			{"id":"doc","name":"Notes","mimeType":"application/vnd.google-apps.document"}]`,
+		"pub": `[
+			{"id":"a2","name":"sop.pdf","md5Checksum":"aaa","size":"100","modifiedTime":"2024-01-01T00:00:00Z"},
+			{"id":"b2","name":"big copy.bin","md5Checksum":"bbb","size":"1000","modifiedTime":"2024-02-01T00:00:00Z"},
+			{"id":"u","name":"unique.txt","md5Checksum":"ccc","size":"5"}]`,
+	}
+	var trashed []string
+	srv := treeServer(tree, &trashed)
+	defer srv.Close()
+	ctx := context.Background()
+
+	groups, err := FindDuplicates(ctx, testClient(srv), "root", DuplicateOptions{})
+	if err != nil || len(groups) != 2 {
+		t.Fatalf("FindDuplicates = %+v, %v", groups, err)
+	}
+	if g := groups[0]; g.MD5 != "bbb" || g.Keep.File.ID != "b2" || g.Wasted() != 1000 {
+		t.Fatalf("largest group = %+v", g)
+	}
+	if g := groups[1]; g.Keep.File.ID != "a1" || g.Duplicates[0].Path != "Published/sop.pdf" {
+		t.Fatalf("newest copy not kept: %+v", g)
+	}
+
+	groups, err = FindDuplicates(ctx, testClient(srv), "root", DuplicateOptions{Prefer: "/Published/", Trash: true})
+	if err != nil || groups[1].Keep.File.ID != "a2" {
+		t.Fatalf("preferred copy not kept: %+v, %v", groups, err)
+	}
+	if strings.Join(trashed, ",") != "b1,a1" {
+		t.Fatalf("trashed = %v", trashed)
+	}
+}
+
+func TestFindDuplicates_FileShortcut(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	root := srv.AddFolder("Root", "")
+	srv.Add(drive.File{Name: "a-link", MimeType: drive.ShortcutMimeType, Parents: []string{root},
+		ShortcutDetails: &drive.ShortcutDetails{TargetID: "x", TargetMimeType: "application/pdf"}}, nil)
+	srv.Add(drive.File{Name: "sop.pdf", Parents: []string{root}}, []byte("%PDF"))
+	srv.Add(drive.File{Name: "sop copy.pdf", Parents: []string{root}}, []byte("%PDF"))
+
+	// A file shortcut must not hide the files after it.
+	groups, err := FindDuplicates(context.Background(), srv.Client(), root, DuplicateOptions{})
+	if err != nil || len(groups) != 1 || len(groups[0].Duplicates) != 1 {
+		t.Fatalf("FindDuplicates = %+v, %v; want one pair", groups, err)
+	}
+}
@@ -0,0 +1,121 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// EmptyFolderOptions tunes EmptyFolders.
+type EmptyFolderOptions struct {
+	// Trash moves every empty folder found to the trash.
+	Trash bool
+	// DryRun reports what Trash would remove without removing anything.
+	DryRun bool
+}
+
+// EmptyFolder is a folder with nothing but other empty folders below it.
+type EmptyFolder struct {
+	// Path is relative to the scanned folder.
+	Path string
+	ID   string
+	// Err is set if trashing the folder failed.
+	Err error
+}
+
+// EmptyFolderReport is the result of EmptyFolders.
+type EmptyFolderReport struct {
+	Folders []EmptyFolder
+	Trash   bool
+	DryRun  bool
+}
+
+// WriteTo writes one line per empty folder, saying what was done with it.
+func (r *EmptyFolderReport) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, f := range r.Folders {
+		var m int
+		var err error
+		switch {
+		case !r.Trash:
+			m, err = fmt.Fprintf(w, "empty %s\n", f.Path)
+		case r.DryRun:
+			m, err = fmt.Fprintf(w, "would trash %s\n", f.Path)
+		case f.Err != nil:
+			m, err = fmt.Fprintf(w, "failed %s: %v\n", f.Path, f.Err)
+		default:
+			m, err = fmt.Fprintf(w, "trashed %s\n", f.Path)
+		}
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// EmptyFolders finds the folders below folderID that have no untrashed
+// files anywhere beneath them. Only the topmost folder of an empty subtree
+// is reported, since trashing it takes the rest along; folderID itself is
+// never reported. Shortcuts count as content.
+func EmptyFolders(ctx context.Context, c *drive.Client, folderID string, opts EmptyFolderOptions) (*EmptyFolderReport, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]string{}
+	parentOf := map[string]string{}
+	full := map[string]bool{}
+	err = list.Walk(ctx, c, folderID, func(p string, f drive.File) error {
+		parent := ""
+		if len(f.Parents) > 0 {
+			parent = f.Parents[0]
+		}
+		if f.IsFolder() {
+			paths[f.ID] = p
+			parentOf[f.ID] = parent
+			return nil
+		}
+		for id := parent; id != "" && !full[id]; id = parentOf[id] {
+			full[id] = true
+		}
+		if f.IsShortcut() {
+			return list.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EmptyFolderReport{Trash: opts.Trash, DryRun: opts.DryRun}
+	for id, p := range paths {
+		if full[id] {
+			continue
+		}
+		if parent := parentOf[id]; parent != folderID && !full[parent] {
+			continue
+		}
+		report.Folders = append(report.Folders, EmptyFolder{Path: p, ID: id})
+	}
+	sort.Slice(report.Folders, func(i, j int) bool { return report.Folders[i].Path < report.Folders[j].Path })
+	if !opts.Trash || opts.DryRun {
+		return report, nil
+	}
+	failed := 0
+	for i := range report.Folders {
+		f := &report.Folders[i]
+		if _, err := c.TrashFile(ctx, f.ID); err != nil {
+			f.Err = err
+			failed++
+		}
+	}
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d empty folders could not be trashed", failed, len(report.Folders))
+	}
+	return report, nil
+}
@@ -0,0 +1,67 @@
+package cleanup
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func TestEmptyFolders(t *testing.T) {
+	folder := `"mimeType":"application/vnd.google-apps.folder"`
+	tree := map[string]string{
+		"root": `[
+			{"id":"a","name":"a.txt","parents":["root"]},
+			{"id":"keep","name":"Keep",` + folder + `,"parents":["root"]},
+			{"id":"old","name":"Old",` + folder + `,"parents":["root"]},
+			{"id":"links","name":"Links",` + folder + `,"parents":["root"]}]`,
+		"keep": `[
+			{"id":"gone","name":"Gone",` + folder + `,"parents":["keep"]},
+			{"id":"deep","name":"Deep",` + folder + `,"parents":["keep"]}]`,
+		"deep":  `[{"id":"b","name":"b.txt","parents":["deep"]}]`,
+		"old":   `[{"id":"older","name":"Older",` + folder + `,"parents":["old"]}]`,
+		"links": `[{"id":"s","name":"s","mimeType":"application/vnd.google-apps.shortcut","parents":["links"]}]`,
+	}
+	var trashed []string
+	srv := treeServer(tree, &trashed)
+	defer srv.Close()
+	ctx := context.Background()
+
+	r, err := EmptyFolders(ctx, testClient(srv), "root", EmptyFolderOptions{Trash: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("EmptyFolders: %v", err)
+	}
+	var out strings.Builder
+	r.WriteTo(&out)
+	if want := "would trash Keep/Gone\nwould trash Old\n"; out.String() != want {
+		t.Fatalf("dry run:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if len(trashed) != 0 {
+		t.Fatalf("dry run trashed %v", trashed)
+	}
+
+	if _, err := EmptyFolders(ctx, testClient(srv), "root", EmptyFolderOptions{Trash: true}); err != nil {
+		t.Fatalf("EmptyFolders: %v", err)
+	}
+	if strings.Join(trashed, ",") != "gone,old" {
+		t.Fatalf("trashed = %v", trashed)
+	}
+}
+
+func TestEmptyFolders_FileShortcut(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	root := srv.AddFolder("Root", "")
+	links := srv.AddFolder("Links", root)
+	srv.Add(drive.File{Name: "a-link", MimeType: drive.ShortcutMimeType, Parents: []string{links},
+		ShortcutDetails: &drive.ShortcutDetails{TargetID: "x", TargetMimeType: "application/pdf"}}, nil)
+	srv.AddFolder("Gone", links)
+
+	// A file shortcut must not hide the folders after it.
+	r, err := EmptyFolders(context.Background(), srv.Client(), root, EmptyFolderOptions{})
+	if err != nil || len(r.Folders) != 1 || r.Folders[0].Path != "Links/Gone" {
+		t.Fatalf("EmptyFolders = %+v, %v; want Links/Gone", r, err)
+	}
+}
@@ -0,0 +1,150 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// OrphanOptions tunes FindOrphans.
+type OrphanOptions struct {
+	// Roots are the folders (IDs or URLs) files are expected to live under;
+	// "root" is My Drive itself. If empty, only files with no parent at
+	// all are reported.
+	Roots []string
+}
+
+// FindOrphans lists untrashed files and folders owned by the caller that
+// have no parent, or that are not below any of opts.Roots. Only the topmost
+// such item is reported: the contents of an orphaned folder go with it.
+func FindOrphans(ctx context.Context, c *drive.Client, opts OrphanOptions) ([]drive.File, error) {
+	roots := map[string]bool{}
+	for _, r := range opts.Roots {
+		id, err := drive.ParseID(r)
+		if err != nil {
+			return nil, err
+		}
+		if id == "root" {
+			// Parents report the real ID of My Drive, not the alias.
+			f, err := c.GetFile(ctx, "root", "id")
+			if err != nil {
+				return nil, fmt.Errorf("resolve My Drive root: %w", err)
+			}
+			id = f.ID
+		}
+		roots[id] = true
+	}
+	owned, err := list.ListFiles(ctx, c, list.Options{
+		Query:  query.New().Owner("me").NotTrashed().String(),
+		Fields: "id,name,mimeType,size,modifiedTime,parents",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]drive.File, len(owned))
+	for _, f := range owned {
+		known[f.ID] = f
+	}
+	a := &ancestry{ctx: ctx, c: c, roots: roots, known: known, below: map[string]bool{}}
+	outside := map[string]bool{}
+	for _, f := range owned {
+		if len(f.Parents) == 0 {
+			outside[f.ID] = true
+			continue
+		}
+		if len(roots) == 0 {
+			continue
+		}
+		in, err := a.underRoot(f.ID, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		if !in && !roots[f.ID] {
+			outside[f.ID] = true
+		}
+	}
+	var orphans []drive.File
+	for _, f := range owned {
+		if !outside[f.ID] {
+			continue
+		}
+		top := true
+		for _, p := range f.Parents {
+			if outside[p] {
+				top = false
+			}
+		}
+		if top {
+			orphans = append(orphans, f)
+		}
+	}
+	return orphans, nil
+}
+
+// ancestry answers whether items sit below one of a set of roots, looking
+// up parents not already known and caching every answer.
+type ancestry struct {
+	ctx   context.Context
+	c     *drive.Client
+	roots map[string]bool
+	known map[string]drive.File
+	below map[string]bool
+}
+
+func (a *ancestry) underRoot(id string, visiting map[string]bool) (bool, error) {
+	if in, ok := a.below[id]; ok {
+		return in, nil
+	}
+	if visiting[id] {
+		return false, nil
+	}
+	visiting[id] = true
+	f, ok := a.known[id]
+	if !ok {
+		var err error
+		if f, err = a.c.GetFile(a.ctx, id, "id", "parents"); err != nil {
+			return false, fmt.Errorf("read parents of %s: %w", id, err)
+		}
+		a.known[id] = f
+	}
+	in := false
+	for _, p := range f.Parents {
+		if a.roots[p] {
+			in = true
+			break
+		}
+		up, err := a.underRoot(p, visiting)
+		if err != nil {
+			return false, err
+		}
+		if up {
+			in = true
+			break
+		}
+	}
+	a.below[id] = in
+	return in, nil
+}
+
+// MoveToReview moves files into reviewFolderID for someone to sort out.
+// It moves as many as it can and returns an error counting the failures.
+func MoveToReview(ctx context.Context, c *drive.Client, files []drive.File, reviewFolderID string) error {
+	failed := 0
+	var first error
+	for _, f := range files {
+		if _, err := c.Move(ctx, f.ID, reviewFolderID); err != nil {
+			failed++
+			if first == nil {
+				first = fmt.Errorf("move %s: %w", f.Name, err)
+			}
+		}
+	}
+	if failed > 0 {
+		return &drive.BatchError{Failed: failed, Total: len(files), Summary: "files could not be moved", First: first}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cleanup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	files := map[string]string{
+		"root":   `{"id":"myroot"}`,
+		"shared": `{"id":"shared","parents":["elsewhere"]}`,
+	}
+	var moved []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+		switch {
+		case r.Method == http.MethodPatch:
+			moved = append(moved, id+"->"+r.URL.Query().Get("addParents"))
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case id == "/drive/v3/files":
+			if q := r.URL.Query().Get("q"); !strings.Contains(q, "'me' in owners") {
+				t.Errorf("query = %q", q)
+			}
+			w.Write([]byte(`{"files":[
+				{"id":"a","name":"a.txt","parents":["myroot"]},
+				{"id":"lost","name":"lost.txt"},
+				{"id":"box","name":"Box","mimeType":"application/vnd.google-apps.folder"},
+				{"id":"inbox","name":"in box.txt","parents":["box"]},
+				{"id":"stray","name":"stray.pdf","parents":["shared"]},
+				{"id":"sop","name":"SOPs","mimeType":"application/vnd.google-apps.folder","parents":["shared"]},
+				{"id":"s1","name":"s1.pdf","parents":["sop"]}]}`))
+		case files[id] != "":
+			w.Write([]byte(files[id]))
+		default:
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := testClient(srv)
+
+	got, err := FindOrphans(ctx, c, OrphanOptions{})
+	if err != nil || len(got) != 2 || got[0].ID != "lost" || got[1].ID != "box" {
+		t.Fatalf("parentless = %+v, %v", got, err)
+	}
+
+	got, err = FindOrphans(ctx, c, OrphanOptions{Roots: []string{"root", "sop"}})
+	if err != nil {
+		t.Fatalf("FindOrphans: %v", err)
+	}
+	var ids []string
+	for _, f := range got {
+		ids = append(ids, f.ID)
+	}
+	if strings.Join(ids, ",") != "lost,box,stray" {
+		t.Fatalf("outside roots = %v", ids)
+	}
+
+	if err := MoveToReview(ctx, c, got, "review"); err != nil {
+		t.Fatalf("MoveToReview: %v", err)
+	}
+	if len(moved) != 3 || moved[0] != "lost->review" {
+		t.Fatalf("moved = %v", moved)
+	}
+}
@@ -0,0 +1,127 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// FolderUsage is the space used by one folder and everything below it.
+type FolderUsage struct {
+	// Path is relative to the scanned folder; "" is the folder itself.
+	Path  string
+	ID    string
+	Size  int64
+	Files int
+}
+
+// UsageReport is the result of Usage.
+type UsageReport struct {
+	// Folders lists every folder of the tree, largest first.
+	Folders []FolderUsage
+	// Largest holds the biggest files, largest first.
+	Largest []Entry
+}
+
+// Total is the size of the whole tree.
+func (r *UsageReport) Total() int64 {
+	for _, f := range r.Folders {
+		if f.Path == "" {
+			return f.Size
+		}
+	}
+	return 0
+}
+
+// WriteTo writes a du-style listing, one folder per line.
+func (r *UsageReport) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, f := range r.Folders {
+		p := f.Path
+		if p == "" {
+			p = "."
+		}
+		m, err := fmt.Fprintf(w, "%12d  %s\n", f.Size, p)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Usage walks the tree below folderID and totals file sizes per folder,
+// like du, and collects the top largest files. Google-native files report
+// no size and count as 0; shortcuts are not followed.
+func Usage(ctx context.Context, c *drive.Client, folderID string, top int) (*UsageReport, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	// Folders are tracked by ID rather than path, since names may contain
+	// slashes.
+	folders := map[string]*FolderUsage{folderID: {ID: folderID}}
+	parentOf := map[string]string{}
+	report := &UsageReport{}
+	err = list.Walk(ctx, c, folderID, func(p string, f drive.File) error {
+		// Shortcuts take no space.
+		if f.IsShortcut() {
+			return list.SkipDir
+		}
+		parent := ""
+		if len(f.Parents) > 0 {
+			parent = f.Parents[0]
+		}
+		if f.IsFolder() {
+			folders[f.ID] = &FolderUsage{Path: p, ID: f.ID}
+			parentOf[f.ID] = parent
+			return nil
+		}
+		for id := parent; id != ""; id = parentOf[id] {
+			if u := folders[id]; u != nil {
+				u.Size += f.Size
+				u.Files++
+			}
+			if id == folderID {
+				break
+			}
+		}
+		if top > 0 {
+			report.Largest = insertLargest(report.Largest, Entry{Path: p, File: f}, top)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range folders {
+		report.Folders = append(report.Folders, *u)
+	}
+	sort.Slice(report.Folders, func(i, j int) bool {
+		a, b := report.Folders[i], report.Folders[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Path < b.Path
+	})
+	return report, nil
+}
+
+// insertLargest adds e to the size-ordered list, keeping at most n entries.
+func insertLargest(largest []Entry, e Entry, n int) []Entry {
+	i := sort.Search(len(largest), func(i int) bool { return largest[i].File.Size < e.File.Size })
+	if i >= n {
+		return largest
+	}
+	largest = append(largest, Entry{})
+	copy(largest[i+1:], largest[i:])
+	largest[i] = e
+	if len(largest) > n {
+		largest = largest[:n]
+	}
+	return largest
+}
@@ -0,0 +1,62 @@
+package cleanup
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func TestUsage(t *testing.T) {
+	tree := map[string]string{
+		"root": `[
+			{"id":"a","name":"a.bin","size":"100","parents":["root"]},
+			{"id":"sub","name":"Sub","mimeType":"application/vnd.google-apps.folder","parents":["root"]},
+			{"id":"empty","name":"Empty","mimeType":"application/vnd.google-apps.folder"}]`,
+		"sub": `[
+			{"id":"b","name":"b.bin","size":"300","parents":["sub"]},
+			{"id":"deep","name":"Deep","mimeType":"application/vnd.google-apps.folder","parents":["sub"]}]`,
+		"deep": `[
+			{"id":"c","name":"c.bin","size":"50","parents":["deep"]},
+			{"id":"d","name":"Doc","mimeType":"application/vnd.google-apps.document","parents":["deep"]}]`,
+	}
+	srv := treeServer(tree, nil)
+	defer srv.Close()
+
+	r, err := Usage(context.Background(), testClient(srv), "root", 2)
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if r.Total() != 450 {
+		t.Fatalf("Total = %d", r.Total())
+	}
+	var out strings.Builder
+	r.WriteTo(&out)
+	want := "         450  .\n         350  Sub\n          50  Sub/Deep\n           0  Empty\n"
+	if out.String() != want {
+		t.Fatalf("report:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if len(r.Largest) != 2 || r.Largest[0].Path != "Sub/b.bin" || r.Largest[1].Path != "a.bin" {
+		t.Fatalf("Largest = %+v", r.Largest)
+	}
+	if r.Folders[0].Files != 4 {
+		t.Fatalf("root files = %d", r.Folders[0].Files)
+	}
+}
+
+func TestUsage_FileShortcut(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	root := srv.AddFolder("Root", "")
+	srv.Add(drive.File{Name: "a-link", MimeType: drive.ShortcutMimeType, Parents: []string{root},
+		ShortcutDetails: &drive.ShortcutDetails{TargetID: "x", TargetMimeType: "application/pdf"}}, nil)
+	srv.Add(drive.File{Name: "b.bin", Parents: []string{root}}, make([]byte, 100))
+
+	// A file shortcut must not hide the files after it.
+	r, err := Usage(context.Background(), srv.Client(), root, 10)
+	if err != nil || r.Total() != 100 || len(r.Largest) != 1 {
+		t.Fatalf("Usage = %+v, %v; want the 100 bytes of b.bin", r, err)
+	}
+}
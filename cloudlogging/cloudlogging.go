@@ -0,0 +1,217 @@
+// Package cloudlogging ships the lifecycle events of deploys, rollbacks,
+// and syncs, and the entries of an audit log, to Google Cloud Logging as
+// structured entries, for teams whose observability is on Google Cloud.
+//
+// A Sink satisfies events.Events, so it can be set as deploy.Events or
+// dirsync.Events, and takes audit entries through RecordAudit, which fits
+// auditlog.Log.OnRecord. Its client needs the logging.write scope.
+package cloudlogging
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/auditlog"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/events"
+)
+
+// Endpoint is where entries are written.
+const Endpoint = "https://logging.googleapis.com/v2/entries:write"
+
+// Severities of entries.
+const (
+	SeverityInfo   = "INFO"
+	SeverityNotice = "NOTICE"
+	SeverityError  = "ERROR"
+)
+
+// Resource is the monitored resource entries are written against.
+type Resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// GenericTask returns the generic_task resource of job in projectID, run
+// on this host, so entries can be filtered by job and host.
+func GenericTask(projectID, job string) Resource {
+	host, _ := os.Hostname()
+	return Resource{Type: "generic_task", Labels: map[string]string{
+		"project_id": projectID, "location": "global", "namespace": "gdrivetoolbox", "job": job, "task_id": host,
+	}}
+}
+
+// Entry is one log entry, whose Payload shows up as its jsonPayload.
+type Entry struct {
+	Severity string            `json:"severity"`
+	Time     time.Time         `json:"timestamp"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Payload  map[string]any    `json:"jsonPayload"`
+}
+
+// Sink writes entries to a log of a Google Cloud project. Entries are held
+// and written in one request when a deploy, rollback, or sync finishes,
+// when MaxPending are held, or on Flush, which callers make when they are
+// done, so that audit entries left over are written too. Its methods are
+// safe for concurrent use.
+type Sink struct {
+	Client    *drive.Client
+	ProjectID string
+	// LogName is the log written to; empty means "gdrivetoolbox".
+	LogName string
+	// Resource is the resource entries are written against; the zero
+	// value means GenericTask(ProjectID, LogName).
+	Resource Resource
+	// Labels are added to every entry.
+	Labels map[string]string
+	// OnError, if set, receives the errors of writing entries, which are
+	// otherwise dropped so as not to fail what they describe.
+	OnError func(error)
+	// MaxPending is how many entries are held before they are written;
+	// 0 means 1000.
+	MaxPending int
+
+	mu      sync.Mutex
+	pending []Entry
+}
+
+var _ events.Events = (*Sink)(nil)
+
+func (s *Sink) logName() string {
+	if s.LogName != "" {
+		return s.LogName
+	}
+	return "gdrivetoolbox"
+}
+
+// Write writes entries in one request.
+func (s *Sink) Write(ctx context.Context, entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	res := s.Resource
+	if res.Type == "" {
+		res = GenericTask(s.ProjectID, s.logName())
+	}
+	body := map[string]any{
+		"logName":  "projects/" + s.ProjectID + "/logs/" + url.PathEscape(s.logName()),
+		"resource": res,
+		"labels":   s.Labels,
+		"entries":  entries,
+	}
+	if err := s.Client.DoJSON(ctx, "POST", Endpoint, body, nil); err != nil {
+		return fmt.Errorf("cloud logging: %w", err)
+	}
+	return nil
+}
+
+// add holds e until the next flush, flushing now if MaxPending entries
+// are held.
+func (s *Sink) add(e Entry) {
+	s.mu.Lock()
+	s.pending = append(s.pending, e)
+	full := len(s.pending) >= cmp.Or(s.MaxPending, 1000)
+	s.mu.Unlock()
+	if full {
+		s.flush()
+	}
+}
+
+// Flush writes the entries held so far.
+func (s *Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	entries := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	return s.Write(ctx, entries...)
+}
+
+// flush is Flush, reporting its error to OnError.
+func (s *Sink) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.Flush(ctx); err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// payload returns fields without the empty ones.
+func payload(fields ...any) map[string]any {
+	p := map[string]any{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		switch v := fields[i+1].(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+		case error:
+			if v == nil {
+				continue
+			}
+			fields[i+1] = v.Error()
+		case nil:
+			continue
+		}
+		p[fields[i].(string)] = fields[i+1]
+	}
+	return p
+}
+
+// labels returns the labels of an entry of the operation correlationID.
+func labels(correlationID string) map[string]string {
+	if correlationID == "" {
+		return nil
+	}
+	return map[string]string{"correlation_id": correlationID}
+}
+
+func (s *Sink) DeployStarted(e events.DeployStarted) {
+	s.add(Entry{Severity: SeverityInfo, Time: e.Time, Labels: labels(e.CorrelationID), Payload: payload(
+		"event", "started", "message", e.Source+" started: "+e.Name, "source", e.Source, "name", e.Name, "version", e.Version)})
+}
+
+func (s *Sink) FileArchived(e events.FileArchived) {
+	s.add(Entry{Severity: SeverityInfo, Time: e.Time, Payload: payload(
+		"event", "archived", "message", "archived "+e.FileID+" as "+e.ArchivedAs, "source", e.Source, "name", e.Name,
+		"fileId", e.FileID, "archivedAs", e.ArchivedAs, "version", e.Version, "folderId", e.FolderID)})
+}
+
+// UploadProgress is not logged.
+func (s *Sink) UploadProgress(events.UploadProgress) {}
+
+// DeployFinished logs the end of the operation and writes its entries.
+func (s *Sink) DeployFinished(e events.DeployFinished) {
+	sev := SeverityInfo
+	if e.Err != nil {
+		sev = SeverityError
+	}
+	s.add(Entry{Severity: sev, Time: e.Time, Labels: labels(e.CorrelationID), Payload: payload(
+		"event", "finished", "message", e.Source+" "+e.Status+": "+e.Name, "source", e.Source, "name", e.Name,
+		"version", e.Version, "fileId", e.FileID, "status", e.Status, "error", e.Err,
+		"bytes", e.Stats.Bytes, "elapsedSeconds", e.Stats.Elapsed.Seconds(), "retries", e.Stats.Retries)})
+	s.flush()
+}
+
+func (s *Sink) Error(e events.Error) {
+	s.add(Entry{Severity: SeverityError, Time: e.Time, Labels: labels(e.CorrelationID), Payload: payload(
+		"event", "error", "message", e.Op+" failed", "source", e.Source, "name", e.Name, "op", e.Op, "error", e.Err)})
+}
+
+// RecordAudit holds e with the events, so as not to hold up the change it
+// records. It fits auditlog.Log.OnRecord.
+func (s *Sink) RecordAudit(e auditlog.Entry) {
+	p := payload("event", "audit", "message", e.Operation+" "+e.FileID, "operation", e.Operation, "actor", e.Actor,
+		"fileId", e.FileID, "permissionId", e.PermissionID, "revisionId", e.RevisionID)
+	if e.Old != nil {
+		p["old"] = e.Old
+	}
+	if e.New != nil {
+		p["new"] = e.New
+	}
+	s.add(Entry{Severity: SeverityNotice, Time: e.Time, Payload: p})
+}
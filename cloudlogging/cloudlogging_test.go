@@ -0,0 +1,153 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/auditlog"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/events"
+)
+
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+type written struct {
+	LogName  string            `json:"logName"`
+	Resource Resource          `json:"resource"`
+	Labels   map[string]string `json:"labels"`
+	Entries  []Entry           `json:"entries"`
+}
+
+func testSink(t *testing.T, status int) (*Sink, *[]written) {
+	t.Helper()
+	var writes []written
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/entries:write" {
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		var body written
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		writes = append(writes, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return &Sink{Client: c, ProjectID: "docs-prod", Labels: map[string]string{"team": "docs"}}, &writes
+}
+
+func TestSinkDeploy(t *testing.T) {
+	s, writes := testSink(t, http.StatusOK)
+	s.Resource = Resource{Type: "generic_task", Labels: map[string]string{"job": "deploy"}}
+	at := time.Date(2024, 5, 2, 10, 14, 0, 0, time.UTC)
+
+	s.DeployStarted(events.DeployStarted{Source: events.SourceDeploy, Name: "mydoc.pdf", Version: "v2", CorrelationID: "c1", Time: at})
+	s.FileArchived(events.FileArchived{Source: events.SourceDeploy, Name: "mydoc.pdf", FileID: "old", ArchivedAs: "mydoc-v1.pdf", FolderID: "arch", Time: at})
+	s.UploadProgress(events.UploadProgress{Source: events.SourceDeploy, Name: "mydoc.pdf"})
+	if len(*writes) != 0 {
+		t.Fatalf("wrote before the deploy finished: %+v", *writes)
+	}
+	s.DeployFinished(events.DeployFinished{Source: events.SourceDeploy, Name: "mydoc.pdf", Version: "v2", FileID: "new",
+		Status: "deployed", CorrelationID: "c1", Time: at})
+
+	if len(*writes) != 1 {
+		t.Fatalf("got %d writes, want 1", len(*writes))
+	}
+	w := (*writes)[0]
+	if w.LogName != "projects/docs-prod/logs/gdrivetoolbox" || w.Resource.Labels["job"] != "deploy" || w.Labels["team"] != "docs" {
+		t.Errorf("write %+v", w)
+	}
+	if len(w.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(w.Entries), w.Entries)
+	}
+	fin := w.Entries[2]
+	if fin.Severity != SeverityInfo || !fin.Time.Equal(at) || fin.Labels["correlation_id"] != "c1" ||
+		fin.Payload["event"] != "finished" || fin.Payload["fileId"] != "new" || fin.Payload["status"] != "deployed" {
+		t.Errorf("finished entry %+v", fin)
+	}
+	if w.Entries[1].Payload["archivedAs"] != "mydoc-v1.pdf" {
+		t.Errorf("archived entry %+v", w.Entries[1])
+	}
+
+	// A failure is logged as an error, and entries are not written twice.
+	s.Error(events.Error{Source: events.SourceDeploy, Name: "mydoc.pdf", Op: "deploy upload", Err: errors.New("boom"), Time: at})
+	s.DeployFinished(events.DeployFinished{Source: events.SourceDeploy, Name: "mydoc.pdf", Status: "failed", Err: errors.New("boom"), Time: at})
+	if len(*writes) != 2 || len((*writes)[1].Entries) != 2 {
+		t.Fatalf("writes %+v", *writes)
+	}
+	for _, e := range (*writes)[1].Entries {
+		if e.Severity != SeverityError || e.Payload["error"] != "boom" {
+			t.Errorf("failure entry %+v", e)
+		}
+	}
+}
+
+func TestSinkAudit(t *testing.T) {
+	s, writes := testSink(t, http.StatusOK)
+	log := auditlog.New(io.Discard)
+	log.OnRecord = s.RecordAudit
+	log.Record(auditlog.Entry{Actor: "ada@example.com", Operation: auditlog.OpRename, FileID: "f1",
+		Old: map[string]any{"name": "a.pdf"}, New: map[string]any{"name": "b.pdf"}})
+	if len(*writes) != 0 {
+		t.Fatalf("wrote before the flush: %+v", *writes)
+	}
+	if err := s.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*writes) != 1 || len((*writes)[0].Entries) != 1 {
+		t.Fatalf("writes %+v", *writes)
+	}
+	w := (*writes)[0]
+	if w.Resource.Type != "generic_task" || w.Resource.Labels["project_id"] != "docs-prod" || w.Resource.Labels["job"] != "gdrivetoolbox" {
+		t.Errorf("resource %+v", w.Resource)
+	}
+	e := w.Entries[0]
+	if e.Severity != SeverityNotice || e.Payload["operation"] != "rename" || e.Payload["actor"] != "ada@example.com" ||
+		e.Payload["old"].(map[string]any)["name"] != "a.pdf" {
+		t.Errorf("entry %+v", e)
+	}
+}
+
+func TestSinkMaxPending(t *testing.T) {
+	s, writes := testSink(t, http.StatusOK)
+	s.MaxPending = 2
+	for _, id := range []string{"f1", "f2", "f3"} {
+		s.RecordAudit(auditlog.Entry{Operation: auditlog.OpTrash, FileID: id})
+	}
+	if len(*writes) != 1 || len((*writes)[0].Entries) != 2 {
+		t.Fatalf("writes %+v, want the first two entries", *writes)
+	}
+}
+
+func TestSinkError(t *testing.T) {
+	s, _ := testSink(t, http.StatusForbidden)
+	var errs []error
+	s.OnError = func(err error) { errs = append(errs, err) }
+	s.DeployFinished(events.DeployFinished{Source: events.SourceDeploy, Name: "mydoc.pdf", Status: "deployed", Time: time.Now()})
+	if len(errs) != 1 {
+		t.Fatalf("errors %v, want 1", errs)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/hwalton/gdrivetoolbox/auditlog"
+)
+
+// setupAudit makes every change sent through http.DefaultClient, which all
+// clients of the command use, appear in the --audit-log file. An entry
+// that cannot be written is logged, leaving the change itself in place.
+// With --cloud-logging, entries are shipped there too, or only there if
+// there is no --audit-log.
+func (a *app) setupAudit() {
+	if a.auditLog == "" && a.cloudLog == nil {
+		return
+	}
+	log := auditlog.New(io.Discard)
+	if a.auditLog != "" {
+		log = auditlog.OpenFile(a.auditLog)
+	}
+	if a.cloudLog != nil {
+		log.OnRecord = a.cloudLog.RecordAudit
+	}
+	hc := *http.DefaultClient
+	hc.Transport = log.Transport(hc.Transport, func(err error) {
+		a.logger.Error("audit log not written", "err", err)
+	})
+	http.DefaultClient = &hc
+}
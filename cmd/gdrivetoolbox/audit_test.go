@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/auditlog"
+)
+
+func TestAuditLog(t *testing.T) {
+	sampleTree(t)
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+
+	if out, err := run(t, "mv", "--access-token", "tok", "--audit-log", path, "q1", "/Reports/archive/q1-final.pdf"); err != nil {
+		t.Fatalf("mv: %v\n%s", err, out)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []auditlog.Entry
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var e auditlog.Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 ||
+		got[0].Operation != auditlog.OpRename || got[0].FileID != "q1" || got[0].Old["name"] != "q1.pdf" || got[0].New["name"] != "q1-final.pdf" ||
+		got[1].Operation != auditlog.OpMove || got[1].Old["parents"] == nil || got[1].New["parents"] == nil {
+		t.Errorf("audit log:\n%s", b)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// openBrowser opens url in the user's browser; tests replace it.
+var openBrowser = func(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+func newAuthCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage stored credentials",
+	}
+	cmd.AddCommand(newLoginCmd(a))
+	return cmd
+}
+
+// loggedIn is auth login's result.
+type loggedIn struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Profile string `json:"profile"`
+}
+
+func newLoginCmd(a *app) *cobra.Command {
+	var device, noBrowser bool
+	var scopes []string
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in with a Google account and store the refresh token",
+		Long: `Login opens Google's consent page in a browser and stores the resulting
+refresh token in the credentials file, so later commands need no token
+flags. With --device it prints a code to enter on another device instead;
+Google only allows the drive.file scope with that flow.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, secret := a.clientID, a.clientSecret
+			if id == "" || secret == "" {
+				return errors.New("login needs --client-id and --client-secret")
+			}
+			if device && !cmd.Flags().Changed("scope") {
+				scopes = []string{auth.DriveFileScope}
+			}
+			out := a.messages(cmd)
+			ctx := cmd.Context()
+
+			var tok *auth.Token
+			var err error
+			if device {
+				var dc *auth.DeviceCode
+				if dc, err = auth.RequestDeviceCode(ctx, id, scopes); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Visit %s and enter the code %s\n", dc.VerificationURL, dc.UserCode)
+				tok, err = auth.PollDeviceToken(ctx, id, secret, dc)
+			} else {
+				tok, err = auth.BrowserLogin(ctx, id, secret, scopes, func(url string) error {
+					fmt.Fprintf(out, "Open this URL to log in:\n  %s\n", url)
+					if !noBrowser {
+						// The URL is printed anyway, so a failure is not fatal.
+						openBrowser(url)
+					}
+					return nil
+				})
+			}
+			if err != nil {
+				return err
+			}
+			if tok.RefreshToken == "" {
+				return errors.New("login returned no refresh token")
+			}
+
+			about, err := drive.NewClient(tok.AccessToken).GetAbout(ctx)
+			if err != nil {
+				return fmt.Errorf("read account: %w", err)
+			}
+			store, err := a.store()
+			if err != nil {
+				return err
+			}
+			creds := auth.Credentials{ClientID: id, ClientSecret: secret, RefreshToken: tok.RefreshToken, Account: about.User.EmailAddress}
+			if err := store.Save(creds); err != nil {
+				return fmt.Errorf("save credentials: %w", err)
+			}
+			res := loggedIn{Name: about.User.DisplayName, Email: about.User.EmailAddress, Profile: a.profile}
+			return writeResult(a, cmd, res, func(w io.Writer, l loggedIn) {
+				fmt.Fprintf(w, "Logged in as %s <%s>\n", l.Name, l.Email)
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&device, "device", false, "use the device flow, for machines without a browser")
+	f.BoolVar(&noBrowser, "no-browser", false, "print the login URL without opening a browser")
+	f.StringSliceVar(&scopes, "scope", []string{auth.DriveScope}, "OAuth2 scopes to request")
+	return cmd
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+)
+
+func TestAuthLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			// GetGoogleAccessToken posts JSON; the login flows post forms.
+			if r.Header.Get("Content-Type") == "application/json" {
+				w.Write([]byte(`{"access_token":"tok2"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"tok","refresh_token":"ref"}`))
+		case "/drive/v3/about":
+			w.Write([]byte(`{"user":{"displayName":"Ada","emailAddress":"ada@example.com"}}`))
+		case "/drive/v3/files":
+			if r.Header.Get("Authorization") != "Bearer tok2" {
+				t.Errorf("Authorization = %q, want the refreshed token", r.Header.Get("Authorization"))
+			}
+			w.Write([]byte(`{"files":[]}`))
+		default:
+			w.Write([]byte(`{"id":"new","parents":["temp"]}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	// Act as the browser: approve at once and follow the redirect.
+	defer func(f func(string) error) { openBrowser = f }(openBrowser)
+	openBrowser = func(consent string) error {
+		u, _ := url.Parse(consent)
+		q := u.Query()
+		back := q.Get("redirect_uri") + "?" + url.Values{"code": {"abc"}, "state": {q.Get("state")}}.Encode()
+		go func() {
+			if resp, err := (&http.Client{}).Get(back); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	creds := filepath.Join(t.TempDir(), "credentials.json")
+	t.Setenv("GDRIVE_CREDENTIALS", creds)
+	t.Setenv("GDRIVE_ACCESS_TOKEN", "")
+	t.Setenv("GDRIVE_REFRESH_TOKEN", "")
+	out, err := run(t, "auth", "login", "--client-id", "id", "--client-secret", "secret")
+	if err != nil {
+		t.Fatalf("login: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Logged in as Ada <ada@example.com>") {
+		t.Fatalf("output = %q", out)
+	}
+	got, err := auth.Store{Path: creds}.Load()
+	if err != nil || got.RefreshToken != "ref" || got.Account != "ada@example.com" {
+		t.Fatalf("stored = %+v, %v", got, err)
+	}
+
+	// Later commands pick up the stored credentials.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, "deploy", "--file", "mydoc", "--version", "v1", "--folder", "f", "--temp-folder", "temp", "--dir", dir); err != nil {
+		t.Fatalf("deploy with stored credentials: %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// ciTimeFormat starts every line written under --ci.
+const ciTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// ciNow is the clock of --ci timestamps.
+var ciNow = time.Now
+
+// stampWriter starts every line written through it with the time, so CI
+// logs can be read and grepped without interactive output in the way.
+type stampWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	mid bool // within a line
+}
+
+func (s *stampWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		if !s.mid {
+			if _, err := io.WriteString(s.w, ciNow().UTC().Format(ciTimeFormat)+" "); err != nil {
+				return 0, err
+			}
+			s.mid = true
+		}
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line, s.mid = p[:i+1], false
+		}
+		if _, err := s.w.Write(line); err != nil {
+			return 0, err
+		}
+		p = p[len(line):]
+	}
+	return n, nil
+}
+
+// setupCI makes --ci timestamp every line of stderr, and of stdout unless
+// it carries machine output. Written through a stampWriter, output is no
+// terminal, so there are no colors or progress bars either.
+func (a *app) setupCI(cmd *cobra.Command) {
+	if !a.ci {
+		return
+	}
+	root := cmd.Root()
+	root.SetErr(&stampWriter{w: root.ErrOrStderr()})
+	if !a.machine() {
+		root.SetOut(&stampWriter{w: root.OutOrStdout()})
+	}
+}
+
+// ciDeploys collects the deploys of a command for its --ci summary.
+type ciDeploys struct {
+	mu      sync.Mutex
+	results []deploy.Result
+}
+
+func (d *ciDeploys) NotifyDeploy(r deploy.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results = append(d.results, r)
+}
+
+// ciSummaries makes cmd and its subcommands end with a summary block under
+// --ci: how the command ended, how long it took, and what it deployed.
+func ciSummaries(cmd *cobra.Command, a *app) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			start := time.Now()
+			err := run(cmd, args)
+			if a.ci {
+				a.writeSummary(cmd.ErrOrStderr(), cmd, err, time.Since(start))
+			}
+			return err
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		ciSummaries(sub, a)
+	}
+}
+
+func (a *app) writeSummary(w io.Writer, cmd *cobra.Command, err error, took time.Duration) {
+	fmt.Fprintf(w, "==== summary: %s ====\n", cmd.CommandPath())
+	result := "ok"
+	if err != nil {
+		result = "failed"
+	}
+	fmt.Fprintf(w, "result=%s exit=%d elapsed=%s", result, exitCode(err), took.Round(time.Millisecond))
+	if err != nil {
+		fmt.Fprintf(w, " error=%s", strconv.Quote(err.Error()))
+	}
+	fmt.Fprintln(w)
+	if calls := a.quota.Calls(); len(calls) > 0 {
+		fmt.Fprint(w, "requests")
+		for _, m := range slices.Sorted(maps.Keys(calls)) {
+			fmt.Fprintf(w, " %s=%d", m, calls[m])
+		}
+		fmt.Fprintln(w)
+	}
+	var results []deploy.Result
+	if a.deploys != nil {
+		a.deploys.mu.Lock()
+		results = a.deploys.results
+		a.deploys.mu.Unlock()
+	}
+	if len(results) > 0 {
+		counts := map[string]int{}
+		for _, r := range results {
+			counts[r.Status]++
+		}
+		fmt.Fprintf(w, "deployed=%d skipped=%d failed=%d\n",
+			counts[deploy.StatusDeployed], counts[deploy.StatusSkipped], counts[deploy.StatusFailed])
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %s %s", r.Status, r.File, r.Version)
+			if r.FileID != "" {
+				fmt.Fprintf(w, " %s", drive.ViewURL(r.FileID))
+			}
+			if r.Err != nil {
+				fmt.Fprintf(w, " error=%s", strconv.Quote(r.Err.Error()))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintln(w, "==== end summary ====")
+}
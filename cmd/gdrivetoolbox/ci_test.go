@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCI(t *testing.T) {
+	defer func(now func() time.Time) { ciNow = now }(ciNow)
+	ciNow = func() time.Time { return time.Date(2024, 5, 2, 10, 14, 0, 0, time.UTC) }
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files" {
+			w.Write([]byte(`{"files":[]}`))
+			return
+		}
+		w.Write([]byte(`{"id":"new"}`))
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	out, err := run(t, "deploy", "--ci", "-v", "--correlation-id", "ci-7", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir)
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, out)
+	}
+	const stamp = "2024-05-02T10:14:00.000Z "
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !strings.HasPrefix(line, stamp) {
+			t.Errorf("line without timestamp: %q", line)
+		}
+		if strings.Contains(line, "time=") {
+			t.Errorf("log line with a second time: %q", line)
+		}
+	}
+	for _, want := range []string{
+		stamp + "level=INFO msg=deployed correlation_id=ci-7 file=mydoc.pdf version=v3 id=new\n",
+		stamp + "deployed mydoc.pdf (v3)\n",
+		stamp + "==== summary: gdrivetoolbox deploy ====\n" + stamp + "result=ok exit=0 elapsed=",
+		stamp + "requests files.create=1 files.get=1 files.list=2 files.update=2\n",
+		stamp + "deployed=1 skipped=0 failed=0\n" +
+			stamp + "deployed mydoc.pdf v3 https://drive.google.com/file/d/new/view\n" +
+			stamp + "==== end summary ====\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "==== end summary ====\n") {
+		t.Errorf("output does not end with the summary:\n%s", out)
+	}
+}
+
+func TestCIFailure(t *testing.T) {
+	sampleTree(t)
+
+	out, err := run(t, "rm", "--ci", "--access-token", "tok", "--yes", "/Reports/missing.pdf")
+	if err == nil {
+		t.Fatalf("rm of a missing file succeeded:\n%s", out)
+	}
+	if !strings.Contains(out, `result=failed exit=4 elapsed=`) || !strings.Contains(out, ` error="resolve \"/Reports/missing.pdf\"`) {
+		t.Errorf("summary does not report the failure:\n%s", out)
+	}
+	if strings.Contains(out, "deployed=") {
+		t.Errorf("summary of a command that deploys nothing lists deploys:\n%s", out)
+	}
+}
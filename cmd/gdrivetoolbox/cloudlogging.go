@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/cloudlogging"
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/dirsync"
+)
+
+// setupCloudLogging ships the events of the deploys, rollbacks, and syncs
+// of cmd, and with setupAudit its audit entries, to the log of the
+// --cloud-logging project, written against the generic_task resource of
+// the command on this host.
+func (a *app) setupCloudLogging(cmd *cobra.Command) error {
+	deploy.Events, dirsync.Events, a.cloudLog = nil, nil, nil
+	if a.cloudLogging == "" {
+		return nil
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	a.cloudLog = &cloudlogging.Sink{
+		Client:    c,
+		ProjectID: a.cloudLogging,
+		Resource:  cloudlogging.GenericTask(a.cloudLogging, cmd.Name()),
+		Labels:    a.cloudLabels,
+		OnError: func(err error) {
+			a.logger.Warn("log entries not written to Cloud Logging", "err", err)
+		},
+	}
+	deploy.Events, dirsync.Events = a.cloudLog, a.cloudLog
+	return nil
+}
+
+// flushCloudLogs makes cmd and its subcommands write the entries the
+// --cloud-logging sink still holds when they end, such as the audit
+// entries of changes made outside a deploy, even if they failed or were
+// cancelled.
+func flushCloudLogs(cmd *cobra.Command, a *app) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if a.cloudLog != nil {
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(cmd.Context()), 30*time.Second)
+				defer cancel()
+				if err := a.cloudLog.Flush(ctx); err != nil {
+					a.logger.Warn("log entries not written to Cloud Logging", "err", err)
+				}
+			}
+			return err
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		flushCloudLogs(sub, a)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/cloudlogging"
+)
+
+func TestCloudLogging(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	type write struct {
+		Resource cloudlogging.Resource
+		Labels   map[string]string
+		Entries  []cloudlogging.Entry
+	}
+	var writes []write
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v2/entries:write":
+			var body write
+			json.NewDecoder(r.Body).Decode(&body)
+			writes = append(writes, body)
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/drive/v3/about":
+			w.Write([]byte(`{"user":{"emailAddress":"ada@example.com"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+	t.Setenv("GDRIVE_CLOUD_LOGGING", "docs-prod")
+
+	out, err := run(t, "deploy", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir, "--cloud-logging-label", "team=docs")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, out)
+	}
+	var audits, finished int
+	for _, w := range writes {
+		if w.Resource.Labels["job"] != "deploy" || w.Resource.Labels["project_id"] != "docs-prod" || w.Labels["team"] != "docs" {
+			t.Errorf("write %+v", w)
+		}
+		for _, e := range w.Entries {
+			switch e.Payload["event"] {
+			case "audit":
+				audits++
+			case "finished":
+				finished++
+				if e.Payload["status"] != "deployed" || e.Payload["fileId"] != "new" {
+					t.Errorf("finished entry %+v", e)
+				}
+			}
+		}
+	}
+	if audits == 0 || finished != 1 {
+		t.Errorf("%d audit entries and %d finished, in %+v", audits, finished, writes)
+	}
+}
+
+func TestCloudLoggingAuditFlushedAtEnd(t *testing.T) {
+	var entries []cloudlogging.Entry
+	srv := newTree(t)
+	srv.HandleFunc("POST /v2/entries:write", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Entries []cloudlogging.Entry }
+		json.NewDecoder(r.Body).Decode(&body)
+		entries = append(entries, body.Entries...)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	})
+	t.Setenv("GDRIVE_CLOUD_LOGGING", "docs-prod")
+
+	out, err := run(t, "mkdir", "--access-token", "tok", "Reports")
+	if err != nil {
+		t.Fatalf("mkdir: %v\n%s", err, out)
+	}
+	if len(entries) != 1 || entries[0].Payload["event"] != "audit" || entries[0].Payload["operation"] != "create" {
+		t.Errorf("entries %+v, want the audit entry of the new folder", entries)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// palette colors text written to a terminal; when off, it returns text
+// unchanged.
+type palette struct{ on bool }
+
+// newPalette returns the palette for w: colors if w is a terminal, unless
+// noColor or the NO_COLOR environment variable (https://no-color.org)
+// turns them off.
+func newPalette(w io.Writer, noColor bool) palette {
+	return palette{on: !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(w)}
+}
+
+// palette returns the palette for w under --no-color.
+func (a *app) palette(w io.Writer) palette {
+	return newPalette(w, a.noColor)
+}
+
+func (p palette) paint(code, s string) string {
+	if !p.on || s == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+func (p palette) green(s string) string  { return p.paint("32", s) }
+func (p palette) yellow(s string) string { return p.paint("33", s) }
+func (p palette) red(s string) string    { return p.paint("31", s) }
+func (p palette) dim(s string) string    { return p.paint("2", s) }
+
+// writePlan writes the lines of plan to w colored by their mark: green
+// for "+" (new), yellow for "~" (updated), red for "-" (deleted) and "!"
+// (conflicts), and dim for indented lines, which change nothing.
+func (p palette) writePlan(w io.Writer, plan io.WriterTo) (int64, error) {
+	if !p.on {
+		return plan.WriteTo(w)
+	}
+	var buf bytes.Buffer
+	if _, err := plan.WriteTo(&buf); err != nil {
+		return 0, err
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		text, nl := strings.CutSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(text, "+"):
+			text = p.green(text)
+		case strings.HasPrefix(text, "~"):
+			text = p.yellow(text)
+		case strings.HasPrefix(text, "-"), strings.HasPrefix(text, "!"):
+			text = p.red(text)
+		case strings.HasPrefix(text, " "):
+			text = p.dim(text)
+		}
+		out.WriteString(text)
+		if nl {
+			out.WriteByte('\n')
+		}
+	}
+	n, err := io.WriteString(w, out.String())
+	return int64(n), err
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func TestPaletteWritePlan(t *testing.T) {
+	plan := &deploy.DeployPlan{Steps: []deploy.DeployStep{
+		{Document: deploy.Document{File: "a", Version: "v2"}, Kind: deploy.StepUpload},
+		{Document: deploy.Document{File: "b", Version: "v2"}, Kind: deploy.StepReplace, Live: "v1"},
+		{Document: deploy.Document{File: "c", Version: "v1"}, Kind: deploy.StepSkip},
+	}}
+
+	var plain bytes.Buffer
+	palette{}.writePlan(&plain, plan)
+	if strings.Contains(plain.String(), "\033") {
+		t.Errorf("colors without a palette:\n%q", plain.String())
+	}
+
+	var colored bytes.Buffer
+	palette{on: true}.writePlan(&colored, plan)
+	lines := strings.Split(plain.String(), "\n")
+	want := "\033[32m" + lines[0] + "\033[0m\n" +
+		"\033[33m" + lines[1] + "\033[0m\n" +
+		"\033[2m" + lines[2] + "\033[0m\n"
+	if colored.String() != want {
+		t.Errorf("colored plan = %q, want %q", colored.String(), want)
+	}
+}
+
+func TestNewPalette(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	if newPalette(&bytes.Buffer{}, false).on {
+		t.Error("colors for a buffer")
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no terminal:", err)
+	}
+	defer tty.Close()
+	t.Setenv("TERM", "xterm")
+	if !newPalette(tty, false).on {
+		t.Error("no colors for a terminal")
+	}
+	if newPalette(tty, true).on {
+		t.Error("colors despite --no-color")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if newPalette(tty, false).on {
+		t.Error("colors despite NO_COLOR")
+	}
+}
+
+func TestErrorsUncoloredOffTerminal(t *testing.T) {
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetErr(&out)
+	reportError(root, os.ErrNotExist)
+	if got := out.String(); got != "Error: file does not exist\n" {
+		t.Errorf("reportError wrote %q", got)
+	}
+}
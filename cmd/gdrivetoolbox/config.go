@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the optional YAML config file:
+//
+//	client-id: 1234.apps.googleusercontent.com
+//	client-secret: GOCSPX-...
+//	folders:
+//	  prod: 1AbC...
+//	  prod-archive: 1DeF...
+//	deploy:
+//	  temp-folder: 1GhI...
+//	  archive-folder: prod-archive
+//	profiles:
+//	  client-b:
+//	    client-id: 5678.apps.googleusercontent.com
+//	    folders:
+//	      prod: 1JkL...
+//
+// Top-level keys set the flag of that name for every command that has it,
+// a section named after a command sets flags of that command only, and
+// folders names folder IDs that any folder flag accepts in their place.
+// Each profile is a config of its own laid over the rest when selected.
+type config struct {
+	flags    map[string]string
+	commands map[string]map[string]string
+	folders  map[string]string
+	profiles map[string]*config
+}
+
+// commandLineOnly names the flags that skip confirmations or make deletes
+// permanent, which neither the environment nor the config file may set: a
+// shared config or a stray GDRIVE_YES would otherwise do so for every run.
+var commandLineOnly = map[string]bool{"yes": true, "permanent": true}
+
+// folderAnnotation marks flags that take a folder; see folderFlags.
+const folderAnnotation = "gdrivetoolbox_folder"
+
+// configDir is the gdrivetoolbox directory in the user's configuration
+// directory, which holds the config file, stored credentials, and the
+// current profile.
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gdrivetoolbox"), nil
+}
+
+// loadConfig reads the config file at path. A missing file is an empty
+// config unless the path was asked for explicitly.
+func loadConfig(path string, explicit bool) (*config, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return parseConfig(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return parseConfig(raw), nil
+}
+
+func parseConfig(raw map[string]any) *config {
+	cfg := &config{
+		flags:    map[string]string{},
+		commands: map[string]map[string]string{},
+		folders:  map[string]string{},
+		profiles: map[string]*config{},
+	}
+	for key, v := range raw {
+		section, isSection := v.(map[string]any)
+		switch {
+		case key == "folders" && isSection:
+			for name, id := range section {
+				cfg.folders[name] = fmt.Sprint(id)
+			}
+		case key == "profiles" && isSection:
+			for name, v := range section {
+				p, _ := v.(map[string]any)
+				cfg.profiles[name] = parseConfig(p)
+			}
+		case isSection:
+			cfg.commands[key] = map[string]string{}
+			for name, v := range section {
+				cfg.commands[key][name] = configValue(v)
+			}
+		default:
+			cfg.flags[key] = configValue(v)
+		}
+	}
+	return cfg
+}
+
+// overlay returns cfg with every value p sets replaced.
+func (cfg *config) overlay(p *config) *config {
+	out := parseConfig(nil)
+	for _, c := range []*config{cfg, p} {
+		maps.Copy(out.flags, c.flags)
+		maps.Copy(out.folders, c.folders)
+		for cmd, section := range c.commands {
+			if out.commands[cmd] == nil {
+				out.commands[cmd] = map[string]string{}
+			}
+			maps.Copy(out.commands[cmd], section)
+		}
+	}
+	return out
+}
+
+// configValue renders a YAML value as a flag value; lists become the
+// comma-separated form slice flags accept.
+func configValue(v any) string {
+	if list, ok := v.([]any); ok {
+		parts := make([]string, len(list))
+		for i, e := range list {
+			parts[i] = fmt.Sprint(e)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// envKey is the environment variable for a flag: --temp-folder is read
+// from GDRIVE_TEMP_FOLDER.
+func envKey(flag string) string {
+	return "GDRIVE_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// folderEnvPrefix names folders from the environment: GDRIVE_FOLDER_PROD
+// is the folder "prod", taking precedence over the config file.
+const folderEnvPrefix = "GDRIVE_FOLDER_"
+
+// readConfig loads the config file named by --config or GDRIVE_CONFIG,
+// or the default one, and returns it with its path.
+func (a *app) readConfig() (*config, string, error) {
+	path, explicit := a.configPath, a.configPath != ""
+	if p := os.Getenv("GDRIVE_CONFIG"); !explicit && p != "" {
+		path, explicit = p, true
+	}
+	if !explicit {
+		dir, err := configDir()
+		if err != nil {
+			return nil, "", err
+		}
+		path = filepath.Join(dir, "config.yaml")
+	}
+	cfg, err := loadConfig(path, explicit)
+	return cfg, path, err
+}
+
+// forProfile returns cfg with the section of profile laid over it and the
+// folders that GDRIVE_FOLDER_NAME variables define added.
+func (cfg *config) forProfile(profile string) *config {
+	if p, ok := cfg.profiles[profile]; ok {
+		cfg = cfg.overlay(p)
+	}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutPrefix(k, folderEnvPrefix); ok && name != "" {
+			cfg.folders[strings.ToLower(strings.ReplaceAll(name, "_", "-"))] = v
+		}
+	}
+	return cfg
+}
+
+// lookup returns the value the environment or cfg gives flag of the
+// command named cmd, and where it comes from, in the order bind uses.
+func (cfg *config) lookup(cmd, flag string) (v, src string, ok bool) {
+	if v, ok := os.LookupEnv(envKey(flag)); ok {
+		return v, envKey(flag), true
+	}
+	if v, ok := cfg.commands[cmd][flag]; ok {
+		return v, "config " + cmd + "." + flag, true
+	}
+	v, ok = cfg.flags[flag]
+	return v, "config " + flag, ok
+}
+
+// bind fills every flag of cmd not given on the command line, in order of
+// precedence: its environment variable, the command's section of the
+// config file, then the config file's top level, with the selected
+// profile's values taking precedence over the rest of the file. Values of
+// folder flags that name a configured folder are replaced by its ID.
+func (a *app) bind(cmd *cobra.Command) error {
+	cfg, path, err := a.readConfig()
+	if err != nil {
+		return err
+	}
+	if a.profile, err = selectProfile(a.profile); err != nil {
+		return err
+	}
+	if !picksProfile(cmd) {
+		if err := a.checkProfile(a.profile); err != nil {
+			return err
+		}
+	}
+	cfg = cfg.forProfile(a.profile)
+
+	flags := cmd.Flags()
+	section := cfg.commands[cmd.Name()]
+	for name := range section {
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("config %s: %s: unknown flag --%s", path, cmd.Name(), name)
+		}
+	}
+	var errs []error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "config" || f.Name == "profile" {
+			return
+		}
+		if commandLineOnly[f.Name] {
+			if _, ok := section[f.Name]; ok {
+				errs = append(errs, fmt.Errorf("config %s: %s: --%s can only be given on the command line", path, cmd.Name(), f.Name))
+			} else if _, ok := cfg.flags[f.Name]; ok {
+				errs = append(errs, fmt.Errorf("config %s: --%s can only be given on the command line", path, f.Name))
+			}
+			return
+		}
+		if !f.Changed {
+			v, src, ok := cfg.lookup(cmd.Name(), f.Name)
+			if !ok || v == "" {
+				return
+			}
+			if err := flags.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", src, err))
+				return
+			}
+		}
+		if _, ok := f.Annotations[folderAnnotation]; ok {
+			if id, ok := cfg.folders[f.Value.String()]; ok {
+				f.Value.Set(id)
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// folderFlags marks the named flags of cmd as taking a folder, so names
+// from the folders section of the config file are accepted in their place.
+func folderFlags(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		cmd.Flags().SetAnnotation(name, folderAnnotation, []string{"true"})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+archive-folder: prod-archive
+dir: /top
+folders:
+  prod: P1
+  prod-archive: A1
+probe:
+  temp-folder: T1
+  dir: /section
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GDRIVE_CONFIG", path)
+	t.Setenv("GDRIVE_TEMP_FOLDER", "T2")
+	t.Setenv("GDRIVE_FOLDER_PROD", "P2")
+
+	var folder, temp, archive, dir string
+	probe := &cobra.Command{Use: "probe", RunE: func(*cobra.Command, []string) error { return nil }}
+	f := probe.Flags()
+	f.StringVar(&folder, "folder", "", "")
+	f.StringVar(&temp, "temp-folder", "", "")
+	f.StringVar(&archive, "archive-folder", "", "")
+	f.StringVar(&dir, "dir", ".", "")
+	folderFlags(probe, "folder", "temp-folder", "archive-folder")
+	root := newRootCmd()
+	root.AddCommand(probe)
+	root.SetArgs([]string{"probe", "--folder", "prod"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	// The flag names a folder overridden from the environment, the
+	// environment beats the command's section, which beats the top level.
+	if folder != "P2" || temp != "T2" || archive != "A1" || dir != "/section" {
+		t.Fatalf("folder=%q temp=%q archive=%q dir=%q", folder, temp, archive, dir)
+	}
+
+	os.WriteFile(path, []byte("probe:\n  nope: 1\n"), 0o644)
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "unknown flag --nope") {
+		t.Fatalf("unknown key: err = %v", err)
+	}
+	root.SetArgs([]string{"probe", "--config", filepath.Join(t.TempDir(), "missing.yaml")})
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected an error for a missing --config file")
+	}
+}
+
+func TestConfigCommandLineOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GDRIVE_CONFIG", path)
+	t.Setenv("GDRIVE_YES", "1")
+	t.Setenv("GDRIVE_PERMANENT", "true")
+
+	var yes, permanent bool
+	probe := &cobra.Command{Use: "probe", RunE: func(*cobra.Command, []string) error { return nil }}
+	probe.Flags().BoolVarP(&yes, "yes", "y", false, "")
+	probe.Flags().BoolVar(&permanent, "permanent", false, "")
+	root := newRootCmd()
+	root.AddCommand(probe)
+	root.SetArgs([]string{"probe"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if yes || permanent {
+		t.Fatalf("yes=%v permanent=%v set from the environment", yes, permanent)
+	}
+
+	for _, cfg := range []string{"yes: true\n", "probe:\n  permanent: true\n"} {
+		os.WriteFile(path, []byte(cfg), 0o644)
+		if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "can only be given on the command line") {
+			t.Fatalf("config %q: err = %v", cfg, err)
+		}
+	}
+	os.WriteFile(path, nil, 0o644)
+	root.SetArgs([]string{"probe", "-y"})
+	if err := root.Execute(); err != nil || !yes {
+		t.Fatalf("-y: yes=%v, err = %v", yes, err)
+	}
+}
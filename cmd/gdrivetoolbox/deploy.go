@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// deployed is deploy's result.
+type deployed struct {
+	File    string      `json:"file"`
+	Version string      `json:"version"`
+	Status  string      `json:"status"`
+	Stats   drive.Stats `json:"stats"`
+}
+
+func newDeployCmd(a *app) *cobra.Command {
+	var file, version, folder, tempFolder, archiveFolder, dir string
+	cmd := &cobra.Command{
+		Use:   "deploy --file NAME --version VERSION --folder ID --temp-folder ID",
+		Short: "Deploy a PDF, archiving or deleting the previous version",
+		Long: `Deploy uploads DIR/NAME.pdf to the temporary folder, tags it with VERSION,
+and moves it into the final folder. A copy already at VERSION is left alone.
+The previous version is renamed and moved to the archive folder if one is
+given, and deleted otherwise.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := a.token()
+			if err != nil {
+				return err
+			}
+			r, err := deploy.DeployPDFResult(cmd.Context(), token, file, version, tempFolder, folder, archiveFolder, dir)
+			if err != nil {
+				return err
+			}
+			return writeResult(a, cmd, deployed{File: r.File, Version: r.Version, Status: r.Status, Stats: r.Stats}, func(w io.Writer, d deployed) {
+				fmt.Fprintf(w, "%s %s (%s)\n", a.palette(w).green("deployed"), d.File, d.Version)
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&file, "file", "", "PDF name without the .pdf extension")
+	f.StringVar(&version, "version", "", "version stored in the file's description")
+	f.StringVar(&folder, "folder", "", "final Drive folder ID or URL")
+	f.StringVar(&tempFolder, "temp-folder", "", "Drive folder ID or URL to upload into first")
+	f.StringVar(&archiveFolder, "archive-folder", "", "Drive folder ID or URL for the previous version (deleted if unset)")
+	f.StringVar(&dir, "dir", ".", "local directory containing the PDF")
+	for _, name := range []string{"file", "version", "folder", "temp-folder"} {
+		cmd.MarkFlagRequired(name)
+	}
+	folderFlags(cmd, "folder", "temp-folder", "archive-folder")
+	notifyFlags(cmd, a)
+	verifyFlag(cmd, a)
+	return cmd
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func installTestClient(t *testing.T, srv *httptest.Server) {
+	t.Helper()
+	orig := http.DefaultClient
+	u, _ := url.Parse(srv.URL)
+	http.DefaultClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	t.Cleanup(func() { http.DefaultClient = orig })
+}
+
+// run executes the CLI with args and returns its output.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	root := newRootCmd()
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestDeploy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var calls []string
+	ids := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("addParents"))
+		ids[r.Header.Get("X-Request-Id")] = true
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"id":"new","parents":["temp"]}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	out, err := run(t, "deploy", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir, "--output", "json")
+	if err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	var res struct {
+		Status string
+		Stats  map[string]float64
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil || res.Status != "deployed" || res.Stats["bytes"] != 3 || res.Stats["retries"] != 0 {
+		t.Fatalf("result = %s (%v)", out, err)
+	}
+	last := calls[len(calls)-1]
+	if last != "PATCH /drive/v3/files/new final" {
+		t.Fatalf("last call = %q, want the move into the final folder; calls: %v", last, calls)
+	}
+	if len(ids) != 1 || ids[""] {
+		t.Errorf("X-Request-Id headers = %v, want one ID for every call", ids)
+	}
+}
+
+func TestDeployNotify(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var posted, notes, indexes []string
+	var rows [][]string
+	// Webhooks are posted with a plain client, not the one for Google APIs.
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		posted = append(posted, msg.Text)
+	}))
+	defer hook.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v4/spreadsheets/sheet1/values/"):
+			var body struct{ Values [][]string }
+			json.NewDecoder(r.Body).Decode(&body)
+			rows = append(rows, body.Values...)
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files" && strings.Contains(r.URL.RawQuery, "fields=id"):
+			b, _ := io.ReadAll(r.Body)
+			indexes = append(indexes, string(b))
+			w.Write([]byte(`{"id":"idx"}`))
+		case strings.HasSuffix(r.URL.Path, ":batchUpdate"):
+			notes = append(notes, r.URL.Path)
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/drive/v3/about":
+			w.Write([]byte(`{"user":{"emailAddress":"ada@example.com"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+	t.Setenv("GDRIVE_NOTIFY", hook.URL+"/services/hook")
+	t.Setenv("GDRIVE_CHANGELOG", "Release notes")
+	t.Setenv("GDRIVE_INDEX", "true")
+	t.Setenv("GDRIVE_SHEET", "https://docs.google.com/spreadsheets/d/sheet1/edit")
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_OUTPUT", filepath.Join(dir, "output"))
+	t.Setenv("GITHUB_STEP_SUMMARY", filepath.Join(dir, "summary"))
+
+	out, err := run(t, "deploy", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir, "--notify-env", "staging")
+	if err != nil {
+		t.Fatalf("deploy: %v\n%s", err, out)
+	}
+	want := "✅ Deployed *mydoc.pdf* v3 to staging: <https://drive.google.com/file/d/new/view|open in Drive>"
+	if len(posted) != 1 || posted[0] != want {
+		t.Errorf("posted %q, want %q", posted, want)
+	}
+
+	if len(rows) != 1 || len(rows[0]) != 5 || rows[0][0] != "mydoc.pdf" || rows[0][1] != "v3" || rows[0][2] != "ada@example.com" ||
+		rows[0][4] != "https://drive.google.com/file/d/new/view" {
+		t.Errorf("sheet rows = %q", rows)
+	}
+	if len(notes) != 1 || notes[0] != "/v1/documents/new:batchUpdate" {
+		t.Errorf("release notes updated through %q", notes)
+	}
+	if len(indexes) != 1 || !strings.Contains(indexes[0], `"file": "mydoc.pdf",
+      "version": "v3",
+      "id": "new",`) {
+		t.Errorf("index.json uploads = %q", indexes)
+	}
+	if !strings.Contains(out, "::notice title=Deployed mydoc.pdf::v3 is live: https://drive.google.com/file/d/new/view\n") {
+		t.Errorf("no GitHub Actions annotation:\n%s", out)
+	}
+	outputs, _ := os.ReadFile(filepath.Join(dir, "output"))
+	summary, _ := os.ReadFile(filepath.Join(dir, "summary"))
+	if !strings.Contains(string(outputs), "file_id=new\n") || !strings.Contains(string(summary), "| mydoc.pdf | v3 | deployed |") {
+		t.Errorf("GitHub Actions outputs:\n%s\nsummary:\n%s", outputs, summary)
+	}
+}
+
+func TestDeployFlags(t *testing.T) {
+	t.Setenv("GDRIVE_ACCESS_TOKEN", "")
+	t.Setenv("GDRIVE_CLIENT_ID", "")
+	t.Setenv("GDRIVE_CREDENTIALS", filepath.Join(t.TempDir(), "none.json"))
+	if _, err := run(t, "deploy", "--file", "mydoc"); err == nil || !strings.Contains(err.Error(), "required flag") {
+		t.Fatalf("missing flags: err = %v", err)
+	}
+	_, err := run(t, "deploy", "--file", "mydoc", "--version", "v3", "--folder", "f", "--temp-folder", "t")
+	if err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Fatalf("missing credentials: err = %v", err)
+	}
+}
+
+func TestDeployVerify(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The new version is moved, but never listed.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"id":"new","parents":["temp"]}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	_, err := run(t, "deploy", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir, "--verify", "10ms")
+	if !errors.Is(err, deploy.ErrNotPropagated) || !strings.Contains(err.Error(), "deployed as new, but not verified") {
+		t.Fatalf("deploy --verify: err = %v", err)
+	}
+	if _, err := run(t, "deploy", "--access-token", "tok", "--file", "mydoc", "--version", "v3",
+		"--folder", "final", "--temp-folder", "temp", "--dir", dir); err != nil {
+		t.Fatalf("deploy without --verify: %v", err)
+	}
+}
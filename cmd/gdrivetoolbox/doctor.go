@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Outcomes of a doctor check.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// check is one finding of doctor, with how to fix it unless it is ok.
+type check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+func newDoctorCmd(a *app) *cobra.Command {
+	var folders []string
+	var manifest string
+	cmd := &cobra.Command{
+		Use:   "doctor [--folder FOLDER]... [-f MANIFEST]",
+		Short: "Check credentials, scopes, and folder access before deploying",
+		Long: `Doctor checks what a deploy needs, in order, and says how to fix what is
+wrong instead of leaving it to fail halfway: that the credentials give an
+access token, that the token has a Drive scope, that the Drive API answers,
+and that each folder exists and can be read and written: those given with
+--folder or named in the manifest of -f, the folders of the deploy section
+of the config file, and every folder the config file names. It changes nothing, and fails if any
+check does.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p := newPrinter(a, cmd, func(w io.Writer, c check) {
+				pal := a.palette(w)
+				status := map[string]func(string) string{checkOK: pal.green, checkWarn: pal.yellow, checkFail: pal.red}[c.Status]
+				fmt.Fprintf(w, "%s %s: %s\n", status(fmt.Sprintf("%-4s", c.Status)), c.Name, c.Detail)
+				if c.Fix != "" {
+					fmt.Fprintf(w, "     fix: %s\n", c.Fix)
+				}
+			})
+			defer p.flush()
+			failed, total := 0, 0
+			report := func(c check) bool {
+				total++
+				if c.Status == checkFail {
+					failed++
+				}
+				p.print(c)
+				return c.Status != checkFail
+			}
+			doctor(cmd.Context(), a, folders, manifest, report)
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, total)
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&folders, "folder", nil, "Drive folder ID, URL, or path to check read and write access to")
+	f.StringVarP(&manifest, "file", "f", "", "deploy manifest whose folders to check")
+	return cmd
+}
+
+// doctor runs the checks, passing each to report, and stops at the first
+// whose failure makes the rest pointless, for which report returns false.
+func doctor(ctx context.Context, a *app, folders []string, manifest string, report func(check) bool) {
+	tok, err := a.token()
+	if err != nil {
+		c := check{Name: "credentials", Status: checkFail, Detail: err.Error(),
+			Fix: `log in again with "gdrivetoolbox auth login"; the refresh token may have been revoked or expired`}
+		if errors.Is(err, errNoCredentials) {
+			c.Detail = "no credentials"
+			c.Fix = `run "gdrivetoolbox auth login", or set --access-token, or --client-id, --client-secret, and --refresh-token`
+		}
+		report(c)
+		return
+	}
+	report(check{Name: "credentials", Status: checkOK, Detail: "got an access token " + a.credentialSource()})
+
+	if !report(scopeCheck(ctx, tok)) {
+		return
+	}
+
+	c := drive.NewClient(tok)
+	about, err := c.GetAbout(ctx)
+	if !report(apiCheck(about, err)) {
+		return
+	}
+
+	// Each target is checked once, under the first name it is found by.
+	type target struct{ name, ref string }
+	var targets []target
+	seen := map[string]bool{}
+	add := func(name, ref string) {
+		if ref != "" && !seen[ref] {
+			seen[ref] = true
+			targets = append(targets, target{name, ref})
+		}
+	}
+	for _, ref := range folders {
+		add("folder "+ref, ref)
+	}
+	if manifest != "" {
+		m, err := deploy.LoadManifest(manifest)
+		if err != nil {
+			report(check{Name: "manifest", Status: checkFail, Detail: err.Error(), Fix: "correct " + manifest})
+			return
+		}
+		add("folder "+m.Folder, m.Folder)
+		add("temp folder "+m.TempFolder, m.TempFolder)
+		add("archive folder "+m.ArchiveFolder, m.ArchiveFolder)
+	}
+	cfg, _, err := a.readConfig()
+	if err != nil {
+		report(check{Name: "config", Status: checkFail, Detail: err.Error(), Fix: "correct the config file"})
+		return
+	}
+	cfg = cfg.forProfile(a.profile)
+	for _, flag := range []string{"folder", "temp-folder", "archive-folder"} {
+		ref, _, _ := cfg.lookup("deploy", flag)
+		name := "deploy " + strings.ReplaceAll(flag, "-", " ") + " " + ref
+		if id, ok := cfg.folders[ref]; ok {
+			name, ref = name+" ("+id+")", id
+		}
+		add(name, ref)
+	}
+	for _, alias := range slices.Sorted(maps.Keys(cfg.folders)) {
+		add("folder "+alias+" ("+cfg.folders[alias]+")", cfg.folders[alias])
+	}
+	for _, t := range targets {
+		report(folderCheck(ctx, c, t.name, t.ref, about.User.EmailAddress))
+	}
+}
+
+// credentialSource says where token takes the access token from.
+func (a *app) credentialSource() string {
+	switch {
+	case a.accessToken != "":
+		return "from --access-token"
+	case a.refreshToken != "":
+		return "for --refresh-token"
+	case a.credentials != "":
+		return "for the credentials in " + a.credentials
+	}
+	return fmt.Sprintf("for the stored credentials of profile %q", a.profile)
+}
+
+// scopeCheck checks that tok grants access to Drive.
+func scopeCheck(ctx context.Context, tok string) check {
+	c := check{Name: "scopes"}
+	info, err := auth.LookupToken(ctx, tok)
+	if err != nil {
+		c.Status, c.Detail = checkFail, err.Error()
+		c.Fix = `log in again with "gdrivetoolbox auth login", or pass a current --access-token`
+		var te *auth.TokenError
+		if !errors.As(err, &te) {
+			c.Fix = "check that oauth2.googleapis.com can be reached through your network and proxy"
+		}
+		return c
+	}
+	names := make([]string, 0, len(info.Scopes()))
+	for _, s := range info.Scopes() {
+		names = append(names, strings.TrimPrefix(s, "https://www.googleapis.com/auth/"))
+	}
+	c.Detail = strings.Join(names, ", ")
+	switch {
+	case info.HasScope(auth.DriveScope):
+		c.Status = checkOK
+	case info.HasScope(auth.DriveFileScope):
+		c.Status = checkWarn
+		c.Detail += "; drive.file only sees files this tool created or was given"
+		c.Fix = `log in with "gdrivetoolbox auth login --scope ` + auth.DriveScope + `" to reach folders shared with you`
+	default:
+		c.Status = checkFail
+		c.Detail = "no Drive scope granted (" + c.Detail + ")"
+		c.Fix = `log in with "gdrivetoolbox auth login", which asks for the drive scope`
+	}
+	return c
+}
+
+// apiCheck checks the answer of the Drive API to GetAbout.
+func apiCheck(about drive.About, err error) check {
+	c := check{Name: "drive api"}
+	var apiErr *drive.APIError
+	switch {
+	case err == nil:
+		c.Status = checkOK
+		c.Detail = fmt.Sprintf("signed in as %s <%s>", about.User.DisplayName, about.User.EmailAddress)
+	case errors.As(err, &apiErr) && apiErr.Reason == "accessNotConfigured":
+		c.Status, c.Detail = checkFail, err.Error()
+		c.Fix = "enable the Google Drive API for the OAuth client's project in the Google Cloud console"
+	case errors.As(err, &apiErr):
+		c.Status, c.Detail = checkFail, err.Error()
+		c.Fix = `log in again with "gdrivetoolbox auth login"`
+	default:
+		c.Status, c.Detail = checkFail, err.Error()
+		c.Fix = "check that www.googleapis.com can be reached through your network and proxy"
+	}
+	return c
+}
+
+// folderCheck checks that ref is a folder that email can list and add to,
+// reporting it as name.
+func folderCheck(ctx context.Context, c *drive.Client, name, ref, email string) check {
+	ck := check{Name: name, Status: checkFail}
+	id, err := resolveFolder(ctx, c, ref)
+	if err != nil {
+		ck.Detail, ck.Fix = err.Error(), "give the folder's ID, its Drive URL, or its path from My Drive"
+		return ck
+	}
+	f, err := c.GetFile(ctx, id, "id", "name", "mimeType", "trashed", "capabilities(canListChildren,canAddChildren)")
+	switch {
+	case errors.Is(err, drive.ErrNotFound):
+		ck.Detail = "not found, or not shared with " + email
+		ck.Fix = "check the ID, and share the folder with " + email
+	case err != nil:
+		ck.Detail = err.Error()
+	case !f.IsFolder():
+		ck.Detail = fmt.Sprintf("%q is not a folder but %s", f.Name, f.MimeType)
+		ck.Fix = "give the ID of the folder that contains it"
+	case f.Trashed:
+		ck.Detail = fmt.Sprintf("%q is in the trash", f.Name)
+		ck.Fix = "restore it from the trash"
+	case !f.Capabilities["canListChildren"]:
+		ck.Detail = fmt.Sprintf("%q cannot be read by %s", f.Name, email)
+		ck.Fix = "ask its owner to share it with " + email
+	case !f.Capabilities["canAddChildren"]:
+		ck.Detail = fmt.Sprintf("%q is read-only for %s", f.Name, email)
+		ck.Fix = "ask its owner to make " + email + " an editor, or a content manager of its shared drive"
+	default:
+		ck.Status, ck.Detail = checkOK, fmt.Sprintf("%q can be read and written", f.Name)
+	}
+	return ck
+}
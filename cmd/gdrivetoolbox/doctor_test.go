@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	scope := "https://www.googleapis.com/auth/drive"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/tokeninfo":
+			if scope == "" {
+				http.Error(w, `{"error":"invalid_token","error_description":"Invalid Value"}`, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"scope":"` + scope + `","expires_in":"3000"}`))
+		case "/drive/v3/about":
+			w.Write([]byte(`{"user":{"displayName":"Ada","emailAddress":"ada@example.com"}}`))
+		case "/drive/v3/files/pub":
+			w.Write([]byte(`{"id":"pub","name":"Published","mimeType":"application/vnd.google-apps.folder","capabilities":{"canListChildren":true,"canAddChildren":true}}`))
+		case "/drive/v3/files/tmp":
+			w.Write([]byte(`{"id":"tmp","name":"Staging","mimeType":"application/vnd.google-apps.folder","capabilities":{"canListChildren":true}}`))
+		case "/drive/v3/files/arc":
+			w.Write([]byte(`{"id":"arc","name":"Archive","mimeType":"application/vnd.google-apps.folder","capabilities":{"canListChildren":true,"canAddChildren":true}}`))
+		default:
+			http.Error(w, `{"error":{"message":"File not found"}}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(manifest, []byte("folder: pub\ntempFolder: tmp\ndocuments:\n  - {file: mydoc, version: v1}\n"), 0o644)
+	// The folders of the config file are checked too, each folder once.
+	config := filepath.Join(dir, "config.yaml")
+	os.WriteFile(config, []byte("folders:\n  prod: pub\n  old: arc\n  team: team1\ndeploy:\n  archive-folder: old\n"), 0o644)
+	t.Setenv("GDRIVE_CONFIG", config)
+
+	out, err := run(t, "doctor", "--access-token", "tok", "--folder", "gone", "-f", manifest)
+	want := `ok   credentials: got an access token from --access-token
+ok   scopes: drive
+ok   drive api: signed in as Ada <ada@example.com>
+fail folder gone: not found, or not shared with ada@example.com
+     fix: check the ID, and share the folder with ada@example.com
+ok   folder pub: "Published" can be read and written
+fail temp folder tmp: "Staging" is read-only for ada@example.com
+     fix: ask its owner to make ada@example.com an editor, or a content manager of its shared drive
+ok   deploy archive folder old (arc): "Archive" can be read and written
+fail folder team (team1): not found, or not shared with ada@example.com
+     fix: check the ID, and share the folder with ada@example.com
+`
+	if out != want || err == nil || err.Error() != "3 of 8 checks failed" {
+		t.Fatalf("doctor = %v\n%s\nwant:\n%s", err, out, want)
+	}
+
+	scope = "https://www.googleapis.com/auth/spreadsheets"
+	out, err = run(t, "doctor", "--access-token", "tok", "--output", "json")
+	var checks []check
+	if jerr := json.Unmarshal([]byte(out), &checks); jerr != nil || err == nil || len(checks) != 2 ||
+		checks[1].Status != checkFail || !strings.Contains(checks[1].Detail, "no Drive scope") || checks[1].Fix == "" {
+		t.Fatalf("doctor without a Drive scope = %v, %s", err, out)
+	}
+
+	// A token Google rejects needs a new login, not a look at the network.
+	scope = ""
+	out, err = run(t, "doctor", "--access-token", "tok", "--output", "json")
+	checks = nil
+	if jerr := json.Unmarshal([]byte(out), &checks); jerr != nil || err == nil || len(checks) != 2 ||
+		checks[1].Status != checkFail || !strings.HasPrefix(checks[1].Fix, "log in again") {
+		t.Fatalf("doctor with a rejected token = %v, %s", err, out)
+	}
+
+	out, err = run(t, "doctor", "--credentials", filepath.Join(dir, "none.json"))
+	if err == nil || !strings.HasPrefix(out, "fail credentials: no credentials\n     fix: run \"gdrivetoolbox auth login\"") {
+		t.Fatalf("doctor without credentials = %v, %q", err, out)
+	}
+}
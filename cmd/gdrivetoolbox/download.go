@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// exportTypes maps the extensions accepted by download --format to the
+// MIME types Drive exports.
+var exportTypes = map[string]string{
+	"pdf":  "application/pdf",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"odt":  "application/vnd.oasis.opendocument.text",
+	"ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	"odp":  "application/vnd.oasis.opendocument.presentation",
+	"rtf":  "application/rtf",
+	"txt":  "text/plain",
+	"md":   "text/markdown",
+	"html": "text/html",
+	"csv":  "text/csv",
+	"tsv":  "text/tab-separated-values",
+	"epub": "application/epub+zip",
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"svg":  "image/svg+xml",
+	"json": "application/vnd.google-apps.script+json",
+}
+
+// downloaded is download's result: the Drive file, where it was saved,
+// and what it took.
+type downloaded struct {
+	ID    string      `json:"id"`
+	Name  string      `json:"name"`
+	Path  string      `json:"path"`
+	Stats drive.Stats `json:"stats"`
+}
+
+func newDownloadCmd(a *app) *cobra.Command {
+	var dest, format string
+	var noProgress bool
+	cmd := &cobra.Command{
+		Use:   "download FILE [-o DEST]",
+		Short: "Download a file, exporting Google Docs, Sheets, and Slides",
+		Long: `Download saves FILE, a file ID, a Drive URL, or a path from My Drive such
+as /Reports/q1.pdf, to DEST, which defaults to the file's name in the
+current directory and may be a directory.
+
+Google-native files are exported, by default as Office documents; --format
+picks another type by extension (pdf, csv, ...) or MIME type. Other files
+are saved through DEST.part, and a rerun after an interruption resumes
+from where that left off.
+
+A DEST of - writes the file to standard output instead, for piping into
+other tools; it is still checked against Drive's MD5, but cannot resume.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			id, err := resolveFile(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			f, err := c.GetFile(ctx, id, "id", "name", "mimeType", "size", "md5Checksum")
+			if err != nil {
+				return err
+			}
+			if f.IsFolder() {
+				return fmt.Errorf("%s is a folder", f.Name)
+			}
+			name := f.Name
+			var exportType string
+			if drive.IsNative(f.MimeType) {
+				exportType, name, err = exportTarget(f, format)
+				if err != nil {
+					return err
+				}
+			}
+			var prog *transfers
+			if !noProgress && !a.quiet {
+				prog = newTransfers(cmd.ErrOrStderr())
+			}
+			prog.expect(1, f.Size)
+			if dest == "-" {
+				c.OnProgress = prog.track(name)
+				err := downloadStream(ctx, c, f, exportType, cmd.OutOrStdout())
+				prog.finish()
+				return err
+			}
+			target, err := downloadTarget(dest, name)
+			if err != nil {
+				return err
+			}
+			c.OnProgress = prog.track(filepath.Base(target))
+			var stats drive.Stats
+			start := time.Now()
+			ctx = drive.WithStats(ctx, &stats)
+			if exportType != "" {
+				stats.Bytes, err = exportFile(ctx, c, f.ID, exportType, target)
+			} else {
+				stats.Bytes, err = downloadFile(ctx, c, f, target)
+			}
+			stats.Elapsed = time.Since(start)
+			prog.finish()
+			if err != nil {
+				return err
+			}
+			res := downloaded{ID: f.ID, Name: f.Name, Path: target, Stats: stats}
+			return writeResult(a, cmd, res, func(w io.Writer, d downloaded) {
+				fmt.Fprintln(w, d.Path)
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&dest, "dest", "o", "", "destination file or directory, or - for standard output")
+	f.StringVar(&format, "format", "", "export format for Google-native files, as an extension or MIME type")
+	f.BoolVar(&noProgress, "no-progress", false, "do not report transfer progress")
+	return cmd
+}
+
+// exportTarget returns the MIME type to export f as and the file name to
+// save it under.
+func exportTarget(f drive.File, format string) (mimeType, name string, err error) {
+	if format == "" {
+		def, ok := drive.DefaultExportFormats[f.MimeType]
+		if !ok {
+			return "", "", fmt.Errorf("%s cannot be downloaded without --format", f.MimeType)
+		}
+		return def.MimeType, withExt(f.Name, def.Extension), nil
+	}
+	if strings.Contains(format, "/") {
+		return format, f.Name, nil
+	}
+	ext := strings.ToLower(strings.TrimPrefix(format, "."))
+	mimeType, ok := exportTypes[ext]
+	if !ok {
+		return "", "", fmt.Errorf("unknown export format %q", format)
+	}
+	return mimeType, withExt(f.Name, "."+ext), nil
+}
+
+// withExt appends ext to name unless it already ends with it.
+func withExt(name, ext string) string {
+	if strings.EqualFold(filepath.Ext(name), ext) {
+		return name
+	}
+	return name + ext
+}
+
+// downloadTarget returns where to save a file called name: dest itself,
+// or name inside dest if that is a directory.
+func downloadTarget(dest, name string) (string, error) {
+	name = filepath.Base(filepath.FromSlash(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("cannot save a file named %q", name)
+	}
+	if dest == "" {
+		return name, nil
+	}
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		return filepath.Join(dest, name), nil
+	}
+	return dest, nil
+}
+
+// downloadFile saves f to target through target.part, appending to any
+// part left by an earlier attempt, and checks the result against Drive's
+// MD5 before putting it in place. It returns the bytes downloaded, which
+// do not include those of the earlier attempt.
+func downloadFile(ctx context.Context, c *drive.Client, f drive.File, target string) (int64, error) {
+	part := target + ".part"
+	var offset int64
+	if info, err := os.Stat(part); err == nil && info.Size() <= f.Size {
+		offset = info.Size()
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.DownloadFrom(ctx, f.ID, offset, out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// The part is kept so a rerun can resume.
+		return n, err
+	}
+	if f.MD5 != "" {
+		sum, err := fileMD5(part)
+		if err != nil {
+			return n, err
+		}
+		if sum != f.MD5 {
+			os.Remove(part)
+			return n, errors.New("checksum mismatch: the file changed in Drive or the download was corrupted; run again to start over")
+		}
+	}
+	return n, os.Rename(part, target)
+}
+
+// downloadStream writes f to w, exported as exportType if that is set.
+// Downloads are checked against Drive's MD5 once written.
+func downloadStream(ctx context.Context, c *drive.Client, f drive.File, exportType string, w io.Writer) error {
+	if exportType != "" {
+		_, err := c.Export(ctx, f.ID, exportType, w)
+		return err
+	}
+	h := md5.New()
+	if _, err := c.Download(ctx, f.ID, io.MultiWriter(w, h)); err != nil {
+		return err
+	}
+	if f.MD5 != "" && hex.EncodeToString(h.Sum(nil)) != f.MD5 {
+		return errors.New("checksum mismatch: the file changed in Drive or the download was corrupted")
+	}
+	return nil
+}
+
+// exportFile exports a Google-native file to target, returning its size.
+// Exports have no fixed size to resume against, so they always start over.
+func exportFile(ctx context.Context, c *drive.Client, id, mimeType, target string) (int64, error) {
+	part := target + ".part"
+	out, err := os.Create(part)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.Export(ctx, id, mimeType, out)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(part)
+		return n, err
+	}
+	return n, os.Rename(part, target)
+}
+
+func fileMD5(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+	const content = "0123456789"
+	sum := md5.Sum([]byte(content))
+	var ranges []string
+	var exported string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/drive/v3/files/bin" && r.URL.Query().Get("alt") == "media":
+			ranges = append(ranges, r.Header.Get("Range"))
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+		case r.URL.Path == "/drive/v3/files/bin":
+			fmt.Fprintf(w, `{"id":"bin","name":"data.bin","mimeType":"application/octet-stream","size":"10","md5Checksum":%q}`, hex.EncodeToString(sum[:]))
+		case r.URL.Path == "/drive/v3/files/doc":
+			w.Write([]byte(`{"id":"doc","name":"Plan","mimeType":"application/vnd.google-apps.document"}`))
+		case r.URL.Path == "/drive/v3/files/doc/export":
+			exported = r.URL.Query().Get("mimeType")
+			w.Write([]byte("exported"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+	dir := t.TempDir()
+
+	// Resume from a part left by an interrupted run.
+	target := filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(target+".part", []byte("0123"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(t, "download", "--access-token", "tok", "--no-progress", "bin", "-o", dir)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	if strings.TrimSpace(out) != target {
+		t.Fatalf("output = %q, want %q", out, target)
+	}
+	if b, _ := os.ReadFile(target); string(b) != content {
+		t.Fatalf("content = %q", b)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=4-" {
+		t.Fatalf("ranges = %q, want a resume from byte 4", ranges)
+	}
+	if _, err := os.Stat(target + ".part"); !os.IsNotExist(err) {
+		t.Fatal("part file left behind")
+	}
+
+	// A DEST of - is standard output.
+	out, err = run(t, "download", "--access-token", "tok", "--no-progress", "bin", "-o", "-")
+	if err != nil || out != content {
+		t.Fatalf("download to stdout = %q, %v", out, err)
+	}
+
+	// A corrupt part is detected and discarded.
+	if err := os.WriteFile(target+".part", []byte("xxxx"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(t, "download", "--access-token", "tok", "--no-progress", "bin", "-o", dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("corrupt part: err = %v", err)
+	}
+	if _, err := os.Stat(target + ".part"); !os.IsNotExist(err) {
+		t.Fatal("corrupt part file kept")
+	}
+
+	// Native files are exported, by default as Office documents.
+	for _, tt := range []struct{ format, want string }{
+		{"", "Plan.docx"},
+		{"pdf", "Plan.pdf"},
+	} {
+		format, want := tt.format, tt.want
+		args := []string{"download", "--access-token", "tok", "--no-progress", "doc", "-o", dir}
+		if format != "" {
+			args = append(args, "--format", format)
+		}
+		if _, err := run(t, args...); err != nil {
+			t.Fatalf("export %q: %v", format, err)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, want)); string(b) != "exported" {
+			t.Fatalf("export %q: %s = %q", format, want, b)
+		}
+	}
+	if exported != "application/pdf" {
+		t.Fatalf("last export type = %q", exported)
+	}
+	if _, err := run(t, "download", "--access-token", "tok", "doc", "--format", "bogus"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestDownloadStats(t *testing.T) {
+	defer func(b func(int) time.Duration) { retryBackoff = b }(retryBackoff)
+	retryBackoff = func(int) time.Duration { return 0 }
+	busy := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("alt") == "media" && busy:
+			busy = false
+			http.Error(w, "backend error", http.StatusServiceUnavailable)
+		case r.URL.Query().Get("alt") == "media":
+			w.Write([]byte("0123456789"))
+		default:
+			w.Write([]byte(`{"id":"bin","name":"data.bin","mimeType":"application/octet-stream","size":"10"}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	out, err := run(t, "download", "--access-token", "tok", "--no-progress", "bin", "-o", t.TempDir(), "--output", "json")
+	if err != nil {
+		t.Fatalf("download: %v\n%s", err, out)
+	}
+	var res struct{ Stats map[string]float64 }
+	if err := json.Unmarshal([]byte(out), &res); err != nil || res.Stats["bytes"] != 10 || res.Stats["retries"] != 1 {
+		t.Fatalf("result = %s (%v)", out, err)
+	}
+	if _, ok := res.Stats["bytesPerSecond"]; !ok {
+		t.Errorf("no throughput in %s", out)
+	}
+
+	busy = true
+	installTestClient(t, srv) // drop the first run's retries
+	if _, err := run(t, "download", "--access-token", "tok", "--no-progress", "bin", "-o", t.TempDir(), "--retries", "0"); err == nil {
+		t.Fatal("download with --retries 0 did not fail on a 503")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Exit codes, so that scripts can tell failures apart without parsing
+// stderr.
+const (
+	exitFailure  = 1 // anything not covered below
+	exitUsage    = 2 // bad flags, arguments, config, or manifest
+	exitAuth     = 3 // missing or rejected credentials, or no permission
+	exitNotFound = 4 // a file or folder does not exist
+	exitLimited  = 5 // rate limited, or out of storage quota
+	exitPartial  = 6 // some items of a batch failed, the rest succeeded
+	exitConflict = 7 // a file changed since it was read
+)
+
+// errAuth marks failures to get an access token.
+var errAuth = errors.New("authentication failed")
+
+// errNoCredentials is the failure to get an access token when none of the
+// ways to get one is set up.
+var errNoCredentials = fmt.Errorf("%w: no credentials: run \"gdrivetoolbox auth login\", or set --access-token, or --client-id, --client-secret, and --refresh-token", errAuth)
+
+// usageError is a mistake in how the command was called.
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// exitCode maps err to the exit code for its class. A partial batch wins
+// over the class of its first failure, which Unwrap would expose.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, drive.ErrPartialFailure):
+		return exitPartial
+	case errors.As(err, &usage), errors.Is(err, deploy.ErrInvalidManifest):
+		return exitUsage
+	case errors.Is(err, errAuth), errors.Is(err, auth.ErrNoCredentials),
+		errors.Is(err, drive.ErrUnauthorized), errors.Is(err, drive.ErrInsufficientPermission):
+		return exitAuth
+	case errors.Is(err, drive.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, drive.ErrRateLimited), errors.Is(err, drive.ErrQuotaExceeded):
+		return exitLimited
+	case errors.Is(err, drive.ErrConflict):
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// markUsageErrors makes the flag and argument errors of cmd and its
+// subcommands usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return usageError{err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestExitCode(t *testing.T) {
+	notFound := &drive.APIError{StatusCode: 404}
+	for _, tt := range []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), exitFailure},
+		{usageErrorf("bad"), exitUsage},
+		{fmt.Errorf("load: %w", deploy.ErrInvalidManifest), exitUsage},
+		{fmt.Errorf("%w: expired", errAuth), exitAuth},
+		{&drive.APIError{StatusCode: 401}, exitAuth},
+		{&drive.PermissionError{}, exitAuth},
+		{fmt.Errorf("resolve: %w", notFound), exitNotFound},
+		{&drive.APIError{StatusCode: 429}, exitLimited},
+		{fmt.Errorf("upload: %w", drive.ErrQuotaExceeded), exitLimited},
+		{&drive.ConflictError{FileID: "f1"}, exitConflict},
+		{&drive.APIError{StatusCode: 412}, exitConflict},
+		{&drive.BatchError{Failed: 1, Total: 2, First: notFound}, exitPartial},
+		{&drive.BatchError{Failed: 2, Total: 2, First: notFound}, exitNotFound},
+	} {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodeFromCommands(t *testing.T) {
+	sampleTree(t)
+	t.Setenv("GDRIVE_ACCESS_TOKEN", "")
+	t.Setenv("GDRIVE_CLIENT_ID", "")
+	t.Setenv("GDRIVE_CREDENTIALS", filepath.Join(t.TempDir(), "none.json"))
+
+	for _, tt := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"ls", "--bogus"}, exitUsage},
+		{[]string{"mkdir"}, exitUsage},
+		{[]string{"deploy", "--file", "x"}, exitUsage},
+		{[]string{"ls", "rep"}, exitAuth},
+		{[]string{"rm", "/Reports/nothing.pdf", "--access-token", "tok"}, exitNotFound},
+	} {
+		_, err := run(t, tt.args...)
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("%v: exit %d (%v), want %d", tt.args, got, err, tt.want)
+		}
+	}
+}
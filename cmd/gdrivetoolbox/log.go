@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/spf13/cobra"
+)
+
+// logLevel is the level set by --log-level, or else by --quiet and
+// --verbose: warnings by default, only errors with --quiet, info with -v,
+// and debug with -vv.
+func (a *app) logLevel() (slog.Level, error) {
+	set := 0
+	for _, on := range []bool{a.quiet, a.verbose > 0, a.logLevelName != ""} {
+		if on {
+			set++
+		}
+	}
+	if set > 1 {
+		return 0, errors.New("use only one of --quiet, --verbose, and --log-level")
+	}
+	switch {
+	case a.logLevelName != "":
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(a.logLevelName)); err != nil {
+			return 0, fmt.Errorf("bad --log-level: %w", err)
+		}
+		return l, nil
+	case a.quiet:
+		return slog.LevelError, nil
+	case a.verbose == 1:
+		return slog.LevelInfo, nil
+	case a.verbose > 1:
+		return slog.LevelDebug, nil
+	}
+	return slog.LevelWarn, nil
+}
+
+// setupLogging points the command's logger and the library loggers at w,
+// filtered by logLevel.
+func (a *app) setupLogging(w io.Writer) error {
+	level, err := a.logLevel()
+	if err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if a.ci {
+		// Every line starts with the time already.
+		opts.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return attr
+		}
+	}
+	a.logger = slog.New(slog.NewTextHandler(w, opts))
+	deploy.Logger = a.logger
+	return nil
+}
+
+// setupCorrelation gives the command's context the correlation ID set by
+// --correlation-id, or a new one, so that every request it makes carries
+// it, and notes it on the command's log entries. The library loggers are
+// left alone: they note the ID of the context they are given themselves.
+func (a *app) setupCorrelation(cmd *cobra.Command) {
+	if a.correlation == "" {
+		a.correlation = drive.NewCorrelationID()
+	}
+	cmd.SetContext(drive.WithCorrelationID(cmd.Context(), a.correlation))
+	a.logger = a.logger.With("correlation_id", a.correlation)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		a    app
+		want slog.Level
+	}{
+		{app{}, slog.LevelWarn},
+		{app{quiet: true}, slog.LevelError},
+		{app{verbose: 1}, slog.LevelInfo},
+		{app{verbose: 3}, slog.LevelDebug},
+		{app{logLevelName: "DEBUG"}, slog.LevelDebug},
+		{app{logLevelName: "info+2"}, slog.LevelInfo + 2},
+	} {
+		if got, err := tt.a.logLevel(); err != nil || got != tt.want {
+			t.Errorf("%+v: level %v, %v; want %v", tt.a, got, err, tt.want)
+		}
+	}
+	if _, err := (&app{logLevelName: "loud"}).logLevel(); err == nil {
+		t.Error("expected an error for --log-level loud")
+	}
+	if _, err := run(t, "ls", "-q", "-v", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "only one of") {
+		t.Errorf("-q -v: err = %v", err)
+	}
+}
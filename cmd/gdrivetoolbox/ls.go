@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// typeAliases are the short names ls --type accepts for Google types.
+var typeAliases = map[string]string{
+	"folder":       drive.FolderMimeType,
+	"shortcut":     drive.ShortcutMimeType,
+	"document":     "application/vnd.google-apps.document",
+	"spreadsheet":  "application/vnd.google-apps.spreadsheet",
+	"presentation": "application/vnd.google-apps.presentation",
+	"form":         "application/vnd.google-apps.form",
+	"drawing":      "application/vnd.google-apps.drawing",
+	"pdf":          "application/pdf",
+}
+
+// lsEntry is one listed item; Path is relative to the listed folder.
+type lsEntry struct {
+	Path string `json:"path"`
+	drive.File
+}
+
+func newLsCmd(a *app) *cobra.Command {
+	var long, recursive bool
+	var name, mimeType string
+	cmd := &cobra.Command{
+		Use:   "ls [FOLDER]",
+		Short: "List a folder",
+		Long: `Ls lists FOLDER, a folder ID, a Drive URL, or a path from My Drive such as
+/Reports, defaulting to My Drive itself. Folders are shown with a trailing
+slash.
+
+--name filters by a glob on the item's name, and --type by MIME type: an
+exact type, a family such as image/*, or one of folder, shortcut, document,
+spreadsheet, presentation, form, drawing, and pdf. With --recursive,
+filters apply to what is shown; every subfolder is still searched.
+
+With --output json or ndjson, each item is printed with its path and the
+Drive metadata of the long listing.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := path.Match(name, ""); err != nil {
+				return fmt.Errorf("bad --name pattern: %w", err)
+			}
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			ref := "root"
+			if len(args) == 1 {
+				ref = args[0]
+			}
+			id, err := resolveFolder(ctx, c, ref)
+			if err != nil {
+				return err
+			}
+
+			keep := func(f drive.File) bool {
+				if name != "" {
+					if ok, _ := path.Match(name, f.Name); !ok {
+						return false
+					}
+				}
+				return mimeType == "" || matchType(mimeType, f.MimeType)
+			}
+			var entries []lsEntry
+			if recursive {
+				err = list.Walk(ctx, c, id, func(p string, f drive.File) error {
+					if keep(f) {
+						entries = append(entries, lsEntry{Path: p, File: f})
+					}
+					return nil
+				})
+			} else {
+				var children []drive.File
+				children, err = list.Children(ctx, c, id)
+				for _, f := range children {
+					if keep(f) {
+						entries = append(entries, lsEntry{Path: f.Name, File: f})
+					}
+				}
+				sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+			}
+			if err != nil {
+				return err
+			}
+
+			return writeResults(a, cmd, entries, func(w io.Writer, entries []lsEntry) error {
+				if long {
+					return writeLong(w, entries)
+				}
+				for _, e := range entries {
+					fmt.Fprintln(w, displayPath(e))
+				}
+				return nil
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&long, "long", "l", false, "show ID, size, modification time, and type")
+	f.BoolVarP(&recursive, "recursive", "R", false, "list subfolders too")
+	f.StringVar(&name, "name", "", "only show items whose name matches this glob")
+	f.StringVar(&mimeType, "type", "", "only show items of this MIME type, family, or alias")
+	return cmd
+}
+
+// matchType reports whether mimeType satisfies the --type filter want.
+func matchType(want, mimeType string) bool {
+	if alias, ok := typeAliases[want]; ok {
+		want = alias
+	}
+	if family, ok := strings.CutSuffix(want, "/*"); ok {
+		return strings.HasPrefix(mimeType, family+"/")
+	}
+	return mimeType == want
+}
+
+func displayPath(e lsEntry) string {
+	if e.IsFolder() {
+		return e.Path + "/"
+	}
+	return e.Path
+}
+
+// writeLong prints one aligned row per entry.
+func writeLong(w io.Writer, entries []lsEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		size := "-"
+		if !e.IsFolder() && !drive.IsNative(e.MimeType) {
+			size = formatBytes(e.Size)
+		}
+		modified := "-"
+		if !e.ModifiedTime.IsZero() {
+			modified = e.ModifiedTime.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.ID, size, modified, e.MimeType, displayPath(e))
+	}
+	return tw.Flush()
+}
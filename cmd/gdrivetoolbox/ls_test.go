@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// addFile stores a file in s under parent, with its name for content; a
+// name ending in "/" makes a folder.
+func addFile(s *drivetest.Server, id, parent, name, mimeType string) {
+	f := drive.File{ID: id, Name: name, MimeType: mimeType, Parents: []string{parent},
+		ModifiedTime: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	content := []byte(name)
+	if strings.HasSuffix(name, "/") {
+		f.Name, f.MimeType, content = strings.TrimSuffix(name, "/"), drive.FolderMimeType, nil
+	}
+	s.Add(f, content)
+}
+
+// newTree starts an empty fake Drive that the CLI's requests go to.
+func newTree(t *testing.T) *drivetest.Server {
+	t.Helper()
+	s := drivetest.NewServer()
+	t.Cleanup(s.Close)
+	orig := http.DefaultClient
+	http.DefaultClient = s.HTTPClient()
+	t.Cleanup(func() { http.DefaultClient = orig })
+	return s
+}
+
+// sampleTree is My Drive with a Reports folder holding a PDF, a Google Doc,
+// and an archive folder with an image.
+func sampleTree(t *testing.T) *drivetest.Server {
+	s := newTree(t)
+	addFile(s, "root", "", "My Drive/", "")
+	addFile(s, "rep", "root", "Reports/", "")
+	addFile(s, "arc", "rep", "archive/", "")
+	addFile(s, "img", "arc", "chart.png", "image/png")
+	addFile(s, "old", "arc", "q0.pdf", "application/pdf")
+	addFile(s, "plan", "rep", "Plan", "application/vnd.google-apps.document")
+	addFile(s, "q1", "rep", "q1.pdf", "application/pdf")
+	return s
+}
+
+func TestLs(t *testing.T) {
+	sampleTree(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ls"}, "Reports/\n"},
+		{[]string{"ls", "/Reports"}, "Plan\narchive/\nq1.pdf\n"},
+		// Recursive listings keep the walk order: the fake lists in the order added.
+		{[]string{"ls", "rep", "-R"}, "archive/\narchive/chart.png\narchive/q0.pdf\nPlan\nq1.pdf\n"},
+		{[]string{"ls", "rep", "-R", "--name", "*.pdf"}, "archive/q0.pdf\nq1.pdf\n"},
+		{[]string{"ls", "rep", "-R", "--type", "image/*"}, "archive/chart.png\n"},
+		{[]string{"ls", "rep", "--type", "folder"}, "archive/\n"},
+	}
+	for _, tt := range tests {
+		out, err := run(t, append(tt.args, "--access-token", "tok")...)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if out != tt.want {
+			t.Errorf("%v:\n got %q\nwant %q", tt.args, out, tt.want)
+		}
+	}
+
+	out, err := run(t, "ls", "rep", "--output", "json", "--name", "q1*", "--access-token", "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []lsEntry
+	if err := json.Unmarshal([]byte(out), &got); err != nil || len(got) != 1 || got[0].ID != "q1" || got[0].Path != "q1.pdf" {
+		t.Fatalf("--output json = %+v, %v\n%s", got, err, out)
+	}
+
+	out, err = run(t, "ls", "rep", "-l", "--access-token", "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("-l:\n%s", out)
+	}
+	// ID, size, date, time (local), type, name.
+	if f := strings.Fields(lines[2]); len(f) != 7 || f[0] != "q1" || f[1]+" "+f[2] != "6 B" || f[5] != "application/pdf" || f[6] != "q1.pdf" {
+		t.Fatalf("-l row = %q", lines[2])
+	}
+}
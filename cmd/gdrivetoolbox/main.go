@@ -0,0 +1,254 @@
+// Command gdrivetoolbox exposes the toolbox on the command line, so shell
+// pipelines can deploy and manage Drive files without embedding Go snippets
+// or curl scripts.
+//
+// Credentials come from --access-token, or from --client-id,
+// --client-secret, and --refresh-token, which are exchanged for an access
+// token. Without any of them the credentials saved by "auth login" are used.
+//
+// Any flag not given on the command line is read from its GDRIVE_*
+// environment variable (--temp-folder from GDRIVE_TEMP_FOLDER), then from
+// the config file; see config.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+	"github.com/hwalton/gdrivetoolbox/cloudlogging"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/metrics"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+func main() {
+	// Ctrl-C and SIGTERM, as from a CI timeout, cancel the command, so it
+	// can clean up after itself; a second one kills it at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	root := newRootCmd()
+	err := root.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		reportError(root, err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// app holds the global flags shared by every subcommand.
+type app struct {
+	accessToken  string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	credentials  string
+	configPath   string
+	profile      string
+	output       string
+	quiet        bool
+	verbose      int
+	logLevelName string
+	noColor      bool
+	auditLog     string
+	cloudLogging string
+	cloudLabels  map[string]string
+	retries      int
+	throttle     bool
+	strict       bool
+	notifyURL    string
+	notifyEnv    string
+	sheet        string
+	sheetTab     string
+	emailTo      []string
+	emailTmpl    string
+	changelog    string
+	index        bool
+	verify       time.Duration
+	ci           bool
+	correlation  string
+	deploys      *ciDeploys         // under --ci
+	metrics      *metrics.Collector // in serve and watch
+	quota        *drive.QuotaTracker
+	cloudLog     *cloudlogging.Sink
+	logger       *slog.Logger
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+	root := &cobra.Command{
+		Use:           "gdrivetoolbox",
+		Short:         "Deploy and manage Google Drive files",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := a.bind(cmd); err != nil {
+				return usageError{err}
+			}
+			a.setupCI(cmd)
+			if err := a.setupLogging(cmd.ErrOrStderr()); err != nil {
+				return usageError{err}
+			}
+			a.setupCorrelation(cmd)
+			if err := checkOutput(a.output); err != nil {
+				return usageError{err}
+			}
+			a.setupQuota()
+			a.setupRetries()
+			if err := a.setupCloudLogging(cmd); err != nil {
+				return err
+			}
+			a.setupAudit()
+			// Cobra checks required flags only after this hook; checking
+			// here as well marks a missing one as a usage error.
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return usageError{err}
+			}
+			return a.setupNotify(cmd)
+		},
+	}
+	f := root.PersistentFlags()
+	f.StringVar(&a.accessToken, "access-token", "", "OAuth2 access token (env GDRIVE_ACCESS_TOKEN)")
+	f.StringVar(&a.clientID, "client-id", "", "OAuth2 client ID (env GDRIVE_CLIENT_ID)")
+	f.StringVar(&a.clientSecret, "client-secret", "", "OAuth2 client secret (env GDRIVE_CLIENT_SECRET)")
+	f.StringVar(&a.refreshToken, "refresh-token", "", "OAuth2 refresh token (env GDRIVE_REFRESH_TOKEN)")
+	f.StringVar(&a.credentials, "credentials", "", "credentials file written by auth login (env GDRIVE_CREDENTIALS)")
+	f.BoolVarP(&a.quiet, "quiet", "q", false, "log only errors and hide progress")
+	f.CountVarP(&a.verbose, "verbose", "v", "log what is done (-v), and how (-vv)")
+	f.StringVar(&a.logLevelName, "log-level", "", "log level: debug, info, warn, or error (default warn)")
+	f.StringVar(&a.output, "output", outputTable, "result format: table, json, or ndjson")
+	f.BoolVar(&a.noColor, "no-color", false, "do not color output (also NO_COLOR)")
+	f.BoolVar(&a.ci, "ci", false, "write terse, timestamped lines and end with a summary, for CI logs (env GDRIVE_CI)")
+	f.StringVar(&a.correlation, "correlation-id", "", "ID sent as X-Request-Id and logged with everything the command does (env GDRIVE_CORRELATION_ID, default random)")
+	f.IntVar(&a.retries, "retries", 3, "repeat requests up to this many times after transient failures (env GDRIVE_RETRIES)")
+	f.BoolVar(&a.throttle, "throttle", false, "slow down to stay within Drive's per-user rate limits instead of failing (env GDRIVE_THROTTLE)")
+	f.BoolVar(&a.strict, "strict", false, "fail deploys when a best-effort step, such as restricting sharing of the upload, fails (env GDRIVE_STRICT)")
+	f.StringVar(&a.auditLog, "audit-log", "", "append every change made in Drive to this file as JSON lines (env GDRIVE_AUDIT_LOG)")
+	f.StringVar(&a.cloudLogging, "cloud-logging", "", "ship deploy events and audit entries to Cloud Logging in this Google Cloud project (env GDRIVE_CLOUD_LOGGING)")
+	f.StringToStringVar(&a.cloudLabels, "cloud-logging-label", nil, "label added to every Cloud Logging entry, as key=value")
+	f.StringVar(&a.profile, "profile", "", "profile to use (env GDRIVE_PROFILE, default set by profile use)")
+	f.StringVar(&a.configPath, "config", "", "config file (env GDRIVE_CONFIG, default ~/.config/gdrivetoolbox/config.yaml)")
+
+	root.AddCommand(newAuthCmd(a))
+	root.AddCommand(newDoctorCmd(a))
+	root.AddCommand(newProfileCmd(a))
+	root.AddCommand(newDeployCmd(a))
+	root.AddCommand(newRollbackCmd(a))
+	root.AddCommand(newVersionsCmd(a))
+	root.AddCommand(newPlanCmd(a))
+	root.AddCommand(newApplyCmd(a))
+	root.AddCommand(newWatchCmd(a))
+	root.AddCommand(newServeCmd(a))
+	root.AddCommand(newUploadCmd(a))
+	root.AddCommand(newDownloadCmd(a))
+	root.AddCommand(newLsCmd(a))
+	root.AddCommand(newRmCmd(a))
+	root.AddCommand(newMvCmd(a))
+	root.AddCommand(newCpCmd(a))
+	root.AddCommand(newMkdirCmd(a))
+	markUsageErrors(root)
+	flushCloudLogs(root, a)
+	ciSummaries(root, a)
+	return root
+}
+
+// token returns an access token, exchanging a refresh token for one when
+// no access token is given.
+func (a *app) token() (string, error) {
+	if a.accessToken != "" {
+		return a.accessToken, nil
+	}
+	if a.clientID != "" && a.clientSecret != "" && a.refreshToken != "" {
+		t, err := auth.GetGoogleAccessToken(a.clientID, a.clientSecret, a.refreshToken)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", errAuth, err)
+		}
+		return t, nil
+	}
+	store, err := a.store()
+	if err != nil {
+		return "", err
+	}
+	creds, err := store.Load()
+	if errors.Is(err, auth.ErrNoCredentials) {
+		return "", errNoCredentials
+	}
+	if err != nil {
+		return "", err
+	}
+	t, err := creds.AccessToken()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errAuth, err)
+	}
+	return t, nil
+}
+
+// client returns a Drive client authorized by token.
+func (a *app) client() (*drive.Client, error) {
+	t, err := a.token()
+	if err != nil {
+		return nil, err
+	}
+	return drive.NewClient(t), nil
+}
+
+// resolveFolder returns the folder ID for ref, which is an ID, a Drive URL,
+// or a path from My Drive starting with "/".
+func resolveFolder(ctx context.Context, c *drive.Client, ref string) (string, error) {
+	if strings.HasPrefix(ref, "/") {
+		return folder.NewResolver(c, 0).ResolvePath(ctx, ref)
+	}
+	return drive.ParseID(ref)
+}
+
+// resolveFile is like resolveFolder for a file of any kind; a path names
+// the first untrashed file with that name in its parent folder.
+func resolveFile(ctx context.Context, c *drive.Client, ref string) (string, error) {
+	if !strings.HasPrefix(ref, "/") {
+		return drive.ParseID(ref)
+	}
+	dir, name := path.Split(strings.TrimRight(ref, "/"))
+	if name == "" {
+		return "root", nil
+	}
+	parent, err := resolveFolder(ctx, c, dir)
+	if err != nil {
+		return "", err
+	}
+	f, err := list.First(ctx, c, list.Options{
+		Query:  query.New().InParent(parent).NameEquals(name).NotTrashed().String(),
+		Fields: "id",
+	})
+	if err != nil {
+		return "", fmt.Errorf("resolve %q: %w", ref, err)
+	}
+	return f.ID, nil
+}
+
+// store returns the credentials store named by --credentials, or the one
+// of the selected profile.
+func (a *app) store() (auth.Store, error) {
+	if a.credentials != "" {
+		return auth.Store{Path: a.credentials}, nil
+	}
+	dflt, err := auth.DefaultStore()
+	if err != nil {
+		return auth.Store{}, err
+	}
+	return profileStore(dflt, a.profile), nil
+}
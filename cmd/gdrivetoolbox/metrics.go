@@ -0,0 +1,58 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/metrics"
+)
+
+// collectMetrics counts the Drive API calls made through
+// http.DefaultClient, which all clients of the command use, their
+// retries, and every deploy, returning the Collector to serve them from.
+// Requests to other hosts, such as webhooks, Sheets, Gmail, and OAuth, go
+// through uncounted.
+func (a *app) collectMetrics() *metrics.Collector {
+	m := metrics.New()
+	a.metrics = m
+	hc := *http.DefaultClient
+	base := cmp.Or(hc.Transport, http.DefaultTransport)
+	hc.Transport = driveOnly{drive: m.Transport(base), other: base}
+	http.DefaultClient = &hc
+	deploy.Metrics = m
+	return m
+}
+
+// driveOnly sends the calls of the Drive API through drive, and any other
+// request through other.
+type driveOnly struct {
+	drive, other http.RoundTripper
+}
+
+func (t driveOnly) RoundTrip(req *http.Request) (*http.Response, error) {
+	if drive.APIMethod(req) == "" {
+		return t.other.RoundTrip(req)
+	}
+	return t.drive.RoundTrip(req)
+}
+
+// writeBatchMetrics writes the BatchMetrics of plan to path as JSON, with
+// the API calls m counted.
+func writeBatchMetrics(path string, plan *deploy.DeployPlan, m *metrics.Collector) error {
+	bm := plan.Metrics()
+	sent, retried := m.Requests()
+	bm.APICalls = int64(sent + retried)
+	b, err := json.MarshalIndent(bm, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write metrics file: %w", err)
+	}
+	return nil
+}
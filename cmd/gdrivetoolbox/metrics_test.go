@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestCollectMetricsCountsDriveOnly(t *testing.T) {
+	srv := newTree(t)
+	srv.HandleFunc("POST /v4/", func(w http.ResponseWriter, r *http.Request) {})
+	t.Cleanup(func() { deploy.Metrics = nil })
+
+	m := (&app{}).collectMetrics()
+	if _, err := drive.NewClient("tok").GetFile(context.Background(), srv.AddFolder("Published", "")); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "https://sheets.googleapis.com/v4/spreadsheets/s/values/A1:append", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if sent, _ := m.Requests(); sent != 1 {
+		t.Errorf("counted %d requests, want only the Drive call", sent)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// made is one folder printed by mkdir.
+type made struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+}
+
+func newMkdirCmd(a *app) *cobra.Command {
+	var parents, interactive bool
+	var parent string
+	cmd := &cobra.Command{
+		Use:   "mkdir PATH...",
+		Short: "Create folders",
+		Long: `Mkdir creates each folder PATH below --parent, My Drive by default, and
+prints its ID. Without -p the parent of PATH must already exist and PATH
+must not; with -p every missing folder along the way is created, existing
+ones are reused, and the ID of each folder in the chain is printed.
+With --interactive, the parent is chosen by browsing from --parent.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			parentID, err := resolveFolder(ctx, c, parent)
+			if err != nil {
+				return err
+			}
+			if interactive {
+				if parentID, err = pickFolder(ctx, c, bufio.NewReader(cmd.InOrStdin()), a.messages(cmd), parentID); err != nil {
+					return err
+				}
+			}
+			r := folder.NewResolver(c, 0)
+			p := newPrinter(a, cmd, func(w io.Writer, m made) { fmt.Fprintf(w, "%s\t%s\n", m.ID, m.Path) })
+			defer p.flush()
+			for _, arg := range args {
+				names := strings.FieldsFunc(arg, func(r rune) bool { return r == '/' })
+				if len(names) == 0 {
+					return fmt.Errorf("%q names no folder", arg)
+				}
+				if parents {
+					for i := range names {
+						chain := strings.Join(names[:i+1], "/")
+						id, err := r.EnsureFolderPath(ctx, parentID, chain)
+						if err != nil {
+							return err
+						}
+						p.print(made{ID: id, Path: "/" + chain})
+					}
+					continue
+				}
+				dir, name := path.Split(strings.Join(names, "/"))
+				dirID, err := r.Resolve(ctx, parentID, dir)
+				if err != nil {
+					return fmt.Errorf("%s: %w (use -p to create parents)", arg, err)
+				}
+				if _, err := r.Resolve(ctx, dirID, name); err == nil {
+					return fmt.Errorf("%s already exists", arg)
+				} else if !errors.Is(err, drive.ErrNotFound) {
+					return err
+				}
+				f, err := c.CreateFolder(ctx, name, dirID)
+				if err != nil {
+					return fmt.Errorf("%s: %w", arg, err)
+				}
+				p.print(made{ID: f.ID, Path: "/" + strings.Join(names, "/")})
+			}
+			return p.flush()
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&parents, "parents", "p", false, "create missing parent folders and accept existing ones")
+	f.StringVar(&parent, "parent", "root", "folder ID, URL, or /path that PATH is relative to")
+	f.BoolVarP(&interactive, "interactive", "i", false, "choose the parent folder by browsing")
+	folderFlags(cmd, "parent")
+	return cmd
+}
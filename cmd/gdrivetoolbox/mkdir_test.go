@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMkdir(t *testing.T) {
+	s := sampleTree(t)
+
+	out, err := run(t, "mkdir", "-p", "/Reports/2025/Q1", "--access-token", "tok")
+	if err != nil {
+		t.Fatalf("mkdir -p: %v", err)
+	}
+	want := "rep\t/Reports\nnew1\t/Reports/2025\nnew2\t/Reports/2025/Q1\n"
+	if out != want {
+		t.Fatalf("mkdir -p printed %q, want %q", out, want)
+	}
+	if parentOf(s, "Q1") != "2025" || parentOf(s, "2025") != "Reports" {
+		t.Fatal("folder chain not created")
+	}
+
+	// Running it again reuses the chain.
+	if out, err := run(t, "mkdir", "-p", "/Reports/2025/Q1", "--access-token", "tok"); err != nil || out != want {
+		t.Fatalf("rerun = %q, %v", out, err)
+	}
+
+	if _, err := run(t, "mkdir", "/Reports/2025", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("mkdir existing: err = %v", err)
+	}
+	if _, err := run(t, "mkdir", "/Nope/x", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "-p") {
+		t.Fatalf("mkdir without parent: err = %v", err)
+	}
+	out, err = run(t, "mkdir", "Q2", "--parent", "/Reports/2025", "--access-token", "tok")
+	if err != nil || out != "new3\t/Q2\n" {
+		t.Fatalf("mkdir --parent = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// moved is one item moved by mv. Name is set when it was also renamed.
+type moved struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	Folder string `json:"folder"`
+	Name   string `json:"name,omitempty"`
+}
+
+// copied is the top-level copy cp made of one source.
+type copied struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Folder string `json:"folder"`
+}
+
+func newMvCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "mv SOURCE... DEST",
+		Short: "Move or rename files and folders",
+		Long: `Mv moves each SOURCE into the folder DEST. With a single SOURCE, DEST may
+instead be a path that does not exist yet, in which case SOURCE is moved
+to its parent and renamed, as with the shell's mv. Both sides take IDs,
+Drive URLs, or paths from My Drive.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			srcs, dest := args[:len(args)-1], args[len(args)-1]
+			destID, newName, err := resolveDest(ctx, c, dest, len(srcs) > 1)
+			if err != nil {
+				return err
+			}
+			p := newPrinter(a, cmd, func(w io.Writer, m moved) { fmt.Fprintf(w, "moved %s -> %s\n", m.Source, dest) })
+			defer p.flush()
+			for _, src := range srcs {
+				id, err := resolveFile(ctx, c, src)
+				if err != nil {
+					return err
+				}
+				if newName != "" {
+					if _, err := c.Rename(ctx, id, newName); err != nil {
+						return fmt.Errorf("rename %s: %w", src, err)
+					}
+				}
+				if _, err := c.Move(ctx, id, destID); err != nil {
+					return fmt.Errorf("move %s: %w", src, err)
+				}
+				p.print(moved{Source: src, ID: id, Folder: destID, Name: newName})
+			}
+			return p.flush()
+		},
+	}
+}
+
+func newCpCmd(a *app) *cobra.Command {
+	var recursive bool
+	cmd := &cobra.Command{
+		Use:   "cp SOURCE... DEST",
+		Short: "Copy files and folders",
+		Long: `Cp copies each SOURCE into the folder DEST, or, with a single SOURCE, to a
+new path DEST. Files are copied server-side, without downloading them.
+Folders need --recursive; their tree is recreated and every file in it
+copied. The ID and name of each new top-level copy is printed.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			srcs, dest := args[:len(args)-1], args[len(args)-1]
+			destID, newName, err := resolveDest(ctx, c, dest, len(srcs) > 1)
+			if err != nil {
+				return err
+			}
+			p := newPrinter(a, cmd, func(w io.Writer, cp copied) { fmt.Fprintf(w, "%s\t%s\n", cp.ID, cp.Name) })
+			defer p.flush()
+			for _, src := range srcs {
+				id, err := resolveFile(ctx, c, src)
+				if err != nil {
+					return err
+				}
+				f, err := c.GetFile(ctx, id, "id", "name", "mimeType")
+				if err != nil {
+					return err
+				}
+				name := newName
+				if name == "" {
+					name = f.Name
+				}
+				var copyID string
+				if f.IsFolder() {
+					if !recursive {
+						return fmt.Errorf("%s is a folder; use --recursive", f.Name)
+					}
+					report, err := folder.CopyFolder(ctx, c, f.ID, destID, folder.CopyOptions{Name: name})
+					if err != nil {
+						return fmt.Errorf("copy %s: %w", src, err)
+					}
+					copyID = report.RootID
+				} else {
+					dup, err := c.CopyFile(ctx, f.ID, destID, name)
+					if err != nil {
+						return fmt.Errorf("copy %s: %w", src, err)
+					}
+					copyID = dup.ID
+				}
+				p.print(copied{Source: src, ID: copyID, Name: name, Folder: destID})
+			}
+			return p.flush()
+		},
+	}
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "copy folders and everything in them")
+	return cmd
+}
+
+// resolveDest interprets the destination of mv or cp. An existing folder
+// is returned as is. A path that does not exist yet names a new item in an
+// existing parent folder, which is only allowed for a single source.
+func resolveDest(ctx context.Context, c *drive.Client, dest string, multiple bool) (folderID, newName string, err error) {
+	id, err := resolveFile(ctx, c, dest)
+	if err == nil {
+		f, err := c.GetFile(ctx, id, "id", "name", "mimeType")
+		if err != nil {
+			return "", "", err
+		}
+		if !f.IsFolder() {
+			return "", "", fmt.Errorf("%s already exists and is not a folder", dest)
+		}
+		return f.ID, "", nil
+	}
+	if !errors.Is(err, drive.ErrNotFound) || !strings.HasPrefix(dest, "/") {
+		return "", "", err
+	}
+	if multiple {
+		return "", "", fmt.Errorf("%s is not a folder", dest)
+	}
+	dir, name := path.Split(strings.TrimRight(dest, "/"))
+	folderID, err = resolveFolder(ctx, c, dir)
+	if err != nil {
+		return "", "", err
+	}
+	return folderID, name, nil
+}
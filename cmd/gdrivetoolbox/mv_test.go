@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// parentOf returns the name of the folder holding the first file called
+// name in s.
+func parentOf(s *drivetest.Server, name string) string {
+	for _, f := range s.Files() {
+		if f.Name == name {
+			parent, _ := s.File(f.Parents[0])
+			return parent.Name
+		}
+	}
+	return ""
+}
+
+// fileOf returns the file with id in s.
+func fileOf(s *drivetest.Server, id string) drive.File {
+	f, _ := s.File(id)
+	return f
+}
+
+func TestMv(t *testing.T) {
+	s := sampleTree(t)
+
+	// Into an existing folder, by path and by ID.
+	if _, err := run(t, "mv", "/Reports/q1.pdf", "plan", "/Reports/archive", "--access-token", "tok"); err != nil {
+		t.Fatalf("mv into folder: %v", err)
+	}
+	if fileOf(s, "q1").Parents[0] != "arc" || fileOf(s, "plan").Parents[0] != "arc" {
+		t.Fatalf("parents = %v, %v", fileOf(s, "q1").Parents, fileOf(s, "plan").Parents)
+	}
+	// To a new path: moved and renamed.
+	if _, err := run(t, "mv", "old", "/Reports/q0-final.pdf", "--access-token", "tok"); err != nil {
+		t.Fatalf("mv to new path: %v", err)
+	}
+	if f := fileOf(s, "old"); f.Name != "q0-final.pdf" || f.Parents[0] != "rep" {
+		t.Fatalf("renamed file = %+v", f)
+	}
+
+	if _, err := run(t, "mv", "img", "/Reports/archive/q1.pdf", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("mv onto a file: err = %v", err)
+	}
+	if _, err := run(t, "mv", "img", "old", "/Reports/nowhere", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "not a folder") {
+		t.Fatalf("mv several to a new path: err = %v", err)
+	}
+}
+
+func TestCp(t *testing.T) {
+	s := sampleTree(t)
+
+	out, err := run(t, "cp", "q1", "/Reports/q1-copy.pdf", "--access-token", "tok")
+	if err != nil || !strings.HasSuffix(out, "\tq1-copy.pdf\n") {
+		t.Fatalf("cp file = %q, %v", out, err)
+	}
+	if parentOf(s, "q1-copy.pdf") != "Reports" {
+		t.Fatal("copy not in Reports")
+	}
+
+	if _, err := run(t, "cp", "/Reports/archive", "/", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "--recursive") {
+		t.Fatalf("cp folder without -r: err = %v", err)
+	}
+	if _, err := run(t, "cp", "-r", "/Reports/archive", "/Backup", "--access-token", "tok"); err != nil {
+		t.Fatalf("cp -r: %v", err)
+	}
+	if parentOf(s, "Backup") != "My Drive" {
+		t.Fatalf("tree not copied: %v", s.Files())
+	}
+	copies := 0
+	for _, f := range s.Files() {
+		if f.Name == "chart.png" && fileOf(s, f.Parents[0]).Name == "Backup" {
+			copies++
+		}
+	}
+	if copies != 1 {
+		t.Fatalf("found %d copies of chart.png in Backup", copies)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/notify"
+)
+
+// notifyFlags adds the flags that post the deploys of cmd to a webhook,
+// record them in a sheet, an index, and release notes, and email them. Set in a profile of the config file, they
+// notify each environment's channel of its own deploys.
+func notifyFlags(cmd *cobra.Command, a *app) {
+	f := cmd.Flags()
+	f.StringVar(&a.notifyURL, "notify", "", "Slack or Google Chat webhook URL to post deploy results to (env GDRIVE_NOTIFY)")
+	f.StringVar(&a.notifyEnv, "notify-env", "", "environment named in notifications (default the profile, if not default)")
+	f.StringVar(&a.sheet, "sheet", "", "Google Sheet ID or URL to append a row to for each deploy (env GDRIVE_SHEET)")
+	f.StringVar(&a.sheetTab, "sheet-tab", "", "sheet of --sheet to append to (default the first)")
+	f.StringSliceVar(&a.emailTo, "email-to", nil, "addresses to email through Gmail when a document is published or rolled back (env GDRIVE_EMAIL_TO)")
+	f.StringVar(&a.emailTmpl, "email-template", "", "text/template file of the email, a Subject header, a blank line, and the body")
+	f.BoolVar(&a.index, "index", false, "keep index.json, a catalog of the live documents, in the folder (env GDRIVE_INDEX)")
+	f.StringVar(&a.changelog, "changelog", "", "add each change to release notes of this name in the folder, a Google Doc, or a text file if it ends in .txt (env GDRIVE_CHANGELOG)")
+}
+
+// verifyFlag adds --verify, which makes the deploys of cmd wait until the
+// new version is visible in its folder before it is announced.
+func verifyFlag(cmd *cobra.Command, a *app) {
+	cmd.Flags().DurationVar(&a.verify, "verify", 0, "after each deploy, wait up to this long until the new version is listed in its folder, before notifying (env GDRIVE_VERIFY)")
+}
+
+// notifiers tells each of its notifiers of every deploy.
+type notifiers []interface{ NotifyDeploy(deploy.Result) }
+
+func (ns notifiers) NotifyDeploy(r deploy.Result) {
+	for _, n := range ns {
+		n.NotifyDeploy(r)
+	}
+}
+
+// setupNotify points deploy.Notifier at the --notify webhook, the --sheet,
+// the --index, the --changelog, and the --email-to list, if any, under GitHub Actions at the run's annotations, outputs,
+// and summary, and under --ci at the summary of the command. With --verify,
+// deploys are checked to be visible first, and with --strict, fail when a
+// best-effort step does.
+func (a *app) setupNotify(cmd *cobra.Command) error {
+	deploy.Notifier = nil
+	deploy.Propagation = nil
+	deploy.Strict = a.strict
+	if a.verify > 0 {
+		deploy.Propagation = &deploy.PropagationCheck{Timeout: a.verify}
+	}
+	var ns notifiers
+	env := a.notifyEnv
+	if env == "" && a.profile != defaultProfile {
+		env = a.profile
+	}
+	if a.notifyURL != "" {
+		ns = append(ns, &notify.Webhook{URL: a.notifyURL, Environment: env, OnError: func(err error) {
+			a.logger.Warn("notification not sent", "err", err)
+		}})
+	}
+	if a.sheet != "" {
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		ns = append(ns, &notify.Sheet{Client: c, SpreadsheetID: a.sheet, Tab: a.sheetTab, OnError: func(err error) {
+			a.logger.Warn("deploy not recorded in sheet", "err", err)
+		}})
+	}
+	if a.index {
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		ns = append(ns, &notify.Index{Client: c, OnError: func(err error) {
+			a.logger.Warn("index.json not updated", "err", err)
+		}})
+	}
+	if a.changelog != "" {
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		ns = append(ns, &notify.Changelog{Client: c, Name: a.changelog, OnError: func(err error) {
+			a.logger.Warn("release notes not updated", "err", err)
+		}})
+	}
+	if len(a.emailTo) > 0 {
+		e := &notify.Email{To: a.emailTo, Environment: env, OnError: func(err error) {
+			a.logger.Warn("notification email not sent", "err", err)
+		}}
+		if a.emailTmpl != "" {
+			b, err := os.ReadFile(a.emailTmpl)
+			if err != nil {
+				return usageError{err}
+			}
+			e.Template = string(b)
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		e.Client = c
+		ns = append(ns, e)
+	}
+	if notify.InGitHubActions() {
+		g := notify.NewGitHubActions(a.messages(cmd))
+		g.OnError = func(err error) { a.logger.Warn("GitHub Actions output not written", "err", err) }
+		ns = append(ns, g)
+	}
+	if a.ci {
+		a.deploys = &ciDeploys{}
+		ns = append(ns, a.deploys)
+	}
+	if len(ns) > 0 {
+		deploy.Notifier = ns
+	}
+	return nil
+}
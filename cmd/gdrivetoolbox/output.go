@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats accepted by --output.
+const (
+	outputTable  = "table"  // aligned text for people
+	outputJSON   = "json"   // one JSON document: an array, or an object for single results
+	outputNDJSON = "ndjson" // one JSON object per line, as results come
+)
+
+func checkOutput(format string) error {
+	switch format {
+	case outputTable, outputJSON, outputNDJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown --output %q: want table, json, or ndjson", format)
+}
+
+// machine reports whether output is meant for programs. Prompts and
+// progress notes then go to stderr, leaving stdout to the results.
+func (a *app) machine() bool {
+	return a.output != outputTable
+}
+
+// messages is where a command writes what is not a result: summaries,
+// questions, and notes. That is stdout for tables and stderr otherwise.
+func (a *app) messages(cmd *cobra.Command) io.Writer {
+	if a.machine() {
+		return cmd.ErrOrStderr()
+	}
+	return cmd.OutOrStdout()
+}
+
+// resultPrinter prints a command's results in the --output format as they
+// come: table rows with row and ndjson lines at once, and a json array,
+// which needs all of them, on flush. Deferring flush as well as returning
+// it keeps the array complete when a command fails half way; only the
+// first call writes.
+type resultPrinter[T any] struct {
+	w      io.Writer
+	format string
+	row    func(io.Writer, T)
+	buf    []T
+	done   bool
+}
+
+func newPrinter[T any](a *app, cmd *cobra.Command, row func(io.Writer, T)) *resultPrinter[T] {
+	return &resultPrinter[T]{w: cmd.OutOrStdout(), format: a.output, row: row}
+}
+
+func (p *resultPrinter[T]) print(v T) error {
+	switch p.format {
+	case outputJSON:
+		p.buf = append(p.buf, v)
+		return nil
+	case outputNDJSON:
+		return json.NewEncoder(p.w).Encode(v)
+	}
+	p.row(p.w, v)
+	return nil
+}
+
+func (p *resultPrinter[T]) flush() error {
+	if p.format != outputJSON || p.done {
+		return nil
+	}
+	p.done = true
+	if p.buf == nil {
+		p.buf = []T{}
+	}
+	return writeJSON(p.w, p.buf)
+}
+
+// writeResults prints results all at once, with table for the table
+// format, which may align them.
+func writeResults[T any](a *app, cmd *cobra.Command, results []T, table func(io.Writer, []T) error) error {
+	w := cmd.OutOrStdout()
+	switch a.output {
+	case outputJSON:
+		if results == nil {
+			results = []T{}
+		}
+		return writeJSON(w, results)
+	case outputNDJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return table(w, results)
+}
+
+// writeResult prints the single result of a command; table prints it for
+// the table format.
+func writeResult[T any](a *app, cmd *cobra.Command, result T, table func(io.Writer, T)) error {
+	w := cmd.OutOrStdout()
+	switch a.output {
+	case outputJSON:
+		return writeJSON(w, result)
+	case outputNDJSON:
+		return json.NewEncoder(w).Encode(result)
+	}
+	table(w, result)
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// reportError prints err from running root the way its --output asks: as
+// {"error": "..."} for json and ndjson, so that scripts reading stderr can
+// parse it, and as "Error: ..." otherwise, in red on a terminal.
+func reportError(root *cobra.Command, err error) {
+	w := root.ErrOrStderr()
+	flags := root.PersistentFlags()
+	if f := flags.Lookup("output"); f != nil && f.Value.String() != outputTable {
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	noColor, _ := flags.GetBool("no-color")
+	fmt.Fprintln(w, newPalette(w, noColor).red("Error:"), err)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOutputFormats(t *testing.T) {
+	sampleTree(t)
+
+	out, err := run(t, "mkdir", "-p", "/Reports/2025", "--output", "ndjson", "--access-token", "tok")
+	want := `{"id":"rep","path":"/Reports"}` + "\n" + `{"id":"new1","path":"/Reports/2025"}` + "\n"
+	if err != nil || out != want {
+		t.Fatalf("mkdir --output ndjson = %q, %v", out, err)
+	}
+	out, err = run(t, "ls", "rep", "--name", "nothing*", "--output", "json", "--access-token", "tok")
+	if err != nil || out != "[]\n" {
+		t.Fatalf("empty ls --output json = %q, %v", out, err)
+	}
+	out, err = run(t, "rm", "/Reports/q1.pdf", "--output", "json", "--access-token", "tok")
+	want = "[\n  {\n    \"id\": \"q1\",\n    \"name\": \"q1.pdf\",\n    \"action\": \"trashed\"\n  }\n]\n"
+	if err != nil || out != want {
+		t.Fatalf("rm --output json = %q, %v", out, err)
+	}
+	if _, err := run(t, "ls", "--output", "yaml", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "unknown --output") {
+		t.Fatalf("--output yaml: err = %v", err)
+	}
+}
+
+func TestReportError(t *testing.T) {
+	for _, tt := range []struct{ format, want string }{
+		{"table", "Error: boom\n"},
+		{"ndjson", `{"error":"boom"}` + "\n"},
+	} {
+		root := newRootCmd()
+		var buf bytes.Buffer
+		root.SetErr(&buf)
+		root.PersistentFlags().Set("output", tt.format)
+		reportError(root, errors.New("boom"))
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// errNoFolderPicked is returned when the user leaves the folder picker.
+var errNoFolderPicked = errors.New("no folder chosen")
+
+// pickFolder lets the user browse the folders below startID, reading
+// choices from in and writing the listing to out, and returns the ID of
+// the folder they choose. Folders are opened by number; ".." goes back up,
+// "." chooses the folder shown, and "q" gives up.
+func pickFolder(ctx context.Context, c *drive.Client, in *bufio.Reader, out io.Writer, startID string) (string, error) {
+	start := drive.File{ID: startID, Name: "My Drive"}
+	if startID != "root" {
+		f, err := c.GetFile(ctx, startID, "id", "name")
+		if err != nil {
+			return "", err
+		}
+		start = f
+	}
+	trail := []drive.File{start}
+	for {
+		cur := trail[len(trail)-1]
+		folders, err := list.ListFiles(ctx, c, list.Options{
+			Query:   query.New().InParent(cur.ID).Folders().NotTrashed().String(),
+			Fields:  "id,name",
+			OrderBy: "name",
+		})
+		if err != nil {
+			return "", err
+		}
+		slices.SortFunc(folders, func(a, b drive.File) int { return strings.Compare(a.Name, b.Name) })
+
+		names := make([]string, len(trail))
+		for i, f := range trail {
+			names[i] = f.Name
+		}
+		fmt.Fprintf(out, "\n%s\n", strings.Join(names, "/"))
+		for i, f := range folders {
+			fmt.Fprintf(out, "%4d) %s/\n", i+1, f.Name)
+		}
+		if len(folders) == 0 {
+			fmt.Fprintln(out, "     (no folders)")
+		}
+		fmt.Fprint(out, "Open a folder by number, .. to go up, . to choose this one, q to quit: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(out)
+			return "", errNoFolderPicked
+		}
+		switch answer := strings.TrimSpace(line); answer {
+		case ".":
+			return cur.ID, nil
+		case "..":
+			if len(trail) > 1 {
+				trail = trail[:len(trail)-1]
+			}
+		case "q":
+			return "", errNoFolderPicked
+		default:
+			n, err := strconv.Atoi(answer)
+			if err != nil || n < 1 || n > len(folders) {
+				fmt.Fprintf(out, "No folder %q.\n", answer)
+				continue
+			}
+			trail = append(trail, folders[n-1])
+		}
+	}
+}
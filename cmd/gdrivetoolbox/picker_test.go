@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMkdirInteractive(t *testing.T) {
+	s := sampleTree(t)
+
+	mkdir := func(input string) (string, error) {
+		root := newRootCmd()
+		var out strings.Builder
+		root.SetOut(&out)
+		root.SetErr(&out)
+		root.SetIn(strings.NewReader(input))
+		root.SetArgs([]string{"mkdir", "--access-token", "tok", "-i", "Drafts"})
+		err := root.Execute()
+		return out.String(), err
+	}
+
+	// Open Reports, then archive, mistype, go back up, and choose Reports.
+	out, err := mkdir("1\n1\nx\n..\n.\n")
+	if err != nil {
+		t.Fatalf("mkdir -i: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		"\nMy Drive\n   1) Reports/\n",
+		"\nMy Drive/Reports\n   1) archive/\n",
+		"\nMy Drive/Reports/archive\n     (no folders)\n",
+		`No folder "x".`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("picker output missing %q:\n%s", want, out)
+		}
+	}
+	var created string
+	for _, f := range s.Files() {
+		if f.Name == "Drafts" {
+			created = f.Parents[0]
+			if !strings.HasSuffix(out, f.ID+"\t/Drafts\n") {
+				t.Errorf("output does not end with the new folder:\n%s", out)
+			}
+		}
+	}
+	if created != "rep" {
+		t.Fatalf("Drafts created in %q, want Reports", created)
+	}
+
+	for _, input := range []string{"q\n", ""} {
+		if _, err := mkdir(input); !errors.Is(err, errNoFolderPicked) {
+			t.Errorf("input %q: err = %v, want %v", input, err, errNoFolderPicked)
+		}
+	}
+}
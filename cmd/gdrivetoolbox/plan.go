@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/metrics"
+)
+
+func newPlanCmd(a *app) *cobra.Command {
+	var manifest string
+	cmd := &cobra.Command{
+		Use:   "plan -f MANIFEST",
+		Short: "Show what deploying a manifest would change",
+		Long: `Plan compares every document of a deploy manifest with the live folder and
+prints what apply would do: "+" for documents uploaded for the first time,
+"~" for ones replacing another version, with whether that version is
+archived or deleted, and an indented line for ones already up to date.
+On a terminal the lines are colored; --no-color or NO_COLOR turns that
+off. Nothing is changed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := planManifest(cmd, a, manifest)
+			if err != nil {
+				return err
+			}
+			return writePlan(a, cmd, plan)
+		},
+	}
+	cmd.Flags().StringVarP(&manifest, "file", "f", "deploy.yaml", "deploy manifest")
+	return cmd
+}
+
+func newApplyCmd(a *app) *cobra.Command {
+	var manifest, metricsFile string
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "apply -f MANIFEST",
+		Short: "Deploy every changed document of a manifest",
+		Long: `Apply shows the plan for a deploy manifest, asks for confirmation, and
+deploys each document that is not up to date. --yes skips the question.
+With --output json or ndjson, the plan is shown on stderr and its steps
+are printed once they are deployed. --metrics-file writes totals of the
+batch as JSON, whether it succeeded or not: documents by outcome, bytes
+uploaded, deploy durations, retries, and API calls.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var m *metrics.Collector
+			if metricsFile != "" {
+				m = a.collectMetrics()
+			}
+			plan, err := planManifest(cmd, a, manifest)
+			if err != nil {
+				return err
+			}
+			msgs := a.messages(cmd)
+			a.palette(msgs).writePlan(msgs, plan)
+			if plan.Changes() == 0 {
+				fmt.Fprintln(msgs, "Nothing to deploy.")
+			} else {
+				question := fmt.Sprintf("Deploy %d documents?", plan.Changes())
+				if !yes && !confirm(bufio.NewReader(cmd.InOrStdin()), msgs, question) {
+					return errors.New("apply aborted")
+				}
+				c, cerr := a.client()
+				if cerr != nil {
+					return cerr
+				}
+				err = deploy.ApplyManifest(cmd.Context(), c, plan)
+			}
+			if m != nil {
+				err = errors.Join(err, writeBatchMetrics(metricsFile, plan, m))
+			}
+			if err != nil {
+				return err
+			}
+			if a.machine() {
+				return writePlan(a, cmd, plan)
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&manifest, "file", "f", "deploy.yaml", "deploy manifest")
+	f.BoolVarP(&yes, "yes", "y", false, "do not ask before deploying")
+	f.StringVar(&metricsFile, "metrics-file", "", "write totals of the deploys to this JSON file (env GDRIVE_METRICS_FILE)")
+	notifyFlags(cmd, a)
+	verifyFlag(cmd, a)
+	return cmd
+}
+
+func planManifest(cmd *cobra.Command, a *app, path string) (*deploy.DeployPlan, error) {
+	m, err := deploy.LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := a.client()
+	if err != nil {
+		return nil, err
+	}
+	return deploy.PlanManifest(cmd.Context(), c, m)
+}
+
+// writePlan prints plan as colored plan lines, or its steps for json and
+// ndjson.
+func writePlan(a *app, cmd *cobra.Command, plan *deploy.DeployPlan) error {
+	return writeResults(a, cmd, plan.Steps, func(w io.Writer, _ []deploy.DeployStep) error {
+		_, err := a.palette(w).writePlan(w, plan)
+		return err
+	})
+}
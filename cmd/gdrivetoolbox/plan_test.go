@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestPlanApply(t *testing.T) {
+	s := newTree(t)
+	addFile(s, "pub", "root", "Published/", "")
+	s.Add(drive.File{ID: "live", Name: "mydoc.pdf", MimeType: "application/pdf", Parents: []string{"pub"}, Description: "v3"}, []byte("pdf"))
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644)
+	os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("pdf"), 0o644)
+	manifest := filepath.Join(dir, "deploy.yaml")
+	write := func(docs string) {
+		yaml := "folder: pub\ntempFolder: tmp\narchiveFolder: arc\ndir: .\ndocuments:\n" + docs
+		if err := os.WriteFile(manifest, []byte(yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("  - {file: mydoc, version: v4}\n  - {file: guide, version: v1}\n")
+	out, err := run(t, "plan", "-f", manifest, "--access-token", "tok")
+	want := "~ mydoc.pdf v3 -> v4 (archive as mydoc-v3.pdf)\n+ guide.pdf v1\n"
+	if err != nil || out != want {
+		t.Fatalf("plan = %q, %v; want %q", out, err, want)
+	}
+
+	write("  - {file: mydoc, version: v3}\n")
+	metricsFile := filepath.Join(dir, "metrics.json")
+	out, err = run(t, "apply", "-f", manifest, "--access-token", "tok", "--metrics-file", metricsFile)
+	if err != nil || !strings.HasSuffix(out, "Nothing to deploy.\n") {
+		t.Fatalf("apply with nothing to do = %q, %v", out, err)
+	}
+	b, err := os.ReadFile(metricsFile)
+	var m deploy.BatchMetrics
+	if err != nil || json.Unmarshal(b, &m) != nil {
+		t.Fatalf("metrics file: %s, %v", b, err)
+	}
+	if m.Documents != 1 || m.Outcomes[deploy.StatusSkipped] != 1 || m.Outcomes[deploy.StatusDeployed] != 0 || m.APICalls == 0 {
+		t.Errorf("metrics = %+v", m)
+	}
+}
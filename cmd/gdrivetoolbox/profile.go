@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+)
+
+// defaultProfile is the profile of the config file's top level and the
+// plain credentials file; selecting it selects no profile.
+const defaultProfile = "default"
+
+// profileName is what the name of a profile must look like; it becomes
+// part of the name of its credentials file.
+var profileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// checkProfileName returns an error if name cannot be a profile's.
+func checkProfileName(name string) error {
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use only letters, digits, _, and -", name)
+	}
+	return nil
+}
+
+// selectProfile returns the profile to use: flag, if set, then
+// GDRIVE_PROFILE, then the one chosen with "profile use". It fails if
+// that is not a valid profile name.
+func selectProfile(flag string) (string, error) {
+	p, err := profileSetting(flag)
+	if err != nil {
+		return "", err
+	}
+	if err := checkProfileName(p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+func profileSetting(flag string) (string, error) {
+	if flag != "" {
+		return flag, nil
+	}
+	if p := os.Getenv("GDRIVE_PROFILE"); p != "" {
+		return p, nil
+	}
+	path, err := currentProfilePath()
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultProfile, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if p := strings.TrimSpace(string(b)); p != "" {
+		return p, nil
+	}
+	return defaultProfile, nil
+}
+
+// checkProfile returns an error listing the profiles there are if name is
+// not one of them, so that a mistyped profile fails rather than falls back
+// to the default settings.
+func (a *app) checkProfile(name string) error {
+	if name == defaultProfile {
+		return nil
+	}
+	names, err := a.profiles()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(names, name) {
+		return nil
+	}
+	return fmt.Errorf("unknown profile %q; the profiles are %s", name, strings.Join(names, ", "))
+}
+
+// picksProfile reports whether cmd is one that may be given a profile that
+// does not exist yet: auth login, which creates it, and the profile
+// commands, which fix a current profile that went away.
+func picksProfile(cmd *cobra.Command) bool {
+	p := cmd.Parent()
+	if p == nil {
+		return false
+	}
+	return p.Name() == "profile" || p.Name() == "auth" && cmd.Name() == "login"
+}
+
+func currentProfilePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profile"), nil
+}
+
+// profileStore returns where the credentials of profile are stored:
+// credentials.json for the default profile and credentials-NAME.json for
+// the others, in the directory of dflt.
+func profileStore(dflt auth.Store, profile string) auth.Store {
+	if profile == "" || profile == defaultProfile {
+		return dflt
+	}
+	return auth.Store{Path: filepath.Join(filepath.Dir(dflt.Path), "credentials-"+profile+".json")}
+}
+
+// profiles lists the default profile, every profile of the config file,
+// and every profile with stored credentials, sorted.
+func (a *app) profiles() ([]string, error) {
+	names := []string{defaultProfile}
+	cfg, _, err := a.readConfig()
+	if err != nil {
+		return nil, err
+	}
+	for name := range cfg.profiles {
+		names = append(names, name)
+	}
+	dflt, err := auth.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(dflt.Path), "credentials-*.json"))
+	for _, m := range matches {
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "credentials-"), ".json"))
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// profileInfo is one profile as profile list and use print it.
+type profileInfo struct {
+	Name    string `json:"name"`
+	Account string `json:"account,omitempty"`
+	Current bool   `json:"current"`
+}
+
+func newProfileCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Switch between accounts and folder configurations",
+		Long: `A profile is a section of the config file's profiles, laid over the rest
+of the file, together with its own stored credentials, so one machine can
+work with several Google accounts or clients. "auth login --profile NAME"
+stores a profile's credentials. Commands use --profile, then
+GDRIVE_PROFILE, then the profile chosen with "profile use", and fail
+if it is not a profile there is.`,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "use NAME",
+		Short: "Make NAME the profile used by default",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if err := checkProfileName(name); err != nil {
+				return usageError{err}
+			}
+			names, err := a.profiles()
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(names, name) {
+				return fmt.Errorf("unknown profile %q: add it to the config file or run \"gdrivetoolbox auth login --profile %s\"", name, name)
+			}
+			path, err := currentProfilePath()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+				return err
+			}
+			return writeResult(a, cmd, profileInfo{Name: name, Current: true}, func(w io.Writer, p profileInfo) {
+				fmt.Fprintf(w, "Using profile %s\n", p.Name)
+			})
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List profiles, marking the one in use",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := a.profiles()
+			if err != nil {
+				return err
+			}
+			dflt, err := auth.DefaultStore()
+			if err != nil {
+				return err
+			}
+			var infos []profileInfo
+			for _, name := range names {
+				info := profileInfo{Name: name, Current: name == a.profile}
+				if creds, err := profileStore(dflt, name).Load(); err == nil {
+					info.Account = creds.Account
+				}
+				infos = append(infos, info)
+			}
+			return writeResults(a, cmd, infos, func(w io.Writer, infos []profileInfo) error {
+				tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+				for _, info := range infos {
+					mark, account := " ", info.Account
+					if info.Current {
+						mark = "*"
+					}
+					if account == "" {
+						account = "-"
+					}
+					fmt.Fprintf(tw, "%s %s\t%s\n", mark, info.Name, account)
+				}
+				return tw.Flush()
+			})
+		},
+	})
+	return cmd
+}
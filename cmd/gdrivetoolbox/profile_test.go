@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GDRIVE_CONFIG", "")
+	t.Setenv("GDRIVE_CREDENTIALS", "")
+	t.Setenv("GDRIVE_PROFILE", "")
+	dir := filepath.Join(home, "gdrivetoolbox")
+	os.MkdirAll(dir, 0o700)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`
+folder: prod
+folders:
+  prod: P1
+profiles:
+  client-b:
+    folders:
+      prod: P2
+`), 0o600)
+	os.WriteFile(filepath.Join(dir, "credentials-work.json"), []byte(`{"account":"ada@example.com"}`), 0o600)
+
+	out, err := run(t, "profile", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(out); strings.Join(got, " ") != "client-b - * default - work ada@example.com" {
+		t.Fatalf("profile list:\n%s", out)
+	}
+	if _, err := run(t, "profile", "use", "nope"); err == nil || !strings.Contains(err.Error(), "unknown profile") {
+		t.Fatalf("use nope: err = %v", err)
+	}
+	if out, err := run(t, "profile", "use", "client-b"); err != nil || out != "Using profile client-b\n" {
+		t.Fatalf("use client-b = %q, %v", out, err)
+	}
+
+	folder := func(args ...string) string {
+		var v string
+		probe := &cobra.Command{Use: "probe", RunE: func(*cobra.Command, []string) error { return nil }}
+		probe.Flags().StringVar(&v, "folder", "", "")
+		folderFlags(probe, "folder")
+		root := newRootCmd()
+		root.AddCommand(probe)
+		root.SetArgs(append([]string{"probe"}, args...))
+		if err := root.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if got := folder(); got != "P2" {
+		t.Fatalf("folder with profile client-b = %q", got)
+	}
+	if got := folder("--profile", "default"); got != "P1" {
+		t.Fatalf("folder with --profile default = %q", got)
+	}
+
+	// A mistyped profile fails rather than fall back to the default.
+	const want = `unknown profile "client-c"; the profiles are client-b, default, work`
+	if _, err := run(t, "ls", "--profile", "client-c"); err == nil || err.Error() != want {
+		t.Fatalf("--profile client-c: err = %v", err)
+	}
+	t.Setenv("GDRIVE_PROFILE", "client-c")
+	if _, err := run(t, "ls"); err == nil || err.Error() != want {
+		t.Fatalf("GDRIVE_PROFILE=client-c: err = %v", err)
+	}
+
+	// A profile name is part of a file name, so it cannot be a path.
+	for _, args := range [][]string{{"auth", "login", "--profile", "../../x"}, {"profile", "use", "a/b"}} {
+		_, err := run(t, args...)
+		if err == nil || !strings.Contains(err.Error(), "invalid profile name") || exitCode(err) != 2 {
+			t.Errorf("%s: err = %v, want a usage error", strings.Join(args, " "), err)
+		}
+	}
+}
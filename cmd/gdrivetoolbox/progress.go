@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// progressLogInterval is how often progress is reported when stderr is not
+// a terminal.
+var progressLogInterval = 5 * time.Second
+
+// transfers reports the progress of a command's transfers. On a terminal
+// it redraws a bar for the current file and, when there are several files,
+// one for all of them; elsewhere, such as in CI logs, it writes a line per
+// file at most every progressLogInterval and once the file is done. A nil
+// *transfers reports nothing.
+type transfers struct {
+	w   io.Writer
+	tty bool
+
+	mu        sync.Mutex
+	start     time.Time
+	files     int   // files expected
+	total     int64 // bytes expected
+	done      int   // files finished
+	doneBytes int64 // bytes of finished files
+	name      string
+	cur       drive.Progress
+	drawn     int // lines of bars on screen
+	lastLog   time.Time
+}
+
+func newTransfers(w io.Writer) *transfers {
+	return &transfers{w: w, tty: isTerminal(w), start: time.Now()}
+}
+
+// isTerminal reports whether w is a terminal that understands cursor
+// movement.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// expect adds files of size bytes in total to what the batch will transfer.
+func (t *transfers) expect(files int, bytes int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files += files
+	t.total += bytes
+}
+
+// track returns the ProgressFunc for transfers shown as name. A client
+// keeps it across transfers, so each Done update finishes one file.
+func (t *transfers) track(name string) drive.ProgressFunc {
+	if t == nil {
+		return nil
+	}
+	return func(p drive.Progress) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.name, t.cur = name, p
+		if p.Done {
+			t.done++
+			t.doneBytes += p.Transferred
+			t.cur = drive.Progress{}
+			t.fileDone(name, p)
+			return
+		}
+		if t.tty {
+			t.redraw()
+			return
+		}
+		if now := time.Now(); now.Sub(t.lastLog) >= progressLogInterval {
+			t.lastLog = now
+			fmt.Fprintln(t.w, t.logLine(name, p))
+		}
+	}
+}
+
+// finish clears the bars and, after several files, sums them up.
+func (t *transfers) finish() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	if t.done > 1 {
+		elapsed := time.Since(t.start)
+		line := fmt.Sprintf("%d files, %s in %s", t.done, formatBytes(t.doneBytes), elapsed.Round(time.Millisecond))
+		if s := elapsed.Seconds(); s > 0 {
+			line += fmt.Sprintf(" (%s/s)", formatBytes(int64(float64(t.doneBytes)/s)))
+		}
+		fmt.Fprintln(t.w, line)
+	}
+}
+
+func (t *transfers) fileDone(name string, p drive.Progress) {
+	t.clear()
+	line := fmt.Sprintf("%s: %s in %s", name, formatBytes(p.Transferred), p.Elapsed.Round(time.Millisecond))
+	if t.files > 1 {
+		line += fmt.Sprintf(" [%d/%d]", t.done, t.files)
+	}
+	fmt.Fprintln(t.w, line)
+	if t.tty && t.done < t.files {
+		t.redraw()
+	}
+}
+
+// clear removes the bars, leaving the cursor where they started.
+func (t *transfers) clear() {
+	if t.drawn == 0 {
+		return
+	}
+	fmt.Fprint(t.w, "\r\033[K")
+	for ; t.drawn > 1; t.drawn-- {
+		fmt.Fprint(t.w, "\033[1A\r\033[K")
+	}
+	t.drawn = 0
+}
+
+func (t *transfers) redraw() {
+	t.clear()
+	lines := []string{t.fileBar()}
+	if t.files > 1 {
+		lines = append(lines, t.totalBar())
+	}
+	fmt.Fprint(t.w, strings.Join(lines, "\n"))
+	t.drawn = len(lines)
+}
+
+func (t *transfers) fileBar() string {
+	p := t.cur
+	s := fmt.Sprintf("%-20s %s", truncate(t.name, 20), bar(p.Transferred, p.Total))
+	s += " " + amount(p.Transferred, p.Total)
+	if p.Rate > 0 {
+		s += fmt.Sprintf("  %s/s", formatBytes(int64(p.Rate)))
+	}
+	if p.ETA > 0 {
+		s += fmt.Sprintf("  %s left", p.ETA.Round(time.Second))
+	}
+	return s
+}
+
+func (t *transfers) totalBar() string {
+	sent := t.doneBytes + t.cur.Transferred
+	s := fmt.Sprintf("%-20s %s", fmt.Sprintf("total %d/%d", t.done, t.files), bar(sent, t.total))
+	s += " " + amount(sent, t.total)
+	if secs := time.Since(t.start).Seconds(); secs > 0 && sent > 0 {
+		rate := float64(sent) / secs
+		s += fmt.Sprintf("  %s/s", formatBytes(int64(rate)))
+		if t.total > sent {
+			s += fmt.Sprintf("  %s left", time.Duration(float64(t.total-sent)/rate*float64(time.Second)).Round(time.Second))
+		}
+	}
+	return s
+}
+
+func (t *transfers) logLine(name string, p drive.Progress) string {
+	line := fmt.Sprintf("%s: %s", name, formatBytes(p.Transferred))
+	if pct := p.Percent(); pct >= 0 {
+		line += fmt.Sprintf(" of %s (%.0f%%)", formatBytes(p.Total), pct)
+	}
+	if p.Rate > 0 {
+		line += fmt.Sprintf(", %s/s", formatBytes(int64(p.Rate)))
+	}
+	if p.ETA > 0 {
+		line += fmt.Sprintf(", %s left", p.ETA.Round(time.Second))
+	}
+	if t.files > 1 {
+		line += fmt.Sprintf(" [%d/%d]", t.done, t.files)
+	}
+	return line
+}
+
+// bar draws a 24-character bar for n of total, or blanks if total is
+// unknown.
+func bar(n, total int64) string {
+	const width = 24
+	if total <= 0 {
+		return strings.Repeat(" ", width+2)
+	}
+	filled := int(min(n, total) * width / total)
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func amount(n, total int64) string {
+	if total <= 0 {
+		return formatBytes(n)
+	}
+	return fmt.Sprintf("%3.0f%% %s of %s", float64(min(n, total))*100/float64(total), formatBytes(n), formatBytes(total))
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+// formatBytes renders n in binary units, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestTransfersLogLines(t *testing.T) {
+	defer func(d time.Duration) { progressLogInterval = d }(progressLogInterval)
+	progressLogInterval = 0
+
+	var buf bytes.Buffer
+	tr := newTransfers(&buf)
+	if tr.tty {
+		t.Fatal("buffer taken for a terminal")
+	}
+	tr.expect(2, 3072)
+	track := tr.track("a.pdf")
+	track(drive.Progress{Transferred: 1024, Total: 2048, Rate: 512, ETA: 2 * time.Second})
+	track(drive.Progress{Transferred: 2048, Total: 2048, Elapsed: time.Second, Done: true})
+	track = tr.track("b.pdf")
+	track(drive.Progress{Transferred: 1024, Total: 1024, Elapsed: time.Second, Done: true})
+	tr.finish()
+
+	out := buf.String()
+	for _, want := range []string{
+		"a.pdf: 1.0 KiB of 2.0 KiB (50%), 512 B/s, 2s left [0/2]\n",
+		"a.pdf: 2.0 KiB in 1s [1/2]\n",
+		"b.pdf: 1.0 KiB in 1s [2/2]\n",
+		"2 files, 3.0 KiB in ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\033") {
+		t.Errorf("escape codes outside a terminal:\n%q", out)
+	}
+}
+
+func TestTransfersBars(t *testing.T) {
+	var buf bytes.Buffer
+	tr := newTransfers(&buf)
+	tr.tty = true
+	tr.expect(2, 2048)
+	track := tr.track("a.pdf")
+	track(drive.Progress{Transferred: 512, Total: 1024})
+
+	out := buf.String()
+	if !strings.Contains(out, "a.pdf                [============            ]  50% 512 B of 1.0 KiB") {
+		t.Errorf("no file bar:\n%q", out)
+	}
+	if !strings.Contains(out, "total 0/2") || !strings.Contains(out, "[======                  ]") {
+		t.Errorf("no total bar:\n%q", out)
+	}
+
+	buf.Reset()
+	track(drive.Progress{Transferred: 1024, Total: 1024, Done: true})
+	if out := buf.String(); !strings.HasPrefix(out, "\r\033[K\033[1A\r\033[Ka.pdf: 1.0 KiB") || !strings.Contains(out, "total 1/2") {
+		t.Errorf("bars not replaced by the done line:\n%q", out)
+	}
+
+	buf.Reset()
+	tr.finish()
+	if out := buf.String(); !strings.HasPrefix(out, "\r\033[K\033[1A\r\033[K") {
+		t.Errorf("finish left the bars:\n%q", out)
+	}
+}
+
+func TestNilTransfers(t *testing.T) {
+	var tr *transfers
+	tr.expect(1, 10)
+	if tr.track("a") != nil {
+		t.Error("nil transfers tracks")
+	}
+	tr.finish()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// retryBackoff is swapped out by tests; nil waits as drive.Retrier does.
+var retryBackoff func(n int) time.Duration
+
+// setupQuota counts the Drive API calls sent through http.DefaultClient,
+// warning when they near the per-user rate limits, and under --throttle
+// slowing down to stay within them. It goes below the Retrier, as retries
+// count against the limits too.
+func (a *app) setupQuota() {
+	hc := *http.DefaultClient
+	a.quota = &drive.QuotaTracker{Base: hc.Transport, Throttle: a.throttle,
+		OnWarn: func(w drive.QuotaWarning) {
+			a.logger.Warn("nearing the Drive API rate limit", "limit", w.Limit, "calls", w.Calls, "max", w.Max,
+				"window", drive.QuotaWindow)
+		},
+		OnThrottle: func(req *http.Request, wait time.Duration) {
+			a.logger.Info("throttling to stay within the Drive API rate limit", "method", req.Method, "path", req.URL.Path,
+				"wait", wait.Round(time.Millisecond))
+		}}
+	hc.Transport = a.quota
+	http.DefaultClient = &hc
+}
+
+// setupRetries makes every request sent through http.DefaultClient, which
+// all clients of the command use, be repeated up to --retries times after
+// a transient failure. It goes below the audit log and metrics, which see
+// a repeated request once, though metrics count the retries as well.
+func (a *app) setupRetries() {
+	if a.retries <= 0 {
+		return
+	}
+	hc := *http.DefaultClient
+	hc.Transport = &drive.Retrier{Base: hc.Transport, Max: a.retries, Backoff: retryBackoff, OnRetry: func(req *http.Request, err error) {
+		a.logger.Info("retrying request", "method", req.Method, "path", req.URL.Path, "err", err)
+		if a.metrics != nil && drive.APIMethod(req) != "" {
+			a.metrics.RecordRetry()
+		}
+	}}
+	http.DefaultClient = &hc
+}
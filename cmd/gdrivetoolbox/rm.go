@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// removed is one file or folder removed by rm; Action is "trashed" or
+// "deleted".
+type removed struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Folder bool   `json:"folder,omitempty"`
+	Action string `json:"action"`
+}
+
+func newRmCmd(a *app) *cobra.Command {
+	var permanent, recursive, yes bool
+	cmd := &cobra.Command{
+		Use:   "rm TARGET...",
+		Short: "Move files and folders to the trash, or delete them",
+		Long: `Rm moves each TARGET, a file ID, a Drive URL, or a path from My Drive, to
+the trash, from where it can be restored for 30 days. --permanent deletes
+it outright instead.
+
+Folders need --recursive. Their contents are counted first and the command
+asks before going ahead; --yes skips the question, e.g. in scripts.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			msgs := a.messages(cmd)
+			verb, need := "trashed", drive.CanTrash
+			if permanent {
+				verb, need = "deleted", drive.CanDelete
+			}
+			in := bufio.NewReader(cmd.InOrStdin())
+			p := newPrinter(a, cmd, func(w io.Writer, r removed) {
+				action := a.palette(w).red(r.Action)
+				if r.Folder {
+					fmt.Fprintf(w, "%s %s/\n", action, r.Name)
+				} else {
+					fmt.Fprintf(w, "%s %s\n", action, r.Name)
+				}
+			})
+			defer p.flush()
+			for _, ref := range args {
+				id, err := resolveFile(ctx, c, ref)
+				if err != nil {
+					return err
+				}
+				f, err := c.GetFile(ctx, id, "id", "name", "mimeType")
+				if err != nil {
+					return err
+				}
+				if f.IsFolder() {
+					if !recursive {
+						return fmt.Errorf("%s is a folder; use --recursive", f.Name)
+					}
+					opts := folder.DeleteOptions{Permanent: permanent}
+					if !yes {
+						opts.Confirm = func(items []folder.DeletedItem) bool {
+							return confirm(in, msgs, fmt.Sprintf("%d items will be %s. Continue?", len(items), verb))
+						}
+					}
+					if _, err := folder.DeleteFolderRecursive(ctx, c, f.ID, opts); err != nil {
+						if errors.Is(err, folder.ErrAborted) {
+							return fmt.Errorf("%s: aborted", f.Name)
+						}
+						return err
+					}
+					p.print(removed{ID: f.ID, Name: f.Name, Folder: true, Action: verb})
+					continue
+				}
+				if err := c.CheckCapabilities(ctx, f.ID, need); err != nil {
+					return err
+				}
+				if permanent {
+					err = c.Delete(ctx, f.ID)
+				} else {
+					_, err = c.TrashFile(ctx, f.ID)
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %w", f.Name, err)
+				}
+				p.print(removed{ID: f.ID, Name: f.Name, Action: verb})
+			}
+			return p.flush()
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&permanent, "permanent", false, "delete instead of moving to the trash; this cannot be undone")
+	f.BoolVarP(&recursive, "recursive", "r", false, "remove folders and everything in them")
+	f.BoolVarP(&yes, "yes", "y", false, "do not ask before removing a folder")
+	return cmd
+}
+
+// confirm asks question on out and reports whether the answer read from in
+// is yes. Anything else, including end of input, is no.
+func confirm(in *bufio.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRm(t *testing.T) {
+	s := sampleTree(t)
+
+	out, err := run(t, "rm", "/Reports/q1.pdf", "--access-token", "tok")
+	if err != nil || out != "trashed q1.pdf\n" {
+		t.Fatalf("rm file = %q, %v", out, err)
+	}
+	if !fileOf(s, "q1").Trashed {
+		t.Fatal("q1.pdf was not trashed")
+	}
+
+	if _, err := run(t, "rm", "arc", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "--recursive") {
+		t.Fatalf("rm folder without -r: err = %v", err)
+	}
+
+	// Declining the confirmation leaves the folder alone.
+	root := newRootCmd()
+	var buf strings.Builder
+	root.SetOut(&buf)
+	root.SetErr(&buf)
+	root.SetIn(strings.NewReader("n\n"))
+	root.SetArgs([]string{"rm", "-r", "--permanent", "arc", "--access-token", "tok"})
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("declined rm -r: err = %v", err)
+	}
+	if !strings.Contains(buf.String(), "3 items will be deleted. Continue? [y/N]") {
+		t.Fatalf("prompt = %q", buf.String())
+	}
+	if _, ok := s.File("arc"); !ok {
+		t.Fatal("folder deleted despite declining")
+	}
+
+	out, err = run(t, "rm", "-r", "--permanent", "--yes", "arc", "--access-token", "tok")
+	if err != nil || out != "deleted archive/\n" {
+		t.Fatalf("rm -r --yes = %q, %v", out, err)
+	}
+	if _, ok := s.File("img"); ok {
+		t.Fatal("folder contents not deleted")
+	}
+
+	plan := fileOf(s, "plan")
+	plan.Capabilities["canTrash"] = false
+	s.Add(plan, nil)
+	if _, err := run(t, "rm", "plan", "--access-token", "tok"); err == nil || !strings.Contains(err.Error(), "insufficient permission") {
+		t.Fatalf("rm without canTrash: err = %v", err)
+	}
+}
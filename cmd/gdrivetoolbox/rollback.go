@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+// rolledBack is rollback's result. ID is the now live file and ArchivedID
+// the one it replaced, if that was archived.
+type rolledBack struct {
+	File       string `json:"file"`
+	Version    string `json:"version,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	ID         string `json:"id"`
+	ArchivedID string `json:"archivedId,omitempty"`
+}
+
+func newRollbackCmd(a *app) *cobra.Command {
+	var opts deploy.RollbackOptions
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "rollback --file NAME --to VERSION --folder ID --archive-folder ID",
+		Short: "Restore an earlier deployed version of a PDF",
+		Long: `Rollback puts the archived copy of VERSION back in the live folder and
+archives the copy it replaces, as deploy would. With --revision it instead
+restores a revision of the live file, typically a pinned one, and --to then
+only sets the recorded version. A summary is shown first and the command
+asks before making any change; --yes skips the question.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			plan, err := deploy.PlanRollback(ctx, c, opts)
+			if err != nil {
+				return err
+			}
+			msgs := a.messages(cmd)
+			plan.WriteTo(msgs)
+			if !yes && !confirm(bufio.NewReader(cmd.InOrStdin()), msgs, "Roll back?") {
+				return errors.New("rollback aborted")
+			}
+			if err := deploy.ApplyRollback(ctx, c, plan); err != nil {
+				return err
+			}
+			res := rolledBack{File: opts.FileName + ".pdf", Version: opts.Version, Revision: opts.RevisionID}
+			switch {
+			case plan.Archived != nil:
+				res.ID = plan.Archived.ID
+				if plan.Live != nil {
+					res.ArchivedID = plan.Live.ID
+				}
+			case plan.Live != nil:
+				res.ID = plan.Live.ID
+			}
+			return writeResult(a, cmd, res, func(w io.Writer, _ rolledBack) {
+				fmt.Fprintln(w, a.palette(w).green("Rollback complete."))
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&opts.FileName, "file", "", "PDF name without the .pdf extension")
+	f.StringVar(&opts.Version, "to", "", "version to restore")
+	f.StringVar(&opts.FolderID, "folder", "", "live Drive folder ID or URL")
+	f.StringVar(&opts.ArchiveFolderID, "archive-folder", "", "Drive folder ID or URL holding archived versions")
+	f.StringVar(&opts.RevisionID, "revision", "", "restore this revision of the live file instead of an archived copy")
+	f.BoolVarP(&yes, "yes", "y", false, "do not ask before rolling back")
+	cmd.MarkFlagRequired("file")
+	cmd.MarkFlagRequired("folder")
+	folderFlags(cmd, "folder", "archive-folder")
+	notifyFlags(cmd, a)
+	return cmd
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func TestRollback(t *testing.T) {
+	s := drivetest.NewServer()
+	defer s.Close()
+	addFile(s, "pub", "root", "Published/", "")
+	addFile(s, "arc", "root", "Archive/", "")
+	s.Add(drive.File{ID: "v3", Name: "mydoc.pdf", MimeType: "application/pdf", Parents: []string{"pub"}, Description: "v3"}, []byte("pdf"))
+	s.Add(drive.File{ID: "v2", Name: "mydoc-v2.pdf", MimeType: "application/pdf", Parents: []string{"arc"}, Description: "v2"}, []byte("pdf"))
+	var emails []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gmail/v1/users/me/messages/send" {
+			s.ServeHTTP(w, r)
+			return
+		}
+		var in struct{ Raw string }
+		json.NewDecoder(r.Body).Decode(&in)
+		b, _ := base64.URLEncoding.DecodeString(in.Raw)
+		emails = append(emails, string(b))
+		w.Write([]byte(`{"id":"m1"}`))
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	args := []string{"rollback", "--file", "mydoc", "--to", "v2", "--folder", "pub", "--archive-folder", "arc", "--access-token", "tok"}
+	root := newRootCmd()
+	var out strings.Builder
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetIn(strings.NewReader("no\n"))
+	root.SetArgs(args)
+	if err := root.Execute(); err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("declined rollback: err = %v", err)
+	}
+	if !strings.Contains(out.String(), "restore mydoc-v2.pdf from the archive as mydoc.pdf (v2)") {
+		t.Fatalf("summary = %q", out.String())
+	}
+	if fileOf(s, "v2").Parents[0] != "arc" || len(emails) != 0 {
+		t.Fatalf("declined rollback changed files or sent %d emails", len(emails))
+	}
+
+	t.Setenv("GDRIVE_EMAIL_TO", "qa@example.com,docs@example.com")
+
+	got, err := run(t, append(args, "--yes")...)
+	if err != nil || !strings.HasSuffix(got, "Rollback complete.\n") {
+		t.Fatalf("rollback --yes = %q, %v", got, err)
+	}
+	if fileOf(s, "v2").Parents[0] != "pub" || fileOf(s, "v3").Name != "mydoc-v3.pdf" {
+		t.Fatalf("after rollback: v2 %+v, v3 %+v", fileOf(s, "v2"), fileOf(s, "v3"))
+	}
+	if len(emails) != 1 || !strings.HasPrefix(emails[0], "To: qa@example.com, docs@example.com\r\nSubject: mydoc.pdf v2 rolled back\r\n") ||
+		!strings.Contains(emails[0], "mydoc.pdf was rolled back from v3 to v2.\r\n\r\nhttps://drive.google.com/file/d/v2/view") {
+		t.Errorf("emails = %q", emails)
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/metrics"
+)
+
+func newServeCmd(a *app) *cobra.Command {
+	var manifest, addr, apiToken string
+	cmd := &cobra.Command{
+		Use:   "serve -f MANIFEST",
+		Short: "Serve an HTTP API to deploy a manifest",
+		Long: `Serve runs an HTTP server through which other systems deploy a manifest
+without running the CLI themselves:
+
+  POST /deploys                 start a deploy of the manifest; a JSON body
+                                {"documents": [{"file": "mydoc", "version": "v2"}]}
+                                deploys only those documents, at those versions
+  GET  /deploys                 list deploys, newest first
+  GET  /deploys/{id}            show a deploy and the state of each document
+  GET  /deploys/{id}/events     stream the deploy as JSON lines until it ends
+  GET  /versions                show the live version of each document
+  GET  /metrics                 Prometheus metrics: Drive requests, bytes
+                                transferred, and deploy durations
+  GET  /stats                   deploys running and queued, how many
+                                succeeded and failed, and the last error
+  GET  /healthz                 200 while the server runs
+  GET  /readyz                  200 while it can take deploys: the manifest
+                                loads, the queue has room, and it is not
+                                shutting down; 503 and the reason otherwise
+
+Deploys run one at a time, in the order they were asked for, reading the
+manifest afresh each time. The server remembers the last 1000 deploys,
+forgetting older ones once they have ended, and takes request bodies of up
+to 1 MiB. With --api-token, every request needs the header
+"Authorization: Bearer TOKEN", except /healthz and /readyz, for
+orchestrators' health checks.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			s := newServer(ctx, a, manifest, apiToken)
+			srv := &http.Server{Addr: addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				<-ctx.Done()
+				shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdown)
+			}()
+			a.logger.Info("serving", "addr", addr, "manifest", manifest)
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&manifest, "file", "f", "deploy.yaml", "deploy manifest")
+	f.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	f.StringVar(&apiToken, "api-token", "", "bearer token requests must carry (env GDRIVE_API_TOKEN)")
+	notifyFlags(cmd, a)
+	verifyFlag(cmd, a)
+	return cmd
+}
+
+// Statuses of a job and of its steps.
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+	stepPending  = "pending"
+	stepSkipped  = "skipped"
+	stepDeployed = "deployed"
+)
+
+// maxJobs is how many deploys the server remembers; tests lower it.
+var maxJobs = 1000
+
+// maxBodyBytes caps the request bodies the server reads.
+const maxBodyBytes = 1 << 20
+
+// requestIDPattern is what a caller's X-Request-Id must look like to be
+// taken as a deploy's correlation ID, which is sent to Drive and logged;
+// any other gets a new one.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// job is one deploy asked for through the API.
+type job struct {
+	ID        string            `json:"id"`
+	Status    string            `json:"status"`
+	Documents []deploy.Document `json:"documents,omitempty"`
+	Steps     []jobStep         `json:"steps,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Created   time.Time         `json:"created"`
+	Finished  *time.Time        `json:"finished,omitempty"`
+	// CorrelationID is sent with the job's requests to Drive and noted on
+	// its log entries: the caller's X-Request-Id, if it is up to 128
+	// letters, digits, dots, dashes, and underscores, or a new one.
+	CorrelationID string `json:"correlationId"`
+}
+
+// jobStep is a step of a job's plan with how far it got.
+type jobStep struct {
+	deploy.DeployStep
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (j *job) done() bool {
+	return j.Status == jobSucceeded || j.Status == jobFailed
+}
+
+// server runs the deploys of serve. Jobs are changed only under mu, and
+// each change closes changed, waking the streams watching them.
+type server struct {
+	ctx      context.Context
+	a        *app
+	manifest string
+	apiToken string
+	queue    chan *job
+	metrics  *metrics.Collector
+	started  time.Time
+
+	mu      sync.Mutex
+	jobs    []*job
+	lastID  int
+	changed chan struct{}
+	// forgotten sums up the deploys dropped from jobs, so that /stats
+	// still counts them.
+	forgotten serverStats
+}
+
+func newServer(ctx context.Context, a *app, manifest, apiToken string) *server {
+	s := &server{ctx: ctx, a: a, manifest: manifest, apiToken: apiToken,
+		queue: make(chan *job, 100), changed: make(chan struct{}), metrics: a.collectMetrics(), started: time.Now()}
+	go s.work()
+	return s
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /deploys", s.startDeploy)
+	mux.HandleFunc("GET /deploys", s.listDeploys)
+	mux.HandleFunc("GET /deploys/{id}", s.getDeploy)
+	mux.HandleFunc("GET /deploys/{id}/events", s.streamDeploy)
+	mux.HandleFunc("GET /versions", s.versions)
+	mux.Handle("GET /metrics", s.metrics)
+	mux.HandleFunc("GET /stats", s.stats)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probe := r.URL.Path == "/healthz" || r.URL.Path == "/readyz"
+		if s.apiToken != "" && !probe && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.apiToken)) != 1 {
+			httpError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) startDeploy(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Documents []deploy.Document `json:"documents"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		code := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		httpError(w, code, fmt.Errorf("bad request body: %w", err))
+		return
+	}
+	id := r.Header.Get(drive.CorrelationHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = drive.NewCorrelationID()
+	}
+	s.mu.Lock()
+	j := &job{ID: strconv.Itoa(s.lastID + 1), Status: jobQueued, Documents: body.Documents, Created: time.Now(), CorrelationID: id}
+	select {
+	case s.queue <- j:
+	default:
+		s.mu.Unlock()
+		httpError(w, http.StatusServiceUnavailable, errors.New("too many deploys queued"))
+		return
+	}
+	s.lastID++
+	s.jobs = append(s.jobs, j)
+	s.forget()
+	snap := *j
+	s.mu.Unlock()
+	w.Header().Set("Location", "/deploys/"+j.ID)
+	writeHTTP(w, http.StatusAccepted, snap)
+}
+
+func (s *server) listDeploys(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]job, 0, len(s.jobs))
+	for _, j := range slices.Backward(s.jobs) {
+		snap := *j
+		snap.Steps = slices.Clone(j.Steps)
+		jobs = append(jobs, snap)
+	}
+	s.mu.Unlock()
+	writeHTTP(w, http.StatusOK, jobs)
+}
+
+func (s *server) getDeploy(w http.ResponseWriter, r *http.Request) {
+	j, _, ok := s.snapshot(r.PathValue("id"))
+	if !ok {
+		httpError(w, http.StatusNotFound, errors.New("no such deploy"))
+		return
+	}
+	writeHTTP(w, http.StatusOK, j)
+}
+
+// streamDeploy writes the job as a JSON line now and after every change,
+// until it is done or the client goes away.
+func (s *server) streamDeploy(w http.ResponseWriter, r *http.Request) {
+	j, changed, ok := s.snapshot(r.PathValue("id"))
+	if !ok {
+		httpError(w, http.StatusNotFound, errors.New("no such deploy"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for {
+		if err := enc.Encode(j); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if j.done() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		if j, changed, ok = s.snapshot(j.ID); !ok {
+			return
+		}
+	}
+}
+
+// versions shows the plan of the manifest: each document with its live
+// version and what a deploy would do.
+func (s *server) versions(w http.ResponseWriter, r *http.Request) {
+	m, err := deploy.LoadManifest(s.manifest)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	c, err := s.a.client()
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	plan, err := deploy.PlanManifest(r.Context(), c, m)
+	if err != nil {
+		httpError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeHTTP(w, http.StatusOK, plan.Steps)
+}
+
+// serverStats is what GET /stats shows.
+type serverStats struct {
+	InFlight  int        `json:"inFlight"`
+	Queued    int        `json:"queued"`
+	Succeeded int        `json:"succeeded"`
+	Failed    int        `json:"failed"`
+	LastError *lastError `json:"lastError"`
+	Uptime    float64    `json:"uptimeSeconds"`
+}
+
+// lastError is the error of the latest failed deploy.
+type lastError struct {
+	Deploy string    `json:"deploy"`
+	Error  string    `json:"error"`
+	Time   time.Time `json:"time"`
+}
+
+func (s *server) stats(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	st := s.forgotten
+	for _, j := range s.jobs {
+		st.count(j)
+	}
+	s.mu.Unlock()
+	st.Uptime = time.Since(s.started).Round(time.Second).Seconds()
+	writeHTTP(w, http.StatusOK, st)
+}
+
+// count adds j to st.
+func (st *serverStats) count(j *job) {
+	switch j.Status {
+	case jobRunning:
+		st.InFlight++
+	case jobQueued:
+		st.Queued++
+	case jobSucceeded:
+		st.Succeeded++
+	case jobFailed:
+		st.Failed++
+		if st.LastError == nil || j.Finished.After(st.LastError.Time) {
+			st.LastError = &lastError{Deploy: j.ID, Error: j.Error, Time: *j.Finished}
+		}
+	}
+}
+
+// forget drops the oldest deploys that have ended while there are more
+// than maxJobs. Deploys queued or running are kept, so that their streams
+// end. It is called with mu held.
+func (s *server) forget() {
+	n := len(s.jobs) - maxJobs
+	s.jobs = slices.DeleteFunc(s.jobs, func(j *job) bool {
+		if n <= 0 || !j.done() {
+			return false
+		}
+		s.forgotten.count(j)
+		n--
+		return true
+	})
+}
+
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHTTP(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz reports whether a deploy asked for now would be queued and could
+// start: it checks no credentials, so as not to ask Google for a token on
+// every probe.
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	var err error
+	switch {
+	case s.ctx.Err() != nil:
+		err = errors.New("shutting down")
+	case len(s.queue) == cap(s.queue):
+		err = errors.New("too many deploys queued")
+	default:
+		_, err = deploy.LoadManifest(s.manifest)
+	}
+	if err != nil {
+		httpError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeHTTP(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// snapshot returns a copy of job id and the channel closed on its next
+// change.
+func (s *server) snapshot(id string) (job, <-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.ID == id {
+			snap := *j
+			snap.Steps = slices.Clone(j.Steps)
+			return snap, s.changed, true
+		}
+	}
+	return job{}, nil, false
+}
+
+// update changes j with fn and wakes whoever watches it.
+func (s *server) update(j *job, fn func(*job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(j)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *server) work() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case j := <-s.queue:
+			err := s.run(j)
+			s.update(j, func(j *job) {
+				now := time.Now()
+				j.Finished = &now
+				j.Status = jobSucceeded
+				if err != nil {
+					j.Status, j.Error = jobFailed, err.Error()
+				}
+			})
+			if err != nil {
+				s.a.logger.Error("deploy failed", "job", j.ID, "job_correlation_id", j.CorrelationID, "err", err)
+			}
+		}
+	}
+}
+
+// run deploys the documents of j one by one, recording each step.
+func (s *server) run(j *job) error {
+	s.update(j, func(j *job) { j.Status = jobRunning })
+	m, err := deploy.LoadManifest(s.manifest)
+	if err != nil {
+		return err
+	}
+	if len(j.Documents) > 0 {
+		docs := make([]deploy.Document, 0, len(j.Documents))
+		for _, d := range j.Documents {
+			i := slices.IndexFunc(m.Documents, func(md deploy.Document) bool { return md.File == d.File })
+			if i < 0 {
+				return fmt.Errorf("%s is not in the manifest", d.File)
+			}
+			if d.Version == "" {
+				d.Version = m.Documents[i].Version
+			}
+			docs = append(docs, d)
+		}
+		m.Documents = docs
+	}
+	c, err := s.a.client()
+	if err != nil {
+		return err
+	}
+	ctx := drive.WithCorrelationID(s.ctx, j.CorrelationID)
+	plan, err := deploy.PlanManifest(ctx, c, m)
+	if err != nil {
+		return err
+	}
+	s.update(j, func(j *job) {
+		for _, step := range plan.Steps {
+			status := stepPending
+			if step.Kind == deploy.StepSkip {
+				status = stepSkipped
+			}
+			j.Steps = append(j.Steps, jobStep{DeployStep: step, Status: status})
+		}
+	})
+	failed, total := 0, 0
+	for i, step := range plan.Steps {
+		if step.Kind == deploy.StepSkip {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		total++
+		s.update(j, func(j *job) { j.Steps[i].Status = jobRunning })
+		_, err := deploy.DeployPDFResult(ctx, c.AccessToken, step.File, step.Version, m.TempFolder, m.Folder, m.ArchiveFolder, m.Dir)
+		s.update(j, func(j *job) {
+			j.Steps[i].Status = stepDeployed
+			if err != nil {
+				j.Steps[i].Status, j.Steps[i].Error = jobFailed, err.Error()
+			}
+		})
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &drive.BatchError{Failed: failed, Total: total, Summary: "deployments failed"}
+	}
+	return nil
+}
+
+func writeHTTP(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// httpError writes err as {"error": "..."}, like the CLI does for json
+// output.
+func httpError(w http.ResponseWriter, code int, err error) {
+	writeHTTP(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func TestServe(t *testing.T) {
+	var mu sync.Mutex
+	var uploadID string
+	drv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[{"id":"live","name":"mydoc.pdf","description":"v1"}]}`))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/"):
+			mu.Lock()
+			uploadID = r.Header.Get("X-Request-Id")
+			mu.Unlock()
+			w.Write([]byte(`{"id":"new"}`))
+		default:
+			w.Write([]byte(`{"id":"new","parents":["tmp"],"capabilities":{"canDelete":true}}`))
+		}
+	}))
+	defer drv.Close()
+	installTestClient(t, drv)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdf"), 0o644)
+	manifest := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(manifest, []byte("folder: pub\ntempFolder: tmp\ndocuments:\n  - {file: mydoc, version: v1}\n"), 0o644)
+
+	// The app is not set up by the root command, so drop any notifier an
+	// earlier test left behind.
+	deploy.Notifier = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := &app{accessToken: "tok", logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	api := httptest.NewServer(newServer(ctx, a, manifest, "secret").handler())
+	defer api.Close()
+
+	var requestID string
+	call := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, api.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		if requestID != "" {
+			req.Header.Set("X-Request-Id", requestID)
+		}
+		resp, err := api.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp, err := api.Client().Get(api.URL + "/versions")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("without token: %v, %v", resp.Status, err)
+	}
+
+	resp = call("GET", "/versions", "")
+	var steps []map[string]any
+	json.NewDecoder(resp.Body).Decode(&steps)
+	resp.Body.Close()
+	if len(steps) != 1 || steps[0]["live"] != "v1" || steps[0]["kind"] != "skip" {
+		t.Fatalf("versions = %v", steps)
+	}
+
+	requestID = "req-1"
+	resp = call("POST", "/deploys", `{"documents": [{"file": "mydoc", "version": "v2"}]}`)
+	requestID = ""
+	var started job
+	json.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || started.ID != "1" || resp.Header.Get("Location") != "/deploys/1" || started.CorrelationID != "req-1" {
+		t.Fatalf("start = %s %+v", resp.Status, started)
+	}
+
+	resp = call("GET", "/deploys/1/events", "")
+	var last job
+	lines := 0
+	for sc := bufio.NewScanner(resp.Body); sc.Scan(); lines++ {
+		if err := json.Unmarshal(sc.Bytes(), &last); err != nil {
+			t.Fatalf("event %q: %v", sc.Text(), err)
+		}
+	}
+	resp.Body.Close()
+	if last.Status != jobSucceeded || len(last.Steps) != 1 || last.Steps[0].Status != stepDeployed || last.Steps[0].Version != "v2" {
+		t.Fatalf("after %d events, deploy = %s: %+v (%s)", lines, last.Status, last.Steps, last.Error)
+	}
+	mu.Lock()
+	if uploadID != "req-1" {
+		t.Errorf("upload sent X-Request-Id %q, want the caller's", uploadID)
+	}
+	mu.Unlock()
+
+	resp = call("POST", "/deploys", `{"documents": [{"file": "other"}]}`)
+	resp.Body.Close()
+	resp = call("GET", "/deploys/2/events", "")
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	resp = call("GET", "/deploys", "")
+	var jobs []job
+	json.NewDecoder(resp.Body).Decode(&jobs)
+	resp.Body.Close()
+	if len(jobs) != 2 || jobs[0].Status != jobFailed || !strings.Contains(jobs[0].Error, "not in the manifest") || jobs[0].CorrelationID == "" {
+		t.Fatalf("deploys = %+v", jobs)
+	}
+
+	if resp = call("GET", "/deploys/9", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown deploy: %s", resp.Status)
+	}
+
+	resp = call("GET", "/metrics", "")
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{
+		`gdrivetoolbox_requests_total{method="POST",status="200"} 1`,
+		`gdrivetoolbox_uploaded_bytes_total `,
+		`gdrivetoolbox_deploy_duration_seconds_count{result="ok"} 1`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b)
+		}
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(manifest, []byte("folder: pub\ntempFolder: tmp\n"), 0o644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := &app{accessToken: "tok", logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := newServer(ctx, a, manifest, "secret")
+	api := httptest.NewServer(s.handler())
+	defer api.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", api.URL+path, nil)
+		if path == "/stats" {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		resp, err := api.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	// Probes need no token.
+	if code, body := get("/healthz"); code != http.StatusOK || body != `{"status":"ok"}` {
+		t.Errorf("healthz = %d %s", code, body)
+	}
+	if code, body := get("/readyz"); code != http.StatusOK || body != `{"status":"ready"}` {
+		t.Errorf("readyz = %d %s", code, body)
+	}
+
+	finished := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.mu.Lock()
+	s.jobs = []*job{
+		{ID: "1", Status: jobFailed, Error: "drive: status 500: boom", Finished: &finished},
+		{ID: "2", Status: jobSucceeded, Finished: &finished},
+		{ID: "3", Status: jobRunning},
+		{ID: "4", Status: jobQueued},
+	}
+	s.mu.Unlock()
+	code, body := get("/stats")
+	var st serverStats
+	if err := json.Unmarshal([]byte(body), &st); err != nil || code != http.StatusOK ||
+		st.InFlight != 1 || st.Queued != 1 || st.Succeeded != 1 || st.Failed != 1 ||
+		st.LastError == nil || st.LastError.Deploy != "1" || st.LastError.Error != "drive: status 500: boom" {
+		t.Errorf("stats = %d %s", code, body)
+	}
+
+	os.WriteFile(manifest, []byte("folder: pub\n"), 0o644)
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "tempFolder") {
+		t.Errorf("readyz with a broken manifest = %d %s", code, body)
+	}
+	cancel()
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "shutting down") {
+		t.Errorf("readyz when stopping = %d %s", code, body)
+	}
+}
+
+func TestServeLimits(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(manifest, []byte("folder: pub\ntempFolder: tmp\n"), 0o644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func(n int) { maxJobs = n }(maxJobs)
+	maxJobs = 2
+	a := &app{accessToken: "tok", logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s := newServer(ctx, a, manifest, "")
+	api := httptest.NewServer(s.handler())
+	defer api.Close()
+
+	resp, err := api.Client().Post(api.URL+"/deploys", "application/json", strings.NewReader(`{"documents": [`+strings.Repeat(" ", maxBodyBytes)+`]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: %s", resp.Status)
+	}
+
+	finished := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.mu.Lock()
+	s.jobs = []*job{
+		{ID: "1", Status: jobFailed, Error: "drive: status 500: boom", Finished: &finished},
+		{ID: "2", Status: jobSucceeded, Finished: &finished},
+	}
+	s.lastID = 2
+	s.mu.Unlock()
+	// An X-Request-Id too long to be a correlation ID is replaced.
+	req, _ := http.NewRequest("POST", api.URL+"/deploys", strings.NewReader(`{"documents": [{"file": "other"}]}`))
+	req.Header.Set("X-Request-Id", strings.Repeat("a", 129))
+	if resp, err = api.Client().Do(req); err != nil {
+		t.Fatal(err)
+	}
+	var started job
+	json.NewDecoder(resp.Body).Decode(&started)
+	resp.Body.Close()
+	if started.ID != "3" || len(started.CorrelationID) != 16 {
+		t.Fatalf("started deploy %q with correlation ID %q, want 3 with a new one", started.ID, started.CorrelationID)
+	}
+	resp, _ = api.Client().Get(api.URL + "/deploys/3/events")
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// The oldest deploy is forgotten, but still counted.
+	if resp, _ = api.Client().Get(api.URL + "/deploys/1"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("forgotten deploy: %s", resp.Status)
+	}
+	resp.Body.Close()
+	resp, _ = api.Client().Get(api.URL + "/stats")
+	var st serverStats
+	json.NewDecoder(resp.Body).Decode(&st)
+	resp.Body.Close()
+	if st.Succeeded != 1 || st.Failed != 2 || st.LastError == nil || st.LastError.Deploy != "3" {
+		t.Errorf("stats = %+v", st)
+	}
+}
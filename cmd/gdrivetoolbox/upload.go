@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/dirsync"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// uploaded is one line of upload's output. Stats is set on each file
+// uploaded alone, and on a directory's folder for all it sent.
+type uploaded struct {
+	Path   string       `json:"path"`
+	ID     string       `json:"id"`
+	Folder bool         `json:"folder,omitempty"`
+	Stats  *drive.Stats `json:"stats,omitempty"`
+}
+
+func newUploadCmd(a *app) *cobra.Command {
+	var to, name string
+	var noProgress, interactive bool
+	cmd := &cobra.Command{
+		Use:   "upload PATH... --to FOLDER",
+		Short: "Upload files and directories",
+		Long: `Upload copies each PATH into FOLDER, which is a folder ID, a Drive URL, or
+a path from My Drive such as /Reports/2024. PATH may be a glob, quoted so
+the shell leaves it alone. A directory is mirrored into a folder of the
+same name, which is created if needed; files already there with the same
+content are not sent again.
+
+A PATH of - uploads standard input as a file called --name:
+
+  pdflatex -output-format pdf report.tex | gdrivetoolbox upload - --name report.pdf --to /Reports
+
+With --interactive, FOLDER is chosen by browsing from --to instead.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch n := slices.Index(args, "-"); {
+			case n >= 0 && slices.Contains(args[n+1:], "-"):
+				return usageErrorf("standard input can be uploaded only once")
+			case n >= 0 && name == "":
+				return usageErrorf("uploading standard input needs --name")
+			case n < 0 && name != "":
+				return usageErrorf("--name is only for uploading standard input (-)")
+			case n >= 0 && interactive:
+				return usageErrorf("--interactive reads standard input, so it cannot upload it (-)")
+			}
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			parent, err := resolveFolder(ctx, c, to)
+			if err != nil {
+				return err
+			}
+			if interactive {
+				if parent, err = pickFolder(ctx, c, bufio.NewReader(cmd.InOrStdin()), a.messages(cmd), parent); err != nil {
+					return err
+				}
+			}
+			paths, err := expandGlobs(args)
+			if err != nil {
+				return err
+			}
+
+			var prog *transfers
+			if !noProgress && !a.quiet {
+				prog = newTransfers(cmd.ErrOrStderr())
+			}
+			var results []uploaded
+			failed := 0
+			var first error
+			for _, p := range paths {
+				var res []uploaded
+				if p == "-" {
+					res, err = upload(ctx, c, cmd.InOrStdin(), 0, name, parent, prog)
+				} else {
+					res, err = uploadPath(ctx, c, p, parent, prog)
+				}
+				results = append(results, res...)
+				if err != nil {
+					failed++
+					if first == nil {
+						first = fmt.Errorf("%s: %w", p, err)
+					}
+				}
+			}
+			prog.finish()
+
+			err = writeResults(a, cmd, results, func(w io.Writer, results []uploaded) error {
+				for _, r := range results {
+					fmt.Fprintf(w, "%s\t%s\n", r.ID, r.Path)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if failed > 0 {
+				return &drive.BatchError{Failed: failed, Total: len(paths), Summary: "uploads failed", First: first}
+			}
+			return nil
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&to, "to", "root", "destination folder ID, URL, or /path")
+	f.StringVar(&name, "name", "", "name of the file uploaded from standard input")
+	f.BoolVarP(&interactive, "interactive", "i", false, "choose the destination folder by browsing")
+	f.BoolVar(&noProgress, "no-progress", false, "do not report transfer progress")
+	folderFlags(cmd, "to")
+	return cmd
+}
+
+// expandGlobs replaces each argument containing glob characters with the
+// paths it matches. A glob that matches nothing is an error, as it is
+// almost always a typo.
+func expandGlobs(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		if !strings.ContainsAny(arg, "*?[") {
+			paths = append(paths, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", arg)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// uploadPath uploads the file or directory p into parentID, reporting
+// progress to prog.
+func uploadPath(ctx context.Context, c *drive.Client, p, parentID string, prog *transfers) ([]uploaded, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return uploadDir(ctx, c, p, parentID, prog)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return upload(ctx, c, f, info.Size(), filepath.Base(p), parentID, prog)
+}
+
+// upload uploads what is read from r, size bytes or 0 if unknown, as a file
+// called name in parentID.
+func upload(ctx context.Context, c *drive.Client, r io.Reader, size int64, name, parentID string, prog *transfers) ([]uploaded, error) {
+	prog.expect(1, size)
+	c.OnProgress = prog.track(name)
+	stats := &drive.Stats{}
+	start := time.Now()
+	cr := &countingReader{Reader: r}
+	id, err := c.Upload(drive.WithStats(ctx, stats), drive.Metadata{
+		Name:     name,
+		Parents:  []string{parentID},
+		MimeType: mime.TypeByExtension(filepath.Ext(name)),
+	}, cr, size)
+	if err != nil {
+		return nil, err
+	}
+	stats.Bytes, stats.Elapsed = cr.n, time.Since(start)
+	return []uploaded{{Path: name, ID: id, Stats: stats}}, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// uploadDir mirrors dir into a folder of the same name below parentID with
+// a dirsync push, reporting what it created or updated.
+func uploadDir(ctx context.Context, c *drive.Client, dir, parentID string, prog *transfers) ([]uploaded, error) {
+	name := filepath.Base(filepath.Clean(dir))
+	folderID, err := folder.NewResolver(c, 0).EnsureFolderPath(ctx, parentID, name)
+	if err != nil {
+		return nil, err
+	}
+	stats := &drive.Stats{}
+	start := time.Now()
+	ctx = drive.WithStats(ctx, stats)
+	results := []uploaded{{Path: name, ID: folderID, Folder: true, Stats: stats}}
+	plan, err := dirsync.PlanPush(ctx, c, dir, folderID, dirsync.PushOptions{})
+	if err != nil {
+		return results, err
+	}
+	for _, act := range plan.Actions {
+		if !act.Folder && (act.Op == dirsync.OpCreate || act.Op == dirsync.OpUpdate) {
+			prog.expect(1, act.Size)
+		}
+	}
+	c.OnProgress = prog.track(name + "/")
+	err = dirsync.Apply(ctx, c, plan)
+	stats.Elapsed = time.Since(start)
+	for _, act := range plan.Actions {
+		if act.Err == nil && act.RemoteID != "" && (act.Op == dirsync.OpCreate || act.Op == dirsync.OpUpdate) {
+			results = append(results, uploaded{Path: path.Join(name, act.Path), ID: act.RemoteID, Folder: act.Folder})
+			if !act.Folder {
+				stats.Bytes += act.Size
+			}
+		}
+	}
+	return results, err
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uploadServer accepts folder creations and multipart uploads, recording
+// the name and parent of each, and lists every folder as empty.
+type uploadServer struct {
+	mu      sync.Mutex
+	created map[string]string // name -> parent
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodGet {
+		w.Write([]byte(`{"files":[]}`))
+		return
+	}
+	var meta struct {
+		Name    string   `json:"name"`
+		Parents []string `json:"parents"`
+	}
+	if strings.HasPrefix(r.URL.Path, "/upload/") {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(part).Decode(&meta)
+	} else {
+		json.NewDecoder(r.Body).Decode(&meta)
+	}
+	io.Copy(io.Discard, r.Body)
+	s.created[meta.Name] = meta.Parents[0]
+	json.NewEncoder(w).Encode(map[string]string{"id": "id" + strconv.Itoa(len(s.created)), "name": meta.Name})
+}
+
+func TestUpload(t *testing.T) {
+	s := &uploadServer{created: map[string]string{}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.pdf":      "a",
+		"b.pdf":      "b",
+		"notes.txt":  "n",
+		"site/index": "i",
+		"site/css/s": "s",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := run(t, "upload", "--access-token", "tok", "--to", "dest", "--output", "json", "--no-progress",
+		filepath.Join(dir, "*.pdf"), filepath.Join(dir, "site"))
+	if err != nil {
+		t.Fatalf("upload: %v\n%s", err, out)
+	}
+	var got []uploaded
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	var paths []string
+	for _, u := range got {
+		if u.ID == "" {
+			t.Errorf("%s has no ID", u.Path)
+		}
+		paths = append(paths, u.Path)
+		var want int64 = -1 // no stats
+		switch u.Path {
+		case "a.pdf", "b.pdf":
+			want = 1
+		case "site":
+			want = 2
+		}
+		if u.Stats == nil && want >= 0 || u.Stats != nil && u.Stats.Bytes != want {
+			t.Errorf("%s: stats %+v, want %d bytes", u.Path, u.Stats, want)
+		}
+	}
+	sort.Strings(paths)
+	want := "a.pdf b.pdf site site/css site/css/s site/index"
+	if strings.Join(paths, " ") != want {
+		t.Fatalf("uploaded %v, want %s", paths, want)
+	}
+	if s.created["a.pdf"] != "dest" || s.created["site"] != "dest" || s.created["index"] == "dest" {
+		t.Fatalf("parents = %v", s.created)
+	}
+	if _, ok := s.created["notes.txt"]; ok {
+		t.Fatal("notes.txt was uploaded though no argument matched it")
+	}
+
+	if _, err := run(t, "upload", "--access-token", "tok", filepath.Join(dir, "*.doc")); err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Fatalf("unmatched glob: err = %v", err)
+	}
+}
+
+func TestUploadStdin(t *testing.T) {
+	s := &uploadServer{created: map[string]string{}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	root := newRootCmd()
+	var out strings.Builder
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetIn(strings.NewReader("%PDF-1.7"))
+	root.SetArgs([]string{"upload", "--access-token", "tok", "--no-progress", "--to", "dest", "-", "--name", "report.pdf"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("upload -: %v\n%s", err, out.String())
+	}
+	if s.created["report.pdf"] != "dest" || !strings.Contains(out.String(), "report.pdf") {
+		t.Fatalf("created %v; output %q", s.created, out.String())
+	}
+
+	for _, args := range [][]string{
+		{"-"},
+		{"-", "-", "--name", "x"},
+		{"a.pdf", "--name", "x"},
+	} {
+		_, err := run(t, append([]string{"upload", "--access-token", "tok"}, args...)...)
+		if exitCode(err) != exitUsage {
+			t.Errorf("upload %v: err = %v, want a usage error", args, err)
+		}
+	}
+}
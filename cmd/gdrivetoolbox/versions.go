@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func newVersionsCmd(a *app) *cobra.Command {
+	opts := deploy.VersionsOptions{}
+	cmd := &cobra.Command{
+		Use:   "versions FILE",
+		Short: "List the deployed, archived, and stored versions of a PDF",
+		Long: `Versions lists every version of a deployed PDF that Drive holds: the live
+one, the copies deploy archived into --archive-folder, and with
+--revisions the stored revisions of the live file, which rollback
+--revision restores. Each comes with when it was deployed and by whom.
+
+With --folder, FILE is the PDF name as given to deploy, with or without
+.pdf. Without it, FILE is the live file itself: an ID, a Drive URL, or a
+path from My Drive such as /Published/mydoc.pdf.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			c, err := a.client()
+			if err != nil {
+				return err
+			}
+			opts.FileName = strings.TrimSuffix(args[0], ".pdf")
+			if opts.FolderID == "" {
+				id, err := resolveFile(ctx, c, args[0])
+				if err != nil {
+					return err
+				}
+				f, err := c.GetFile(ctx, id, "id", "name", "parents")
+				if err != nil {
+					return err
+				}
+				if len(f.Parents) == 0 {
+					return fmt.Errorf("%s is in no folder", f.Name)
+				}
+				opts.FileName, opts.FolderID = strings.TrimSuffix(f.Name, ".pdf"), f.Parents[0]
+			}
+			versions, err := deploy.ListVersions(ctx, c, opts)
+			if err != nil {
+				return err
+			}
+			return writeResults(a, cmd, versions, func(w io.Writer, versions []deploy.Version) error {
+				if len(versions) == 0 {
+					fmt.Fprintf(a.messages(cmd), "No versions of %s.pdf found.\n", opts.FileName)
+					return nil
+				}
+				tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+				for _, v := range versions {
+					version := cmp.Or(v.Version, v.RevisionID)
+					if v.Pinned {
+						version += " (pinned)"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.Where, version, v.Time.Local().Format("2006-01-02 15:04"), cmp.Or(v.By, "-"), v.FileID)
+				}
+				return tw.Flush()
+			})
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&opts.FolderID, "folder", "", "live Drive folder ID or URL, to name FILE as deploy does")
+	f.StringVar(&opts.ArchiveFolderID, "archive-folder", "", "Drive folder ID or URL holding archived versions")
+	f.BoolVar(&opts.Revisions, "revisions", false, "also list the stored revisions of the live file")
+	folderFlags(cmd, "folder", "archive-folder")
+	return cmd
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	defer func(loc *time.Location) { time.Local = loc }(time.Local)
+	time.Local = time.UTC
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query().Get("q")
+		switch {
+		case r.URL.Path == "/drive/v3/files" && strings.Contains(q, "'pub' in parents"):
+			w.Write([]byte(`{"files":[{"id":"live","name":"mydoc.pdf","description":"v3","createdTime":"2024-03-01T09:30:00Z"}]}`))
+		case r.URL.Path == "/drive/v3/files" && strings.Contains(q, "'arc' in parents"):
+			w.Write([]byte(`{"files":[{"id":"old","name":"mydoc-v2.pdf","description":"v2","createdTime":"2024-02-01T09:30:00Z"}]}`))
+		case r.URL.Path == "/drive/v3/files/live":
+			w.Write([]byte(`{"id":"live","name":"mydoc.pdf","parents":["pub"]}`))
+		case strings.HasSuffix(r.URL.Path, "/revisions"):
+			w.Write([]byte(`{"revisions":[{"id":"r1","keepForever":true,"modifiedTime":"2024-03-01T09:30:00Z","lastModifyingUser":{"emailAddress":"ada@example.com"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	want := "" +
+		"live      v3           2024-03-01 09:30  ada@example.com  live\n" +
+		"archive   v2           2024-02-01 09:30  ada@example.com  old\n" +
+		"revision  r1 (pinned)  2024-03-01 09:30  ada@example.com  live\n"
+	for _, args := range [][]string{
+		{"mydoc", "--folder", "pub"},
+		{"mydoc.pdf", "--folder", "pub"},
+		{"live"},
+	} {
+		args = append([]string{"versions", "--access-token", "tok", "--archive-folder", "arc", "--revisions"}, args...)
+		out, err := run(t, args...)
+		if err != nil || out != want {
+			t.Errorf("%v = %q, %v; want %q", args, out, err, want)
+		}
+	}
+}
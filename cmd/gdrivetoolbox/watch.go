@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func newWatchCmd(a *app) *cobra.Command {
+	var manifest string
+	var debounce time.Duration
+	var metricsAddr string
+	cmd := &cobra.Command{
+		Use:   "watch DIR -f MANIFEST",
+		Short: "Deploy a manifest's documents whenever they change",
+		Long: `Watch deploys a manifest like "apply --yes", taking the PDFs from DIR in
+place of the manifest's dir, and deploys it again each time a PDF in DIR
+or the manifest itself changes. Changes are gathered until none has come
+for --debounce, so a build writing many files deploys once.
+
+As with apply, only documents whose version is not live yet are deployed:
+a PDF saved again under the version already live is left alone until its
+version in the manifest changes. A failed deploy is logged and tried again
+on the next change. Watch runs until interrupted.
+
+--metrics-addr serves Prometheus metrics at /metrics on that address: Drive
+requests, bytes transferred, and deploy durations.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			dir := filepath.Clean(args[0])
+			if metricsAddr != "" {
+				mux := http.NewServeMux()
+				mux.Handle("GET /metrics", a.collectMetrics())
+				srv := &http.Server{Addr: metricsAddr, Handler: mux}
+				go func() {
+					if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+						a.logger.Error("metrics server failed", "err", err)
+					}
+				}()
+				defer srv.Close()
+			}
+			w, err := fsnotify.NewWatcher()
+			if err != nil {
+				return err
+			}
+			defer w.Close()
+			// Directories rather than files are watched, so files replaced
+			// by a rename, as editors save them, are still seen.
+			for _, d := range []string{dir, filepath.Dir(manifest)} {
+				if err := w.Add(d); err != nil {
+					return err
+				}
+			}
+			relevant := func(name string) bool {
+				name = filepath.Clean(name)
+				return name == filepath.Clean(manifest) ||
+					filepath.Dir(name) == dir && filepath.Ext(name) == ".pdf"
+			}
+
+			deployAll := func() {
+				if err := watchDeploy(ctx, cmd, a, manifest, dir); err != nil && ctx.Err() == nil {
+					a.logger.Error("deploy failed", "err", err)
+				}
+			}
+			a.logger.Info("watching", "dir", dir, "manifest", manifest)
+			deployAll()
+			var timer <-chan time.Time
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case ev, ok := <-w.Events:
+					if !ok {
+						return nil
+					}
+					if ev.Has(fsnotify.Create|fsnotify.Write) && relevant(ev.Name) {
+						a.logger.Debug("changed", "file", ev.Name)
+						timer = time.After(debounce)
+					}
+				case err, ok := <-w.Errors:
+					if !ok {
+						return nil
+					}
+					a.logger.Warn("watch error", "err", err)
+				case <-timer:
+					timer = nil
+					deployAll()
+				}
+			}
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&manifest, "file", "f", "deploy.yaml", "deploy manifest")
+	f.DurationVar(&debounce, "debounce", 2*time.Second, "how long changes must settle before deploying")
+	f.StringVar(&metricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on, e.g. :9090")
+	notifyFlags(cmd, a)
+	verifyFlag(cmd, a)
+	return cmd
+}
+
+// watchDeploy deploys the documents of the manifest at path, read from
+// dir, that are not up to date, showing the plan first.
+func watchDeploy(ctx context.Context, cmd *cobra.Command, a *app, path, dir string) error {
+	m, err := deploy.LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	m.Dir = dir
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	plan, err := deploy.PlanManifest(ctx, c, m)
+	if err != nil {
+		return err
+	}
+	if plan.Changes() == 0 {
+		a.logger.Info("nothing to deploy")
+		return nil
+	}
+	msgs := a.messages(cmd)
+	a.palette(msgs).writePlan(msgs, plan)
+	if err := deploy.ApplyManifest(ctx, c, plan); err != nil {
+		return err
+	}
+	if a.machine() {
+		return writePlan(a, cmd, plan)
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, a.palette(out).green(fmt.Sprintf("deployed %d documents", plan.Changes())))
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe to write while a test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	var mu sync.Mutex
+	uploads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/"):
+			mu.Lock()
+			uploads++
+			mu.Unlock()
+			w.Write([]byte(`{"id":"new"}`))
+		default:
+			w.Write([]byte(`{"id":"new","parents":["tmp"]}`))
+		}
+	}))
+	defer srv.Close()
+	installTestClient(t, srv)
+
+	dir := t.TempDir()
+	pdfs := filepath.Join(dir, "pdfs")
+	os.Mkdir(pdfs, 0o755)
+	os.WriteFile(filepath.Join(pdfs, "mydoc.pdf"), []byte("pdf"), 0o644)
+	manifest := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(manifest, []byte("folder: pub\ntempFolder: tmp\ndocuments:\n  - {file: mydoc, version: v1}\n"), 0o644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	root := newRootCmd()
+	var out lockedBuffer
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs([]string{"watch", pdfs, "-f", manifest, "--debounce", "20ms", "--access-token", "tok"})
+	done := make(chan error)
+	go func() { done <- root.ExecuteContext(ctx) }()
+
+	waitUploads := func(n int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			mu.Lock()
+			got := uploads
+			mu.Unlock()
+			if got >= n {
+				return
+			}
+		}
+		t.Fatalf("no upload %d; output:\n%s", n, out.String())
+	}
+	waitUploads(1)
+	os.WriteFile(filepath.Join(pdfs, "notes.txt"), []byte("ignored"), 0o644)
+	os.WriteFile(filepath.Join(pdfs, "mydoc.pdf"), []byte("pdf v2"), 0o644)
+	waitUploads(2)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if got := strings.Count(out.String(), "deployed 1 documents"); got != 2 {
+		t.Errorf("deployed %d times, want 2; output:\n%s", got, out.String())
+	}
+}
@@ -0,0 +1,146 @@
+// Package comments posts and reads comments on Drive files.
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Fields is the field selection that populates every Comment field. The
+// comments API requires an explicit selection.
+const Fields = "id,content,author(displayName,emailAddress),createdTime,modifiedTime,resolved,deleted,quotedFileContent(value),replies(id,content,author(displayName,emailAddress),createdTime,action)"
+
+// Author is who wrote a comment or reply. EmailAddress is only reported
+// for the caller's own comments.
+type Author struct {
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+}
+
+// Comment is a comment thread on a file.
+type Comment struct {
+	ID           string    `json:"id"`
+	Content      string    `json:"content"`
+	Author       Author    `json:"author"`
+	CreatedTime  time.Time `json:"createdTime"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+	Resolved     bool      `json:"resolved"`
+	Deleted      bool      `json:"deleted"`
+	// Quoted is the document text the comment is anchored to, if any.
+	Quoted  string  `json:"-"`
+	Replies []Reply `json:"replies"`
+}
+
+// Reply is one reply in a comment thread. Action is "resolve" or "reopen"
+// when the reply changed the thread's state.
+type Reply struct {
+	ID          string    `json:"id"`
+	Content     string    `json:"content"`
+	Author      Author    `json:"author"`
+	CreatedTime time.Time `json:"createdTime"`
+	Action      string    `json:"action,omitempty"`
+}
+
+type rawComment struct {
+	Comment
+	QuotedFileContent struct {
+		Value string `json:"value"`
+	} `json:"quotedFileContent"`
+}
+
+func (r rawComment) comment() Comment {
+	c := r.Comment
+	c.Quoted = r.QuotedFileContent.Value
+	return c
+}
+
+func commentsPath(fileID string) (string, error) {
+	id, err := drive.ParseID(fileID)
+	if err != nil {
+		return "", err
+	}
+	return "files/" + url.PathEscape(id) + "/comments", nil
+}
+
+// Post adds an unanchored comment to fileID, e.g. "Deployed v3.2 from
+// pipeline #123".
+func Post(ctx context.Context, c *drive.Client, fileID, content string) (Comment, error) {
+	if content == "" {
+		return Comment{}, errors.New("comment content is required")
+	}
+	path, err := commentsPath(fileID)
+	if err != nil {
+		return Comment{}, err
+	}
+	var out rawComment
+	q := url.Values{"fields": {Fields}}
+	if err := c.DoJSON(ctx, http.MethodPost, path+"?"+q.Encode(), map[string]string{"content": content}, &out); err != nil {
+		return Comment{}, err
+	}
+	return out.comment(), nil
+}
+
+// ReplyTo adds a reply to a comment thread.
+func ReplyTo(ctx context.Context, c *drive.Client, fileID, commentID, content string) (Reply, error) {
+	if content == "" {
+		return Reply{}, errors.New("reply content is required")
+	}
+	path, err := commentsPath(fileID)
+	if err != nil {
+		return Reply{}, err
+	}
+	var out Reply
+	q := url.Values{"fields": {"id,content,author(displayName,emailAddress),createdTime,action"}}
+	if err := c.DoJSON(ctx, http.MethodPost, path+"/"+url.PathEscape(commentID)+"/replies?"+q.Encode(), map[string]string{"content": content}, &out); err != nil {
+		return Reply{}, err
+	}
+	return out, nil
+}
+
+// List returns the comment threads on fileID, oldest first. Deleted
+// comments are left out.
+func List(ctx context.Context, c *drive.Client, fileID string) ([]Comment, error) {
+	path, err := commentsPath(fileID)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{"fields": {"nextPageToken,comments(" + Fields + ")"}, "pageSize": {"100"}}
+	var all []Comment
+	for {
+		var page struct {
+			NextPageToken string       `json:"nextPageToken"`
+			Comments      []rawComment `json:"comments"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("list comments: %w", err)
+		}
+		for _, r := range page.Comments {
+			all = append(all, r.comment())
+		}
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// ListUnresolved returns the open comment threads on fileID.
+func ListUnresolved(ctx context.Context, c *drive.Client, fileID string) ([]Comment, error) {
+	all, err := List(ctx, c, fileID)
+	if err != nil {
+		return nil, err
+	}
+	var open []Comment
+	for _, cm := range all {
+		if !cm.Resolved && !cm.Deleted {
+			open = append(open, cm)
+		}
+	}
+	return open, nil
+}
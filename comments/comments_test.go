@@ -0,0 +1,72 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func TestComments(t *testing.T) {
+	var posted map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fields") == "" {
+			http.Error(w, "fields is required", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files/f/comments":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"id":"c9","content":"` + posted["content"] + `","author":{"displayName":"CI"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files/f/comments/c1/replies":
+			w.Write([]byte(`{"id":"r1","content":"done","action":"resolve"}`))
+		case r.Method == http.MethodGet && r.URL.Query().Get("pageToken") == "":
+			w.Write([]byte(`{"nextPageToken":"n","comments":[
+				{"id":"c1","content":"Typo in step 3","quotedFileContent":{"value":"stpe"}},
+				{"id":"c2","content":"Fixed","resolved":true}]}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"comments":[{"id":"c3","content":"Please add a diagram","replies":[{"id":"r0","content":"+1"}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	ctx := context.Background()
+
+	cm, err := Post(ctx, c, "f", "Deployed v3.2 from pipeline #123")
+	if err != nil || cm.ID != "c9" || posted["content"] != "Deployed v3.2 from pipeline #123" {
+		t.Fatalf("Post = %+v, %v", cm, err)
+	}
+	if _, err := Post(ctx, c, "f", ""); err == nil {
+		t.Fatal("expected error for empty comment")
+	}
+
+	open, err := ListUnresolved(ctx, c, "f")
+	if err != nil || len(open) != 2 || open[0].Quoted != "stpe" || open[1].ID != "c3" || len(open[1].Replies) != 1 {
+		t.Fatalf("ListUnresolved = %+v, %v", open, err)
+	}
+
+	if r, err := ReplyTo(ctx, c, "f", "c1", "done"); err != nil || r.Action != "resolve" {
+		t.Fatalf("ReplyTo = %+v, %v", r, err)
+	}
+}
@@ -0,0 +1,339 @@
+// Package crypt encrypts file content client-side before it reaches Drive.
+//
+// Content is sealed with AES-256-GCM in fixed-size chunks so files of any
+// size stream through without being buffered. The parameters needed to
+// decrypt (algorithm, chunk size, base nonce, and a key fingerprint) are
+// stored in the file's appProperties; the key itself never leaves the caller.
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Algorithm identifies the chunked AES-256-GCM format written by this package.
+const Algorithm = "AES-256-GCM-CHUNKED"
+
+// KeySize is the required length of a raw key in bytes.
+const KeySize = 32
+
+// DefaultChunkSize is the plaintext size of every chunk but the last.
+const DefaultChunkSize = 64 << 10
+
+// MaxChunkSize is the largest chunk size Decrypt accepts. The size is read
+// from an unauthenticated property that anyone who can edit the file may
+// set, and Decrypt holds a whole chunk in memory.
+const MaxChunkSize = 16 << 20
+
+// appProperties keys describing the cipher of an encrypted file.
+const (
+	PropAlgorithm = "gdtEncAlg"
+	PropKeyID     = "gdtEncKeyId"
+	PropNonce     = "gdtEncNonce"
+	PropChunkSize = "gdtEncChunk"
+)
+
+var (
+	// ErrNotEncrypted is returned when a file carries no cipher metadata.
+	ErrNotEncrypted = errors.New("crypt: file is not encrypted")
+	// ErrWrongKey is returned when a file was encrypted with a different key.
+	ErrWrongKey = errors.New("crypt: file was encrypted with a different key")
+	// ErrCorrupt is returned when ciphertext fails authentication or is truncated.
+	ErrCorrupt = errors.New("crypt: ciphertext is corrupt or truncated")
+)
+
+// Key is a user-supplied AES-256 key.
+type Key struct {
+	aead cipher.AEAD
+	id   string
+}
+
+// NewKey returns a Key for a raw 32-byte secret.
+func NewKey(secret []byte) (*Key, error) {
+	if len(secret) != KeySize {
+		return nil, fmt.Errorf("crypt: key must be %d bytes, got %d", KeySize, len(secret))
+	}
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte("gdrivetoolbox key id\x00"), secret...))
+	return &Key{aead: aead, id: hex.EncodeToString(sum[:8])}, nil
+}
+
+// ParseKey decodes a base64 (standard or URL alphabet) or hex encoded key.
+func ParseKey(s string) (*Key, error) {
+	for _, dec := range []func(string) ([]byte, error){
+		base64.StdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+		hex.DecodeString,
+	} {
+		if b, err := dec(s); err == nil && len(b) == KeySize {
+			return NewKey(b)
+		}
+	}
+	return nil, fmt.Errorf("crypt: key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// GenerateKey returns a new random key encoded as base64, suitable for ParseKey.
+func GenerateKey() (string, error) {
+	b := make([]byte, KeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// ID is a short fingerprint of the key, stored alongside ciphertext so the
+// wrong key is reported as such rather than as corrupt data.
+func (k *Key) ID() string {
+	return k.id
+}
+
+// IsEncrypted reports whether props carry cipher metadata.
+func IsEncrypted(props map[string]string) bool {
+	return props[PropAlgorithm] != ""
+}
+
+// EncryptedSize returns the ciphertext length for size bytes of plaintext.
+func EncryptedSize(size int64) int64 {
+	chunks := (size + DefaultChunkSize - 1) / DefaultChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*16
+}
+
+// Encrypt returns a reader yielding the encrypted form of r, and the
+// appProperties that must be stored with it for Decrypt.
+func Encrypt(k *Key, r io.Reader) (io.Reader, map[string]string, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	props := map[string]string{
+		PropAlgorithm: Algorithm,
+		PropKeyID:     k.id,
+		PropNonce:     base64.StdEncoding.EncodeToString(nonce),
+		PropChunkSize: strconv.Itoa(DefaultChunkSize),
+	}
+	return &encryptReader{
+		key:   k,
+		src:   bufio.NewReaderSize(r, DefaultChunkSize),
+		nonce: nonce,
+		plain: make([]byte, DefaultChunkSize),
+	}, props, nil
+}
+
+// Decrypt returns a writer that decrypts everything written to it into w.
+// Close must be called once all ciphertext is written; it verifies that the
+// stream was not truncated.
+func Decrypt(k *Key, props map[string]string, w io.Writer) (io.WriteCloser, error) {
+	if !IsEncrypted(props) {
+		return nil, ErrNotEncrypted
+	}
+	if alg := props[PropAlgorithm]; alg != Algorithm {
+		return nil, fmt.Errorf("crypt: unsupported algorithm %q", alg)
+	}
+	if props[PropKeyID] != k.id {
+		return nil, ErrWrongKey
+	}
+	nonce, err := base64.StdEncoding.DecodeString(props[PropNonce])
+	if err != nil || len(nonce) != k.aead.NonceSize() {
+		return nil, fmt.Errorf("crypt: invalid nonce property")
+	}
+	chunk, err := strconv.Atoi(props[PropChunkSize])
+	if err != nil || chunk <= 0 || chunk > MaxChunkSize {
+		return nil, fmt.Errorf("crypt: invalid chunk size property")
+	}
+	return &decryptWriter{key: k, w: w, nonce: nonce, sealed: chunk + k.aead.Overhead()}, nil
+}
+
+// chunkNonce derives the nonce of chunk i by XORing the counter into the
+// last 8 bytes of the base nonce.
+func chunkNonce(dst, base []byte, i uint64) []byte {
+	dst = append(dst[:0], base...)
+	var ctr [8]byte
+	binary.BigEndian.PutUint64(ctr[:], i)
+	for j := range ctr {
+		dst[len(dst)-8+j] ^= ctr[j]
+	}
+	return dst
+}
+
+// chunkAAD marks the final chunk so truncation at a chunk boundary is detected.
+func chunkAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type encryptReader struct {
+	key     *Key
+	src     *bufio.Reader
+	nonce   []byte
+	scratch []byte
+	plain   []byte
+	out     bytes.Buffer
+	counter uint64
+	done    bool
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for e.out.Len() == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+	return e.out.Read(p)
+}
+
+func (e *encryptReader) sealNext() error {
+	n, err := io.ReadFull(e.src, e.plain)
+	last := false
+	switch err {
+	case nil:
+		if _, perr := e.src.Peek(1); perr == io.EOF {
+			last = true
+		} else if perr != nil {
+			return perr
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	e.scratch = chunkNonce(e.scratch, e.nonce, e.counter)
+	e.counter++
+	e.out.Write(e.key.aead.Seal(nil, e.scratch, e.plain[:n], chunkAAD(last)))
+	e.done = last
+	return nil
+}
+
+type decryptWriter struct {
+	key     *Key
+	w       io.Writer
+	nonce   []byte
+	scratch []byte
+	sealed  int
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+func (d *decryptWriter) Write(p []byte) (int, error) {
+	if d.closed {
+		return 0, errors.New("crypt: write after close")
+	}
+	d.buf = append(d.buf, p...)
+	// A full chunk is only known not to be the last once more data follows it.
+	for len(d.buf) > d.sealed {
+		if err := d.open(d.buf[:d.sealed], false); err != nil {
+			return 0, err
+		}
+		d.buf = append(d.buf[:0], d.buf[d.sealed:]...)
+	}
+	return len(p), nil
+}
+
+func (d *decryptWriter) Close() error {
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	if len(d.buf) == 0 {
+		return ErrCorrupt
+	}
+	return d.open(d.buf, true)
+}
+
+func (d *decryptWriter) open(sealed []byte, last bool) error {
+	d.scratch = chunkNonce(d.scratch, d.nonce, d.counter)
+	d.counter++
+	plain, err := d.key.aead.Open(nil, d.scratch, sealed, chunkAAD(last))
+	if err != nil {
+		return ErrCorrupt
+	}
+	_, err = d.w.Write(plain)
+	return err
+}
+
+// Upload encrypts content with k and uploads it with c, recording the
+// cipher metadata in the file's appProperties. size is the plaintext size
+// and may be 0 if unknown.
+func Upload(ctx context.Context, c *drive.Client, k *Key, meta drive.Metadata, content io.Reader, size int64) (string, error) {
+	enc, props, err := Encrypt(k, content)
+	if err != nil {
+		return "", err
+	}
+	merged := make(map[string]string, len(meta.AppProperties)+len(props))
+	for name, v := range meta.AppProperties {
+		merged[name] = v
+	}
+	for name, v := range props {
+		merged[name] = v
+	}
+	meta.AppProperties = merged
+	// Drive would otherwise sniff or convert the ciphertext.
+	meta.MimeType = "application/octet-stream"
+	if size > 0 {
+		size = EncryptedSize(size)
+	}
+	return c.Upload(ctx, meta, enc, size)
+}
+
+// Download fetches fileID with c, decrypts it with k, and writes the
+// plaintext to w. It returns the number of plaintext bytes written.
+func Download(ctx context.Context, c *drive.Client, k *Key, fileID string, w io.Writer) (int64, error) {
+	props, err := c.AppProperties(ctx, fileID)
+	if err != nil {
+		return 0, fmt.Errorf("read cipher metadata: %w", err)
+	}
+	cw := &countingWriter{w: w}
+	dw, err := Decrypt(k, props, cw)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.Download(ctx, fileID, dw); err != nil {
+		return cw.n, err
+	}
+	if err := dw.Close(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,195 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func testKey(t *testing.T) *Key {
+	t.Helper()
+	s, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	k, err := ParseKey(s)
+	if err != nil {
+		t.Fatalf("ParseKey: %v", err)
+	}
+	return k
+}
+
+func roundTrip(t *testing.T, k *Key, plain []byte) []byte {
+	t.Helper()
+	enc, props, err := Encrypt(k, bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	sealed, err := io.ReadAll(enc)
+	if err != nil {
+		t.Fatalf("read ciphertext: %v", err)
+	}
+	if int64(len(sealed)) != EncryptedSize(int64(len(plain))) {
+		t.Fatalf("ciphertext is %d bytes; EncryptedSize says %d", len(sealed), EncryptedSize(int64(len(plain))))
+	}
+	var out bytes.Buffer
+	dw, err := Decrypt(k, props, &out)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	// write in odd-sized pieces to exercise chunk reassembly
+	for rest := sealed; len(rest) > 0; {
+		n := 1000
+		if n > len(rest) {
+			n = len(rest)
+		}
+		if _, err := dw.Write(rest[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := dw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := testKey(t)
+	for _, size := range []int{0, 1, DefaultChunkSize - 1, DefaultChunkSize, DefaultChunkSize + 1, 3*DefaultChunkSize + 17} {
+		plain := bytes.Repeat([]byte("x"), size)
+		if got := roundTrip(t, k, plain); !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestDecrypt_Errors(t *testing.T) {
+	k := testKey(t)
+	enc, props, _ := Encrypt(k, strings.NewReader(strings.Repeat("a", 2*DefaultChunkSize)))
+	sealed, _ := io.ReadAll(enc)
+
+	if _, err := Decrypt(testKey(t), props, io.Discard); !errors.Is(err, ErrWrongKey) {
+		t.Fatalf("expected ErrWrongKey, got %v", err)
+	}
+	if _, err := Decrypt(k, map[string]string{}, io.Discard); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted, got %v", err)
+	}
+	for _, size := range []string{"0", "x", "2147483647"} {
+		bad := maps.Clone(props)
+		bad[PropChunkSize] = size
+		if _, err := Decrypt(k, bad, io.Discard); err == nil || !strings.Contains(err.Error(), "invalid chunk size property") {
+			t.Errorf("chunk size %s: got %v", size, err)
+		}
+	}
+
+	// dropping the final chunk must not go unnoticed
+	dw, _ := Decrypt(k, props, io.Discard)
+	dw.Write(sealed[:DefaultChunkSize+16])
+	if err := dw.Close(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for truncation, got %v", err)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[10] ^= 1
+	dw, _ = Decrypt(k, props, io.Discard)
+	_, err := dw.Write(tampered)
+	if err == nil {
+		err = dw.Close()
+	}
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt for tampering, got %v", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := ParseKey(strings.Repeat("ab", KeySize)); err != nil {
+		t.Fatalf("hex key: %v", err)
+	}
+	if _, err := ParseKey("c2hvcnQ="); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
+
+func TestUploadDownload(t *testing.T) {
+	var stored []byte
+	var storedProps map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/upload/drive/v3/files":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			metaPart, _ := mr.NextPart()
+			var meta drive.Metadata
+			json.NewDecoder(metaPart).Decode(&meta)
+			storedProps = meta.AppProperties
+			filePart, _ := mr.NextPart()
+			stored, _ = io.ReadAll(filePart)
+			w.Write([]byte(`{"id":"enc1"}`))
+		case r.URL.Path == "/drive/v3/files/enc1" && r.URL.Query().Get("alt") == "media":
+			w.Write(stored)
+		case r.URL.Path == "/drive/v3/files/enc1":
+			json.NewEncoder(w).Encode(map[string]interface{}{"appProperties": storedProps})
+		default:
+			http.Error(w, "unexpected "+r.URL.String(), http.StatusNotImplemented)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	k := testKey(t)
+
+	id, err := Upload(context.Background(), c, k, drive.Metadata{
+		Name:          "secret.txt",
+		AppProperties: map[string]string{"team": "ops"},
+	}, strings.NewReader("top secret"), 10)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if bytes.Contains(stored, []byte("top secret")) {
+		t.Fatal("plaintext reached the server")
+	}
+	if storedProps["team"] != "ops" || storedProps[PropKeyID] != k.ID() {
+		t.Fatalf("unexpected appProperties: %v", storedProps)
+	}
+
+	var out bytes.Buffer
+	n, err := Download(context.Background(), c, k, id, &out)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if n != 10 || out.String() != "top secret" {
+		t.Fatalf("got %d %q", n, out.String())
+	}
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// BatchMetrics sums up the deploys of an applied plan for release
+// dashboards, next to the per-document results.
+type BatchMetrics struct {
+	Documents int `json:"documents"`
+	// Outcomes counts the documents by status; those up to date in the
+	// plan count as skipped.
+	Outcomes map[string]int  `json:"outcomes"`
+	Bytes    int64           `json:"bytes"`
+	Retries  int             `json:"retries"`
+	Duration DurationMetrics `json:"durationSeconds"`
+	// APICalls is how many requests the batch made, retries included, if
+	// counted by the caller; DeployPlan.Metrics leaves it 0.
+	APICalls int64 `json:"apiCalls"`
+}
+
+// DurationMetrics are the durations of the deploys of a batch, in seconds.
+type DurationMetrics struct {
+	Total float64 `json:"total"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+	Max   float64 `json:"max"`
+}
+
+// Metrics returns the BatchMetrics of p, once ApplyManifest has run it.
+func (p *DeployPlan) Metrics() BatchMetrics {
+	m := BatchMetrics{Documents: len(p.Steps), Outcomes: map[string]int{
+		StatusDeployed: 0, StatusSkipped: 0, StatusFailed: 0,
+	}}
+	for _, s := range p.Steps {
+		if s.Kind == StepSkip {
+			m.Outcomes[StatusSkipped]++
+		}
+	}
+	took := make([]time.Duration, 0, len(p.Results))
+	for _, r := range p.Results {
+		m.Outcomes[r.Status]++
+		m.Bytes += r.Stats.Bytes
+		m.Retries += r.Stats.Retries
+		took = append(took, r.Stats.Elapsed)
+	}
+	m.Duration = durationMetrics(took)
+	return m
+}
+
+// durationMetrics returns the total, mean, median, 95th percentile, and
+// longest of took, by the nearest rank.
+func durationMetrics(took []time.Duration) DurationMetrics {
+	if len(took) == 0 {
+		return DurationMetrics{}
+	}
+	slices.Sort(took)
+	var total time.Duration
+	for _, d := range took {
+		total += d
+	}
+	rank := func(p float64) time.Duration {
+		return took[int(math.Ceil(p*float64(len(took))))-1]
+	}
+	secs := func(d time.Duration) float64 { return math.Round(d.Seconds()*1000) / 1000 }
+	return DurationMetrics{
+		Total: secs(total),
+		Mean:  secs(total / time.Duration(len(took))),
+		P50:   secs(rank(0.5)),
+		P95:   secs(rank(0.95)),
+		Max:   secs(took[len(took)-1]),
+	}
+}
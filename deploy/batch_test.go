@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestDeployPlanMetrics(t *testing.T) {
+	plan := &DeployPlan{Steps: []DeployStep{{Kind: StepSkip}, {Kind: StepUpload}, {Kind: StepReplace}, {Kind: StepUpload}}}
+	if m := plan.Metrics(); m.Documents != 4 || m.Outcomes[StatusSkipped] != 1 || m.Outcomes[StatusFailed] != 0 || m.Duration != (DurationMetrics{}) {
+		t.Fatalf("before apply: %+v", m)
+	}
+	plan.Results = []Result{
+		{Status: StatusDeployed, Stats: drive.Stats{Bytes: 100, Elapsed: 2 * time.Second, Retries: 1}},
+		{Status: StatusDeployed, Stats: drive.Stats{Bytes: 50, Elapsed: 1500 * time.Millisecond}},
+		{Status: StatusFailed, Stats: drive.Stats{Elapsed: 500 * time.Millisecond, Retries: 3}},
+	}
+	m := plan.Metrics()
+	want := map[string]int{StatusDeployed: 2, StatusSkipped: 1, StatusFailed: 1}
+	if m.Outcomes[StatusDeployed] != 2 || m.Outcomes[StatusSkipped] != 1 || m.Outcomes[StatusFailed] != 1 || len(m.Outcomes) != len(want) {
+		t.Errorf("outcomes = %v, want %v", m.Outcomes, want)
+	}
+	if m.Bytes != 150 || m.Retries != 4 {
+		t.Errorf("bytes %d, retries %d", m.Bytes, m.Retries)
+	}
+	if d := (DurationMetrics{Total: 4, Mean: 1.333, P50: 1.5, P95: 2, Max: 2}); m.Duration != d {
+		t.Errorf("durations = %+v, want %+v", m.Duration, d)
+	}
+}
@@ -2,198 +2,425 @@ package deploy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
-	"net/url"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/events"
+)
+
+// Logger receives what DeployPDF and CheckRemoteVersionExists report as
+// they go, at debug, info, and warn levels. When nil, slog.Default() is
+// used, so output follows the application's own logging setup.
+var Logger *slog.Logger
+
+// Metrics, if set, is told how long each DeployPDF took and whether it
+// failed; a *metrics.Collector satisfies it.
+var Metrics interface {
+	ObserveDeploy(file string, took time.Duration, err error)
+}
+
+// Notifier, if set, is told the outcome of each DeployPDF; a
+// *notify.Webhook satisfies it.
+var Notifier interface {
+	NotifyDeploy(r Result)
+}
+
+// ErrorReporter, if set, is told of each DeployPDF and ApplyRollback that
+// fails, with the step that failed and the Drive API's response.
+var ErrorReporter events.ErrorReporter
+
+// Events, if set, follows each DeployPDF and ApplyRollback as it goes: its
+// start, the version it archives, the progress of its upload, any error,
+// and its end.
+var Events events.Events
+
+// What became of a DeployPDF or ApplyRollback.
+const (
+	StatusDeployed   = "deployed"
+	StatusSkipped    = "skipped" // the version was live already
+	StatusRolledBack = "rolled-back"
+	StatusFailed     = "failed"
 )
 
+// Result is what a DeployPDF or ApplyRollback did, as passed to Notifier.
+type Result struct {
+	File    string // name of the PDF, with .pdf
+	Version string
+	// Previous is the version that was live before, if any.
+	Previous string
+	// FileID is the live file, unless the change failed.
+	FileID string
+	// FolderID is the folder the file is live in.
+	FolderID string
+	Status   string
+	Err      error
+	// Resumed is set when a deploy moved live the copy an earlier, failed
+	// deploy of the same version had uploaded, rather than upload another.
+	Resumed bool
+	// CorrelationID is sent with every request of the change and noted on
+	// its log entries.
+	CorrelationID string
+	// Stats is the content uploaded, how long the deploy took, and the
+	// requests a drive.Retrier repeated for it.
+	Stats drive.Stats
+}
+
+// logger returns Logger or slog.Default(), noting the correlation ID of
+// ctx, if any, on every entry.
+func logger(ctx context.Context) *slog.Logger {
+	l := Logger
+	if l == nil {
+		l = slog.Default()
+	}
+	if id := drive.CorrelationID(ctx); id != "" {
+		l = l.With("correlation_id", id)
+	}
+	return l
+}
+
+// parseIDs replaces each non-empty ID in place with the ID extracted from it,
+// so callers can pass Drive share URLs instead of bare IDs.
+func parseIDs(ids ...*string) error {
+	for _, id := range ids {
+		if *id == "" {
+			continue
+		}
+		parsed, err := drive.ParseID(*id)
+		if err != nil {
+			return err
+		}
+		*id = parsed
+	}
+	return nil
+}
+
+// findFile returns the first untrashed file called name in folderID, or nil
+// if there is none.
+func findFile(ctx context.Context, accessToken, folderID, name string) (*drive.File, error) {
+	return findIn(ctx, drive.NewClient(accessToken), folderID, name)
+}
+
 func DeployPDF(accessToken string, fileName string, versionSafe string, tempFolderID string, folderID string, oldFolderID string, sopDir string) error {
+	_, err := DeployPDFResult(context.Background(), accessToken, fileName, versionSafe, tempFolderID, folderID, oldFolderID, sopDir)
+	return err
+}
+
+// DeployPDFResult is DeployPDF, also returning what it did, as Notifier is
+// told. Its requests and log entries carry the correlation ID of ctx,
+// which is given a new one if it has none.
+func DeployPDFResult(ctx context.Context, accessToken string, fileName string, versionSafe string, tempFolderID string, folderID string, oldFolderID string, sopDir string) (Result, error) {
+	ctx, id := drive.EnsureCorrelationID(ctx)
+	start := time.Now()
+	if Events != nil {
+		Events.DeployStarted(events.DeployStarted{Source: events.SourceDeploy, Name: fileName + ".pdf", Version: versionSafe,
+			CorrelationID: id, Time: start})
+	}
+	r, err := deployPDF(ctx, accessToken, fileName, versionSafe, tempFolderID, folderID, oldFolderID, sopDir)
+	r.Stats.Elapsed = time.Since(start)
+	if Metrics != nil {
+		Metrics.ObserveDeploy(fileName+".pdf", r.Stats.Elapsed, err)
+	}
+	r.File, r.Version, r.CorrelationID = fileName+".pdf", versionSafe, id
+	finish(events.SourceDeploy, &r, err)
+	return r, err
+}
+
+// stepError is a failure of one step of a deploy, such as "upload".
+type stepError struct {
+	step string
+	err  error
+}
+
+func (e *stepError) Error() string { return e.err.Error() }
+func (e *stepError) Unwrap() error { return e.err }
+
+// finish marks r as failed with err, if not nil, and tells Notifier,
+// ErrorReporter, and Events, if set, of it.
+func finish(source string, r *Result, err error) {
+	op := source
+	if err != nil {
+		r.FileID, r.Status, r.Err = "", StatusFailed, err
+		var se *stepError
+		if errors.As(err, &se) {
+			op += " " + se.step
+		}
+	}
+	if Notifier != nil {
+		Notifier.NotifyDeploy(*r)
+	}
+	if ErrorReporter != nil && err != nil {
+		ErrorReporter.ReportError(events.NewErrorReport(source, op, r.File, r.CorrelationID, err))
+	}
+	if Events != nil {
+		now := time.Now()
+		if err != nil {
+			Events.Error(events.Error{Source: source, Name: r.File, Op: op, Err: err, CorrelationID: r.CorrelationID, Time: now})
+		}
+		Events.DeployFinished(events.DeployFinished{Source: source, Name: r.File, Version: r.Version, FileID: r.FileID,
+			Status: r.Status, Stats: r.Stats, Err: err, CorrelationID: r.CorrelationID, Time: now})
+	}
+}
+
+// archived tells Events, if set, that file was moved to folderID as name.
+func archived(source, doc string, file drive.File, name, folderID string) {
+	if Events != nil {
+		Events.FileArchived(events.FileArchived{Source: source, Name: doc, FileID: file.ID, ArchivedAs: name,
+			Version: file.Description, FolderID: folderID, Time: time.Now()})
+	}
+}
+
+// deployPDF does DeployPDF, returning the live file, the version it
+// replaced, and whether it was at versionSafe already.
+func deployPDF(ctx context.Context, accessToken string, fileName string, versionSafe string, tempFolderID string, folderID string, oldFolderID string, sopDir string) (Result, error) {
+	var r Result
+	ctx = drive.WithStats(ctx, &r.Stats)
 	// Sanity checks
 	if fileName == "" || accessToken == "" || tempFolderID == "" || folderID == "" {
-		return errors.New("missing required variable(s): fileName, accessToken, tempFolderID, folderID")
+		return r, errors.New("missing required variable(s): fileName, accessToken, tempFolderID, folderID")
+	}
+	if err := parseIDs(&tempFolderID, &folderID, &oldFolderID); err != nil {
+		return r, err
 	}
+	r.FolderID = folderID
 
 	pdfFile := fileName + ".pdf"
 
 	pdfPath := filepath.Join(sopDir, pdfFile)
 	if _, err := os.Stat(pdfPath); err != nil {
-		return fmt.Errorf("PDF '%s' not found", pdfPath)
+		return r, fmt.Errorf("PDF '%s' not found", pdfPath)
 	}
 	if versionSafe == "" {
-		return errors.New("version-safe.txt missing or empty, or VERSION_SUFFIX not set")
+		return r, errors.New("version-safe.txt missing or empty, or VERSION_SUFFIX not set")
 	}
 
 	// Query for existing file
-	encodedName := url.QueryEscape(pdfFile)
-	queryURL := fmt.Sprintf(
-		"https://www.googleapis.com/drive/v3/files?q='%s'+in+parents+and+name='%s'+and+trashed=false&fields=files(id,name,description)",
-		folderID, encodedName,
-	)
-	req, _ := http.NewRequest("GET", queryURL, nil)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err := http.DefaultClient.Do(req)
+	existing, err := findFile(ctx, accessToken, folderID, pdfFile)
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	var result struct {
-		Files []struct {
-			ID          string `json:"id"`
-			Name        string `json:"name"`
-			Description string `json:"description"`
-		} `json:"files"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return err
+		return r, &stepError{"find", err}
 	}
 	var existingFileID, existingFileDesc string
-	if len(result.Files) > 0 {
-		existingFileID = result.Files[0].ID
-		existingFileDesc = result.Files[0].Description
+	if existing != nil {
+		existingFileID = existing.ID
+		existingFileDesc = existing.Description
+		r.Previous = existingFileDesc
 	}
 
 	if existingFileID != "" && existingFileDesc == versionSafe {
-		fmt.Println("-- Skipped: Version already deployed")
-		return nil
+		logger(ctx).Info("skipped: version already deployed", "file", pdfFile, "version", versionSafe)
+		r.FileID, r.Previous, r.Status = existingFileID, existingFileDesc, StatusSkipped
+		return r, nil
 	}
 
-	// Archive old version if needed
-	if existingFileID != "" && oldFolderID != "" {
-		renamedFile := fileName + "-" + (existingFileDesc)
-		if existingFileDesc == "" || existingFileDesc == "null" {
-			renamedFile = fileName + "-unknown"
+	// Check the old version can be archived or deleted before uploading,
+	// so a shared file we may only read fails here rather than halfway.
+	if existingFileID != "" {
+		caps := []drive.Capability{drive.CanDelete}
+		if oldFolderID != "" {
+			caps = []drive.Capability{drive.CanRename, drive.CanMoveItemWithinDrive}
+		}
+		if err := drive.NewClient(accessToken).CheckCapabilities(ctx, existingFileID, caps...); err != nil {
+			return r, &stepError{"check", err}
 		}
-		renamedFile += ".pdf"
+	}
 
-		// Rename
-		renameURL := fmt.Sprintf("https://www.googleapis.com/drive/v3/files/%s", existingFileID)
-		renameBody := fmt.Sprintf(`{"name": "%s"}`, renamedFile)
-		req, _ := http.NewRequest("PATCH", renameURL, bytes.NewBuffer([]byte(renameBody)))
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-		req.Header.Set("Content-Type", "application/json")
-		resp, err := http.DefaultClient.Do(req)
+	// A deploy that failed after its upload left the new version in the
+	// temp folder; finish deploying that copy rather than upload another.
+	// One that is cancelled deletes it instead, until it starts to archive
+	// or delete the old version; from then on the staged copy is kept for
+	// the next run to resume.
+	var newFileID string
+	live, replacing := false, false
+	defer func() {
+		if !live && !replacing && ctx.Err() != nil {
+			discardStaged(ctx, drive.NewClient(accessToken), newFileID, tempFolderID, pdfFile, versionSafe, pdfPath)
+		}
+	}()
+	staged, err := findStaged(ctx, drive.NewClient(accessToken), tempFolderID, pdfFile, versionSafe, pdfPath)
+	if err != nil {
+		return r, &stepError{"find", err}
+	}
+	if staged != nil {
+		newFileID, r.Resumed = staged.ID, true
+		logger(ctx).Info("resuming: found the upload of an earlier deploy", "file", pdfFile, "id", newFileID)
+	} else {
+		id, size, err := uploadPDF(ctx, accessToken, pdfFile, pdfPath, tempFolderID, versionSafe)
 		if err != nil {
-			return fmt.Errorf("failed to rename existing file: %w", err)
+			return r, err
+		}
+		newFileID = id
+		r.Stats.Bytes = size
+		logger(ctx).Info("uploaded new file", "file", pdfFile, "id", newFileID)
+	}
+
+	// Set sharing restrictions (best effort)
+	restrict, share := true, false
+	if _, err := drive.NewClient(accessToken).UpdateMetadata(ctx, newFileID, drive.MetadataPatch{
+		CopyRequiresWriterPermission: &restrict,
+		WritersCanShare:              &share,
+	}); err != nil {
+		if err := bestEffort(ctx, "restrict", fmt.Errorf("failed to set sharing restrictions: %w", err)); err != nil {
+			return r, err
+		}
+	}
+
+	// Only now that the new version is staged, archive or delete the old
+	// one, which someone may have edited since it was found.
+	if existingFileID != "" && (existing.Version != 0 || !existing.ModifiedTime.IsZero()) {
+		if err := drive.NewClient(accessToken).CheckUnchanged(ctx, *existing); err != nil {
+			return r, &stepError{"check", err}
+		}
+	}
+	replacing = existingFileID != ""
+	if existingFileID != "" && oldFolderID != "" {
+		renamedFile := archivedName(fileName, existingFileDesc)
+
+		// Rename
+		if _, err := drive.NewClient(accessToken).Rename(ctx, existingFileID, renamedFile); err != nil {
+			return r, &stepError{"archive", fmt.Errorf("failed to rename existing file: %w", err)}
 		}
-		resp.Body.Close()
 
 		// Move
-		moveURL := fmt.Sprintf("https://www.googleapis.com/drive/v3/files/%s?addParents=%s&removeParents=%s&fields=id,parents", existingFileID, oldFolderID, folderID)
-		req, _ = http.NewRequest("PATCH", moveURL, nil)
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-		resp, err = http.DefaultClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to move old file to archive: %w", err)
+		if _, err := drive.NewClient(accessToken).Move(ctx, existingFileID, oldFolderID); err != nil {
+			return r, &stepError{"archive", fmt.Errorf("failed to move old file to archive: %w", err)}
 		}
-		resp.Body.Close()
-		fmt.Printf("Archived old version as '%s'\n", renamedFile)
+		archived(events.SourceDeploy, pdfFile, *existing, renamedFile, oldFolderID)
+		logger(ctx).Info("archived old version", "file", pdfFile, "as", renamedFile)
 	} else if existingFileID != "" {
-		fmt.Println("Warning: oldFolderID not set; existing file will be deleted")
-		delURL := fmt.Sprintf("https://www.googleapis.com/drive/v3/files/%s", existingFileID)
-		req, _ := http.NewRequest("DELETE", delURL, nil)
-		req.Header.Set("Authorization", "Bearer "+accessToken)
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to delete existing file: %w", err)
-		}
-		defer resp.Body.Close()
-		// Expect 204 No Content on success; some endpoints may return 200
-		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to delete existing file: status %d: %s", resp.StatusCode, string(body))
+		logger(ctx).Warn("no archive folder set; deleting the existing file", "file", pdfFile, "version", existingFileDesc)
+		if err := drive.NewClient(accessToken).Delete(ctx, existingFileID); err != nil {
+			return r, &stepError{"delete", fmt.Errorf("failed to delete existing file: %w", err)}
 		}
 	} else {
-		fmt.Println("No existing version found")
+		logger(ctx).Debug("no existing version found", "file", pdfFile)
+	}
+
+	// Move to final folder. Once the old version is gone, a cancelled
+	// deploy still finishes this, so as not to leave the folder empty.
+	moveCtx := ctx
+	if replacing {
+		var cancel context.CancelFunc
+		moveCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+		defer cancel()
+	}
+	if _, err := drive.NewClient(accessToken).Move(moveCtx, newFileID, folderID); err != nil {
+		return r, &stepError{"move", fmt.Errorf("upload succeeded, but move failed: %w", err)}
 	}
+	live = true
+	if Propagation != nil {
+		looks, err := Propagation.wait(ctx, drive.NewClient(accessToken), folderID, pdfFile, newFileID, versionSafe)
+		if err != nil {
+			return r, &stepError{"verify", fmt.Errorf("deployed as %s, but not verified: %w", newFileID, err)}
+		}
+		logger(ctx).Info("visible in folder", "file", pdfFile, "id", newFileID, "looks", looks)
+	}
+	logger(ctx).Info("deployed", "file", pdfFile, "version", versionSafe, "id", newFileID)
+	r.FileID, r.Status = newFileID, StatusDeployed
+	return r, nil
+}
 
-	// Upload new file (multipart/related)
+// uploadPDF uploads the PDF at pdfPath to tempFolderID as pdfFile, noting
+// versionSafe as its description, and returns its ID and size.
+func uploadPDF(ctx context.Context, accessToken, pdfFile, pdfPath, tempFolderID, versionSafe string) (string, int64, error) {
+	fail := func(format string, err error) (string, int64, error) {
+		return "", 0, &stepError{"upload", fmt.Errorf(format, err)}
+	}
 	metadata := map[string]interface{}{
 		"name":        pdfFile,
 		"parents":     []string{tempFolderID},
 		"description": versionSafe,
 	}
-	metadataJSON, _ := json.Marshal(metadata)
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fail("marshal metadata: %w", err)
+	}
 
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 
 	// Add metadata part
-	metaPart, _ := writer.CreatePart(map[string][]string{
+	metaPart, err := writer.CreatePart(map[string][]string{
 		"Content-Type": {"application/json; charset=UTF-8"},
 	})
-	metaPart.Write(metadataJSON)
+	if err != nil {
+		return fail("create metadata part: %w", err)
+	}
+	if _, err := metaPart.Write(metadataJSON); err != nil {
+		return fail("write metadata part: %w", err)
+	}
 
 	// Add PDF part
-	pdfPart, _ := writer.CreatePart(map[string][]string{
+	pdfPart, err := writer.CreatePart(map[string][]string{
 		"Content-Type": {"application/pdf"},
 	})
+	if err != nil {
+		return fail("create file part: %w", err)
+	}
 
 	osPDFFile, err := os.Open(pdfPath)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 	defer osPDFFile.Close()
-	io.Copy(pdfPart, osPDFFile)
-	writer.Close()
+	size, err := io.Copy(pdfPart, osPDFFile)
+	if err != nil {
+		return fail("copy file part: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fail("close multipart writer: %w", err)
+	}
 
 	uploadURL := "https://www.googleapis.com/upload/drive/v3/files?uploadType=multipart"
-	req, _ = http.NewRequest("POST", uploadURL, &buf)
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &buf)
+	if err != nil {
+		return fail("new request: %w", err)
+	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	resp, err = http.DefaultClient.Do(req)
+	drive.SetCorrelationHeader(req)
+	if Events != nil {
+		content := buf.Bytes()
+		progress := func(p drive.Progress) {
+			Events.UploadProgress(events.UploadProgress{Source: events.SourceDeploy, Name: pdfFile, File: pdfPath, Progress: p})
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(drive.NewProgressReader(bytes.NewReader(content), int64(len(content)), progress)), nil
+		}
+		req.Body, _ = req.GetBody() // never fails
+	}
+	// Sent through a drive.Client, so that a refusal is a *drive.APIError.
+	resp, err := drive.NewClient(accessToken).Do(req)
 	if err != nil {
-		return fmt.Errorf("upload failed: %w", err)
+		return fail("upload failed: %w", err)
 	}
 	defer resp.Body.Close()
-	uploadRespBody, _ := io.ReadAll(resp.Body)
+	uploadRespBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail("upload failed: read response: %w", err)
+	}
 	var uploadResult struct {
 		ID string `json:"id"`
 	}
 	if err := json.Unmarshal(uploadRespBody, &uploadResult); err != nil || uploadResult.ID == "" {
-		return fmt.Errorf("upload failed: %s", string(uploadRespBody))
-	}
-	newFileID := uploadResult.ID
-	fmt.Printf("Uploaded new file: ID %s\n", newFileID)
-
-	// Set sharing restrictions
-	permURL := fmt.Sprintf("https://www.googleapis.com/drive/v3/files/%s", newFileID)
-	permBody := []byte(`{"copyRequiresWriterPermission": true, "writersCanShare": false}`)
-	req, _ = http.NewRequest("PATCH", permURL, bytes.NewBuffer(permBody))
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/json")
-	http.DefaultClient.Do(req) // ignore errors
-
-	// Move to final folder
-	moveNewURL := fmt.Sprintf("https://www.googleapis.com/drive/v3/files/%s?addParents=%s&removeParents=%s&fields=id,parents", newFileID, folderID, tempFolderID)
-	req, _ = http.NewRequest("PATCH", moveNewURL, nil)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	resp, err = http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("move to final folder failed: %w", err)
-	}
-	defer resp.Body.Close()
-	moveRespBody, _ := io.ReadAll(resp.Body)
-	if !bytes.Contains(moveRespBody, []byte(`"id"`)) {
-		return fmt.Errorf("upload succeeded, but move failed: %s", string(moveRespBody))
+		return "", 0, &stepError{"upload", fmt.Errorf("upload failed: %s", string(uploadRespBody))}
 	}
-	fmt.Println("Deployment successful: moved to final folder.")
-	return nil
+	return uploadResult.ID, size, nil
 }
 
 func CheckRemoteVersionExists(accessToken string, fileName string, folderID string, versionSafe string) (bool, error) {
-	fmt.Println("  accessToken:", accessToken != "")
-	fmt.Println("  fileName:", fileName)
-	fmt.Println("  folderID:", folderID)
-	fmt.Println("  versionSafe:", versionSafe)
+	ctx, _ := drive.EnsureCorrelationID(context.Background())
+	logger(ctx).Debug("checking remote version", "file", fileName, "folder", folderID, "version", versionSafe)
 
 	if accessToken == "" {
 		return false, fmt.Errorf("ACCESS_TOKEN is not set")
@@ -201,44 +428,22 @@ func CheckRemoteVersionExists(accessToken string, fileName string, folderID stri
 	if fileName == "" || folderID == "" || versionSafe == "" {
 		return false, fmt.Errorf("missing required variable(s): FileName, FolderID, VersionSafe")
 	}
-
-	pdfFile := fileName + ".pdf"
-
-	encodedName := url.QueryEscape(pdfFile)
-	url := fmt.Sprintf(
-		"https://www.googleapis.com/drive/v3/files?q='%s'+in+parents+and+name='%s'+and+trashed=false&fields=files(id,name,description)",
-		folderID, encodedName,
-	)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+	if err := parseIDs(&folderID); err != nil {
 		return false, err
 	}
-	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+	pdfFile := fileName + ".pdf"
 
-	var result struct {
-		Files []struct {
-			ID          string `json:"id"`
-			Name        string `json:"name"`
-			Description string `json:"description"`
-		} `json:"files"`
-	}
-	if err := json.Unmarshal(body, &result); err != nil {
+	existing, err := findFile(ctx, accessToken, folderID, pdfFile)
+	if err != nil {
 		return false, err
 	}
 
-	if len(result.Files) > 0 && result.Files[0].Description == versionSafe {
-		fmt.Printf("-- Skipped: Exact version already deployed (%s)\n", pdfFile)
+	if existing != nil && existing.Description == versionSafe {
+		logger(ctx).Info("skipped: exact version already deployed", "file", pdfFile, "version", versionSafe)
 		return true, nil
 	}
-	fmt.Printf("-- Will deploy: New or unmatched version for %s\n", pdfFile)
+	logger(ctx).Info("will deploy: new or unmatched version", "file", pdfFile, "version", versionSafe)
 	return false, nil
 }
 
@@ -249,6 +454,9 @@ func UploadFileToDrive(accessToken, folderID, filePath string) (string, error) {
 	if folderID == "" {
 		return "", errors.New("folderID is required")
 	}
+	if err := parseIDs(&folderID); err != nil {
+		return "", err
+	}
 	if filePath == "" {
 		return "", errors.New("filePath is required")
 	}
@@ -302,7 +510,7 @@ func UploadFileToDrive(accessToken, folderID, filePath string) (string, error) {
 	fileHeader := make(textproto.MIMEHeader)
 	fileHeader.Set("Content-Type", ctype)
 	// filename in disposition (Drive doesn't require form-data disposition, but keep for clarity)
-	fileHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, fileName))
+	fileHeader.Set("Content-Disposition", drive.ContentDisposition("form-data", "file", fileName))
 	filePart, err := writer.CreatePart(fileHeader)
 	if err != nil {
 		return "", fmt.Errorf("create file part: %w", err)
@@ -2,8 +2,11 @@ package deploy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -11,9 +14,15 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+	"github.com/hwalton/gdrivetoolbox/events"
 )
 
 // rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
@@ -87,6 +96,278 @@ func TestCheckRemoteVersionExists_MatchesAndNotMatches(t *testing.T) {
 	}
 }
 
+func TestCheckRemoteVersionExists_AcceptsFolderURL(t *testing.T) {
+	var gotQ string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQ = r.URL.Query().Get("q")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"files": []}`))
+	}))
+	defer srv.Close()
+	restore := installTestClient(t, srv)
+	defer restore()
+
+	if _, err := CheckRemoteVersionExists("token", "doc", "https://drive.google.com/drive/folders/folder123?usp=sharing", "v1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.Contains(gotQ, "'folder123' in parents") {
+		t.Fatalf("expected parsed folder ID in query, got %q", gotQ)
+	}
+
+	if _, err := CheckRemoteVersionExists("token", "doc", "https://example.com/not-drive", "v1"); err == nil {
+		t.Fatalf("expected error for URL without an ID")
+	}
+}
+
+func TestCheckRemoteVersionExists_EscapesApostrophes(t *testing.T) {
+	var gotQ string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQ = r.URL.Query().Get("q")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"files": []}`))
+	}))
+	defer srv.Close()
+	restore := installTestClient(t, srv)
+	defer restore()
+
+	if _, err := CheckRemoteVersionExists("token", "Bob's SOP", "folder", "v1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !strings.Contains(gotQ, `name = 'Bob\'s SOP.pdf'`) {
+		t.Fatalf("expected escaped name in query, got %q", gotQ)
+	}
+}
+
+func TestDeployPDFResult_Retries(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "mydoc.pdf"), []byte("pdfdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	busy := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && busy > 0:
+			busy--
+			http.Error(w, "backend error", http.StatusServiceUnavailable)
+		case r.Method == "GET":
+			w.Write([]byte(`{"files": []}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: &drive.Retrier{
+		Base:    rewriteRT{base: u, rt: http.DefaultTransport},
+		Max:     3,
+		Backoff: func(int) time.Duration { return 0 },
+	}}
+	defer func() { http.DefaultClient = orig }()
+
+	r, err := DeployPDFResult(context.Background(), "token", "mydoc", "v1", "temp", "final", "", td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != StatusDeployed || r.FileID != "new" || r.Stats.Bytes != 7 || r.Stats.Retries != 2 {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestDeployPDFResult_CorrelationID(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "mydoc.pdf"), []byte("pdfdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(drive.CorrelationHeader))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files": [{"id":"old","name":"mydoc.pdf","description":"v0"}]}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"id":"old","capabilities":{"canDelete":true}}`))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	restore := installTestClient(t, srv)
+	defer restore()
+	var logs bytes.Buffer
+	Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer func() { Logger = nil }()
+
+	ctx := drive.WithCorrelationID(context.Background(), "op-42")
+	r, err := DeployPDFResult(ctx, "token", "mydoc", "v1", "temp", "final", "", td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CorrelationID != "op-42" {
+		t.Errorf("CorrelationID = %q", r.CorrelationID)
+	}
+	if len(ids) < 4 || slices.ContainsFunc(ids, func(id string) bool { return id != "op-42" }) {
+		t.Errorf("request IDs = %q", ids)
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) < 2 || slices.ContainsFunc(lines, func(l string) bool { return !strings.Contains(l, "correlation_id=op-42") }) {
+		t.Errorf("logs:\n%s", logs.String())
+	}
+
+	// Without one, each deploy is given its own.
+	ids = nil
+	r, err = DeployPDFResult(context.Background(), "token", "mydoc", "v1", "temp", "final", "", td)
+	if err != nil || r.CorrelationID == "" || r.CorrelationID == "op-42" || ids[0] != r.CorrelationID {
+		t.Errorf("new ID %q sent as %q, err %v", r.CorrelationID, ids, err)
+	}
+}
+
+// errorReporterFunc adapts a function to events.ErrorReporter.
+type errorReporterFunc func(events.ErrorReport)
+
+func (f errorReporterFunc) ReportError(r events.ErrorReport) { f(r) }
+
+func TestDeployPDFResult_ErrorReporter(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "mydoc.pdf"), []byte("pdfdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files": []}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"parents":["temp"]}`))
+		case r.Method == "PATCH" && r.URL.Query().Get("addParents") != "":
+			http.Error(w, `{"error":{"errors":[{"reason":"rateLimitExceeded"}],"message":"slow down"}}`, http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: &drive.Retrier{
+		Base:    rewriteRT{base: u, rt: http.DefaultTransport},
+		Max:     1,
+		Backoff: func(int) time.Duration { return 0 },
+	}}
+	defer func() { http.DefaultClient = orig }()
+	var reports []events.ErrorReport
+	ErrorReporter = errorReporterFunc(func(r events.ErrorReport) { reports = append(reports, r) })
+	defer func() { ErrorReporter = nil }()
+
+	ctx := drive.WithCorrelationID(context.Background(), "op-7")
+	_, err := DeployPDFResult(ctx, "token", "mydoc", "v1", "temp", "final", "", td)
+	if err == nil || !strings.Contains(err.Error(), "move failed") {
+		t.Fatalf("err = %v, want the move to fail", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("reports = %+v", reports)
+	}
+	got := reports[0]
+	if got.Source != events.SourceDeploy || got.Op != "deploy move" || got.File != "mydoc.pdf" || got.Status != 429 ||
+		got.Reason != "rateLimitExceeded" || got.Attempt != 2 || got.CorrelationID != "op-7" || got.Err != err {
+		t.Errorf("report = %+v", got)
+	}
+
+	// A failure before any request has no status or attempt.
+	reports = nil
+	if _, err := DeployPDFResult(ctx, "token", "other", "v1", "temp", "final", "", filepath.Join(td, "missing")); err == nil || len(reports) != 1 ||
+		reports[0].Op != "deploy" || reports[0].Status != 0 || reports[0].Attempt != 0 {
+		t.Errorf("missing PDF: err %v, reports %+v", err, reports)
+	}
+}
+
+// refuse answers the requests match picks with a 403 of reason.
+type refuse struct {
+	base   http.RoundTripper
+	match  func(*http.Request) bool
+	reason string
+}
+
+func (f refuse) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !f.match(req) {
+		return f.base.RoundTrip(req)
+	}
+	body := `{"error":{"code":403,"errors":[{"reason":"` + f.reason + `"}],"message":"refused"}}`
+	return &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"Content-Type": {"application/json"}},
+		Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestDeployPDFResult_ErrorReporterUploadAndDelete(t *testing.T) {
+	for _, tc := range []struct {
+		step, reason string
+		match        func(*http.Request) bool
+	}{
+		{"upload", "storageQuotaExceeded", func(req *http.Request) bool { return strings.HasPrefix(req.URL.Path, "/upload/") }},
+		{"delete", "insufficientFilePermissions", func(req *http.Request) bool { return req.Method == http.MethodDelete }},
+	} {
+		t.Run(tc.step, func(t *testing.T) {
+			srv := drivetest.NewServer()
+			defer srv.Close()
+			pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+			srv.Add(drive.File{Name: "mydoc.pdf", Description: "v1", Parents: []string{pub}}, []byte("old"))
+			orig := http.DefaultClient
+			http.DefaultClient = &http.Client{Transport: refuse{srv.HTTPClient().Transport, tc.match, tc.reason}}
+			t.Cleanup(func() { http.DefaultClient = orig })
+			var reports []events.ErrorReport
+			ErrorReporter = errorReporterFunc(func(r events.ErrorReport) { reports = append(reports, r) })
+			defer func() { ErrorReporter = nil }()
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("pdfdata"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := DeployPDFResult(context.Background(), "tok", "mydoc", "v2", tmp, pub, "", dir); err == nil {
+				t.Fatal("deploy succeeded")
+			}
+			if len(reports) != 1 {
+				t.Fatalf("reports = %+v", reports)
+			}
+			if got := reports[0]; got.Op != "deploy "+tc.step || got.Status != 403 || got.Reason != tc.reason || got.Attempt != 1 {
+				t.Errorf("report = %+v", got)
+			}
+		})
+	}
+}
+
+// eventLog records lifecycle events as lines.
+type eventLog struct {
+	events.Nop
+	lines    []string
+	progress []drive.Progress
+}
+
+func (l *eventLog) DeployStarted(e events.DeployStarted) {
+	l.lines = append(l.lines, fmt.Sprintf("started %s %s %s", e.Source, e.Name, e.Version))
+}
+
+func (l *eventLog) FileArchived(e events.FileArchived) {
+	l.lines = append(l.lines, fmt.Sprintf("archived %s %s (%s) as %s in %s", e.Name, e.FileID, e.Version, e.ArchivedAs, e.FolderID))
+}
+
+func (l *eventLog) UploadProgress(e events.UploadProgress) {
+	l.progress = append(l.progress, e.Progress)
+}
+
+func (l *eventLog) DeployFinished(e events.DeployFinished) {
+	l.lines = append(l.lines, fmt.Sprintf("finished %s %s %s %s", e.Source, e.Name, e.Status, e.FileID))
+}
+
+func (l *eventLog) Error(e events.Error) {
+	l.lines = append(l.lines, fmt.Sprintf("error %s %s: %v", e.Op, e.Name, e.Err))
+}
+
+type notifierFunc func(Result)
+
+func (f notifierFunc) NotifyDeploy(r Result) { f(r) }
+
 func TestDeployPDF_NoExisting_UploadAndMove(t *testing.T) {
 	// Create temp dir with dummy PDF
 	td := t.TempDir()
@@ -133,11 +414,30 @@ func TestDeployPDF_NoExisting_UploadAndMove(t *testing.T) {
 	restore := installTestClient(t, srv)
 	defer restore()
 
+	var logs bytes.Buffer
+	Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer func() { Logger = nil }()
+
+	var notified []Result
+	Notifier = notifierFunc(func(r Result) { notified = append(notified, r) })
+	defer func() { Notifier = nil }()
+
 	// Call DeployPDF
 	err := DeployPDF("token", "mydoc", "v1", "temp", "final", "old", td)
 	if err != nil {
 		t.Fatalf("DeployPDF failed: %v", err)
 	}
+	if len(notified) != 1 || notified[0].Stats.Bytes != int64(len("pdfdata")) || notified[0].Stats.Elapsed <= 0 || notified[0].CorrelationID == "" {
+		t.Fatalf("notified %+v", notified)
+	}
+	if !strings.Contains(logs.String(), "msg=deployed correlation_id="+notified[0].CorrelationID+" file=mydoc.pdf version=v1 id=new-file-id") {
+		t.Fatalf("logs:\n%s", logs.String())
+	}
+	notified[0].Stats, notified[0].CorrelationID = drive.Stats{}, ""
+	want := Result{File: "mydoc.pdf", Version: "v1", FileID: "new-file-id", FolderID: "final", Status: StatusDeployed}
+	if notified[0] != want {
+		t.Fatalf("notified %+v, want %+v", notified, want)
+	}
 
 	// basic assertions about sequence
 	mu.Lock()
@@ -170,6 +470,13 @@ func TestDeployPDF_ExistingDeletedWhenNoOldFolder(t *testing.T) {
 		seen = append(seen, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
 		mu.Unlock()
 
+		// Capability check on the existing file
+		if r.Method == "GET" && r.URL.Path == "/drive/v3/files/oldid" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"oldid","capabilities":{"canDelete":true}}`))
+			return
+		}
+
 		// Initial query
 		if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/drive/v3/files") && r.URL.RawQuery != "uploadType=multipart" {
 			w.Header().Set("Content-Type", "application/json")
@@ -223,6 +530,83 @@ func TestDeployPDF_ExistingDeletedWhenNoOldFolder(t *testing.T) {
 	}
 }
 
+func TestDeployPDF_ArchivesExistingVersion(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "doc.pdf"), []byte("pdfdata"), 0644); err != nil {
+		t.Fatalf("write pdf: %v", err)
+	}
+
+	var mu sync.Mutex
+	var renamedTo string
+	moves := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[{"id":"oldid","name":"doc.pdf","description":"v1 \"beta\""}]}`))
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files/oldid":
+			w.Write([]byte(`{"parents":["final"],"capabilities":{"canRename":true,"canMoveItemWithinDrive":true}}`))
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files/newid":
+			w.Write([]byte(`{"parents":["temp"]}`))
+		case r.Method == "PATCH" && r.URL.Query().Get("addParents") != "":
+			id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+			moves[id] = r.URL.Query().Get("removeParents") + "->" + r.URL.Query().Get("addParents")
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case r.Method == "PATCH" && r.URL.Path == "/drive/v3/files/oldid":
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "bad json", http.StatusBadRequest)
+				return
+			}
+			renamedTo = body["name"]
+			w.Write([]byte(`{"id":"oldid"}`))
+		case r.Method == "PATCH":
+			w.Write([]byte(`{"id":"newid"}`))
+		case r.Method == "POST":
+			w.Write([]byte(`{"id":"newid"}`))
+		default:
+			http.Error(w, "not implemented", http.StatusNotImplemented)
+		}
+	}))
+	defer srv.Close()
+	restore := installTestClient(t, srv)
+	defer restore()
+	var log eventLog
+	Events = &log
+	defer func() { Events = nil }()
+
+	if err := DeployPDF("token", "doc", "v2", "temp", "final", "archive", td); err != nil {
+		t.Fatalf("DeployPDF failed: %v", err)
+	}
+	want := []string{
+		"started deploy doc.pdf v2",
+		`archived doc.pdf oldid (v1 "beta") as doc-v1 "beta".pdf in archive`,
+		"finished deploy doc.pdf deployed newid",
+	}
+	if !slices.Equal(log.lines, want) {
+		t.Fatalf("events:\n%s\nwant:\n%s", strings.Join(log.lines, "\n"), strings.Join(want, "\n"))
+	}
+	if n := len(log.progress); n == 0 || !log.progress[n-1].Done || log.progress[n-1].Transferred == 0 {
+		t.Fatalf("upload progress = %+v", log.progress)
+	}
+
+	log.lines = nil
+	if err := DeployPDF("token", "doc", "v2", "temp", "final", "archive", filepath.Join(td, "missing")); err == nil {
+		t.Fatal("deploy of a missing PDF succeeded")
+	}
+	if len(log.lines) != 3 || !strings.HasPrefix(log.lines[1], "error deploy doc.pdf: PDF") || log.lines[2] != "finished deploy doc.pdf failed " {
+		t.Fatalf("events of a failed deploy: %q", log.lines)
+	}
+	if renamedTo != `doc-v1 "beta".pdf` {
+		t.Fatalf("renamed to %q", renamedTo)
+	}
+	if moves["oldid"] != "final->archive" || moves["newid"] != "temp->final" {
+		t.Fatalf("unexpected moves: %v", moves)
+	}
+}
+
 type rewritingRoundTripper struct {
 	orig       http.RoundTripper
 	targetBase *url.URL
@@ -398,3 +782,38 @@ func mustParseURL(s string) *url.URL {
 	}
 	return u
 }
+
+func TestUnicodeNames(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	orig := http.DefaultClient
+	http.DefaultClient = srv.HTTPClient()
+	t.Cleanup(func() { http.DefaultClient = orig })
+	pub, tmp, arc := srv.AddFolder("Published", ""), srv.AddFolder("Staging", ""), srv.AddFolder("Archive", "")
+	ctx := context.Background()
+
+	for _, name := range []string{"標準作業手順書", "품질 매뉴얼", "Procédure d'hygiène – révisée", "Release 🚀 notes"} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, name+".pdf"), []byte("%PDF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DeployPDFResult(ctx, "tok", name, "v1", tmp, pub, arc, dir); err != nil {
+			t.Fatalf("deploy %q: %v", name, err)
+		}
+		r, err := DeployPDFResult(ctx, "tok", name, "v2", tmp, pub, arc, dir)
+		if err != nil || r.Status != StatusDeployed || r.Previous != "v1" {
+			t.Fatalf("redeploy %q = %+v, %v", name, r, err)
+		}
+		if f, _ := srv.File(r.FileID); f.Name != name+".pdf" {
+			t.Errorf("deployed as %q, want %q", f.Name, name+".pdf")
+		}
+
+		id, err := UploadFileToDrive("tok", pub, filepath.Join(dir, name+".pdf"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f, _ := srv.File(id); f.Name != name+".pdf" {
+			t.Errorf("uploaded as %q, want %q", f.Name, name+".pdf")
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Manifest declares a set of PDFs to deploy together. In YAML:
+//
+//	folder: finalFolderID
+//	tempFolder: tempFolderID
+//	archiveFolder: archiveFolderID # optional; replaced versions are deleted without it
+//	dir: pdfs                      # relative to the manifest file
+//	documents:
+//	  - file: mydoc
+//	    version: v1.2.3
+//	  - file: handbook
+//	    version: v4
+type Manifest struct {
+	Folder        string     `yaml:"folder"`
+	TempFolder    string     `yaml:"tempFolder"`
+	ArchiveFolder string     `yaml:"archiveFolder"`
+	Dir           string     `yaml:"dir"`
+	Documents     []Document `yaml:"documents"`
+}
+
+// Document is one PDF of a Manifest: Dir/File.pdf deployed at Version.
+type Document struct {
+	File    string `yaml:"file" json:"file"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// ErrInvalidManifest is matched by errors.Is for a manifest that cannot be
+// parsed or misses required values.
+var ErrInvalidManifest = errors.New("invalid manifest")
+
+// ParseManifest reads a YAML deploy manifest.
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+	if m.Folder == "" || m.TempFolder == "" {
+		return nil, fmt.Errorf("%w: folder and tempFolder are required", ErrInvalidManifest)
+	}
+	seen := map[string]bool{}
+	for _, d := range m.Documents {
+		if d.File == "" || d.Version == "" {
+			return nil, fmt.Errorf("%w: document %q needs a file and a version", ErrInvalidManifest, d.File)
+		}
+		if seen[d.File] {
+			return nil, fmt.Errorf("%w: duplicate document %q", ErrInvalidManifest, d.File)
+		}
+		seen[d.File] = true
+	}
+	return &m, nil
+}
+
+// LoadManifest reads a YAML deploy manifest file. A relative Dir is taken
+// relative to the file.
+func LoadManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ParseManifest(f)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(m.Dir) {
+		m.Dir = filepath.Join(filepath.Dir(path), m.Dir)
+	}
+	return m, nil
+}
+
+// Step kinds of a DeployPlan.
+const (
+	StepUpload  = "upload"  // nothing deployed yet
+	StepReplace = "replace" // a different version is live
+	StepSkip    = "skip"    // the version is already live
+)
+
+// DeployStep is what applying a plan does for one document.
+type DeployStep struct {
+	Document
+	Kind string `json:"kind"`
+	// Live is the version currently deployed, if any.
+	Live string `json:"live,omitempty"`
+	// Archive is true if a replaced version is archived rather than
+	// deleted.
+	Archive bool `json:"archive,omitempty"`
+}
+
+// String renders the step as one plan line.
+func (s DeployStep) String() string {
+	name := s.File + ".pdf"
+	switch s.Kind {
+	case StepUpload:
+		return fmt.Sprintf("+ %s %s", name, s.Version)
+	case StepReplace:
+		fate := "delete"
+		if s.Archive {
+			fate = "archive as " + archivedName(s.File, s.Live)
+		}
+		return fmt.Sprintf("~ %s %s -> %s (%s)", name, versionOf(&drive.File{Description: s.Live}), s.Version, fate)
+	}
+	return fmt.Sprintf("  %s %s (up to date)", name, s.Version)
+}
+
+// DeployPlan is a manifest deploy worked out but not yet made.
+type DeployPlan struct {
+	Manifest *Manifest
+	Steps    []DeployStep
+	// Results are what ApplyManifest did for each step it deployed, in
+	// order.
+	Results []Result
+}
+
+// Changes reports how many documents the plan uploads or replaces.
+func (p *DeployPlan) Changes() int {
+	n := 0
+	for _, s := range p.Steps {
+		if s.Kind != StepSkip {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTo writes one line per document: "+" for uploads, "~" for
+// replacements with what happens to the old version, and an indented line
+// for documents already up to date.
+func (p *DeployPlan) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, s := range p.Steps {
+		m, err := fmt.Fprintln(w, s)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// PlanManifest compares every document of m with the live folder and
+// returns what deploying it would do, without changing anything. A local
+// PDF that is missing fails the plan.
+func PlanManifest(ctx context.Context, c *drive.Client, m *Manifest) (*DeployPlan, error) {
+	folderID := m.Folder
+	if err := parseIDs(&folderID); err != nil {
+		return nil, err
+	}
+	plan := &DeployPlan{Manifest: m}
+	for _, d := range m.Documents {
+		pdfPath := filepath.Join(m.Dir, d.File+".pdf")
+		if _, err := os.Stat(pdfPath); err != nil {
+			return nil, fmt.Errorf("PDF '%s' not found", pdfPath)
+		}
+		live, err := findIn(ctx, c, folderID, d.File+".pdf")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.File, err)
+		}
+		step := DeployStep{Document: d, Kind: StepUpload, Archive: m.ArchiveFolder != ""}
+		if live != nil {
+			step.Live = live.Description
+			step.Kind = StepReplace
+			if live.Description == d.Version {
+				step.Kind = StepSkip
+			}
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, nil
+}
+
+// ApplyManifest deploys every document of plan that is not up to date with
+// DeployPDF, authenticating with c.AccessToken. It carries on past
+// failures and returns an error counting them. Every deploy shares the
+// correlation ID of ctx, if any. The result of each is added to
+// plan.Results.
+func ApplyManifest(ctx context.Context, c *drive.Client, plan *DeployPlan) error {
+	m := plan.Manifest
+	failed, total := 0, 0
+	var first error
+	for _, s := range plan.Steps {
+		if s.Kind == StepSkip {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		total++
+		r, err := DeployPDFResult(ctx, c.AccessToken, s.File, s.Version, m.TempFolder, m.Folder, m.ArchiveFolder, m.Dir)
+		plan.Results = append(plan.Results, r)
+		if err != nil {
+			failed++
+			if first == nil {
+				first = fmt.Errorf("%s: %w", s.File, err)
+			}
+		}
+	}
+	if failed > 0 {
+		return &drive.BatchError{Failed: failed, Total: total, Summary: "deployments failed", First: first}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestParseManifest(t *testing.T) {
+	for _, bad := range []string{
+		"documents: []",
+		"folder: f\ntempFolder: t\ndocuments:\n  - file: a\n",
+		"folder: f\ntempFolder: t\ndocuments:\n  - {file: a, version: v1}\n  - {file: a, version: v2}\n",
+		"folder: f\ntempFolder: t\nextra: 1\n",
+	} {
+		if _, err := ParseManifest(strings.NewReader(bad)); !errors.Is(err, ErrInvalidManifest) {
+			t.Errorf("ParseManifest(%q): err = %v", bad, err)
+		}
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deploy.yaml")
+	os.WriteFile(path, []byte("folder: f\ntempFolder: t\ndir: pdfs\ndocuments:\n  - {file: a, version: v1}\n"), 0o644)
+	m, err := LoadManifest(path)
+	if err != nil || m.Dir != filepath.Join(dir, "pdfs") || len(m.Documents) != 1 {
+		t.Fatalf("LoadManifest = %+v, %v", m, err)
+	}
+}
+
+func TestManifestPlanApply(t *testing.T) {
+	s := newRollbackServer()
+	s.files["hb"] = &drive.File{ID: "hb", Name: "handbook.pdf", Description: "v1", Parents: []string{"live"}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+	ctx := context.Background()
+	c := drive.NewClient("tok")
+
+	dir := t.TempDir()
+	for _, name := range []string{"mydoc", "handbook", "guide"} {
+		os.WriteFile(filepath.Join(dir, name+".pdf"), []byte(name), 0o644)
+	}
+	m := &Manifest{Folder: "live", TempFolder: "temp", ArchiveFolder: "archive", Dir: dir, Documents: []Document{
+		{File: "mydoc", Version: "v3"},
+		{File: "handbook", Version: "v2"},
+		{File: "guide", Version: "v1"},
+	}}
+	plan, err := PlanManifest(ctx, c, m)
+	if err != nil {
+		t.Fatalf("PlanManifest: %v", err)
+	}
+	var buf bytes.Buffer
+	plan.WriteTo(&buf)
+	want := "  mydoc.pdf v3 (up to date)\n~ handbook.pdf v1 -> v2 (archive as handbook-v1.pdf)\n+ guide.pdf v1\n"
+	if buf.String() != want || plan.Changes() != 2 {
+		t.Fatalf("plan:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if err := ApplyManifest(ctx, c, plan); err != nil {
+		t.Fatalf("ApplyManifest: %v", err)
+	}
+	if f := s.files["hb"]; f.Name != "handbook-v1.pdf" || f.Parents[0] != "archive" {
+		t.Fatalf("old handbook = %+v", f)
+	}
+	for _, id := range []string{"up-handbook", "up-guide"} {
+		if f := s.files[id]; f == nil || f.Parents[0] != "live" {
+			t.Fatalf("%s = %+v", id, f)
+		}
+	}
+	if _, ok := s.files["up-mydoc"]; ok {
+		t.Fatal("up-to-date document was redeployed")
+	}
+	if len(plan.Results) != 2 || plan.Results[0].File != "handbook.pdf" || plan.Results[1].Status != StatusDeployed {
+		t.Fatalf("results = %+v", plan.Results)
+	}
+	if bm := plan.Metrics(); bm.Outcomes[StatusDeployed] != 2 || bm.Outcomes[StatusSkipped] != 1 || bm.Bytes != int64(len("handbookguide")) {
+		t.Fatalf("metrics = %+v", bm)
+	}
+
+	m.Documents = append(m.Documents, Document{File: "missing", Version: "v1"})
+	if _, err := PlanManifest(ctx, c, m); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("missing PDF: err = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package deploy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// findStaged returns the copy of the PDF at pdfPath that an earlier deploy
+// of version uploaded to tempFolderID as name but did not get to move
+// live, or nil if there is none. A copy counts only if its content is that
+// of pdfPath, so a rebuilt PDF of the same version is uploaded afresh.
+func findStaged(ctx context.Context, c *drive.Client, tempFolderID, name, version, pdfPath string) (*drive.File, error) {
+	files, err := list.ListFiles(ctx, c, list.Options{
+		Query:  query.New().InParent(tempFolderID).NameEquals(name).NotTrashed().String(),
+		Fields: "id,name,description,md5Checksum,size",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var sum string
+	for _, f := range files {
+		if f.Description != version || f.MD5 == "" {
+			continue
+		}
+		if sum == "" {
+			if sum, err = fileMD5(pdfPath); err != nil {
+				return nil, err
+			}
+		}
+		if f.MD5 == sum {
+			return &f, nil
+		}
+	}
+	return nil, nil
+}
+
+// cleanupTimeout bounds the requests that clean up after or finish a
+// cancelled deploy, which can no longer use its own context.
+const cleanupTimeout = 30 * time.Second
+
+// discardStaged deletes the copy of the PDF at pdfPath that a cancelled
+// deploy staged in tempFolderID, so that it leaves nothing behind: id, if
+// the upload returned one and the file is still there, or else the copy
+// findStaged finds, in case Drive finished an upload whose response the
+// cancellation cut off. The deploy has failed already, so its errors are
+// only logged.
+func discardStaged(ctx context.Context, c *drive.Client, id, tempFolderID, name, version, pdfPath string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+	if id != "" {
+		f, err := c.GetFile(ctx, id, "id", "parents")
+		if err != nil {
+			logger(ctx).Warn("cancelled: staged upload not deleted", "file", name, "id", id, "err", err)
+			return
+		}
+		if !slices.Contains(f.Parents, tempFolderID) {
+			// Moved live after all.
+			return
+		}
+	} else {
+		f, err := findStaged(ctx, c, tempFolderID, name, version, pdfPath)
+		if err != nil {
+			logger(ctx).Warn("cancelled: staged upload not looked for", "file", name, "err", err)
+			return
+		}
+		if f == nil {
+			return
+		}
+		id = f.ID
+	}
+	if err := c.Delete(ctx, id); err != nil && !errors.Is(err, drive.ErrNotFound) {
+		logger(ctx).Warn("cancelled: staged upload not deleted", "file", name, "id", id, "err", err)
+		return
+	}
+	logger(ctx).Info("cancelled: deleted staged upload", "file", name, "id", id)
+}
+
+// fileMD5 returns the hex MD5 of the file at p, as Drive reports it.
+func fileMD5(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
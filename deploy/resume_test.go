@@ -0,0 +1,287 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// failMoves fails the next n requests that move a file into folder.
+type failMoves struct {
+	base   http.RoundTripper
+	folder string
+	n      int
+}
+
+func (f *failMoves) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch && req.URL.Query().Get("addParents") == f.folder && f.n > 0 {
+		f.n--
+		return nil, errors.New("connection reset")
+	}
+	return f.base.RoundTrip(req)
+}
+
+func TestDeployPDFResult_Resumes(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	moves := &failMoves{base: srv.HTTPClient().Transport}
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: moves}
+	t.Cleanup(func() { http.DefaultClient = orig })
+	pub, tmp, arc := srv.AddFolder("Published", ""), srv.AddFolder("Staging", ""), srv.AddFolder("Archive", "")
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "guide.pdf")
+	ctx := context.Background()
+
+	if err := os.WriteFile(pdf, []byte("%PDF v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeployPDFResult(ctx, "tok", "guide", "v1", tmp, pub, arc, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// The move of v2 live fails after its upload, leaving it staged.
+	if err := os.WriteFile(pdf, []byte("%PDF v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	moves.folder, moves.n = pub, 1
+	if _, err := DeployPDFResult(ctx, "tok", "guide", "v2", tmp, pub, arc, dir); err == nil {
+		t.Fatal("deploy with a failing move succeeded")
+	}
+	staged := srv.Children(tmp)
+	if len(staged) != 1 {
+		t.Fatalf("staged %+v, want the upload of v2", staged)
+	}
+
+	uploads := func() int {
+		n := 0
+		for _, req := range srv.Requests() {
+			if strings.HasPrefix(req, "POST /upload/") {
+				n++
+			}
+		}
+		return n
+	}
+	before := uploads()
+	r, err := DeployPDFResult(ctx, "tok", "guide", "v2", tmp, pub, arc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Resumed || r.Status != StatusDeployed || r.FileID != staged[0].ID || r.Stats.Bytes != 0 {
+		t.Errorf("rerun = %+v, want the staged %s resumed", r, staged[0].ID)
+	}
+	if n := uploads() - before; n != 0 {
+		t.Errorf("rerun uploaded %d files", n)
+	}
+	if live := srv.Children(pub); len(live) != 1 || live[0].ID != staged[0].ID || live[0].Description != "v2" {
+		t.Errorf("live %+v", live)
+	}
+	if left := srv.Children(tmp); len(left) != 0 {
+		t.Errorf("left in the temp folder: %+v", left)
+	}
+	if old := srv.Children(arc); len(old) != 1 || old[0].Name != "guide-v1.pdf" {
+		t.Errorf("archive %+v", old)
+	}
+}
+
+func TestDeployPDFResult_StagedContentDiffers(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	orig := http.DefaultClient
+	http.DefaultClient = srv.HTTPClient()
+	t.Cleanup(func() { http.DefaultClient = orig })
+	pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("%PDF rebuilt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An earlier build of the same version, and another version.
+	stale := srv.Add(drive.File{Name: "guide.pdf", Description: "v2", Parents: []string{tmp}}, []byte("%PDF first"))
+	other := srv.Add(drive.File{Name: "guide.pdf", Description: "v3", Parents: []string{tmp}}, []byte("%PDF rebuilt"))
+
+	r, err := DeployPDFResult(context.Background(), "tok", "guide", "v2", tmp, pub, "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Resumed || r.FileID == stale.ID || r.FileID == other.ID {
+		t.Errorf("deploy = %+v, want a new upload", r)
+	}
+	if got := string(srv.Content(r.FileID)); got != "%PDF rebuilt" {
+		t.Errorf("live content %q", got)
+	}
+}
+
+// cancelAt cancels a deploy at the first request match picks, after
+// sending it on if send is set, as Ctrl-C might.
+type cancelAt struct {
+	base   http.RoundTripper
+	match  func(*http.Request) bool
+	send   bool
+	cancel context.CancelFunc
+}
+
+func (c *cancelAt) RoundTrip(req *http.Request) (*http.Response, error) {
+	if c.cancel == nil || !c.match(req) {
+		return c.base.RoundTrip(req)
+	}
+	if c.send {
+		if resp, err := c.base.RoundTrip(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	c.cancel()
+	c.cancel = nil
+	return nil, context.Canceled
+}
+
+func TestDeployPDFResult_CancelDeletesStaged(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		match func(req *http.Request, live string) bool
+		send  bool
+	}{
+		// Drive stores the upload, but its response is lost.
+		{"upload", func(req *http.Request, _ string) bool { return strings.HasPrefix(req.URL.Path, "/upload/") }, true},
+		{"move", func(req *http.Request, live string) bool { return req.URL.Query().Get("addParents") == live }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := drivetest.NewServer()
+			defer srv.Close()
+			pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			rt := &cancelAt{base: srv.HTTPClient().Transport, send: tc.send, cancel: cancel,
+				match: func(req *http.Request) bool { return tc.match(req, pub) }}
+			orig := http.DefaultClient
+			http.DefaultClient = &http.Client{Transport: rt}
+			t.Cleanup(func() { http.DefaultClient = orig })
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("%PDF"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := DeployPDFResult(ctx, "tok", "guide", "v1", tmp, pub, "", dir)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			if rt.cancel != nil {
+				t.Fatal("the deploy was not cancelled")
+			}
+			if left := srv.Children(tmp); len(left) != 0 {
+				t.Errorf("left in the temp folder: %+v", left)
+			}
+			if live := srv.Children(pub); len(live) != 0 {
+				t.Errorf("live: %+v", live)
+			}
+		})
+	}
+}
+
+// cancelAfter cancels a deploy once the first request match picks has
+// succeeded, passing its response on.
+type cancelAfter struct {
+	base   http.RoundTripper
+	match  func(*http.Request) bool
+	cancel context.CancelFunc
+}
+
+func (c *cancelAfter) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.base.RoundTrip(req)
+	if err == nil && c.cancel != nil && c.match(req) {
+		c.cancel()
+		c.cancel = nil
+	}
+	return resp, err
+}
+
+func TestDeployPDFResult_CancelAfterReplacingFinishes(t *testing.T) {
+	for _, archive := range []bool{true, false} {
+		t.Run(fmt.Sprintf("archive=%v", archive), func(t *testing.T) {
+			srv := drivetest.NewServer()
+			defer srv.Close()
+			pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+			var arc string
+			if archive {
+				arc = srv.AddFolder("Archive", "")
+			}
+			old := srv.Add(drive.File{Name: "guide.pdf", Description: "v1", Parents: []string{pub}}, []byte("%PDF v1"))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// Cancelled once the old version has left the live folder,
+			// before the new one is moved in.
+			rt := &cancelAfter{base: srv.HTTPClient().Transport, cancel: cancel, match: func(req *http.Request) bool {
+				if archive {
+					return req.URL.Query().Get("addParents") == arc
+				}
+				return req.Method == http.MethodDelete
+			}}
+			orig := http.DefaultClient
+			http.DefaultClient = &http.Client{Transport: rt}
+			t.Cleanup(func() { http.DefaultClient = orig })
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("%PDF v2"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := DeployPDFResult(ctx, "tok", "guide", "v2", tmp, pub, arc, dir)
+			if rt.cancel != nil {
+				t.Fatal("the deploy was not cancelled")
+			}
+			if err != nil || r.Status != StatusDeployed {
+				t.Fatalf("deploy = %+v, %v; want the move finished", r, err)
+			}
+			if live := srv.Children(pub); len(live) != 1 || live[0].ID != r.FileID || live[0].Description != "v2" {
+				t.Errorf("live %+v", live)
+			}
+			if left := srv.Children(tmp); len(left) != 0 {
+				t.Errorf("left in the temp folder: %+v", left)
+			}
+			if archive {
+				if got := srv.Children(arc); len(got) != 1 || got[0].ID != old.ID {
+					t.Errorf("archive %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployPDFResult_CancelWhileArchivingKeepsStaged(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	pub, tmp, arc := srv.AddFolder("Published", ""), srv.AddFolder("Staging", ""), srv.AddFolder("Archive", "")
+	srv.Add(drive.File{Name: "guide.pdf", Description: "v1", Parents: []string{pub}}, []byte("%PDF v1"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The rename of the old version goes through; its move is cut off.
+	rt := &cancelAt{base: srv.HTTPClient().Transport, cancel: cancel,
+		match: func(req *http.Request) bool { return req.URL.Query().Get("addParents") == arc }}
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: rt}
+	t.Cleanup(func() { http.DefaultClient = orig })
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("%PDF v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DeployPDFResult(ctx, "tok", "guide", "v2", tmp, pub, arc, dir); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	staged := srv.Children(tmp)
+	if len(staged) != 1 {
+		t.Fatalf("staged %+v, want the upload kept", staged)
+	}
+
+	http.DefaultClient = srv.HTTPClient()
+	r, err := DeployPDFResult(context.Background(), "tok", "guide", "v2", tmp, pub, arc, dir)
+	if err != nil || !r.Resumed || r.FileID != staged[0].ID {
+		t.Fatalf("rerun = %+v, %v; want the staged %s resumed", r, err, staged[0].ID)
+	}
+}
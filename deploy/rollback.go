@@ -0,0 +1,224 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/events"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// RollbackOptions says which deployed PDF to roll back, and to what.
+type RollbackOptions struct {
+	// FileName is the PDF name without .pdf, as passed to DeployPDF.
+	FileName string
+	// FolderID is the live folder and ArchiveFolderID the folder DeployPDF
+	// archived previous versions into.
+	FolderID        string
+	ArchiveFolderID string
+	// Version is the version to restore. Without RevisionID, the archived
+	// copy of that version is moved back into the live folder.
+	Version string
+	// RevisionID, if set, restores that revision of the live file's content
+	// instead, typically one pinned with drive.Client.PinRevision. Version,
+	// if also set, becomes the file's recorded version.
+	RevisionID string
+}
+
+// RollbackPlan is a rollback worked out but not yet made.
+type RollbackPlan struct {
+	Options RollbackOptions
+	// Live is the file currently deployed, or nil if there is none.
+	Live *drive.File
+	// Archived is the archived copy to restore; nil for a revision restore.
+	Archived *drive.File
+	// Revision is the revision to restore; nil for an archive restore.
+	Revision *drive.Revision
+}
+
+// PlanRollback finds what a rollback would restore and replace, without
+// changing anything.
+func PlanRollback(ctx context.Context, c *drive.Client, opts RollbackOptions) (*RollbackPlan, error) {
+	if opts.FileName == "" || opts.FolderID == "" {
+		return nil, errors.New("missing required variable(s): FileName, FolderID")
+	}
+	if opts.Version == "" && opts.RevisionID == "" {
+		return nil, errors.New("a version or revision to restore is required")
+	}
+	if err := parseIDs(&opts.FolderID, &opts.ArchiveFolderID); err != nil {
+		return nil, err
+	}
+	plan := &RollbackPlan{Options: opts}
+	live, err := findIn(ctx, c, opts.FolderID, opts.FileName+".pdf")
+	if err != nil {
+		return nil, err
+	}
+	plan.Live = live
+
+	if opts.RevisionID != "" {
+		if live == nil {
+			return nil, fmt.Errorf("%s.pdf is not deployed, so it has no revisions", opts.FileName)
+		}
+		revs, err := c.ListRevisions(ctx, live.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range revs {
+			if revs[i].ID == opts.RevisionID {
+				plan.Revision = &revs[i]
+			}
+		}
+		if plan.Revision == nil {
+			return nil, fmt.Errorf("revision %s of %s.pdf: %w", opts.RevisionID, opts.FileName, drive.ErrNotFound)
+		}
+		return plan, nil
+	}
+
+	if live != nil && live.Description == opts.Version {
+		return nil, fmt.Errorf("%s.pdf is already at %s", opts.FileName, opts.Version)
+	}
+	if opts.ArchiveFolderID == "" {
+		return nil, errors.New("an archive folder is required to restore an archived version")
+	}
+	archived, err := findIn(ctx, c, opts.ArchiveFolderID, archivedName(opts.FileName, opts.Version))
+	if err != nil {
+		return nil, err
+	}
+	if archived == nil {
+		return nil, fmt.Errorf("no archived copy of %s.pdf at %s: %w", opts.FileName, opts.Version, drive.ErrNotFound)
+	}
+	plan.Archived = archived
+	return plan, nil
+}
+
+// WriteTo prints a summary of the rollback for confirmation.
+func (p *RollbackPlan) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	name := p.Options.FileName + ".pdf"
+	switch {
+	case p.Revision != nil:
+		fmt.Fprintf(&buf, "restore revision %s of %s (modified %s)\n", p.Revision.ID, name, p.Revision.ModifiedTime.Format("2006-01-02 15:04"))
+		fmt.Fprintf(&buf, "  replacing the current content (%s)\n", versionOf(p.Live))
+		if p.Options.Version != "" {
+			fmt.Fprintf(&buf, "  recording the version as %s\n", p.Options.Version)
+		}
+	default:
+		fmt.Fprintf(&buf, "restore %s from the archive as %s (%s)\n", p.Archived.Name, name, p.Options.Version)
+		if p.Live != nil {
+			fmt.Fprintf(&buf, "  archiving the live copy (%s) as %s\n", versionOf(p.Live), archivedName(p.Options.FileName, p.Live.Description))
+		}
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ApplyRollback makes the rollback in plan. An archive restore swaps the
+// files, so the version it replaces is archived just as DeployPDF would
+// and can itself be restored later. Notifier, if set, is told how it went.
+// Like DeployPDFResult, it gives ctx a correlation ID if it has none.
+func ApplyRollback(ctx context.Context, c *drive.Client, plan *RollbackPlan) error {
+	r := Result{File: plan.Options.FileName + ".pdf", Version: plan.Options.Version, FolderID: plan.Options.FolderID, Status: StatusRolledBack}
+	if r.Version == "" && plan.Revision != nil {
+		r.Version = "revision " + plan.Revision.ID
+	}
+	ctx, r.CorrelationID = drive.EnsureCorrelationID(ctx)
+	start := time.Now()
+	if Events != nil {
+		Events.DeployStarted(events.DeployStarted{Source: events.SourceRollback, Name: r.File, Version: r.Version,
+			CorrelationID: r.CorrelationID, Time: start})
+	}
+	err := applyRollback(drive.WithStats(ctx, &r.Stats), c, plan)
+	r.Stats.Elapsed = time.Since(start)
+	if plan.Live != nil {
+		r.Previous, r.FileID = plan.Live.Description, plan.Live.ID
+	}
+	if plan.Archived != nil {
+		r.FileID = plan.Archived.ID
+	}
+	finish(events.SourceRollback, &r, err)
+	return err
+}
+
+func applyRollback(ctx context.Context, c *drive.Client, plan *RollbackPlan) error {
+	opts := plan.Options
+	// The plan may have waited for confirmation; someone may have edited
+	// or moved the files since.
+	for _, f := range []*drive.File{plan.Live, plan.Archived} {
+		if f != nil && (f.Version != 0 || !f.ModifiedTime.IsZero()) {
+			if err := c.CheckUnchanged(ctx, *f); err != nil {
+				return err
+			}
+		}
+	}
+	if plan.Revision != nil {
+		var content bytes.Buffer
+		if _, err := c.DownloadRevision(ctx, plan.Live.ID, plan.Revision.ID, &content); err != nil {
+			return fmt.Errorf("download revision: %w", err)
+		}
+		if _, err := c.UpdateContent(ctx, plan.Live.ID, &content, int64(content.Len())); err != nil {
+			return fmt.Errorf("restore content: %w", err)
+		}
+		if opts.Version != "" {
+			if _, err := c.UpdateMetadata(ctx, plan.Live.ID, drive.MetadataPatch{Description: &opts.Version}); err != nil {
+				return fmt.Errorf("record version: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if plan.Live != nil {
+		if err := c.CheckCapabilities(ctx, plan.Live.ID, drive.CanRename, drive.CanMoveItemWithinDrive); err != nil {
+			return err
+		}
+		if _, err := c.Rename(ctx, plan.Live.ID, archivedName(opts.FileName, plan.Live.Description)); err != nil {
+			return fmt.Errorf("rename live file: %w", err)
+		}
+		if _, err := c.Move(ctx, plan.Live.ID, opts.ArchiveFolderID); err != nil {
+			return fmt.Errorf("archive live file: %w", err)
+		}
+		archived(events.SourceRollback, opts.FileName+".pdf", *plan.Live, archivedName(opts.FileName, plan.Live.Description), opts.ArchiveFolderID)
+	}
+	if _, err := c.Rename(ctx, plan.Archived.ID, opts.FileName+".pdf"); err != nil {
+		return fmt.Errorf("rename archived file: %w", err)
+	}
+	if _, err := c.Move(ctx, plan.Archived.ID, opts.FolderID); err != nil {
+		return fmt.Errorf("restore archived file: %w", err)
+	}
+	return nil
+}
+
+// archivedName is the name DeployPDF gives a replaced version.
+func archivedName(fileName, version string) string {
+	if version == "" || version == "null" {
+		version = "unknown"
+	}
+	return fileName + "-" + version + ".pdf"
+}
+
+func versionOf(f *drive.File) string {
+	if f == nil || f.Description == "" {
+		return "unknown version"
+	}
+	return f.Description
+}
+
+// findIn is findFile with a context and client.
+func findIn(ctx context.Context, c *drive.Client, folderID, name string) (*drive.File, error) {
+	f, err := list.First(ctx, c, list.Options{
+		Query:  query.New().InParent(folderID).NameEquals(name).NotTrashed().String(),
+		Fields: "id,name,description,version,modifiedTime",
+	})
+	if errors.Is(err, drive.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
@@ -0,0 +1,219 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// rollbackServer holds a live folder and an archive folder of PDFs, plus
+// revisions of the live file, and accepts DeployPDF's uploads.
+type rollbackServer struct {
+	mu      sync.Mutex
+	files   map[string]*drive.File
+	content map[string]string // file or "file/revision" -> content
+}
+
+var (
+	inParents = regexp.MustCompile(`'([^']+)' in parents`)
+	nameIs    = regexp.MustCompile(`name = '([^']*)'`)
+	nameHas   = regexp.MustCompile(`name contains '([^']*)'`)
+)
+
+func (s *rollbackServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	p := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/upload"), "/drive/v3/files")
+	p = strings.TrimPrefix(p, "/")
+	switch {
+	case r.Method == http.MethodGet && p == "":
+		q := r.URL.Query().Get("q")
+		parent := inParents.FindStringSubmatch(q)[1]
+		match := func(f *drive.File) bool { return f.Name == nameIs.FindStringSubmatch(q)[1] }
+		if m := nameHas.FindStringSubmatch(q); m != nil {
+			match = func(f *drive.File) bool { return strings.Contains(f.Name, m[1]) }
+		}
+		var out []drive.File
+		for _, f := range s.files {
+			if f.Parents[0] == parent && match(f) {
+				out = append(out, *f)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"files": out})
+	case r.Method == http.MethodGet && strings.HasSuffix(p, "/revisions"):
+		w.Write([]byte(`{"revisions":[` +
+			`{"id":"r1","keepForever":true,"modifiedTime":"2024-05-01T00:00:00Z","lastModifyingUser":{"emailAddress":"ada@example.com"}},` +
+			`{"id":"r2","modifiedTime":"2024-06-01T00:00:00Z","lastModifyingUser":{"displayName":"Bob"}}]}`))
+	case r.Method == http.MethodGet && strings.Contains(p, "/revisions/"):
+		w.Write([]byte(s.content[strings.Replace(p, "/revisions/", "/", 1)]))
+	case r.Method == http.MethodGet:
+		f := *s.files[p]
+		f.Capabilities = map[string]bool{"canRename": true, "canMoveItemWithinDrive": true}
+		json.NewEncoder(w).Encode(f)
+	case r.Method == http.MethodPatch && r.URL.Query().Get("uploadType") == "media":
+		b, _ := io.ReadAll(r.Body)
+		s.content[p] = string(b)
+		json.NewEncoder(w).Encode(s.files[p])
+	case r.Method == http.MethodPatch:
+		f := s.files[p]
+		var patch struct {
+			Name        string  `json:"name"`
+			Description *string `json:"description"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		if patch.Name != "" {
+			f.Name = patch.Name
+		}
+		if patch.Description != nil {
+			f.Description = *patch.Description
+		}
+		if add := r.URL.Query().Get("addParents"); add != "" {
+			f.Parents = []string{add}
+		}
+		json.NewEncoder(w).Encode(f)
+	case r.Method == http.MethodPost:
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var f drive.File
+		json.NewDecoder(part).Decode(&f)
+		f.ID = "up-" + strings.TrimSuffix(f.Name, ".pdf")
+		s.files[f.ID] = &f
+		json.NewEncoder(w).Encode(f)
+	default:
+		http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusMethodNotAllowed)
+	}
+}
+
+func newRollbackServer() *rollbackServer {
+	return &rollbackServer{
+		files: map[string]*drive.File{
+			"v3": {ID: "v3", Name: "mydoc.pdf", Description: "v3", Parents: []string{"live"}},
+			"v2": {ID: "v2", Name: "mydoc-v2.pdf", Description: "v2", Parents: []string{"archive"}},
+		},
+		content: map[string]string{"v3": "three", "v3/r1": "pinned"},
+	}
+}
+
+func TestRollbackFromArchive(t *testing.T) {
+	s := newRollbackServer()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+	ctx := context.Background()
+	c := drive.NewClient("tok")
+
+	opts := RollbackOptions{FileName: "mydoc", FolderID: "live", ArchiveFolderID: "archive", Version: "v2"}
+	plan, err := PlanRollback(ctx, c, opts)
+	if err != nil {
+		t.Fatalf("PlanRollback: %v", err)
+	}
+	var buf bytes.Buffer
+	plan.WriteTo(&buf)
+	want := "restore mydoc-v2.pdf from the archive as mydoc.pdf (v2)\n  archiving the live copy (v3) as mydoc-v3.pdf\n"
+	if buf.String() != want {
+		t.Fatalf("summary:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	var notified []Result
+	Notifier = notifierFunc(func(r Result) { notified = append(notified, r) })
+	defer func() { Notifier = nil }()
+	var log eventLog
+	Events = &log
+	defer func() { Events = nil }()
+	if err := ApplyRollback(drive.WithCorrelationID(ctx, "rb-1"), c, plan); err != nil {
+		t.Fatalf("ApplyRollback: %v", err)
+	}
+	if len(notified) != 1 || notified[0].Stats.Elapsed <= 0 {
+		t.Fatalf("notified %+v", notified)
+	}
+	notified[0].Stats = drive.Stats{}
+	wantResult := Result{File: "mydoc.pdf", Version: "v2", Previous: "v3", FileID: "v2", FolderID: "live", Status: StatusRolledBack, CorrelationID: "rb-1"}
+	if notified[0] != wantResult {
+		t.Fatalf("notified %+v, want %+v", notified, wantResult)
+	}
+	wantEvents := "started rollback mydoc.pdf v2|archived mydoc.pdf v3 (v3) as mydoc-v3.pdf in archive|finished rollback mydoc.pdf rolled-back v2"
+	if got := strings.Join(log.lines, "|"); got != wantEvents {
+		t.Fatalf("events = %s\nwant %s", got, wantEvents)
+	}
+	if f := s.files["v2"]; f.Name != "mydoc.pdf" || f.Parents[0] != "live" {
+		t.Fatalf("restored file = %+v", f)
+	}
+	if f := s.files["v3"]; f.Name != "mydoc-v3.pdf" || f.Parents[0] != "archive" {
+		t.Fatalf("replaced file = %+v", f)
+	}
+
+	// Rolling forward again is the same operation.
+	opts.Version = "v3"
+	if plan, err = PlanRollback(ctx, c, opts); err != nil || plan.Archived.ID != "v3" {
+		t.Fatalf("roll forward plan = %+v, %v", plan, err)
+	}
+	opts.Version = "v1"
+	if _, err := PlanRollback(ctx, c, opts); !strings.Contains(err.Error(), "no archived copy") {
+		t.Fatalf("missing version: err = %v", err)
+	}
+}
+
+func TestRollbackToRevision(t *testing.T) {
+	s := newRollbackServer()
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+	ctx := context.Background()
+	c := drive.NewClient("tok")
+
+	plan, err := PlanRollback(ctx, c, RollbackOptions{FileName: "mydoc", FolderID: "live", RevisionID: "r1", Version: "v3-pinned"})
+	if err != nil {
+		t.Fatalf("PlanRollback: %v", err)
+	}
+	if err := ApplyRollback(ctx, c, plan); err != nil {
+		t.Fatalf("ApplyRollback: %v", err)
+	}
+	if s.content["v3"] != "pinned" || s.files["v3"].Description != "v3-pinned" {
+		t.Fatalf("live file = %+v with %q", s.files["v3"], s.content["v3"])
+	}
+	if _, err := PlanRollback(ctx, c, RollbackOptions{FileName: "mydoc", FolderID: "live", RevisionID: "r9"}); err == nil {
+		t.Fatal("expected an error for an unknown revision")
+	}
+}
+
+func TestRollbackConflict(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	live, archive := srv.AddFolder("Live", ""), srv.AddFolder("Archive", "")
+	srv.Add(drive.File{ID: "cur", Name: "mydoc.pdf", Description: "v3", Parents: []string{live}}, []byte("v3"))
+	srv.Add(drive.File{ID: "old", Name: "mydoc-v2.pdf", Description: "v2", Parents: []string{archive}}, []byte("v2"))
+	c := srv.Client()
+	ctx := context.Background()
+
+	plan, err := PlanRollback(ctx, c, RollbackOptions{FileName: "mydoc", FolderID: live, ArchiveFolderID: archive, Version: "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Someone edits the live copy while the plan waits for confirmation.
+	if _, err := c.UpdateContent(ctx, "cur", strings.NewReader("v3, fixed"), 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyRollback(ctx, c, plan); !errors.Is(err, drive.ErrConflict) {
+		t.Fatalf("ApplyRollback = %v, want a conflict", err)
+	}
+	if f, _ := srv.File("cur"); f.Name != "mydoc.pdf" || f.Parents[0] != live {
+		t.Errorf("live copy touched: %+v", f)
+	}
+}
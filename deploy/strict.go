@@ -0,0 +1,23 @@
+package deploy
+
+import "context"
+
+// Strict, when set, makes DeployPDF fail when a best-effort step fails,
+// such as putting sharing restrictions on the new upload, rather than log
+// a warning and go on. Errors of every other step fail a deploy either
+// way. It will be the default in v2.
+var Strict bool
+
+// bestEffort returns err, the failure of step, which a deploy can do
+// without, as a failure of the deploy if Strict is set; otherwise it logs
+// it and returns nil.
+func bestEffort(ctx context.Context, step string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if Strict {
+		return &stepError{step, err}
+	}
+	logger(ctx).Warn("best-effort step failed; going on", "step", step, "err", err)
+	return nil
+}
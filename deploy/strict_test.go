@@ -0,0 +1,69 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// refuseRestrictions answers requests that restrict sharing with 403.
+type refuseRestrictions struct {
+	base http.RoundTripper
+}
+
+func (f refuseRestrictions) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(body, []byte("copyRequiresWriterPermission")) {
+			return &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"Content-Type": {"application/json"}},
+				Body:    io.NopCloser(strings.NewReader(`{"error":{"code":403,"message":"insufficient permissions"}}`)),
+				Request: req}, nil
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return f.base.RoundTrip(req)
+}
+
+func TestDeployPDFResult_Strict(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: refuseRestrictions{srv.HTTPClient().Transport}}
+	t.Cleanup(func() { http.DefaultClient = orig })
+	pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "guide.pdf"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Restricting sharing is best effort by default.
+	r, err := DeployPDFResult(ctx, "tok", "guide", "v1", tmp, pub, "", dir)
+	if err != nil || r.Status != StatusDeployed {
+		t.Fatalf("deploy = %+v, %v", r, err)
+	}
+	first := r.FileID
+
+	Strict = true
+	defer func() { Strict = false }()
+	r, err = DeployPDFResult(ctx, "tok", "guide", "v2", tmp, pub, "", dir)
+	var se *stepError
+	if !errors.As(err, &se) || se.step != "restrict" || r.Status != StatusFailed {
+		t.Fatalf("strict deploy = %+v, %v; want a failure to restrict", r, err)
+	}
+	// The old version is left live, as restricting comes before removing it.
+	if live := srv.Children(pub); len(live) != 1 || live[0].ID != first || live[0].Description != "v1" {
+		t.Errorf("live %+v; want v1 still there", live)
+	}
+}
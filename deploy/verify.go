@@ -0,0 +1,89 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Propagation, if set, makes DeployPDF wait, once the new version is in
+// its folder, until Drive shows it there, before Notifier and Events are
+// told it is deployed. Drive is eventually consistent: a listing of the
+// folder can return the replaced version, or nothing, for a while after
+// the move, and so could the people a notification sends there.
+var Propagation *PropagationCheck
+
+// ErrNotPropagated is matched by errors.Is for a deploy whose new version
+// was not yet visible when PropagationCheck.Timeout ran out.
+var ErrNotPropagated = errors.New("deploy: not visible in the folder yet")
+
+// PropagationCheck is how DeployPDF waits for a new version to be visible:
+// a listing of the folder by name must return it, at its version, and
+// fetching it by ID, as its link and shortcuts to it do, must show it in
+// the folder.
+type PropagationCheck struct {
+	// Timeout bounds the wait; 0 means one minute. When it runs out the
+	// deploy fails at step "verify" with ErrNotPropagated, though the new
+	// version stays in place.
+	Timeout time.Duration
+	// Interval is the wait before the second look, doubled after each up
+	// to 10 seconds; 0 means one second.
+	Interval time.Duration
+}
+
+// maxPropagationInterval caps the doubling of PropagationCheck.Interval.
+const maxPropagationInterval = 10 * time.Second
+
+// wait polls until file id, called name and at version, is visible in
+// folderID, returning how many looks it took.
+func (p *PropagationCheck) wait(ctx context.Context, c *drive.Client, folderID, name, id, version string) (int, error) {
+	timeout, interval := p.Timeout, p.Interval
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for n := 1; ; n++ {
+		seen, err := visible(ctx, c, folderID, name, id, version)
+		if err != nil || seen {
+			return n, err
+		}
+		logger(ctx).Debug("not visible yet", "file", name, "id", id, "look", n, "next", interval)
+		t := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return n, ctx.Err()
+		case <-deadline.C:
+			t.Stop()
+			return n, fmt.Errorf("%w after %s (%d looks)", ErrNotPropagated, timeout, n)
+		case <-t.C:
+		}
+		interval = min(2*interval, maxPropagationInterval)
+	}
+}
+
+// visible reports whether a listing of folderID returns file id as name at
+// version, and fetching id by itself shows it in folderID. A 404 for id
+// only means it is not visible yet.
+func visible(ctx context.Context, c *drive.Client, folderID, name, id, version string) (bool, error) {
+	f, err := findIn(ctx, c, folderID, name)
+	if err != nil || f == nil || f.ID != id || f.Description != version {
+		return false, err
+	}
+	got, err := c.GetFile(ctx, id, "id", "parents", "trashed")
+	if errors.Is(err, drive.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !got.Trashed && slices.Contains(got.Parents, folderID), nil
+}
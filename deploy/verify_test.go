@@ -0,0 +1,77 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeployPDFResult_Propagation(t *testing.T) {
+	td := t.TempDir()
+	if err := os.WriteFile(filepath.Join(td, "mydoc.pdf"), []byte("pdfdata"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The listing returns the old version for stale looks after the
+	// move, and the new file shows its final parent only once moved.
+	var stale, lists int
+	moved := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files":
+			lists++
+			if moved && stale == 0 {
+				w.Write([]byte(`{"files": [{"id":"new","name":"mydoc.pdf","description":"v1"}]}`))
+				return
+			}
+			if moved {
+				stale--
+			}
+			w.Write([]byte(`{"files": [{"id":"old","name":"mydoc.pdf","description":"v0"}]}`))
+		case r.Method == "GET" && r.URL.Path == "/drive/v3/files/old":
+			w.Write([]byte(`{"id":"old","capabilities":{"canDelete":true}}`))
+		case r.Method == "GET" && moved:
+			w.Write([]byte(`{"id":"new","parents":["final"]}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"id":"new","parents":["temp"]}`))
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "PATCH" && r.URL.Query().Get("addParents") != "":
+			moved = true
+			w.Write([]byte(`{"id":"new","parents":["final"]}`))
+		default:
+			w.Write([]byte(`{"id":"new"}`))
+		}
+	}))
+	defer srv.Close()
+	restore := installTestClient(t, srv)
+	defer restore()
+	Propagation = &PropagationCheck{Interval: time.Millisecond, Timeout: time.Second}
+	defer func() { Propagation = nil }()
+	var notified []Result
+	Notifier = notifierFunc(func(r Result) { notified = append(notified, r) })
+	defer func() { Notifier = nil }()
+
+	stale = 2
+	r, err := DeployPDFResult(context.Background(), "token", "mydoc", "v1", "temp", "final", "", td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One listing to find the old version, one for an earlier upload of
+	// it, then three looks.
+	if r.Status != StatusDeployed || r.FileID != "new" || lists != 5 || len(notified) != 1 {
+		t.Errorf("result %+v after %d listings, notified %d times", r, lists, len(notified))
+	}
+
+	moved, stale, notified = false, 1000, nil
+	Propagation.Timeout = 20 * time.Millisecond
+	r, err = DeployPDFResult(context.Background(), "token", "mydoc", "v1", "temp", "final", "", td)
+	if !errors.Is(err, ErrNotPropagated) || r.Status != StatusFailed || len(notified) != 1 || notified[0].Status != StatusFailed {
+		t.Errorf("err = %v, result %+v, notified %+v", err, r, notified)
+	}
+}
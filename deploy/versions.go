@@ -0,0 +1,123 @@
+package deploy
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// Where a Version is kept.
+const (
+	WhereLive     = "live"     // the file in the live folder
+	WhereArchive  = "archive"  // a copy archived by DeployPDF
+	WhereRevision = "revision" // a stored revision of the live file
+)
+
+// Version is one version of a deployed PDF that exists in Drive.
+type Version struct {
+	// Version is the recorded version; revisions have none.
+	Version    string `json:"version,omitempty"`
+	Where      string `json:"where"`
+	FileID     string `json:"fileId"`
+	RevisionID string `json:"revisionId,omitempty"`
+	// Time is when the version was deployed, or for a revision when it was
+	// stored, and By who did it.
+	Time time.Time `json:"time"`
+	By   string    `json:"by,omitempty"`
+	// Pinned is set for revisions kept forever.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// VersionsOptions says which deployed PDF ListVersions looks at.
+type VersionsOptions struct {
+	// FileName is the PDF name without .pdf, as passed to DeployPDF.
+	FileName string
+	// FolderID is the live folder and ArchiveFolderID, if set, the folder
+	// DeployPDF archived previous versions into.
+	FolderID        string
+	ArchiveFolderID string
+	// Revisions adds the stored revisions of the live file.
+	Revisions bool
+}
+
+// ListVersions returns the versions of a deployed PDF: the live one, its
+// archived copies newest first, and, if asked for, the revisions of the
+// live file newest first. Who deployed a copy is whoever stored its first
+// revision.
+func ListVersions(ctx context.Context, c *drive.Client, opts VersionsOptions) ([]Version, error) {
+	if opts.FileName == "" || opts.FolderID == "" {
+		return nil, errors.New("missing required variable(s): FileName, FolderID")
+	}
+	if err := parseIDs(&opts.FolderID, &opts.ArchiveFolderID); err != nil {
+		return nil, err
+	}
+	const fields = "id,name,description,createdTime"
+	var versions []Version
+	live, err := list.ListFiles(ctx, c, list.Options{
+		Query:  query.New().InParent(opts.FolderID).NameEquals(opts.FileName + ".pdf").NotTrashed().String(),
+		Fields: fields,
+		Limit:  1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var liveRevs []drive.Revision
+	for _, f := range live {
+		if liveRevs, err = c.ListRevisions(ctx, f.ID); err != nil {
+			return nil, err
+		}
+		versions = append(versions, Version{Version: f.Description, Where: WhereLive, FileID: f.ID, Time: f.CreatedTime, By: firstAuthor(liveRevs)})
+	}
+
+	if opts.ArchiveFolderID != "" {
+		archived, err := list.ListFiles(ctx, c, list.Options{
+			Query:  query.New().InParent(opts.ArchiveFolderID).NameContains(opts.FileName + "-").NotTrashed().String(),
+			Fields: fields,
+		})
+		if err != nil {
+			return nil, err
+		}
+		slices.SortFunc(archived, func(a, b drive.File) int { return b.CreatedTime.Compare(a.CreatedTime) })
+		for _, f := range archived {
+			v, ok := strings.CutPrefix(f.Name, opts.FileName+"-")
+			v, pdf := strings.CutSuffix(v, ".pdf")
+			// A recorded version must be the one in the name, so that
+			// guide-old-v1.pdf, of the document guide-old, is not taken
+			// for version old-v1 of guide.
+			if !ok || !pdf || f.Description != "" && f.Name != archivedName(opts.FileName, f.Description) {
+				continue
+			}
+			revs, err := c.ListRevisions(ctx, f.ID)
+			if err != nil {
+				return nil, err
+			}
+			versions = append(versions, Version{Version: cmp.Or(f.Description, v), Where: WhereArchive, FileID: f.ID, Time: f.CreatedTime, By: firstAuthor(revs)})
+		}
+	}
+
+	if opts.Revisions && len(live) > 0 {
+		for _, r := range slices.Backward(liveRevs) {
+			versions = append(versions, Version{Where: WhereRevision, FileID: live[0].ID, RevisionID: r.ID,
+				Time: r.ModifiedTime, By: author(r), Pinned: r.KeepForever})
+		}
+	}
+	return versions, nil
+}
+
+func firstAuthor(revs []drive.Revision) string {
+	if len(revs) == 0 {
+		return ""
+	}
+	return author(revs[0])
+}
+
+func author(r drive.Revision) string {
+	return cmp.Or(r.LastModifyingUser.EmailAddress, r.LastModifyingUser.DisplayName)
+}
@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestListVersions(t *testing.T) {
+	s := newRollbackServer()
+	s.files["v1"] = &drive.File{ID: "v1", Name: "mydoc-v1.pdf", Parents: []string{"archive"}}
+	s.files["other"] = &drive.File{ID: "other", Name: "mydoc-notes.txt", Parents: []string{"archive"}}
+	s.files["old"] = &drive.File{ID: "old", Name: "mydoc-old-v1.pdf", Description: "v1", Parents: []string{"archive"}}
+	s.files["v2"].CreatedTime = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	s.files["v1"].CreatedTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	defer installTestClient(t, srv)()
+
+	got, err := ListVersions(context.Background(), drive.NewClient("tok"), VersionsOptions{
+		FileName: "mydoc", FolderID: "live", ArchiveFolderID: "archive", Revisions: true,
+	})
+	if err != nil {
+		t.Fatalf("ListVersions: %v", err)
+	}
+	want := []Version{
+		{Version: "v3", Where: WhereLive, FileID: "v3", By: "ada@example.com"},
+		{Version: "v2", Where: WhereArchive, FileID: "v2", Time: s.files["v2"].CreatedTime, By: "ada@example.com"},
+		{Version: "v1", Where: WhereArchive, FileID: "v1", Time: s.files["v1"].CreatedTime, By: "ada@example.com"},
+		{Where: WhereRevision, FileID: "v3", RevisionID: "r2", Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), By: "Bob"},
+		{Where: WhereRevision, FileID: "v3", RevisionID: "r1", Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), By: "ada@example.com", Pinned: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d versions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) {
+			t.Errorf("version %d time = %v, want %v", i, got[i].Time, want[i].Time)
+		}
+		got[i].Time, want[i].Time = time.Time{}, time.Time{}
+		if got[i] != want[i] {
+			t.Errorf("version %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
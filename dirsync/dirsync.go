@@ -0,0 +1,343 @@
+// Package dirsync mirrors a local directory and a Drive folder into each
+// other. Every run first builds a Plan by comparing both trees; the plan
+// can be printed as a dry run, then applied.
+package dirsync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/events"
+	"github.com/hwalton/gdrivetoolbox/ignore"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// Events, if set, follows each Apply: its start, the remote files it
+// trashes, the progress of its uploads, the actions that fail, and its end.
+var Events events.Events
+
+// ErrorReporter, if set, is told of each action of Apply that fails.
+var ErrorReporter events.ErrorReporter
+
+// Directions.
+const (
+	// Push copies local changes to Drive.
+	Push = "push"
+	// Pull copies Drive changes to the local directory.
+	Pull = "pull"
+)
+
+// Ops.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	// OpConflict is reported but never applied.
+	OpConflict = "conflict"
+)
+
+// Action is one change in a Plan.
+type Action struct {
+	// Direction is Push or Pull: the side that is read from.
+	Direction string
+	Op        string
+	// Path is relative to both roots, with slash separators.
+	Path   string
+	Folder bool
+	Size   int64
+	// RemoteID is the existing Drive item an update or delete applies to,
+	// or the item a pull reads from.
+	RemoteID string
+	// RemoteVersion is the version of the file RemoteID when planned; a
+	// push that would update or delete it fails with drive.ErrConflict if
+	// it has changed since.
+	RemoteVersion int64
+	// ExportMimeType is set when pulling a Google-native file, whose Path
+	// carries the export extension.
+	ExportMimeType string
+	// ModifiedTime is when the side read from last changed.
+	ModifiedTime time.Time
+	// KeepAs, on a pull that overwrites a conflicting local file, is the
+	// path the local copy is moved to and uploaded as first.
+	KeepAs string
+	// Reason explains a conflict, or how one was resolved.
+	Reason string
+	// Err is set if applying the action failed.
+	Err error
+}
+
+func (a Action) String() string {
+	p := a.Path
+	if a.Folder {
+		p += "/"
+	}
+	var s string
+	switch a.Op {
+	case OpCreate:
+		s = fmt.Sprintf("+ %s %s", a.Direction, p)
+	case OpUpdate:
+		s = fmt.Sprintf("~ %s %s", a.Direction, p)
+	case OpDelete:
+		s = fmt.Sprintf("- %s %s", a.Direction, p)
+	default:
+		return fmt.Sprintf("! %s: %s", p, a.Reason)
+	}
+	if a.Reason != "" {
+		s += " (" + a.Reason + ")"
+	}
+	return s
+}
+
+// Plan is the set of changes Apply makes.
+type Plan struct {
+	// Local is the local directory and RemoteID the Drive folder.
+	Local    string
+	RemoteID string
+	Actions  []Action
+	// folders maps relative paths of existing remote folders to their IDs.
+	folders map[string]string
+	// startToken, driveID, and settled are kept by PlanSync for
+	// ApplySync: the changes feed position before Drive was read, and the
+	// Drive IDs of paths already in agreement ("" if gone on both sides).
+	startToken string
+	driveID    string
+	settled    map[string]string
+}
+
+// Empty reports whether there is nothing to apply.
+func (p *Plan) Empty() bool {
+	for _, a := range p.Actions {
+		if a.Op != OpConflict {
+			return false
+		}
+	}
+	return true
+}
+
+// Conflicts returns the actions involving a conflict: those Apply skips,
+// and those that resolve one, with their Reason saying how.
+func (p *Plan) Conflicts() []Action {
+	var out []Action
+	for _, a := range p.Actions {
+		if a.Reason != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// WriteTo writes the plan one action per line.
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, a := range p.Actions {
+		m, err := fmt.Fprintln(w, a)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// sortActions orders deletes first, then creates with parents before their
+// contents, then updates, then conflicts.
+func sortActions(actions []Action) {
+	rank := map[string]int{OpDelete: 0, OpCreate: 1, OpUpdate: 2, OpConflict: 3}
+	sort.SliceStable(actions, func(i, j int) bool {
+		a, b := actions[i], actions[j]
+		if rank[a.Op] != rank[b.Op] {
+			return rank[a.Op] < rank[b.Op]
+		}
+		return a.Path < b.Path
+	})
+}
+
+// Apply makes the changes in plan, in order. It carries on past failures,
+// recording them on each Action, and returns an error counting them. Its
+// requests carry the correlation ID of ctx, which is given a new one if it
+// has none.
+func Apply(ctx context.Context, c *drive.Client, plan *Plan) error {
+	if plan.folders == nil {
+		plan.folders = map[string]string{}
+	}
+	plan.folders[""] = plan.RemoteID
+	ctx, id := drive.EnsureCorrelationID(ctx)
+	start := time.Now()
+	if Events != nil {
+		Events.DeployStarted(events.DeployStarted{Source: events.SourceSync, Name: plan.Local, CorrelationID: id, Time: start})
+	}
+	var stats drive.Stats
+	failed := 0
+	var first error
+	for i := range plan.Actions {
+		a := &plan.Actions[i]
+		var err error
+		switch {
+		case a.Op == OpConflict:
+			continue
+		case a.Direction == Push:
+			err = applyPush(ctx, following(c, plan, a), plan, a)
+		case a.Direction == Pull:
+			err = applyPull(ctx, c, plan, a)
+		default:
+			err = fmt.Errorf("unknown direction %q", a.Direction)
+		}
+		if err != nil {
+			a.Err = err
+			failed++
+			if first == nil {
+				first = fmt.Errorf("%s: %w", a, err)
+			}
+			op := a.Direction + " " + a.Op + " " + a.Path
+			if ErrorReporter != nil {
+				ErrorReporter.ReportError(events.NewErrorReport(events.SourceSync, op, a.Path, id, err))
+			}
+			if Events != nil {
+				Events.Error(events.Error{Source: events.SourceSync, Name: plan.Local, Op: op, Err: err, CorrelationID: id, Time: time.Now()})
+			}
+			continue
+		}
+		if a.Op != OpDelete && !a.Folder {
+			stats.Bytes += a.Size
+		}
+		if Events != nil && a.Direction == Push && a.Op == OpDelete {
+			Events.FileArchived(events.FileArchived{Source: events.SourceSync, Name: plan.Local, FileID: a.RemoteID, ArchivedAs: a.Path, Time: time.Now()})
+		}
+	}
+	var err error
+	if failed > 0 {
+		err = &drive.BatchError{Failed: failed, Total: len(plan.Actions), Summary: "sync actions failed", First: first}
+	}
+	if Events != nil {
+		stats.Elapsed = time.Since(start)
+		status := "synced"
+		if err != nil {
+			status = "failed"
+		}
+		Events.DeployFinished(events.DeployFinished{Source: events.SourceSync, Name: plan.Local, Status: status, Stats: stats, Err: err,
+			CorrelationID: id, Time: time.Now()})
+	}
+	return err
+}
+
+// following returns c, or a copy of it that also reports the progress of
+// uploading a to Events, if set.
+func following(c *drive.Client, plan *Plan, a *Action) *drive.Client {
+	if Events == nil || a.Folder || a.Op == OpDelete {
+		return c
+	}
+	cc := *c
+	cc.OnProgress = func(p drive.Progress) {
+		if c.OnProgress != nil {
+			c.OnProgress(p)
+		}
+		Events.UploadProgress(events.UploadProgress{Source: events.SourceSync, Name: plan.Local, File: a.Path, Progress: p})
+	}
+	return &cc
+}
+
+// localEntry is a file or directory below the local root.
+type localEntry struct {
+	Dir     bool
+	Size    int64
+	ModTime time.Time
+}
+
+// scanLocal lists everything below root by slash-separated relative path.
+// Only regular files and directories are included, and nothing ign matches.
+func scanLocal(root string, ign *ignore.Matcher) (map[string]localEntry, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	entries := map[string]localEntry{}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root || !(d.IsDir() || d.Type().IsRegular()) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ign.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries[rel] = localEntry{Dir: d.IsDir(), Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// scanRemote lists everything below folderID by relative path. Shortcuts,
+// items whose names cannot be local file names, and items ign matches are
+// left out; of several items with the same path, the first listed wins.
+func scanRemote(ctx context.Context, c *drive.Client, folderID string, ign *ignore.Matcher) (map[string]drive.File, error) {
+	files := map[string]drive.File{}
+	err := list.Walk(ctx, c, folderID, func(p string, f drive.File) error {
+		skip := f.IsShortcut() || strings.ContainsAny(f.Name, `/\`) || f.Name == "." || f.Name == ".." || ign.Match(p, f.IsFolder())
+		if _, dup := files[p]; dup || skip {
+			return list.SkipDir
+		}
+		files[p] = f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// fileMD5 returns the hex MD5 of the file at p, as Drive reports it.
+func fileMD5(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// isNative reports whether f is a Google-native file with no binary
+// content of its own.
+func isNative(f drive.File) bool {
+	return drive.IsNative(f.MimeType) && !f.IsFolder() && !f.IsShortcut()
+}
+
+// parentPath returns the slash-separated parent of p, "" at the root.
+func parentPath(p string) string {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return p[:i]
+	}
+	return ""
+}
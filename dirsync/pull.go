@@ -0,0 +1,204 @@
+package dirsync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/ignore"
+)
+
+// PullOptions tunes PlanPull.
+type PullOptions struct {
+	// Delete removes local files and directories that are not in Drive.
+	Delete bool
+	// ExportFormats says how Google-native files are saved locally; nil
+	// means drive.DefaultExportFormats. Native types it does not list are
+	// skipped.
+	ExportFormats map[string]drive.ExportFormat
+}
+
+// PlanPull compares the Drive folder folderID with localDir and returns the
+// changes that make the local directory match it, without making any.
+// Files are compared by size, then MD5; exported native files, which have
+// neither, are refreshed when Drive's copy is newer than the local one.
+func PlanPull(ctx context.Context, c *drive.Client, folderID, localDir string, opts PullOptions) (*Plan, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	ign, err := ignore.Load(localDir)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scanLocal(localDir, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", localDir, err)
+	}
+	scanned, err := scanRemote(ctx, c, folderID, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan Drive folder: %w", err)
+	}
+	remote, exports := localNames(scanned, opts.ExportFormats)
+	for p, f := range remote {
+		if ign.Match(p, f.IsFolder()) {
+			delete(remote, p)
+		}
+	}
+	plan := &Plan{Local: localDir, RemoteID: folderID}
+
+	deleted := map[string]bool{}
+	for p, l := range local {
+		r, ok := remote[p]
+		switch {
+		case ok && l.Dir == r.IsFolder():
+			continue
+		case ok && !opts.Delete:
+			plan.Actions = append(plan.Actions, Action{Direction: Pull, Op: OpConflict, Path: p, RemoteID: r.ID, Reason: kindMismatch(l.Dir)})
+			continue
+		case !opts.Delete || p == ignore.FileName:
+			// The ignore file is kept even if Drive has none.
+			continue
+		}
+		plan.Actions = append(plan.Actions, Action{Direction: Pull, Op: OpDelete, Path: p, Folder: l.Dir})
+		deleted[p] = true
+	}
+	plan.Actions = dropNested(plan.Actions, deleted)
+
+	for p, r := range remote {
+		l, exists := local[p]
+		a := Action{Direction: Pull, Path: p, Folder: r.IsFolder(), Size: r.Size, RemoteID: r.ID, ExportMimeType: exports[p], ModifiedTime: r.ModifiedTime}
+		switch {
+		case !exists || inside(p, deleted):
+			a.Op = OpCreate
+		case l.Dir || r.IsFolder():
+			// Matching folders, or a mismatch already reported.
+			continue
+		case a.ExportMimeType != "":
+			if !r.ModifiedTime.Truncate(time.Second).After(l.ModTime.Truncate(time.Second)) {
+				continue
+			}
+			a.Op = OpUpdate
+		default:
+			changed, err := contentChanged(filepath.Join(localDir, filepath.FromSlash(p)), l, r)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			a.Op = OpUpdate
+		}
+		plan.Actions = append(plan.Actions, a)
+	}
+	sortActions(plan.Actions)
+	return plan, nil
+}
+
+// localNames keys Drive items by the local path they are saved as, adding
+// the export extension to native files. It also returns the export MIME
+// type for each native file kept.
+func localNames(remote map[string]drive.File, formats map[string]drive.ExportFormat) (map[string]drive.File, map[string]string) {
+	if formats == nil {
+		formats = drive.DefaultExportFormats
+	}
+	out := make(map[string]drive.File, len(remote))
+	exports := map[string]string{}
+	for p, f := range remote {
+		if isNative(f) {
+			format, ok := formats[f.MimeType]
+			if !ok {
+				continue
+			}
+			if !strings.EqualFold(path.Ext(p), format.Extension) {
+				p += format.Extension
+			}
+			exports[p] = format.MimeType
+		}
+		if _, dup := out[p]; dup {
+			continue
+		}
+		out[p] = f
+	}
+	return out, exports
+}
+
+func applyPull(ctx context.Context, c *drive.Client, plan *Plan, a *Action) error {
+	target := filepath.Join(plan.Local, filepath.FromSlash(a.Path))
+	switch a.Op {
+	case OpDelete:
+		return os.RemoveAll(target)
+	case OpCreate, OpUpdate:
+		if a.Folder {
+			return os.MkdirAll(target, 0o755)
+		}
+		if a.KeepAs != "" {
+			if err := keepLocal(ctx, c, plan, target, a.KeepAs); err != nil {
+				return fmt.Errorf("keep local copy: %w", err)
+			}
+		}
+		return download(ctx, c, a, target)
+	}
+	return fmt.Errorf("unknown op %q", a.Op)
+}
+
+// download fetches a Drive file to target through a temporary file in the
+// same directory, so an interrupted transfer never leaves a partial file,
+// and stamps it with Drive's modification time.
+func download(ctx context.Context, c *drive.Client, a *Action, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".gdt-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if a.ExportMimeType != "" {
+		_, err = c.Export(ctx, a.RemoteID, a.ExportMimeType, tmp)
+	} else {
+		_, err = c.Download(ctx, a.RemoteID, tmp)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	if a.ModifiedTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(target, a.ModifiedTime, a.ModifiedTime)
+}
+
+// keepLocal moves the local file at target aside to keepAs and uploads it
+// next to the Drive copy, before a pull overwrites it.
+func keepLocal(ctx context.Context, c *drive.Client, plan *Plan, target, keepAs string) error {
+	parent, ok := plan.folders[parentPath(keepAs)]
+	if !ok {
+		return fmt.Errorf("no Drive folder for %s", keepAs)
+	}
+	kept := filepath.Join(plan.Local, filepath.FromSlash(keepAs))
+	if err := os.Rename(target, kept); err != nil {
+		return err
+	}
+	f, err := os.Open(kept)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = upload(ctx, c, f, path.Base(keepAs), parent, info.Size())
+	return err
+}
@@ -0,0 +1,121 @@
+package dirsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+// readTree lists everything below dir like tree.
+func readTree(t *testing.T, dir string) []string {
+	t.Helper()
+	var out []string
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			out = append(out, rel+"/")
+			return nil
+		}
+		b, _ := os.ReadFile(p)
+		out = append(out, rel+"="+string(b))
+		return nil
+	})
+	sort.Strings(out)
+	return out
+}
+
+func TestPull(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	add(srv, "same", "root", "same.txt", "unchanged")
+	add(srv, "chg", "root", "changed.txt", "new")
+	add(srv, "sub", "root", "sub", "/")
+	add(srv, "a", "sub", "a.txt", "aaa")
+	edited := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	srv.Add(drive.File{ID: "doc", Name: "Notes", MimeType: "application/vnd.google-apps.document", Parents: []string{"root"}, ModifiedTime: edited},
+		[]byte("exported Notes"))
+	srv.Add(drive.File{ID: "form", Name: "Survey", MimeType: "application/vnd.google-apps.form", Parents: []string{"root"}}, nil)
+	c := srv.Client()
+	ctx := context.Background()
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"same.txt":      "unchanged",
+		"changed.txt":   "old",
+		"stale/old.txt": "x",
+		"stray.tmp":     "x",
+	})
+
+	plan, err := PlanPull(ctx, c, "root", local, PullOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("PlanPull: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	want := `- pull stale/
+- pull stray.tmp
++ pull Notes.docx
++ pull sub/
++ pull sub/a.txt
+~ pull changed.txt
+`
+	if out.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if err := Apply(ctx, c, plan); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	got := strings.Join(readTree(t, local), "\n")
+	if want := "Notes.docx=exported Notes\nchanged.txt=new\nsame.txt=unchanged\nsub/\nsub/a.txt=aaa"; got != want {
+		t.Fatalf("local after pull:\n%s\nwant:\n%s", got, want)
+	}
+	if info, err := os.Stat(filepath.Join(local, "Notes.docx")); err != nil || !info.ModTime().Equal(edited) {
+		t.Fatalf("export mtime = %v, %v", info.ModTime(), err)
+	}
+
+	plan, err = PlanPull(ctx, c, "root", local, PullOptions{Delete: true})
+	if err != nil || !plan.Empty() {
+		t.Fatalf("second plan not empty: %+v, %v", plan.Actions, err)
+	}
+	doc, _ := srv.File("doc")
+	doc.ModifiedTime = edited.Add(time.Hour)
+	srv.Add(doc, srv.Content("doc"))
+	plan, err = PlanPull(ctx, c, "root", local, PullOptions{
+		ExportFormats: map[string]drive.ExportFormat{"application/vnd.google-apps.document": {MimeType: "application/pdf", Extension: ".pdf"}},
+	})
+	if err != nil || len(plan.Actions) != 1 || plan.Actions[0].Path != "Notes.pdf" || plan.Actions[0].ExportMimeType != "application/pdf" {
+		t.Fatalf("custom export plan = %+v, %v", plan.Actions, err)
+	}
+}
+
+func TestPlanPull_FileShortcut(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	root := srv.AddFolder("Root", "")
+	srv.Add(drive.File{Name: "a-link", MimeType: drive.ShortcutMimeType, Parents: []string{root},
+		ShortcutDetails: &drive.ShortcutDetails{TargetID: "x", TargetMimeType: "application/pdf"}}, nil)
+	srv.Add(drive.File{Name: "report.pdf", Parents: []string{root}}, []byte("%PDF"))
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"report.pdf": "%PDF"})
+
+	// A file shortcut must not hide the files after it, or they would be
+	// deleted locally as gone from Drive.
+	plan, err := PlanPull(context.Background(), srv.Client(), root, local, PullOptions{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("plan = %+v; want nothing to do", plan.Actions)
+	}
+}
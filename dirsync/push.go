@@ -0,0 +1,192 @@
+package dirsync
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/ignore"
+)
+
+// PushOptions tunes PlanPush.
+type PushOptions struct {
+	// Delete trashes remote items that no longer exist locally.
+	Delete bool
+}
+
+// PlanPush compares localDir with the Drive folder folderID and returns the
+// changes that make Drive match it, without making any. Files are compared
+// by size, then MD5. Google-native files in Drive have no local
+// counterpart and are never touched.
+func PlanPush(ctx context.Context, c *drive.Client, localDir, folderID string, opts PushOptions) (*Plan, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	ign, err := ignore.Load(localDir)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scanLocal(localDir, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", localDir, err)
+	}
+	remote, err := scanRemote(ctx, c, folderID, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan Drive folder: %w", err)
+	}
+	plan := &Plan{Local: localDir, RemoteID: folderID, folders: map[string]string{}}
+	for p, f := range remote {
+		if f.IsFolder() {
+			plan.folders[p] = f.ID
+		}
+	}
+
+	// deleted holds remote items about to be trashed; nothing below them
+	// can be reused.
+	deleted := map[string]bool{}
+	for p, r := range remote {
+		l, ok := local[p]
+		switch {
+		case isNative(r):
+			continue
+		case ok && l.Dir == r.IsFolder():
+			continue
+		case ok && !opts.Delete:
+			plan.Actions = append(plan.Actions, Action{Direction: Push, Op: OpConflict, Path: p, RemoteID: r.ID, Reason: kindMismatch(l.Dir)})
+			continue
+		case !opts.Delete:
+			continue
+		}
+		a := Action{Direction: Push, Op: OpDelete, Path: p, Folder: r.IsFolder(), RemoteID: r.ID}
+		if !r.IsFolder() {
+			a.RemoteVersion = r.Version
+		}
+		plan.Actions = append(plan.Actions, a)
+		deleted[p] = true
+		delete(plan.folders, p)
+	}
+	plan.Actions = dropNested(plan.Actions, deleted)
+
+	for p, l := range local {
+		r, exists := remote[p]
+		switch {
+		case !exists || inside(p, deleted):
+			plan.Actions = append(plan.Actions, Action{Direction: Push, Op: OpCreate, Path: p, Folder: l.Dir, Size: l.Size, ModifiedTime: l.ModTime})
+		case isNative(r):
+			plan.Actions = append(plan.Actions, Action{Direction: Push, Op: OpConflict, Path: p, RemoteID: r.ID, Reason: "a Google-native file has this name in Drive"})
+		case l.Dir || r.IsFolder():
+			// Matching folders, or a mismatch already reported.
+		default:
+			changed, err := contentChanged(filepath.Join(localDir, filepath.FromSlash(p)), l, r)
+			if err != nil {
+				return nil, err
+			}
+			if changed {
+				plan.Actions = append(plan.Actions, Action{Direction: Push, Op: OpUpdate, Path: p, Size: l.Size, RemoteID: r.ID, RemoteVersion: r.Version, ModifiedTime: l.ModTime})
+			}
+		}
+	}
+	sortActions(plan.Actions)
+	return plan, nil
+}
+
+// contentChanged compares a local file with its Drive copy by size, then MD5.
+func contentChanged(localPath string, l localEntry, r drive.File) (bool, error) {
+	if l.Size != r.Size {
+		return true, nil
+	}
+	if r.MD5 == "" {
+		return false, nil
+	}
+	sum, err := fileMD5(localPath)
+	if err != nil {
+		return false, err
+	}
+	return sum != r.MD5, nil
+}
+
+func kindMismatch(localDir bool) string {
+	if localDir {
+		return "a directory locally but a file in Drive"
+	}
+	return "a file locally but a folder in Drive"
+}
+
+// inside reports whether p or any folder above it is in set.
+func inside(p string, set map[string]bool) bool {
+	for dir := p; dir != ""; dir = parentPath(dir) {
+		if set[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// dropNested removes deletes of items inside folders that are themselves
+// deleted, since trashing a folder takes its contents along.
+func dropNested(actions []Action, deleted map[string]bool) []Action {
+	out := actions[:0]
+	for _, a := range actions {
+		if a.Op == OpDelete && inside(parentPath(a.Path), deleted) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func applyPush(ctx context.Context, c *drive.Client, plan *Plan, a *Action) error {
+	if a.RemoteVersion != 0 {
+		if err := c.CheckUnchanged(ctx, drive.File{ID: a.RemoteID, Version: a.RemoteVersion}); err != nil {
+			return err
+		}
+	}
+	switch a.Op {
+	case OpDelete:
+		_, err := c.TrashFile(ctx, a.RemoteID)
+		return err
+	case OpUpdate:
+		f, err := os.Open(filepath.Join(plan.Local, filepath.FromSlash(a.Path)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = c.UpdateContent(ctx, a.RemoteID, f, a.Size)
+		return err
+	case OpCreate:
+		parent, ok := plan.folders[parentPath(a.Path)]
+		if !ok {
+			return fmt.Errorf("parent folder of %s was not created", a.Path)
+		}
+		name := path.Base(a.Path)
+		if a.Folder {
+			f, err := c.CreateFolder(ctx, name, parent)
+			if err != nil {
+				return err
+			}
+			plan.folders[a.Path] = f.ID
+			a.RemoteID = f.ID
+			return nil
+		}
+		f, err := os.Open(filepath.Join(plan.Local, filepath.FromSlash(a.Path)))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		id, err := upload(ctx, c, f, name, parent, a.Size)
+		if err == nil {
+			a.RemoteID = id
+		}
+		return err
+	}
+	return fmt.Errorf("unknown op %q", a.Op)
+}
+
+func upload(ctx context.Context, c *drive.Client, f *os.File, name, parentID string, size int64) (string, error) {
+	return c.Upload(ctx, drive.Metadata{Name: name, MimeType: mime.TypeByExtension(path.Ext(name)), Parents: []string{parentID}}, f, size)
+}
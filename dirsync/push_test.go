@@ -0,0 +1,292 @@
+package dirsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+	"github.com/hwalton/gdrivetoolbox/events"
+)
+
+// add stores a file in srv under parent: a folder if content is "/", a
+// Google Doc that exports as what follows "native:", and else a file with
+// content.
+func add(srv *drivetest.Server, id, parent, name, content string) {
+	f := drive.File{ID: id, Name: name, Parents: []string{parent}}
+	switch {
+	case content == "/":
+		f.MimeType, content = drive.FolderMimeType, ""
+	case strings.HasPrefix(content, "native:"):
+		f.MimeType, content = "application/vnd.google-apps.document", strings.TrimPrefix(content, "native:")
+	}
+	srv.Add(f, []byte(content))
+}
+
+// edit changes a file in srv as another client would.
+func edit(t *testing.T, srv *drivetest.Server, id, content string) {
+	t.Helper()
+	if _, err := srv.Client().UpdateContent(context.Background(), id, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tree lists the untrashed paths below root in srv, folders with a
+// trailing slash and files with their content.
+func tree(srv *drivetest.Server) []string {
+	var out []string
+	var walk func(id, prefix string)
+	walk = func(id, prefix string) {
+		for _, f := range srv.Children(id) {
+			if f.IsFolder() {
+				out = append(out, prefix+f.Name+"/")
+				walk(f.ID, prefix+f.Name+"/")
+			} else {
+				out = append(out, prefix+f.Name+"="+string(srv.Content(f.ID)))
+			}
+		}
+	}
+	walk("root", "")
+	sort.Strings(out)
+	return out
+}
+
+// lists counts the folder listings srv has answered.
+func lists(srv *drivetest.Server) int {
+	n := 0
+	for _, req := range srv.Requests() {
+		if req == "GET /drive/v3/files" {
+			n++
+		}
+	}
+	return n
+}
+
+// writeTree creates files below dir; names ending in "/" are directories.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPush(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	add(srv, "same", "root", "same.txt", "unchanged")
+	add(srv, "chg", "root", "changed.txt", "old")
+	add(srv, "sub", "root", "sub", "/")
+	add(srv, "gone", "sub", "gone.txt", "x")
+	add(srv, "olddir", "root", "olddir", "/")
+	add(srv, "deep", "olddir", "deep.txt", "x")
+	add(srv, "doc", "root", "Notes", "native:")
+	c := srv.Client()
+	ctx := context.Background()
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		"same.txt":        "unchanged",
+		"changed.txt":     "new",
+		"sub/kept.txt":    "k",
+		"new/nested/a.md": "aaa",
+		"empty/":          "",
+	})
+
+	plan, err := PlanPush(ctx, c, local, "root", PushOptions{})
+	if err != nil {
+		t.Fatalf("PlanPush: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	want := `+ push empty/
++ push new/
++ push new/nested/
++ push new/nested/a.md
++ push sub/kept.txt
+~ push changed.txt
+`
+	if out.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	plan, err = PlanPush(ctx, c, local, "root", PushOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("PlanPush: %v", err)
+	}
+	out.Reset()
+	plan.WriteTo(&out)
+	want = "- push olddir/\n- push sub/gone.txt\n" + want
+	if out.String() != want {
+		t.Fatalf("plan with delete:\n%s\nwant:\n%s", out.String(), want)
+	}
+	rec := &eventRecorder{}
+	Events = rec
+	defer func() { Events = nil }()
+	if err := Apply(ctx, c, plan); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	wantEvents := "started " + local + "|archived olddir olddir|archived sub/gone.txt gone|finished synced 7 bytes"
+	if got := strings.Join(rec.lines, "|"); got != wantEvents {
+		t.Fatalf("events = %s\nwant %s", got, wantEvents)
+	}
+	if len(rec.uploaded) != 3 || rec.uploaded["new/nested/a.md"] != 3 || rec.uploaded["changed.txt"] != 3 {
+		t.Fatalf("upload progress = %v", rec.uploaded)
+	}
+	got := strings.Join(tree(srv), "\n")
+	if want := "Notes=\nchanged.txt=new\nempty/\nnew/\nnew/nested/\nnew/nested/a.md=aaa\nsame.txt=unchanged\nsub/\nsub/kept.txt=k"; got != want {
+		t.Fatalf("Drive after push:\n%s\nwant:\n%s", got, want)
+	}
+
+	plan, err = PlanPush(ctx, c, local, "root", PushOptions{Delete: true})
+	if err != nil || !plan.Empty() {
+		t.Fatalf("second plan not empty: %+v, %v", plan.Actions, err)
+	}
+}
+
+// eventRecorder records the events of an Apply.
+type eventRecorder struct {
+	events.Nop
+	lines    []string
+	uploaded map[string]int64 // bytes by path, once done
+}
+
+func (r *eventRecorder) DeployStarted(e events.DeployStarted) {
+	r.lines = append(r.lines, "started "+e.Name)
+}
+
+func (r *eventRecorder) FileArchived(e events.FileArchived) {
+	r.lines = append(r.lines, "archived "+e.ArchivedAs+" "+e.FileID)
+}
+
+func (r *eventRecorder) UploadProgress(e events.UploadProgress) {
+	if e.Progress.Done {
+		if r.uploaded == nil {
+			r.uploaded = map[string]int64{}
+		}
+		r.uploaded[e.File] = e.Progress.Transferred
+	}
+}
+
+func (r *eventRecorder) DeployFinished(e events.DeployFinished) {
+	r.lines = append(r.lines, fmt.Sprintf("finished %s %d bytes", e.Status, e.Stats.Bytes))
+}
+
+func (r *eventRecorder) Error(e events.Error) {
+	r.lines = append(r.lines, fmt.Sprintf("error %s: %v", e.Op, e.Err))
+}
+
+func TestPushConflicts(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	add(srv, "x", "root", "x", "/")
+	add(srv, "doc", "root", "Notes", "native:")
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"x": "file now", "Notes": "plain"})
+	plan, err := PlanPush(context.Background(), srv.Client(), local, "root", PushOptions{})
+	if err != nil {
+		t.Fatalf("PlanPush: %v", err)
+	}
+	if !plan.Empty() || len(plan.Conflicts()) != 2 {
+		t.Fatalf("expected two conflicts, got %+v", plan.Actions)
+	}
+	if c := plan.Conflicts()[1]; c.Path != "x" || !strings.Contains(c.String(), "folder in Drive") {
+		t.Fatalf("conflict = %s", c)
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	add(srv, "log", "root", "debug.log", "remote log")
+	add(srv, "doc", "root", "Draft", "native:")
+	c := srv.Client()
+	ctx := context.Background()
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{
+		".gdriveignore":  "*.log\nbuild/\n.DS_Store\nDraft.docx\n",
+		"main.txt":       "m",
+		".DS_Store":      "x",
+		"build/out.bin":  "x",
+		"sub/.DS_Store":  "x",
+		"sub/keep.txt":   "k",
+		"local-only.log": "x",
+	})
+	plan, err := PlanPush(ctx, c, local, "root", PushOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("PlanPush: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	if want := "+ push .gdriveignore\n+ push main.txt\n+ push sub/\n+ push sub/keep.txt\n"; out.String() != want {
+		t.Fatalf("push plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	plan, err = PlanPull(ctx, c, "root", local, PullOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("PlanPull: %v", err)
+	}
+	out.Reset()
+	plan.WriteTo(&out)
+	if want := "- pull main.txt\n- pull sub/\n"; out.String() != want {
+		t.Fatalf("pull plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestPushRemoteChanged(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	root := srv.AddFolder("Docs", "")
+	srv.Add(drive.File{ID: "a", Name: "a.txt", Parents: []string{root}}, []byte("old a"))
+	srv.Add(drive.File{ID: "b", Name: "b.txt", Parents: []string{root}}, []byte("old b"))
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("new a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := srv.Client()
+	ctx := context.Background()
+
+	plan, err := PlanPush(ctx, c, dir, root, PushOptions{Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Someone edits both files in Drive after the plan was made.
+	for _, id := range []string{"a", "b"} {
+		if _, err := c.UpdateContent(ctx, id, strings.NewReader("edited"), 6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = Apply(ctx, c, plan)
+	if !errors.Is(err, drive.ErrConflict) {
+		t.Fatalf("Apply = %v, want conflicts", err)
+	}
+	for _, a := range plan.Actions {
+		if !errors.Is(a.Err, drive.ErrConflict) {
+			t.Errorf("%s: %v, want a conflict", a, a.Err)
+		}
+	}
+	for _, id := range []string{"a", "b"} {
+		if f, _ := srv.File(id); string(srv.Content(id)) != "edited" || f.Trashed {
+			t.Errorf("%s overwritten: %+v %q", id, f, srv.Content(id))
+		}
+	}
+}
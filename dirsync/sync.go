@@ -0,0 +1,494 @@
+package dirsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/changes"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/ignore"
+)
+
+// Conflict strategies, for items changed on both sides since the last sync.
+const (
+	// NewestWins keeps whichever copy was modified last.
+	NewestWins = "newest"
+	// RemoteWins keeps the Drive copy.
+	RemoteWins = "remote"
+	// KeepBoth keeps the Drive copy under the original name and the local
+	// copy under a name with a conflict suffix, on both sides.
+	KeepBoth = "keep-both"
+)
+
+// StateFile is where Sync keeps its state by default, in the root of the
+// local directory. It is never synced itself.
+const StateFile = ".gdrivesync.json"
+
+// State is what two-way sync remembers between runs: the content of every
+// item as it was when both sides last agreed, so a difference can be
+// attributed to the side that changed.
+type State struct {
+	// PageToken is the changes feed position at the last sync; while the
+	// feed reports nothing relevant, Drive is not walked again.
+	PageToken string           `json:"pageToken,omitempty"`
+	Files     map[string]Entry `json:"files"`
+}
+
+// Entry is the last synced state of one path.
+type Entry struct {
+	RemoteID string `json:"remoteId"`
+	Folder   bool   `json:"folder,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	MD5      string `json:"md5,omitempty"`
+	// LocalModified lets unchanged local files skip hashing.
+	LocalModified time.Time `json:"localModified,omitempty"`
+}
+
+// LoadState reads a state file. A missing file is an empty state, as on the
+// first sync.
+func LoadState(name string) (*State, error) {
+	s := &State{Files: map[string]Entry{}}
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	if s.Files == nil {
+		s.Files = map[string]Entry{}
+	}
+	return s, nil
+}
+
+// Save writes the state file atomically.
+func (s *State) Save(name string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".gdrivesync-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// SyncOptions tunes Sync and PlanSync.
+type SyncOptions struct {
+	// Strategy resolves items changed on both sides; empty means
+	// NewestWins.
+	Strategy string
+	// StatePath is where state is kept; empty means StateFile in the
+	// local directory.
+	StatePath string
+	// DriveID must be set when the folder is in a shared drive, to follow
+	// that drive's changes feed.
+	DriveID string
+	// DryRun plans without applying anything or saving state.
+	DryRun bool
+}
+
+// Sync makes localDir and the Drive folder folderID agree, carrying changes
+// made on either side since the last run over to the other. It returns the
+// plan it applied, whose Conflicts say how each conflict was handled.
+func Sync(ctx context.Context, c *drive.Client, localDir, folderID string, opts SyncOptions) (*Plan, error) {
+	statePath := opts.StatePath
+	if statePath == "" {
+		statePath = filepath.Join(localDir, StateFile)
+	}
+	state, err := LoadState(statePath)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := PlanSync(ctx, c, localDir, folderID, state, opts)
+	if err != nil || opts.DryRun {
+		return plan, err
+	}
+	err = ApplySync(ctx, c, plan, state)
+	if serr := state.Save(statePath); serr != nil && err == nil {
+		err = fmt.Errorf("save sync state: %w", serr)
+	}
+	return plan, err
+}
+
+// PlanSync compares localDir, the Drive folder folderID, and state, and
+// returns the changes that bring both sides together, without making any.
+// Google-native files are left out of two-way sync; use PlanPull to export
+// them.
+func PlanSync(ctx context.Context, c *drive.Client, localDir, folderID string, state *State, opts SyncOptions) (*Plan, error) {
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = NewestWins
+	case NewestWins, RemoteWins, KeepBoth:
+	default:
+		return nil, fmt.Errorf("unknown conflict strategy %q", opts.Strategy)
+	}
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	ign, err := ignore.Load(localDir)
+	if err != nil {
+		return nil, err
+	}
+	local, err := scanLocal(localDir, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", localDir, err)
+	}
+	delete(local, StateFile)
+
+	// Take the token before looking at Drive, so anything changed while
+	// the sync runs is seen next time.
+	token, err := changes.StartPageToken(ctx, c, opts.DriveID)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := remoteSince(ctx, c, folderID, state, opts.DriveID, ign)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Local: localDir, RemoteID: folderID, folders: map[string]string{}, startToken: token, driveID: opts.DriveID, settled: map[string]string{}}
+	for p, f := range remote {
+		if isNative(f) {
+			delete(remote, p)
+		} else if f.IsFolder() {
+			plan.folders[p] = f.ID
+		}
+	}
+
+	paths := map[string]bool{}
+	for p := range local {
+		paths[p] = true
+	}
+	for p := range remote {
+		paths[p] = true
+	}
+	for p, e := range state.Files {
+		// A path ignored since the last sync is left alone on both sides.
+		if !ign.Match(p, e.Folder) {
+			paths[p] = true
+		}
+	}
+	for p := range paths {
+		if err := planPath(plan, p, local, remote, state, opts.Strategy); err != nil {
+			return nil, err
+		}
+	}
+	keepChangedFolders(plan)
+	sortActions(plan.Actions)
+	return plan, nil
+}
+
+// remoteSince returns the Drive tree. If the changes feed has nothing for
+// the synced items since the last run, the tree is rebuilt from state
+// instead of walking Drive again.
+func remoteSince(ctx context.Context, c *drive.Client, folderID string, state *State, driveID string, ign *ignore.Matcher) (map[string]drive.File, error) {
+	if state.PageToken != "" {
+		chs, _, err := changes.Poll(ctx, c, state.PageToken, changes.Options{DriveID: driveID, Fields: "id,parents", IncludeRemoved: true})
+		if err == nil && !relevant(chs, knownIDs(state, folderID), nil) {
+			remote := make(map[string]drive.File, len(state.Files))
+			for p, e := range state.Files {
+				if ign.Match(p, e.Folder) {
+					continue
+				}
+				f := drive.File{ID: e.RemoteID, Name: path.Base(p), Size: e.Size, MD5: e.MD5}
+				if e.Folder {
+					f.MimeType = drive.FolderMimeType
+				}
+				remote[p] = f
+			}
+			return remote, nil
+		}
+	}
+	remote, err := scanRemote(ctx, c, folderID, ign)
+	if err != nil {
+		return nil, fmt.Errorf("scan Drive folder: %w", err)
+	}
+	return remote, nil
+}
+
+// knownIDs returns the IDs of the root and every synced item.
+func knownIDs(state *State, folderID string) map[string]bool {
+	ids := map[string]bool{folderID: true}
+	for _, e := range state.Files {
+		ids[e.RemoteID] = true
+	}
+	return ids
+}
+
+// relevant reports whether any change touches a known item or lands in a
+// known folder, ignoring changes to the IDs in ours.
+func relevant(chs []changes.Change, known, ours map[string]bool) bool {
+	for _, ch := range chs {
+		if ours[ch.FileID] {
+			continue
+		}
+		if known[ch.FileID] {
+			return true
+		}
+		if ch.File != nil {
+			for _, p := range ch.File.Parents {
+				if known[p] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// planPath decides what to do with one path, comparing each side with the
+// last synced state.
+func planPath(plan *Plan, p string, local map[string]localEntry, remote map[string]drive.File, state *State, strategy string) error {
+	l, lok := local[p]
+	r, rok := remote[p]
+	s, sok := state.Files[p]
+	localPath := filepath.Join(plan.Local, filepath.FromSlash(p))
+
+	lch, err := localChanged(localPath, l, lok, s, sok)
+	if err != nil {
+		return err
+	}
+	rch := remoteChanged(r, rok, s, sok)
+	push := Action{Direction: Push, Path: p, Folder: l.Dir, Size: l.Size, RemoteID: r.ID, ModifiedTime: l.ModTime}
+	pull := Action{Direction: Pull, Path: p, Folder: r.IsFolder(), Size: r.Size, RemoteID: r.ID, ModifiedTime: r.ModifiedTime}
+	if rok && !r.IsFolder() {
+		push.RemoteVersion = r.Version
+	}
+	switch {
+	case !lch && !rch:
+		return nil
+	case lok && rok && l.Dir != r.IsFolder():
+		plan.Actions = append(plan.Actions, Action{Direction: Push, Op: OpConflict, Path: p, RemoteID: r.ID, Reason: kindMismatch(l.Dir)})
+		return nil
+	case lok && rok:
+		same, err := sameContent(localPath, l, r)
+		if err != nil {
+			return err
+		}
+		if same {
+			plan.settled[p] = r.ID
+			return nil
+		}
+	case !lok && !rok:
+		plan.settled[p] = ""
+		return nil
+	}
+
+	switch {
+	case lch && !rch:
+		plan.Actions = append(plan.Actions, sideAction(push, lok, rok, r.IsFolder()))
+	case rch && !lch:
+		plan.Actions = append(plan.Actions, sideAction(pull, rok, lok, l.Dir))
+	case !lok:
+		pull.Op, pull.Reason = OpCreate, "deleted locally but changed in Drive; keeping the change"
+		plan.Actions = append(plan.Actions, pull)
+	case !rok:
+		push.Op, push.Reason = OpCreate, "deleted in Drive but changed locally; keeping the change"
+		plan.Actions = append(plan.Actions, push)
+	case strategy == RemoteWins:
+		pull.Op, pull.Reason = OpUpdate, "changed on both sides; Drive copy wins"
+		plan.Actions = append(plan.Actions, pull)
+	case strategy == KeepBoth:
+		pull.Op, pull.KeepAs = OpUpdate, conflictName(p, l.ModTime)
+		pull.Reason = "changed on both sides; local copy kept as " + pull.KeepAs
+		plan.Actions = append(plan.Actions, pull)
+	case r.ModifiedTime.After(l.ModTime):
+		pull.Op, pull.Reason = OpUpdate, "changed on both sides; newer Drive copy wins"
+		plan.Actions = append(plan.Actions, pull)
+	default:
+		push.Op, push.Reason = OpUpdate, "changed on both sides; newer local copy wins"
+		plan.Actions = append(plan.Actions, push)
+	}
+	return nil
+}
+
+// sideAction turns a change on one side into a create, update, or delete
+// on the other.
+func sideAction(a Action, fromExists, toExists, toFolder bool) Action {
+	switch {
+	case !fromExists:
+		a.Op, a.Folder = OpDelete, toFolder
+	case toExists:
+		a.Op = OpUpdate
+	default:
+		a.Op = OpCreate
+	}
+	return a
+}
+
+func localChanged(localPath string, l localEntry, lok bool, s Entry, sok bool) (bool, error) {
+	switch {
+	case !sok || !lok:
+		return lok != sok, nil
+	case l.Dir != s.Folder:
+		return true, nil
+	case l.Dir:
+		return false, nil
+	case l.Size != s.Size:
+		return true, nil
+	case l.ModTime.Equal(s.LocalModified):
+		return false, nil
+	}
+	sum, err := fileMD5(localPath)
+	if err != nil {
+		return false, err
+	}
+	return sum != s.MD5, nil
+}
+
+func remoteChanged(r drive.File, rok bool, s Entry, sok bool) bool {
+	switch {
+	case !sok || !rok:
+		return rok != sok
+	case r.IsFolder() != s.Folder || r.ID != s.RemoteID:
+		return true
+	}
+	return !r.IsFolder() && (r.Size != s.Size || r.MD5 != s.MD5)
+}
+
+// sameContent reports whether a local item and its Drive counterpart
+// already agree.
+func sameContent(localPath string, l localEntry, r drive.File) (bool, error) {
+	if l.Dir || r.IsFolder() {
+		return l.Dir == r.IsFolder(), nil
+	}
+	changed, err := contentChanged(localPath, l, r)
+	return !changed, err
+}
+
+// conflictName inserts a conflict suffix before the extension of p.
+func conflictName(p string, t time.Time) string {
+	ext := path.Ext(p)
+	return fmt.Sprintf("%s (conflict %s)%s", strings.TrimSuffix(p, ext), t.Format("2006-01-02 150405"), ext)
+}
+
+// keepChangedFolders stops a folder from being deleted on one side when
+// something inside it is being created or updated from that side, and drops
+// deletes made redundant by deleting their folder.
+func keepChangedFolders(plan *Plan) {
+	for i := range plan.Actions {
+		d := &plan.Actions[i]
+		if d.Op != OpDelete || !d.Folder {
+			continue
+		}
+		for _, a := range plan.Actions {
+			if a.Direction != d.Direction && (a.Op == OpCreate || a.Op == OpUpdate) && under(a.Path, d.Path) {
+				// Recreate the folder where it was deleted instead.
+				if d.Direction == Push {
+					d.Direction = Pull
+				} else {
+					d.Direction = Push
+				}
+				d.Op, d.Reason = OpCreate, "deleted on one side but changed inside on the other; keeping the change"
+				break
+			}
+		}
+	}
+	deleted := map[string]map[string]bool{Push: {}, Pull: {}}
+	for _, a := range plan.Actions {
+		if a.Op == OpDelete && a.Folder {
+			deleted[a.Direction][a.Path] = true
+		}
+	}
+	out := plan.Actions[:0]
+	for _, a := range plan.Actions {
+		if a.Op == OpDelete && inside(parentPath(a.Path), deleted[a.Direction]) {
+			continue
+		}
+		out = append(out, a)
+	}
+	plan.Actions = out
+}
+
+// under reports whether p is strictly inside dir.
+func under(p, dir string) bool {
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// ApplySync applies a plan from PlanSync and records the outcome in state:
+// every path that now agrees on both sides, and the changes feed position
+// to resume from. Failed actions are left out of state, so the next sync
+// looks at them again.
+func ApplySync(ctx context.Context, c *drive.Client, plan *Plan, state *State) error {
+	err := Apply(ctx, c, plan)
+	ours := map[string]bool{}
+	for _, a := range plan.Actions {
+		if a.Op == OpConflict || a.Err != nil {
+			continue
+		}
+		ours[a.RemoteID] = true
+		if a.Op == OpDelete {
+			for p := range state.Files {
+				if p == a.Path || under(p, a.Path) {
+					delete(state.Files, p)
+				}
+			}
+			continue
+		}
+		e, serr := settle(plan, a.Path, a.RemoteID)
+		if serr != nil {
+			return serr
+		}
+		state.Files[a.Path] = e
+	}
+	for p, id := range plan.settled {
+		if id == "" {
+			delete(state.Files, p)
+			continue
+		}
+		e, serr := settle(plan, p, id)
+		if serr != nil {
+			return serr
+		}
+		state.Files[p] = e
+	}
+
+	// Resume from before this sync unless the feed since then holds only
+	// the changes just made; a failure forces a full walk next time.
+	state.PageToken = ""
+	if err == nil {
+		state.PageToken = plan.startToken
+		chs, next, perr := changes.Poll(ctx, c, plan.startToken, changes.Options{DriveID: plan.driveID, Fields: "id,parents", IncludeRemoved: true})
+		if perr == nil && !relevant(chs, knownIDs(state, plan.RemoteID), ours) {
+			state.PageToken = next
+		}
+	}
+	return err
+}
+
+// settle builds the state entry for a path that agrees on both sides, from
+// the local copy.
+func settle(plan *Plan, p, remoteID string) (Entry, error) {
+	localPath := filepath.Join(plan.Local, filepath.FromSlash(p))
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{RemoteID: remoteID, Folder: info.IsDir()}
+	if e.Folder {
+		return e, nil
+	}
+	e.Size, e.LocalModified = info.Size(), info.ModTime()
+	e.MD5, err = fileMD5(localPath)
+	return e, err
+}
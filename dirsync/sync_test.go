@@ -0,0 +1,116 @@
+package dirsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func TestSync(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	add(srv, "a", "root", "a.txt", "a")
+	add(srv, "sub", "root", "sub", "/")
+	add(srv, "b", "sub", "b.txt", "b")
+	add(srv, "doc", "root", "Notes", "native:")
+	c := srv.Client()
+	ctx := context.Background()
+
+	local := t.TempDir()
+	writeTree(t, local, map[string]string{"c.txt": "c"})
+
+	plan, err := Sync(ctx, c, local, "root", SyncOptions{})
+	if err != nil {
+		t.Fatalf("first Sync: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	if want := "+ pull a.txt\n+ push c.txt\n+ pull sub/\n+ pull sub/b.txt\n"; out.String() != want {
+		t.Fatalf("first plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if got, want := strings.Join(readTree(t, local), "\n"), "a.txt=a\nc.txt=c\nsub/\nsub/b.txt=b"; !strings.HasPrefix(got, ".gdrivesync.json=") || !strings.HasSuffix(got, want) {
+		t.Fatalf("local after first sync:\n%s", got)
+	}
+	if got := strings.Join(tree(srv), "\n"); got != "Notes=\na.txt=a\nc.txt=c\nsub/\nsub/b.txt=b" {
+		t.Fatalf("Drive after first sync:\n%s", got)
+	}
+
+	// Nothing changed: the changes feed saves walking Drive.
+	before := lists(srv)
+	plan, err = Sync(ctx, c, local, "root", SyncOptions{})
+	if err != nil || !plan.Empty() {
+		t.Fatalf("second Sync = %+v, %v", plan.Actions, err)
+	}
+	if lists(srv) != before {
+		t.Fatalf("Drive walked although nothing changed")
+	}
+
+	// One-sided changes travel to the other side.
+	writeTree(t, local, map[string]string{"a.txt": "a2"})
+	os.Remove(filepath.Join(local, "c.txt"))
+	edit(t, srv, "b", "b2")
+	plan, err = Sync(ctx, c, local, "root", SyncOptions{})
+	if err != nil {
+		t.Fatalf("third Sync: %v", err)
+	}
+	out.Reset()
+	plan.WriteTo(&out)
+	if want := "- push c.txt\n~ push a.txt\n~ pull sub/b.txt\n"; out.String() != want {
+		t.Fatalf("third plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if got := strings.Join(tree(srv), "\n"); got != "Notes=\na.txt=a2\nsub/\nsub/b.txt=b2" {
+		t.Fatalf("Drive after third sync:\n%s", got)
+	}
+
+	// Both sides changed a.txt.
+	writeTree(t, local, map[string]string{"a.txt": "local edit"})
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(local, "a.txt"), old, old)
+	edit(t, srv, "a", "drive edit")
+	for strategy, want := range map[string]string{
+		NewestWins: "~ pull a.txt (changed on both sides; newer Drive copy wins)",
+		RemoteWins: "~ pull a.txt (changed on both sides; Drive copy wins)",
+		KeepBoth:   "~ pull a.txt (changed on both sides; local copy kept as a (conflict 2020-01-01 000000).txt)",
+	} {
+		plan, err := Sync(ctx, c, local, "root", SyncOptions{Strategy: strategy, DryRun: true})
+		if err != nil || len(plan.Conflicts()) != 1 || plan.Conflicts()[0].String() != want {
+			t.Fatalf("%s: conflicts = %v, %v", strategy, plan.Conflicts(), err)
+		}
+	}
+	if _, err := Sync(ctx, c, local, "root", SyncOptions{Strategy: KeepBoth}); err != nil {
+		t.Fatalf("KeepBoth Sync: %v", err)
+	}
+	want := "a (conflict 2020-01-01 000000).txt=local edit\na.txt=drive edit\nsub/\nsub/b.txt=b2"
+	if got := strings.Join(tree(srv), "\n"); got != "Notes=\n"+want {
+		t.Fatalf("Drive after conflict:\n%s", got)
+	}
+	if got := strings.Join(readTree(t, local), "\n"); !strings.HasSuffix(got, "\n"+want) {
+		t.Fatalf("local after conflict:\n%s", got)
+	}
+	plan, err = Sync(ctx, c, local, "root", SyncOptions{})
+	if err != nil || !plan.Empty() {
+		t.Fatalf("Sync after conflict = %+v, %v", plan.Actions, err)
+	}
+
+	// A folder deleted locally survives if Drive added to it meanwhile.
+	os.RemoveAll(filepath.Join(local, "sub"))
+	add(srv, "n", "sub", "new.txt", "n")
+	plan, err = Sync(ctx, c, local, "root", SyncOptions{})
+	if err != nil {
+		t.Fatalf("Sync after folder delete: %v", err)
+	}
+	out.Reset()
+	plan.WriteTo(&out)
+	if want := "- push sub/b.txt\n+ pull sub/ (deleted on one side but changed inside on the other; keeping the change)\n+ pull sub/new.txt\n"; out.String() != want {
+		t.Fatalf("folder delete plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if _, err := Sync(ctx, c, local, "root", SyncOptions{Strategy: "mine"}); err == nil {
+		t.Fatal("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,88 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// AboutFields is the field selection that populates every About field.
+const AboutFields = "user(displayName,emailAddress,permissionId),storageQuota,importFormats,exportFormats,maxUploadSize"
+
+// About describes the caller's account and what Drive supports for it.
+type About struct {
+	User struct {
+		DisplayName  string `json:"displayName"`
+		EmailAddress string `json:"emailAddress"`
+		PermissionID string `json:"permissionId"`
+	} `json:"user"`
+	StorageQuota StorageQuota `json:"storageQuota"`
+	// ImportFormats maps a source MIME type to the Google-native types it
+	// can be converted to on upload.
+	ImportFormats map[string][]string `json:"importFormats"`
+	// ExportFormats maps a Google-native type to the MIME types Export can
+	// produce from it.
+	ExportFormats map[string][]string `json:"exportFormats"`
+	MaxUploadSize int64               `json:"maxUploadSize,string"`
+}
+
+// StorageQuota is the caller's storage use in bytes.
+type StorageQuota struct {
+	// Limit is 0 for accounts with unlimited storage.
+	Limit             int64 `json:"limit,string,omitempty"`
+	Usage             int64 `json:"usage,string"`
+	UsageInDrive      int64 `json:"usageInDrive,string"`
+	UsageInDriveTrash int64 `json:"usageInDriveTrash,string"`
+}
+
+// Remaining returns the free space in bytes, and false if storage is
+// unlimited.
+func (q StorageQuota) Remaining() (int64, bool) {
+	if q.Limit == 0 {
+		return 0, false
+	}
+	if q.Usage >= q.Limit {
+		return 0, true
+	}
+	return q.Limit - q.Usage, true
+}
+
+// GetAbout returns information about the caller's account.
+func (c *Client) GetAbout(ctx context.Context) (About, error) {
+	var a About
+	if err := c.DoJSON(ctx, http.MethodGet, "about?"+url.Values{"fields": {AboutFields}}.Encode(), nil, &a); err != nil {
+		return About{}, err
+	}
+	return a, nil
+}
+
+// CheckQuota fails with ErrQuotaExceeded if fewer than need bytes of
+// storage quota remain. Run it before large uploads or bulk syncs. Files in
+// shared drives count against the organization's pool rather than the
+// caller's quota, so skip the check for those.
+func (c *Client) CheckQuota(ctx context.Context, need int64) error {
+	a, err := c.GetAbout(ctx)
+	if err != nil {
+		return fmt.Errorf("check quota: %w", err)
+	}
+	free, limited := a.StorageQuota.Remaining()
+	if limited && need > free {
+		return fmt.Errorf("%w: %s needed but only %s of %s free", ErrQuotaExceeded, formatBytes(need), formatBytes(free), formatBytes(a.StorageQuota.Limit))
+	}
+	return nil
+}
+
+// formatBytes renders n in binary units, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,72 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAbout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/drive/v3/about" || r.URL.Query().Get("fields") != AboutFields {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{
+			"user":{"displayName":"Ann","emailAddress":"ann@example.com"},
+			"storageQuota":{"limit":"1000","usage":"250","usageInDrive":"200","usageInDriveTrash":"10"},
+			"exportFormats":{"application/vnd.google-apps.document":["application/pdf"]},
+			"maxUploadSize":"5242880000000"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	a, err := c.GetAbout(context.Background())
+	if err != nil {
+		t.Fatalf("GetAbout: %v", err)
+	}
+	if a.User.EmailAddress != "ann@example.com" || a.ExportFormats["application/vnd.google-apps.document"][0] != "application/pdf" || a.MaxUploadSize != 5242880000000 {
+		t.Fatalf("About = %+v", a)
+	}
+	if free, limited := a.StorageQuota.Remaining(); free != 750 || !limited {
+		t.Fatalf("Remaining = %d, %v", free, limited)
+	}
+	if _, limited := (StorageQuota{Usage: 5}).Remaining(); limited {
+		t.Fatal("zero limit should be unlimited")
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	uploads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/drive/v3/about" {
+			w.Write([]byte(`{"storageQuota":{"limit":"3221225472","usage":"2147483648"}}`))
+			return
+		}
+		uploads++
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"errors":[{"reason":"storageQuotaExceeded"}],"message":"The user's Drive storage quota has been exceeded."}}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+
+	if err := c.CheckQuota(ctx, 1<<20); err != nil {
+		t.Fatalf("CheckQuota(1 MiB): %v", err)
+	}
+	err := c.CheckQuota(ctx, 3<<30)
+	if !errors.Is(err, ErrQuotaExceeded) || !strings.Contains(err.Error(), "3.0 GiB needed but only 1.0 GiB of 3.0 GiB free") {
+		t.Fatalf("CheckQuota(3 GiB) = %v", err)
+	}
+
+	c.QuotaGuard = 1 << 30
+	if _, err := c.Upload(ctx, Metadata{Name: "big.bin"}, strings.NewReader(""), 2<<30); !errors.Is(err, ErrQuotaExceeded) || uploads != 0 {
+		t.Fatalf("guarded Upload = %v after %d uploads", err, uploads)
+	}
+	if _, err := c.Upload(ctx, Metadata{Name: "small.bin"}, strings.NewReader("x"), 1); !errors.Is(err, ErrQuotaExceeded) || uploads != 1 {
+		t.Fatalf("API quota error = %v after %d uploads", err, uploads)
+	}
+}
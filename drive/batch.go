@@ -0,0 +1,36 @@
+package drive
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPartialFailure is matched by errors.Is when a batch of operations
+// failed for some of its items but succeeded for others.
+var ErrPartialFailure = errors.New("drive: partial failure")
+
+// BatchError reports the failures of a batch that carried on past them,
+// such as a sync or a bulk move. It unwraps to the first failure, so
+// errors.Is finds its cause, and matches ErrPartialFailure unless every
+// item failed.
+type BatchError struct {
+	Failed, Total int
+	// Summary says what failed, such as "uploads failed".
+	Summary string
+	// First is the first failure, if it was kept.
+	First error
+}
+
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("%d of %d %s", e.Failed, e.Total, e.Summary)
+	if e.First != nil {
+		msg += ", first: " + e.First.Error()
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() error { return e.First }
+
+func (e *BatchError) Is(target error) bool {
+	return target == ErrPartialFailure && e.Failed < e.Total
+}
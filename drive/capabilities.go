@@ -0,0 +1,65 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Capability names a file capability reported by Drive for the caller.
+type Capability string
+
+// Capabilities checked before destructive operations.
+const (
+	CanEdit                Capability = "canEdit"
+	CanRename              Capability = "canRename"
+	CanDelete              Capability = "canDelete"
+	CanTrash               Capability = "canTrash"
+	CanMoveItemWithinDrive Capability = "canMoveItemWithinDrive"
+	CanAddChildren         Capability = "canAddChildren"
+)
+
+// ErrInsufficientPermission is matched by errors.Is when the caller lacks a
+// capability, whether found by CheckCapabilities or reported by the API.
+var ErrInsufficientPermission = errors.New("drive: insufficient permission")
+
+// PermissionError reports a capability the caller lacks on a file.
+type PermissionError struct {
+	FileID     string
+	Name       string
+	Capability Capability
+}
+
+func (e *PermissionError) Error() string {
+	name := e.Name
+	if name == "" {
+		name = e.FileID
+	}
+	return fmt.Sprintf("insufficient permission on %q: %s is false", name, e.Capability)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return ErrInsufficientPermission
+}
+
+// CheckCapabilities fetches the caller's capabilities on fileID and returns
+// a *PermissionError for the first of caps that is missing. Multi-step
+// operations call it up front so they fail before changing anything rather
+// than with a 403 halfway through.
+func (c *Client) CheckCapabilities(ctx context.Context, fileID string, caps ...Capability) error {
+	names := make([]string, len(caps))
+	for i, cp := range caps {
+		names[i] = string(cp)
+	}
+	f, err := c.GetFile(ctx, fileID, "id", "name", "capabilities("+strings.Join(names, ",")+")")
+	if err != nil {
+		return fmt.Errorf("read capabilities: %w", err)
+	}
+	for _, cp := range caps {
+		if !f.Capabilities[string(cp)] {
+			return &PermissionError{FileID: f.ID, Name: f.Name, Capability: cp}
+		}
+	}
+	return nil
+}
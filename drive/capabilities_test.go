@@ -0,0 +1,43 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/files/f1":
+			if got := r.URL.Query().Get("fields"); !strings.HasPrefix(got, "id,name,capabilities(canRename") {
+				t.Errorf("fields = %q", got)
+			}
+			w.Write([]byte(`{"id":"f1","name":"SOP.pdf","capabilities":{"canRename":true,"canDelete":false}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"message":"no","errors":[{"reason":"insufficientFilePermissions"}]}}`))
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+
+	err := c.CheckCapabilities(ctx, "f1", CanRename, CanDelete)
+	var perr *PermissionError
+	if !errors.As(err, &perr) || perr.Capability != CanDelete || !errors.Is(err, ErrInsufficientPermission) {
+		t.Fatalf("CheckCapabilities = %v", err)
+	}
+	if err.Error() != `insufficient permission on "SOP.pdf": canDelete is false` {
+		t.Fatalf("message = %q", err)
+	}
+	if err := c.CheckCapabilities(ctx, "f1", CanRename); err != nil {
+		t.Fatalf("CheckCapabilities(canRename) = %v", err)
+	}
+	if _, err := c.Rename(ctx, "f2", "x"); !errors.Is(err, ErrInsufficientPermission) {
+		t.Fatalf("API 403 not matched: %v", err)
+	}
+}
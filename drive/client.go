@@ -0,0 +1,222 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// APIBase is the root of the Drive v3 metadata API.
+	APIBase = "https://www.googleapis.com/drive/v3"
+	// UploadBase is the root of the Drive v3 media upload API.
+	UploadBase = "https://www.googleapis.com/upload/drive/v3"
+)
+
+// ErrNotFound is matched by errors.Is for any 404 returned by the API.
+var ErrNotFound = errors.New("drive: not found")
+
+// ErrUnauthorized is matched by errors.Is for a 401, when the credentials
+// are missing, expired, or revoked.
+var ErrUnauthorized = errors.New("drive: unauthorized")
+
+// ErrRateLimited is matched by errors.Is when the API asks the caller to
+// slow down, with a 429 or a 403 rate limit reason.
+var ErrRateLimited = errors.New("drive: rate limited")
+
+// ErrQuotaExceeded is matched by errors.Is when the caller's storage quota
+// is too small, whether reported by the API or found by CheckQuota.
+var ErrQuotaExceeded = errors.New("drive: storage quota exceeded")
+
+// Client issues requests against the Drive API. It authenticates with an
+// OAuth2 access token, an API key, or nothing at all for anonymous reads
+// of publicly shared files.
+type Client struct {
+	AccessToken string
+	APIKey      string
+	// HTTPClient is used for every request; nil means http.DefaultClient.
+	HTTPClient *http.Client
+	// OnProgress, if set, receives progress for every content transfer.
+	OnProgress ProgressFunc
+	// QuotaGuard, if positive, makes Upload check the remaining storage
+	// quota before sending content of at least this many bytes, failing
+	// with ErrQuotaExceeded instead of partway through the transfer.
+	QuotaGuard int64
+}
+
+// NewClient returns a Client authenticated with an OAuth2 access token.
+func NewClient(accessToken string) *Client {
+	return &Client{AccessToken: accessToken}
+}
+
+// NewAPIKeyClient returns a Client that can only read publicly shared files.
+func NewAPIKeyClient(apiKey string) *Client {
+	return &Client{APIKey: apiKey}
+}
+
+// NewAnonymousClient returns a Client with no credentials at all.
+func NewAnonymousClient() *Client {
+	return &Client{}
+}
+
+// Anonymous reports whether the client has neither a token nor an API key.
+func (c *Client) Anonymous() bool {
+	return c.AccessToken == "" && c.APIKey == ""
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Endpoint resolves a path relative to APIBase. Absolute URLs are returned
+// unchanged so callers can target the upload API or other Google APIs.
+func Endpoint(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	return APIBase + "/" + strings.TrimPrefix(path, "/")
+}
+
+// NewRequest builds an authenticated request. When the client only has an
+// API key it is appended as the key query parameter. A correlation ID on
+// ctx is sent as CorrelationHeader.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	target := Endpoint(path)
+	if c.AccessToken == "" && c.APIKey != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		q := u.Query()
+		q.Set("key", c.APIKey)
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	}
+	SetCorrelationHeader(req)
+	return req, nil
+}
+
+// Do sends req and converts any non-2xx response into an *APIError. On
+// success the caller owns the response body.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		e := newAPIError(resp.StatusCode, body)
+		e.Attempts = attempts(resp.Request)
+		return nil, e
+	}
+	return resp, nil
+}
+
+// DoJSON sends in (if non-nil) as a JSON body and decodes the response into
+// out (if non-nil).
+func (c *Client) DoJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := c.NewRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// APIError is returned for any non-2xx response from the API.
+type APIError struct {
+	StatusCode int
+	Reason     string
+	Message    string
+	Body       string
+	// Attempts is how many times the request was sent, counting the
+	// repeats of a Retrier.
+	Attempts int
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	e := &APIError{StatusCode: status, Body: string(body)}
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		e.Message = payload.Error.Message
+		if len(payload.Error.Errors) > 0 {
+			e.Reason = payload.Error.Errors[0].Reason
+		}
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.TrimSpace(e.Body)
+	}
+	if e.Reason != "" {
+		return fmt.Sprintf("drive: status %d (%s): %s", e.StatusCode, e.Reason, msg)
+	}
+	return fmt.Sprintf("drive: status %d: %s", e.StatusCode, msg)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode == http.StatusForbidden && (e.Reason == "rateLimitExceeded" || e.Reason == "userRateLimitExceeded")
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusForbidden && e.Reason == "storageQuotaExceeded"
+	case ErrInsufficientPermission:
+		return e.StatusCode == http.StatusForbidden && (e.Reason == "insufficientFilePermissions" || e.Reason == "insufficientPermissions")
+	case ErrConflict:
+		return e.StatusCode == http.StatusPreconditionFailed
+	}
+	return false
+}
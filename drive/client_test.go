@@ -0,0 +1,112 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+// testClient points c at srv regardless of which Google host it targets.
+func testClient(t *testing.T, srv *httptest.Server, c *Client) *Client {
+	t.Helper()
+	u, _ := url.Parse(srv.URL)
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func TestNewRequest_Credentials(t *testing.T) {
+	ctx := context.Background()
+
+	req, err := NewClient("tok").NewRequest(ctx, "GET", "files/abc", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Fatalf("Authorization = %q", got)
+	}
+	if req.URL.String() != APIBase+"/files/abc" {
+		t.Fatalf("url = %s", req.URL)
+	}
+
+	req, _ = NewAPIKeyClient("k1").NewRequest(ctx, "GET", "files/abc?alt=media", nil)
+	if req.Header.Get("Authorization") != "" {
+		t.Fatalf("api key client must not send Authorization")
+	}
+	if req.URL.Query().Get("key") != "k1" || req.URL.Query().Get("alt") != "media" {
+		t.Fatalf("unexpected query: %s", req.URL.RawQuery)
+	}
+
+	req, _ = NewAnonymousClient().NewRequest(ctx, "GET", "https://example.com/x", nil)
+	if req.Header.Get("Authorization") != "" || req.URL.Query().Get("key") != "" {
+		t.Fatalf("anonymous client must not send credentials")
+	}
+}
+
+func TestDo_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":404,"message":"File not found: x.","errors":[{"reason":"notFound"}]}}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	err := c.DoJSON(context.Background(), "GET", "files/x", nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 404 || apiErr.Reason != "notFound" || apiErr.Message != "File not found: x." {
+		t.Fatalf("unexpected error fields: %+v", apiErr)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected errors.Is(err, ErrNotFound)")
+	}
+}
+
+func TestAPIErrorClasses(t *testing.T) {
+	for _, tt := range []struct {
+		err  *APIError
+		want error
+	}{
+		{&APIError{StatusCode: 401}, ErrUnauthorized},
+		{&APIError{StatusCode: 429}, ErrRateLimited},
+		{&APIError{StatusCode: 403, Reason: "userRateLimitExceeded"}, ErrRateLimited},
+		{&APIError{StatusCode: 403, Reason: "storageQuotaExceeded"}, ErrQuotaExceeded},
+		{&APIError{StatusCode: 403, Reason: "insufficientFilePermissions"}, ErrInsufficientPermission},
+	} {
+		for _, class := range []error{ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrQuotaExceeded, ErrInsufficientPermission} {
+			if got := errors.Is(tt.err, class); got != (class == tt.want) {
+				t.Errorf("errors.Is(%v, %v) = %v", tt.err, class, got)
+			}
+		}
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	some := &BatchError{Failed: 1, Total: 3, Summary: "moves failed", First: &APIError{StatusCode: 404, Message: "gone"}}
+	if some.Error() != "1 of 3 moves failed, first: drive: status 404: gone" {
+		t.Fatalf("Error() = %q", some.Error())
+	}
+	if !errors.Is(some, ErrPartialFailure) || !errors.Is(some, ErrNotFound) {
+		t.Fatal("a partial batch should match ErrPartialFailure and its first failure")
+	}
+	if all := (&BatchError{Failed: 2, Total: 2, Summary: "moves failed"}); errors.Is(all, ErrPartialFailure) {
+		t.Fatal("a batch where everything failed is not partial")
+	}
+}
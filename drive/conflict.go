@@ -0,0 +1,92 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrConflict is matched by errors.Is when a file changed since it was
+// read, so a change that depended on it was not made.
+var ErrConflict = errors.New("drive: file changed since it was read")
+
+// ConflictError reports a file that changed since it was read.
+type ConflictError struct {
+	FileID string
+	Name   string
+	// Read and Current are the versions of the file when it was read and
+	// now; both are 0 when it was compared by ModifiedTime.
+	Read, Current int64
+	// ModifiedTime is when it was last changed, and ModifiedBy by whom,
+	// if Drive says.
+	ModifiedTime time.Time
+	ModifiedBy   string
+}
+
+func (e *ConflictError) Error() string {
+	name := e.Name
+	if name == "" {
+		name = e.FileID
+	}
+	msg := fmt.Sprintf("%q changed since it was read", name)
+	if e.Read != 0 {
+		msg += fmt.Sprintf(" (version %d, now %d)", e.Read, e.Current)
+	}
+	if !e.ModifiedTime.IsZero() {
+		msg += ": modified " + e.ModifiedTime.Format(time.RFC3339)
+		if e.ModifiedBy != "" {
+			msg += " by " + e.ModifiedBy
+		}
+	}
+	return msg
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// CheckUnchanged returns a *ConflictError if f has changed in Drive since
+// it was read: if its Version, which Drive raises on every change to the
+// file or its metadata, differs, or without one if its ModifiedTime does.
+// Drive v3 has no ETags or If-Match on files, so operations that read a
+// file and later change it call this just before, to fail rather than
+// overwrite what someone did in between; a change in the moment between
+// the check and the write can still be lost.
+func (c *Client) CheckUnchanged(ctx context.Context, f File) error {
+	if f.Version == 0 && f.ModifiedTime.IsZero() {
+		return fmt.Errorf("check %s is unchanged: it was read without its version or modified time", f.ID)
+	}
+	id, err := ParseID(f.ID)
+	if err != nil {
+		return err
+	}
+	q := url.Values{"supportsAllDrives": {"true"}, "fields": {"id,name,version,modifiedTime,lastModifyingUser(displayName,emailAddress)"}}
+	var now struct {
+		File
+		LastModifyingUser struct {
+			DisplayName  string `json:"displayName"`
+			EmailAddress string `json:"emailAddress"`
+		} `json:"lastModifyingUser"`
+	}
+	if err := c.DoJSON(ctx, http.MethodGet, "files/"+url.PathEscape(id)+"?"+q.Encode(), nil, &now); err != nil {
+		return fmt.Errorf("check %s is unchanged: %w", id, err)
+	}
+	changed := f.Version != now.Version
+	if f.Version == 0 {
+		changed = !f.ModifiedTime.Equal(now.ModifiedTime)
+	}
+	if !changed {
+		return nil
+	}
+	e := &ConflictError{FileID: id, Name: now.Name, ModifiedTime: now.ModifiedTime, ModifiedBy: now.LastModifyingUser.EmailAddress}
+	if e.ModifiedBy == "" {
+		e.ModifiedBy = now.LastModifyingUser.DisplayName
+	}
+	if f.Version != 0 {
+		e.Read, e.Current = f.Version, now.Version
+	}
+	return e
+}
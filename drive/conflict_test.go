@@ -0,0 +1,51 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckUnchanged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/drive/v3/files/f1" || !strings.Contains(r.URL.Query().Get("fields"), "version") {
+			http.Error(w, "unexpected "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"id":"f1","name":"SOP.pdf","version":"7","modifiedTime":"2026-10-14T09:30:00Z",
+			"lastModifyingUser":{"displayName":"Ana","emailAddress":"ana@example.com"}}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+	modified := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+
+	if err := c.CheckUnchanged(ctx, File{ID: "f1", Version: 7}); err != nil {
+		t.Errorf("same version: %v", err)
+	}
+	if err := c.CheckUnchanged(ctx, File{ID: "f1", ModifiedTime: modified}); err != nil {
+		t.Errorf("same modified time: %v", err)
+	}
+
+	err := c.CheckUnchanged(ctx, File{ID: "f1", Version: 5})
+	var ce *ConflictError
+	if !errors.As(err, &ce) || !errors.Is(err, ErrConflict) || ce.Read != 5 || ce.Current != 7 || ce.ModifiedBy != "ana@example.com" {
+		t.Fatalf("changed version: %#v", err)
+	}
+	if want := `"SOP.pdf" changed since it was read (version 5, now 7): modified 2026-10-14T09:30:00Z by ana@example.com`; err.Error() != want {
+		t.Errorf("message %q\nwant    %q", err.Error(), want)
+	}
+	if err := c.CheckUnchanged(ctx, File{ID: "f1", ModifiedTime: modified.Add(-time.Hour)}); !errors.Is(err, ErrConflict) {
+		t.Errorf("older modified time: %v", err)
+	}
+	if err := c.CheckUnchanged(ctx, File{ID: "f1"}); err == nil || errors.Is(err, ErrConflict) {
+		t.Errorf("nothing to compare: %v", err)
+	}
+	if !errors.Is(&APIError{StatusCode: http.StatusPreconditionFailed}, ErrConflict) {
+		t.Error("412 is not a conflict")
+	}
+}
@@ -0,0 +1,52 @@
+package drive
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// CorrelationHeader carries the correlation ID of the operation a request
+// is part of, so that the requests of a multi-step deploy or sync can be
+// found together in logs and proxies.
+const CorrelationHeader = "X-Request-Id"
+
+type correlationKey struct{}
+
+// NewCorrelationID returns a random ID of 16 hex digits.
+func NewCorrelationID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithCorrelationID returns a copy of ctx whose requests, made through
+// Client or SetCorrelationHeader, carry id.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of ctx, or "" if it has none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// EnsureCorrelationID returns ctx and its correlation ID, giving it a new
+// one first if it has none.
+func EnsureCorrelationID(ctx context.Context) (context.Context, string) {
+	if id := CorrelationID(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewCorrelationID()
+	return WithCorrelationID(ctx, id), id
+}
+
+// SetCorrelationHeader sets CorrelationHeader on req to the correlation ID
+// of its context, if any and if not set already.
+func SetCorrelationHeader(req *http.Request) {
+	if id := CorrelationID(req.Context()); id != "" && req.Header.Get(CorrelationHeader) == "" {
+		req.Header.Set(CorrelationHeader, id)
+	}
+}
@@ -0,0 +1,38 @@
+package drive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCorrelationID(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(CorrelationHeader))
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	c := NewClient("token")
+
+	if err := c.DoJSON(context.Background(), "GET", srv.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx, id := EnsureCorrelationID(context.Background())
+	if len(id) != 16 || CorrelationID(ctx) != id {
+		t.Fatalf("EnsureCorrelationID gave %q, context has %q", id, CorrelationID(ctx))
+	}
+	if again, same := EnsureCorrelationID(ctx); same != id || again != ctx {
+		t.Errorf("EnsureCorrelationID replaced %q with %q", id, same)
+	}
+	if err := c.DoJSON(ctx, "GET", srv.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "" || got[1] != id {
+		t.Errorf("headers sent = %q, want none and then %q", got, id)
+	}
+	if NewCorrelationID() == id {
+		t.Error("NewCorrelationID repeated itself")
+	}
+}
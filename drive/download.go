@@ -0,0 +1,133 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PublicDownloadURL is the endpoint used for anonymous downloads of files
+// shared with "anyone with the link".
+const PublicDownloadURL = "https://drive.google.com/uc"
+
+// ErrExportRequiresCredentials is returned by Export on an anonymous client;
+// the export endpoint needs at least an API key.
+var ErrExportRequiresCredentials = errors.New("drive: export requires an access token or API key")
+
+// Download streams the content of a binary (non-Google-native) file to w and
+// returns the number of bytes written. fileID may also be a share URL. Anonymous clients go through the
+// public download endpoint, so the file must be shared publicly.
+func (c *Client) Download(ctx context.Context, fileID string, w io.Writer) (int64, error) {
+	return c.DownloadFrom(ctx, fileID, 0, w)
+}
+
+// DownloadFrom is like Download but starts at byte offset, so an interrupted
+// transfer of a large file can be resumed by appending to what was already
+// saved. An offset at or past the end of the file writes nothing.
+func (c *Client) DownloadFrom(ctx context.Context, fileID string, offset int64, w io.Writer) (int64, error) {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return 0, err
+	}
+	var path string
+	if c.Anonymous() {
+		path = PublicDownloadURL + "?" + url.Values{
+			"export":  {"download"},
+			"id":      {fileID},
+			"confirm": {"t"},
+		}.Encode()
+	} else {
+		path = "files/" + url.PathEscape(fileID) + "?alt=media&supportsAllDrives=true"
+	}
+	return c.fetch(ctx, path, offset, w)
+}
+
+// Export converts a Google-native document (Docs, Sheets, Slides, ...) to
+// mimeType and streams the result to w.
+func (c *Client) Export(ctx context.Context, fileID, mimeType string, w io.Writer) (int64, error) {
+	if mimeType == "" {
+		return 0, errors.New("mimeType is required")
+	}
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return 0, err
+	}
+	if c.Anonymous() {
+		return 0, ErrExportRequiresCredentials
+	}
+	path := "files/" + url.PathEscape(fileID) + "/export?mimeType=" + url.QueryEscape(mimeType)
+	return c.fetch(ctx, path, 0, w)
+}
+
+// fetch streams the content at path from byte offset on to w.
+func (c *Client) fetch(ctx context.Context, path string, offset int64, w io.Writer) (int64, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.Do(req)
+	var apiErr *APIError
+	if offset > 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// Nothing left to fetch.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// The public endpoint answers with an HTML interstitial instead of the
+	// content when the file is not actually shared publicly.
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "text/html" && c.Anonymous() {
+		return 0, fmt.Errorf("drive: file %s is not publicly downloadable", req.URL.Query().Get("id"))
+	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// The range was ignored and the whole file is coming; skip what
+		// the caller already has.
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			return 0, fmt.Errorf("read content: %w", err)
+		}
+		if resp.ContentLength > 0 {
+			resp.ContentLength -= offset
+		}
+	}
+	pw, finish := NewProgressWriter(w, resp.ContentLength, c.OnProgress)
+	n, err := io.Copy(pw, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("read content: %w", err)
+	}
+	finish()
+	return n, nil
+}
+
+// FolderMimeType identifies folders in Drive.
+const FolderMimeType = "application/vnd.google-apps.folder"
+
+// ExportFormat is the target of a Google-native file export.
+type ExportFormat struct {
+	MimeType  string
+	Extension string
+}
+
+// DefaultExportFormats maps Google-native MIME types to the format they are
+// exported as when the content is needed outside Drive.
+var DefaultExportFormats = map[string]ExportFormat{
+	"application/vnd.google-apps.document":     {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+	"application/vnd.google-apps.spreadsheet":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"},
+	"application/vnd.google-apps.presentation": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"},
+	"application/vnd.google-apps.drawing":      {"application/pdf", ".pdf"},
+	"application/vnd.google-apps.script":       {"application/vnd.google-apps.script+json", ".json"},
+}
+
+// IsNative reports whether mimeType is a Google-native type whose content
+// must be exported rather than downloaded.
+func IsNative(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "application/vnd.google-apps.")
+}
@@ -0,0 +1,120 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownload_APIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/drive/v3/files/f1" || r.URL.Query().Get("alt") != "media" || r.URL.Query().Get("key") != "k" {
+			http.Error(w, "bad request: "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("content"))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewAPIKeyClient("k"))
+
+	var buf bytes.Buffer
+	n, err := c.Download(context.Background(), "f1", &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 7 || buf.String() != "content" {
+		t.Fatalf("got %d %q", n, buf.String())
+	}
+}
+
+func TestDownload_Anonymous(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/uc" || r.URL.Query().Get("id") != "pub" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("export") != "download" {
+			http.Error(w, "missing export", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF"))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewAnonymousClient())
+
+	var buf bytes.Buffer
+	if _, err := c.Download(context.Background(), "pub", &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "%PDF" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestDownload_AnonymousNotPublic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>sign in</html>"))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewAnonymousClient())
+
+	if _, err := c.Download(context.Background(), "private", &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error for non-public file")
+	}
+}
+
+func TestDownloadFrom(t *testing.T) {
+	ignoreRange := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ignoreRange {
+			w.Write([]byte("0123456789"))
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	for _, ignore := range []bool{false, true} {
+		ignoreRange = ignore
+		for offset, want := range map[int64]string{0: "0123456789", 4: "456789", 10: ""} {
+			var buf bytes.Buffer
+			n, err := c.DownloadFrom(context.Background(), "f1", offset, &buf)
+			if err != nil || buf.String() != want || n != int64(len(want)) {
+				t.Errorf("ignoreRange=%t DownloadFrom(%d) = %d %q, %v; want %q", ignore, offset, n, buf.String(), err, want)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/drive/v3/files/doc/export" || r.URL.Query().Get("mimeType") != "application/pdf" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("pdf"))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewAPIKeyClient("k"))
+
+	var buf bytes.Buffer
+	if _, err := c.Export(context.Background(), "doc", "application/pdf", &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "pdf" {
+		t.Fatalf("got %q", buf.String())
+	}
+
+	_, err := NewAnonymousClient().Export(context.Background(), "doc", "application/pdf", &buf)
+	if !errors.Is(err, ErrExportRequiresCredentials) {
+		t.Fatalf("expected ErrExportRequiresCredentials, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package drive
+
+import "time"
+
+// FileFields is the field selection that populates every File field.
+const FileFields = "id,name,mimeType,size,md5Checksum,createdTime,modifiedTime,parents,description,trashed,webViewLink,webContentLink,appProperties,properties,starred,viewedByMeTime,shortcutDetails,version"
+
+// ShortcutMimeType identifies shortcuts in Drive.
+const ShortcutMimeType = "application/vnd.google-apps.shortcut"
+
+// File is the subset of Drive file metadata used throughout the toolbox.
+type File struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	// Size is the content size in bytes; Google-native files report 0.
+	Size         int64     `json:"size,string,omitempty"`
+	MD5          string    `json:"md5Checksum,omitempty"`
+	CreatedTime  time.Time `json:"createdTime"`
+	ModifiedTime time.Time `json:"modifiedTime"`
+	Parents      []string  `json:"parents,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	Trashed      bool      `json:"trashed,omitempty"`
+	// WebViewLink opens the file in the Drive UI; WebContentLink downloads
+	// it and is empty for Google-native files.
+	WebViewLink    string            `json:"webViewLink,omitempty"`
+	WebContentLink string            `json:"webContentLink,omitempty"`
+	AppProperties  map[string]string `json:"appProperties,omitempty"`
+	// Properties are visible to every app; AppProperties only to this one.
+	Properties     map[string]string `json:"properties,omitempty"`
+	Starred        bool              `json:"starred,omitempty"`
+	ViewedByMeTime time.Time         `json:"viewedByMeTime"`
+	// ShortcutDetails is set only for shortcuts.
+	ShortcutDetails *ShortcutDetails `json:"shortcutDetails,omitempty"`
+	// Version rises with every change to the file or its metadata; see
+	// CheckUnchanged.
+	Version int64 `json:"version,string,omitempty"`
+	// Capabilities is only set when requested, e.g. by CheckCapabilities.
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+}
+
+// ShortcutDetails identifies the file a shortcut points at.
+type ShortcutDetails struct {
+	TargetID       string `json:"targetId"`
+	TargetMimeType string `json:"targetMimeType,omitempty"`
+}
+
+// IsFolder reports whether f is a folder.
+func (f File) IsFolder() bool {
+	return f.MimeType == FolderMimeType
+}
+
+// IsShortcut reports whether f is a shortcut to another file.
+func (f File) IsShortcut() bool {
+	return f.MimeType == ShortcutMimeType
+}
+
+// IsFolderShortcut reports whether f is a shortcut to a folder. It needs
+// shortcutDetails among the fields f was read with.
+func (f File) IsFolderShortcut() bool {
+	return f.IsShortcut() && f.ShortcutDetails != nil && f.ShortcutDetails.TargetMimeType == FolderMimeType
+}
@@ -0,0 +1,153 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// fileQuery is appended to single-file requests so they work in shared drives
+// and return every File field.
+var fileQuery = "?" + url.Values{"supportsAllDrives": {"true"}, "fields": {FileFields}}.Encode()
+
+// CreateFolder creates a folder called name inside parentID.
+func (c *Client) CreateFolder(ctx context.Context, name, parentID string) (File, error) {
+	if name == "" {
+		return File{}, errors.New("folder name is required")
+	}
+	parentID, err := ParseID(parentID)
+	if err != nil {
+		return File{}, err
+	}
+	meta := Metadata{Name: name, MimeType: FolderMimeType, Parents: []string{parentID}}
+	var f File
+	if err := c.DoJSON(ctx, http.MethodPost, "files"+fileQuery, meta, &f); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// Delete permanently deletes fileID, bypassing the trash.
+func (c *Client) Delete(ctx context.Context, fileID string) error {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return err
+	}
+	return c.DoJSON(ctx, http.MethodDelete, "files/"+url.PathEscape(fileID)+"?supportsAllDrives=true", nil, nil)
+}
+
+// CopyFile copies fileID into destFolderID without transferring content
+// through the client. An empty destFolderID keeps the source's parents and
+// an empty newName leaves the naming to Drive.
+func (c *Client) CopyFile(ctx context.Context, fileID, destFolderID, newName string) (File, error) {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	var meta Metadata
+	meta.Name = newName
+	if destFolderID != "" {
+		dest, err := ParseID(destFolderID)
+		if err != nil {
+			return File{}, err
+		}
+		meta.Parents = []string{dest}
+	}
+	var f File
+	if err := c.DoJSON(ctx, http.MethodPost, "files/"+url.PathEscape(fileID)+"/copy"+fileQuery, meta, &f); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// Move makes newParentID the only parent of fileID. It works within and
+// between shared drives and My Drive, subject to Drive's own restrictions.
+func (c *Client) Move(ctx context.Context, fileID, newParentID string) (File, error) {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	newParentID, err = ParseID(newParentID)
+	if err != nil {
+		return File{}, err
+	}
+	current, err := c.GetFile(ctx, fileID, "parents")
+	if err != nil {
+		return File{}, fmt.Errorf("read parents: %w", err)
+	}
+	var remove []string
+	for _, p := range current.Parents {
+		if p != newParentID {
+			remove = append(remove, p)
+		}
+	}
+	q := url.Values{
+		"supportsAllDrives": {"true"},
+		"fields":            {FileFields},
+		"addParents":        {newParentID},
+	}
+	if len(remove) > 0 {
+		q.Set("removeParents", strings.Join(remove, ","))
+	}
+	var f File
+	if err := c.DoJSON(ctx, http.MethodPatch, "files/"+url.PathEscape(fileID)+"?"+q.Encode(), struct{}{}, &f); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// Rename changes the name of fileID.
+func (c *Client) Rename(ctx context.Context, fileID, newName string) (File, error) {
+	if newName == "" {
+		return File{}, errors.New("new name is required")
+	}
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	var f File
+	if err := c.DoJSON(ctx, http.MethodPatch, "files/"+url.PathEscape(fileID)+fileQuery, Metadata{Name: newName}, &f); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// TrashFile moves fileID to the trash, from where it can be restored with
+// UntrashFile until the trash is emptied.
+func (c *Client) TrashFile(ctx context.Context, fileID string) (File, error) {
+	return c.setTrashed(ctx, fileID, true)
+}
+
+// UntrashFile restores fileID from the trash.
+func (c *Client) UntrashFile(ctx context.Context, fileID string) (File, error) {
+	return c.setTrashed(ctx, fileID, false)
+}
+
+func (c *Client) setTrashed(ctx context.Context, fileID string, trashed bool) (File, error) {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	var f File
+	if err := c.DoJSON(ctx, http.MethodPatch, "files/"+url.PathEscape(fileID)+fileQuery, map[string]bool{"trashed": trashed}, &f); err != nil {
+		return File{}, err
+	}
+	return f, nil
+}
+
+// EmptyTrash permanently deletes every trashed file owned by the caller, or
+// every trashed file of the shared drive driveID if it is not empty.
+func (c *Client) EmptyTrash(ctx context.Context, driveID string) error {
+	path := "files/trash"
+	if driveID != "" {
+		id, err := ParseID(driveID)
+		if err != nil {
+			return err
+		}
+		path += "?" + url.Values{"driveId": {id}}.Encode()
+	}
+	return c.DoJSON(ctx, http.MethodDelete, path, nil, nil)
+}
@@ -0,0 +1,160 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCreateFolder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta Metadata
+		json.NewDecoder(r.Body).Decode(&meta)
+		if r.Method != http.MethodPost || r.URL.Path != "/drive/v3/files" || meta.MimeType != FolderMimeType || meta.Parents[0] != "p" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"id":"new","name":"` + meta.Name + `","mimeType":"` + FolderMimeType + `"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	f, err := c.CreateFolder(context.Background(), "docs", "p")
+	if err != nil || f.ID != "new" || f.Name != "docs" || !f.IsFolder() {
+		t.Fatalf("CreateFolder = %+v, %v", f, err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var meta Metadata
+		json.NewDecoder(r.Body).Decode(&meta)
+		if r.URL.Path != "/drive/v3/files/src/copy" || r.URL.Query().Get("fields") != FileFields {
+			http.Error(w, "bad request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(File{ID: "cp", Name: meta.Name, Parents: meta.Parents})
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	f, err := c.CopyFile(context.Background(), "src", "https://drive.google.com/drive/folders/prod", "SOP.pdf")
+	if err != nil {
+		t.Fatalf("CopyFile: %v", err)
+	}
+	if f.ID != "cp" || f.Name != "SOP.pdf" || len(f.Parents) != 1 || f.Parents[0] != "prod" {
+		t.Fatalf("unexpected copy: %+v", f)
+	}
+}
+
+func TestMove(t *testing.T) {
+	var patched url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"parents":["old1","new","old2"]}`))
+		case http.MethodPatch:
+			patched = r.URL.Query()
+			w.Write([]byte(`{"id":"f","parents":["new"]}`))
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	f, err := c.Move(context.Background(), "f", "new")
+	if err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if patched.Get("addParents") != "new" || patched.Get("removeParents") != "old1,old2" || patched.Get("supportsAllDrives") != "true" {
+		t.Fatalf("unexpected patch query: %v", patched)
+	}
+	if len(f.Parents) != 1 || f.Parents[0] != "new" {
+		t.Fatalf("parents = %v", f.Parents)
+	}
+}
+
+func TestMove_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"parents":["old"]}`))
+			return
+		}
+		http.Error(w, `{"error":{"message":"Insufficient permissions","errors":[{"reason":"insufficientFilePermissions"}]}}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	if _, err := c.Move(context.Background(), "f", "new"); err == nil || !strings.Contains(err.Error(), "insufficientFilePermissions") {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/drive/v3/files/f" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id":"f","name":"x"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	name := `Bob's "final" \ v2.pdf`
+	if _, err := c.Rename(context.Background(), "f", name); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if len(body) != 1 || body["name"] != name {
+		t.Fatalf("unexpected body: %v", body)
+	}
+	if _, err := c.Rename(context.Background(), "f", ""); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+}
+
+func TestTrash(t *testing.T) {
+	var bodies []map[string]bool
+	var emptied []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			var body map[string]bool
+			json.NewDecoder(r.Body).Decode(&body)
+			bodies = append(bodies, body)
+			json.NewEncoder(w).Encode(File{ID: "f", Trashed: body["trashed"]})
+		case r.Method == http.MethodDelete && r.URL.Path == "/drive/v3/files/trash":
+			emptied = append(emptied, r.URL.Query().Get("driveId"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+
+	if f, err := c.TrashFile(ctx, "f"); err != nil || !f.Trashed {
+		t.Fatalf("TrashFile = %+v, %v", f, err)
+	}
+	if f, err := c.UntrashFile(ctx, "f"); err != nil || f.Trashed {
+		t.Fatalf("UntrashFile = %+v, %v", f, err)
+	}
+	if len(bodies) != 2 || !bodies[0]["trashed"] || bodies[1]["trashed"] {
+		t.Fatalf("unexpected bodies: %v", bodies)
+	}
+	if err := c.EmptyTrash(ctx, ""); err != nil {
+		t.Fatalf("EmptyTrash: %v", err)
+	}
+	if err := c.EmptyTrash(ctx, "sd1"); err != nil {
+		t.Fatalf("EmptyTrash(sd1): %v", err)
+	}
+	if strings.Join(emptied, ",") != ",sd1" {
+		t.Fatalf("emptied = %v", emptied)
+	}
+}
@@ -0,0 +1,34 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// MaxGeneratedIDs is the most IDs one GenerateIDs call returns.
+const MaxGeneratedIDs = 1000
+
+// GenerateIDs reserves count file IDs. Passing one as Metadata.ID to Upload
+// creates the file under that ID, so links to it (see ViewURL) can be
+// published before the content exists. Unused IDs simply expire.
+func (c *Client) GenerateIDs(ctx context.Context, count int) ([]string, error) {
+	if count < 1 || count > MaxGeneratedIDs {
+		return nil, fmt.Errorf("count must be between 1 and %d", MaxGeneratedIDs)
+	}
+	q := url.Values{"count": {strconv.Itoa(count)}, "space": {"drive"}, "type": {"files"}}
+	var out struct {
+		IDs []string `json:"ids"`
+	}
+	if err := c.DoJSON(ctx, http.MethodGet, "files/generateIds?"+q.Encode(), nil, &out); err != nil {
+		return nil, fmt.Errorf("generate ids: %w", err)
+	}
+	return out.IDs, nil
+}
+
+// ViewURL returns the browser link for a file ID.
+func ViewURL(id string) string {
+	return "https://drive.google.com/file/d/" + url.PathEscape(id) + "/view"
+}
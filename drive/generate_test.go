@@ -0,0 +1,56 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateIDsAndUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/files/generateIds":
+			if r.URL.Query().Get("count") != "2" {
+				http.Error(w, "bad count", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"ids":["id1","id2"]}`))
+		case "/upload/drive/v3/files":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			part, _ := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+			var meta Metadata
+			json.NewDecoder(part).Decode(&meta)
+			io.Copy(io.Discard, r.Body)
+			w.Write([]byte(`{"id":"` + meta.ID + `"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+
+	ids, err := c.GenerateIDs(ctx, 2)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("GenerateIDs = %v, %v", ids, err)
+	}
+	id, err := c.Upload(ctx, Metadata{ID: ids[0], Name: "SOP.pdf"}, strings.NewReader("pdf"), 3)
+	if err != nil || id != "id1" {
+		t.Fatalf("Upload = %q, %v", id, err)
+	}
+	if _, err := c.GenerateIDs(ctx, 0); err == nil {
+		t.Fatal("expected error for count 0")
+	}
+	if got := ViewURL("id1"); got != "https://drive.google.com/file/d/id1/view" {
+		t.Fatalf("ViewURL = %s", got)
+	}
+	if parsed, _ := ParseID(ViewURL("id1")); parsed != "id1" {
+		t.Fatalf("ParseID(ViewURL) = %s", parsed)
+	}
+}
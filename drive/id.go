@@ -0,0 +1,41 @@
+package drive
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ParseID extracts a file, folder, or shared drive ID from any of the URL
+// forms Drive hands out (file/d/<id>/view, open?id=<id>, uc?id=<id>,
+// drive/folders/<id>, drive/u/0/folders/<id>, drive/shared-drives/<id>,
+// docs.google.com/document/d/<id>/edit, ...). A bare ID is returned as is.
+func ParseID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("drive: empty ID")
+	}
+	if idPattern.MatchString(s) {
+		return s, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("drive: %q is neither an ID nor a Drive URL", s)
+	}
+	if id := u.Query().Get("id"); id != "" && idPattern.MatchString(id) {
+		return id, nil
+	}
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i < len(segs)-1; i++ {
+		switch segs[i] {
+		case "d", "folders", "shared-drives":
+			if id := segs[i+1]; idPattern.MatchString(id) {
+				return id, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("drive: no ID found in %q", s)
+}
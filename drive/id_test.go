@@ -0,0 +1,38 @@
+package drive
+
+import "testing"
+
+func TestParseID(t *testing.T) {
+	const id = "1AbC-dEf_123"
+	cases := []string{
+		id,
+		"  " + id + "\n",
+		"https://drive.google.com/file/d/" + id + "/view?usp=sharing",
+		"https://drive.google.com/file/d/" + id,
+		"https://drive.google.com/open?id=" + id,
+		"https://drive.google.com/uc?export=download&id=" + id,
+		"https://drive.google.com/drive/folders/" + id,
+		"https://drive.google.com/drive/u/0/folders/" + id + "?resourcekey=x",
+		"https://drive.google.com/drive/shared-drives/" + id,
+		"https://docs.google.com/document/d/" + id + "/edit#heading=h.1",
+		"https://docs.google.com/spreadsheets/d/" + id + "/edit",
+	}
+	for _, in := range cases {
+		got, err := ParseID(in)
+		if err != nil {
+			t.Errorf("ParseID(%q) error: %v", in, err)
+			continue
+		}
+		if got != id {
+			t.Errorf("ParseID(%q) = %q; want %q", in, got, id)
+		}
+	}
+}
+
+func TestParseID_Invalid(t *testing.T) {
+	for _, in := range []string{"", "not an id", "https://drive.google.com/drive/my-drive", "mydoc.pdf"} {
+		if got, err := ParseID(in); err == nil {
+			t.Errorf("ParseID(%q) = %q; expected error", in, got)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package drive
+
+import (
+	"context"
+	"io"
+)
+
+// Uploader creates files with content and replaces the content of files.
+// Client implements it; accept it instead of a *Client to test code that
+// uploads with a fake, such as the one in drivemock.
+type Uploader interface {
+	Upload(ctx context.Context, meta Metadata, content io.Reader, size int64) (string, error)
+	UpdateContent(ctx context.Context, fileID string, content io.Reader, size int64) (File, error)
+}
+
+// Mover moves, renames, copies, trashes, and deletes files. Client
+// implements it.
+type Mover interface {
+	Move(ctx context.Context, fileID, newParentID string) (File, error)
+	Rename(ctx context.Context, fileID, newName string) (File, error)
+	CopyFile(ctx context.Context, fileID, destFolderID, newName string) (File, error)
+	TrashFile(ctx context.Context, fileID string) (File, error)
+	Delete(ctx context.Context, fileID string) error
+}
+
+var (
+	_ Uploader = (*Client)(nil)
+	_ Mover    = (*Client)(nil)
+)
@@ -0,0 +1,75 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetFile(t *testing.T) {
+	var fields []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields = append(fields, r.URL.Query().Get("fields"))
+		w.Write([]byte(`{"id":"f","name":"a.pdf","starred":true,"properties":{"team":"qa"}}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	f, err := c.GetFile(context.Background(), "f")
+	if err != nil || !f.Starred || f.Properties["team"] != "qa" {
+		t.Fatalf("GetFile = %+v, %v", f, err)
+	}
+	if _, err := c.GetFile(context.Background(), "f", "id", "name"); err != nil {
+		t.Fatalf("GetFile: %v", err)
+	}
+	if fields[0] != FileFields || fields[1] != "id,name" {
+		t.Fatalf("fields = %v", fields)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	fakeClock(t, time.Second)
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, "bad method", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id":"f"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	desc, starred := "v2", false
+	_, err := c.UpdateMetadata(context.Background(), "f", MetadataPatch{
+		Description:         &desc,
+		Starred:             &starred,
+		AppProperties:       map[string]string{"rev": "2"},
+		RemoveAppProperties: []string{"old"},
+		MarkViewed:          true,
+	})
+	if err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	props, _ := body["appProperties"].(map[string]interface{})
+	if body["description"] != "v2" || body["starred"] != false || props["rev"] != "2" {
+		t.Fatalf("unexpected body: %v", body)
+	}
+	if v, ok := props["old"]; !ok || v != nil {
+		t.Fatalf("removed key must be sent as null: %v", props)
+	}
+	if body["viewedByMeTime"] != "1970-01-01T00:00:01.000Z" {
+		t.Fatalf("viewedByMeTime = %v", body["viewedByMeTime"])
+	}
+	if _, hasName := body["name"]; hasName {
+		t.Fatal("unset fields must not be sent")
+	}
+
+	if _, err := c.UpdateMetadata(context.Background(), "f", MetadataPatch{}); err == nil {
+		t.Fatal("expected error for empty patch")
+	}
+}
@@ -0,0 +1,154 @@
+package drive
+
+import (
+	"io"
+	"time"
+)
+
+const (
+	// progressWindow is how far back throughput is averaged.
+	progressWindow = 5 * time.Second
+	// progressInterval throttles how often a ProgressFunc is called.
+	progressInterval = 250 * time.Millisecond
+)
+
+// timeNow is swapped out by tests.
+var timeNow = time.Now
+
+// Progress is a snapshot of a transfer.
+type Progress struct {
+	Transferred int64
+	// Total is the expected size in bytes, or 0 when unknown.
+	Total   int64
+	Elapsed time.Duration
+	// Rate is the throughput in bytes per second over the last few seconds.
+	Rate float64
+	// ETA is the estimated time remaining, or 0 when it cannot be estimated.
+	ETA  time.Duration
+	Done bool
+}
+
+// Percent returns the completed fraction in [0,100], or -1 if Total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Transferred) * 100 / float64(p.Total)
+}
+
+// ProgressFunc receives throttled progress updates and a final update with
+// Done set once the transfer completes.
+type ProgressFunc func(Progress)
+
+type progressSample struct {
+	at    time.Time
+	bytes int64
+}
+
+type progressTracker struct {
+	fn       ProgressFunc
+	total    int64
+	done     int64
+	start    time.Time
+	lastEmit time.Time
+	samples  []progressSample
+}
+
+func newProgressTracker(total int64, fn ProgressFunc) *progressTracker {
+	now := timeNow()
+	if total < 0 {
+		total = 0
+	}
+	return &progressTracker{
+		fn:      fn,
+		total:   total,
+		start:   now,
+		samples: []progressSample{{at: now}},
+	}
+}
+
+func (t *progressTracker) add(n int64) {
+	now := timeNow()
+	t.done += n
+	t.samples = append(t.samples, progressSample{at: now, bytes: t.done})
+	cutoff := now.Add(-progressWindow)
+	// keep one sample at or before the cutoff so the window stays full
+	for len(t.samples) > 2 && !t.samples[1].at.After(cutoff) {
+		t.samples = t.samples[1:]
+	}
+	if now.Sub(t.lastEmit) >= progressInterval {
+		t.emit(now, false)
+	}
+}
+
+func (t *progressTracker) finish() {
+	t.emit(timeNow(), true)
+}
+
+func (t *progressTracker) emit(now time.Time, done bool) {
+	t.lastEmit = now
+	p := Progress{
+		Transferred: t.done,
+		Total:       t.total,
+		Elapsed:     now.Sub(t.start),
+		Done:        done,
+	}
+	oldest := t.samples[0]
+	if span := now.Sub(oldest.at); span > 0 {
+		p.Rate = float64(t.done-oldest.bytes) / span.Seconds()
+	}
+	if t.total > 0 && p.Rate > 0 && t.done < t.total {
+		p.ETA = time.Duration(float64(t.total-t.done) / p.Rate * float64(time.Second))
+	}
+	t.fn(p)
+}
+
+type progressReader struct {
+	r       io.Reader
+	tracker *progressTracker
+	closed  bool
+}
+
+// NewProgressReader wraps r so that reads are reported to fn. total may be
+// 0 if the size is unknown. The final Done update is sent at EOF.
+func NewProgressReader(r io.Reader, total int64, fn ProgressFunc) io.Reader {
+	if fn == nil {
+		return r
+	}
+	return &progressReader{r: r, tracker: newProgressTracker(total, fn)}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.tracker.add(int64(n))
+	}
+	if err == io.EOF && !p.closed {
+		p.closed = true
+		p.tracker.finish()
+	}
+	return n, err
+}
+
+type progressWriter struct {
+	w       io.Writer
+	tracker *progressTracker
+}
+
+// NewProgressWriter wraps w so that writes are reported to fn. Call the
+// returned finish func once the transfer completes to send the Done update.
+func NewProgressWriter(w io.Writer, total int64, fn ProgressFunc) (io.Writer, func()) {
+	if fn == nil {
+		return w, func() {}
+	}
+	pw := &progressWriter{w: w, tracker: newProgressTracker(total, fn)}
+	return pw, pw.tracker.finish
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		p.tracker.add(int64(n))
+	}
+	return n, err
+}
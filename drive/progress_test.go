@@ -0,0 +1,99 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock makes timeNow advance by step on every call.
+func fakeClock(t *testing.T, step time.Duration) {
+	t.Helper()
+	now := time.Unix(0, 0)
+	orig := timeNow
+	timeNow = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+	t.Cleanup(func() { timeNow = orig })
+}
+
+// chunkReader returns at most n bytes per Read.
+type chunkReader struct {
+	r io.Reader
+	n int
+}
+
+func (c chunkReader) Read(b []byte) (int, error) {
+	if len(b) > c.n {
+		b = b[:c.n]
+	}
+	return c.r.Read(b)
+}
+
+func TestProgressReader_RateAndETA(t *testing.T) {
+	fakeClock(t, time.Second)
+
+	var updates []Progress
+	src := chunkReader{r: strings.NewReader(strings.Repeat("x", 1000)), n: 100}
+	r := NewProgressReader(src, 1000, func(p Progress) { updates = append(updates, p) })
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	if len(updates) < 2 {
+		t.Fatalf("expected several updates, got %d", len(updates))
+	}
+	mid := updates[0]
+	if mid.Transferred != 100 || mid.Rate != 100 {
+		t.Fatalf("first update = %+v; want 100 bytes at 100 B/s", mid)
+	}
+	if mid.ETA != 9*time.Second {
+		t.Fatalf("ETA = %v; want 9s", mid.ETA)
+	}
+	if mid.Percent() != 10 {
+		t.Fatalf("Percent = %v; want 10", mid.Percent())
+	}
+	last := updates[len(updates)-1]
+	if !last.Done || last.Transferred != 1000 || last.ETA != 0 {
+		t.Fatalf("last update = %+v", last)
+	}
+	for _, p := range updates[:len(updates)-1] {
+		if p.Done {
+			t.Fatalf("only the final update should be Done")
+		}
+	}
+}
+
+func TestProgress_UnknownTotal(t *testing.T) {
+	fakeClock(t, time.Second)
+	var last Progress
+	w, finish := NewProgressWriter(io.Discard, -1, func(p Progress) { last = p })
+	w.Write(make([]byte, 50))
+	finish()
+	if last.Total != 0 || last.Percent() != -1 || last.ETA != 0 || !last.Done {
+		t.Fatalf("unexpected progress for unknown total: %+v", last)
+	}
+}
+
+func TestDownload_ReportsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+	var last Progress
+	c := testClient(t, srv, NewClient("tok"))
+	c.OnProgress = func(p Progress) { last = p }
+
+	if _, err := c.Download(context.Background(), "f1", &bytes.Buffer{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !last.Done || last.Transferred != 10 || last.Total != 10 {
+		t.Fatalf("unexpected final progress: %+v", last)
+	}
+}
@@ -0,0 +1,212 @@
+package drive
+
+import (
+	"cmp"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaWindow is the span over which Google counts the Drive API calls of
+// a user against its rate limits.
+const QuotaWindow = 100 * time.Second
+
+// Default limits of a user's Drive API calls per QuotaWindow. Projects can
+// have larger query quotas; the sustained write rate of about three per
+// second is fixed.
+const (
+	DefaultQueryLimit = 20000
+	DefaultWriteLimit = 300
+)
+
+// QuotaTracker is a RoundTripper that counts the Drive API calls of a run
+// by API method, such as "files.list", and keeps track of how close the
+// last QuotaWindow came to the per-user limits: all calls, and writes,
+// which are the calls other than GET and HEAD. Calls to other Google
+// APIs go through uncounted.
+//
+// Put it under a Retrier, so that retries, which count against the quota
+// too, are counted and throttled as well.
+type QuotaTracker struct {
+	// Base sends the requests; nil means http.DefaultTransport.
+	Base http.RoundTripper
+	// QueryLimit and WriteLimit are the calls allowed per QuotaWindow; 0
+	// means DefaultQueryLimit and DefaultWriteLimit.
+	QueryLimit, WriteLimit int
+	// WarnAt is the share of a limit at which OnWarn is called; 0 means
+	// 0.8.
+	WarnAt float64
+	// OnWarn, if set, is called when the calls of the last QuotaWindow
+	// reach WarnAt of a limit, and again only after they fell below it.
+	OnWarn func(QuotaWarning)
+	// Throttle makes a call that would go over a limit wait until the
+	// window has room, instead of being sent to fail with a rate limit
+	// error.
+	Throttle bool
+	// OnThrottle, if set, is called before each such wait.
+	OnThrottle func(req *http.Request, wait time.Duration)
+
+	mu      sync.Mutex
+	calls   map[string]int
+	queries window
+	writes  window
+}
+
+// QuotaWarning says that the calls of the last QuotaWindow neared a limit.
+type QuotaWarning struct {
+	Limit string // "queries" or "writes"
+	Calls int
+	Max   int
+}
+
+// window holds the times of the calls of the last QuotaWindow, oldest
+// first.
+type window struct {
+	times  []time.Time
+	warned bool
+}
+
+// prune drops the calls older than QuotaWindow before now.
+func (w *window) prune(now time.Time) {
+	i := 0
+	for i < len(w.times) && now.Sub(w.times[i]) >= QuotaWindow {
+		i++
+	}
+	w.times = w.times[i:]
+}
+
+// Calls returns how many calls of each API method t has sent.
+func (t *QuotaTracker) Calls() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.calls)
+}
+
+func (t *QuotaTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	method := APIMethod(req)
+	if method == "" {
+		return base.RoundTrip(req)
+	}
+	write := req.Method != http.MethodGet && req.Method != http.MethodHead
+	for {
+		wait := t.take(method, write, time.Now())
+		if wait == 0 {
+			break
+		}
+		if t.OnThrottle != nil {
+			t.OnThrottle(req, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	return base.RoundTrip(req)
+}
+
+// take counts a call of method at now, or, when throttling and the call
+// would go over a limit, returns how long to wait before trying again.
+func (t *QuotaTracker) take(method string, write bool, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.calls == nil {
+		t.calls = map[string]int{}
+	}
+	type limited struct {
+		name string
+		w    *window
+		max  int
+	}
+	limits := []limited{{"queries", &t.queries, cmp.Or(t.QueryLimit, DefaultQueryLimit)}}
+	if write {
+		limits = append(limits, limited{"writes", &t.writes, cmp.Or(t.WriteLimit, DefaultWriteLimit)})
+	}
+	if t.Throttle {
+		var wait time.Duration
+		for _, l := range limits {
+			l.w.prune(now)
+			if len(l.w.times) >= l.max {
+				wait = max(wait, l.w.times[len(l.w.times)-l.max].Add(QuotaWindow).Sub(now))
+			}
+		}
+		if wait > 0 {
+			return wait
+		}
+	}
+	t.calls[method]++
+	warnAt := t.WarnAt
+	if warnAt <= 0 {
+		warnAt = 0.8
+	}
+	for _, l := range limits {
+		l.w.prune(now)
+		l.w.times = append(l.w.times, now)
+		near := float64(len(l.w.times)) >= warnAt*float64(l.max)
+		if near && !l.w.warned && t.OnWarn != nil {
+			t.OnWarn(QuotaWarning{Limit: l.name, Calls: len(l.w.times), Max: l.max})
+		}
+		l.w.warned = near
+	}
+	return 0
+}
+
+// driveCollections are the resources of the Drive API, whose paths
+// alternate between a collection and an ID.
+var driveCollections = map[string]bool{
+	"files": true, "permissions": true, "revisions": true, "comments": true, "replies": true,
+	"changes": true, "drives": true, "about": true, "channels": true, "apps": true,
+}
+
+// collectionActions are the methods called on a collection rather than an
+// item, by the last part of their path.
+var collectionActions = map[string]string{
+	"generateIds": "generateIds", "trash": "emptyTrash", "startPageToken": "getStartPageToken",
+	"watch": "watch", "stop": "stop",
+}
+
+// APIMethod returns the Drive API method req calls, such as "files.list"
+// or "permissions.create", or "" if req is not a call of the Drive API.
+func APIMethod(req *http.Request) string {
+	_, path, ok := strings.Cut(req.URL.Path, "/drive/v3/")
+	if !ok {
+		return ""
+	}
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	n := 0 // index of the innermost resource
+	for i := 0; i < len(segs); i += 2 {
+		if !driveCollections[segs[i]] {
+			break
+		}
+		n = i
+	}
+	resource, rest := segs[n], segs[n+1:]
+	switch {
+	case resource == "about":
+		return "about.get"
+	case len(rest) == 0 && req.Method == http.MethodPost:
+		return resource + ".create"
+	case len(rest) == 0:
+		return resource + ".list"
+	case len(rest) == 1 && collectionActions[rest[0]] != "":
+		return resource + "." + collectionActions[rest[0]]
+	case len(rest) > 1:
+		// An action on an item, as files/ID/copy.
+		return resource + "." + rest[1]
+	}
+	switch req.Method {
+	case http.MethodPatch, http.MethodPut:
+		return resource + ".update"
+	case http.MethodDelete:
+		return resource + ".delete"
+	}
+	return resource + ".get"
+}
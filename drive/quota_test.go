@@ -0,0 +1,96 @@
+package drive
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIMethod(t *testing.T) {
+	for _, tc := range []struct{ method, url, want string }{
+		{"GET", APIBase + "/files", "files.list"},
+		{"POST", APIBase + "/files", "files.create"},
+		{"POST", UploadBase + "/files?uploadType=multipart", "files.create"},
+		{"GET", APIBase + "/files/abc?alt=media", "files.get"},
+		{"PATCH", APIBase + "/files/abc?addParents=x", "files.update"},
+		{"PATCH", UploadBase + "/files/abc", "files.update"},
+		{"DELETE", APIBase + "/files/abc", "files.delete"},
+		{"DELETE", APIBase + "/files/trash", "files.emptyTrash"},
+		{"POST", APIBase + "/files/abc/copy", "files.copy"},
+		{"GET", APIBase + "/files/abc/export", "files.export"},
+		{"GET", APIBase + "/files/generateIds", "files.generateIds"},
+		{"GET", APIBase + "/files/abc/permissions", "permissions.list"},
+		{"POST", APIBase + "/files/abc/permissions", "permissions.create"},
+		{"DELETE", APIBase + "/files/abc/permissions/p1", "permissions.delete"},
+		{"GET", APIBase + "/files/abc/comments/c1/replies", "replies.list"},
+		{"GET", APIBase + "/changes/startPageToken", "changes.getStartPageToken"},
+		{"GET", APIBase + "/about?fields=user", "about.get"},
+		{"POST", "https://oauth2.googleapis.com/token", ""},
+		{"GET", "https://sheets.googleapis.com/v4/spreadsheets/s", ""},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.url, nil)
+		if got := APIMethod(req); got != tc.want {
+			t.Errorf("APIMethod(%s %s) = %q, want %q", tc.method, tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestQuotaTracker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var warnings []QuotaWarning
+	q := &QuotaTracker{QueryLimit: 10, WriteLimit: 3, OnWarn: func(w QuotaWarning) { warnings = append(warnings, w) }}
+	c := &http.Client{Transport: q}
+	for _, m := range []string{"GET", "GET", "PATCH", "PATCH", "DELETE"} {
+		req, _ := http.NewRequest(m, srv.URL+"/drive/v3/files/abc", nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := c.Get(srv.URL + "/v4/spreadsheets/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	calls := q.Calls()
+	if len(calls) != 3 || calls["files.get"] != 2 || calls["files.update"] != 2 || calls["files.delete"] != 1 {
+		t.Errorf("Calls() = %v", calls)
+	}
+	if len(warnings) != 1 || warnings[0] != (QuotaWarning{Limit: "writes", Calls: 3, Max: 3}) {
+		t.Errorf("warnings = %+v", warnings)
+	}
+
+	// Throttled, a call over the limit waits for the oldest one of the
+	// window to leave it; without throttling it goes through.
+	now := time.Now()
+	q = &QuotaTracker{QueryLimit: 2, Throttle: true}
+	for i := range 2 {
+		if wait := q.take("files.get", false, now.Add(time.Duration(i)*time.Second)); wait != 0 {
+			t.Fatalf("call %d waits %s", i, wait)
+		}
+	}
+	if wait := q.take("files.get", false, now.Add(10*time.Second)); wait != 90*time.Second {
+		t.Errorf("third call waits %s, want 90s", wait)
+	}
+	if wait := q.take("files.get", false, now.Add(QuotaWindow)); wait != 0 {
+		t.Errorf("call after the window waits %s", wait)
+	}
+	q.Throttle = false
+	if wait := q.take("files.get", false, now.Add(QuotaWindow)); wait != 0 || q.Calls()["files.get"] != 4 {
+		t.Errorf("unthrottled call waits %s", wait)
+	}
+
+	// A throttled wait ends with the request's context.
+	q.Throttle = true
+	ctx, cancel := context.WithCancel(context.Background())
+	var waited time.Duration
+	q.OnThrottle = func(_ *http.Request, wait time.Duration) { waited = wait; cancel() }
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/drive/v3/files", nil)
+	if _, err := q.RoundTrip(req); err != context.Canceled || waited <= 0 {
+		t.Errorf("cancelled wait: err %v after %s", err, waited)
+	}
+}
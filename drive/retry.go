@@ -0,0 +1,150 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// maxRetryWait caps how long a Retrier waits before a retry, whatever the
+// server asks for.
+const maxRetryWait = time.Minute
+
+// Retrier is a RoundTripper that repeats requests which failed
+// transiently, waiting longer before each retry, as Google asks API
+// clients to. Requests that read, delete, or replace something are
+// repeated after network errors, rate limits, and 5xx responses; others,
+// such as uploads, only after rate limits, which refuse the request before
+// it is carried out. Requests whose body cannot be sent again are never
+// repeated.
+type Retrier struct {
+	// Base sends the requests; nil means http.DefaultTransport.
+	Base http.RoundTripper
+	// Max is how many times a request is repeated at most.
+	Max int
+	// Backoff returns how long to wait before retry n, counting from 1.
+	// Nil means 1s, 2s, 4s, and so on with some jitter, or what a
+	// Retry-After header asks for.
+	Backoff func(n int) time.Duration
+	// OnRetry, if set, is called before each retry with the failure that
+	// caused it.
+	OnRetry func(req *http.Request, err error)
+
+	retries atomic.Int64
+}
+
+// Retries returns how many requests r has repeated.
+func (r *Retrier) Retries() int64 {
+	return r.retries.Load()
+}
+
+func (r *Retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for n := 1; ; n++ {
+		req = req.WithContext(context.WithValue(req.Context(), attemptKey{}, n))
+		resp, err := base.RoundTrip(req)
+		if n > r.Max || !replayable || req.Context().Err() != nil {
+			return resp, err
+		}
+		cause, wait := r.transient(req, resp, err, n)
+		if cause == nil {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(req, cause)
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		r.retries.Add(1)
+		countRetry(req.Context())
+	}
+}
+
+type attemptKey struct{}
+
+// attempts returns how many times a Retrier has sent req, or 1 if it did
+// not send it.
+func attempts(req *http.Request) int {
+	if req == nil {
+		return 1
+	}
+	if n, ok := req.Context().Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// transient returns why the nth attempt at req should be repeated, and
+// how long to wait first, or nil if it should not be.
+func (r *Retrier) transient(req *http.Request, resp *http.Response, err error, n int) (error, time.Duration) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodPut ||
+		req.Method == http.MethodDelete || req.Method == http.MethodOptions
+	var cause error
+	switch {
+	case err != nil:
+		if idempotent {
+			cause = err
+		}
+	case resp.StatusCode == http.StatusTooManyRequests,
+		idempotent && resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		cause = r.responseError(resp)
+	case resp.StatusCode == http.StatusForbidden:
+		// A 403 is retried only for a rate limit reason, so its body is
+		// read and put back for the caller otherwise.
+		apiErr := r.responseError(resp)
+		if !errors.Is(apiErr, ErrRateLimited) {
+			return nil, 0
+		}
+		cause = apiErr
+	}
+	if cause == nil {
+		return nil, 0
+	}
+	if r.Backoff != nil {
+		return cause, r.Backoff(n)
+	}
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return cause, min(time.Duration(secs)*time.Second, maxRetryWait)
+		}
+	}
+	wait := time.Second << min(n-1, 6)
+	return cause, min(wait+rand.N(time.Second), maxRetryWait)
+}
+
+// responseError reads resp's body into an *APIError, leaving a copy of it
+// in resp for the caller.
+func (r *Retrier) responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	e := newAPIError(resp.StatusCode, body)
+	e.Attempts = attempts(resp.Request)
+	return e
+}
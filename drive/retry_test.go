@@ -0,0 +1,120 @@
+package drive
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetrier(t *testing.T) {
+	var attempts []string
+	fail := map[string][]int{
+		"/flaky":   {503, 500},
+		"/limited": {429},
+		"/quota":   {403},
+		"/post":    {503},
+		"/busy":    {503, 503, 503, 503},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		attempts = append(attempts, r.Method+" "+r.URL.Path+" "+string(b))
+		if codes := fail[r.URL.Path]; len(codes) > 0 {
+			fail[r.URL.Path] = codes[1:]
+			if codes[0] == 403 {
+				http.Error(w, `{"error":{"errors":[{"reason":"storageQuotaExceeded"}],"message":"full"}}`, 403)
+				return
+			}
+			if codes[0] == 429 {
+				w.Header().Set("Retry-After", "120")
+			}
+			http.Error(w, "try again", codes[0])
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var waits []time.Duration
+	var causes []error
+	r := &Retrier{Max: 3, Backoff: func(n int) time.Duration { waits = append(waits, time.Duration(n)); return 0 },
+		OnRetry: func(_ *http.Request, err error) { causes = append(causes, err) }}
+	hc := &http.Client{Transport: r}
+	var stats Stats
+	ctx := WithStats(context.Background(), &stats)
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		attempts = nil
+		req, _ := http.NewRequestWithContext(ctx, method, srv.URL+path, strings.NewReader(body))
+		resp, err := hc.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+
+	if resp := do("PUT", "/flaky", "data"); resp.StatusCode != 200 || len(attempts) != 3 || attempts[2] != "PUT /flaky data" {
+		t.Errorf("flaky PUT: %s after %q", resp.Status, attempts)
+	}
+	var apiErr *APIError
+	if len(waits) != 2 || waits[1] != 2 || len(causes) != 2 || !errors.As(causes[0], &apiErr) || apiErr.StatusCode != 503 {
+		t.Errorf("waits %v, causes %v", waits, causes)
+	}
+	if resp := do("POST", "/limited", "x"); resp.StatusCode != 200 || len(attempts) != 2 || !errors.Is(causes[2], ErrRateLimited) {
+		t.Errorf("rate limited POST: %s after %q", resp.Status, attempts)
+	}
+	if resp := do("POST", "/post", "x"); resp.StatusCode != 503 || len(attempts) != 1 {
+		t.Errorf("POST answered 503 was repeated: %q", attempts)
+	}
+	resp := do("GET", "/quota", "")
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 403 || len(attempts) != 1 || !strings.Contains(string(b), "storageQuotaExceeded") {
+		t.Errorf("quota 403: %s %q after %q", resp.Status, b, attempts)
+	}
+	if resp := do("GET", "/busy", ""); resp.StatusCode != 503 || len(attempts) != 4 {
+		t.Errorf("busy GET: %s after %d attempts, want 4", resp.Status, len(attempts))
+	}
+	if stats.Retries != 6 || r.Retries() != 6 {
+		t.Errorf("retries: stats %d, retrier %d, want 6", stats.Retries, r.Retries())
+	}
+}
+
+func TestRetrier_RetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	start := time.Now()
+	_, err := (&http.Client{Transport: &Retrier{Max: 1}}).Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("err = %v after %s; want the context's deadline while waiting", err, time.Since(start))
+	}
+}
+
+func TestRetrier_Attempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"busy"}}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	for _, tc := range []struct {
+		hc   *http.Client
+		want int
+	}{
+		{&http.Client{}, 1},
+		{&http.Client{Transport: &Retrier{Max: 2, Backoff: func(int) time.Duration { return 0 }}}, 3},
+	} {
+		err := (&Client{HTTPClient: tc.hc}).DoJSON(context.Background(), "GET", srv.URL, nil, nil)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != 503 || apiErr.Attempts != tc.want {
+			t.Errorf("err = %#v, want a 503 after %d attempts", err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package drive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRevisions(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/f/revisions":
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"nextPageToken":"n","revisions":[{"id":"r1","size":"3","lastModifyingUser":{"emailAddress":"a@example.com"}}]}`))
+				return
+			}
+			w.Write([]byte(`{"revisions":[{"id":"r2","keepForever":true}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/f/revisions/r1":
+			if r.URL.Query().Get("alt") != "media" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("old"))
+		case r.Method == http.MethodPatch:
+			var body map[string]bool
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "r1", "keepForever": body["keepForever"]})
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+	ctx := context.Background()
+
+	revs, err := c.ListRevisions(ctx, "f")
+	if err != nil || len(revs) != 2 || revs[0].Size != 3 || revs[0].LastModifyingUser.EmailAddress != "a@example.com" || !revs[1].KeepForever {
+		t.Fatalf("ListRevisions = %+v, %v", revs, err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := c.DownloadRevision(ctx, "f", "r1", &buf); err != nil || n != 3 || buf.String() != "old" {
+		t.Fatalf("DownloadRevision = %d, %v, %q", n, err, buf.String())
+	}
+
+	if r, err := c.PinRevision(ctx, "f", "r1", true); err != nil || !r.KeepForever {
+		t.Fatalf("PinRevision = %+v, %v", r, err)
+	}
+
+	if err := c.DeleteRevision(ctx, "f", "r1"); err != nil {
+		t.Fatalf("DeleteRevision: %v", err)
+	}
+	if last := calls[len(calls)-1]; last != "DELETE /drive/v3/files/f/revisions/r1" {
+		t.Fatalf("last call = %s", last)
+	}
+}
@@ -0,0 +1,56 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateShortcut(t *testing.T) {
+	var meta Metadata
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/pub":
+			w.Write([]byte(`{"name":"SOP-001.pdf"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			json.NewDecoder(r.Body).Decode(&meta)
+			w.Write([]byte(`{"id":"sc1","mimeType":"application/vnd.google-apps.shortcut"}`))
+		default:
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	f, err := c.CreateShortcut(context.Background(), "pub", "team", "")
+	if err != nil || f.ID != "sc1" || !f.IsShortcut() {
+		t.Fatalf("CreateShortcut = %+v, %v", f, err)
+	}
+	if meta.Name != "SOP-001.pdf" || meta.MimeType != ShortcutMimeType || meta.Parents[0] != "team" || meta.ShortcutDetails.TargetID != "pub" {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestResolveShortcut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/files/sc":
+			w.Write([]byte(`{"id":"sc","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"real"}}`))
+		case "/drive/v3/files/real":
+			w.Write([]byte(`{"id":"real","name":"a.pdf","mimeType":"application/pdf"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	for _, id := range []string{"sc", "real"} {
+		f, err := c.ResolveShortcut(context.Background(), id)
+		if err != nil || f.ID != "real" {
+			t.Fatalf("ResolveShortcut(%s) = %+v, %v", id, f, err)
+		}
+	}
+}
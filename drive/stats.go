@@ -0,0 +1,61 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// Stats describes a transfer, or a whole operation made of several, so
+// that pipelines can track how long deploys take: how much content was
+// sent or received, how long it took, and how many requests were repeated.
+type Stats struct {
+	Bytes   int64
+	Elapsed time.Duration
+	// Retries counts the requests a Retrier repeated under a context
+	// from WithStats.
+	Retries int
+}
+
+// Throughput returns the average rate in bytes per second, or 0 if nothing
+// was transferred or no time was measured.
+func (s Stats) Throughput() float64 {
+	if s.Bytes <= 0 || s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// MarshalJSON encodes s as bytes, elapsedSeconds, bytesPerSecond, and
+// retries.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Bytes          int64   `json:"bytes"`
+		ElapsedSeconds float64 `json:"elapsedSeconds"`
+		BytesPerSecond float64 `json:"bytesPerSecond"`
+		Retries        int     `json:"retries"`
+	}{s.Bytes, math.Round(s.Elapsed.Seconds()*1000) / 1000, math.Round(s.Throughput()), s.Retries})
+}
+
+type statsKey struct{}
+
+// statsMu guards the Retries of every Stats given to WithStats, as
+// requests of one operation may be retried concurrently.
+var statsMu sync.Mutex
+
+// WithStats returns a copy of ctx under which the requests a Retrier
+// repeats are counted in s.Retries.
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// countRetry adds a retry to the Stats of ctx, if any.
+func countRetry(ctx context.Context) {
+	if s, ok := ctx.Value(statsKey{}).(*Stats); ok {
+		statsMu.Lock()
+		s.Retries++
+		statsMu.Unlock()
+	}
+}
@@ -0,0 +1,22 @@
+package drive
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := Stats{Bytes: 3 << 20, Elapsed: 1500 * time.Millisecond, Retries: 2}
+	if got := s.Throughput(); got != 2<<20 {
+		t.Errorf("Throughput = %v", got)
+	}
+	if got := (Stats{Bytes: 10}).Throughput(); got != 0 {
+		t.Errorf("Throughput without elapsed time = %v", got)
+	}
+	b, err := json.Marshal(s)
+	want := `{"bytes":3145728,"elapsedSeconds":1.5,"bytesPerSecond":2097152,"retries":2}`
+	if err != nil || string(b) != want {
+		t.Errorf("json = %s, %v; want %s", b, err, want)
+	}
+}
@@ -0,0 +1,137 @@
+package drive
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DefaultSwapSettle is how long SwapAppProperty waits, by default, for
+// writes racing with its own to land before it checks whether it won.
+const DefaultSwapSettle = 2 * time.Second
+
+// SwapOptions tunes SwapAppProperty.
+type SwapOptions struct {
+	// Settle is how long to wait after writing before reading the value
+	// back, and the longest the read and the write may be apart; 0 means
+	// DefaultSwapSettle. It should be well above the latency of a request.
+	Settle time.Duration
+}
+
+// SwapError says that an app property did not hold the value a swap
+// expected, or that another writer won a race for it.
+type SwapError struct {
+	FileID, Key string
+	// Want is the value the swap expected and Got the one found, before
+	// or after writing; "" means unset.
+	Want, Got string
+	// Raced is set when the value matched but another write got in the
+	// way of this one.
+	Raced bool
+}
+
+func (e *SwapError) Error() string {
+	if e.Raced {
+		return fmt.Sprintf("app property %q of %s: lost a race to another writer", e.Key, e.FileID)
+	}
+	return fmt.Sprintf("app property %q of %s is %q, not %q", e.Key, e.FileID, e.Got, e.Want)
+}
+
+func (e *SwapError) Unwrap() error {
+	return ErrConflict
+}
+
+// swapNonceKey is the app property that holds the nonce of the last swap
+// of key. It is left on the file after a swap, as clearing it would take
+// another write, so it shows in AppProperties next to key and counts
+// toward Drive's limit on their size.
+func swapNonceKey(key string) string {
+	return key + ".swap"
+}
+
+// SwapAppProperty sets the app property key of fileID to value if it is
+// old, and returns the file's metadata as it then was; "" stands for an
+// unset property in both. If the property is not old, or another writer
+// raced this one, it fails with a *SwapError matching ErrConflict and
+// changes nothing, so locks, leases, and version gates can be built on it.
+//
+// Drive has no conditional writes, so this is done by reading the value,
+// writing the new one with a random nonce in the property key+".swap",
+// waiting opts.Settle, and reading the nonce back. The swap succeeds only
+// if the nonce is still its own and the read and the write were less than
+// Settle apart; so of writers racing for one value at most one succeeds,
+// as long as no request takes longer than Settle. A swap whose write was
+// too late puts back old, which is best effort: the write may already have
+// replaced the value of a writer that won.
+//
+// The nonce property stays on the file once a swap succeeds, so a key
+// managed this way has a sibling key+".swap" in AppProperties, of 16
+// bytes besides its name, that readers of the properties should ignore.
+func (c *Client) SwapAppProperty(ctx context.Context, fileID, key, old, value string, opts SwapOptions) (File, error) {
+	id, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	settle := opts.Settle
+	if settle <= 0 {
+		settle = DefaultSwapSettle
+	}
+	read := timeNow()
+	f, err := c.GetFile(ctx, id, "id", "version", "appProperties")
+	if err != nil {
+		return File{}, fmt.Errorf("swap %s: %w", key, err)
+	}
+	if got := f.AppProperties[key]; got != old {
+		return File{}, &SwapError{FileID: id, Key: key, Want: old, Got: got}
+	}
+
+	if timeNow().Sub(read) >= settle {
+		return File{}, &SwapError{FileID: id, Key: key, Want: old, Got: old, Raced: true}
+	}
+	nonce := NewCorrelationID()
+	if _, err := c.UpdateMetadata(ctx, id, setProperty(key, value, nonce)); err != nil {
+		return File{}, fmt.Errorf("swap %s: %w", key, err)
+	}
+	late := timeNow().Sub(read) >= settle
+
+	timer := time.NewTimer(settle)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return File{}, ctx.Err()
+	case <-timer.C:
+	}
+	f, err = c.GetFile(ctx, id, "id", "version", "appProperties")
+	if err != nil {
+		return File{}, fmt.Errorf("swap %s: verify: %w", key, err)
+	}
+	if f.AppProperties[swapNonceKey(key)] != nonce {
+		return File{}, &SwapError{FileID: id, Key: key, Want: old, Got: f.AppProperties[key], Raced: true}
+	}
+	if late {
+		// Another writer may have read the old value and been told it
+		// won before this write landed; take it back.
+		if _, err := c.UpdateMetadata(ctx, id, setProperty(key, old, "")); err != nil {
+			return File{}, fmt.Errorf("swap %s: undo a late write: %w", key, err)
+		}
+		return File{}, &SwapError{FileID: id, Key: key, Want: old, Got: old, Raced: true}
+	}
+	return f, nil
+}
+
+// setProperty returns the patch that sets key to value, and its nonce to
+// nonce; "" removes either.
+func setProperty(key, value, nonce string) MetadataPatch {
+	var p MetadataPatch
+	for k, v := range map[string]string{key: value, swapNonceKey(key): nonce} {
+		if v == "" {
+			p.RemoveAppProperties = append(p.RemoveAppProperties, k)
+		} else {
+			if p.AppProperties == nil {
+				p.AppProperties = map[string]string{}
+			}
+			p.AppProperties[k] = v
+		}
+	}
+	return p
+}
@@ -0,0 +1,104 @@
+package drive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func TestSwapAppProperty(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	f := srv.Add(drive.File{Name: "SOP.pdf"}, []byte("%PDF"))
+	c := srv.Client()
+	ctx := context.Background()
+	opts := drive.SwapOptions{Settle: 10 * time.Millisecond}
+
+	got, err := c.SwapAppProperty(ctx, f.ID, "lock", "", "alice", opts)
+	if err != nil || got.AppProperties["lock"] != "alice" {
+		t.Fatalf("swap from unset = %+v, %v", got, err)
+	}
+	_, err = c.SwapAppProperty(ctx, f.ID, "lock", "", "bob", opts)
+	var se *drive.SwapError
+	if !errors.As(err, &se) || !errors.Is(err, drive.ErrConflict) || se.Got != "alice" || se.Raced {
+		t.Fatalf("swap from a stale value: %v", err)
+	}
+	if _, err := c.SwapAppProperty(ctx, f.ID, "lock", "alice", "", opts); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := srv.File(f.ID); f.AppProperties["lock"] != "" {
+		t.Errorf("lock not released: %v", f.AppProperties)
+	}
+}
+
+func TestSwapAppPropertyRace(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	f := srv.Add(drive.File{Name: "SOP.pdf", AppProperties: map[string]string{"version": "v1"}}, []byte("%PDF"))
+	ctx := context.Background()
+	opts := drive.SwapOptions{Settle: 100 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = srv.Client().SwapAppProperty(ctx, f.ID, "version", "v1", fmt.Sprintf("v2-%d", i), opts)
+		}()
+	}
+	wg.Wait()
+	stored, _ := srv.File(f.ID)
+	winners := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			winners++
+			if want := fmt.Sprintf("v2-%d", i); stored.AppProperties["version"] != want {
+				t.Errorf("writer %d won, but the value is %q", i, stored.AppProperties["version"])
+			}
+		case !errors.Is(err, drive.ErrConflict):
+			t.Errorf("writer %d: %v", i, err)
+		}
+	}
+	if winners > 1 {
+		t.Fatalf("%d writers won", winners)
+	}
+}
+
+// slowWrites delays the PATCH requests it sends.
+type slowWrites struct {
+	rt    http.RoundTripper
+	delay time.Duration
+}
+
+func (s slowWrites) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch {
+		time.Sleep(s.delay)
+	}
+	return s.rt.RoundTrip(req)
+}
+
+func TestSwapAppPropertyLateWrite(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	f := srv.Add(drive.File{Name: "SOP.pdf", AppProperties: map[string]string{"lock": "alice"}}, []byte("%PDF"))
+	c := srv.Client()
+	c.HTTPClient = &http.Client{Transport: slowWrites{rt: c.HTTPClient.Transport, delay: 30 * time.Millisecond}}
+
+	_, err := c.SwapAppProperty(context.Background(), f.ID, "lock", "alice", "bob", drive.SwapOptions{Settle: 20 * time.Millisecond})
+	var se *drive.SwapError
+	if !errors.As(err, &se) || !se.Raced {
+		t.Fatalf("late write: %v", err)
+	}
+	if stored, _ := srv.File(f.ID); stored.AppProperties["lock"] != "alice" {
+		t.Errorf("late write left %v", stored.AppProperties)
+	}
+}
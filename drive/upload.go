@@ -0,0 +1,202 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// Metadata describes a file being created by Upload.
+type Metadata struct {
+	// ID, if set, must come from GenerateIDs; the file is created with it.
+	ID            string            `json:"id,omitempty"`
+	Name          string            `json:"name,omitempty"`
+	MimeType      string            `json:"mimeType,omitempty"`
+	Parents       []string          `json:"parents,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	AppProperties map[string]string `json:"appProperties,omitempty"`
+	// ShortcutDetails is only set when creating a shortcut.
+	ShortcutDetails *ShortcutDetails `json:"shortcutDetails,omitempty"`
+}
+
+// Upload creates a file from content using a multipart upload and returns
+// the new file's ID. The body is streamed, so content is read only once.
+// size is used for progress reporting and Client.QuotaGuard, and may be 0 if
+// unknown.
+func (c *Client) Upload(ctx context.Context, meta Metadata, content io.Reader, size int64) (string, error) {
+	if meta.Name == "" {
+		return "", errors.New("metadata name is required")
+	}
+	meta.Parents = append([]string(nil), meta.Parents...)
+	for i, p := range meta.Parents {
+		id, err := ParseID(p)
+		if err != nil {
+			return "", err
+		}
+		meta.Parents[i] = id
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("marshal metadata: %w", err)
+	}
+	ctype := meta.MimeType
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	if c.QuotaGuard > 0 && size >= c.QuotaGuard {
+		if err := c.CheckQuota(ctx, size); err != nil {
+			return "", err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(writer, metaJSON, meta.Name, ctype, NewProgressReader(content, size, c.OnProgress)))
+	}()
+
+	req, err := c.NewRequest(ctx, http.MethodPost, UploadBase+"/files?uploadType=multipart&supportsAllDrives=true&fields=id", pr)
+	if err != nil {
+		pr.Close()
+		return "", err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+writer.Boundary())
+	resp, err := c.Do(req)
+	pr.Close()
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decode upload response: %w", err)
+	}
+	if result.ID == "" {
+		return "", errors.New("upload succeeded but returned empty id")
+	}
+	return result.ID, nil
+}
+
+func writeMultipart(writer *multipart.Writer, metaJSON []byte, name, ctype string, content io.Reader) error {
+	metaHeader := make(textproto.MIMEHeader)
+	metaHeader.Set("Content-Type", "application/json; charset=UTF-8")
+	metaPart, err := writer.CreatePart(metaHeader)
+	if err != nil {
+		return err
+	}
+	if _, err := metaPart.Write(metaJSON); err != nil {
+		return err
+	}
+	fileHeader := make(textproto.MIMEHeader)
+	fileHeader.Set("Content-Type", ctype)
+	fileHeader.Set("Content-Disposition", ContentDisposition("form-data", "file", name))
+	filePart, err := writer.CreatePart(fileHeader)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(filePart, content); err != nil {
+		return fmt.Errorf("copy content: %w", err)
+	}
+	return writer.Close()
+}
+
+// ContentDisposition returns a Content-Disposition header value of type
+// disposition for the part called name, if not empty, holding a file
+// called filename. Drive names the file after its metadata, not this
+// header, but proxies and servers that log or check it see the real name:
+// a filename that is not plain ASCII is given as filename* in UTF-8, as in
+// RFC 5987, after a filename with its other characters replaced by "_" for
+// readers that do not know the extended form.
+func ContentDisposition(disposition, name, filename string) string {
+	var b strings.Builder
+	b.WriteString(disposition)
+	if name != "" {
+		b.WriteString("; name=")
+		b.WriteString(quoteParam(name))
+	}
+	ascii := true
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			ascii = false
+			return '_'
+		}
+		return r
+	}, filename)
+	b.WriteString("; filename=")
+	b.WriteString(quoteParam(fallback))
+	if !ascii {
+		b.WriteString("; filename*=UTF-8''")
+		for _, c := range []byte(filename) {
+			if isAttrChar(c) {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		}
+	}
+	return b.String()
+}
+
+// quoteParam returns s as a quoted string, escaping quotes and
+// backslashes.
+func quoteParam(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// isAttrChar reports whether c may appear unencoded in an RFC 5987 value.
+func isAttrChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// UpdateContent replaces the content of fileID, keeping its ID, metadata,
+// and sharing. Drive keeps the old content as a revision. size is used as
+// in Upload.
+func (c *Client) UpdateContent(ctx context.Context, fileID string, content io.Reader, size int64) (File, error) {
+	fileID, err := ParseID(fileID)
+	if err != nil {
+		return File{}, err
+	}
+	if c.QuotaGuard > 0 && size >= c.QuotaGuard {
+		if err := c.CheckQuota(ctx, size); err != nil {
+			return File{}, err
+		}
+	}
+	body := NewProgressReader(content, size, c.OnProgress)
+	req, err := c.NewRequest(ctx, http.MethodPatch, UploadBase+"/files/"+url.PathEscape(fileID)+fileQuery+"&uploadType=media", body)
+	if err != nil {
+		return File{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if size > 0 {
+		req.ContentLength = size
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return File{}, fmt.Errorf("update content: %w", err)
+	}
+	defer resp.Body.Close()
+	var f File
+	if err := json.NewDecoder(resp.Body).Decode(&f); err != nil {
+		return File{}, fmt.Errorf("decode update response: %w", err)
+	}
+	return f, nil
+}
+
+// AppProperties returns the private application properties of a file.
+func (c *Client) AppProperties(ctx context.Context, fileID string) (map[string]string, error) {
+	f, err := c.GetFile(ctx, fileID, "appProperties")
+	if err != nil {
+		return nil, err
+	}
+	return f.AppProperties, nil
+}
@@ -0,0 +1,161 @@
+package drive
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/upload/drive/v3/files" || r.URL.Query().Get("uploadType") != "multipart" {
+			http.Error(w, "bad path", http.StatusBadRequest)
+			return
+		}
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, "bad content-type", http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		metaPart, _ := mr.NextPart()
+		var meta Metadata
+		if err := json.NewDecoder(metaPart).Decode(&meta); err != nil {
+			http.Error(w, "bad meta", http.StatusBadRequest)
+			return
+		}
+		if meta.Name != "a.txt" || len(meta.Parents) != 1 || meta.Parents[0] != "folder1" || meta.AppProperties["k"] != "v" {
+			http.Error(w, "unexpected meta", http.StatusBadRequest)
+			return
+		}
+		filePart, _ := mr.NextPart()
+		if ct := filePart.Header.Get("Content-Type"); ct != "text/plain" {
+			http.Error(w, "unexpected content type "+ct, http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(filePart)
+		if string(body) != "hello" {
+			http.Error(w, "unexpected body", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"id":"new1"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	var last Progress
+	c.OnProgress = func(p Progress) { last = p }
+	id, err := c.Upload(context.Background(), Metadata{
+		Name:          "a.txt",
+		MimeType:      "text/plain",
+		Parents:       []string{"https://drive.google.com/drive/folders/folder1"},
+		AppProperties: map[string]string{"k": "v"},
+	}, strings.NewReader("hello"), 5)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if id != "new1" {
+		t.Fatalf("id = %q", id)
+	}
+	if !last.Done || last.Transferred != 5 {
+		t.Fatalf("unexpected progress: %+v", last)
+	}
+}
+
+func TestUpload_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	if _, err := c.Upload(context.Background(), Metadata{Name: "a"}, strings.NewReader("x"), 1); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := c.Upload(context.Background(), Metadata{}, strings.NewReader("x"), 1); err == nil {
+		t.Fatal("expected error for missing name")
+	}
+}
+
+func TestAppProperties(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fields") != "appProperties" {
+			http.Error(w, "bad fields", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"appProperties":{"a":"1"}}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	props, err := c.AppProperties(context.Background(), "f1")
+	if err != nil {
+		t.Fatalf("AppProperties: %v", err)
+	}
+	if props["a"] != "1" {
+		t.Fatalf("props = %v", props)
+	}
+}
+
+// titles are document names in scripts and forms that have been mangled
+// by uploads.
+var titles = []string{
+	"標準作業手順書.pdf", "품질 매뉴얼.pdf", "Procédure d'hygiène – révisée.pdf", "Ärztliche Übergabe.pdf",
+	"Release 🚀 notes.pdf", `say "hi" \ bye.pdf`, "plain.pdf",
+}
+
+func TestContentDisposition(t *testing.T) {
+	cases := map[string]string{
+		"plain.pdf":     `form-data; name="file"; filename="plain.pdf"`,
+		`say "hi".pdf`:  `form-data; name="file"; filename="say \"hi\".pdf"`,
+		"標準.pdf":        `form-data; name="file"; filename="__.pdf"; filename*=UTF-8''%E6%A8%99%E6%BA%96.pdf`,
+		"Café 🚀.pdf":    `form-data; name="file"; filename="Caf_ _.pdf"; filename*=UTF-8''Caf%C3%A9%20%F0%9F%9A%80.pdf`,
+		"100% done.pdf": `form-data; name="file"; filename="100% done.pdf"`,
+	}
+	for in, want := range cases {
+		if got := ContentDisposition("form-data", "file", in); got != want {
+			t.Errorf("ContentDisposition(%q) =\n %s\nwant\n %s", in, got, want)
+		}
+	}
+	for _, title := range titles {
+		_, params, err := mime.ParseMediaType(ContentDisposition("attachment", "", title))
+		if err != nil || params["filename"] != title {
+			t.Errorf("%q parses back as %q, %v", title, params["filename"], err)
+		}
+	}
+}
+
+func TestUpload_UnicodeNames(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		metaPart, _ := mr.NextPart()
+		var meta Metadata
+		json.NewDecoder(metaPart).Decode(&meta)
+		filePart, _ := mr.NextPart()
+		if filePart.FileName() != meta.Name {
+			t.Errorf("part filename %q, metadata name %q", filePart.FileName(), meta.Name)
+		}
+		got = append(got, meta.Name)
+		w.Write([]byte(`{"id":"new1"}`))
+	}))
+	defer srv.Close()
+	c := testClient(t, srv, NewClient("tok"))
+
+	for _, title := range titles {
+		if _, err := c.Upload(context.Background(), Metadata{Name: title}, strings.NewReader("x"), 1); err != nil {
+			t.Fatalf("Upload(%q): %v", title, err)
+		}
+	}
+	if strings.Join(got, "|") != strings.Join(titles, "|") {
+		t.Errorf("uploaded as %q, want %q", got, titles)
+	}
+}
@@ -0,0 +1,39 @@
+// Package drivemock has mocks of the toolbox's service interfaces:
+// drive.Uploader, drive.Mover, list.Querier, and permissions.Permissioner.
+// Set the function of each method the code under test calls, and check
+// the calls made afterwards:
+//
+//	up := &drivemock.Uploader{
+//		UploadFunc: func(ctx context.Context, meta drive.Metadata, content io.Reader, size int64) (string, error) {
+//			return "1AbC", nil
+//		},
+//	}
+//	publish(ctx, up, "report.pdf")
+//	if calls := up.Calls(); len(calls) != 1 || calls[0].Args[1].(drive.Metadata).Name != "report.pdf" {
+//		t.Errorf("calls = %+v", calls)
+//	}
+//
+// The mocks are generated from the interfaces by gen.go.
+package drivemock
+
+//go:generate go run gen.go
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotMocked is matched by errors.Is for the error of a method whose
+// function is not set.
+var ErrNotMocked = errors.New("drivemock: method not mocked")
+
+// Call is a call of a mock's method, with its arguments in order; the
+// values of a variadic parameter are one slice.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+func notMocked(method string) error {
+	return fmt.Errorf("%w: %s", ErrNotMocked, method)
+}
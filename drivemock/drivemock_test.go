@@ -0,0 +1,86 @@
+package drivemock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/permissions"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// publish is orchestration code as a consumer would write it: replace the
+// content of name in folderID if it is there, or upload it, then share it.
+func publish(ctx context.Context, q list.Querier, up drive.Uploader, p permissions.Permissioner, folderID, name, content string) (string, error) {
+	f, err := q.First(ctx, list.Options{Query: query.New().NameEquals(name).String()})
+	switch {
+	case errors.Is(err, drive.ErrNotFound):
+		id, err := up.Upload(ctx, drive.Metadata{Name: name, Parents: []string{folderID}}, strings.NewReader(content), int64(len(content)))
+		if err != nil {
+			return "", err
+		}
+		f.ID = id
+	case err != nil:
+		return "", err
+	default:
+		if _, err := up.UpdateContent(ctx, f.ID, strings.NewReader(content), int64(len(content))); err != nil {
+			return "", err
+		}
+	}
+	_, err = p.Create(ctx, f.ID, permissions.Permission{Type: permissions.TypeDomain, Role: permissions.RoleReader, Domain: "example.com"}, permissions.CreateOptions{})
+	return f.ID, err
+}
+
+func TestMocks(t *testing.T) {
+	q := &Querier{FirstFunc: func(context.Context, list.Options) (drive.File, error) {
+		return drive.File{}, drive.ErrNotFound
+	}}
+	var uploaded string
+	up := &Uploader{UploadFunc: func(_ context.Context, meta drive.Metadata, content io.Reader, _ int64) (string, error) {
+		b, _ := io.ReadAll(content)
+		uploaded = string(b)
+		return "1AbC", nil
+	}}
+	p := &Permissioner{CreateFunc: func(_ context.Context, fileID string, perm permissions.Permission, _ permissions.CreateOptions) (permissions.Permission, error) {
+		perm.ID = "perm1"
+		return perm, nil
+	}}
+
+	id, err := publish(context.Background(), q, up, p, "pub", "a.pdf", "pdf")
+	if err != nil || id != "1AbC" || uploaded != "pdf" {
+		t.Fatalf("publish = %q, %v; uploaded %q", id, err, uploaded)
+	}
+	calls := up.Calls()
+	if len(calls) != 1 || calls[0].Method != "Upload" || calls[0].Args[1].(drive.Metadata).Parents[0] != "pub" {
+		t.Errorf("uploader calls = %+v", calls)
+	}
+	if calls := p.Calls(); len(calls) != 1 || calls[0].Args[1] != "1AbC" {
+		t.Errorf("permissioner calls = %+v", calls)
+	}
+
+	// An existing file gets new content, which is not mocked here.
+	q.FirstFunc = func(context.Context, list.Options) (drive.File, error) { return drive.File{ID: "old"}, nil }
+	if _, err := publish(context.Background(), q, up, p, "pub", "a.pdf", "pdf"); !errors.Is(err, ErrNotMocked) || !strings.Contains(err.Error(), "Uploader.UpdateContent") {
+		t.Errorf("unmocked method: %v", err)
+	}
+	if len(q.Calls()) != 2 {
+		t.Errorf("querier calls = %+v", q.Calls())
+	}
+}
+
+func TestMockVariadic(t *testing.T) {
+	q := &Querier{GetFileFunc: func(_ context.Context, fileID string, fields ...string) (drive.File, error) {
+		return drive.File{ID: fileID, Name: strings.Join(fields, ",")}, nil
+	}}
+	f, err := q.GetFile(context.Background(), "f1", "id", "name")
+	if err != nil || f.Name != "id,name" {
+		t.Fatalf("GetFile = %+v, %v", f, err)
+	}
+	if args := q.Calls()[0].Args; len(args) != 3 || len(args[2].([]string)) != 2 {
+		t.Errorf("args = %v", args)
+	}
+}
@@ -0,0 +1,226 @@
+//go:build ignore
+
+// gen.go writes mocks.go, a mock for each interface in sources. Run it
+// with go generate after changing one of them.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sources are the interfaces to mock, by the directory of their package.
+var sources = []struct {
+	dir, pkg   string
+	interfaces []string
+}{
+	{"../drive", "github.com/hwalton/gdrivetoolbox/drive", []string{"Uploader", "Mover"}},
+	{"../list", "github.com/hwalton/gdrivetoolbox/list", []string{"Querier"}},
+	{"../permissions", "github.com/hwalton/gdrivetoolbox/permissions", []string{"Permissioner"}},
+}
+
+func main() {
+	var body bytes.Buffer
+	imports := map[string]bool{"sync": true}
+	for _, src := range sources {
+		fset := token.NewFileSet()
+		pkgs, err := parser.ParseDir(fset, src.dir, func(fi os.FileInfo) bool {
+			return !strings.HasSuffix(fi.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := filepath.Base(src.pkg)
+		for _, iface := range src.interfaces {
+			it, file := find(pkgs[name], iface)
+			if it == nil {
+				log.Fatalf("%s.%s not found", name, iface)
+			}
+			imports[src.pkg] = true
+			for _, imp := range file.Imports {
+				path, _ := strconv.Unquote(imp.Path.Value)
+				imports[path] = true
+			}
+			writeMock(&body, name, iface, it)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by gen.go; DO NOT EDIT.\n\npackage drivemock\n\nimport (\n")
+	// The standard library, then the toolbox, as gofmt does not regroup.
+	var std, own []string
+	for p := range imports {
+		switch {
+		case !strings.Contains(body.String(), filepath.Base(p)+"."):
+		case strings.Contains(p, "."):
+			own = append(own, p)
+		default:
+			std = append(std, p)
+		}
+	}
+	for i, group := range [][]string{std, own} {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		slices.Sort(group)
+		for _, p := range group {
+			fmt.Fprintf(&out, "\t%q\n", p)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatalf("%v\n%s", err, out.Bytes())
+	}
+	if err := os.WriteFile("mocks.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// find returns the interface type called name in pkg, and its file.
+func find(pkg *ast.Package, name string) (*ast.InterfaceType, *ast.File) {
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+					if it, ok := ts.Type.(*ast.InterfaceType); ok {
+						return it, f
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// writeMock writes the mock of the interface pkg.name.
+func writeMock(w *bytes.Buffer, pkg, name string, it *ast.InterfaceType) {
+	type method struct {
+		name, params, args, results string
+		variadic                    bool
+		zero                        []string
+	}
+	var methods []method
+	for _, m := range it.Methods.List {
+		ft := m.Type.(*ast.FuncType)
+		var params, args []string
+		variadic := false
+		n := 0
+		for _, p := range ft.Params.List {
+			typ := expr(pkg, p.Type)
+			names := p.Names
+			if len(names) == 0 {
+				names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("a%d", n))}
+			}
+			for _, id := range names {
+				params = append(params, id.Name+" "+typ)
+				args = append(args, id.Name)
+				n++
+			}
+			_, variadic = p.Type.(*ast.Ellipsis)
+		}
+		var results, zero []string
+		for _, r := range ft.Results.List {
+			typ := expr(pkg, r.Type)
+			for range max(1, len(r.Names)) {
+				results = append(results, typ)
+				zero = append(zero, zeroValue(r.Type, typ))
+			}
+		}
+		if len(results) == 0 || results[len(results)-1] != "error" {
+			log.Fatalf("%s.%s.%s does not return an error", pkg, name, m.Names[0].Name)
+		}
+		res := strings.Join(results, ", ")
+		if len(results) > 1 {
+			res = "(" + res + ")"
+		}
+		methods = append(methods, method{m.Names[0].Name, strings.Join(params, ", "), strings.Join(args, ", "), res, variadic, zero})
+	}
+
+	fmt.Fprintf(w, "\n// %s is a mock %s.%s. Each method calls its function, as\n", name, pkg, name)
+	fmt.Fprintf(w, "// %sFunc for %s, and is recorded in Calls; one whose function is\n", methods[0].name, methods[0].name)
+	fmt.Fprintf(w, "// nil fails with ErrNotMocked.\n")
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	for _, m := range methods {
+		fmt.Fprintf(w, "\t%sFunc func(%s) %s\n", m.name, m.params, m.results)
+	}
+	fmt.Fprintf(w, "\n\tmu    sync.Mutex\n\tcalls []Call\n}\n\n")
+	fmt.Fprintf(w, "var _ %s.%s = (*%s)(nil)\n", pkg, name, name)
+	fmt.Fprintf(w, "\n// Calls returns the calls made so far, oldest first.\n")
+	fmt.Fprintf(w, "func (m *%s) Calls() []Call {\n\tm.mu.Lock()\n\tdefer m.mu.Unlock()\n\treturn append([]Call(nil), m.calls...)\n}\n", name)
+	for _, m := range methods {
+		call := m.args
+		if m.variadic {
+			call += "..."
+		}
+		fmt.Fprintf(w, "\nfunc (m *%s) %s(%s) %s {\n", name, m.name, m.params, m.results)
+		fmt.Fprintf(w, "\tm.mu.Lock()\n\tm.calls = append(m.calls, Call{Method: %q, Args: []any{%s}})\n\tm.mu.Unlock()\n", m.name, m.args)
+		fmt.Fprintf(w, "\tif m.%sFunc == nil {\n", m.name)
+		zero := append(slices.Clone(m.zero[:len(m.zero)-1]), fmt.Sprintf("notMocked(%q)", name+"."+m.name))
+		fmt.Fprintf(w, "\t\treturn %s\n\t}\n", strings.Join(zero, ", "))
+		fmt.Fprintf(w, "\treturn m.%sFunc(%s)\n}\n", m.name, call)
+	}
+}
+
+// expr prints e as written outside pkg, qualifying the names pkg declares.
+func expr(pkg string, e ast.Expr) string {
+	return types.ExprString(qualify(pkg, e))
+}
+
+func qualify(pkg string, e ast.Expr) ast.Expr {
+	switch t := e.(type) {
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: t}
+		}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify(pkg, t.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: t.Len, Elt: qualify(pkg, t.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify(pkg, t.Key), Value: qualify(pkg, t.Value)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: qualify(pkg, t.Elt)}
+	}
+	return e
+}
+
+// zeroValue returns the zero value of the type e, printed as typ.
+func zeroValue(e ast.Expr, typ string) string {
+	switch t := e.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return `""`
+		case "bool":
+			return "false"
+		case "error":
+			return "nil"
+		case "int", "int64", "int32", "float64", "uint", "uint64":
+			return "0"
+		}
+		if !ast.IsExported(t.Name) {
+			log.Fatalf("no zero value for %s", typ)
+		}
+		return typ + "{}"
+	case *ast.SelectorExpr:
+		return typ + "{}"
+	}
+	return "nil"
+}
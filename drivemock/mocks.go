@@ -0,0 +1,239 @@
+// Code generated by gen.go; DO NOT EDIT.
+
+package drivemock
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/permissions"
+)
+
+// Uploader is a mock drive.Uploader. Each method calls its function, as
+// UploadFunc for Upload, and is recorded in Calls; one whose function is
+// nil fails with ErrNotMocked.
+type Uploader struct {
+	UploadFunc        func(ctx context.Context, meta drive.Metadata, content io.Reader, size int64) (string, error)
+	UpdateContentFunc func(ctx context.Context, fileID string, content io.Reader, size int64) (drive.File, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ drive.Uploader = (*Uploader)(nil)
+
+// Calls returns the calls made so far, oldest first.
+func (m *Uploader) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+func (m *Uploader) Upload(ctx context.Context, meta drive.Metadata, content io.Reader, size int64) (string, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Upload", Args: []any{ctx, meta, content, size}})
+	m.mu.Unlock()
+	if m.UploadFunc == nil {
+		return "", notMocked("Uploader.Upload")
+	}
+	return m.UploadFunc(ctx, meta, content, size)
+}
+
+func (m *Uploader) UpdateContent(ctx context.Context, fileID string, content io.Reader, size int64) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "UpdateContent", Args: []any{ctx, fileID, content, size}})
+	m.mu.Unlock()
+	if m.UpdateContentFunc == nil {
+		return drive.File{}, notMocked("Uploader.UpdateContent")
+	}
+	return m.UpdateContentFunc(ctx, fileID, content, size)
+}
+
+// Mover is a mock drive.Mover. Each method calls its function, as
+// MoveFunc for Move, and is recorded in Calls; one whose function is
+// nil fails with ErrNotMocked.
+type Mover struct {
+	MoveFunc      func(ctx context.Context, fileID string, newParentID string) (drive.File, error)
+	RenameFunc    func(ctx context.Context, fileID string, newName string) (drive.File, error)
+	CopyFileFunc  func(ctx context.Context, fileID string, destFolderID string, newName string) (drive.File, error)
+	TrashFileFunc func(ctx context.Context, fileID string) (drive.File, error)
+	DeleteFunc    func(ctx context.Context, fileID string) error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ drive.Mover = (*Mover)(nil)
+
+// Calls returns the calls made so far, oldest first.
+func (m *Mover) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+func (m *Mover) Move(ctx context.Context, fileID string, newParentID string) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Move", Args: []any{ctx, fileID, newParentID}})
+	m.mu.Unlock()
+	if m.MoveFunc == nil {
+		return drive.File{}, notMocked("Mover.Move")
+	}
+	return m.MoveFunc(ctx, fileID, newParentID)
+}
+
+func (m *Mover) Rename(ctx context.Context, fileID string, newName string) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Rename", Args: []any{ctx, fileID, newName}})
+	m.mu.Unlock()
+	if m.RenameFunc == nil {
+		return drive.File{}, notMocked("Mover.Rename")
+	}
+	return m.RenameFunc(ctx, fileID, newName)
+}
+
+func (m *Mover) CopyFile(ctx context.Context, fileID string, destFolderID string, newName string) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "CopyFile", Args: []any{ctx, fileID, destFolderID, newName}})
+	m.mu.Unlock()
+	if m.CopyFileFunc == nil {
+		return drive.File{}, notMocked("Mover.CopyFile")
+	}
+	return m.CopyFileFunc(ctx, fileID, destFolderID, newName)
+}
+
+func (m *Mover) TrashFile(ctx context.Context, fileID string) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "TrashFile", Args: []any{ctx, fileID}})
+	m.mu.Unlock()
+	if m.TrashFileFunc == nil {
+		return drive.File{}, notMocked("Mover.TrashFile")
+	}
+	return m.TrashFileFunc(ctx, fileID)
+}
+
+func (m *Mover) Delete(ctx context.Context, fileID string) error {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Delete", Args: []any{ctx, fileID}})
+	m.mu.Unlock()
+	if m.DeleteFunc == nil {
+		return notMocked("Mover.Delete")
+	}
+	return m.DeleteFunc(ctx, fileID)
+}
+
+// Querier is a mock list.Querier. Each method calls its function, as
+// ListFilesFunc for ListFiles, and is recorded in Calls; one whose function is
+// nil fails with ErrNotMocked.
+type Querier struct {
+	ListFilesFunc func(ctx context.Context, opts list.Options) ([]drive.File, error)
+	FirstFunc     func(ctx context.Context, opts list.Options) (drive.File, error)
+	GetFileFunc   func(ctx context.Context, fileID string, fields ...string) (drive.File, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ list.Querier = (*Querier)(nil)
+
+// Calls returns the calls made so far, oldest first.
+func (m *Querier) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+func (m *Querier) ListFiles(ctx context.Context, opts list.Options) ([]drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "ListFiles", Args: []any{ctx, opts}})
+	m.mu.Unlock()
+	if m.ListFilesFunc == nil {
+		return nil, notMocked("Querier.ListFiles")
+	}
+	return m.ListFilesFunc(ctx, opts)
+}
+
+func (m *Querier) First(ctx context.Context, opts list.Options) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "First", Args: []any{ctx, opts}})
+	m.mu.Unlock()
+	if m.FirstFunc == nil {
+		return drive.File{}, notMocked("Querier.First")
+	}
+	return m.FirstFunc(ctx, opts)
+}
+
+func (m *Querier) GetFile(ctx context.Context, fileID string, fields ...string) (drive.File, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "GetFile", Args: []any{ctx, fileID, fields}})
+	m.mu.Unlock()
+	if m.GetFileFunc == nil {
+		return drive.File{}, notMocked("Querier.GetFile")
+	}
+	return m.GetFileFunc(ctx, fileID, fields...)
+}
+
+// Permissioner is a mock permissions.Permissioner. Each method calls its function, as
+// CreateFunc for Create, and is recorded in Calls; one whose function is
+// nil fails with ErrNotMocked.
+type Permissioner struct {
+	CreateFunc func(ctx context.Context, fileID string, p permissions.Permission, opts permissions.CreateOptions) (permissions.Permission, error)
+	ListFunc   func(ctx context.Context, fileID string) ([]permissions.Permission, error)
+	UpdateFunc func(ctx context.Context, fileID string, permissionID string, role string) (permissions.Permission, error)
+	DeleteFunc func(ctx context.Context, fileID string, permissionID string) error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ permissions.Permissioner = (*Permissioner)(nil)
+
+// Calls returns the calls made so far, oldest first.
+func (m *Permissioner) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+func (m *Permissioner) Create(ctx context.Context, fileID string, p permissions.Permission, opts permissions.CreateOptions) (permissions.Permission, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Create", Args: []any{ctx, fileID, p, opts}})
+	m.mu.Unlock()
+	if m.CreateFunc == nil {
+		return permissions.Permission{}, notMocked("Permissioner.Create")
+	}
+	return m.CreateFunc(ctx, fileID, p, opts)
+}
+
+func (m *Permissioner) List(ctx context.Context, fileID string) ([]permissions.Permission, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "List", Args: []any{ctx, fileID}})
+	m.mu.Unlock()
+	if m.ListFunc == nil {
+		return nil, notMocked("Permissioner.List")
+	}
+	return m.ListFunc(ctx, fileID)
+}
+
+func (m *Permissioner) Update(ctx context.Context, fileID string, permissionID string, role string) (permissions.Permission, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Update", Args: []any{ctx, fileID, permissionID, role}})
+	m.mu.Unlock()
+	if m.UpdateFunc == nil {
+		return permissions.Permission{}, notMocked("Permissioner.Update")
+	}
+	return m.UpdateFunc(ctx, fileID, permissionID, role)
+}
+
+func (m *Permissioner) Delete(ctx context.Context, fileID string, permissionID string) error {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: "Delete", Args: []any{ctx, fileID, permissionID}})
+	m.mu.Unlock()
+	if m.DeleteFunc == nil {
+		return notMocked("Permissioner.Delete")
+	}
+	return m.DeleteFunc(ctx, fileID, permissionID)
+}
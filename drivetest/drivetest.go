@@ -0,0 +1,733 @@
+// Package drivetest runs a fake Drive API in memory, for testing code that
+// uses the toolbox, or the Drive API directly, without credentials or
+// network access.
+//
+// A Server answers the files methods of the Drive v3 API: list, with the
+// search query language, get, create, uploads, update, copy, delete, and
+// export, plus about.get and the changes feed. Any credentials are accepted. Point a drive.Client at it
+// with Client, or send every request made through http.DefaultClient to it
+// with HTTPClient, as deploys do:
+//
+//	srv := drivetest.NewServer()
+//	defer srv.Close()
+//	pub, tmp := srv.AddFolder("Published", ""), srv.AddFolder("Staging", "")
+//	http.DefaultClient = srv.HTTPClient()
+//	r, err := deploy.DeployPDFResult(ctx, "token", "mydoc", "v2", tmp, pub, "", "docs")
+//	live := srv.Children(pub)
+//
+// For a route it does not answer, such as permissions or a Sheets call
+// made alongside Drive's, a test can Handle that route itself, leaving the
+// rest to the fake:
+//
+//	srv := drivetest.NewServer()
+//	defer srv.Close()
+//	srv.HandleFunc("GET /drive/v3/files/{id}/permissions", func(w http.ResponseWriter, r *http.Request) {
+//		w.Write([]byte(`{"permissions":[]}`))
+//	})
+//
+// Where the fake is not close enough, a Recorder records the calls a test
+// makes to the real API to a cassette, with credentials and tokens
+// redacted, and replays them in later runs.
+package drivetest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// DefaultEmail is the email address of the account about.get reports.
+const DefaultEmail = "drivetest@example.com"
+
+// Server is a fake Drive API holding its files in memory. Its methods are
+// safe for concurrent use, also while it serves requests.
+//
+// Files created through the API get the IDs new1, new2, and so on, and
+// listings are in the order files were added unless orderBy says
+// otherwise. Parents are not checked to exist, so a test need only add the
+// folders it looks at; a file created without parents is put in "root".
+type Server struct {
+	// URL is the root of the server, as http://127.0.0.1:port.
+	URL string
+	// Email is the address of the account about.get reports; empty means
+	// DefaultEmail.
+	Email string
+	// Now returns the times recorded on files; nil means time.Now.
+	Now func() time.Time
+
+	srv      *httptest.Server
+	mu       sync.Mutex
+	mux      *http.ServeMux // handlers of Handle, if any
+	files    map[string]*file
+	order    []string // IDs, in the order added
+	next     int
+	requests []string
+	changes  []string // IDs of changed files, in order; a page token indexes it
+}
+
+// file is a stored file with its content.
+type file struct {
+	drive.File
+	content []byte
+}
+
+// NewServer starts a Server with no files. Close it when done.
+func NewServer() *Server {
+	s := &Server{files: map[string]*file{}}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts s down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// HTTPClient returns an http.Client that sends every request to s,
+// whatever its host, so that code calling Google APIs reaches s instead.
+func (s *Server) HTTPClient() *http.Client {
+	u, _ := url.Parse(s.URL)
+	return &http.Client{Transport: rewrite{base: u, rt: s.srv.Client().Transport}}
+}
+
+// Client returns a drive.Client whose requests go to s.
+func (s *Server) Client() *drive.Client {
+	c := drive.NewClient("drivetest-token")
+	c.HTTPClient = s.HTTPClient()
+	return c
+}
+
+// rewrite sends requests to base instead of their own host.
+type rewrite struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewrite) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme, out.URL.Host = r.base.Scheme, r.base.Host
+	out.Host = ""
+	return r.rt.RoundTrip(out)
+}
+
+// Handle serves the requests that match pattern, as http.ServeMux matches
+// them, with h instead of the fake. It is meant for single routes; a test
+// that scripts every answer has no use for the fake.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mux == nil {
+		s.mux = http.NewServeMux()
+	}
+	s.mux.Handle(pattern, h)
+}
+
+// HandleFunc is Handle for a function.
+func (s *Server) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	s.Handle(pattern, http.HandlerFunc(h))
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Add stores f with content, replacing any file with its ID, and returns
+// it as stored. An empty ID is generated, and the MIME type, times, size,
+// checksum, link, and capabilities are filled in where unset. Content is
+// nil for folders; for Google-native files it is what export returns.
+func (s *Server) Add(f drive.File, content []byte) drive.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := &file{File: f}
+	return s.put(in.clone(), content).clone()
+}
+
+// AddFolder adds a folder called name in parent, or in "root" if parent is
+// empty, and returns its ID.
+func (s *Server) AddFolder(name, parent string) string {
+	f := drive.File{Name: name, MimeType: drive.FolderMimeType}
+	if parent != "" {
+		f.Parents = []string{parent}
+	}
+	return s.Add(f, nil).ID
+}
+
+// put is Add, with s.mu held.
+func (s *Server) put(f drive.File, content []byte) *file {
+	if f.ID == "" {
+		s.next++
+		f.ID = "new" + strconv.Itoa(s.next)
+	}
+	if f.MimeType == "" {
+		f.MimeType = "application/octet-stream"
+	}
+	if len(f.Parents) == 0 {
+		f.Parents = []string{"root"}
+	}
+	now := s.now()
+	if f.CreatedTime.IsZero() {
+		f.CreatedTime = now
+	}
+	if f.ModifiedTime.IsZero() {
+		f.ModifiedTime = f.CreatedTime
+	}
+	if f.Version == 0 {
+		f.Version = 1
+	}
+	if f.WebViewLink == "" {
+		f.WebViewLink = drive.ViewURL(f.ID)
+	}
+	if f.Capabilities == nil {
+		// The caller owns every file.
+		f.Capabilities = map[string]bool{
+			"canEdit": true, "canRename": true, "canTrash": true, "canDelete": true, "canShare": true,
+			"canDownload": true, "canCopy": !f.IsFolder(), "canAddChildren": f.IsFolder(),
+			"canMoveItemWithinDrive": true, "canReadRevisions": true,
+		}
+	}
+	stored := &file{File: f}
+	stored.setContent(content)
+	if _, ok := s.files[f.ID]; !ok {
+		s.order = append(s.order, f.ID)
+	}
+	s.files[f.ID] = stored
+	s.changes = append(s.changes, f.ID)
+	return stored
+}
+
+// setContent replaces f's content, if not nil, with its size and checksum;
+// Google-native files have neither.
+func (f *file) setContent(content []byte) {
+	if content == nil {
+		return
+	}
+	f.content = bytes.Clone(content)
+	if drive.IsNative(f.MimeType) {
+		return
+	}
+	sum := md5.Sum(content)
+	f.Size, f.MD5 = int64(len(content)), hex.EncodeToString(sum[:])
+}
+
+// clone returns a copy of f that shares nothing with it.
+func (f *file) clone() drive.File {
+	c := f.File
+	c.Parents = slices.Clone(f.Parents)
+	c.AppProperties = maps.Clone(f.AppProperties)
+	c.Properties = maps.Clone(f.Properties)
+	c.Capabilities = maps.Clone(f.Capabilities)
+	return c
+}
+
+// File returns the file with id, and whether there is one.
+func (s *Server) File(id string) (drive.File, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[id]
+	if !ok {
+		return drive.File{}, false
+	}
+	return f.clone(), true
+}
+
+// Content returns the content of the file with id, or nil if there is no
+// such file.
+func (s *Server) Content(id string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files[id]; ok {
+		return bytes.Clone(f.content)
+	}
+	return nil
+}
+
+// Files returns every file, trashed or not, in the order added.
+func (s *Server) Files() []drive.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]drive.File, 0, len(s.order))
+	for _, id := range s.order {
+		out = append(out, s.files[id].clone())
+	}
+	return out
+}
+
+// Children returns the untrashed files in folderID, in the order added.
+func (s *Server) Children(folderID string) []drive.File {
+	var out []drive.File
+	for _, f := range s.Files() {
+		if !f.Trashed && slices.Contains(f.Parents, folderID) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Requests returns the requests s has answered, as "METHOD /path", such as
+// "PATCH /drive/v3/files/new1", oldest first.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	mux := s.mux
+	s.mu.Unlock()
+	if mux != nil {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+	}
+	s.serve(w, r)
+}
+
+// serve answers r with the fake.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	path, upload := r.URL.Path, false
+	switch {
+	case strings.HasPrefix(path, "/upload/drive/v3/"):
+		path, upload = strings.TrimPrefix(path, "/upload/drive/v3/"), true
+	case strings.HasPrefix(path, "/drive/v3/"):
+		path = strings.TrimPrefix(path, "/drive/v3/")
+	default:
+		writeError(w, http.StatusNotImplemented, "notImplemented", "drivetest: no API at "+r.URL.Path)
+		return
+	}
+	seg := strings.Split(strings.Trim(path, "/"), "/")
+	for i := range seg {
+		seg[i], _ = url.PathUnescape(seg[i])
+	}
+
+	switch {
+	case seg[0] == "about" && r.Method == http.MethodGet:
+		email := s.Email
+		if email == "" {
+			email = DefaultEmail
+		}
+		writeJSON(w, map[string]any{"user": map[string]string{"displayName": "drivetest", "emailAddress": email}})
+	case seg[0] == "changes" && r.Method == http.MethodGet:
+		s.listChanges(w, r, seg)
+	case seg[0] != "files":
+		writeError(w, http.StatusNotImplemented, "notImplemented", "drivetest: no API at "+r.URL.Path)
+	case len(seg) == 1 && r.Method == http.MethodGet && !upload:
+		s.list(w, r)
+	case len(seg) == 1 && r.Method == http.MethodPost:
+		s.create(w, r, upload)
+	case len(seg) == 2 && seg[1] == "trash" && r.Method == http.MethodDelete:
+		for _, id := range slices.Clone(s.order) {
+			if f, ok := s.files[id]; ok && f.Trashed {
+				s.remove(id)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(seg) == 2 && s.files[seg[1]] == nil:
+		writeError(w, http.StatusNotFound, "notFound", "File not found: "+seg[1]+".")
+	case len(seg) == 2 && r.Method == http.MethodGet:
+		f := s.files[seg[1]]
+		if r.URL.Query().Get("alt") == "media" {
+			content := f.content
+			if from, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+				n, err := strconv.Atoi(strings.TrimSuffix(from, "-"))
+				if err != nil || n >= len(content) {
+					writeError(w, http.StatusRequestedRangeNotSatisfiable, "rangeNotSatisfiable", "Request range not satisfiable")
+					return
+				}
+				content = content[n:]
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", n, len(f.content)-1, len(f.content)))
+				w.Header().Set("Content-Type", f.MimeType)
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(content)
+				return
+			}
+			w.Header().Set("Content-Type", f.MimeType)
+			w.Write(content)
+			return
+		}
+		writeJSON(w, f.File)
+	case len(seg) == 2 && r.Method == http.MethodPatch:
+		s.update(w, r, s.files[seg[1]], upload)
+	case len(seg) == 2 && r.Method == http.MethodDelete:
+		s.remove(seg[1])
+		w.WriteHeader(http.StatusNoContent)
+	case len(seg) == 3 && seg[2] == "copy" && r.Method == http.MethodPost:
+		s.copy(w, r, seg[1])
+	case len(seg) == 3 && seg[2] == "export" && r.Method == http.MethodGet:
+		f := s.files[seg[1]]
+		if !drive.IsNative(f.MimeType) || f.IsFolder() {
+			writeError(w, http.StatusForbidden, "fileNotExportable", "Export only supports Docs Editors files.")
+			return
+		}
+		w.Header().Set("Content-Type", r.URL.Query().Get("mimeType"))
+		w.Write(f.content)
+	default:
+		writeError(w, http.StatusNotImplemented, "notImplemented", "drivetest: "+r.Method+" "+r.URL.Path+" is not supported")
+	}
+}
+
+// list answers files.list.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	m, err := compile(q.Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "Invalid Value: "+err.Error())
+		return
+	}
+	var out []*file
+	for _, id := range s.order {
+		if f := s.files[id]; m(f) {
+			out = append(out, f)
+		}
+	}
+	if err := sortFiles(out, q.Get("orderBy")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", "Invalid Value: "+err.Error())
+		return
+	}
+	size := 100
+	if n, err := strconv.Atoi(q.Get("pageSize")); err == nil && n > 0 {
+		size = min(n, 1000)
+	}
+	start := 0
+	if tok := q.Get("pageToken"); tok != "" {
+		if start, err = strconv.Atoi(tok); err != nil || start < 0 || start > len(out) {
+			writeError(w, http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+			return
+		}
+	}
+	page := map[string]any{"files": []drive.File{}}
+	files := []drive.File{}
+	for _, f := range out[start:min(start+size, len(out))] {
+		files = append(files, f.File)
+	}
+	page["files"] = files
+	if start+size < len(out) {
+		page["nextPageToken"] = strconv.Itoa(start + size)
+	}
+	writeJSON(w, page)
+}
+
+// sortFiles sorts files by the orderBy of a listing, keeping their order
+// where it does not tell them apart.
+func sortFiles(files []*file, orderBy string) error {
+	if orderBy == "" {
+		return nil
+	}
+	type key struct {
+		cmp  func(a, b *file) int
+		desc bool
+	}
+	var keys []key
+	for _, term := range strings.Split(orderBy, ",") {
+		name, dir, _ := strings.Cut(strings.TrimSpace(term), " ")
+		k := key{desc: strings.TrimSpace(dir) == "desc"}
+		switch name {
+		case "name", "name_natural":
+			k.cmp = func(a, b *file) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+		case "folder":
+			k.cmp = func(a, b *file) int { return boolCmp(b.IsFolder(), a.IsFolder()) }
+		case "createdTime":
+			k.cmp = func(a, b *file) int { return a.CreatedTime.Compare(b.CreatedTime) }
+		case "modifiedTime", "modifiedByMeTime", "recency":
+			k.cmp = func(a, b *file) int { return a.ModifiedTime.Compare(b.ModifiedTime) }
+		case "starred":
+			k.cmp = func(a, b *file) int { return boolCmp(b.Starred, a.Starred) }
+		case "quotaBytesUsed":
+			k.cmp = func(a, b *file) int { return int(a.Size - b.Size) }
+		default:
+			return fmt.Errorf("orderBy %q", name)
+		}
+		keys = append(keys, k)
+	}
+	slices.SortStableFunc(files, func(a, b *file) int {
+		for _, k := range keys {
+			if c := k.cmp(a, b); c != 0 {
+				if k.desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+func boolCmp(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// listChanges answers changes.getStartPageToken and changes.list: every
+// file added, changed, or deleted since the page token, each once, in the
+// order of its last change.
+func (s *Server) listChanges(w http.ResponseWriter, r *http.Request, seg []string) {
+	token := strconv.Itoa(len(s.changes))
+	switch {
+	case len(seg) == 2 && seg[1] == "startPageToken":
+		writeJSON(w, map[string]string{"startPageToken": token})
+		return
+	case len(seg) != 1:
+		writeError(w, http.StatusNotImplemented, "notImplemented", "drivetest: no API at "+r.URL.Path)
+		return
+	}
+	from, err := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	if err != nil || from < 0 || from > len(s.changes) {
+		writeError(w, http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+		return
+	}
+	var ids []string
+	for _, id := range slices.Backward(s.changes[from:]) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Reverse(ids)
+	changes := []map[string]any{}
+	for _, id := range ids {
+		ch := map[string]any{"changeType": "file", "fileId": id, "time": s.now()}
+		if f, ok := s.files[id]; ok {
+			ch["file"] = f.File
+		} else {
+			ch["removed"] = true
+		}
+		changes = append(changes, ch)
+	}
+	writeJSON(w, map[string]any{"newStartPageToken": token, "changes": changes})
+}
+
+// create answers files.create, with or without content.
+func (s *Server) create(w http.ResponseWriter, r *http.Request, upload bool) {
+	meta, content, err := readBody(r, upload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	f := &file{}
+	if id, ok := meta["id"].(string); ok && id != "" {
+		if s.files[id] != nil {
+			writeError(w, http.StatusConflict, "duplicate", "A file already exists with the provided ID.")
+			return
+		}
+		f.ID = id
+	}
+	apply(f, meta, nil)
+	if f.Name == "" {
+		f.Name = "Untitled"
+	}
+	if upload && content == nil {
+		content = []byte{}
+	}
+	writeJSON(w, s.put(f.File, content).File)
+}
+
+// update answers files.update, with or without content.
+func (s *Server) update(w http.ResponseWriter, r *http.Request, f *file, upload bool) {
+	meta, content, err := readBody(r, upload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	apply(f, meta, r.URL.Query())
+	if upload && content == nil {
+		content = []byte{}
+	}
+	f.setContent(content)
+	f.ModifiedTime = s.now()
+	f.Version++
+	s.changes = append(s.changes, f.ID)
+	writeJSON(w, f.File)
+}
+
+// copy answers files.copy.
+func (s *Server) copy(w http.ResponseWriter, r *http.Request, id string) {
+	src := s.files[id]
+	if src == nil {
+		writeError(w, http.StatusNotFound, "notFound", "File not found: "+id+".")
+		return
+	}
+	meta, _, err := readBody(r, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badContent", err.Error())
+		return
+	}
+	f := &file{File: src.clone()}
+	f.ID, f.CreatedTime, f.ModifiedTime, f.WebViewLink, f.Capabilities, f.Trashed, f.Version = "", time.Time{}, time.Time{}, "", nil, false, 0
+	apply(f, meta, nil)
+	writeJSON(w, s.put(f.File, src.content).File)
+}
+
+// remove deletes id and everything in it.
+func (s *Server) remove(id string) {
+	delete(s.files, id)
+	s.changes = append(s.changes, id)
+	s.order = slices.DeleteFunc(s.order, func(o string) bool { return o == id })
+	for _, child := range slices.Clone(s.order) {
+		if f, ok := s.files[child]; ok && slices.Contains(f.Parents, id) {
+			s.remove(child)
+		}
+	}
+}
+
+// readBody reads the metadata of a request, and its content if it is an
+// upload: multipart, with the metadata first, or the content alone.
+func readBody(r *http.Request, upload bool) (map[string]any, []byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !upload {
+		var meta map[string]any
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, &meta); err != nil {
+				return nil, nil, fmt.Errorf("metadata: %w", err)
+			}
+		}
+		return meta, nil, nil
+	}
+	mt, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mt, "multipart/") {
+		return nil, body, nil
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	var meta map[string]any
+	var content []byte
+	for i := 0; ; i++ {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := io.ReadAll(part)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			if err := json.Unmarshal(b, &meta); err != nil {
+				return nil, nil, fmt.Errorf("metadata: %w", err)
+			}
+			continue
+		}
+		content = b
+		// Without a MIME type in the metadata, the content's is used.
+		if ct, _, err := mime.ParseMediaType(part.Header.Get("Content-Type")); err == nil && meta != nil && meta["mimeType"] == nil {
+			meta["mimeType"] = ct
+		}
+	}
+	return meta, content, nil
+}
+
+// apply sets the metadata in meta, and the parents added and removed by
+// query, on f. Fields it does not know are ignored.
+func apply(f *file, meta map[string]any, query url.Values) {
+	for k, v := range meta {
+		switch k {
+		case "name", "description", "mimeType":
+			s, _ := v.(string)
+			switch k {
+			case "name":
+				f.Name = s
+			case "description":
+				f.Description = s
+			case "mimeType":
+				if s != "" {
+					f.MimeType = s
+				}
+			}
+		case "parents":
+			if ps, ok := v.([]any); ok && len(ps) > 0 {
+				f.Parents = nil
+				for _, p := range ps {
+					if p, ok := p.(string); ok {
+						f.Parents = append(f.Parents, p)
+					}
+				}
+			}
+		case "trashed":
+			f.Trashed, _ = v.(bool)
+		case "starred":
+			f.Starred, _ = v.(bool)
+		case "appProperties":
+			f.AppProperties = mergeProps(f.AppProperties, v)
+		case "properties":
+			f.Properties = mergeProps(f.Properties, v)
+		}
+	}
+	if query == nil {
+		return
+	}
+	if rm := query.Get("removeParents"); rm != "" {
+		drop := strings.Split(rm, ",")
+		f.Parents = slices.DeleteFunc(f.Parents, func(p string) bool { return slices.Contains(drop, p) })
+	}
+	if add := query.Get("addParents"); add != "" {
+		for _, p := range strings.Split(add, ",") {
+			if !slices.Contains(f.Parents, p) {
+				f.Parents = append(f.Parents, p)
+			}
+		}
+	}
+}
+
+// mergeProps sets the properties in v on props; a null value removes one.
+func mergeProps(props map[string]string, v any) map[string]string {
+	in, _ := v.(map[string]any)
+	if props == nil && len(in) > 0 {
+		props = map[string]string{}
+	}
+	for k, val := range in {
+		if s, ok := val.(string); ok {
+			props[k] = s
+		} else {
+			delete(props, k)
+		}
+	}
+	return props
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers as the Drive API does when a request fails.
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeJSON(w, map[string]any{"error": map[string]any{
+		"code": status, "message": message,
+		"errors": []map[string]string{{"domain": "global", "reason": reason, "message": message}},
+	}})
+}
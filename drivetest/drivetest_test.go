@@ -0,0 +1,215 @@
+package drivetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/changes"
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+func TestServerFiles(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	pub := srv.AddFolder("Published", "")
+
+	id, err := c.Upload(ctx, drive.Metadata{Name: "a.pdf", MimeType: "application/pdf", Parents: []string{pub}, Description: "v1"},
+		strings.NewReader("first"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "new2" {
+		t.Errorf("uploaded as %q, want new2", id)
+	}
+	f, err := c.GetFile(ctx, id)
+	if err != nil || f.Name != "a.pdf" || f.Size != 5 || f.Description != "v1" || f.Parents[0] != pub || !f.Capabilities["canTrash"] {
+		t.Fatalf("GetFile = %+v, %v", f, err)
+	}
+
+	if _, err := c.UpdateContent(ctx, id, strings.NewReader("second"), 6); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if _, err := c.Download(ctx, id, &got); err != nil || got.String() != "second" {
+		t.Fatalf("Download = %q, %v", got.String(), err)
+	}
+	got.Reset()
+	if _, err := c.DownloadFrom(ctx, id, 3, &got); err != nil || got.String() != "ond" {
+		t.Fatalf("DownloadFrom = %q, %v", got.String(), err)
+	}
+
+	if _, err := c.Rename(ctx, id, "b.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	arc := srv.AddFolder("Archive", "")
+	if _, err := c.Move(ctx, id, arc); err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := srv.File(id); f.Name != "b.pdf" || len(f.Parents) != 1 || f.Parents[0] != arc {
+		t.Fatalf("after rename and move: %+v", f)
+	}
+	cp, err := c.CopyFile(ctx, id, pub, "c.pdf")
+	if err != nil || string(srv.Content(cp.ID)) != "second" || srv.Children(pub)[0].Name != "c.pdf" {
+		t.Fatalf("Copy = %+v, %v", cp, err)
+	}
+
+	if err := c.Delete(ctx, arc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.File(id); ok {
+		t.Error("file in a deleted folder was kept")
+	}
+	if _, err := c.GetFile(ctx, id); !errors.Is(err, drive.ErrNotFound) {
+		t.Errorf("GetFile of a deleted file: %v", err)
+	}
+}
+
+func TestServerList(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	pub := srv.AddFolder("Published", "")
+	srv.Add(drive.File{ID: "b", Name: "Bob's.pdf", MimeType: "application/pdf", Parents: []string{pub}}, []byte("pdf"))
+	srv.Add(drive.File{ID: "a", Name: "a.pdf", MimeType: "application/pdf", Parents: []string{pub},
+		AppProperties: map[string]string{"version": "v2"}}, []byte("pdf"))
+	srv.Add(drive.File{ID: "t", Name: "old.pdf", MimeType: "application/pdf", Parents: []string{pub}, Trashed: true}, nil)
+	srv.Add(drive.File{ID: "n", Name: "notes.txt", MimeType: "text/plain", Parents: []string{"elsewhere"}}, []byte("reagent list"))
+
+	for _, tt := range []struct {
+		q, orderBy string
+		want       string
+	}{
+		{query.New().InParent(pub).NotTrashed().String(), "", "b a"},
+		{query.New().InParent(pub).String(), "name", "a b t"},
+		{query.New().InParent(pub).NameEquals("Bob's.pdf").String(), "", "b"},
+		{query.New().AppProperty("version", "v2").String(), "", "a"},
+		{query.New().FullText("REAGENT").String(), "", "n"},
+		{query.New().NotTrashed().Or(query.New().MimeType("text/plain"), query.New().NameContains("bob")).String(), "", "b n"},
+		{"not trashed = false and mimeType != 'application/pdf'", "", ""},
+	} {
+		files, err := list.ListFiles(ctx, c, list.Options{Query: tt.q, OrderBy: tt.orderBy, PageSize: 1})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.q, err)
+		}
+		var ids []string
+		for _, f := range files {
+			ids = append(ids, f.ID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.q, got, tt.want)
+		}
+	}
+
+	_, err := list.ListFiles(ctx, c, list.Options{Query: "shortcutDetails.targetId = 'x'"})
+	var apiErr *drive.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unsupported query: %v", err)
+	}
+}
+
+func TestServerChangesAndExport(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	ctx := context.Background()
+	srv.Add(drive.File{ID: "a", Name: "a.txt"}, []byte("a"))
+	doc := srv.Add(drive.File{ID: "doc", Name: "Notes", MimeType: "application/vnd.google-apps.document"}, []byte("exported"))
+	if doc.Size != 0 || doc.MD5 != "" {
+		t.Errorf("Google Doc stored with size and checksum: %+v", doc)
+	}
+	var got bytes.Buffer
+	if _, err := c.Export(ctx, "doc", "text/plain", &got); err != nil || got.String() != "exported" {
+		t.Fatalf("Export = %q, %v", got.String(), err)
+	}
+
+	token, err := changes.StartPageToken(ctx, c, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdateContent(ctx, "a", strings.NewReader("a2"), 2); err != nil {
+		t.Fatal(err)
+	}
+	srv.Add(drive.File{ID: "b", Name: "b.txt"}, []byte("b"))
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	chs, next, err := changes.Poll(ctx, c, token, changes.Options{IncludeRemoved: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, ch := range chs {
+		ids = append(ids, fmt.Sprintf("%s:%v", ch.FileID, ch.File != nil))
+	}
+	if got := strings.Join(ids, " "); got != "b:true a:false" {
+		t.Errorf("changes = %s", got)
+	}
+	if chs, _, err := changes.Poll(ctx, c, next, changes.Options{}); err != nil || len(chs) != 0 {
+		t.Errorf("changes since %s = %+v, %v", next, chs, err)
+	}
+}
+
+func TestServerHandle(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.HandleFunc("GET /drive/v3/files/{id}/permissions", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"permissions":[{"id":"p-` + r.PathValue("id") + `"}]}`))
+	})
+	c := srv.Client()
+	ctx := context.Background()
+	var out struct{ Permissions []struct{ ID string } }
+	if err := c.DoJSON(ctx, http.MethodGet, "files/x/permissions", nil, &out); err != nil || len(out.Permissions) != 1 || out.Permissions[0].ID != "p-x" {
+		t.Fatalf("permissions = %+v, %v", out, err)
+	}
+	// Everything else still reaches the fake.
+	if _, err := c.GetFile(ctx, "x"); !errors.Is(err, drive.ErrNotFound) {
+		t.Errorf("GetFile = %v, want not found", err)
+	}
+	if got := srv.Requests(); len(got) != 2 || got[0] != "GET /drive/v3/files/x/permissions" {
+		t.Errorf("requests = %q", got)
+	}
+}
+
+func TestServerDeploy(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	orig := http.DefaultClient
+	http.DefaultClient = srv.HTTPClient()
+	defer func() { http.DefaultClient = orig }()
+	pub, tmp, arc := srv.AddFolder("Published", ""), srv.AddFolder("Staging", ""), srv.AddFolder("Archive", "")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mydoc.pdf"), []byte("%PDF-1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, v := range []string{"v1", "v2"} {
+		r, err := deploy.DeployPDFResult(ctx, "token", "mydoc", v, tmp, pub, arc, dir)
+		if err != nil || r.Status != deploy.StatusDeployed {
+			t.Fatalf("deploy %s = %+v, %v", v, r, err)
+		}
+	}
+	live, archived := srv.Children(pub), srv.Children(arc)
+	if len(live) != 1 || live[0].Name != "mydoc.pdf" || live[0].Description != "v2" || string(srv.Content(live[0].ID)) != "%PDF-1" {
+		t.Errorf("live = %+v", live)
+	}
+	if len(archived) != 1 || archived[0].Description != "v1" || !strings.HasPrefix(archived[0].Name, "mydoc-") {
+		t.Errorf("archived = %+v", archived)
+	}
+	if len(srv.Children(tmp)) != 0 {
+		t.Errorf("left in the temp folder: %+v", srv.Children(tmp))
+	}
+}
@@ -0,0 +1,314 @@
+package drivetest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// match is a compiled search query, as in the q parameter of files.list.
+type match func(f *file) bool
+
+// token is a word of a query: a quoted string, with its quotes removed and
+// escapes resolved, or anything else as written.
+type token struct {
+	text   string
+	quoted bool
+}
+
+// lex splits q into tokens.
+func lex(q string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'':
+			var b strings.Builder
+			i++
+			for ; i < len(q) && q[i] != '\''; i++ {
+				if q[i] == '\\' && i+1 < len(q) {
+					i++
+				}
+				b.WriteByte(q[i])
+			}
+			if i == len(q) {
+				return nil, fmt.Errorf("unterminated string in %q", q)
+			}
+			i++
+			toks = append(toks, token{text: b.String(), quoted: true})
+		case strings.ContainsRune("(){}", rune(c)):
+			toks = append(toks, token{text: string(c)})
+			i++
+		case strings.ContainsRune("=!<>", rune(c)):
+			j := i + 1
+			if j < len(q) && q[j] == '=' {
+				j++
+			}
+			toks = append(toks, token{text: q[i:j]})
+			i = j
+		default:
+			j := i
+			for j < len(q) && (unicode.IsLetter(rune(q[j])) || unicode.IsDigit(rune(q[j])) || q[j] == '_' || q[j] == '.' || q[j] == '/') {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q in %q", c, q)
+			}
+			toks = append(toks, token{text: q[i:j]})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// parser compiles the tokens of a query. Terms are joined by and, which
+// binds tighter than or, and can be negated with not and grouped in
+// parentheses.
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// compile returns the match of q; an empty q matches every file.
+func compile(q string) (match, error) {
+	toks, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return func(*file) bool { return true }, nil
+	}
+	p := &parser{toks: toks}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q in %q", p.toks[p.pos].text, q)
+	}
+	return m, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.toks) {
+		return token{}, fmt.Errorf("query ends too soon")
+	}
+	p.pos++
+	return p.toks[p.pos-1], nil
+}
+
+// keyword reports whether the next token is the unquoted word w, taking
+// it if so.
+func (p *parser) keyword(w string) bool {
+	if t := p.peek(); !t.quoted && strings.EqualFold(t.text, w) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(w string) error {
+	if !p.keyword(w) {
+		return fmt.Errorf("expected %q, got %q", w, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) or() (match, error) {
+	m, err := p.and()
+	for err == nil && p.keyword("or") {
+		var r match
+		if r, err = p.and(); err == nil {
+			l := m
+			m = func(f *file) bool { return l(f) || r(f) }
+		}
+	}
+	return m, err
+}
+
+func (p *parser) and() (match, error) {
+	m, err := p.unary()
+	for err == nil && p.keyword("and") {
+		var r match
+		if r, err = p.unary(); err == nil {
+			l := m
+			m = func(f *file) bool { return l(f) && r(f) }
+		}
+	}
+	return m, err
+}
+
+func (p *parser) unary() (match, error) {
+	switch {
+	case p.keyword("not"):
+		m, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *file) bool { return !m(f) }, nil
+	case p.keyword("("):
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return m, p.expect(")")
+	}
+	return p.term()
+}
+
+// term compiles a single condition, as "'ID' in parents" or
+// "name = 'a.pdf'".
+func (p *parser) term() (match, error) {
+	left, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if left.quoted {
+		if err := p.expect("in"); err != nil {
+			return nil, err
+		}
+		coll, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch coll.text {
+		case "parents":
+			return func(f *file) bool { return slices.Contains(f.Parents, left.text) }, nil
+		case "owners", "writers", "readers":
+			// Every file is the caller's own.
+			me := left.text == "me"
+			return func(*file) bool { return me }, nil
+		}
+		return nil, fmt.Errorf("unsupported collection %q", coll.text)
+	}
+	field := left.text
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "has" {
+		return p.has(field)
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch field {
+	case "name", "mimeType", "description":
+		get := map[string]func(*file) string{
+			"name":        func(f *file) string { return f.Name },
+			"mimeType":    func(f *file) string { return f.MimeType },
+			"description": func(f *file) string { return f.Description },
+		}[field]
+		return compareString(get, op.text, value.text)
+	case "fullText":
+		if op.text != "contains" {
+			return nil, fmt.Errorf("unsupported operator %q for fullText", op.text)
+		}
+		want := strings.ToLower(value.text)
+		return func(f *file) bool {
+			return strings.Contains(strings.ToLower(f.Name+"\n"+f.Description+"\n"+string(f.content)), want)
+		}, nil
+	case "trashed", "starred":
+		want := value.text == "true"
+		if value.quoted || value.text != "true" && value.text != "false" || op.text != "=" && op.text != "!=" {
+			return nil, fmt.Errorf("invalid condition %s %s %s", field, op.text, value.text)
+		}
+		get := func(f *file) bool { return f.Trashed }
+		if field == "starred" {
+			get = func(f *file) bool { return f.Starred }
+		}
+		return func(f *file) bool { return (get(f) == want) == (op.text == "=") }, nil
+	case "modifiedTime", "createdTime":
+		t, err := time.Parse(time.RFC3339, value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q", value.text)
+		}
+		get := func(f *file) time.Time { return f.ModifiedTime }
+		if field == "createdTime" {
+			get = func(f *file) time.Time { return f.CreatedTime }
+		}
+		return compareTime(get, op.text, t)
+	}
+	return nil, fmt.Errorf("unsupported field %q", field)
+}
+
+// has compiles "appProperties has { key='k' and value='v' }", and the same
+// of properties.
+func (p *parser) has(field string) (match, error) {
+	if field != "appProperties" && field != "properties" {
+		return nil, fmt.Errorf("unsupported field %q", field)
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var kv [2]string
+	for i, name := range []string{"key", "value"} {
+		if i > 0 {
+			if err := p.expect("and"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect(name); err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		kv[i] = t.text
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return func(f *file) bool {
+		props := f.AppProperties
+		if field == "properties" {
+			props = f.Properties
+		}
+		v, ok := props[kv[0]]
+		return ok && v == kv[1]
+	}, nil
+}
+
+func compareString(get func(*file) string, op, want string) (match, error) {
+	switch op {
+	case "=":
+		return func(f *file) bool { return get(f) == want }, nil
+	case "!=":
+		return func(f *file) bool { return get(f) != want }, nil
+	case "contains":
+		want = strings.ToLower(want)
+		return func(f *file) bool { return strings.Contains(strings.ToLower(get(f)), want) }, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+func compareTime(get func(*file) time.Time, op string, t time.Time) (match, error) {
+	cmp := map[string]func(a time.Time) bool{
+		"=":  func(a time.Time) bool { return a.Equal(t) },
+		"!=": func(a time.Time) bool { return !a.Equal(t) },
+		"<":  func(a time.Time) bool { return a.Before(t) },
+		"<=": func(a time.Time) bool { return !a.After(t) },
+		">":  func(a time.Time) bool { return a.After(t) },
+		">=": func(a time.Time) bool { return !a.Before(t) },
+	}[op]
+	if cmp == nil {
+		return nil, fmt.Errorf("unsupported operator %q", op)
+	}
+	return func(f *file) bool { return cmp(get(f)) }, nil
+}
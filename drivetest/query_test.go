@@ -0,0 +1,40 @@
+package drivetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestCompile(t *testing.T) {
+	f := &file{File: drive.File{Name: "Bob's SOP.pdf", MimeType: "application/pdf", Parents: []string{"p"},
+		ModifiedTime: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), Properties: map[string]string{"k": "v"}}}
+	for q, want := range map[string]bool{
+		``:                                   true,
+		`name = 'Bob\'s SOP.pdf'`:            true,
+		`name contains 'sop'`:                true,
+		`'p' in parents and trashed = false`: true,
+		`'q' in parents or starred = true`:   false,
+		`'q' in parents and 'p' in parents or 'p' in parents`: true,
+		`not ('q' in parents or 'p' in parents)`:              false,
+		`modifiedTime > '2024-05-01T00:00:00Z'`:               true,
+		`modifiedTime <= '2024-05-01T00:00:00Z'`:              false,
+		`properties has { key='k' and value='v' }`:            true,
+		`'me' in owners`: true,
+	} {
+		m, err := compile(q)
+		if err != nil {
+			t.Errorf("%s: %v", q, err)
+			continue
+		}
+		if got := m(f); got != want {
+			t.Errorf("%s = %v, want %v", q, got, want)
+		}
+	}
+	for _, q := range []string{`name = 'open`, `name ~ 'x'`, `(trashed = false`, `trashed = 'no'`, `'x' in labels`, `size > 10`} {
+		if _, err := compile(q); err == nil {
+			t.Errorf("%s: no error", q)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package drivetest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Redacted replaces secrets in cassettes.
+const Redacted = "REDACTED"
+
+// Mode is whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ReplayOrRecord replays the cassette if it exists and records it
+	// otherwise, so deleting a cassette and running its test again records
+	// it afresh.
+	ReplayOrRecord Mode = iota
+	// Replay answers from the cassette, failing requests it has no
+	// answer for.
+	Replay
+	// Record sends requests on and writes them to the cassette, replacing
+	// what it held.
+	Record
+)
+
+// Cassette is what a Recorder records: the requests made and their
+// responses, in order.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as kept in a cassette.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitzero"`
+}
+
+// RecordedResponse is a response as kept in a cassette.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitzero"`
+}
+
+// Body is the content of a request or response. It is kept as text, so
+// that cassettes can be read and diffed, unless it is not UTF-8, as
+// downloads of binary files, which are kept in base64.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var enc struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return err
+	}
+	dec, err := base64.StdEncoding.DecodeString(enc.Base64)
+	*b = dec
+	return err
+}
+
+// secretHeaders are dropped from cassettes.
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Goog-Api-Key", "Proxy-Authorization"}
+
+// secretFields are redacted from JSON bodies, and with secretParams from
+// query strings and forms.
+var (
+	secretFields = map[string]bool{
+		"access_token": true, "refresh_token": true, "id_token": true, "client_secret": true,
+		"assertion": true, "private_key": true, "private_key_id": true, "subject_token": true,
+	}
+	secretParams = map[string]bool{"key": true, "code": true, "code_verifier": true}
+)
+
+// Recorder is a RoundTripper that records the requests sent through it,
+// and their responses, to a cassette file, or replays them from one, so
+// that tests can run against real API responses without credentials or
+// network access.
+//
+// Cassettes hold no secrets: credentials are dropped from headers, and
+// tokens, keys, and client secrets are redacted from query strings, forms,
+// and JSON bodies, as those of an OAuth token exchange. Redact can remove
+// anything else, such as email addresses.
+//
+// In replay, each request gets the response of the first interaction not
+// yet replayed with the same method and URL, so a test may make the same
+// call several times, and multipart bodies, whose boundaries are random,
+// need not match.
+type Recorder struct {
+	// Base sends the requests being recorded; nil means
+	// http.DefaultTransport.
+	Base http.RoundTripper
+	// Redact, if set, edits each interaction before it is recorded. It
+	// should leave the method and URL of the request alone, as replay
+	// matches on them.
+	Redact func(*Interaction)
+
+	path     string
+	mode     Mode
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. In Replay the
+// cassette must exist; in Record it is written by Save.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode}
+	if mode == Record {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && mode == ReplayOrRecord {
+		r.mode = Record
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	r.mode = Replay
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Recording reports whether r records, rather than replays.
+func (r *Recorder) Recording() bool {
+	return r.mode == Record
+}
+
+// HTTPClient returns an http.Client whose requests go through r.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Client returns a drive.Client with token whose requests go through r.
+// In replay the token is never sent, so any will do.
+func (r *Recorder) Client(token string) *drive.Client {
+	c := drive.NewClient(token)
+	c.HTTPClient = r.HTTPClient()
+	return c
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	rec := RecordedRequest{Method: req.Method, URL: redactURL(req.URL), Header: redactHeader(req.Header),
+		Body: redactBody(req.Header.Get("Content-Type"), body)}
+	if r.mode != Record {
+		return r.replay(req, rec)
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	in := Interaction{Request: rec, Response: RecordedResponse{Status: resp.StatusCode, Header: redactHeader(resp.Header),
+		Body: redactBody(resp.Header.Get("Content-Type"), respBody)}}
+	if r.Redact != nil {
+		r.Redact(&in)
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// replay returns the response to rec from the cassette.
+func (r *Recorder) replay(req *http.Request, rec RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.replayed[i] || in.Request.Method != rec.Method || in.Request.URL != rec.URL {
+			continue
+		}
+		r.replayed[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no response to %s %s", r.path, rec.Method, rec.URL)
+}
+
+// Unplayed returns the interactions of the cassette not replayed yet, for
+// a test to check that it made every call it used to.
+func (r *Recorder) Unplayed() []Interaction {
+	if r.mode == Record {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Interaction
+	for i, in := range r.cassette.Interactions {
+		if !r.replayed[i] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// Save writes the cassette when recording, creating its directory if
+// needed; in replay it does nothing.
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func readAll(rc io.ReadCloser) ([]byte, error) {
+	if rc == nil || rc == http.NoBody {
+		return nil, nil
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range secretHeaders {
+		h.Del(k)
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	if c.RawQuery != "" {
+		c.RawQuery = redactValues(c.Query()).Encode()
+	}
+	return c.String()
+}
+
+func redactValues(v url.Values) url.Values {
+	for k := range v {
+		if secretFields[k] || secretParams[k] {
+			v[k] = []string{Redacted}
+		}
+	}
+	return v
+}
+
+// redactBody redacts the secrets of a form or JSON body; others are kept
+// as they are.
+func redactBody(contentType string, body []byte) Body {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		if v, err := url.ParseQuery(string(body)); err == nil {
+			return Body(redactValues(v).Encode())
+		}
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		var v any
+		if err := json.Unmarshal(body, &v); err == nil && redactJSON(v) {
+			if out, err := json.Marshal(v); err == nil {
+				return out
+			}
+		}
+	}
+	return body
+}
+
+// redactJSON redacts the secret fields in v, reporting whether there were
+// any.
+func redactJSON(v any) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, x := range v {
+			if _, ok := x.(string); ok && secretFields[k] {
+				v[k] = Redacted
+				found = true
+			} else if redactJSON(x) {
+				found = true
+			}
+		}
+	case []any:
+		for _, x := range v {
+			if redactJSON(x) {
+				found = true
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,104 @@
+package drivetest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "upload.json")
+	ctx := context.Background()
+
+	// Record against a fake, standing in for the real API.
+	srv := NewServer()
+	pub := srv.AddFolder("Published", "")
+	rec, err := NewRecorder(path, ReplayOrRecord)
+	if err != nil || !rec.Recording() {
+		t.Fatalf("NewRecorder = %v, recording %v", err, rec.Recording())
+	}
+	rec.Base = srv.HTTPClient().Transport
+	rec.Redact = func(in *Interaction) {
+		in.Response.Body = bytes.ReplaceAll(in.Response.Body, []byte(DefaultEmail), []byte("someone@example.com"))
+	}
+	c := rec.Client("ya29.secret-token")
+	kc := drive.NewAPIKeyClient("AIza-secret-key")
+	kc.HTTPClient = rec.HTTPClient()
+	id, err := c.Upload(ctx, drive.Metadata{Name: "a.pdf", Parents: []string{pub}}, strings.NewReader("\x00\xffpdf"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetFile(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := kc.DoJSON(ctx, "GET", "about?fields=user", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if _, err := c.Download(ctx, id, &got); err != nil {
+		t.Fatal(err)
+	}
+	tok, _ := http.NewRequest("POST", "https://oauth2.googleapis.com/token", strings.NewReader(url.Values{
+		"client_secret": {"shh"}, "refresh_token": {"1//secret-refresh"}}.Encode()))
+	tok.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if resp, err := rec.RoundTrip(tok); err != nil {
+		t.Fatal(err)
+	} else {
+		resp.Body.Close()
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"ya29.secret-token", "AIza-secret-key", "shh", "1//secret-refresh", DefaultEmail} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("cassette holds %q", secret)
+		}
+	}
+
+	// Replay with the server gone and another token.
+	rec, err = NewRecorder(path, ReplayOrRecord)
+	if err != nil || rec.Recording() {
+		t.Fatalf("NewRecorder = %v, recording %v", err, rec.Recording())
+	}
+	c = rec.Client("other")
+	kc = drive.NewAPIKeyClient("other")
+	kc.HTTPClient = rec.HTTPClient()
+	if _, err := c.Upload(ctx, drive.Metadata{Name: "a.pdf", Parents: []string{pub}}, strings.NewReader("\x00\xffpdf"), 5); err != nil {
+		t.Fatal(err)
+	}
+	f, err := c.GetFile(ctx, id)
+	if err != nil || f.Name != "a.pdf" || f.Parents[0] != pub {
+		t.Fatalf("replayed GetFile = %+v, %v", f, err)
+	}
+	var about struct{ User struct{ EmailAddress string } }
+	if err := kc.DoJSON(ctx, "GET", "about?fields=user", nil, &about); err != nil || about.User.EmailAddress != "someone@example.com" {
+		t.Fatalf("replayed about = %+v, %v", about, err)
+	}
+	got.Reset()
+	if _, err := c.Download(ctx, id, &got); err != nil || got.String() != "\x00\xffpdf" {
+		t.Fatalf("replayed Download = %q, %v", got.String(), err)
+	}
+	if n := len(rec.Unplayed()); n != 1 {
+		t.Errorf("%d unplayed, want the token exchange", n)
+	}
+	if _, err := c.GetFile(ctx, id); err == nil || !strings.Contains(err.Error(), "no response") {
+		t.Errorf("unrecorded call: %v", err)
+	}
+
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), Replay); err == nil {
+		t.Error("Replay of a missing cassette succeeded")
+	}
+}
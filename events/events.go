@@ -0,0 +1,112 @@
+// Package events defines the lifecycle events that deploys, rollbacks, and
+// directory syncs publish as they go, so that dashboards and notifications
+// can follow them without wrapping every call. Implement Events, embedding
+// Nop to skip the events not needed, and set deploy.Events or
+// dirsync.Events to it. Failures alone can be sent to an error tracker
+// through an ErrorReporter.
+package events
+
+import (
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Sources of events.
+const (
+	SourceDeploy   = "deploy"
+	SourceRollback = "rollback"
+	SourceSync     = "sync"
+)
+
+// Events receives lifecycle events. Its methods are called synchronously
+// by the deploy or sync being followed, so they should return quickly.
+type Events interface {
+	DeployStarted(DeployStarted)
+	FileArchived(FileArchived)
+	UploadProgress(UploadProgress)
+	DeployFinished(DeployFinished)
+	Error(Error)
+}
+
+// DeployStarted is published before a deploy, rollback, or sync makes any
+// change.
+type DeployStarted struct {
+	Source string
+	// Name is the document deployed, with .pdf, or the local directory
+	// synced.
+	Name    string
+	Version string // the version deployed or rolled back to, if any
+	// CorrelationID is sent with each request of the deploy, rollback, or
+	// sync, and noted on its log entries.
+	CorrelationID string
+	Time          time.Time
+}
+
+// FileArchived is published when a file is set aside rather than removed:
+// the version a deploy or rollback replaces, moved to the archive folder,
+// or a file a sync trashed.
+type FileArchived struct {
+	Source string
+	Name   string // as in DeployStarted
+	FileID string
+	// ArchivedAs is the file's new name, or its path in the synced
+	// directory.
+	ArchivedAs string
+	// Version is the archived file's version, if known.
+	Version string
+	// FolderID is the archive folder, or empty if the file was trashed.
+	FolderID string
+	Time     time.Time
+}
+
+// UploadProgress reports a content upload of a deploy, rollback, or sync.
+type UploadProgress struct {
+	Source string
+	Name   string // as in DeployStarted
+	// File is the file being uploaded: the PDF, or a path in the synced
+	// directory.
+	File     string
+	Progress drive.Progress
+}
+
+// DeployFinished is published once a deploy, rollback, or sync is over,
+// whether it succeeded or not.
+type DeployFinished struct {
+	Source  string
+	Name    string // as in DeployStarted
+	Version string
+	// FileID is the live file of a deploy or rollback that succeeded.
+	FileID string
+	// Status is one of the deploy package's statuses for a deploy or
+	// rollback, and "synced" or "failed" for a sync.
+	Status        string
+	Stats         drive.Stats
+	Err           error
+	CorrelationID string // as in DeployStarted
+	Time          time.Time
+}
+
+// Error is published for each failure: the one that ended a deploy or
+// rollback, and each action of a sync that could not be applied.
+type Error struct {
+	Source string
+	Name   string // as in DeployStarted
+	// Op is what failed, such as "deploy", "deploy upload", or a sync
+	// action like "push update docs/a.pdf".
+	Op            string
+	Err           error
+	CorrelationID string // as in DeployStarted
+	Time          time.Time
+}
+
+// Nop ignores every event. Embed it to implement only some of Events.
+type Nop struct{}
+
+var _ Events = Nop{}
+
+func (Nop) DeployStarted(DeployStarted)   {}
+func (Nop) FileArchived(FileArchived)     {}
+func (Nop) UploadProgress(UploadProgress) {}
+func (Nop) DeployFinished(DeployFinished) {}
+func (Nop) Error(Error)                   {}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// ErrorReporter receives each failure of a deploy, rollback, or sync with
+// what is known about it, to send on to an error tracker such as Sentry
+// or to alerting of one's own. Set deploy.ErrorReporter or
+// dirsync.ErrorReporter to it. ReportError is called synchronously, so it
+// should return quickly.
+type ErrorReporter interface {
+	ReportError(ErrorReport)
+}
+
+// ErrorReport is a failure and its context.
+type ErrorReport struct {
+	Source string
+	// Op is what failed, such as "deploy upload", "rollback", or a sync
+	// action like "push update docs/a.pdf".
+	Op string
+	// File is the document, with .pdf, or the path in the synced
+	// directory.
+	File string
+	// Status and Reason are those of the Drive API response that failed,
+	// if any.
+	Status int
+	Reason string
+	// Attempt is how many times the failed request was sent, or 0 if the
+	// failure was not a response of the Drive API.
+	Attempt       int
+	CorrelationID string
+	Err           error
+	Time          time.Time
+}
+
+// NewErrorReport returns the report of err, taking Status, Reason, and
+// Attempt from the *drive.APIError in its chain, if any.
+func NewErrorReport(source, op, file, correlationID string, err error) ErrorReport {
+	r := ErrorReport{Source: source, Op: op, File: file, CorrelationID: correlationID, Err: err, Time: time.Now()}
+	var apiErr *drive.APIError
+	if errors.As(err, &apiErr) {
+		r.Status, r.Reason, r.Attempt = apiErr.StatusCode, apiErr.Reason, apiErr.Attempts
+	}
+	return r
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestNewErrorReport(t *testing.T) {
+	err := fmt.Errorf("upload failed: %w", &drive.APIError{StatusCode: 503, Reason: "backendError", Attempts: 4})
+	r := NewErrorReport(SourceSync, "push add a.pdf", "a.pdf", "abc", err)
+	if r.Status != 503 || r.Reason != "backendError" || r.Attempt != 4 || r.Err != err || r.CorrelationID != "abc" || r.Time.IsZero() {
+		t.Errorf("report = %+v", r)
+	}
+	r = NewErrorReport(SourceDeploy, "deploy", "a.pdf", "", errors.New("PDF not found"))
+	if r.Status != 0 || r.Attempt != 0 || r.Op != "deploy" || r.File != "a.pdf" {
+		t.Errorf("report without an API error = %+v", r)
+	}
+}
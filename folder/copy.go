@@ -0,0 +1,191 @@
+package folder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// DefaultCopyConcurrency is how many files CopyFolder copies at once when
+// CopyOptions.Concurrency is 0.
+const DefaultCopyConcurrency = 4
+
+// CopyOptions tunes CopyFolder.
+type CopyOptions struct {
+	// Name is the name of the new top-level folder; empty keeps the source's.
+	Name string
+	// Concurrency is how many files are copied in parallel.
+	Concurrency int
+	// OnItem, if set, is called after every folder created and every file
+	// copied, skipped, or failed. Calls are serialized.
+	OnItem func(CopyItem)
+}
+
+// CopyItem records what happened to one item of the source tree.
+type CopyItem struct {
+	// Path is relative to the source folder.
+	Path     string
+	SourceID string
+	// CopyID is the ID of the new file or folder; empty if skipped or failed.
+	CopyID  string
+	Folder  bool
+	Skipped bool
+	Err     error
+}
+
+// CopyReport summarizes a CopyFolder run.
+type CopyReport struct {
+	// RootID is the ID of the new top-level folder.
+	RootID  string
+	Folders int
+	Files   int
+	Skipped int
+	Failed  int
+	Items   []CopyItem
+}
+
+// CopyFolder duplicates the tree below srcFolderID into a new folder inside
+// destParentID. Drive has no recursive copy, so folders are recreated and
+// each file is copied with files.copy. Shortcuts are skipped. A failure to
+// copy a file does not stop the run; it is recorded in the report and
+// CopyFolder returns an error once everything else is done. Listing or
+// folder creation failures abort immediately.
+func CopyFolder(ctx context.Context, c *drive.Client, srcFolderID, destParentID string, opts CopyOptions) (*CopyReport, error) {
+	srcFolderID, err := drive.ParseID(srcFolderID)
+	if err != nil {
+		return nil, err
+	}
+	name := opts.Name
+	if name == "" {
+		src, err := c.GetFile(ctx, srcFolderID, "id", "name", "mimeType")
+		if err != nil {
+			return nil, fmt.Errorf("read source folder: %w", err)
+		}
+		if !src.IsFolder() {
+			return nil, fmt.Errorf("%s is not a folder", srcFolderID)
+		}
+		name = src.Name
+	}
+	root, err := c.CreateFolder(ctx, name, destParentID)
+	if err != nil {
+		return nil, fmt.Errorf("create destination folder: %w", err)
+	}
+
+	cp := &copier{
+		ctx:     ctx,
+		c:       c,
+		opts:    opts,
+		report:  &CopyReport{RootID: root.ID},
+		jobs:    make(chan copyJob),
+		visited: map[string]bool{srcFolderID: true, root.ID: true},
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultCopyConcurrency
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cp.work()
+		}()
+	}
+	err = cp.copyDir(srcFolderID, root.ID, "")
+	close(cp.jobs)
+	wg.Wait()
+	if err != nil {
+		return cp.report, err
+	}
+	if cp.report.Failed > 0 {
+		return cp.report, fmt.Errorf("%d of %d files failed to copy", cp.report.Failed, cp.report.Failed+cp.report.Files)
+	}
+	return cp.report, nil
+}
+
+type copier struct {
+	ctx    context.Context
+	c      *drive.Client
+	opts   CopyOptions
+	jobs   chan copyJob
+	mu     sync.Mutex
+	report *CopyReport
+	// visited holds source folders already copied and every folder created
+	// by this run, so copying a folder into itself cannot recurse forever.
+	// Only copyDir touches it, so it needs no locking.
+	visited map[string]bool
+}
+
+type copyJob struct {
+	item CopyItem
+	name string
+	dest string
+}
+
+func (cp *copier) copyDir(srcID, dstID, prefix string) error {
+	children, err := list.Children(cp.ctx, cp.c, srcID)
+	if err != nil {
+		return fmt.Errorf("list %q: %w", prefix, err)
+	}
+	for _, f := range children {
+		item := CopyItem{Path: prefix + f.Name, SourceID: f.ID}
+		switch {
+		case f.IsFolder():
+			if cp.visited[f.ID] {
+				continue
+			}
+			cp.visited[f.ID] = true
+			created, err := cp.c.CreateFolder(cp.ctx, f.Name, dstID)
+			if err != nil {
+				return fmt.Errorf("create %q: %w", item.Path, err)
+			}
+			item.Folder, item.CopyID = true, created.ID
+			cp.visited[created.ID] = true
+			cp.record(item)
+			if err := cp.copyDir(f.ID, created.ID, item.Path+"/"); err != nil {
+				return err
+			}
+		case f.IsShortcut():
+			item.Skipped = true
+			cp.record(item)
+		default:
+			select {
+			case cp.jobs <- copyJob{item: item, name: f.Name, dest: dstID}:
+			case <-cp.ctx.Done():
+				return cp.ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+func (cp *copier) work() {
+	for job := range cp.jobs {
+		copied, err := cp.c.CopyFile(cp.ctx, job.item.SourceID, job.dest, job.name)
+		job.item.CopyID, job.item.Err = copied.ID, err
+		cp.record(job.item)
+	}
+}
+
+func (cp *copier) record(item CopyItem) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	r := cp.report
+	switch {
+	case item.Err != nil:
+		r.Failed++
+	case item.Skipped:
+		r.Skipped++
+	case item.Folder:
+		r.Folders++
+	default:
+		r.Files++
+	}
+	r.Items = append(r.Items, item)
+	if cp.opts.OnItem != nil {
+		cp.opts.OnItem(item)
+	}
+}
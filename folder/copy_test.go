@@ -0,0 +1,77 @@
+package folder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestCopyFolder(t *testing.T) {
+	var mu sync.Mutex
+	var copies []string
+	folders := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/src":
+			w.Write([]byte(`{"id":"src","name":"Release","mimeType":"application/vnd.google-apps.folder"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			var meta drive.Metadata
+			json.NewDecoder(r.Body).Decode(&meta)
+			mu.Lock()
+			folders++
+			id := "new-" + meta.Name
+			mu.Unlock()
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/copy"):
+			var meta drive.Metadata
+			json.NewDecoder(r.Body).Decode(&meta)
+			src := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/drive/v3/files/"), "/copy")
+			if src == "bad" {
+				http.Error(w, "nope", http.StatusForbidden)
+				return
+			}
+			mu.Lock()
+			copies = append(copies, meta.Parents[0]+"/"+meta.Name)
+			mu.Unlock()
+			w.Write([]byte(`{"id":"copy-` + src + `"}`))
+		case strings.HasPrefix(q, "'src' in parents"):
+			w.Write([]byte(`{"files":[
+				{"id":"a","name":"a.pdf","mimeType":"application/pdf"},
+				{"id":"sub","name":"Sub","mimeType":"application/vnd.google-apps.folder"},
+				{"id":"sc","name":"link","mimeType":"application/vnd.google-apps.shortcut"},
+				{"id":"bad","name":"locked.pdf","mimeType":"application/pdf"}]}`))
+		case strings.HasPrefix(q, "'sub' in parents"):
+			w.Write([]byte(`{"files":[{"id":"b","name":"b.pdf","mimeType":"application/pdf"}]}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	var seen int
+	report, err := CopyFolder(context.Background(), testClient(srv), "src", "dest", CopyOptions{
+		Concurrency: 2,
+		OnItem:      func(CopyItem) { seen++ },
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") {
+		t.Fatalf("expected one failure, got %v", err)
+	}
+	if report.RootID != "new-Release" || report.Folders != 1 || report.Files != 2 || report.Skipped != 1 || report.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if seen != len(report.Items) || seen != 5 {
+		t.Fatalf("OnItem called %d times for %d items", seen, len(report.Items))
+	}
+	sort.Strings(copies)
+	if strings.Join(copies, ",") != "new-Release/a.pdf,new-Sub/b.pdf" || folders != 2 {
+		t.Fatalf("copies %v, folders %d", copies, folders)
+	}
+}
@@ -0,0 +1,113 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+var (
+	// ErrTooManyItems is returned when a delete would affect more items
+	// than DeleteOptions.MaxItems allows.
+	ErrTooManyItems = errors.New("folder: delete affects too many items")
+	// ErrAborted is returned when DeleteOptions.Confirm declines.
+	ErrAborted = errors.New("folder: delete aborted")
+)
+
+// DeleteOptions tunes DeleteFolderRecursive.
+type DeleteOptions struct {
+	// Permanent deletes the tree outright instead of moving it to the trash.
+	Permanent bool
+	// MaxItems, if positive, refuses to delete a tree with more items,
+	// counting the folder itself.
+	MaxItems int
+	// Confirm, if set, is shown every affected item before anything is
+	// removed; returning false aborts with ErrAborted.
+	Confirm func(items []DeletedItem) bool
+	// DryRun counts and reports without removing anything.
+	DryRun bool
+}
+
+// DeletedItem is one file or folder removed (or, on a dry run, that would
+// be removed) by DeleteFolderRecursive.
+type DeletedItem struct {
+	// Path is relative to the parent of the deleted folder.
+	Path   string
+	ID     string
+	Folder bool
+}
+
+// DeleteReport lists what DeleteFolderRecursive removed.
+type DeleteReport struct {
+	Items     []DeletedItem
+	Permanent bool
+	DryRun    bool
+}
+
+// DeleteFolderRecursive removes folderID and everything below it. The tree
+// is walked first so the caller can cap or confirm the number of affected
+// items; then the folder itself is trashed (or deleted), which Drive applies
+// to its whole subtree. Shortcuts are counted, but their targets are not
+// touched.
+func DeleteFolderRecursive(ctx context.Context, c *drive.Client, folderID string, opts DeleteOptions) (*DeleteReport, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folderID == "root" {
+		return nil, errors.New("refusing to delete the root of My Drive")
+	}
+	root, err := c.GetFile(ctx, folderID, "id", "name", "mimeType", "capabilities(canTrash,canDelete)")
+	if err != nil {
+		return nil, fmt.Errorf("read folder: %w", err)
+	}
+	if !root.IsFolder() {
+		return nil, fmt.Errorf("%s is not a folder", folderID)
+	}
+	need := drive.CanTrash
+	if opts.Permanent {
+		need = drive.CanDelete
+	}
+	if !root.Capabilities[string(need)] {
+		return nil, &drive.PermissionError{FileID: root.ID, Name: root.Name, Capability: need}
+	}
+
+	report := &DeleteReport{Permanent: opts.Permanent, DryRun: opts.DryRun}
+	report.Items = append(report.Items, DeletedItem{Path: root.Name, ID: root.ID, Folder: true})
+	err = list.Walk(ctx, c, folderID, func(p string, f drive.File) error {
+		report.Items = append(report.Items, DeletedItem{Path: root.Name + "/" + p, ID: f.ID, Folder: f.IsFolder()})
+		if opts.MaxItems > 0 && len(report.Items) > opts.MaxItems {
+			return ErrTooManyItems
+		}
+		// Deleting a shortcut leaves its target alone, so the target's
+		// contents are not counted.
+		if f.IsShortcut() {
+			return list.SkipDir
+		}
+		return nil
+	})
+	if errors.Is(err, ErrTooManyItems) {
+		return report, fmt.Errorf("%w: more than %d", ErrTooManyItems, opts.MaxItems)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("count items: %w", err)
+	}
+	if opts.Confirm != nil && !opts.Confirm(report.Items) {
+		return report, ErrAborted
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+	if opts.Permanent {
+		err = c.Delete(ctx, folderID)
+	} else {
+		_, err = c.TrashFile(ctx, folderID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
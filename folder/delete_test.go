@@ -0,0 +1,129 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+)
+
+func deleteServer(t *testing.T, removed *[]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/top":
+			w.Write([]byte(`{"id":"top","name":"Old","mimeType":"application/vnd.google-apps.folder","capabilities":{"canTrash":true,"canDelete":true}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/shared":
+			w.Write([]byte(`{"id":"shared","name":"Shared","mimeType":"application/vnd.google-apps.folder","capabilities":{"canTrash":true}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/doc":
+			w.Write([]byte(`{"id":"doc","name":"x.pdf","mimeType":"application/pdf"}`))
+		case strings.HasPrefix(q, "'top' in parents"):
+			w.Write([]byte(`{"files":[
+				{"id":"sub","name":"Sub","mimeType":"application/vnd.google-apps.folder"},
+				{"id":"sc","name":"link","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"elsewhere","targetMimeType":"application/vnd.google-apps.folder"}}]}`))
+		case strings.HasPrefix(q, "'sub' in parents"):
+			w.Write([]byte(`{"files":[{"id":"a","name":"a.pdf","mimeType":"application/pdf"}]}`))
+		case strings.HasPrefix(q, "'elsewhere' in parents"):
+			t.Errorf("shortcut target must not be walked")
+			w.Write([]byte(`{"files":[]}`))
+		case r.Method == http.MethodPatch || r.Method == http.MethodDelete:
+			*removed = append(*removed, r.Method+" "+r.URL.Path)
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`{"id":"top","trashed":true}`))
+		default:
+			http.Error(w, "unexpected "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDeleteFolderRecursive(t *testing.T) {
+	var removed []string
+	c := testClient(deleteServer(t, &removed))
+	ctx := context.Background()
+
+	var confirmed int
+	report, err := DeleteFolderRecursive(ctx, c, "top", DeleteOptions{
+		Confirm: func(items []DeletedItem) bool { confirmed = len(items); return true },
+	})
+	if err != nil {
+		t.Fatalf("DeleteFolderRecursive: %v", err)
+	}
+	if confirmed != 4 || len(report.Items) != 4 || report.Items[2].Path != "Old/Sub/a.pdf" {
+		t.Fatalf("unexpected items (confirmed %d): %+v", confirmed, report.Items)
+	}
+	if strings.Join(removed, ",") != "PATCH /drive/v3/files/top" {
+		t.Fatalf("removed = %v; want only the top folder trashed", removed)
+	}
+
+	removed = nil
+	if _, err := DeleteFolderRecursive(ctx, c, "top", DeleteOptions{Permanent: true}); err != nil {
+		t.Fatalf("permanent delete: %v", err)
+	}
+	if strings.Join(removed, ",") != "DELETE /drive/v3/files/top" {
+		t.Fatalf("removed = %v", removed)
+	}
+}
+
+func TestDeleteFolderRecursive_Safety(t *testing.T) {
+	var removed []string
+	c := testClient(deleteServer(t, &removed))
+	ctx := context.Background()
+
+	if _, err := DeleteFolderRecursive(ctx, c, "top", DeleteOptions{MaxItems: 3}); !errors.Is(err, ErrTooManyItems) {
+		t.Fatalf("expected ErrTooManyItems, got %v", err)
+	}
+	if _, err := DeleteFolderRecursive(ctx, c, "top", DeleteOptions{Confirm: func([]DeletedItem) bool { return false }}); !errors.Is(err, ErrAborted) {
+		t.Fatalf("expected ErrAborted, got %v", err)
+	}
+	if report, err := DeleteFolderRecursive(ctx, c, "top", DeleteOptions{DryRun: true}); err != nil || !report.DryRun {
+		t.Fatalf("dry run = %+v, %v", report, err)
+	}
+	if _, err := DeleteFolderRecursive(ctx, c, "doc", DeleteOptions{}); err == nil {
+		t.Fatal("expected error for a non-folder")
+	}
+	if _, err := DeleteFolderRecursive(ctx, c, "root", DeleteOptions{}); err == nil {
+		t.Fatal("expected error for My Drive root")
+	}
+	var perr *drive.PermissionError
+	if _, err := DeleteFolderRecursive(ctx, c, "shared", DeleteOptions{Permanent: true}); !errors.As(err, &perr) || perr.Capability != drive.CanDelete {
+		t.Fatalf("expected missing canDelete, got %v", err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("nothing should have been removed: %v", removed)
+	}
+}
+
+func TestDeleteFolderRecursive_FileShortcut(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	top := srv.AddFolder("Old", "")
+	srv.Add(drive.File{Name: "a-link", MimeType: drive.ShortcutMimeType, Parents: []string{top},
+		ShortcutDetails: &drive.ShortcutDetails{TargetID: "x", TargetMimeType: "application/pdf"}}, nil)
+	for _, name := range []string{"b.pdf", "c.pdf", "d.pdf"} {
+		srv.Add(drive.File{Name: name, Parents: []string{top}}, []byte(name))
+	}
+
+	// A file shortcut must not hide the files after it from the count.
+	report, err := DeleteFolderRecursive(context.Background(), srv.Client(), top, DeleteOptions{MaxItems: 2})
+	if !errors.Is(err, ErrTooManyItems) {
+		t.Fatalf("err = %v, report %+v; want ErrTooManyItems", err, report)
+	}
+	if _, ok := srv.File(top); !ok {
+		t.Fatal("folder deleted past MaxItems")
+	}
+	report, err = DeleteFolderRecursive(context.Background(), srv.Client(), top, DeleteOptions{DryRun: true})
+	if err != nil || len(report.Items) != 5 {
+		t.Fatalf("dry run = %+v, %v; want the folder and its 4 items", report, err)
+	}
+}
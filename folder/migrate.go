@@ -0,0 +1,169 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// MigrateOptions tunes Migrate.
+type MigrateOptions struct {
+	// CopyFallback copies files Drive refuses to move, typically ones the
+	// caller does not own. The originals stay where they are.
+	CopyFallback bool
+	// RemoveEmptySource trashes source folders left empty once their
+	// contents have moved.
+	RemoveEmptySource bool
+	// OnItem, if set, is called after every item is handled.
+	OnItem func(MigrateItem)
+}
+
+// MigrateItem records what happened to one item of the source tree.
+type MigrateItem struct {
+	// Path is relative to the source folder.
+	Path     string
+	SourceID string
+	// NewID is the ID in the destination: the same as SourceID for a move,
+	// the new file's for a copy or a recreated folder.
+	NewID  string
+	Folder bool
+	Copied bool
+	Err    error
+}
+
+// MigrateReport summarizes a Migrate run.
+type MigrateReport struct {
+	// RootID is the ID of the top-level folder in the destination.
+	RootID  string
+	Folders int
+	Moved   int
+	Copied  int
+	Failed  int
+	Items   []MigrateItem
+}
+
+// Migrate moves the tree below srcFolderID into destParentID, typically a
+// folder in a shared drive. Folders cannot always be moved across drives as
+// a whole, so the folder structure is recreated in the destination and each
+// file is moved on its own. A file Drive refuses to move (for example one
+// owned by someone else) is copied if opts.CopyFallback is set, and
+// otherwise recorded as failed; either way the run continues and Migrate
+// returns an error at the end if anything failed. Shortcuts are moved like
+// files.
+func Migrate(ctx context.Context, c *drive.Client, srcFolderID, destParentID string, opts MigrateOptions) (*MigrateReport, error) {
+	srcFolderID, err := drive.ParseID(srcFolderID)
+	if err != nil {
+		return nil, err
+	}
+	src, err := c.GetFile(ctx, srcFolderID, "id", "name", "mimeType")
+	if err != nil {
+		return nil, fmt.Errorf("read source folder: %w", err)
+	}
+	if !src.IsFolder() {
+		return nil, fmt.Errorf("%s is not a folder", srcFolderID)
+	}
+	root, err := c.CreateFolder(ctx, src.Name, destParentID)
+	if err != nil {
+		return nil, fmt.Errorf("create destination folder: %w", err)
+	}
+	m := &migrator{ctx: ctx, c: c, opts: opts, report: &MigrateReport{RootID: root.ID}}
+	empty, err := m.migrateDir(srcFolderID, root.ID, "")
+	if err != nil {
+		return m.report, err
+	}
+	if opts.RemoveEmptySource && empty {
+		if _, err := c.TrashFile(ctx, srcFolderID); err != nil {
+			return m.report, fmt.Errorf("trash source folder: %w", err)
+		}
+	}
+	if m.report.Failed > 0 {
+		return m.report, fmt.Errorf("%d items failed to migrate", m.report.Failed)
+	}
+	return m.report, nil
+}
+
+type migrator struct {
+	ctx    context.Context
+	c      *drive.Client
+	opts   MigrateOptions
+	report *MigrateReport
+}
+
+// migrateDir moves the contents of srcID into dstID and reports whether
+// srcID was left empty.
+func (m *migrator) migrateDir(srcID, dstID, prefix string) (bool, error) {
+	children, err := list.Children(m.ctx, m.c, srcID)
+	if err != nil {
+		return false, fmt.Errorf("list %q: %w", prefix, err)
+	}
+	empty := true
+	for _, f := range children {
+		if err := m.ctx.Err(); err != nil {
+			return false, err
+		}
+		item := MigrateItem{Path: prefix + f.Name, SourceID: f.ID}
+		if f.IsFolder() {
+			created, err := m.c.CreateFolder(m.ctx, f.Name, dstID)
+			if err != nil {
+				return false, fmt.Errorf("create %q: %w", item.Path, err)
+			}
+			item.Folder, item.NewID = true, created.ID
+			m.record(item)
+			sub, err := m.migrateDir(f.ID, created.ID, item.Path+"/")
+			if err != nil {
+				return false, err
+			}
+			if sub && m.opts.RemoveEmptySource {
+				if _, err := m.c.TrashFile(m.ctx, f.ID); err != nil {
+					return false, fmt.Errorf("trash %q: %w", item.Path, err)
+				}
+			} else {
+				empty = false
+			}
+			continue
+		}
+		_, err := m.c.Move(m.ctx, f.ID, dstID)
+		if err == nil {
+			item.NewID = f.ID
+		} else if m.opts.CopyFallback && refused(err) && !f.IsShortcut() {
+			var copied drive.File
+			copied, err = m.c.CopyFile(m.ctx, f.ID, dstID, f.Name)
+			item.NewID, item.Copied = copied.ID, err == nil
+		}
+		item.Err = err
+		if item.Err != nil || item.Copied {
+			empty = false
+		}
+		m.record(item)
+	}
+	return empty, nil
+}
+
+// refused reports whether Drive rejected an operation for lack of rights,
+// as opposed to a transient or request error.
+func refused(err error) bool {
+	var apiErr *drive.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+func (m *migrator) record(item MigrateItem) {
+	r := m.report
+	switch {
+	case item.Err != nil:
+		r.Failed++
+	case item.Folder:
+		r.Folders++
+	case item.Copied:
+		r.Copied++
+	default:
+		r.Moved++
+	}
+	r.Items = append(r.Items, item)
+	if m.opts.OnItem != nil {
+		m.opts.OnItem(item)
+	}
+}
@@ -0,0 +1,80 @@
+package folder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func migrateServer(trashed *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		id := strings.TrimPrefix(r.URL.Path, "/drive/v3/files/")
+		switch {
+		case r.Method == http.MethodGet && id == "src":
+			w.Write([]byte(`{"id":"src","name":"Team","mimeType":"application/vnd.google-apps.folder"}`))
+		case r.Method == http.MethodGet && q == "":
+			w.Write([]byte(`{"id":"` + id + `","parents":["old"]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			var meta drive.Metadata
+			json.NewDecoder(r.Body).Decode(&meta)
+			w.Write([]byte(`{"id":"new-` + meta.Name + `"}`))
+		case r.Method == http.MethodPatch:
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["trashed"] == true {
+				*trashed = append(*trashed, id)
+			} else if id == "foreign" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"error":{"errors":[{"reason":"insufficientFilePermissions"}],"message":"no"}}`))
+				return
+			}
+			w.Write([]byte(`{"id":"` + id + `"}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/copy"):
+			w.Write([]byte(`{"id":"copy-of-foreign"}`))
+		case strings.HasPrefix(q, "'src' in parents"):
+			w.Write([]byte(`{"files":[
+				{"id":"a","name":"a.pdf","mimeType":"application/pdf"},
+				{"id":"sub","name":"Sub","mimeType":"application/vnd.google-apps.folder"},
+				{"id":"other","name":"Other","mimeType":"application/vnd.google-apps.folder"}]}`))
+		case strings.HasPrefix(q, "'sub' in parents"):
+			w.Write([]byte(`{"files":[{"id":"b","name":"b.pdf","mimeType":"application/pdf"}]}`))
+		case strings.HasPrefix(q, "'other' in parents"):
+			w.Write([]byte(`{"files":[{"id":"foreign","name":"theirs.pdf","mimeType":"application/pdf"}]}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+}
+
+func TestMigrate(t *testing.T) {
+	var trashed []string
+	srv := migrateServer(&trashed)
+	defer srv.Close()
+	ctx := context.Background()
+
+	report, err := Migrate(ctx, testClient(srv), "src", "shared", MigrateOptions{RemoveEmptySource: true})
+	if err == nil || report.Failed != 1 || report.Moved != 2 || report.Folders != 2 || report.RootID != "new-Team" {
+		t.Fatalf("Migrate = %+v, %v", report, err)
+	}
+	// Only Sub was emptied; Other still holds the file that failed.
+	if strings.Join(trashed, ",") != "sub" {
+		t.Fatalf("trashed = %v", trashed)
+	}
+
+	trashed = nil
+	report, err = Migrate(ctx, testClient(srv), "src", "shared", MigrateOptions{CopyFallback: true})
+	if err != nil || report.Failed != 0 || report.Copied != 1 || len(trashed) != 0 {
+		t.Fatalf("Migrate with fallback = %+v, %v; trashed %v", report, err, trashed)
+	}
+	for _, it := range report.Items {
+		if it.SourceID == "foreign" && (!it.Copied || it.NewID != "copy-of-foreign") {
+			t.Fatalf("foreign item = %+v", it)
+		}
+	}
+}
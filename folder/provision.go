@@ -0,0 +1,194 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Spec declares a folder hierarchy. In YAML:
+//
+//	parent: root
+//	folders:
+//	  - name: Apollo
+//	    folders:
+//	      - name: environments
+//	        folders:
+//	          - name: staging
+//	          - name: production
+//	      - name: archive
+type Spec struct {
+	// Parent is the ID or URL of the folder the hierarchy is created in;
+	// empty means the root of My Drive.
+	Parent  string       `yaml:"parent"`
+	Folders []FolderSpec `yaml:"folders"`
+}
+
+// FolderSpec is one folder of a Spec and its subfolders.
+type FolderSpec struct {
+	Name    string       `yaml:"name"`
+	Folders []FolderSpec `yaml:"folders"`
+}
+
+// ParseSpec reads a YAML folder spec.
+func ParseSpec(r io.Reader) (*Spec, error) {
+	var s Spec
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	if err := checkNames(s.Folders, ""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func checkNames(folders []FolderSpec, prefix string) error {
+	seen := map[string]bool{}
+	for _, f := range folders {
+		if f.Name == "" || strings.Contains(f.Name, "/") {
+			return fmt.Errorf("spec: invalid folder name %q under %q", f.Name, prefix)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("spec: duplicate folder %q", prefix+f.Name)
+		}
+		seen[f.Name] = true
+		if err := checkNames(f.Folders, prefix+f.Name+"/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSpec reads a YAML folder spec file.
+func LoadSpec(path string) (*Spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSpec(f)
+}
+
+// ProvisionItem is one folder of a provisioning plan.
+type ProvisionItem struct {
+	// Path is relative to the spec's parent.
+	Path string `json:"path"`
+	// ID is empty until the folder exists.
+	ID string `json:"id,omitempty"`
+	// Create is true if the folder did not exist when the plan was made.
+	Create bool `json:"create"`
+}
+
+// ProvisionPlan lists every folder of a Spec, parents before children.
+type ProvisionPlan struct {
+	Parent string
+	Items  []ProvisionItem
+}
+
+// Creates reports how many folders the plan creates.
+func (p *ProvisionPlan) Creates() int {
+	n := 0
+	for _, it := range p.Items {
+		if it.Create {
+			n++
+		}
+	}
+	return n
+}
+
+// IDs maps each folder path to its ID, for folders that exist.
+func (p *ProvisionPlan) IDs() map[string]string {
+	ids := make(map[string]string, len(p.Items))
+	for _, it := range p.Items {
+		if it.ID != "" {
+			ids[it.Path] = it.ID
+		}
+	}
+	return ids
+}
+
+// WriteTo writes one line per folder: "+ path" for folders to create and
+// "  path id" for existing ones.
+func (p *ProvisionPlan) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, it := range p.Items {
+		var m int
+		var err error
+		if it.ID == "" {
+			m, err = fmt.Fprintf(w, "+ %s\n", it.Path)
+		} else {
+			m, err = fmt.Fprintf(w, "  %s %s\n", it.Path, it.ID)
+		}
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// PlanProvision looks up every folder of spec and returns a plan marking
+// the ones that are missing. It changes nothing.
+func (r *Resolver) PlanProvision(ctx context.Context, spec *Spec) (*ProvisionPlan, error) {
+	parent := spec.Parent
+	if parent == "" {
+		parent = r.Root
+	}
+	parent, err := drive.ParseID(parent)
+	if err != nil {
+		return nil, err
+	}
+	plan := &ProvisionPlan{Parent: parent}
+	if err := r.planFolders(ctx, plan, spec.Folders, parent, ""); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (r *Resolver) planFolders(ctx context.Context, plan *ProvisionPlan, folders []FolderSpec, parentID, prefix string) error {
+	for _, f := range folders {
+		item := ProvisionItem{Path: prefix + f.Name}
+		if parentID != "" {
+			id, err := r.lookup(ctx, parentID, f.Name)
+			switch {
+			case err == nil:
+				item.ID = id
+			case errors.Is(err, drive.ErrNotFound):
+			default:
+				return fmt.Errorf("look up %q: %w", item.Path, err)
+			}
+		}
+		item.Create = item.ID == ""
+		plan.Items = append(plan.Items, item)
+		if err := r.planFolders(ctx, plan, f.Folders, item.ID, item.Path+"/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyProvision creates the missing folders of plan and fills in their
+// IDs. Folders are created with EnsureFolderPath, so applying a stale plan,
+// or applying one twice, does not produce duplicates.
+func (r *Resolver) ApplyProvision(ctx context.Context, plan *ProvisionPlan) error {
+	for i, it := range plan.Items {
+		if !it.Create {
+			continue
+		}
+		id, err := r.EnsureFolderPath(ctx, plan.Parent, it.Path)
+		if err != nil {
+			return err
+		}
+		plan.Items[i].ID = id
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package folder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProvision(t *testing.T) {
+	// folders maps "parent/name" to an ID; the server answers lookups from
+	// it and adds created folders to it.
+	var mu sync.Mutex
+	folders := map[string]string{"root/Apollo": "a1", "a1/archive": "ar"}
+	created := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			var meta struct {
+				Name    string   `json:"name"`
+				Parents []string `json:"parents"`
+			}
+			json.NewDecoder(r.Body).Decode(&meta)
+			created++
+			id := fmt.Sprintf("new%d", created)
+			folders[meta.Parents[0]+"/"+meta.Name] = id
+			fmt.Fprintf(w, `{"id":%q}`, id)
+			return
+		}
+		var parent, name string
+		fmt.Sscanf(strings.NewReplacer("'", " ", " in parents and name = ", " ").Replace(r.URL.Query().Get("q")), "%s %s", &parent, &name)
+		if id, ok := folders[parent+"/"+name]; ok {
+			fmt.Fprintf(w, `{"files":[{"id":%q}]}`, id)
+			return
+		}
+		w.Write([]byte(`{"files":[]}`))
+	}))
+	defer srv.Close()
+
+	spec, err := ParseSpec(strings.NewReader(`
+folders:
+  - name: Apollo
+    folders:
+      - name: environments
+        folders:
+          - name: staging
+      - name: archive
+`))
+	if err != nil {
+		t.Fatalf("ParseSpec: %v", err)
+	}
+	r := NewResolver(testClient(srv), 0)
+	ctx := context.Background()
+	plan, err := r.PlanProvision(ctx, spec)
+	if err != nil {
+		t.Fatalf("PlanProvision: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	want := "  Apollo a1\n+ Apollo/environments\n+ Apollo/environments/staging\n  Apollo/archive ar\n"
+	if out.String() != want || plan.Creates() != 2 || created != 0 {
+		t.Fatalf("plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if err := r.ApplyProvision(ctx, plan); err != nil {
+		t.Fatalf("ApplyProvision: %v", err)
+	}
+	ids := plan.IDs()
+	if created != 2 || ids["Apollo/environments"] != "new1" || ids["Apollo/environments/staging"] != "new2" || ids["Apollo"] != "a1" {
+		t.Fatalf("created %d, ids %v", created, ids)
+	}
+
+	plan, err = NewResolver(testClient(srv), 0).PlanProvision(ctx, spec)
+	if err != nil || plan.Creates() != 0 {
+		t.Fatalf("replan = %+v, %v", plan, err)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	for _, bad := range []string{
+		"folders:\n  - name: a/b\n",
+		"folders:\n  - name: ''\n",
+		"folders:\n  - name: a\n  - name: a\n",
+		"folders:\n  - name: a\n    owner: me\n",
+	} {
+		if _, err := ParseSpec(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package folder resolves and creates Drive folders by path.
+package folder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// DefaultTTL is how long a resolved folder ID is trusted by a Resolver
+// created with a zero TTL.
+const DefaultTTL = 5 * time.Minute
+
+// timeNow is swapped out by tests.
+var timeNow = time.Now
+
+type cacheKey struct {
+	parent, name string
+}
+
+type cacheEntry struct {
+	id      string
+	expires time.Time
+}
+
+// Resolver maps slash-separated folder paths to folder IDs, caching each
+// parent/name lookup for TTL so repeated path operations do not re-issue
+// the same listing requests. It is safe for concurrent use.
+type Resolver struct {
+	client *drive.Client
+	// Root is the folder that absolute paths start from. It defaults to
+	// "root", the caller's My Drive.
+	Root string
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+}
+
+// NewResolver returns a Resolver using c. A ttl of 0 means DefaultTTL.
+func NewResolver(c *drive.Client, ttl time.Duration) *Resolver {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Resolver{client: c, Root: "root", ttl: ttl, cache: map[cacheKey]cacheEntry{}}
+}
+
+// ResolvePath returns the ID of the folder at path below r.Root, e.g.
+// "/Quality/SOPs/Published". A missing folder yields an error matching
+// drive.ErrNotFound.
+func (r *Resolver) ResolvePath(ctx context.Context, path string) (string, error) {
+	return r.Resolve(ctx, r.Root, path)
+}
+
+// Resolve is like ResolvePath but starts from parentID.
+func (r *Resolver) Resolve(ctx context.Context, parentID, path string) (string, error) {
+	id, err := drive.ParseID(parentID)
+	if err != nil {
+		return "", err
+	}
+	names := splitPath(path)
+	for i, name := range names {
+		id, err = r.lookup(ctx, id, name)
+		if err != nil {
+			return "", fmt.Errorf("resolve %q: %w", strings.Join(names[:i+1], "/"), err)
+		}
+	}
+	return id, nil
+}
+
+// Forget drops every cached lookup, e.g. after folders were moved or
+// deleted outside the Resolver.
+func (r *Resolver) Forget() {
+	r.mu.Lock()
+	r.cache = map[cacheKey]cacheEntry{}
+	r.mu.Unlock()
+}
+
+func (r *Resolver) cached(parent, name string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.cache[cacheKey{parent, name}]
+	if !ok {
+		return "", false
+	}
+	if timeNow().After(e.expires) {
+		delete(r.cache, cacheKey{parent, name})
+		return "", false
+	}
+	return e.id, true
+}
+
+func (r *Resolver) store(parent, name, id string) {
+	r.mu.Lock()
+	r.cache[cacheKey{parent, name}] = cacheEntry{id: id, expires: timeNow().Add(r.ttl)}
+	r.mu.Unlock()
+}
+
+// lookup returns the ID of the folder called name directly inside parent.
+func (r *Resolver) lookup(ctx context.Context, parent, name string) (string, error) {
+	if id, ok := r.cached(parent, name); ok {
+		return id, nil
+	}
+	f, err := list.First(ctx, r.client, list.Options{
+		Query:  query.New().InParent(parent).NameEquals(name).Folders().NotTrashed().String(),
+		Fields: "id",
+	})
+	if err != nil {
+		return "", err
+	}
+	r.store(parent, name, f.ID)
+	return f.ID, nil
+}
+
+// splitPath splits path into names, ignoring empty segments so leading,
+// trailing, and doubled slashes do not matter.
+func splitPath(path string) []string {
+	var names []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+// EnsureFolderPath returns the ID of the folder at path below parentID,
+// creating any missing folders along the way. It is idempotent, and safe
+// to run concurrently with other writers: after creating a folder it looks
+// the name up again and, if a competing folder of the same name exists,
+// settles on the oldest and deletes its own.
+func (r *Resolver) EnsureFolderPath(ctx context.Context, parentID, path string) (string, error) {
+	id, err := drive.ParseID(parentID)
+	if err != nil {
+		return "", err
+	}
+	names := splitPath(path)
+	for i, name := range names {
+		next, err := r.lookup(ctx, id, name)
+		if errors.Is(err, drive.ErrNotFound) {
+			next, err = r.create(ctx, id, name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("ensure %q: %w", strings.Join(names[:i+1], "/"), err)
+		}
+		id = next
+	}
+	return id, nil
+}
+
+func (r *Resolver) create(ctx context.Context, parent, name string) (string, error) {
+	created, err := r.client.CreateFolder(ctx, name, parent)
+	if err != nil {
+		return "", err
+	}
+	matches, err := list.ListFiles(ctx, r.client, list.Options{
+		Query:  query.New().InParent(parent).NameEquals(name).Folders().NotTrashed().String(),
+		Fields: "id,createdTime",
+	})
+	if err != nil {
+		return "", err
+	}
+	winner := created
+	for _, m := range matches {
+		if m.CreatedTime.Before(winner.CreatedTime) || (m.CreatedTime.Equal(winner.CreatedTime) && m.ID < winner.ID) {
+			winner = m
+		}
+	}
+	if winner.ID != created.ID {
+		// Ours is empty and nobody else has seen it yet.
+		if err := r.client.Delete(ctx, created.ID); err != nil {
+			return "", fmt.Errorf("remove duplicate folder %s: %w", created.ID, err)
+		}
+	}
+	r.store(parent, name, winner.ID)
+	return winner.ID, nil
+}
@@ -0,0 +1,141 @@
+package folder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+// fakeClock pins timeNow to *now for the duration of the test.
+func fakeClock(t *testing.T, now *time.Time) {
+	t.Helper()
+	orig := timeNow
+	timeNow = func() time.Time { return *now }
+	t.Cleanup(func() { timeNow = orig })
+}
+
+func TestResolvePath_Caches(t *testing.T) {
+	now := time.Unix(0, 0)
+	fakeClock(t, &now)
+	lookups := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		q := r.URL.Query().Get("q")
+		switch {
+		case strings.HasPrefix(q, "'root' in parents and name = 'Quality'"):
+			w.Write([]byte(`{"files":[{"id":"q1"}]}`))
+		case strings.HasPrefix(q, "'q1' in parents and name = 'SOPs'"):
+			w.Write([]byte(`{"files":[{"id":"s1"}]}`))
+		default:
+			w.Write([]byte(`{"files":[]}`))
+		}
+	}))
+	defer srv.Close()
+	r := NewResolver(testClient(srv), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		id, err := r.ResolvePath(context.Background(), "/Quality//SOPs/")
+		if err != nil || id != "s1" {
+			t.Fatalf("ResolvePath = %q, %v", id, err)
+		}
+	}
+	if lookups != 2 {
+		t.Fatalf("lookups = %d; want 2 (second call cached)", lookups)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := r.ResolvePath(context.Background(), "Quality/SOPs"); err != nil {
+		t.Fatalf("ResolvePath: %v", err)
+	}
+	if lookups != 4 {
+		t.Fatalf("lookups = %d; want 4 after expiry", lookups)
+	}
+
+	_, err := r.ResolvePath(context.Background(), "/Quality/Missing/Deeper")
+	if !errors.Is(err, drive.ErrNotFound) || !strings.Contains(err.Error(), `"Quality/Missing"`) {
+		t.Fatalf("expected not found for Quality/Missing, got %v", err)
+	}
+}
+
+func TestEnsureFolderPath(t *testing.T) {
+	var created, deleted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case r.Method == http.MethodPost:
+			created = append(created, r.URL.Path)
+			if len(created) == 1 {
+				w.Write([]byte(`{"id":"b1","createdTime":"2025-01-01T00:00:05Z"}`))
+			} else {
+				w.Write([]byte(`{"id":"mine","createdTime":"2025-01-01T00:00:05Z"}`))
+			}
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		case strings.HasPrefix(q, "'p' in parents and name = 'a'"):
+			w.Write([]byte(`{"files":[{"id":"a1"}]}`))
+		case strings.HasPrefix(q, "'a1' in parents and name = 'b'"):
+			// invisible on the first lookup, visible right after creation
+			if len(created) == 0 {
+				w.Write([]byte(`{"files":[]}`))
+				return
+			}
+			w.Write([]byte(`{"files":[{"id":"b1","createdTime":"2025-01-01T00:00:05Z"}]}`))
+		case strings.HasPrefix(q, "'b1' in parents and name = 'c'"):
+			// a concurrent writer created an older "c" at the same time as us
+			if len(created) < 2 {
+				w.Write([]byte(`{"files":[]}`))
+				return
+			}
+			w.Write([]byte(`{"files":[{"id":"mine","createdTime":"2025-01-01T00:00:05Z"},{"id":"theirs","createdTime":"2025-01-01T00:00:04Z"}]}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	r := NewResolver(testClient(srv), 0)
+
+	id, err := r.EnsureFolderPath(context.Background(), "p", "a/b/c")
+	if err != nil {
+		t.Fatalf("EnsureFolderPath: %v", err)
+	}
+	if id != "theirs" {
+		t.Fatalf("id = %q; want the older concurrent folder", id)
+	}
+	if len(created) != 2 || len(deleted) != 1 || deleted[0] != "/drive/v3/files/mine" {
+		t.Fatalf("created %v, deleted %v", created, deleted)
+	}
+
+	// everything is cached now, so a second call makes no requests
+	id, err = r.EnsureFolderPath(context.Background(), "p", "/a/b/c/")
+	if err != nil || id != "theirs" || len(created) != 2 {
+		t.Fatalf("second call = %q, %v (created %v)", id, err, created)
+	}
+}
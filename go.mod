@@ -1,3 +1,15 @@
 module github.com/hwalton/gdrivetoolbox
 
 go 1.24.3
+
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	golang.org/x/sys v0.4.0 // indirect
+)
@@ -0,0 +1,146 @@
+// Package ignore matches paths against gitignore-style patterns, as read
+// from a .gdriveignore file, so build output, temp files, and OS metadata
+// stay out of uploads and syncs.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the ignore file looked for in the root of a local directory.
+const FileName = ".gdriveignore"
+
+// Matcher holds a list of patterns. The zero value and nil ignore nothing.
+type Matcher struct {
+	rules []rule
+}
+
+type rule struct {
+	segs     []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Parse reads patterns, one per line, with gitignore syntax: blank lines
+// and lines starting with # are skipped; ! re-includes; a trailing /
+// matches only directories; a pattern containing a / other than at the
+// end is relative to the root, otherwise it matches a name at any depth;
+// *, ?, and [...] match within a name and ** across directories.
+func Parse(r io.Reader) (*Matcher, error) {
+	m := &Matcher{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var ru rule
+		if strings.HasPrefix(line, "!") {
+			ru.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			ru.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			ru.anchored, line = true, strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			return nil, fmt.Errorf("line %d: empty pattern", n)
+		}
+		ru.segs = strings.Split(line, "/")
+		for _, s := range ru.segs {
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("line %d: bad pattern %q", n, sc.Text())
+			}
+		}
+		m.rules = append(m.rules, ru)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load reads FileName from dir. A missing file ignores nothing.
+func Load(dir string) (*Matcher, error) {
+	f, err := os.Open(filepath.Join(dir, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Matcher{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", FileName, err)
+	}
+	return m, nil
+}
+
+// Match reports whether the slash-separated path p, relative to the root,
+// is ignored; dir says whether it is a directory. Everything inside an
+// ignored directory is ignored too, as in git.
+func (m *Matcher) Match(p string, dir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	segs := strings.Split(p, "/")
+	for i := 1; i < len(segs); i++ {
+		if m.matchOne(segs[:i], true) {
+			return true
+		}
+	}
+	return m.matchOne(segs, dir)
+}
+
+// matchOne applies the rules to a single path; the last matching rule wins.
+func (m *Matcher) matchOne(segs []string, dir bool) bool {
+	ignored := false
+	for _, ru := range m.rules {
+		if ru.dirOnly && !dir {
+			continue
+		}
+		var ok bool
+		if ru.anchored {
+			ok = matchSegs(ru.segs, segs)
+		} else {
+			ok, _ = path.Match(ru.segs[0], segs[len(segs)-1])
+		}
+		if ok {
+			ignored = !ru.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegs matches path segments against pattern segments, where **
+// stands for any number of segments.
+func matchSegs(pat, segs []string) bool {
+	if len(pat) == 0 {
+		return len(segs) == 0
+	}
+	if pat[0] == "**" {
+		for i := 0; i <= len(segs); i++ {
+			if matchSegs(pat[1:], segs[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segs) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pat[0], segs[0])
+	return ok && matchSegs(pat[1:], segs[1:])
+}
@@ -0,0 +1,74 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	m, err := Parse(strings.NewReader(`
+# OS metadata
+.DS_Store
+Thumbs.db
+
+*.tmp
+!keep.tmp
+build/
+/dist
+docs/**/draft-*.md
+\#notes
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tests := []struct {
+		path string
+		dir  bool
+		want bool
+	}{
+		{".DS_Store", false, true},
+		{"a/b/.DS_Store", false, true},
+		{"scratch.tmp", false, true},
+		{"a/keep.tmp", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build/out.o", false, true},
+		{"dist", true, true},
+		{"dist/app.js", false, true},
+		{"src/dist", true, false},
+		{"docs/draft-1.md", false, true},
+		{"docs/x/y/draft-2.md", false, true},
+		{"docs/final.md", false, false},
+		{"#notes", false, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.dir); got != tt.want {
+			t.Errorf("Match(%q, %t) = %t, want %t", tt.path, tt.dir, got, tt.want)
+		}
+	}
+
+	var none *Matcher
+	if none.Match("anything", false) {
+		t.Error("nil Matcher ignored a path")
+	}
+	if _, err := Parse(strings.NewReader("[z-a")); err == nil {
+		t.Error("expected an error for a bad pattern")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Load(dir)
+	if err != nil || m.Match("x.tmp", false) {
+		t.Fatalf("Load without a file = %v, %v", m, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("*.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = Load(dir); err != nil || !m.Match("x.tmp", false) {
+		t.Fatalf("Load = %v, %v", m, err)
+	}
+}
@@ -0,0 +1,17 @@
+// Package integration holds the end-to-end tests of the toolbox against
+// the real Drive API. They are built only with the integration tag and run
+// only when credentials and a scratch folder are given in the environment:
+//
+//	GDRIVE_ACCESS_TOKEN, or GDRIVE_CLIENT_ID, GDRIVE_CLIENT_SECRET, and
+//	GDRIVE_REFRESH_TOKEN: the credentials, as for the CLI
+//	GDRIVE_INTEGRATION_FOLDER: the ID of a folder the tests may write to
+//	GDRIVE_INTEGRATION_SHARE_WITH: optionally, an address to share a test
+//	file with, for when policy forbids sharing with anyone with the link
+//
+// then:
+//
+//	go test -tags integration ./integration
+//
+// Each test works in a folder of its own under the scratch folder, which it
+// deletes when done, pass or fail.
+package integration
@@ -0,0 +1,208 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/auth"
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/permissions"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// setup returns a client and the token it uses, and a new folder under the
+// scratch folder that is deleted when t ends. It skips t without
+// credentials or a scratch folder.
+func setup(t *testing.T) (c *drive.Client, token, folderID string) {
+	t.Helper()
+	scratch := os.Getenv("GDRIVE_INTEGRATION_FOLDER")
+	token = os.Getenv("GDRIVE_ACCESS_TOKEN")
+	if id, secret, refresh := os.Getenv("GDRIVE_CLIENT_ID"), os.Getenv("GDRIVE_CLIENT_SECRET"), os.Getenv("GDRIVE_REFRESH_TOKEN"); token == "" && refresh != "" {
+		var err error
+		if token, err = auth.GetGoogleAccessToken(id, secret, refresh); err != nil {
+			t.Fatalf("exchange refresh token: %v", err)
+		}
+	}
+	if scratch == "" || token == "" {
+		t.Skip("set GDRIVE_INTEGRATION_FOLDER and credentials to run against Drive")
+	}
+	c = drive.NewClient(token)
+	c.HTTPClient = &http.Client{Transport: &drive.Retrier{Max: 3}}
+	ctx := context.Background()
+	name := fmt.Sprintf("gdrivetoolbox-%s-%s", t.Name(), time.Now().UTC().Format("20060102T150405.000"))
+	folder, err := c.CreateFolder(ctx, name, scratch)
+	if err != nil {
+		t.Fatalf("create test folder: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := c.Delete(ctx, folder.ID); err != nil {
+			t.Errorf("delete test folder %s (%s): %v", name, folder.ID, err)
+		}
+	})
+	return c, token, folder.ID
+}
+
+func download(t *testing.T, c *drive.Client, id string) string {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := c.Download(context.Background(), id, &b); err != nil {
+		t.Fatalf("download %s: %v", id, err)
+	}
+	return b.String()
+}
+
+func TestUploadDownload(t *testing.T) {
+	c, _, folder := setup(t)
+	ctx := context.Background()
+
+	id, err := c.Upload(ctx, drive.Metadata{Name: "a.txt", MimeType: "text/plain", Parents: []string{folder}, Description: "v1"},
+		strings.NewReader("first"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := download(t, c, id); got != "first" {
+		t.Errorf("downloaded %q, want first", got)
+	}
+	if _, err := c.UpdateContent(ctx, id, strings.NewReader("second"), 6); err != nil {
+		t.Fatal(err)
+	}
+	var tail bytes.Buffer
+	if _, err := c.DownloadFrom(ctx, id, 3, &tail); err != nil || tail.String() != "ond" {
+		t.Errorf("DownloadFrom = %q, %v", tail.String(), err)
+	}
+
+	sub, err := c.CreateFolder(ctx, "sub", folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Rename(ctx, id, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Move(ctx, id, sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := c.CopyFile(ctx, id, folder, "c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := download(t, c, cp.ID); got != "second" {
+		t.Errorf("copy holds %q, want second", got)
+	}
+	if f, err := c.GetFile(ctx, id, "name", "parents"); err != nil || f.Name != "b.txt" || f.Parents[0] != sub.ID {
+		t.Errorf("after rename and move: %+v, %v", f, err)
+	}
+	if f, err := c.TrashFile(ctx, cp.ID); err != nil || !f.Trashed {
+		t.Errorf("TrashFile = %+v, %v", f, err)
+	}
+
+	files, err := list.ListFiles(ctx, c, list.Options{Query: query.New().InParent(folder).NotTrashed().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].ID != sub.ID {
+		t.Errorf("listed %+v, want only sub", files)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	c, token, folder := setup(t)
+	ctx := context.Background()
+	mkdir := func(name string) string {
+		f, err := c.CreateFolder(ctx, name, folder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f.ID
+	}
+	live, staging, archive := mkdir("live"), mkdir("staging"), mkdir("archive")
+	dir := t.TempDir()
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(dir, "doc.pdf"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("%PDF-1.4 v1")
+	r, err := deploy.DeployPDFResult(ctx, token, "doc", "v1", staging, live, archive, dir)
+	if err != nil || r.Status != deploy.StatusDeployed {
+		t.Fatalf("first deploy = %+v, %v", r, err)
+	}
+	first := r.FileID
+	if r, err := deploy.DeployPDFResult(ctx, token, "doc", "v1", staging, live, archive, dir); err != nil || r.Status != deploy.StatusSkipped {
+		t.Fatalf("same version = %+v, %v", r, err)
+	}
+
+	write("%PDF-1.4 v2")
+	r, err = deploy.DeployPDFResult(ctx, token, "doc", "v2", staging, live, archive, dir)
+	if err != nil || r.Status != deploy.StatusDeployed || r.Previous != "v1" {
+		t.Fatalf("second deploy = %+v, %v", r, err)
+	}
+	if got := download(t, c, r.FileID); got != "%PDF-1.4 v2" {
+		t.Errorf("live content %q", got)
+	}
+	old, err := c.GetFile(ctx, first, "name", "parents")
+	if err != nil || old.Parents[0] != archive || !strings.Contains(old.Name, "v1") {
+		t.Errorf("archived file = %+v, %v", old, err)
+	}
+	versions, err := deploy.ListVersions(ctx, c, deploy.VersionsOptions{FileName: "doc", FolderID: live, ArchiveFolderID: archive})
+	if err != nil || len(versions) != 2 {
+		t.Errorf("ListVersions = %+v, %v", versions, err)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	c, _, folder := setup(t)
+	ctx := context.Background()
+	id, err := c.Upload(ctx, drive.Metadata{Name: "shared.txt", MimeType: "text/plain", Parents: []string{folder}}, strings.NewReader("x"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := permissions.Permission{Type: permissions.TypeAnyone, Role: permissions.RoleReader}
+	if email := os.Getenv("GDRIVE_INTEGRATION_SHARE_WITH"); email != "" {
+		p = permissions.Permission{Type: permissions.TypeUser, Role: permissions.RoleReader, EmailAddress: email}
+	}
+	created, err := permissions.Create(ctx, c, id, p, permissions.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := permissions.Update(ctx, c, id, created.ID, permissions.RoleCommenter); err != nil {
+		t.Fatal(err)
+	}
+	perms, err := permissions.List(ctx, c, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role := roleOf(perms, created.ID); role != permissions.RoleCommenter {
+		t.Errorf("role after update = %q, want commenter", role)
+	}
+	if err := permissions.Delete(ctx, c, id, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if perms, err := permissions.List(ctx, c, id); err != nil || roleOf(perms, created.ID) != "" {
+		t.Errorf("after delete: %+v, %v", perms, err)
+	}
+}
+
+// roleOf returns the role of the permission id in perms, or "".
+func roleOf(perms []permissions.Permission, id string) string {
+	for _, p := range perms {
+		if p.ID == id {
+			return p.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,260 @@
+// Package labels applies Drive labels (classification metadata such as
+// "Controlled Document" or "Confidential") to files and reads them back.
+// Use query.Builder.Label and LabelField to search by label.
+//
+// Looking labels up by title needs the drive.labels.readonly scope;
+// applying them needs the drive scope and a label published by an admin.
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// LabelsAPI is the root of the Drive Labels API.
+const LabelsAPI = "https://drivelabels.googleapis.com/v2"
+
+// Definition is a published label and its fields.
+type Definition struct {
+	ID     string
+	Title  string
+	Fields []FieldDef
+}
+
+// FieldDef is a field of a label. Choices is set for selection fields.
+type FieldDef struct {
+	ID      string
+	Name    string
+	Choices []Choice
+}
+
+// Choice is one option of a selection field.
+type Choice struct {
+	ID   string
+	Name string
+}
+
+// Field returns the field with the given display name, ignoring case.
+func (d Definition) Field(name string) (FieldDef, bool) {
+	for _, f := range d.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return FieldDef{}, false
+}
+
+// Choice returns the ID of the choice with the given display name,
+// ignoring case.
+func (f FieldDef) Choice(name string) (string, bool) {
+	for _, c := range f.Choices {
+		if strings.EqualFold(c.Name, name) {
+			return c.ID, true
+		}
+	}
+	return "", false
+}
+
+type rawDefinition struct {
+	ID         string `json:"id"`
+	Properties struct {
+		Title string `json:"title"`
+	} `json:"properties"`
+	Fields []struct {
+		ID         string `json:"id"`
+		Properties struct {
+			DisplayName string `json:"displayName"`
+		} `json:"properties"`
+		SelectionOptions *struct {
+			Choices []struct {
+				ID         string `json:"id"`
+				Properties struct {
+					DisplayName string `json:"displayName"`
+				} `json:"properties"`
+			} `json:"choices"`
+		} `json:"selectionOptions"`
+	} `json:"fields"`
+}
+
+func (r rawDefinition) definition() Definition {
+	d := Definition{ID: r.ID, Title: r.Properties.Title}
+	for _, rf := range r.Fields {
+		f := FieldDef{ID: rf.ID, Name: rf.Properties.DisplayName}
+		if rf.SelectionOptions != nil {
+			for _, c := range rf.SelectionOptions.Choices {
+				f.Choices = append(f.Choices, Choice{ID: c.ID, Name: c.Properties.DisplayName})
+			}
+		}
+		d.Fields = append(d.Fields, f)
+	}
+	return d
+}
+
+// Definitions returns every published label the caller can apply.
+func Definitions(ctx context.Context, c *drive.Client) ([]Definition, error) {
+	q := url.Values{"view": {"LABEL_VIEW_FULL"}, "publishedOnly": {"true"}, "pageSize": {"200"}}
+	var all []Definition
+	for {
+		var page struct {
+			Labels        []rawDefinition `json:"labels"`
+			NextPageToken string          `json:"nextPageToken"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, LabelsAPI+"/labels?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("list labels: %w", err)
+		}
+		for _, r := range page.Labels {
+			all = append(all, r.definition())
+		}
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Find returns the published label with the given title, ignoring case, or
+// an error matching drive.ErrNotFound.
+func Find(ctx context.Context, c *drive.Client, title string) (Definition, error) {
+	defs, err := Definitions(ctx, c)
+	if err != nil {
+		return Definition{}, err
+	}
+	for _, d := range defs {
+		if strings.EqualFold(d.Title, title) {
+			return d, nil
+		}
+	}
+	return Definition{}, fmt.Errorf("label %q: %w", title, drive.ErrNotFound)
+}
+
+// Applied is a label on a file with its field values, keyed by field ID.
+type Applied struct {
+	ID         string           `json:"id"`
+	RevisionID string           `json:"revisionId"`
+	Fields     map[string]Value `json:"fields"`
+}
+
+// Value is the value of one label field; which slice is set depends on
+// ValueType ("text", "selection", "integer", "dateString", or "user").
+type Value struct {
+	ValueType  string   `json:"valueType"`
+	Text       []string `json:"text,omitempty"`
+	Selection  []string `json:"selection,omitempty"`
+	Integer    []string `json:"integer,omitempty"`
+	DateString []string `json:"dateString,omitempty"`
+	User       []struct {
+		EmailAddress string `json:"emailAddress"`
+	} `json:"user,omitempty"`
+}
+
+func filePath(fileID, method string) (string, error) {
+	id, err := drive.ParseID(fileID)
+	if err != nil {
+		return "", err
+	}
+	return "files/" + url.PathEscape(id) + "/" + method, nil
+}
+
+// List returns the labels applied to fileID.
+func List(ctx context.Context, c *drive.Client, fileID string) ([]Applied, error) {
+	path, err := filePath(fileID, "listLabels")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	var all []Applied
+	for {
+		var page struct {
+			Labels        []Applied `json:"labels"`
+			NextPageToken string    `json:"nextPageToken"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("list file labels: %w", err)
+		}
+		all = append(all, page.Labels...)
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// FieldSet sets (or, with Unset, clears) one field when applying a label.
+// Selection holds choice IDs, see FieldDef.Choice.
+type FieldSet struct {
+	FieldID   string
+	Text      []string
+	Selection []string
+	Integer   []int64
+	Unset     bool
+}
+
+func (f FieldSet) modification() map[string]interface{} {
+	m := map[string]interface{}{"fieldId": f.FieldID}
+	switch {
+	case f.Unset:
+		m["unsetValues"] = true
+	case f.Text != nil:
+		m["setTextValues"] = f.Text
+	case f.Selection != nil:
+		m["setSelectionValues"] = f.Selection
+	case f.Integer != nil:
+		ints := make([]string, len(f.Integer))
+		for i, n := range f.Integer {
+			ints[i] = strconv.FormatInt(n, 10)
+		}
+		m["setIntegerValues"] = ints
+	}
+	return m
+}
+
+func modify(ctx context.Context, c *drive.Client, fileID string, mod map[string]interface{}) ([]Applied, error) {
+	path, err := filePath(fileID, "modifyLabels")
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"kind":               "drive#modifyLabelsRequest",
+		"labelModifications": []interface{}{mod},
+	}
+	var out struct {
+		ModifiedLabels []Applied `json:"modifiedLabels"`
+	}
+	if err := c.DoJSON(ctx, http.MethodPost, path, body, &out); err != nil {
+		return nil, err
+	}
+	return out.ModifiedLabels, nil
+}
+
+// Apply puts label labelID on fileID, setting the given fields. Applying a
+// label that is already present only changes the given fields.
+func Apply(ctx context.Context, c *drive.Client, fileID, labelID string, fields ...FieldSet) ([]Applied, error) {
+	if labelID == "" {
+		return nil, errors.New("label ID is required")
+	}
+	mod := map[string]interface{}{"labelId": labelID}
+	if len(fields) > 0 {
+		mods := make([]interface{}, len(fields))
+		for i, f := range fields {
+			mods[i] = f.modification()
+		}
+		mod["fieldModifications"] = mods
+	}
+	return modify(ctx, c, fileID, mod)
+}
+
+// Remove takes label labelID off fileID.
+func Remove(ctx context.Context, c *drive.Client, fileID, labelID string) error {
+	if labelID == "" {
+		return errors.New("label ID is required")
+	}
+	_, err := modify(ctx, c, fileID, map[string]interface{}{"labelId": labelID, "removeLabel": true})
+	return err
+}
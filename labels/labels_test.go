@@ -0,0 +1,91 @@
+package labels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func TestLabels(t *testing.T) {
+	var modified map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/labels":
+			w.Write([]byte(`{"labels":[{"id":"lbl1","properties":{"title":"Controlled Document"},"fields":[
+				{"id":"fld1","properties":{"displayName":"Status"},"selectionOptions":{"choices":[
+					{"id":"ch1","properties":{"displayName":"Approved"}}]}},
+				{"id":"fld2","properties":{"displayName":"Version"}}]}]}`))
+		case "/drive/v3/files/f/modifyLabels":
+			json.NewDecoder(r.Body).Decode(&modified)
+			w.Write([]byte(`{"modifiedLabels":[{"id":"lbl1"}]}`))
+		case "/drive/v3/files/f/listLabels":
+			w.Write([]byte(`{"labels":[{"id":"lbl1","fields":{"fld1":{"valueType":"selection","selection":["ch1"]}}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	ctx := context.Background()
+
+	def, err := Find(ctx, c, "controlled document")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	status, ok := def.Field("Status")
+	if !ok {
+		t.Fatal("Status field not found")
+	}
+	approved, ok := status.Choice("approved")
+	if !ok || approved != "ch1" {
+		t.Fatalf("Choice = %q, %v", approved, ok)
+	}
+	if _, err := Find(ctx, c, "Secret"); !errors.Is(err, drive.ErrNotFound) {
+		t.Fatalf("Find missing = %v", err)
+	}
+
+	_, err = Apply(ctx, c, "f", def.ID,
+		FieldSet{FieldID: status.ID, Selection: []string{approved}},
+		FieldSet{FieldID: "fld2", Integer: []int64{3}})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	mod := modified["labelModifications"].([]interface{})[0].(map[string]interface{})
+	fields := mod["fieldModifications"].([]interface{})
+	if mod["labelId"] != "lbl1" || fields[0].(map[string]interface{})["setSelectionValues"].([]interface{})[0] != "ch1" || fields[1].(map[string]interface{})["setIntegerValues"].([]interface{})[0] != "3" {
+		t.Fatalf("modifyLabels body = %v", modified)
+	}
+
+	if err := Remove(ctx, c, "f", "lbl1"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if mod := modified["labelModifications"].([]interface{})[0].(map[string]interface{}); mod["removeLabel"] != true {
+		t.Fatalf("remove body = %v", modified)
+	}
+
+	applied, err := List(ctx, c, "f")
+	if err != nil || len(applied) != 1 || applied[0].Fields["fld1"].Selection[0] != "ch1" {
+		t.Fatalf("List = %+v, %v", applied, err)
+	}
+}
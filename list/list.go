@@ -0,0 +1,185 @@
+// Package list reads file listings from Drive, following pagination
+// transparently.
+package list
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// errStop ends a ForEach early without reporting an error.
+var errStop = errors.New("stop listing")
+
+// Options selects which files are listed.
+type Options struct {
+	// Query is a Drive search expression (the q parameter); empty lists
+	// every file the caller can see.
+	Query string
+	// Fields is the per-file field selection; empty means drive.FileFields.
+	Fields string
+	// DriveID restricts the listing to one shared drive.
+	DriveID string
+	// Spaces selects "drive" (the default) or "appDataFolder", the hidden
+	// per-application folder.
+	Spaces string
+	// OrderBy is a comma-separated list of sort keys, each optionally
+	// followed by "desc", e.g. "folder,name" or "modifiedTime desc". Valid
+	// keys are createdTime, folder, modifiedByMeTime, modifiedTime, name,
+	// name_natural, quotaBytesUsed, recency, sharedWithMeTime, starred, and
+	// viewedByMeTime. Drive rejects ordering on fullText queries.
+	OrderBy string
+	// PageSize is how many files are fetched per request, up to
+	// MaxPageSize; 0 lets Drive choose (currently 100).
+	PageSize int
+	// Limit stops the listing after this many files; 0 means no limit.
+	Limit int
+}
+
+// MaxPageSize is the largest page Drive returns.
+const MaxPageSize = 1000
+
+var orderKeys = map[string]bool{
+	"createdTime": true, "folder": true, "modifiedByMeTime": true, "modifiedTime": true,
+	"name": true, "name_natural": true, "quotaBytesUsed": true, "recency": true,
+	"sharedWithMeTime": true, "starred": true, "viewedByMeTime": true,
+}
+
+func (o Options) validate() error {
+	if o.PageSize < 0 || o.PageSize > MaxPageSize {
+		return fmt.Errorf("page size must be between 1 and %d", MaxPageSize)
+	}
+	if o.OrderBy == "" {
+		return nil
+	}
+	for _, term := range strings.Split(o.OrderBy, ",") {
+		fields := strings.Fields(term)
+		if len(fields) == 0 || len(fields) > 2 || !orderKeys[fields[0]] || (len(fields) == 2 && fields[1] != "desc") {
+			return fmt.Errorf("invalid orderBy term %q", strings.TrimSpace(term))
+		}
+	}
+	return nil
+}
+
+func (o Options) values() url.Values {
+	fields := o.Fields
+	if fields == "" {
+		fields = drive.FileFields
+	}
+	v := url.Values{
+		"fields":                    {"nextPageToken,files(" + fields + ")"},
+		"supportsAllDrives":         {"true"},
+		"includeItemsFromAllDrives": {"true"},
+	}
+	if o.Query != "" {
+		v.Set("q", o.Query)
+	}
+	if o.DriveID != "" {
+		v.Set("driveId", o.DriveID)
+		v.Set("corpora", "drive")
+	}
+	if o.Spaces != "" {
+		v.Set("spaces", o.Spaces)
+	}
+	if o.OrderBy != "" {
+		v.Set("orderBy", o.OrderBy)
+	}
+	size := o.PageSize
+	if o.Limit > 0 && o.Limit < MaxPageSize && (size == 0 || o.Limit < size) {
+		size = o.Limit
+	}
+	if size > 0 {
+		v.Set("pageSize", strconv.Itoa(size))
+	}
+	return v
+}
+
+// ListFiles returns every file matching opts, fetching as many pages as needed.
+func ListFiles(ctx context.Context, c *drive.Client, opts Options) ([]drive.File, error) {
+	var all []drive.File
+	err := ForEach(ctx, c, opts, func(f drive.File) error {
+		all = append(all, f)
+		return nil
+	})
+	return all, err
+}
+
+// ForEach calls fn for every file matching opts, one page at a time, so
+// large listings are never held in memory at once. An error from fn stops
+// the listing and is returned.
+func ForEach(ctx context.Context, c *drive.Client, opts Options, fn func(drive.File) error) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	q := opts.values()
+	seen := 0
+	for {
+		var page struct {
+			NextPageToken string       `json:"nextPageToken"`
+			Files         []drive.File `json:"files"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, "files?"+q.Encode(), nil, &page); err != nil {
+			return fmt.Errorf("list files: %w", err)
+		}
+		for _, f := range page.Files {
+			if err := fn(f); err != nil {
+				if err == errStop {
+					return nil
+				}
+				return err
+			}
+			seen++
+			if opts.Limit > 0 && seen >= opts.Limit {
+				return nil
+			}
+		}
+		if page.NextPageToken == "" {
+			return nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// First returns the first file matching opts, or drive.ErrNotFound.
+func First(ctx context.Context, c *drive.Client, opts Options) (drive.File, error) {
+	var found *drive.File
+	opts.Limit = 1
+	err := ForEach(ctx, c, opts, func(f drive.File) error {
+		found = &f
+		return errStop
+	})
+	if err != nil {
+		return drive.File{}, err
+	}
+	if found == nil {
+		return drive.File{}, drive.ErrNotFound
+	}
+	return *found, nil
+}
+
+// Children lists the untrashed direct children of folderID.
+func Children(ctx context.Context, c *drive.Client, folderID string) ([]drive.File, error) {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return nil, err
+	}
+	return ListFiles(ctx, c, Options{Query: query.New().InParent(folderID).NotTrashed().String()})
+}
+
+// ListTrashed returns trashed files matching opts, so they can be reviewed
+// before the trash is emptied. opts.Query further narrows the result.
+func ListTrashed(ctx context.Context, c *drive.Client, opts Options) ([]drive.File, error) {
+	q := query.New().Trashed()
+	if opts.Query != "" {
+		q = q.Raw("(" + opts.Query + ")")
+	}
+	opts.Query = q.String()
+	return ListFiles(ctx, c, opts)
+}
@@ -0,0 +1,146 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func TestListFiles_Pagination(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		q := r.URL.Query()
+		if q.Get("q") != "'p' in parents" || q.Get("fields") != "nextPageToken,files("+drive.FileFields+")" {
+			http.Error(w, "bad query: "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		if q.Get("pageToken") == "" {
+			w.Write([]byte(`{"nextPageToken":"t2","files":[{"id":"a","name":"a.pdf","mimeType":"application/pdf","size":"42","md5Checksum":"abc","modifiedTime":"2025-01-02T03:04:05Z","parents":["p"],"webViewLink":"https://v/a"}]}`))
+			return
+		}
+		w.Write([]byte(`{"files":[{"id":"b","name":"b","mimeType":"application/vnd.google-apps.folder"}]}`))
+	}))
+	defer srv.Close()
+
+	files, err := ListFiles(context.Background(), testClient(srv), Options{Query: "'p' in parents"})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if len(files) != 2 || requests != 2 {
+		t.Fatalf("got %d files in %d requests", len(files), requests)
+	}
+	a := files[0]
+	if a.ID != "a" || a.Size != 42 || a.MD5 != "abc" || a.Parents[0] != "p" || a.WebViewLink != "https://v/a" {
+		t.Fatalf("unexpected file: %+v", a)
+	}
+	if !a.ModifiedTime.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("ModifiedTime = %v", a.ModifiedTime)
+	}
+	if a.IsFolder() || !files[1].IsFolder() {
+		t.Fatal("IsFolder mismatch")
+	}
+}
+
+func TestFirst(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "none" {
+			w.Write([]byte(`{"files":[]}`))
+			return
+		}
+		w.Write([]byte(`{"nextPageToken":"more","files":[{"id":"x"},{"id":"y"}]}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	f, err := First(context.Background(), c, Options{Query: "some"})
+	if err != nil || f.ID != "x" {
+		t.Fatalf("First = %+v, %v", f, err)
+	}
+	if _, err := First(context.Background(), c, Options{Query: "none"}); !errors.Is(err, drive.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestListFiles_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"nope"}}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+	if _, err := ListFiles(context.Background(), testClient(srv), Options{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestListFiles_OrderAndPageSize(t *testing.T) {
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Write([]byte(`{"files":[]}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+
+	if _, err := ListFiles(context.Background(), c, Options{OrderBy: "folder,modifiedTime desc", PageSize: 500}); err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if got.Get("orderBy") != "folder,modifiedTime desc" || got.Get("pageSize") != "500" {
+		t.Fatalf("unexpected query: %v", got)
+	}
+
+	if _, err := ListFiles(context.Background(), c, Options{PageSize: 500, Limit: 20}); err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if got.Get("pageSize") != "20" {
+		t.Fatalf("pageSize = %s; want the smaller limit", got.Get("pageSize"))
+	}
+
+	for _, bad := range []Options{{PageSize: MaxPageSize + 1}, {OrderBy: "size"}, {OrderBy: "name asc"}, {OrderBy: "name,"}} {
+		if _, err := ListFiles(context.Background(), c, bad); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestListTrashed(t *testing.T) {
+	var gotQ string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQ = r.URL.Query().Get("q")
+		w.Write([]byte(`{"files":[{"id":"t","trashed":true}]}`))
+	}))
+	defer srv.Close()
+
+	files, err := ListTrashed(context.Background(), testClient(srv), Options{Query: "'p' in parents or name = 'x'"})
+	if err != nil || len(files) != 1 || !files[0].Trashed {
+		t.Fatalf("ListTrashed = %v, %v", files, err)
+	}
+	if gotQ != "trashed = true and ('p' in parents or name = 'x')" {
+		t.Fatalf("q = %s", gotQ)
+	}
+}
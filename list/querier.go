@@ -0,0 +1,34 @@
+package list
+
+import (
+	"context"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Querier finds files: by search, as ListFiles and First do, or by ID.
+// Client implements it over a drive.Client; accept it instead of a
+// *drive.Client to test code that looks files up with a fake, such as the
+// one in drivemock.
+type Querier interface {
+	ListFiles(ctx context.Context, opts Options) ([]drive.File, error)
+	First(ctx context.Context, opts Options) (drive.File, error)
+	GetFile(ctx context.Context, fileID string, fields ...string) (drive.File, error)
+}
+
+// Client is the Querier of a drive.Client.
+type Client struct {
+	*drive.Client
+}
+
+var _ Querier = Client{}
+
+// ListFiles is the package's ListFiles with c.
+func (c Client) ListFiles(ctx context.Context, opts Options) ([]drive.File, error) {
+	return ListFiles(ctx, c.Client, opts)
+}
+
+// First is the package's First with c.
+func (c Client) First(ctx context.Context, opts Options) (drive.File, error) {
+	return First(ctx, c.Client, opts)
+}
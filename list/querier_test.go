@@ -0,0 +1,30 @@
+package list
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+func TestClient(t *testing.T) {
+	srv := drivetest.NewServer()
+	defer srv.Close()
+	pub := srv.AddFolder("Published", "")
+	srv.Add(drive.File{ID: "a", Name: "a.pdf", Parents: []string{pub}}, []byte("a"))
+	var q Querier = Client{srv.Client()}
+	ctx := context.Background()
+
+	files, err := q.ListFiles(ctx, Options{Query: query.New().InParent(pub).String()})
+	if err != nil || len(files) != 1 || files[0].ID != "a" {
+		t.Fatalf("ListFiles = %+v, %v", files, err)
+	}
+	if f, err := q.First(ctx, Options{Query: query.New().NameEquals("a.pdf").String()}); err != nil || f.ID != "a" {
+		t.Fatalf("First = %+v, %v", f, err)
+	}
+	if f, err := q.GetFile(ctx, "a", "id", "name"); err != nil || f.Name != "a.pdf" {
+		t.Fatalf("GetFile = %+v, %v", f, err)
+	}
+}
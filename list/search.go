@@ -0,0 +1,120 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// searchBatch bounds how many parent folders go into one query so the q
+// parameter stays well below Drive's length limit.
+const searchBatch = 40
+
+// SearchOptions narrows a full-text search.
+type SearchOptions struct {
+	// MimeTypes matches any of the given types; empty matches all.
+	MimeTypes []string
+	// Owner is the email address of the owner.
+	Owner string
+	// ModifiedAfter, if non-zero, excludes files modified at or before it.
+	ModifiedAfter time.Time
+	// Under restricts results to files anywhere below this folder.
+	Under string
+	// PageSize is passed through to Options.PageSize. Drive does not allow
+	// ordering full-text results, so there is no OrderBy.
+	PageSize int
+	// Limit stops the search after this many files; 0 means no limit.
+	Limit int
+}
+
+// Search returns untrashed files whose name, description, or content
+// contains text, filtered by opts. Drive cannot search a subtree directly,
+// so when opts.Under is set its folders are enumerated first and searched
+// in batches.
+func Search(ctx context.Context, c *drive.Client, text string, opts SearchOptions) ([]drive.File, error) {
+	if text == "" {
+		return nil, errors.New("search text is required")
+	}
+	base := query.New().FullText(text).NotTrashed()
+	if len(opts.MimeTypes) > 0 {
+		var alts []query.Builder
+		for _, mt := range opts.MimeTypes {
+			alts = append(alts, query.New().MimeType(mt))
+		}
+		base = base.Or(alts...)
+	}
+	if opts.Owner != "" {
+		base = base.Owner(opts.Owner)
+	}
+	if !opts.ModifiedAfter.IsZero() {
+		base = base.ModifiedAfter(opts.ModifiedAfter)
+	}
+	if opts.Under == "" {
+		return ListFiles(ctx, c, Options{Query: base.String(), PageSize: opts.PageSize, Limit: opts.Limit})
+	}
+
+	root, err := drive.ParseID(opts.Under)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := subfolders(ctx, c, root)
+	if err != nil {
+		return nil, err
+	}
+	var found []drive.File
+	seen := map[string]bool{}
+	for start := 0; start < len(folders); start += searchBatch {
+		end := start + searchBatch
+		if end > len(folders) {
+			end = len(folders)
+		}
+		var parents []query.Builder
+		for _, id := range folders[start:end] {
+			parents = append(parents, query.New().InParent(id))
+		}
+		opt := Options{Query: base.Or(parents...).String(), PageSize: opts.PageSize}
+		if opts.Limit > 0 {
+			opt.Limit = opts.Limit - len(found)
+		}
+		batch, err := ListFiles(ctx, c, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range batch {
+			// files with several parents can match more than one batch
+			if !seen[f.ID] {
+				seen[f.ID] = true
+				found = append(found, f)
+			}
+		}
+		if opts.Limit > 0 && len(found) >= opts.Limit {
+			return found[:opts.Limit], nil
+		}
+	}
+	return found, nil
+}
+
+// subfolders returns root and the IDs of every folder below it, breadth first.
+func subfolders(ctx context.Context, c *drive.Client, root string) ([]string, error) {
+	all := []string{root}
+	seen := map[string]bool{root: true}
+	for i := 0; i < len(all); i++ {
+		err := ForEach(ctx, c, Options{
+			Query:  query.New().InParent(all[i]).Folders().NotTrashed().String(),
+			Fields: "id",
+		}, func(f drive.File) error {
+			if !seen[f.ID] {
+				seen[f.ID] = true
+				all = append(all, f.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return all, nil
+}
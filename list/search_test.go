@@ -0,0 +1,62 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch_Filters(t *testing.T) {
+	var gotQ string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQ = r.URL.Query().Get("q")
+		w.Write([]byte(`{"files":[{"id":"hit"}]}`))
+	}))
+	defer srv.Close()
+
+	files, err := Search(context.Background(), testClient(srv), "reagent X", SearchOptions{
+		MimeTypes:     []string{"application/pdf", "application/vnd.google-apps.document"},
+		Owner:         "qa@example.com",
+		ModifiedAfter: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Search = %v, %v", files, err)
+	}
+	want := `fullText contains 'reagent X' and trashed = false and ((mimeType = 'application/pdf') or (mimeType = 'application/vnd.google-apps.document')) and 'qa@example.com' in owners and modifiedTime > '2025-01-01T00:00:00Z'`
+	if gotQ != want {
+		t.Fatalf("q = %s\nwant %s", gotQ, want)
+	}
+}
+
+func TestSearch_Under(t *testing.T) {
+	var searches []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch {
+		case strings.HasPrefix(q, "'top' in parents and mimeType = 'application/vnd.google-apps.folder'"):
+			w.Write([]byte(`{"files":[{"id":"sub"}]}`))
+		case strings.Contains(q, "mimeType = 'application/vnd.google-apps.folder'"):
+			w.Write([]byte(`{"files":[]}`))
+		case strings.HasPrefix(q, "fullText contains"):
+			searches = append(searches, q)
+			w.Write([]byte(`{"files":[{"id":"a"},{"id":"b"}]}`))
+		default:
+			http.Error(w, "unexpected "+q, http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	files, err := Search(context.Background(), testClient(srv), "sop", SearchOptions{Under: "top"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(files) != 2 || len(searches) != 1 {
+		t.Fatalf("got %d files from %d searches", len(files), len(searches))
+	}
+	if !strings.HasSuffix(searches[0], "(('top' in parents) or ('sub' in parents))") {
+		t.Fatalf("search not restricted to subtree: %s", searches[0])
+	}
+}
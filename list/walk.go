@@ -0,0 +1,71 @@
+package list
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// SkipDir can be returned by a WalkFunc to skip the item it was called
+// for: Walk does not descend into a folder, or follow a shortcut to one,
+// and goes on with the next item either way.
+var SkipDir = errors.New("skip this folder")
+
+// WalkFunc is called by Walk for every file and folder. path is the
+// slash-joined chain of names from the root folder down to f; Drive names
+// may themselves contain slashes, so path is for display and matching only.
+type WalkFunc func(path string, f drive.File) error
+
+// Walk traverses the tree below folderID depth-first, calling fn for each
+// untrashed item before descending into it. Shortcuts to folders are
+// followed like folders. Every folder is visited at most once, so shortcut
+// loops and folders with several parents cannot cause infinite recursion.
+func Walk(ctx context.Context, c *drive.Client, folderID string, fn WalkFunc) error {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return err
+	}
+	visited := map[string]bool{folderID: true}
+	return walk(ctx, c, folderID, "", visited, fn)
+}
+
+func walk(ctx context.Context, c *drive.Client, folderID, prefix string, visited map[string]bool, fn WalkFunc) error {
+	children, err := ListFiles(ctx, c, Options{Query: query.New().InParent(folderID).NotTrashed().String()})
+	if err != nil {
+		return err
+	}
+	for _, f := range children {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := prefix + f.Name
+		target := folderTarget(f)
+		if err := fn(p, f); err == SkipDir {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if target == "" || visited[target] {
+			continue
+		}
+		visited[target] = true
+		if err := walk(ctx, c, target, p+"/", visited, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// folderTarget returns the folder ID to descend into for f, or "" if f is
+// neither a folder nor a shortcut to one.
+func folderTarget(f drive.File) string {
+	switch {
+	case f.IsFolder():
+		return f.ID
+	case f.IsFolderShortcut():
+		return f.ShortcutDetails.TargetID
+	}
+	return ""
+}
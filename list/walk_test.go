@@ -0,0 +1,98 @@
+package list
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// treeServer serves listings for a fixed folder tree keyed by parent ID.
+func treeServer(t *testing.T, tree map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		for parent, body := range tree {
+			if strings.HasPrefix(q, "'"+parent+"' in parents") {
+				w.Write([]byte(body))
+				return
+			}
+		}
+		w.Write([]byte(`{"files":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWalk(t *testing.T) {
+	srv := treeServer(t, map[string]string{
+		"root": `{"files":[
+			{"id":"a","name":"A","mimeType":"application/vnd.google-apps.folder"},
+			{"id":"f1","name":"one.pdf","mimeType":"application/pdf"},
+			{"id":"s1","name":"loop","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"root","targetMimeType":"application/vnd.google-apps.folder"}},
+			{"id":"s2","name":"ext","mimeType":"application/vnd.google-apps.shortcut","shortcutDetails":{"targetId":"ext","targetMimeType":"application/vnd.google-apps.folder"}}]}`,
+		"a":   `{"files":[{"id":"f2","name":"two.pdf","mimeType":"application/pdf"}]}`,
+		"ext": `{"files":[{"id":"f3","name":"three.pdf","mimeType":"application/pdf"}]}`,
+	})
+
+	var got []string
+	err := Walk(context.Background(), testClient(srv), "root", func(path string, f drive.File) error {
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want := "A,A/two.pdf,one.pdf,loop,ext,ext/three.pdf"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("visited %v; want %s", got, want)
+	}
+}
+
+func TestWalk_SkipDirAndError(t *testing.T) {
+	srv := treeServer(t, map[string]string{
+		"root": `{"files":[
+			{"id":"a","name":"A","mimeType":"application/vnd.google-apps.folder"},
+			{"id":"f1","name":"one.pdf","mimeType":"application/pdf"}]}`,
+		"a": `{"files":[{"id":"f2","name":"two.pdf","mimeType":"application/pdf"}]}`,
+	})
+	c := testClient(srv)
+
+	var got []string
+	err := Walk(context.Background(), c, "root", func(path string, f drive.File) error {
+		got = append(got, path)
+		if f.IsFolder() {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "A,one.pdf" {
+		t.Fatalf("visited %v, err %v", got, err)
+	}
+
+	// For a file, it skips only that file.
+	fileFirst := treeServer(t, map[string]string{
+		"root": `{"files":[
+			{"id":"f1","name":"one.pdf","mimeType":"application/pdf"},
+			{"id":"a","name":"A","mimeType":"application/vnd.google-apps.folder"}]}`,
+		"a": `{"files":[]}`,
+	})
+	got = nil
+	err = Walk(context.Background(), testClient(fileFirst), "root", func(path string, f drive.File) error {
+		got = append(got, path)
+		return SkipDir
+	})
+	if err != nil || strings.Join(got, ",") != "one.pdf,A" {
+		t.Fatalf("skipping everything visited %v, err %v", got, err)
+	}
+
+	boom := errors.New("boom")
+	err = Walk(context.Background(), c, "root", func(string, drive.File) error { return boom })
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+}
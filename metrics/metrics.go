@@ -0,0 +1,193 @@
+// Package metrics counts the Drive requests, transferred bytes, retries, and
+// deploys of a long-running process and serves them in the Prometheus text
+// format, so that modes such as serve and watch can be scraped.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeployBuckets are the upper bounds, in seconds, of the deploy duration
+// histogram.
+var DeployBuckets = []float64{1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Collector accumulates metrics. Its methods are safe for concurrent use,
+// and it is an http.Handler serving what it has collected.
+type Collector struct {
+	mu         sync.Mutex
+	requests   map[request]uint64
+	uploaded   uint64
+	downloaded uint64
+	retries    uint64
+	deploys    map[string]*histogram // by result
+}
+
+type request struct{ method, status string }
+
+type histogram struct {
+	counts []uint64 // per bucket of DeployBuckets, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// New returns an empty Collector.
+func New() *Collector {
+	return &Collector{requests: map[request]uint64{}, deploys: map[string]*histogram{}}
+}
+
+// Transport returns a RoundTripper that sends requests through base, or
+// http.DefaultTransport if nil, counting each by method and status code,
+// the content sent to the upload API, and the content of downloads and
+// exports.
+func (c *Collector) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{c: c, base: base}
+}
+
+type transport struct {
+	c    *Collector
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && strings.HasPrefix(req.URL.Path, "/upload/") {
+		req = req.Clone(req.Context())
+		req.Body = &countingReader{ReadCloser: req.Body, n: &t.c.uploaded, mu: &t.c.mu}
+	}
+	resp, err := t.base.RoundTrip(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode/100 == 2 && (req.URL.Query().Get("alt") == "media" || strings.HasSuffix(req.URL.Path, "/export")) {
+			resp.Body = &countingReader{ReadCloser: resp.Body, n: &t.c.downloaded, mu: &t.c.mu}
+		}
+	}
+	t.c.mu.Lock()
+	t.c.requests[request{req.Method, status}]++
+	t.c.mu.Unlock()
+	return resp, err
+}
+
+type countingReader struct {
+	io.ReadCloser
+	mu *sync.Mutex
+	n  *uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.mu.Lock()
+	*r.n += uint64(n)
+	r.mu.Unlock()
+	return n, err
+}
+
+// Requests returns how many requests went through a Transport of c, and
+// how many repeats RecordRetry counted.
+func (c *Collector) Requests() (sent, retried uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.requests {
+		sent += n
+	}
+	return sent, c.retries
+}
+
+// RecordRetry counts a request repeated after a failure.
+func (c *Collector) RecordRetry() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries++
+}
+
+// ObserveDeploy records a deploy of file that took took and failed with
+// err, if not nil. It satisfies the deploy.Metrics hook.
+func (c *Collector) ObserveDeploy(file string, took time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.deploys[result]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(DeployBuckets))}
+		c.deploys[result] = h
+	}
+	secs := took.Seconds()
+	if i, _ := slices.BinarySearch(DeployBuckets, secs); i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += secs
+	h.count++
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	metric(cw, "gdrivetoolbox_requests_total", "counter", "Drive API requests by method and status code.")
+	reqs := slices.SortedFunc(maps.Keys(c.requests), func(a, b request) int {
+		return strings.Compare(a.method+" "+a.status, b.method+" "+b.status)
+	})
+	for _, r := range reqs {
+		fmt.Fprintf(cw, "gdrivetoolbox_requests_total{method=%q,status=%q} %d\n", r.method, r.status, c.requests[r])
+	}
+	metric(cw, "gdrivetoolbox_uploaded_bytes_total", "counter", "Bytes of content sent to Drive.")
+	fmt.Fprintf(cw, "gdrivetoolbox_uploaded_bytes_total %d\n", c.uploaded)
+	metric(cw, "gdrivetoolbox_downloaded_bytes_total", "counter", "Bytes of content downloaded or exported from Drive.")
+	fmt.Fprintf(cw, "gdrivetoolbox_downloaded_bytes_total %d\n", c.downloaded)
+	metric(cw, "gdrivetoolbox_retries_total", "counter", "Requests repeated after a failure.")
+	fmt.Fprintf(cw, "gdrivetoolbox_retries_total %d\n", c.retries)
+
+	metric(cw, "gdrivetoolbox_deploy_duration_seconds", "histogram", "Time taken by each deploy of a PDF, by result.")
+	for _, result := range slices.Sorted(maps.Keys(c.deploys)) {
+		h := c.deploys[result]
+		var cum uint64
+		for i, le := range DeployBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(cw, "gdrivetoolbox_deploy_duration_seconds_bucket{result=%q,le=%q} %d\n", result, strconv.FormatFloat(le, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(cw, "gdrivetoolbox_deploy_duration_seconds_bucket{result=%q,le=\"+Inf\"} %d\n", result, h.count)
+		fmt.Fprintf(cw, "gdrivetoolbox_deploy_duration_seconds_sum{result=%q} %s\n", result, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(cw, "gdrivetoolbox_deploy_duration_seconds_count{result=%q} %d\n", result, h.count)
+	}
+	if err := cw.w.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+func metric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	c := New()
+	hc := &http.Client{Transport: c.Transport(nil)}
+	for _, req := range []struct{ method, path, body string }{
+		{"POST", "/upload/drive/v3/files", "hello"},
+		{"GET", "/drive/v3/files/x?alt=media", ""},
+		{"GET", "/drive/v3/files/x/export?mimeType=text/plain", ""},
+		{"GET", "/drive/v3/files/x", ""},
+		{"GET", "/missing", ""},
+	} {
+		r, _ := http.NewRequest(req.method, srv.URL+req.path, strings.NewReader(req.body))
+		resp, err := hc.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	c.RecordRetry()
+	c.ObserveDeploy("a.pdf", 3*time.Second, nil)
+	c.ObserveDeploy("b.pdf", 500*time.Second, nil)
+	c.ObserveDeploy("c.pdf", time.Second, errors.New("boom"))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	got := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE gdrivetoolbox_requests_total counter\n",
+		`gdrivetoolbox_requests_total{method="GET",status="200"} 3` + "\n",
+		`gdrivetoolbox_requests_total{method="GET",status="404"} 1` + "\n",
+		`gdrivetoolbox_requests_total{method="POST",status="200"} 1` + "\n",
+		"gdrivetoolbox_uploaded_bytes_total 5\n",
+		"gdrivetoolbox_downloaded_bytes_total 20\n",
+		"gdrivetoolbox_retries_total 1\n",
+		"# TYPE gdrivetoolbox_deploy_duration_seconds histogram\n",
+		`gdrivetoolbox_deploy_duration_seconds_bucket{result="error",le="1"} 1` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_bucket{result="ok",le="2.5"} 0` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_bucket{result="ok",le="5"} 1` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_bucket{result="ok",le="300"} 1` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_bucket{result="ok",le="+Inf"} 2` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_sum{result="ok"} 503` + "\n",
+		`gdrivetoolbox_deploy_duration_seconds_count{result="ok"} 2` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
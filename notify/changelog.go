@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// DocsBase is the root of the Google Docs v1 API.
+const DocsBase = "https://docs.googleapis.com/v1"
+
+// DocumentMimeType identifies Google Docs in Drive.
+const DocumentMimeType = "application/vnd.google-apps.document"
+
+// Changelog keeps release notes next to the deployed documents: a line for
+// every version deployed or rolled back, newest first, with the document,
+// the version it replaced and the new one, the UTC date, and the deployer.
+// The notes are a Google Doc, or a text file if Name ends in .txt, created
+// on the first change of each folder.
+type Changelog struct {
+	Client *drive.Client
+	// FolderID holds the notes; empty means the folder of each change.
+	FolderID string
+	// Name is the notes' file name; empty means "Release notes".
+	Name string
+	// Deployer, if set, is recorded instead of the email address of the
+	// client's account.
+	Deployer string
+	// Now returns the time recorded; nil means time.Now.
+	Now func() time.Time
+	// OnError, if set, receives the errors of NotifyDeploy.
+	OnError func(error)
+
+	once sync.Once
+	mu   sync.Mutex // one change at a time, as each rewrites the notes
+}
+
+// Entry returns the line of the notes for r.
+func (l *Changelog) Entry(r deploy.Result, at time.Time) string {
+	change := r.Version + " (first version)"
+	if r.Previous != "" {
+		change = r.Previous + " → " + r.Version
+	}
+	if r.Status == deploy.StatusRolledBack {
+		change += " (rolled back)"
+	}
+	line := at.UTC().Format("2006-01-02 15:04 UTC") + "  " + r.File + ": " + change
+	if l.Deployer != "" {
+		line += ", by " + l.Deployer
+	}
+	return line + "\n"
+}
+
+// Add puts entry at the top of the notes in folderID, creating them if
+// there are none.
+func (l *Changelog) Add(ctx context.Context, folderID, entry string) error {
+	folderID, err := drive.ParseID(folderID)
+	if err != nil {
+		return err
+	}
+	name := l.Name
+	if name == "" {
+		name = "Release notes"
+	}
+	doc := !strings.HasSuffix(name, ".txt")
+	q := query.New().InParent(folderID).NameEquals(name).NotTrashed()
+	if doc {
+		q = q.MimeType(DocumentMimeType)
+	}
+	notes, err := list.First(ctx, l.Client, list.Options{Query: q.String(), Fields: "id"})
+	switch {
+	case errors.Is(err, drive.ErrNotFound) && doc:
+		meta := drive.Metadata{Name: name, MimeType: DocumentMimeType, Parents: []string{folderID}}
+		if err := l.Client.DoJSON(ctx, http.MethodPost, "files?supportsAllDrives=true&fields=id", meta, &notes); err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+	case errors.Is(err, drive.ErrNotFound):
+		meta := drive.Metadata{Name: name, MimeType: "text/plain", Parents: []string{folderID}}
+		if _, err := l.Client.Upload(ctx, meta, strings.NewReader(entry), int64(len(entry))); err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("find %s: %w", name, err)
+	}
+
+	if doc {
+		insert := map[string]any{"requests": []any{map[string]any{
+			"insertText": map[string]any{"location": map[string]int{"index": 1}, "text": entry},
+		}}}
+		return l.Client.DoJSON(ctx, http.MethodPost, DocsBase+"/documents/"+url.PathEscape(notes.ID)+":batchUpdate", insert, nil)
+	}
+	var content bytes.Buffer
+	content.WriteString(entry)
+	if _, err := l.Client.Download(ctx, notes.ID, &content); err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+	if _, err := l.Client.UpdateContent(ctx, notes.ID, &content, int64(content.Len())); err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	return nil
+}
+
+// NotifyDeploy adds an entry for r if it deployed or rolled back a
+// version. It satisfies the deploy.Notifier hook.
+func (l *Changelog) NotifyDeploy(r deploy.Result) {
+	if r.Status != deploy.StatusDeployed && r.Status != deploy.StatusRolledBack {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	l.once.Do(func() {
+		if l.Deployer != "" {
+			return
+		}
+		about, err := l.Client.GetAbout(ctx)
+		if err != nil {
+			l.fail(err)
+			return
+		}
+		l.Deployer = about.User.EmailAddress
+	})
+	now := time.Now
+	if l.Now != nil {
+		now = l.Now
+	}
+	folderID := l.FolderID
+	if folderID == "" {
+		folderID = r.FolderID
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.Add(ctx, folderID, l.Entry(r, now())); err != nil {
+		l.fail(err)
+	}
+}
+
+func (l *Changelog) fail(err error) {
+	if l.OnError != nil {
+		l.OnError(err)
+	}
+}
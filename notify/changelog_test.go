@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestChangelog(t *testing.T) {
+	// The fake Drive keeps one Google Doc and one text file of notes, by
+	// name, with their parent.
+	type notes struct{ id, folder, text string }
+	files := map[string]*notes{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/drive/v3/about":
+			w.Write([]byte(`{"user":{"emailAddress":"ada@example.com"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			q := r.URL.Query().Get("q")
+			for name, n := range files {
+				if strings.Contains(q, "name = '"+name+"'") && strings.Contains(q, "'"+n.folder+"' in parents") {
+					json.NewEncoder(w).Encode(map[string]any{"files": []map[string]string{{"id": n.id}}})
+					return
+				}
+			}
+			w.Write([]byte(`{"files":[]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			var meta drive.Metadata
+			json.NewDecoder(r.Body).Decode(&meta)
+			if meta.MimeType != DocumentMimeType {
+				t.Errorf("created %+v", meta)
+			}
+			files[meta.Name] = &notes{id: "doc1", folder: meta.Parents[0]}
+			w.Write([]byte(`{"id":"doc1"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/documents/doc1:batchUpdate":
+			var body struct {
+				Requests []struct {
+					InsertText struct {
+						Location struct{ Index int }
+						Text     string
+					}
+				}
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			ins := body.Requests[0].InsertText
+			if ins.Location.Index != 1 {
+				t.Errorf("inserted at %d", ins.Location.Index)
+			}
+			files["Release notes"].text = ins.Text + files["Release notes"].text
+			w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			var meta drive.Metadata
+			part, _ := mr.NextPart()
+			json.NewDecoder(part).Decode(&meta)
+			part, _ = mr.NextPart()
+			content, _ := io.ReadAll(part)
+			files[meta.Name] = &notes{id: "txt1", folder: meta.Parents[0], text: string(content)}
+			w.Write([]byte(`{"id":"txt1"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files/txt1":
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, files["notes.txt"].text)
+		case r.Method == http.MethodPatch && r.URL.Path == "/upload/drive/v3/files/txt1":
+			content, _ := io.ReadAll(r.Body)
+			files["notes.txt"].text = string(content)
+			w.Write([]byte(`{"id":"txt1"}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+
+	var errs []error
+	at := time.Date(2024, 5, 2, 12, 14, 0, 0, time.FixedZone("", 7200))
+	l := &Changelog{Client: c, Now: func() time.Time { return at }, OnError: func(err error) { errs = append(errs, err) }}
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v1", FolderID: "pub", Status: deploy.StatusDeployed})
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FolderID: "pub", Status: deploy.StatusSkipped})
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", Previous: "v1", FolderID: "pub", Status: deploy.StatusFailed})
+	at = at.Add(24 * time.Hour)
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", Previous: "v1", FolderID: "pub", Status: deploy.StatusDeployed})
+	want := "2024-05-03 10:14 UTC  mydoc.pdf: v1 → v2, by ada@example.com\n" +
+		"2024-05-02 10:14 UTC  mydoc.pdf: v1 (first version), by ada@example.com\n"
+	if doc := files["Release notes"]; doc == nil || doc.folder != "pub" || doc.text != want || len(errs) > 0 {
+		t.Fatalf("doc = %+v, errors %v", doc, errs)
+	}
+
+	l = &Changelog{Client: c, FolderID: "https://drive.google.com/drive/folders/notesFolder", Name: "notes.txt", Deployer: "release-bot",
+		Now: l.Now, OnError: l.OnError}
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", Previous: "v1", FolderID: "pub", Status: deploy.StatusDeployed})
+	l.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v1", Previous: "v2", FolderID: "pub", Status: deploy.StatusRolledBack})
+	want = "2024-05-03 10:14 UTC  mydoc.pdf: v2 → v1 (rolled back), by release-bot\n" +
+		"2024-05-03 10:14 UTC  mydoc.pdf: v1 → v2, by release-bot\n"
+	if txt := files["notes.txt"]; txt == nil || txt.folder != "notesFolder" || txt.text != want || len(errs) > 0 {
+		t.Fatalf("text file = %+v, errors %v", txt, errs)
+	}
+}
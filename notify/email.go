@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// GmailBase is the root of the Gmail v1 API.
+const GmailBase = "https://gmail.googleapis.com/gmail/v1"
+
+// DefaultEmailTemplate is the message Email sends unless given another.
+const DefaultEmailTemplate = `Subject: {{.File}} {{.Version}} {{if .RolledBack}}rolled back{{else}}published{{end}}{{with .Environment}} in {{.}}{{end}}
+
+{{if .RolledBack -}}
+{{.File}} was rolled back{{with .Previous}} from {{.}}{{end}} to {{.Version}}{{with .Environment}} in {{.}}{{end}}.
+{{- else -}}
+{{.File}} {{.Version}} was published{{with .Environment}} to {{.}}{{end}}{{with .Previous}}, replacing {{.}}{{end}}.
+{{- end}}
+
+{{.Link}}
+`
+
+// EmailData is what an email template is executed with.
+type EmailData struct {
+	deploy.Result
+	RolledBack  bool
+	Link        string
+	Environment string
+	Time        time.Time
+}
+
+// Email mails a distribution list through the Gmail API whenever a
+// document is published or rolled back, as the account of Client, which
+// needs the gmail.send scope besides Drive's.
+type Email struct {
+	Client *drive.Client
+	To     []string
+	// Template is a text/template of the message: headers, of which only
+	// Subject is used, a blank line, and the body, executed with an
+	// EmailData. Empty means DefaultEmailTemplate.
+	Template    string
+	Environment string
+	// OnError, if set, receives the errors of NotifyDeploy.
+	OnError func(error)
+}
+
+// Message renders the message for r, returning its subject and body.
+func (e *Email) Message(r deploy.Result) (subject, body string, err error) {
+	text := e.Template
+	if text == "" {
+		text = DefaultEmailTemplate
+	}
+	tmpl, err := template.New("email").Parse(text)
+	if err != nil {
+		return "", "", fmt.Errorf("email template: %w", err)
+	}
+	data := EmailData{Result: r, RolledBack: r.Status == deploy.StatusRolledBack, Environment: e.Environment, Time: time.Now().UTC()}
+	if r.FileID != "" {
+		data.Link = drive.ViewURL(r.FileID)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", "", fmt.Errorf("email template: %w", err)
+	}
+	msg, err := mail.ReadMessage(&buf)
+	if err != nil {
+		return "", "", fmt.Errorf("email template: %w", err)
+	}
+	b, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return msg.Header.Get("Subject"), string(b), nil
+}
+
+// Send mails the message for r to To.
+func (e *Email) Send(ctx context.Context, r deploy.Result) error {
+	if len(e.To) == 0 {
+		return fmt.Errorf("email: no recipients")
+	}
+	subject, body, err := e.Message(r)
+	if err != nil {
+		return err
+	}
+	var raw strings.Builder
+	fmt.Fprintf(&raw, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&raw, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	raw.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	raw.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	in := map[string]string{"raw": base64.URLEncoding.EncodeToString([]byte(raw.String()))}
+	if err := e.Client.DoJSON(ctx, http.MethodPost, GmailBase+"/users/me/messages/send", in, nil); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// NotifyDeploy mails r if it published or rolled back a document. It
+// satisfies the deploy.Notifier hook.
+func (e *Email) NotifyDeploy(r deploy.Result) {
+	if r.Status != deploy.StatusDeployed && r.Status != deploy.StatusRolledBack {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := e.Send(ctx, r); err != nil && e.OnError != nil {
+		e.OnError(err)
+	}
+}
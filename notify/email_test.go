@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestEmailMessage(t *testing.T) {
+	e := &Email{Environment: "production"}
+	for _, tc := range []struct {
+		r             deploy.Result
+		subject, body string
+	}{{
+		deploy.Result{File: "mydoc.pdf", Version: "v2", Previous: "v1", FileID: "1AbC", Status: deploy.StatusDeployed},
+		"mydoc.pdf v2 published in production",
+		"mydoc.pdf v2 was published to production, replacing v1.\n\nhttps://drive.google.com/file/d/1AbC/view\n",
+	}, {
+		deploy.Result{File: "mydoc.pdf", Version: "v1", Previous: "v2", FileID: "1DeF", Status: deploy.StatusRolledBack},
+		"mydoc.pdf v1 rolled back in production",
+		"mydoc.pdf was rolled back from v2 to v1 in production.\n\nhttps://drive.google.com/file/d/1DeF/view\n",
+	}} {
+		subject, body, err := e.Message(tc.r)
+		if err != nil || subject != tc.subject || body != tc.body {
+			t.Errorf("Message(%s) = %q, %q, %v\nwant %q, %q", tc.r.Status, subject, body, err, tc.subject, tc.body)
+		}
+	}
+
+	e.Template = "Subject: Released {{.File}}\nX-Ignored: yes\n\n{{.Version}} by the docs team"
+	if subject, body, err := e.Message(deploy.Result{File: "a.pdf", Version: "v9"}); err != nil || subject != "Released a.pdf" || body != "v9 by the docs team" {
+		t.Errorf("custom template: %q, %q, %v", subject, body, err)
+	}
+	e.Template = "Subject: {{.Nope}}\n\n"
+	if _, _, err := e.Message(deploy.Result{}); err == nil || !strings.Contains(err.Error(), "email template") {
+		t.Errorf("bad template: err = %v", err)
+	}
+}
+
+func TestEmailSend(t *testing.T) {
+	var raws []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/gmail/v1/users/me/messages/send" {
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+			return
+		}
+		var in struct{ Raw string }
+		json.NewDecoder(r.Body).Decode(&in)
+		b, _ := base64.URLEncoding.DecodeString(in.Raw)
+		raws = append(raws, string(b))
+		w.Write([]byte(`{"id":"m1"}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+
+	var errs []error
+	e := &Email{Client: c, To: []string{"qa@example.com", "docs@example.com"}, OnError: func(err error) { errs = append(errs, err) }}
+	e.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusSkipped})
+	e.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", Status: deploy.StatusFailed})
+	e.NotifyDeploy(deploy.Result{File: "Ünïcode.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusDeployed})
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(raws) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(raws))
+	}
+	want := "To: qa@example.com, docs@example.com\r\nSubject: =?utf-8?q?=C3=9Cn=C3=AFcode.pdf_v2_published?=\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n" +
+		"Ünïcode.pdf v2 was published.\r\n\r\nhttps://drive.google.com/file/d/1AbC/view\r\n"
+	if raws[0] != want {
+		t.Errorf("message:\n%q\nwant:\n%q", raws[0], want)
+	}
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// InGitHubActions reports whether the process runs as a GitHub Actions step.
+func InGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// GitHubActions reports deploys the way a GitHub Actions run shows them:
+// an annotation for each deploy, step outputs naming the last one, and a
+// table of all of them in the job summary.
+type GitHubActions struct {
+	// Out receives the workflow commands that annotate the run.
+	Out io.Writer
+	// OutputPath and SummaryPath are the files step outputs and the job
+	// summary are appended to; either may be empty to skip it.
+	OutputPath  string
+	SummaryPath string
+	// OnError, if set, receives the errors of NotifyDeploy.
+	OnError func(error)
+
+	mu      sync.Mutex
+	results []deploy.Result
+}
+
+// NewGitHubActions returns a GitHubActions writing annotations to out and
+// the rest to the files GitHub names in GITHUB_OUTPUT and
+// GITHUB_STEP_SUMMARY.
+func NewGitHubActions(out io.Writer) *GitHubActions {
+	return &GitHubActions{Out: out, OutputPath: os.Getenv("GITHUB_OUTPUT"), SummaryPath: os.Getenv("GITHUB_STEP_SUMMARY")}
+}
+
+// ghDocument is a deploy as listed in the documents step output.
+type ghDocument struct {
+	File    string `json:"file"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	FileID  string `json:"file_id,omitempty"`
+	Link    string `json:"link,omitempty"`
+}
+
+// NotifyDeploy records r. Deployed, rolled back, and failed documents are
+// annotated; the file_id, link, and version outputs are those of the last
+// document made live, and the documents output lists every deploy as JSON.
+// It satisfies the deploy.Notifier hook.
+func (g *GitHubActions) NotifyDeploy(r deploy.Result) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	first := len(g.results) == 0
+	g.results = append(g.results, r)
+	link := ""
+	if r.FileID != "" {
+		link = drive.ViewURL(r.FileID)
+	}
+
+	switch r.Status {
+	case deploy.StatusDeployed:
+		fmt.Fprintf(g.Out, "::notice title=%s::%s\n", ghProperty("Deployed "+r.File), ghData(r.Version+" is live: "+link))
+	case deploy.StatusRolledBack:
+		fmt.Fprintf(g.Out, "::notice title=%s::%s\n", ghProperty("Rolled back "+r.File), ghData(r.Version+" is live again: "+link))
+	case deploy.StatusFailed:
+		fmt.Fprintf(g.Out, "::error title=%s::%s\n", ghProperty("Failed to deploy "+r.File), ghData(fmt.Sprint(r.Err)))
+	}
+
+	if g.OutputPath != "" {
+		docs := make([]ghDocument, len(g.results))
+		for i, r := range g.results {
+			docs[i] = ghDocument{File: r.File, Version: r.Version, Status: r.Status, FileID: r.FileID}
+			if r.FileID != "" {
+				docs[i].Link = drive.ViewURL(r.FileID)
+			}
+		}
+		b, _ := json.Marshal(docs)
+		outputs := [][2]string{{"documents", string(b)}}
+		if r.Status == deploy.StatusDeployed || r.Status == deploy.StatusRolledBack {
+			outputs = append(outputs, [2]string{"file_id", r.FileID}, [2]string{"link", link}, [2]string{"version", r.Version})
+		}
+		var out strings.Builder
+		for _, kv := range outputs {
+			writeOutput(&out, kv[0], kv[1])
+		}
+		g.append(g.OutputPath, out.String())
+	}
+
+	if g.SummaryPath != "" {
+		var sum strings.Builder
+		if first {
+			sum.WriteString("### Deployed documents\n\n| Document | Version | Status | Link |\n| --- | --- | --- | --- |\n")
+		}
+		status := r.Status
+		if r.Err != nil {
+			status += ": " + r.Err.Error()
+		}
+		cell := func(s string) string {
+			return strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s)
+		}
+		linkCell := ""
+		if link != "" {
+			linkCell = "[open](" + link + ")"
+		}
+		fmt.Fprintf(&sum, "| %s | %s | %s | %s |\n", cell(r.File), cell(r.Version), cell(status), linkCell)
+		g.append(g.SummaryPath, sum.String())
+	}
+}
+
+func (g *GitHubActions) append(path, s string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err == nil {
+		_, err = f.WriteString(s)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil && g.OnError != nil {
+		g.OnError(err)
+	}
+}
+
+// writeOutput writes a step output, using a delimited block for values
+// that span lines.
+func writeOutput(w io.Writer, name, value string) {
+	if !strings.ContainsAny(value, "\r\n") {
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+		return
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	delim := "ghadelimiter_" + hex.EncodeToString(b)
+	fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", name, delim, value, delim)
+}
+
+// ghData escapes the message of a workflow command.
+func ghData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// ghProperty escapes a property value of a workflow command.
+func ghProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func TestGitHubActions(t *testing.T) {
+	dir := t.TempDir()
+	var out strings.Builder
+	g := &GitHubActions{Out: &out, OutputPath: filepath.Join(dir, "output"), SummaryPath: filepath.Join(dir, "summary")}
+	g.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusDeployed})
+	g.NotifyDeploy(deploy.Result{File: "a,b.pdf", Version: "v1", Status: deploy.StatusFailed, Err: errors.New("upload failed: 50%\nretry")})
+	g.NotifyDeploy(deploy.Result{File: "other.pdf", Version: "v3", FileID: "1DeF", Status: deploy.StatusSkipped})
+
+	wantOut := "::notice title=Deployed mydoc.pdf::v2 is live: https://drive.google.com/file/d/1AbC/view\n" +
+		"::error title=Failed to deploy a%2Cb.pdf::upload failed: 50%25%0Aretry\n"
+	if out.String() != wantOut {
+		t.Errorf("annotations:\n%s\nwant:\n%s", out.String(), wantOut)
+	}
+
+	b, err := os.ReadFile(g.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := string(b)
+	for _, want := range []string{
+		"file_id=1AbC\nlink=https://drive.google.com/file/d/1AbC/view\nversion=v2\n",
+		`documents=[{"file":"mydoc.pdf","version":"v2","status":"deployed","file_id":"1AbC","link":"https://drive.google.com/file/d/1AbC/view"},{"file":"a,b.pdf","version":"v1","status":"failed"},{"file":"other.pdf","version":"v3","status":"skipped","file_id":"1DeF","link":"https://drive.google.com/file/d/1DeF/view"}]` + "\n",
+	} {
+		if !strings.Contains(outputs, want) {
+			t.Errorf("outputs missing %q:\n%s", want, outputs)
+		}
+	}
+	if strings.Count(outputs, "file_id=") != 1 {
+		t.Errorf("file_id set for documents not deployed:\n%s", outputs)
+	}
+
+	b, err = os.ReadFile(g.SummaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSummary := "### Deployed documents\n\n| Document | Version | Status | Link |\n| --- | --- | --- | --- |\n" +
+		"| mydoc.pdf | v2 | deployed | [open](https://drive.google.com/file/d/1AbC/view) |\n" +
+		"| a,b.pdf | v1 | failed: upload failed: 50% retry |  |\n" +
+		"| other.pdf | v3 | skipped | [open](https://drive.google.com/file/d/1DeF/view) |\n"
+	if string(b) != wantSummary {
+		t.Errorf("summary:\n%s\nwant:\n%s", b, wantSummary)
+	}
+}
+
+func TestWriteOutputMultiline(t *testing.T) {
+	var b strings.Builder
+	writeOutput(&b, "notes", "one\ntwo")
+	lines := strings.Split(b.String(), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "notes<<ghadelimiter_") || lines[1] != "one" || lines[2] != "two" || lines[3] != strings.TrimPrefix(lines[0], "notes<<") {
+		t.Errorf("output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// Catalog is the content of the index Index keeps: the documents live in
+// a folder, by name.
+type Catalog struct {
+	FolderID  string         `json:"folderId"`
+	Updated   time.Time      `json:"updated"`
+	Documents []CatalogEntry `json:"documents"`
+}
+
+// CatalogEntry is a live document of a Catalog.
+type CatalogEntry struct {
+	File    string    `json:"file"`
+	Version string    `json:"version"`
+	ID      string    `json:"id"`
+	Link    string    `json:"link"`
+	Updated time.Time `json:"updated"`
+}
+
+// Index keeps a JSON catalog of the live PDFs in the folder of each
+// deploy or rollback, so that portals can show the documents without
+// listing the folder themselves. The catalog is rebuilt from a listing of
+// the folder, with the change just made, as the listing can lag behind it.
+// Its file is replaced in a single request and keeps its ID, so readers
+// get the old or the new catalog, never part of either.
+type Index struct {
+	Client *drive.Client
+	// Name is the catalog's file name; empty means "index.json".
+	Name string
+	// Now returns the time recorded; nil means time.Now.
+	Now func() time.Time
+	// OnError, if set, receives the errors of NotifyDeploy.
+	OnError func(error)
+
+	mu sync.Mutex // one update at a time, as each rewrites the catalog
+}
+
+// Build lists the live PDFs of folderID into a Catalog, putting r in it
+// if r deployed or rolled back a version.
+func (x *Index) Build(ctx context.Context, folderID string, r deploy.Result) (Catalog, error) {
+	now := time.Now
+	if x.Now != nil {
+		now = x.Now
+	}
+	cat := Catalog{FolderID: folderID, Updated: now().UTC(), Documents: []CatalogEntry{}}
+	files, err := list.ListFiles(ctx, x.Client, list.Options{
+		Query:  query.New().InParent(folderID).MimeType("application/pdf").NotTrashed().String(),
+		Fields: "id,name,description,modifiedTime",
+	})
+	if err != nil {
+		return Catalog{}, err
+	}
+	for _, f := range files {
+		if f.Name == r.File || slices.ContainsFunc(cat.Documents, func(e CatalogEntry) bool { return e.File == f.Name }) {
+			continue
+		}
+		cat.Documents = append(cat.Documents, CatalogEntry{File: f.Name, Version: f.Description, ID: f.ID,
+			Link: drive.ViewURL(f.ID), Updated: f.ModifiedTime.UTC()})
+	}
+	if r.FileID != "" {
+		cat.Documents = append(cat.Documents, CatalogEntry{File: r.File, Version: r.Version, ID: r.FileID,
+			Link: drive.ViewURL(r.FileID), Updated: cat.Updated})
+	}
+	slices.SortFunc(cat.Documents, func(a, b CatalogEntry) int { return strings.Compare(a.File, b.File) })
+	return cat, nil
+}
+
+// Write puts cat in the catalog file of its folder, creating the file if
+// there is none.
+func (x *Index) Write(ctx context.Context, cat Catalog) error {
+	name := x.Name
+	if name == "" {
+		name = "index.json"
+	}
+	content, err := json.MarshalIndent(cat, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+	f, err := list.First(ctx, x.Client, list.Options{
+		Query:  query.New().InParent(cat.FolderID).NameEquals(name).NotTrashed().String(),
+		Fields: "id",
+	})
+	if errors.Is(err, drive.ErrNotFound) {
+		meta := drive.Metadata{Name: name, MimeType: "application/json", Parents: []string{cat.FolderID}}
+		if _, err := x.Client.Upload(ctx, meta, bytes.NewReader(content), int64(len(content))); err != nil {
+			return fmt.Errorf("create %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find %s: %w", name, err)
+	}
+	if _, err := x.Client.UpdateContent(ctx, f.ID, bytes.NewReader(content), int64(len(content))); err != nil {
+		return fmt.Errorf("update %s: %w", name, err)
+	}
+	return nil
+}
+
+// NotifyDeploy rewrites the catalog of r's folder if r deployed or rolled
+// back a version. It satisfies the deploy.Notifier hook.
+func (x *Index) NotifyDeploy(r deploy.Result) {
+	if r.Status != deploy.StatusDeployed && r.Status != deploy.StatusRolledBack {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	cat, err := x.Build(ctx, r.FolderID, r)
+	if err == nil {
+		err = x.Write(ctx, cat)
+	}
+	if err != nil && x.OnError != nil {
+		x.OnError(err)
+	}
+}
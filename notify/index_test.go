@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+func TestIndex(t *testing.T) {
+	var index []byte
+	var created, updated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query().Get("q")
+		switch {
+		case r.Method == http.MethodGet && strings.Contains(q, "mimeType = 'application/pdf'"):
+			// The listing still has the replaced version of mydoc.pdf.
+			w.Write([]byte(`{"files":[
+				{"id":"h1","name":"handbook.pdf","description":"v4","modifiedTime":"2024-04-01T08:00:00Z"},
+				{"id":"old","name":"mydoc.pdf","description":"v1","modifiedTime":"2024-03-01T08:00:00Z"}]}`))
+		case r.Method == http.MethodGet && strings.Contains(q, "name = 'index.json'"):
+			if index == nil {
+				w.Write([]byte(`{"files":[]}`))
+				return
+			}
+			w.Write([]byte(`{"files":[{"id":"idx"}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			var meta drive.Metadata
+			part, _ := mr.NextPart()
+			json.NewDecoder(part).Decode(&meta)
+			if meta.Name != "index.json" || meta.MimeType != "application/json" || meta.Parents[0] != "pub" {
+				t.Errorf("created %+v", meta)
+			}
+			part, _ = mr.NextPart()
+			index, _ = io.ReadAll(part)
+			created++
+			w.Write([]byte(`{"id":"idx"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/upload/drive/v3/files/idx":
+			index, _ = io.ReadAll(r.Body)
+			updated++
+			w.Write([]byte(`{"id":"idx"}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+
+	var errs []error
+	at := time.Date(2024, 5, 2, 12, 14, 0, 0, time.FixedZone("", 7200))
+	x := &Index{Client: c, Now: func() time.Time { return at }, OnError: func(err error) { errs = append(errs, err) }}
+	x.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "new", FolderID: "pub", Status: deploy.StatusDeployed})
+	x.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "new", FolderID: "pub", Status: deploy.StatusSkipped})
+	x.NotifyDeploy(deploy.Result{File: "other.pdf", Version: "v1", FolderID: "pub", Status: deploy.StatusFailed})
+	x.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "new", FolderID: "pub", Status: deploy.StatusDeployed})
+	if len(errs) > 0 || created != 1 || updated != 1 {
+		t.Fatalf("created %d, updated %d, errors %v", created, updated, errs)
+	}
+	var got Catalog
+	if err := json.Unmarshal(index, &got); err != nil {
+		t.Fatalf("index.json: %v\n%s", err, index)
+	}
+	want := Catalog{FolderID: "pub", Updated: at.UTC(), Documents: []CatalogEntry{
+		{File: "handbook.pdf", Version: "v4", ID: "h1", Link: "https://drive.google.com/file/d/h1/view", Updated: time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC)},
+		{File: "mydoc.pdf", Version: "v2", ID: "new", Link: "https://drive.google.com/file/d/new/view", Updated: at.UTC()},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("index.json = %s", index)
+	}
+}
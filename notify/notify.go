@@ -0,0 +1,113 @@
+// Package notify reports deploy results where a team sees them without
+// reading CI logs: in a Slack or Google Chat channel through an incoming
+// webhook, in the annotations and summary of a GitHub Actions run, as
+// rows of a release register in a Google Sheet, or in an email to a
+// distribution list.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Webhook posts to an incoming webhook URL. Slack and Google Chat accept
+// the same simple message, so either kind of URL works.
+type Webhook struct {
+	URL string
+	// Environment, if set, names where the deploys went, such as
+	// "production", and is part of every message.
+	Environment string
+	// Skipped also posts deploys skipped because the version was live.
+	Skipped bool
+	// HTTPClient is used for posting; nil means a plain client with a 10
+	// second timeout, rather than http.DefaultClient, whose transport may
+	// be wrapped for the Drive API.
+	HTTPClient *http.Client
+	// OnError, if set, receives the errors of NotifyDeploy, which has no
+	// way to return them.
+	OnError func(error)
+}
+
+// webhookClient posts to webhooks that have no HTTPClient of their own.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Message returns the text posted for r.
+func (w *Webhook) Message(r deploy.Result) string {
+	var b strings.Builder
+	where := "to"
+	switch r.Status {
+	case deploy.StatusDeployed:
+		fmt.Fprintf(&b, "✅ Deployed *%s* %s", r.File, r.Version)
+	case deploy.StatusSkipped:
+		fmt.Fprintf(&b, "➖ *%s* %s is live already", r.File, r.Version)
+		where = "in"
+	case deploy.StatusRolledBack:
+		fmt.Fprintf(&b, "⏪ Rolled back *%s*", r.File)
+		if r.Previous != "" {
+			fmt.Fprintf(&b, " from %s", r.Previous)
+		}
+		fmt.Fprintf(&b, " to %s", r.Version)
+		where = "in"
+	default:
+		fmt.Fprintf(&b, "❌ Failed to deploy *%s* %s", r.File, r.Version)
+	}
+	if w.Environment != "" {
+		fmt.Fprintf(&b, " %s %s", where, w.Environment)
+	}
+	if r.FileID != "" {
+		fmt.Fprintf(&b, ": <%s|open in Drive>", drive.ViewURL(r.FileID))
+	}
+	if r.Err != nil {
+		fmt.Fprintf(&b, "\n```%s```", r.Err)
+	}
+	return b.String()
+}
+
+// Post sends the message for r.
+func (w *Webhook) Post(ctx context.Context, r deploy.Result) error {
+	body, err := json.Marshal(map[string]string{"text": w.Message(r)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	hc := w.HTTPClient
+	if hc == nil {
+		hc = webhookClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notify: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// NotifyDeploy posts r, unless it was skipped and Skipped is not set,
+// giving up after 10 seconds. It satisfies the deploy.Notifier hook.
+func (w *Webhook) NotifyDeploy(r deploy.Result) {
+	if r.Status == deploy.StatusSkipped && !w.Skipped {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.Post(ctx, r); err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+}
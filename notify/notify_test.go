@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+)
+
+func TestNotifyDeploy(t *testing.T) {
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json; charset=UTF-8" ||
+			json.NewDecoder(r.Body).Decode(&msg) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		posted = append(posted, msg.Text)
+	}))
+	defer srv.Close()
+
+	var errs []error
+	w := &Webhook{URL: srv.URL, Environment: "production", OnError: func(err error) { errs = append(errs, err) }}
+	w.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusDeployed})
+	w.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusSkipped})
+	w.NotifyDeploy(deploy.Result{File: "other.pdf", Version: "v3", Status: deploy.StatusFailed, Err: errors.New("upload failed")})
+	w.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v1", Previous: "v2", FileID: "1DeF", Status: deploy.StatusRolledBack})
+
+	want := []string{
+		"✅ Deployed *mydoc.pdf* v2 to production: <https://drive.google.com/file/d/1AbC/view|open in Drive>",
+		"❌ Failed to deploy *other.pdf* v3 to production\n```upload failed```",
+		"⏪ Rolled back *mydoc.pdf* from v2 to v1 in production: <https://drive.google.com/file/d/1DeF/view|open in Drive>",
+	}
+	if strings.Join(posted, "\n--\n") != strings.Join(want, "\n--\n") {
+		t.Errorf("posted:\n%q\nwant:\n%q", posted, want)
+	}
+	if len(errs) != 0 {
+		t.Errorf("errors: %v", errs)
+	}
+
+	w.Skipped = true
+	w.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusSkipped})
+	if len(posted) != 4 || !strings.HasPrefix(posted[3], "➖ *mydoc.pdf* v2 is live already in production") {
+		t.Errorf("skipped deploy posted as %q", posted[3:])
+	}
+}
+
+func TestPostError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	var got error
+	w := &Webhook{URL: srv.URL, OnError: func(err error) { got = err }}
+	w.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", Status: deploy.StatusDeployed})
+	if got == nil || !strings.Contains(got.Error(), "404 Not Found: no_service") {
+		t.Errorf("error = %v", got)
+	}
+}
+
+type refuseAll struct{}
+
+func (refuseAll) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("sent through http.DefaultClient")
+}
+
+func TestPostBypassesDefaultClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	orig := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: refuseAll{}}
+	t.Cleanup(func() { http.DefaultClient = orig })
+
+	w := &Webhook{URL: srv.URL}
+	if err := w.Post(t.Context(), deploy.Result{File: "mydoc.pdf", Version: "v2", Status: deploy.StatusDeployed}); err != nil {
+		t.Error(err)
+	}
+}
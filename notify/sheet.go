@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// SheetsBase is the root of the Google Sheets v4 API.
+const SheetsBase = "https://sheets.googleapis.com/v4"
+
+// Sheet appends a row for every successful deploy to a Google Sheet,
+// keeping a release register: document, version, deployer, UTC timestamp,
+// and link. The client needs the spreadsheets scope besides Drive's.
+type Sheet struct {
+	Client        *drive.Client
+	SpreadsheetID string
+	// Tab is the sheet rows are added to; empty means the first one.
+	Tab string
+	// Deployer, if set, is recorded instead of the email address of the
+	// client's account.
+	Deployer string
+	// Now returns the time recorded; nil means time.Now.
+	Now func() time.Time
+	// OnError, if set, receives the errors of NotifyDeploy.
+	OnError func(error)
+
+	once sync.Once
+}
+
+// Append adds a row of values after the last row of the table on the sheet.
+// The values are stored as they are, so Sheets neither turns versions like
+// 1.10 into numbers nor evaluates a value that starts with = as a formula.
+func (s *Sheet) Append(ctx context.Context, values ...string) error {
+	id, err := drive.ParseID(s.SpreadsheetID)
+	if err != nil {
+		return err
+	}
+	rng := "A:A"
+	if s.Tab != "" {
+		rng = "'" + strings.ReplaceAll(s.Tab, "'", "''") + "'!" + rng
+	}
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	q := url.Values{"valueInputOption": {"RAW"}, "insertDataOption": {"INSERT_ROWS"}}
+	path := SheetsBase + "/spreadsheets/" + url.PathEscape(id) + "/values/" + url.PathEscape(rng) + ":append?" + q.Encode()
+	return s.Client.DoJSON(ctx, http.MethodPost, path, map[string]any{"values": [][]any{row}}, nil)
+}
+
+// NotifyDeploy appends a row for r if it deployed a new version. It
+// satisfies the deploy.Notifier hook.
+func (s *Sheet) NotifyDeploy(r deploy.Result) {
+	if r.Status != deploy.StatusDeployed {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s.once.Do(func() {
+		if s.Deployer != "" {
+			return
+		}
+		about, err := s.Client.GetAbout(ctx)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		s.Deployer = about.User.EmailAddress
+	})
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	err := s.Append(ctx, r.File, r.Version, s.Deployer, now().UTC().Format(time.RFC3339), drive.ViewURL(r.FileID))
+	if err != nil {
+		s.fail(err)
+	}
+}
+
+func (s *Sheet) fail(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
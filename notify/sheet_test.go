@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/deploy"
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func TestSheet(t *testing.T) {
+	var rows [][]any
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/drive/v3/about":
+			w.Write([]byte(`{"user":{"emailAddress":"ada@example.com"}}`))
+		case r.Method == http.MethodPost && r.URL.Query().Get("valueInputOption") == "RAW":
+			var body struct{ Values [][]any }
+			json.NewDecoder(r.Body).Decode(&body)
+			rows = append(rows, body.Values...)
+			ranges = append(ranges, r.URL.EscapedPath())
+			w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+
+	var errs []error
+	s := &Sheet{Client: c, SpreadsheetID: "https://docs.google.com/spreadsheets/d/1SheetIDxxxxxxxxxxxx/edit#gid=0",
+		Now:     func() time.Time { return time.Date(2024, 5, 2, 12, 14, 0, 0, time.FixedZone("", 7200)) },
+		OnError: func(err error) { errs = append(errs, err) }}
+	s.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusDeployed})
+	s.NotifyDeploy(deploy.Result{File: "mydoc.pdf", Version: "v2", FileID: "1AbC", Status: deploy.StatusSkipped})
+	s.NotifyDeploy(deploy.Result{File: "other.pdf", Version: "v1", Status: deploy.StatusFailed})
+	s.Tab = "Ada's register"
+	s.NotifyDeploy(deploy.Result{File: "=other.pdf", Version: "1.10", FileID: "1DeF", Status: deploy.StatusDeployed})
+
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	want := [][]any{
+		{"mydoc.pdf", "v2", "ada@example.com", "2024-05-02T10:14:00Z", "https://drive.google.com/file/d/1AbC/view"},
+		{"=other.pdf", "1.10", "ada@example.com", "2024-05-02T10:14:00Z", "https://drive.google.com/file/d/1DeF/view"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+	wantRanges := []string{
+		"/v4/spreadsheets/1SheetIDxxxxxxxxxxxx/values/A:A:append",
+		"/v4/spreadsheets/1SheetIDxxxxxxxxxxxx/values/%27Ada%27%27s%20register%27%21A:A:append",
+	}
+	if !reflect.DeepEqual(ranges, wantRanges) {
+		t.Errorf("ranges = %q, want %q", ranges, wantRanges)
+	}
+}
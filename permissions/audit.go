@@ -0,0 +1,147 @@
+package permissions
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/list"
+)
+
+// AuditRecord is one permission on one item of an audited tree.
+type AuditRecord struct {
+	Path         string `json:"path"`
+	FileID       string `json:"fileId"`
+	MimeType     string `json:"mimeType"`
+	PermissionID string `json:"permissionId"`
+	Type         string `json:"type"`
+	Role         string `json:"role"`
+	EmailAddress string `json:"emailAddress,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	DisplayName  string `json:"displayName,omitempty"`
+	// Inherited is true when the grant comes from a parent folder rather
+	// than being set on the item itself.
+	Inherited     bool   `json:"inherited"`
+	InheritedFrom string `json:"inheritedFrom,omitempty"`
+	// LinkSharing is true for "anyone" and "domain" grants that do not
+	// require being added by name.
+	LinkSharing  bool `json:"linkSharing"`
+	Discoverable bool `json:"discoverable"`
+	// Expires is set for grants Drive revokes automatically.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// AuditPermissions walks the tree below folderID, including the folder
+// itself, and returns a record for every permission on every item.
+// Shortcuts are audited but not followed.
+//
+// Shared drives report inheritance directly. In My Drive a permission is
+// taken to be inherited when the item's parent folder has the same grantee
+// with the same role.
+func AuditPermissions(ctx context.Context, c *drive.Client, folderID string) ([]AuditRecord, error) {
+	root, err := c.GetFile(ctx, folderID, "id", "name", "mimeType")
+	if err != nil {
+		return nil, err
+	}
+	a := &auditor{ctx: ctx, c: c, byFolder: map[string]map[string]string{}}
+	if err := a.audit(root.Name, root, ""); err != nil {
+		return nil, err
+	}
+	err = list.Walk(ctx, c, root.ID, func(p string, f drive.File) error {
+		parent := ""
+		if len(f.Parents) > 0 {
+			parent = f.Parents[0]
+		}
+		if err := a.audit(root.Name+"/"+p, f, parent); err != nil {
+			return err
+		}
+		if f.IsShortcut() {
+			return list.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.records, nil
+}
+
+type auditor struct {
+	ctx     context.Context
+	c       *drive.Client
+	records []AuditRecord
+	// byFolder maps an audited folder's ID to its grants (permission ID to
+	// role), for inferring inheritance in My Drive.
+	byFolder map[string]map[string]string
+}
+
+func (a *auditor) audit(path string, f drive.File, parentID string) error {
+	perms, err := List(a.ctx, a.c, f.ID)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", path, err)
+	}
+	parentGrants := a.byFolder[parentID]
+	var grants map[string]string
+	if f.IsFolder() {
+		grants = make(map[string]string, len(perms))
+		a.byFolder[f.ID] = grants
+	}
+	for _, p := range perms {
+		if grants != nil {
+			grants[p.ID] = p.Role
+		}
+		r := AuditRecord{
+			Path:         path,
+			FileID:       f.ID,
+			MimeType:     f.MimeType,
+			PermissionID: p.ID,
+			Type:         p.Type,
+			Role:         p.Role,
+			EmailAddress: p.EmailAddress,
+			Domain:       p.Domain,
+			DisplayName:  p.DisplayName,
+			LinkSharing:  p.Type == TypeAnyone || p.Type == TypeDomain,
+			Discoverable: p.AllowFileDiscovery,
+			Expires:      p.ExpirationTime,
+		}
+		if len(p.Details) > 0 {
+			r.Inherited = true
+			for _, d := range p.Details {
+				if !d.Inherited {
+					r.Inherited = false
+					break
+				}
+				r.InheritedFrom = d.InheritedFrom
+			}
+			if !r.Inherited {
+				r.InheritedFrom = ""
+			}
+		} else if role, ok := parentGrants[p.ID]; ok && role == p.Role {
+			r.Inherited, r.InheritedFrom = true, parentID
+		}
+		a.records = append(a.records, r)
+	}
+	return nil
+}
+
+// WriteAuditCSV writes records as CSV with a header row.
+func WriteAuditCSV(w io.Writer, records []AuditRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "file_id", "mime_type", "permission_id", "type", "role", "email", "domain", "display_name", "inherited", "inherited_from", "link_sharing", "discoverable", "expires"})
+	for _, r := range records {
+		expires := ""
+		if r.Expires != nil {
+			expires = r.Expires.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			r.Path, r.FileID, r.MimeType, r.PermissionID, r.Type, r.Role, r.EmailAddress, r.Domain, r.DisplayName,
+			strconv.FormatBool(r.Inherited), r.InheritedFrom, strconv.FormatBool(r.LinkSharing), strconv.FormatBool(r.Discoverable), expires,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
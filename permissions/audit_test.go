@@ -0,0 +1,96 @@
+package permissions
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditPermissions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drive/v3/files/top":
+			w.Write([]byte(`{"id":"top","name":"Top","mimeType":"application/vnd.google-apps.folder"}`))
+		case "/drive/v3/files":
+			switch q := r.URL.Query().Get("q"); {
+			case strings.HasPrefix(q, "'top' in parents"):
+				w.Write([]byte(`{"files":[{"id":"doc","name":"Doc","mimeType":"text/plain","parents":["top"]}]}`))
+			default:
+				w.Write([]byte(`{"files":[]}`))
+			}
+		case "/drive/v3/files/top/permissions":
+			w.Write([]byte(`{"permissions":[{"id":"u1","type":"user","role":"writer","emailAddress":"a@example.com"}]}`))
+		case "/drive/v3/files/doc/permissions":
+			w.Write([]byte(`{"permissions":[
+				{"id":"u1","type":"user","role":"writer","emailAddress":"a@example.com"},
+				{"id":"anyoneWithLink","type":"anyone","role":"reader"},
+				{"id":"g1","type":"group","role":"reader","emailAddress":"g@example.com","permissionDetails":[{"role":"reader","inherited":true,"inheritedFrom":"sd"}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	records, err := AuditPermissions(context.Background(), testClient(srv), "top")
+	if err != nil {
+		t.Fatalf("AuditPermissions: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("got %d records: %+v", len(records), records)
+	}
+	if r := records[0]; r.Path != "Top" || r.Inherited {
+		t.Fatalf("root record = %+v", r)
+	}
+	if r := records[1]; r.Path != "Top/Doc" || !r.Inherited || r.InheritedFrom != "top" {
+		t.Fatalf("inherited record = %+v", r)
+	}
+	if r := records[2]; r.Inherited || !r.LinkSharing {
+		t.Fatalf("link record = %+v", r)
+	}
+	if r := records[3]; !r.Inherited || r.InheritedFrom != "sd" {
+		t.Fatalf("shared drive record = %+v", r)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAuditCSV(&buf, records); err != nil {
+		t.Fatalf("WriteAuditCSV: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 5 {
+		t.Fatalf("CSV has %d lines:\n%s", lines, buf.String())
+	}
+}
+
+func TestAuditPermissions_FileShortcut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/drive/v3/files/top":
+			w.Write([]byte(`{"id":"top","name":"Top","mimeType":"application/vnd.google-apps.folder"}`))
+		case r.URL.Path == "/drive/v3/files" && strings.HasPrefix(r.URL.Query().Get("q"), "'top' in parents"):
+			w.Write([]byte(`{"files":[
+				{"id":"sc","name":"a-link","mimeType":"application/vnd.google-apps.shortcut","parents":["top"],"shortcutDetails":{"targetId":"x","targetMimeType":"application/pdf"}},
+				{"id":"doc","name":"b.pdf","mimeType":"application/pdf","parents":["top"]}]}`))
+		case r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/permissions"):
+			w.Write([]byte(`{"permissions":[{"id":"anyoneWithLink","type":"anyone","role":"reader"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	records, err := AuditPermissions(context.Background(), testClient(srv), "top")
+	if err != nil {
+		t.Fatalf("AuditPermissions: %v", err)
+	}
+	var paths []string
+	for _, r := range records {
+		paths = append(paths, r.Path)
+	}
+	if got := strings.Join(paths, ","); got != "Top,Top/a-link,Top/b.pdf" {
+		t.Fatalf("audited %s; want the file after the shortcut too", got)
+	}
+}
@@ -0,0 +1,94 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// roleRank orders roles from least to most privileged.
+var roleRank = map[string]int{
+	RoleReader:        1,
+	RoleCommenter:     2,
+	RoleWriter:        3,
+	RoleFileOrganizer: 4,
+	RoleOrganizer:     5,
+	RoleOwner:         6,
+}
+
+// BulkOptions selects the permissions BulkRevoke changes.
+type BulkOptions struct {
+	// Match picks the permissions to change. It is required.
+	Match func(AuditRecord) bool
+	// Downgrade, if set, lowers matching grants to this role instead of
+	// removing them. Grants already at or below it are left alone.
+	Downgrade string
+	// DryRun reports what would change without changing anything.
+	DryRun bool
+}
+
+// BulkChange is one permission removed or downgraded by BulkRevoke.
+type BulkChange struct {
+	AuditRecord
+	// NewRole is the role after a downgrade; empty for a removal.
+	NewRole string
+	Err     error
+}
+
+// MatchAnyone matches "anyone with the link" grants.
+func MatchAnyone(r AuditRecord) bool { return r.Type == TypeAnyone }
+
+// MatchEmail returns a Match function for grants to a user or group email,
+// compared case-insensitively.
+func MatchEmail(email string) func(AuditRecord) bool {
+	return func(r AuditRecord) bool { return strings.EqualFold(r.EmailAddress, email) }
+}
+
+// BulkRevoke removes or downgrades every permission matched by opts.Match
+// on folderID and everything below it. Inherited grants are skipped: they
+// change with the folder they are inherited from. Owner grants are never
+// touched. A failure on one item does not stop the run; it is recorded in
+// the result and BulkRevoke returns an error once everything else is done.
+func BulkRevoke(ctx context.Context, c *drive.Client, folderID string, opts BulkOptions) ([]BulkChange, error) {
+	if opts.Match == nil {
+		return nil, errors.New("BulkOptions.Match is required")
+	}
+	if opts.Downgrade != "" {
+		if _, ok := roleRank[opts.Downgrade]; !ok || opts.Downgrade == RoleOwner {
+			return nil, fmt.Errorf("cannot downgrade to role %q", opts.Downgrade)
+		}
+	}
+	records, err := AuditPermissions(ctx, c, folderID)
+	if err != nil {
+		return nil, err
+	}
+	var changes []BulkChange
+	failed := 0
+	for _, r := range records {
+		if r.Inherited || r.Role == RoleOwner || !opts.Match(r) {
+			continue
+		}
+		if opts.Downgrade != "" && roleRank[r.Role] <= roleRank[opts.Downgrade] {
+			continue
+		}
+		ch := BulkChange{AuditRecord: r, NewRole: opts.Downgrade}
+		if !opts.DryRun {
+			if opts.Downgrade != "" {
+				_, ch.Err = Update(ctx, c, r.FileID, r.PermissionID, opts.Downgrade)
+			} else {
+				ch.Err = Delete(ctx, c, r.FileID, r.PermissionID)
+			}
+			if ch.Err != nil {
+				failed++
+			}
+		}
+		changes = append(changes, ch)
+	}
+	if failed > 0 {
+		return changes, &drive.BatchError{Failed: failed, Total: len(changes), Summary: "permission changes failed"}
+	}
+	return changes, nil
+}
@@ -0,0 +1,64 @@
+package permissions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestBulkRevoke(t *testing.T) {
+	var mu sync.Mutex
+	var changed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			mu.Lock()
+			changed = append(changed, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			w.Write([]byte(`{}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/drive/v3/files/top":
+			w.Write([]byte(`{"id":"top","name":"Top","mimeType":"application/vnd.google-apps.folder"}`))
+		case "/drive/v3/files":
+			w.Write([]byte(`{"files":[]}`))
+		case "/drive/v3/files/top/permissions":
+			w.Write([]byte(`{"permissions":[
+				{"id":"o","type":"user","role":"owner","emailAddress":"me@example.com"},
+				{"id":"u1","type":"user","role":"writer","emailAddress":"Gone@example.com"},
+				{"id":"u2","type":"user","role":"reader","emailAddress":"stay@example.com"},
+				{"id":"anyoneWithLink","type":"anyone","role":"reader"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+
+	got, err := BulkRevoke(ctx, c, "top", BulkOptions{Match: MatchAnyone, DryRun: true})
+	if err != nil || len(got) != 1 || got[0].PermissionID != "anyoneWithLink" || len(changed) != 0 {
+		t.Fatalf("dry run = %+v, %v; requests %v", got, err, changed)
+	}
+
+	got, err = BulkRevoke(ctx, c, "top", BulkOptions{Match: MatchEmail("gone@example.com")})
+	if err != nil || len(got) != 1 || len(changed) != 1 || changed[0] != "DELETE /drive/v3/files/top/permissions/u1" {
+		t.Fatalf("remove = %+v, %v; requests %v", got, err, changed)
+	}
+
+	changed = nil
+	all := func(AuditRecord) bool { return true }
+	got, err = BulkRevoke(ctx, c, "top", BulkOptions{Match: all, Downgrade: RoleReader})
+	if err != nil || len(got) != 1 || got[0].PermissionID != "u1" || got[0].NewRole != RoleReader {
+		t.Fatalf("downgrade = %+v, %v", got, err)
+	}
+	if len(changed) != 1 || changed[0] != "PATCH /drive/v3/files/top/permissions/u1" {
+		t.Fatalf("requests %v", changed)
+	}
+
+	if _, err := BulkRevoke(ctx, c, "top", BulkOptions{Match: all, Downgrade: RoleOwner}); err == nil {
+		t.Fatal("expected error downgrading to owner")
+	}
+}
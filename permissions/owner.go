@@ -0,0 +1,49 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// TransferOwnership makes newOwnerEmail the owner of fileID.
+//
+// Within a Workspace domain the transfer is immediate and the returned
+// permission has role owner; the previous owner keeps writer access.
+// Between consumer accounts, or across domains, Drive requires the new owner
+// to accept: TransferOwnership then marks them as pending owner, granting
+// writer access first if they have less, and the returned permission has
+// PendingOwner set. The transfer completes when they accept it in Drive.
+// Files in shared drives have no individual owner and cannot be transferred.
+func TransferOwnership(ctx context.Context, c *drive.Client, fileID, newOwnerEmail string) (Permission, error) {
+	if newOwnerEmail == "" {
+		return Permission{}, errors.New("new owner email is required")
+	}
+	p, err := Create(ctx, c, fileID, Permission{Type: TypeUser, Role: RoleOwner, EmailAddress: newOwnerEmail}, CreateOptions{})
+	var apiErr *drive.APIError
+	if err == nil || !errors.As(err, &apiErr) || apiErr.Reason != "consentIsRequired" {
+		return p, err
+	}
+
+	existing, err := List(ctx, c, fileID)
+	if err != nil {
+		return Permission{}, err
+	}
+	var permID string
+	for _, e := range existing {
+		if e.Type == TypeUser && strings.EqualFold(e.EmailAddress, newOwnerEmail) {
+			permID = e.ID
+			break
+		}
+	}
+	if permID == "" {
+		w, err := Create(ctx, c, fileID, Permission{Type: TypeUser, Role: RoleWriter, EmailAddress: newOwnerEmail}, CreateOptions{})
+		if err != nil {
+			return Permission{}, err
+		}
+		permID = w.ID
+	}
+	return patch(ctx, c, fileID, permID, nil, map[string]interface{}{"role": RoleWriter, "pendingOwner": true})
+}
@@ -0,0 +1,67 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransferOwnership(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		consent bool
+		pending bool
+	}{
+		{name: "same domain"},
+		{name: "consent", consent: true, pending: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var patched map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPost:
+					var body Permission
+					json.NewDecoder(r.Body).Decode(&body)
+					if body.Role == RoleOwner {
+						if r.URL.Query().Get("transferOwnership") != "true" {
+							t.Errorf("owner create without transferOwnership: %s", r.URL.RawQuery)
+						}
+						if tc.consent {
+							w.WriteHeader(http.StatusForbidden)
+							w.Write([]byte(`{"error":{"errors":[{"reason":"consentIsRequired"}],"message":"Consent is required"}}`))
+							return
+						}
+						w.Write([]byte(`{"id":"u2","type":"user","role":"owner"}`))
+						return
+					}
+					w.Write([]byte(`{"id":"u2","type":"user","role":"writer"}`))
+				case http.MethodGet:
+					w.Write([]byte(`{"permissions":[{"id":"u1","type":"user","role":"owner","emailAddress":"me@example.com"},{"id":"u2","type":"user","role":"reader","emailAddress":"New@example.com"}]}`))
+				case http.MethodPatch:
+					if r.URL.Path != "/drive/v3/files/f/permissions/u2" {
+						t.Errorf("patched %s", r.URL.Path)
+					}
+					json.NewDecoder(r.Body).Decode(&patched)
+					w.Write([]byte(`{"id":"u2","type":"user","role":"writer","pendingOwner":true}`))
+				}
+			}))
+			defer srv.Close()
+
+			p, err := TransferOwnership(context.Background(), testClient(srv), "f", "new@example.com")
+			if err != nil {
+				t.Fatalf("TransferOwnership: %v", err)
+			}
+			if p.PendingOwner != tc.pending {
+				t.Fatalf("PendingOwner = %v, want %v", p.PendingOwner, tc.pending)
+			}
+			if tc.pending && (patched["pendingOwner"] != true || patched["role"] != RoleWriter) {
+				t.Fatalf("patch body = %v", patched)
+			}
+			if !tc.pending && (p.Role != RoleOwner || patched != nil) {
+				t.Fatalf("direct transfer = %+v, patch %v", p, patched)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package permissions
+
+import (
+	"context"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Permissioner grants, lists, changes, and revokes the permissions of
+// files. Client implements it over a drive.Client; accept it instead of a
+// *drive.Client to test code that shares files with a fake, such as the one
+// in drivemock.
+type Permissioner interface {
+	Create(ctx context.Context, fileID string, p Permission, opts CreateOptions) (Permission, error)
+	List(ctx context.Context, fileID string) ([]Permission, error)
+	Update(ctx context.Context, fileID, permissionID, role string) (Permission, error)
+	Delete(ctx context.Context, fileID, permissionID string) error
+}
+
+// Client is the Permissioner of a drive.Client.
+type Client struct {
+	*drive.Client
+}
+
+var _ Permissioner = Client{}
+
+// Create is the package's Create with c.
+func (c Client) Create(ctx context.Context, fileID string, p Permission, opts CreateOptions) (Permission, error) {
+	return Create(ctx, c.Client, fileID, p, opts)
+}
+
+// List is the package's List with c.
+func (c Client) List(ctx context.Context, fileID string) ([]Permission, error) {
+	return List(ctx, c.Client, fileID)
+}
+
+// Update is the package's Update with c.
+func (c Client) Update(ctx context.Context, fileID, permissionID, role string) (Permission, error) {
+	return Update(ctx, c.Client, fileID, permissionID, role)
+}
+
+// Delete is the package's Delete with c.
+func (c Client) Delete(ctx context.Context, fileID, permissionID string) error {
+	return Delete(ctx, c.Client, fileID, permissionID)
+}
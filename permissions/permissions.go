@@ -0,0 +1,254 @@
+// Package permissions shares Drive files and folders with users, groups,
+// domains, or anyone with the link.
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Grantee types.
+const (
+	TypeUser   = "user"
+	TypeGroup  = "group"
+	TypeDomain = "domain"
+	TypeAnyone = "anyone"
+)
+
+// Roles, from most to least privileged.
+const (
+	RoleOwner         = "owner"
+	RoleOrganizer     = "organizer"
+	RoleFileOrganizer = "fileOrganizer"
+	RoleWriter        = "writer"
+	RoleCommenter     = "commenter"
+	RoleReader        = "reader"
+)
+
+// Fields is the field selection that populates every Permission field.
+const Fields = "id,type,role,emailAddress,domain,displayName,allowFileDiscovery,deleted,pendingOwner,expirationTime,permissionDetails"
+
+// Permission is a grant of a role on a file to a grantee.
+type Permission struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type"`
+	Role string `json:"role"`
+	// EmailAddress identifies user and group grantees.
+	EmailAddress string `json:"emailAddress,omitempty"`
+	// Domain identifies domain grantees.
+	Domain      string `json:"domain,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// AllowFileDiscovery makes domain and anyone grants findable in search
+	// instead of link-only.
+	AllowFileDiscovery bool `json:"allowFileDiscovery,omitempty"`
+	Deleted            bool `json:"deleted,omitempty"`
+	PendingOwner       bool `json:"pendingOwner,omitempty"`
+	// ExpirationTime, if set, is when Drive revokes a user or group grant.
+	ExpirationTime *time.Time `json:"expirationTime,omitempty"`
+	// Details is only reported for items in shared drives.
+	Details []Detail `json:"permissionDetails,omitempty"`
+}
+
+// Detail says whether a shared drive permission is inherited.
+type Detail struct {
+	Role          string `json:"role"`
+	Inherited     bool   `json:"inherited"`
+	InheritedFrom string `json:"inheritedFrom,omitempty"`
+}
+
+// CreateOptions tunes Create.
+type CreateOptions struct {
+	// SendNotificationEmail emails user and group grantees. Drive always
+	// notifies on ownership transfers.
+	SendNotificationEmail bool
+	// EmailMessage is included in the notification email.
+	EmailMessage string
+}
+
+func permissionsPath(fileID string) (string, error) {
+	id, err := drive.ParseID(fileID)
+	if err != nil {
+		return "", err
+	}
+	return "files/" + url.PathEscape(id) + "/permissions", nil
+}
+
+func validate(p Permission) error {
+	switch p.Type {
+	case TypeUser, TypeGroup:
+		if p.EmailAddress == "" {
+			return fmt.Errorf("%s permission requires an email address", p.Type)
+		}
+	case TypeDomain:
+		if p.Domain == "" {
+			return errors.New("domain permission requires a domain")
+		}
+	case TypeAnyone:
+	default:
+		return fmt.Errorf("unknown permission type %q", p.Type)
+	}
+	if p.Role == "" {
+		return errors.New("permission role is required")
+	}
+	if p.ExpirationTime != nil {
+		if err := validateExpiration(p.Type, p.Role, *p.ExpirationTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExpiration applies Drive's rules for expiring grants, so the
+// common mistakes fail before a request is made.
+func validateExpiration(typ, role string, t time.Time) error {
+	if typ != TypeUser && typ != TypeGroup {
+		return fmt.Errorf("%s permissions cannot expire", typ)
+	}
+	if role == RoleOwner || role == RoleOrganizer {
+		return fmt.Errorf("%s permissions cannot expire", role)
+	}
+	if !t.After(time.Now()) {
+		return errors.New("expiration time must be in the future")
+	}
+	return nil
+}
+
+// Create grants p on fileID and returns the stored permission.
+func Create(ctx context.Context, c *drive.Client, fileID string, p Permission, opts CreateOptions) (Permission, error) {
+	if err := validate(p); err != nil {
+		return Permission{}, err
+	}
+	path, err := permissionsPath(fileID)
+	if err != nil {
+		return Permission{}, err
+	}
+	q := url.Values{
+		"supportsAllDrives": {"true"},
+		"fields":            {Fields},
+	}
+	if p.Type == TypeUser || p.Type == TypeGroup {
+		q.Set("sendNotificationEmail", strconv.FormatBool(opts.SendNotificationEmail))
+		if opts.SendNotificationEmail && opts.EmailMessage != "" {
+			q.Set("emailMessage", opts.EmailMessage)
+		}
+	}
+	if p.Role == RoleOwner {
+		q.Set("transferOwnership", "true")
+	}
+	// Drive rejects read-only fields on create.
+	p.ID, p.DisplayName, p.Deleted, p.PendingOwner, p.Details = "", "", false, false, nil
+	var out Permission
+	if err := c.DoJSON(ctx, http.MethodPost, path+"?"+q.Encode(), p, &out); err != nil {
+		return Permission{}, err
+	}
+	return out, nil
+}
+
+// ShareWithUser grants role on fileID to the user with the given email.
+func ShareWithUser(ctx context.Context, c *drive.Client, fileID, email, role string, notify bool) (Permission, error) {
+	return Create(ctx, c, fileID, Permission{Type: TypeUser, Role: role, EmailAddress: email}, CreateOptions{SendNotificationEmail: notify})
+}
+
+// ShareWithUserUntil grants role on fileID to a user until expires, after
+// which Drive revokes it.
+func ShareWithUserUntil(ctx context.Context, c *drive.Client, fileID, email, role string, expires time.Time, notify bool) (Permission, error) {
+	return Create(ctx, c, fileID, Permission{Type: TypeUser, Role: role, EmailAddress: email, ExpirationTime: &expires}, CreateOptions{SendNotificationEmail: notify})
+}
+
+// ShareWithGroup grants role on fileID to a Google group.
+func ShareWithGroup(ctx context.Context, c *drive.Client, fileID, email, role string, notify bool) (Permission, error) {
+	return Create(ctx, c, fileID, Permission{Type: TypeGroup, Role: role, EmailAddress: email}, CreateOptions{SendNotificationEmail: notify})
+}
+
+// ShareWithDomain grants role on fileID to everyone in a Workspace domain.
+// Unless discoverable, they need the link to find it.
+func ShareWithDomain(ctx context.Context, c *drive.Client, fileID, domain, role string, discoverable bool) (Permission, error) {
+	return Create(ctx, c, fileID, Permission{Type: TypeDomain, Role: role, Domain: domain, AllowFileDiscovery: discoverable}, CreateOptions{})
+}
+
+// ShareWithAnyone turns on link sharing for fileID. Unless discoverable,
+// only people with the link can open it.
+func ShareWithAnyone(ctx context.Context, c *drive.Client, fileID, role string, discoverable bool) (Permission, error) {
+	return Create(ctx, c, fileID, Permission{Type: TypeAnyone, Role: role, AllowFileDiscovery: discoverable}, CreateOptions{})
+}
+
+// List returns every permission on fileID.
+func List(ctx context.Context, c *drive.Client, fileID string) ([]Permission, error) {
+	path, err := permissionsPath(fileID)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{
+		"supportsAllDrives": {"true"},
+		"fields":            {"nextPageToken,permissions(" + Fields + ")"},
+	}
+	var all []Permission
+	for {
+		var page struct {
+			NextPageToken string       `json:"nextPageToken"`
+			Permissions   []Permission `json:"permissions"`
+		}
+		if err := c.DoJSON(ctx, http.MethodGet, path+"?"+q.Encode(), nil, &page); err != nil {
+			return nil, fmt.Errorf("list permissions: %w", err)
+		}
+		all = append(all, page.Permissions...)
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Update changes the role of an existing permission.
+func Update(ctx context.Context, c *drive.Client, fileID, permissionID, role string) (Permission, error) {
+	if role == "" {
+		return Permission{}, errors.New("permission role is required")
+	}
+	return patch(ctx, c, fileID, permissionID, nil, map[string]string{"role": role})
+}
+
+// SetExpiration makes a user or group grant expire at t. A zero t removes
+// the expiration, keeping the grant indefinitely.
+func SetExpiration(ctx context.Context, c *drive.Client, fileID, permissionID string, t time.Time) (Permission, error) {
+	if t.IsZero() {
+		return patch(ctx, c, fileID, permissionID, url.Values{"removeExpiration": {"true"}}, map[string]string{})
+	}
+	if !t.After(time.Now()) {
+		return Permission{}, errors.New("expiration time must be in the future")
+	}
+	return patch(ctx, c, fileID, permissionID, nil, map[string]string{"expirationTime": t.UTC().Format(time.RFC3339)})
+}
+
+// patch applies body to an existing permission. extra is merged into the
+// query string.
+func patch(ctx context.Context, c *drive.Client, fileID, permissionID string, extra url.Values, body interface{}) (Permission, error) {
+	path, err := permissionsPath(fileID)
+	if err != nil {
+		return Permission{}, err
+	}
+	q := url.Values{"supportsAllDrives": {"true"}, "fields": {Fields}}
+	for k, v := range extra {
+		q[k] = v
+	}
+	var out Permission
+	if err := c.DoJSON(ctx, http.MethodPatch, path+"/"+url.PathEscape(permissionID)+"?"+q.Encode(), body, &out); err != nil {
+		return Permission{}, err
+	}
+	return out, nil
+}
+
+// Delete revokes a permission.
+func Delete(ctx context.Context, c *drive.Client, fileID, permissionID string) error {
+	path, err := permissionsPath(fileID)
+	if err != nil {
+		return err
+	}
+	return c.DoJSON(ctx, http.MethodDelete, path+"/"+url.PathEscape(permissionID)+"?supportsAllDrives=true", nil, nil)
+}
@@ -0,0 +1,164 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// rewriteRT rewrites outgoing requests to target the test server while preserving the original path+query.
+type rewriteRT struct {
+	base *url.URL
+	rt   http.RoundTripper
+}
+
+func (r rewriteRT) RoundTrip(req *http.Request) (*http.Response, error) {
+	newReq := req.Clone(req.Context())
+	newReq.URL.Scheme = r.base.Scheme
+	newReq.URL.Host = r.base.Host
+	return r.rt.RoundTrip(newReq)
+}
+
+func testClient(srv *httptest.Server) *drive.Client {
+	u, _ := url.Parse(srv.URL)
+	c := drive.NewClient("tok")
+	c.HTTPClient = &http.Client{Transport: rewriteRT{base: u, rt: http.DefaultTransport}}
+	return c
+}
+
+func TestCreate(t *testing.T) {
+	var gotQuery url.Values
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/drive/v3/files/f/permissions" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		gotQuery = r.URL.Query()
+		gotBody = nil
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"id":"p1","type":"user","role":"reader","emailAddress":"a@example.com"}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+
+	p, err := ShareWithUser(ctx, c, "f", "a@example.com", RoleReader, false)
+	if err != nil || p.ID != "p1" {
+		t.Fatalf("ShareWithUser = %+v, %v", p, err)
+	}
+	if gotQuery.Get("sendNotificationEmail") != "false" || gotBody["emailAddress"] != "a@example.com" || gotBody["role"] != "reader" {
+		t.Fatalf("unexpected request: %v %v", gotQuery, gotBody)
+	}
+
+	if _, err := ShareWithAnyone(ctx, c, "f", RoleReader, false); err != nil {
+		t.Fatalf("ShareWithAnyone: %v", err)
+	}
+	if gotBody["type"] != "anyone" || gotQuery.Has("sendNotificationEmail") {
+		t.Fatalf("unexpected request: %v %v", gotQuery, gotBody)
+	}
+
+	if _, err := ShareWithDomain(ctx, c, "f", "example.com", RoleCommenter, true); err != nil {
+		t.Fatalf("ShareWithDomain: %v", err)
+	}
+	if gotBody["domain"] != "example.com" || gotBody["allowFileDiscovery"] != true {
+		t.Fatalf("unexpected body: %v", gotBody)
+	}
+
+	for _, bad := range []Permission{
+		{Type: TypeUser, Role: RoleReader},
+		{Type: TypeDomain, Role: RoleReader},
+		{Type: "robot", Role: RoleReader},
+		{Type: TypeAnyone},
+	} {
+		if _, err := Create(ctx, c, "f", bad, CreateOptions{}); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestExpiringPermissions(t *testing.T) {
+	var gotQuery url.Values
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		gotBody = nil
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"id":"p1","type":"user","role":"commenter","expirationTime":"2030-01-02T03:04:05Z"}`))
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+	expires := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+
+	p, err := ShareWithUserUntil(ctx, c, "f", "r@example.com", RoleCommenter, expires, false)
+	if err != nil || p.ExpirationTime == nil || p.ExpirationTime.Year() != 2030 {
+		t.Fatalf("ShareWithUserUntil = %+v, %v", p, err)
+	}
+	if gotBody["expirationTime"] != expires.Format(time.RFC3339) {
+		t.Fatalf("expirationTime = %v, want %s", gotBody["expirationTime"], expires.Format(time.RFC3339))
+	}
+
+	if _, err := SetExpiration(ctx, c, "f", "p1", time.Time{}); err != nil || gotQuery.Get("removeExpiration") != "true" {
+		t.Fatalf("SetExpiration(zero) = %v, query %v", err, gotQuery)
+	}
+	if _, err := SetExpiration(ctx, c, "f", "p1", expires); err != nil || gotBody["expirationTime"] != expires.Format(time.RFC3339) {
+		t.Fatalf("SetExpiration = %v, body %v", err, gotBody)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	for _, bad := range []Permission{
+		{Type: TypeAnyone, Role: RoleReader, ExpirationTime: &expires},
+		{Type: TypeUser, Role: RoleOwner, EmailAddress: "r@example.com", ExpirationTime: &expires},
+		{Type: TypeUser, Role: RoleReader, EmailAddress: "r@example.com", ExpirationTime: &past},
+	} {
+		if _, err := Create(ctx, c, "f", bad, CreateOptions{}); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestListUpdateDelete(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"nextPageToken":"n","permissions":[{"id":"p1","type":"user","role":"owner"}]}`))
+				return
+			}
+			w.Write([]byte(`{"permissions":[{"id":"anyoneWithLink","type":"anyone","role":"reader"}]}`))
+		case http.MethodPatch:
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"id":"p2","role":"` + body["role"] + `"}`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+
+	perms, err := List(ctx, c, "f")
+	if err != nil || len(perms) != 2 || perms[1].Type != TypeAnyone {
+		t.Fatalf("List = %+v, %v", perms, err)
+	}
+	p, err := Update(ctx, c, "f", "p2", RoleCommenter)
+	if err != nil || p.Role != RoleCommenter {
+		t.Fatalf("Update = %+v, %v", p, err)
+	}
+	if err := Delete(ctx, c, "f", "p2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if calls[2] != "PATCH /drive/v3/files/f/permissions/p2" || calls[3] != "DELETE /drive/v3/files/f/permissions/p2" {
+		t.Fatalf("calls = %v", calls)
+	}
+}
@@ -0,0 +1,317 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// Policy declares the permissions some folders must have. In YAML:
+//
+//	folders:
+//	  - path: Handbook/Published
+//	    exclusive: true
+//	    grants:
+//	      - user: editor@example.com
+//	        role: writer
+//	      - group: staff@example.com
+//	        role: reader
+//	      - domain: example.com
+//	        role: reader
+//	  - id: 1AbCdEf
+//	    grants:
+//	      - anyone: true
+//	        role: reader
+type Policy struct {
+	Folders []FolderPolicy `yaml:"folders"`
+}
+
+// FolderPolicy is the required sharing of one folder.
+type FolderPolicy struct {
+	// Path is resolved from the root of My Drive; ID (or a folder URL) may
+	// be given instead.
+	Path string `yaml:"path"`
+	ID   string `yaml:"id"`
+	// Exclusive revokes grants the policy does not list. Owners and
+	// inherited grants are never revoked.
+	Exclusive bool    `yaml:"exclusive"`
+	Grants    []Grant `yaml:"grants"`
+}
+
+// Grant is one required permission. Exactly one of User, Group, Domain, and
+// Anyone identifies the grantee.
+type Grant struct {
+	User         string `yaml:"user"`
+	Group        string `yaml:"group"`
+	Domain       string `yaml:"domain"`
+	Anyone       bool   `yaml:"anyone"`
+	Role         string `yaml:"role"`
+	Discoverable bool   `yaml:"discoverable"`
+}
+
+func (g Grant) permission() (Permission, error) {
+	var p Permission
+	n := 0
+	if g.User != "" {
+		p.Type, p.EmailAddress = TypeUser, g.User
+		n++
+	}
+	if g.Group != "" {
+		p.Type, p.EmailAddress = TypeGroup, g.Group
+		n++
+	}
+	if g.Domain != "" {
+		p.Type, p.Domain = TypeDomain, g.Domain
+		n++
+	}
+	if g.Anyone {
+		p.Type = TypeAnyone
+		n++
+	}
+	if n != 1 {
+		return Permission{}, errors.New("grant must name exactly one of user, group, domain, or anyone")
+	}
+	if _, ok := roleRank[g.Role]; !ok {
+		return Permission{}, fmt.Errorf("unknown role %q", g.Role)
+	}
+	if g.Role == RoleOwner {
+		return Permission{}, errors.New("owner cannot be granted by policy; use TransferOwnership")
+	}
+	p.Role = g.Role
+	if p.Type == TypeDomain || p.Type == TypeAnyone {
+		p.AllowFileDiscovery = g.Discoverable
+	}
+	return p, nil
+}
+
+// ParsePolicy reads a YAML policy and checks every grant.
+func ParsePolicy(r io.Reader) (*Policy, error) {
+	var pol Policy
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&pol); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	for i, f := range pol.Folders {
+		if (f.Path == "") == (f.ID == "") {
+			return nil, fmt.Errorf("policy folder %d: set exactly one of path and id", i+1)
+		}
+		for j, g := range f.Grants {
+			if _, err := g.permission(); err != nil {
+				return nil, fmt.Errorf("policy folder %d, grant %d: %w", i+1, j+1, err)
+			}
+		}
+	}
+	return &pol, nil
+}
+
+// LoadPolicy reads a YAML policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePolicy(f)
+}
+
+// Plan operations.
+const (
+	OpAdd    = "add"
+	OpUpdate = "update"
+	// OpReplace removes a grant and adds it back with different settings
+	// Drive cannot update in place.
+	OpReplace = "replace"
+	OpRemove  = "remove"
+)
+
+// Action is one change needed to bring a folder in line with a policy.
+type Action struct {
+	Op       string
+	FolderID string
+	// Folder is the path or ID the policy names the folder by.
+	Folder string
+	// Permission is the grant to add, or the existing grant to update,
+	// replace, or remove.
+	Permission Permission
+	// NewRole is the target role of an update.
+	NewRole string
+	// Replacement is the grant that replaces Permission.
+	Replacement Permission
+}
+
+func (a Action) String() string {
+	p := a.Permission
+	who := p.Type
+	switch p.Type {
+	case TypeUser, TypeGroup:
+		who += " " + p.EmailAddress
+	case TypeDomain:
+		who += " " + p.Domain
+	}
+	switch a.Op {
+	case OpAdd:
+		return fmt.Sprintf("+ %s: %s %s", a.Folder, who, p.Role)
+	case OpUpdate:
+		return fmt.Sprintf("~ %s: %s %s -> %s", a.Folder, who, p.Role, a.NewRole)
+	case OpReplace:
+		return fmt.Sprintf("~ %s: %s %s (discoverable %t) -> %s (discoverable %t)", a.Folder, who, p.Role, p.AllowFileDiscovery, a.Replacement.Role, a.Replacement.AllowFileDiscovery)
+	}
+	return fmt.Sprintf("- %s: %s %s", a.Folder, who, p.Role)
+}
+
+// Plan is the set of changes ApplyPlan makes.
+type Plan struct {
+	Actions []Action
+}
+
+// Empty reports whether Drive already matches the policy.
+func (p *Plan) Empty() bool { return len(p.Actions) == 0 }
+
+// WriteTo writes the plan one action per line.
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, a := range p.Actions {
+		m, err := fmt.Fprintln(w, a)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// granteeKey identifies a grantee independently of role.
+func granteeKey(p Permission) string {
+	switch p.Type {
+	case TypeUser, TypeGroup:
+		return p.Type + ":" + strings.ToLower(p.EmailAddress)
+	case TypeDomain:
+		return p.Type + ":" + strings.ToLower(p.Domain)
+	}
+	return p.Type
+}
+
+// PlanPolicy compares pol with the permissions in Drive and returns the
+// changes needed to match it, without making any.
+func PlanPolicy(ctx context.Context, c *drive.Client, pol *Policy) (*Plan, error) {
+	r := folder.NewResolver(c, folder.DefaultTTL)
+	plan := &Plan{}
+	for _, fp := range pol.Folders {
+		id, err := resolveFolder(ctx, r, fp)
+		if err != nil {
+			return nil, err
+		}
+		actions, err := planFolder(ctx, c, fp, id)
+		if err != nil {
+			return nil, err
+		}
+		plan.Actions = append(plan.Actions, actions...)
+	}
+	return plan, nil
+}
+
+// name is how fp is shown in plans and reports.
+func (fp FolderPolicy) name() string {
+	if fp.Path != "" {
+		return fp.Path
+	}
+	return fp.ID
+}
+
+func resolveFolder(ctx context.Context, r *folder.Resolver, fp FolderPolicy) (string, error) {
+	if fp.Path == "" {
+		return drive.ParseID(fp.ID)
+	}
+	return r.ResolvePath(ctx, fp.Path)
+}
+
+func planFolder(ctx context.Context, c *drive.Client, fp FolderPolicy, id string) ([]Action, error) {
+	name := fp.name()
+	existing, err := List(ctx, c, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	current := make(map[string]Permission, len(existing))
+	for _, p := range existing {
+		current[granteeKey(p)] = p
+	}
+	var actions []Action
+	wanted := make(map[string]bool, len(fp.Grants))
+	for _, g := range fp.Grants {
+		want, _ := g.permission()
+		key := granteeKey(want)
+		wanted[key] = true
+		have, ok := current[key]
+		switch {
+		case !ok:
+			actions = append(actions, Action{Op: OpAdd, FolderID: id, Folder: name, Permission: want})
+		case have.Role == RoleOwner:
+			// Owners already have every right a grant could give.
+		case have.AllowFileDiscovery != want.AllowFileDiscovery:
+			actions = append(actions, Action{Op: OpReplace, FolderID: id, Folder: name, Permission: have, Replacement: want})
+		case have.Role != want.Role:
+			actions = append(actions, Action{Op: OpUpdate, FolderID: id, Folder: name, Permission: have, NewRole: want.Role})
+		}
+	}
+	if fp.Exclusive {
+		for _, p := range existing {
+			if wanted[granteeKey(p)] || p.Role == RoleOwner || inherited(p) {
+				continue
+			}
+			actions = append(actions, Action{Op: OpRemove, FolderID: id, Folder: name, Permission: p})
+		}
+	}
+	return actions, nil
+}
+
+// inherited reports whether Drive says p comes entirely from a parent.
+func inherited(p Permission) bool {
+	if len(p.Details) == 0 {
+		return false
+	}
+	for _, d := range p.Details {
+		if !d.Inherited {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyPlan makes the changes in plan. Grants are added and updated before
+// any are removed, so nobody loses access mid-run that the policy keeps.
+// It stops at the first failure.
+func ApplyPlan(ctx context.Context, c *drive.Client, plan *Plan) error {
+	for _, op := range []string{OpAdd, OpUpdate, OpReplace, OpRemove} {
+		for _, a := range plan.Actions {
+			if a.Op != op {
+				continue
+			}
+			var err error
+			switch a.Op {
+			case OpAdd:
+				_, err = Create(ctx, c, a.FolderID, a.Permission, CreateOptions{})
+			case OpUpdate:
+				_, err = Update(ctx, c, a.FolderID, a.Permission.ID, a.NewRole)
+			case OpReplace:
+				if err = Delete(ctx, c, a.FolderID, a.Permission.ID); err == nil {
+					_, err = Create(ctx, c, a.FolderID, a.Replacement, CreateOptions{})
+				}
+			case OpRemove:
+				err = Delete(ctx, c, a.FolderID, a.Permission.ID)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", a, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package permissions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPolicy = `
+folders:
+  - path: Handbook
+    exclusive: true
+    grants:
+      - user: Editor@example.com
+        role: writer
+      - group: staff@example.com
+        role: reader
+      - anyone: true
+        role: reader
+`
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, bad := range []string{
+		"folders:\n  - grants: []\n",
+		"folders:\n  - id: x\n    grants:\n      - user: a@example.com\n        group: g@example.com\n        role: reader\n",
+		"folders:\n  - id: x\n    grants:\n      - user: a@example.com\n        role: boss\n",
+		"folders:\n  - id: x\n    grants:\n      - user: a@example.com\n        role: owner\n",
+		"folders:\n  - id: x\n    color: blue\n",
+	} {
+		if _, err := ParsePolicy(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPlanAndApplyPolicy(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			w.Write([]byte(`{"files":[{"id":"hb","name":"Handbook"}]}`))
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"permissions":[
+				{"id":"o","type":"user","role":"owner","emailAddress":"me@example.com"},
+				{"id":"e","type":"user","role":"reader","emailAddress":"editor@example.com"},
+				{"id":"x","type":"user","role":"writer","emailAddress":"old@example.com"},
+				{"id":"anyoneWithLink","type":"anyone","role":"reader","allowFileDiscovery":true},
+				{"id":"sd","type":"group","role":"organizer","emailAddress":"admins@example.com","permissionDetails":[{"role":"organizer","inherited":true}]}]}`))
+		default:
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			calls = append(calls, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/drive/v3/files/hb/permissions"))
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer srv.Close()
+	c := testClient(srv)
+	ctx := context.Background()
+
+	pol, err := ParsePolicy(strings.NewReader(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	plan, err := PlanPolicy(ctx, c, pol)
+	if err != nil {
+		t.Fatalf("PlanPolicy: %v", err)
+	}
+	var out strings.Builder
+	plan.WriteTo(&out)
+	want := `~ Handbook: user editor@example.com reader -> writer
++ Handbook: group staff@example.com reader
+~ Handbook: anyone reader (discoverable true) -> reader (discoverable false)
+- Handbook: user old@example.com writer
+`
+	if out.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if err := ApplyPlan(ctx, c, plan); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	wantCalls := []string{"POST ", "PATCH /e", "DELETE /anyoneWithLink", "POST ", "DELETE /x"}
+	if strings.Join(calls, ",") != strings.Join(wantCalls, ",") {
+		t.Fatalf("calls = %q, want %q", calls, wantCalls)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			t.Errorf("Verify changed something: %s %s", r.Method, r.URL.Path)
+		case r.URL.Path == "/drive/v3/files":
+			if strings.Contains(r.URL.Query().Get("q"), "Gone") {
+				w.Write([]byte(`{"files":[]}`))
+				return
+			}
+			w.Write([]byte(`{"files":[{"id":"hb","name":"Handbook"}]}`))
+		case r.URL.Path == "/drive/v3/files/hb/permissions":
+			w.Write([]byte(`{"permissions":[{"id":"e","type":"user","role":"writer","emailAddress":"editor@example.com"},{"id":"x","type":"user","role":"writer","emailAddress":"old@example.com"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pol, err := ParsePolicy(strings.NewReader(`
+folders:
+  - path: Handbook
+    grants:
+      - user: editor@example.com
+        role: writer
+  - path: Gone
+  - id: deleted
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	report, err := Verify(context.Background(), testClient(srv), pol)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	var out strings.Builder
+	report.WriteTo(&out)
+	if report.OK() || out.String() != "! Gone: folder missing\n! deleted: folder missing\n" {
+		t.Fatalf("report:\n%s", out.String())
+	}
+
+	pol.Folders = pol.Folders[:1]
+	pol.Folders[0].Exclusive = true
+	if report, err = Verify(context.Background(), testClient(srv), pol); err != nil || report.OK() || len(report.Changes) != 1 || report.Changes[0].Op != OpRemove {
+		t.Fatalf("exclusive report = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,61 @@
+package permissions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/folder"
+)
+
+// DriftReport lists every way Drive differs from a policy.
+type DriftReport struct {
+	// MissingFolders holds the policy folders that do not exist.
+	MissingFolders []string
+	// Changes are the actions ApplyPlan would take to remove the drift.
+	Changes []Action
+}
+
+// OK reports whether Drive matches the policy.
+func (r *DriftReport) OK() bool { return len(r.MissingFolders) == 0 && len(r.Changes) == 0 }
+
+// WriteTo writes one line per difference, in the same notation as a Plan.
+func (r *DriftReport) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, f := range r.MissingFolders {
+		m, err := fmt.Fprintf(w, "! %s: folder missing\n", f)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	m, err := (&Plan{Actions: r.Changes}).WriteTo(w)
+	return n + m, err
+}
+
+// Verify compares Drive with pol and reports drift without changing
+// anything. Unlike PlanPolicy it does not stop at a missing folder, so one
+// run reports every problem; that makes it suitable for a scheduled
+// compliance check that fails when !report.OK().
+func Verify(ctx context.Context, c *drive.Client, pol *Policy) (*DriftReport, error) {
+	r := folder.NewResolver(c, folder.DefaultTTL)
+	report := &DriftReport{}
+	for _, fp := range pol.Folders {
+		id, err := resolveFolder(ctx, r, fp)
+		if err == nil {
+			var actions []Action
+			actions, err = planFolder(ctx, c, fp, id)
+			report.Changes = append(report.Changes, actions...)
+		}
+		if errors.Is(err, drive.ErrNotFound) {
+			report.MissingFolders = append(report.MissingFolders, fp.name())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package query_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+	"github.com/hwalton/gdrivetoolbox/drivetest"
+	"github.com/hwalton/gdrivetoolbox/list"
+	"github.com/hwalton/gdrivetoolbox/query"
+)
+
+// names that have broken hand-built queries.
+var names = []string{
+	"plain.pdf", "Bob's SOP.pdf", `back\slash.pdf`, `trailing\`, `\'`, "100% done.pdf", "a%27b", "a+b&c=d",
+	"' or name contains '", `\' or trashed = true or name = \'`, "quote''twice", "new\nline", "日本語.pdf", "",
+}
+
+func FuzzQuote(f *testing.F) {
+	for _, n := range names {
+		f.Add(n)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		q := query.Quote(s)
+		if len(q) < 2 || q[0] != '\'' || q[len(q)-1] != '\'' {
+			t.Fatalf("Quote(%q) = %s, not a literal", s, q)
+		}
+		// Unquoting gives s back, and no quote ends the literal early.
+		var b strings.Builder
+		body := q[1 : len(q)-1]
+		for i := 0; i < len(body); i++ {
+			switch body[i] {
+			case '\\':
+				i++
+				if i == len(body) || body[i] != '\\' && body[i] != '\'' {
+					t.Fatalf("Quote(%q) = %s, with a stray backslash", s, q)
+				}
+			case '\'':
+				t.Fatalf("Quote(%q) = %s, with an unescaped quote", s, q)
+			}
+			b.WriteByte(body[i])
+		}
+		if b.String() != s {
+			t.Fatalf("Quote(%q) unquotes to %q", s, b.String())
+		}
+	})
+}
+
+// FuzzNameEquals looks files up by name through a fake Drive API, which
+// parses queries as Drive does, so a name that breaks out of its literal
+// finds another file or none.
+func FuzzNameEquals(f *testing.F) {
+	for _, n := range names {
+		f.Add(n)
+	}
+	srv := drivetest.NewServer()
+	f.Cleanup(srv.Close)
+	c := srv.Client()
+	f.Fuzz(func(t *testing.T, name string) {
+		if !utf8.ValidString(name) {
+			t.Skip("Drive names are UTF-8")
+		}
+		folder := srv.AddFolder("f", "")
+		srv.Add(drive.File{Name: "decoy", Parents: []string{folder}, Trashed: true}, nil)
+		want := srv.Add(drive.File{Name: name, Parents: []string{folder}}, nil)
+		files, err := list.ListFiles(context.Background(), c, list.Options{
+			Query: query.New().InParent(folder).NameEquals(name).NotTrashed().String(),
+		})
+		if err != nil {
+			t.Fatalf("NameEquals(%q): %v", name, err)
+		}
+		if len(files) != 1 || files[0].ID != want.ID {
+			t.Fatalf("NameEquals(%q) found %+v, want %s", name, files, want.ID)
+		}
+	})
+}
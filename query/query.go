@@ -0,0 +1,139 @@
+// Package query builds Drive search expressions (the q parameter of
+// files.list) with every string literal escaped, so names containing
+// apostrophes or backslashes cannot break or alter the query.
+package query
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hwalton/gdrivetoolbox/drive"
+)
+
+// Builder accumulates terms that are joined with "and". The zero value is
+// an empty query; every method returns a new Builder, so partial queries can
+// be shared and extended safely.
+type Builder struct {
+	terms []string
+}
+
+// New returns an empty Builder.
+func New() Builder {
+	return Builder{}
+}
+
+// Quote returns s as a single-quoted Drive query literal, with backslashes
+// and single quotes escaped by a backslash. This is query syntax, not URL
+// encoding: the q parameter is URL-encoded as a whole when sent, so percent
+// signs and other characters are left as they are.
+func Quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(s) + "'"
+}
+
+func (b Builder) with(term string) Builder {
+	terms := make([]string, len(b.terms), len(b.terms)+1)
+	copy(terms, b.terms)
+	return Builder{terms: append(terms, term)}
+}
+
+// Raw adds a hand-written term as is. The caller is responsible for quoting.
+func (b Builder) Raw(term string) Builder {
+	return b.with(term)
+}
+
+// InParent matches direct children of folderID.
+func (b Builder) InParent(folderID string) Builder {
+	return b.with(Quote(folderID) + " in parents")
+}
+
+// NameEquals matches files named exactly name.
+func (b Builder) NameEquals(name string) Builder {
+	return b.with("name = " + Quote(name))
+}
+
+// NameContains matches files whose name contains s.
+func (b Builder) NameContains(s string) Builder {
+	return b.with("name contains " + Quote(s))
+}
+
+// MimeType matches files of exactly mimeType.
+func (b Builder) MimeType(mimeType string) Builder {
+	return b.with("mimeType = " + Quote(mimeType))
+}
+
+// NotMimeType excludes files of mimeType.
+func (b Builder) NotMimeType(mimeType string) Builder {
+	return b.with("mimeType != " + Quote(mimeType))
+}
+
+// Folders matches only folders.
+func (b Builder) Folders() Builder {
+	return b.MimeType(drive.FolderMimeType)
+}
+
+// NotFolders excludes folders.
+func (b Builder) NotFolders() Builder {
+	return b.NotMimeType(drive.FolderMimeType)
+}
+
+// NotTrashed excludes trashed files.
+func (b Builder) NotTrashed() Builder {
+	return b.with("trashed = false")
+}
+
+// Trashed matches only trashed files.
+func (b Builder) Trashed() Builder {
+	return b.with("trashed = true")
+}
+
+// FullText matches files whose name, description, or content contains text.
+func (b Builder) FullText(text string) Builder {
+	return b.with("fullText contains " + Quote(text))
+}
+
+// ModifiedAfter matches files modified strictly after t.
+func (b Builder) ModifiedAfter(t time.Time) Builder {
+	return b.with("modifiedTime > " + Quote(t.UTC().Format(time.RFC3339)))
+}
+
+// Owner matches files owned by the given email address.
+func (b Builder) Owner(email string) Builder {
+	return b.with(Quote(email) + " in owners")
+}
+
+// AppProperty matches files whose appProperties contain key=value.
+func (b Builder) AppProperty(key, value string) Builder {
+	return b.with("appProperties has { key=" + Quote(key) + " and value=" + Quote(value) + " }")
+}
+
+// Label matches files that have the Drive label labelID applied.
+func (b Builder) Label(labelID string) Builder {
+	return b.with(Quote("labels/"+labelID) + " in labels")
+}
+
+// LabelField matches files whose label labelID has a text or selection
+// field fieldID equal to value.
+func (b Builder) LabelField(labelID, fieldID, value string) Builder {
+	return b.with("labels/" + labelID + "." + fieldID + " = " + Quote(value))
+}
+
+// Or adds a single term that matches when any of alts matches. Empty
+// alternatives are ignored.
+func (b Builder) Or(alts ...Builder) Builder {
+	var parts []string
+	for _, a := range alts {
+		if s := a.String(); s != "" {
+			parts = append(parts, "("+s+")")
+		}
+	}
+	if len(parts) == 0 {
+		return b
+	}
+	return b.with("(" + strings.Join(parts, " or ") + ")")
+}
+
+// String renders the query for use as the q parameter.
+func (b Builder) String() string {
+	return strings.Join(b.terms, " and ")
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	cases := map[string]string{
+		"plain":      `'plain'`,
+		"Bob's SOP":  `'Bob\'s SOP'`,
+		`back\slash`: `'back\\slash'`,
+		`\'`:         `'\\\''`,
+		"":           `''`,
+	}
+	for in, want := range cases {
+		if got := Quote(in); got != want {
+			t.Errorf("Quote(%q) = %s; want %s", in, got, want)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	got := New().InParent("p1").NameEquals("Bob's.pdf").NotTrashed().String()
+	want := `'p1' in parents and name = 'Bob\'s.pdf' and trashed = false`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+
+	got = New().NotFolders().ModifiedAfter(time.Date(2025, 3, 4, 5, 6, 7, 0, time.FixedZone("x", 3600))).String()
+	want = `mimeType != 'application/vnd.google-apps.folder' and modifiedTime > '2025-03-04T04:06:07Z'`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+
+	got = New().Label("abc123").LabelField("abc123", "f1", "Controlled").String()
+	want = `'labels/abc123' in labels and labels/abc123.f1 = 'Controlled'`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+
+	got = New().FullText("reagent").Or(New().InParent("a"), New(), New().InParent("b")).String()
+	want = `fullText contains 'reagent' and (('a' in parents) or ('b' in parents))`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestBuilder_Immutable(t *testing.T) {
+	base := New().InParent("p")
+	a := base.NameEquals("a")
+	b := base.NameEquals("b")
+	if a.String() == b.String() || base.String() != `'p' in parents` {
+		t.Fatalf("builders share state: %q %q %q", base, a, b)
+	}
+}